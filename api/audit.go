@@ -0,0 +1,296 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/config"
+	intAudit "github.com/banzaicloud/pipeline/internal/audit"
+	pkgAudit "github.com/banzaicloud/pipeline/pkg/audit"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultAuditEventsLimit = 100
+	maxAuditEventsLimit     = 1000
+)
+
+// ListAuditEvents lists the audited API requests of an organization, from the newest to the oldest
+func ListAuditEvents(c *gin.Context) {
+	organization, ok := getAuditOrganization(c)
+	if !ok {
+		return
+	}
+
+	query, err := parseAuditEventQuery(c, organization.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid query parameter",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultAuditEventsLimit
+	}
+
+	events, err := intAudit.NewEvents(config.DB(), viper.GetString("pipeline.basepath")).Find(query)
+	if err != nil {
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error listing audit events",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	response := pkgAudit.EventsResponse{
+		Events: make([]pkgAudit.Event, 0, len(events)),
+		Limit:  query.Limit,
+	}
+
+	for _, event := range events {
+		response.Events = append(response.Events, newAuditEvent(event))
+	}
+
+	if len(events) == query.Limit {
+		response.NextCursor = events[len(events)-1].ID
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ExportAuditEvents streams the audited API requests of an organization matching the filters in CSV or NDJSON format
+func ExportAuditEvents(c *gin.Context) {
+	organization, ok := getAuditOrganization(c)
+	if !ok {
+		return
+	}
+
+	query, err := parseAuditEventQuery(c, organization.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid query parameter",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	format := c.DefaultQuery("format", pkgAudit.ExportNDJSON)
+
+	var write func(event pkgAudit.Event) error
+	var flush func() error
+
+	switch format {
+	case pkgAudit.ExportCSV:
+		c.Header("Content-Type", "text/csv")
+
+		w := csv.NewWriter(c.Writer)
+		header := []string{
+			"id", "time", "correlationId", "userId", "clientIp", "userAgent", "method", "path",
+			"clusterId", "secretId", "statusCode", "latency", "errorMessage", "body",
+		}
+		write = func(event pkgAudit.Event) error {
+			if header != nil {
+				if err := w.Write(header); err != nil {
+					return err
+				}
+				header = nil
+			}
+
+			var body string
+			if event.Body != nil {
+				body = *event.Body
+			}
+
+			return w.Write([]string{
+				fmt.Sprint(event.ID),
+				event.Time.Format(time.RFC3339),
+				event.CorrelationID,
+				fmt.Sprint(event.UserID),
+				event.ClientIP,
+				event.UserAgent,
+				event.Method,
+				event.Path,
+				fmt.Sprint(event.ClusterID),
+				event.SecretID,
+				fmt.Sprint(event.StatusCode),
+				fmt.Sprint(event.Latency),
+				event.ErrorMessage,
+				body,
+			})
+		}
+		flush = func() error {
+			w.Flush()
+			return w.Error()
+		}
+
+	case pkgAudit.ExportNDJSON:
+		c.Header("Content-Type", "application/x-ndjson")
+
+		encoder := json.NewEncoder(c.Writer)
+		write = func(event pkgAudit.Event) error {
+			return encoder.Encode(event)
+		}
+		flush = func() error { return nil }
+
+	default:
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid query parameter",
+			Error:   fmt.Sprintf("format must be %s or %s: %s", pkgAudit.ExportCSV, pkgAudit.ExportNDJSON, format),
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=audit-%d.%s", organization.ID, format))
+	c.Status(http.StatusOK)
+
+	err = intAudit.NewEvents(config.DB(), viper.GetString("pipeline.basepath")).Each(query, func(event *intAudit.AuditEvent) error {
+		return write(newAuditEvent(event))
+	})
+	if err == nil {
+		err = flush()
+	}
+
+	// The status code is already sent, the export is truncated
+	if err != nil {
+		errorHandler.Handle(errors.Wrap(err, "failed to export audit events"))
+	}
+}
+
+// getAuditOrganization returns the organization of the request, the audit log is available to organization admins only
+func getAuditOrganization(c *gin.Context) (*auth.Organization, bool) {
+	organization := auth.GetCurrentOrganization(c.Request)
+	user := auth.GetCurrentUser(c.Request)
+
+	if user == nil || !auth.HasOrgRole(user.ID, organization.ID, auth.OrgRoleAdmin) {
+		c.AbortWithStatusJSON(http.StatusForbidden, pkgCommon.ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "the audit log is available to organization admins only",
+			Error:   http.StatusText(http.StatusForbidden),
+		})
+		return nil, false
+	}
+
+	return organization, true
+}
+
+// parseAuditEventQuery parses the filter (cluster, secret, correlationId, user, method, path, status, from, to) and paging (cursor, limit) query parameters
+func parseAuditEventQuery(c *gin.Context, organizationID uint) (intAudit.EventQuery, error) {
+	query := intAudit.EventQuery{
+		OrganizationID: organizationID,
+		SecretID:       c.Query("secret"),
+		CorrelationID:  c.Query("correlationId"),
+		Method:         c.Query("method"),
+		PathPrefix:     c.Query("path"),
+	}
+
+	if value := c.Query("cluster"); value != "" {
+		clusterID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return query, errors.Errorf("cluster must be a cluster id: %s", value)
+		}
+
+		query.ClusterID = uint(clusterID)
+	}
+
+	if value := c.Query("user"); value != "" {
+		userID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return query, errors.Errorf("user must be a user id: %s", value)
+		}
+
+		id := uint(userID)
+		query.UserID = &id
+	}
+
+	if value := c.Query("status"); value != "" {
+		statusCode, err := strconv.Atoi(value)
+		if err != nil {
+			return query, errors.Errorf("status must be an HTTP status code: %s", value)
+		}
+
+		query.StatusCode = statusCode
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return query, errors.Errorf("invalid cursor: %s", value)
+		}
+
+		query.Cursor = uint(cursor)
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAuditEventsLimit {
+			return query, errors.Errorf("limit must be a number between 1 and %d: %s", maxAuditEventsLimit, value)
+		}
+
+		query.Limit = limit
+	}
+
+	for param, target := range map[string]**time.Time{"from": &query.From, "to": &query.To} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, errors.Wrapf(err, "%s must be an RFC3339 timestamp", param)
+		}
+
+		*target = &t
+	}
+
+	return query, nil
+}
+
+func newAuditEvent(event *intAudit.AuditEvent) pkgAudit.Event {
+	return pkgAudit.Event{
+		ID:            event.ID,
+		Time:          event.Time,
+		CorrelationID: event.CorrelationID,
+		UserID:        event.UserID,
+		ClientIP:      event.ClientIP,
+		UserAgent:     event.UserAgent,
+		Method:        event.Method,
+		Path:          event.Path,
+		ClusterID:     event.ClusterID,
+		SecretID:      event.SecretID,
+		StatusCode:    event.StatusCode,
+		Latency:       event.Latency,
+		ErrorMessage:  event.ErrorMessage,
+		Body:          event.Body,
+	}
+}
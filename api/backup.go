@@ -0,0 +1,414 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/cluster"
+	"github.com/banzaicloud/pipeline/config"
+	intBackup "github.com/banzaicloud/pipeline/internal/backup"
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
+	"github.com/banzaicloud/pipeline/internal/platform/gin/correlationid"
+	"github.com/banzaicloud/pipeline/internal/platform/gin/utils"
+	pkgBackup "github.com/banzaicloud/pipeline/pkg/backup"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/banzaicloud/pipeline/pkg/providers"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// getBackupManager returns a backup manager using the clusters of the application.
+func getBackupManager() *intBackup.Manager {
+	// TODO: move these to a struct and create them only once upon application init
+	secretValidator := providers.NewSecretValidator(secret.Store)
+	clusterManager := cluster.NewManager(intCluster.NewClusters(config.DB()), secretValidator, intCluster.NewWorkflows(config.DB()), log, errorHandler)
+
+	getCluster := func(ctx context.Context, organizationID uint, clusterID uint) (intBackup.Cluster, error) {
+		return clusterManager.GetClusterByID(ctx, organizationID, clusterID)
+	}
+
+	return intBackup.NewManager(config.DB(), secret.Store, getCluster, log, errorHandler)
+}
+
+// CreateBackup starts backing up the Kubernetes resources and Helm releases of a cluster into a bucket
+func CreateBackup(c *gin.Context) {
+	logger := correlationid.Logger(log, c)
+
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	clusterID, ok := ginutils.UintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	logger = logger.WithFields(logrus.Fields{"organization": organizationID, "cluster": clusterID})
+
+	var request pkgBackup.CreateBackupRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Errorf("error during binding CreateBackupRequest: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "error during binding request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if !canUseBucketSecret(c, logger, organizationID, request.Bucket.SecretID) {
+		return
+	}
+
+	ctx := ginutils.Context(context.Background(), c)
+
+	backup, err := getBackupManager().CreateBackup(ctx, organizationID, clusterID, &request)
+	if err != nil {
+		handleBackupError(c, logger, err, "error creating backup")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, backup)
+}
+
+// ListClusterBackups lists the backups of a cluster
+func ListClusterBackups(c *gin.Context) {
+	logger := correlationid.Logger(log, c)
+
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	clusterID, ok := ginutils.UintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	backups, err := getBackupManager().ListBackups(organizationID, clusterID)
+	if err != nil {
+		handleBackupError(c, logger, err, "error listing backups")
+		return
+	}
+
+	c.JSON(http.StatusOK, backups)
+}
+
+// ListBackups lists the backups of an organization, the cluster query parameter selects the backups of a single cluster
+func ListBackups(c *gin.Context) {
+	logger := correlationid.Logger(log, c)
+
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	var clusterID uint
+	if value := c.Query("cluster"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "invalid query parameter",
+				Error:   "cluster must be a cluster ID: " + value,
+			})
+			return
+		}
+
+		clusterID = uint(id)
+	}
+
+	backups, err := getBackupManager().ListBackups(organizationID, clusterID)
+	if err != nil {
+		handleBackupError(c, logger, err, "error listing backups")
+		return
+	}
+
+	accessibleIDs, all, err := auth.GetAccessibleResourceIDs(c.Request, organizationID, auth.ClusterResource)
+	if err != nil {
+		handleBackupError(c, logger, err, "error getting accessible clusters")
+		return
+	}
+
+	if !all {
+		accessibleBackups := make([]pkgBackup.Backup, 0, len(backups))
+		for _, backup := range backups {
+			if accessibleIDs[fmt.Sprint(backup.ClusterID)] {
+				accessibleBackups = append(accessibleBackups, backup)
+			}
+		}
+		backups = accessibleBackups
+	}
+
+	c.JSON(http.StatusOK, backups)
+}
+
+// GetBackup returns a backup of a cluster
+func GetBackup(c *gin.Context) {
+	logger := correlationid.Logger(log, c)
+
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	backup, ok := getBackupFromRequest(c, logger, organizationID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, backup)
+}
+
+// DeleteBackup deletes a backup of a cluster and its object from the bucket
+func DeleteBackup(c *gin.Context) {
+	logger := correlationid.Logger(log, c)
+
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	backup, ok := getBackupFromRequest(c, logger, organizationID)
+	if !ok {
+		return
+	}
+
+	logger.WithField("backup", backup.ID).Info("deleting backup")
+
+	if err := getBackupManager().DeleteBackup(organizationID, backup.ID); err != nil {
+		handleBackupError(c, logger, err, "error deleting backup")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RestoreBackup replays a backup of any cluster of the organization the user has access to onto a cluster
+func RestoreBackup(c *gin.Context) {
+	logger := correlationid.Logger(log, c)
+
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	clusterID, ok := ginutils.UintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	logger = logger.WithFields(logrus.Fields{"organization": organizationID, "cluster": clusterID})
+
+	var request pkgBackup.RestoreRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Errorf("error during binding RestoreRequest: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "error during binding request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	manager := getBackupManager()
+
+	backup, err := manager.GetBackup(organizationID, request.BackupID)
+	if err != nil {
+		handleBackupError(c, logger, err, "error restoring backup")
+		return
+	}
+
+	// Restoring copies the resources of the backed up cluster, including its Secrets, downloaded with the secret of the bucket
+	if !canAccessCluster(c, logger, organizationID, backup.ClusterID) || !canUseBucketSecret(c, logger, organizationID, backup.Bucket.SecretID) {
+		return
+	}
+
+	ctx := ginutils.Context(context.Background(), c)
+
+	response, err := manager.Restore(ctx, organizationID, clusterID, &request)
+	if err != nil {
+		handleBackupError(c, logger, err, "error restoring backup")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetBackupSchedule returns the backup schedule of a cluster
+func GetBackupSchedule(c *gin.Context) {
+	logger := correlationid.Logger(log, c)
+
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	clusterID, ok := ginutils.UintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	schedule, err := getBackupManager().GetSchedule(organizationID, clusterID)
+	if err != nil {
+		handleBackupError(c, logger, err, "error getting backup schedule")
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// SetBackupSchedule creates or updates the backup schedule of a cluster
+func SetBackupSchedule(c *gin.Context) {
+	logger := correlationid.Logger(log, c)
+
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	clusterID, ok := ginutils.UintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	logger = logger.WithFields(logrus.Fields{"organization": organizationID, "cluster": clusterID})
+
+	var request pkgBackup.ScheduleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Errorf("error during binding ScheduleRequest: %s", err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "error during binding request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if !canUseBucketSecret(c, logger, organizationID, request.Bucket.SecretID) {
+		return
+	}
+
+	ctx := ginutils.Context(context.Background(), c)
+
+	schedule, err := getBackupManager().SetSchedule(ctx, organizationID, clusterID, &request)
+	if err != nil {
+		handleBackupError(c, logger, err, "error setting backup schedule")
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// DeleteBackupSchedule deletes the backup schedule of a cluster, the scheduled backups are kept
+func DeleteBackupSchedule(c *gin.Context) {
+	logger := correlationid.Logger(log, c)
+
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	clusterID, ok := ginutils.UintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := getBackupManager().DeleteSchedule(organizationID, clusterID); err != nil {
+		handleBackupError(c, logger, err, "error deleting backup schedule")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// getBackupFromRequest returns the backup of the cluster in the request, it handles error responses directly
+func getBackupFromRequest(c *gin.Context, logger logrus.FieldLogger, organizationID uint) (*pkgBackup.Backup, bool) {
+	clusterID, ok := ginutils.UintParam(c, "id")
+	if !ok {
+		return nil, false
+	}
+
+	backupID, ok := ginutils.UintParam(c, "backupid")
+	if !ok {
+		return nil, false
+	}
+
+	backup, err := getBackupManager().GetBackup(organizationID, backupID)
+	if err == nil && backup.ClusterID != clusterID {
+		c.JSON(http.StatusNotFound, pkgCommon.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "backup not found",
+			Error:   "backup not found",
+		})
+		return nil, false
+	} else if err != nil {
+		handleBackupError(c, logger, err, "error getting backup")
+		return nil, false
+	}
+
+	return backup, true
+}
+
+// canAccessCluster checks whether the user of the request has access to a cluster, it handles error responses directly
+func canAccessCluster(c *gin.Context, logger logrus.FieldLogger, organizationID uint, clusterID uint) bool {
+	accessibleIDs, all, err := auth.GetAccessibleResourceIDs(c.Request, organizationID, auth.ClusterResource)
+	if err != nil {
+		handleBackupError(c, logger, err, "error getting accessible clusters")
+		return false
+	}
+
+	if !all && !accessibleIDs[fmt.Sprint(clusterID)] {
+		c.JSON(http.StatusForbidden, pkgCommon.ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "restoring a backup requires access to the backed up cluster",
+			Error:   "access to the cluster is forbidden",
+		})
+		return false
+	}
+
+	return true
+}
+
+// canUseBucketSecret checks whether the user of the request has full access to the secret of a backup bucket,
+// it handles error responses directly
+func canUseBucketSecret(c *gin.Context, logger logrus.FieldLogger, organizationID uint, secretID string) bool {
+	accessibleIDs, all, err := auth.GetFullAccessResourceIDs(c.Request, organizationID, auth.SecretResource)
+	if err != nil {
+		handleBackupError(c, logger, err, "error getting accessible secrets")
+		return false
+	}
+
+	if !all && !accessibleIDs[secretID] {
+		c.JSON(http.StatusForbidden, pkgCommon.ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "using a backup bucket requires full access to its secret",
+			Error:   "access to the secret is forbidden",
+		})
+		return false
+	}
+
+	return true
+}
+
+// handleBackupError maps backup errors to error responses
+func handleBackupError(c *gin.Context, logger logrus.FieldLogger, err error, message string) {
+	if isNotFound(err) {
+		c.JSON(http.StatusNotFound, pkgCommon.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: message,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if _, ok := err.(intBackup.ValidationError); ok {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: message,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	logger.Errorf("%s: %s", message, err.Error())
+	errorHandler.Handle(err)
+
+	c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+		Code:    http.StatusInternalServerError,
+		Message: message,
+		Error:   err.Error(),
+	})
+}
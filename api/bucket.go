@@ -17,6 +17,7 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
@@ -94,6 +95,24 @@ func ListBuckets(c *gin.Context) {
 		return
 	}
 
+	accessibleNames, all, err := auth.GetAccessibleResourceIDs(c.Request, organization.ID, auth.BucketResource)
+	if err != nil {
+		logger.Errorf("retrieving accessible buckets failed: %s", err.Error())
+		ginutils.ReplyWithErrorResponse(c, errorResponseFrom(err))
+
+		return
+	}
+
+	if !all {
+		accessibleBuckets := make([]*objectstore.BucketInfo, 0, len(accessibleNames))
+		for _, bucket := range bucketList {
+			if accessibleNames[bucket.Name] {
+				accessibleBuckets = append(accessibleBuckets, bucket)
+			}
+		}
+		bucketList = accessibleBuckets
+	}
+
 	c.JSON(http.StatusOK, bucketList)
 }
 
@@ -134,7 +153,7 @@ func CreateBucket(c *gin.Context) {
 		createBucketRequest.SecretId = secret.GenerateSecretIDFromName(createBucketRequest.SecretName)
 	}
 
-	cloudType, err := determineCloudProviderFromRequest(createBucketRequest)
+	cloudType, err := determineCloudProviderFromProperties(createBucketRequest.Properties)
 	if err != nil {
 		ginutils.ReplyWithErrorResponse(c, errorResponseFrom(err))
 
@@ -147,6 +166,16 @@ func CreateBucket(c *gin.Context) {
 		"bucket":   createBucketRequest.Name,
 	})
 
+	if err := providers.ValidateBucketSettings(cloudType, createBucketRequest.Settings); err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid bucket settings",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
 	logger.Debug("validating secret")
 	retrievedSecret, err := getValidatedSecret(organization.ID, createBucketRequest.SecretId, cloudType)
 	if err != nil {
@@ -164,24 +193,7 @@ func CreateBucket(c *gin.Context) {
 		Organization: organization,
 	}
 
-	switch cloudType {
-	case pkgProviders.Alibaba:
-		objectStoreCtx.Location = createBucketRequest.Properties.Alibaba.Location
-
-	case pkgProviders.Amazon:
-		objectStoreCtx.Location = createBucketRequest.Properties.Amazon.Location
-
-	case pkgProviders.Google:
-		objectStoreCtx.Location = createBucketRequest.Properties.Google.Location
-
-	case pkgProviders.Azure:
-		objectStoreCtx.Location = createBucketRequest.Properties.Azure.Location
-		objectStoreCtx.ResourceGroup = createBucketRequest.Properties.Azure.ResourceGroup
-		objectStoreCtx.StorageAccount = createBucketRequest.Properties.Azure.StorageAccount
-
-	case pkgProviders.Oracle:
-		objectStoreCtx.Location = createBucketRequest.Properties.Oracle.Location
-	}
+	setObjectStoreContextProperties(objectStoreCtx, createBucketRequest.Properties)
 
 	objectStore, err := providers.NewObjectStore(objectStoreCtx, logger)
 	if err != nil {
@@ -200,7 +212,7 @@ func CreateBucket(c *gin.Context) {
 	go func() {
 		defer emperror.HandleRecover(errorHandler)
 
-		err := objectStore.CreateBucket(createBucketRequest.Name)
+		err := objectStore.CreateBucket(createBucketRequest.Name, createBucketRequest.Settings)
 		if err != nil {
 			errorHandler.Handle(err)
 		}
@@ -209,6 +221,188 @@ func CreateBucket(c *gin.Context) {
 	return
 }
 
+// ImportBucket registers an existing objectstore bucket (blob container in case of Azure) as managed by Pipeline
+// after checking that it can be accessed with the credentials from the given secret.
+func ImportBucket(c *gin.Context) {
+	logger := correlationid.Logger(log, c)
+
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	logger = logger.WithField("organization", organization.ID)
+
+	var importBucketRequest ImportBucketRequest
+	if err := c.BindJSON(&importBucketRequest); err != nil {
+		logger.Error(errors.Wrap(err, "Error parsing request"))
+
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing request",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
+	if importBucketRequest.SecretId == "" {
+		if importBucketRequest.SecretName == "" {
+			c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "either secretId or secretName has to be set",
+			})
+			return
+		}
+
+		importBucketRequest.SecretId = secret.GenerateSecretIDFromName(importBucketRequest.SecretName)
+	}
+
+	cloudType, err := determineCloudProviderFromProperties(importBucketRequest.Properties)
+	if err != nil {
+		ginutils.ReplyWithErrorResponse(c, errorResponseFrom(err))
+
+		return
+	}
+
+	logger = logger.WithFields(logrus.Fields{
+		"secret":   importBucketRequest.SecretId,
+		"provider": cloudType,
+		"bucket":   importBucketRequest.Name,
+	})
+
+	logger.Debug("validating secret")
+	retrievedSecret, err := getValidatedSecret(organization.ID, importBucketRequest.SecretId, cloudType)
+	if err != nil {
+		logger.Errorf("secret validation failed: %s", err.Error())
+		ginutils.ReplyWithErrorResponse(c, errorResponseFrom(err))
+
+		return
+	}
+
+	objectStoreCtx := &providers.ObjectStoreContext{
+		Provider:     cloudType,
+		Secret:       retrievedSecret,
+		Organization: organization,
+	}
+
+	setObjectStoreContextProperties(objectStoreCtx, importBucketRequest.Properties)
+
+	objectStore, err := providers.NewObjectStore(objectStoreCtx, logger)
+	if err != nil {
+		errorHandler.Handle(err)
+		ginutils.ReplyWithErrorResponse(c, errorResponseFrom(err))
+
+		return
+	}
+
+	logger.Info("importing object store bucket")
+
+	if err := objectStore.ImportBucket(importBucketRequest.Name); err != nil {
+		logger.Errorf("importing object store bucket failed: %s", err.Error())
+		ginutils.ReplyWithErrorResponse(c, errorResponseFrom(err))
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateBucketResponse{
+		BucketName: importBucketRequest.Name,
+	})
+}
+
+// UpdateBucket applies the settings (versioning, encryption, lifecycle rules and labels)
+// to the object storage bucket managed by Pipeline with the given name
+func UpdateBucket(c *gin.Context) {
+	logger := correlationid.Logger(log, c)
+
+	bucketName := c.Param("name")
+	logger = logger.WithField("bucket", bucketName)
+
+	var settings objectstore.BucketSettings
+	if err := c.BindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing request",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
+	organization, secret, cloudType, ok := getBucketContext(c, logger)
+	if !ok {
+		return
+	}
+
+	logger = logger.WithFields(logrus.Fields{
+		"organization": organization.ID,
+		"secret":       secret.ID,
+		"provider":     cloudType,
+	})
+
+	if err := providers.ValidateBucketSettings(cloudType, settings); err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid bucket settings",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
+	objectStoreCtx := &providers.ObjectStoreContext{
+		Provider:     cloudType,
+		Secret:       secret,
+		Organization: organization,
+	}
+
+	switch cloudType {
+	case pkgProviders.Oracle:
+		location, ok := ginutils.RequiredQueryOrAbort(c, "location")
+		if !ok {
+			logger.Debug("missing location")
+
+			return
+		}
+
+		objectStoreCtx.Location = location
+
+	case pkgProviders.Azure:
+		resourceGroup, ok := ginutils.RequiredQueryOrAbort(c, "resourceGroup")
+		if !ok {
+			logger.Debug("missing resource group")
+
+			return
+		}
+
+		storageAccount, ok := ginutils.RequiredQueryOrAbort(c, "storageAccount")
+		if !ok {
+			logger.Debug("missing storage account")
+
+			return
+		}
+
+		objectStoreCtx.ResourceGroup = resourceGroup
+		objectStoreCtx.StorageAccount = storageAccount
+	}
+
+	objectStore, err := providers.NewObjectStore(objectStoreCtx, logger)
+	if err != nil {
+		errorHandler.Handle(err)
+		ginutils.ReplyWithErrorResponse(c, errorResponseFrom(err))
+
+		return
+	}
+
+	logger.Info("updating object store bucket settings")
+
+	if err := objectStore.UpdateBucketSettings(bucketName, settings); err != nil {
+		errorHandler.Handle(err)
+		ginutils.ReplyWithErrorResponse(c, errorResponseFrom(err))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
 // CheckBucket checks if the given there is a bucket exists with the given name
 func CheckBucket(c *gin.Context) {
 	logger := correlationid.Logger(log, c)
@@ -283,7 +477,8 @@ func CheckBucket(c *gin.Context) {
 }
 
 // DeleteBucket deletes object storage buckets (object storage container in case of Azure)
-// that can be accessed with the credentials from the given secret
+// that can be accessed with the credentials from the given secret.
+// Buckets are only removed from the managed ones without deleting them if the forget query parameter is set.
 func DeleteBucket(c *gin.Context) {
 	logger := correlationid.Logger(log, c)
 
@@ -301,6 +496,17 @@ func DeleteBucket(c *gin.Context) {
 		"provider":     cloudType,
 	})
 
+	forget, err := strconv.ParseBool(c.DefaultQuery("forget", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "forget must be a boolean",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
 	logger.Infof("deleting object store bucket")
 
 	objectStoreCtx := &providers.ObjectStoreContext{
@@ -347,14 +553,28 @@ func DeleteBucket(c *gin.Context) {
 		return
 	}
 
-	if err = objectStore.DeleteBucket(bucketName); err != nil {
+	// forgetting the bucket only removes it from the managed ones, it's kept in the cloud
+	if forget {
+		err = objectStore.ForgetBucket(bucketName)
+	} else {
+		err = objectStore.DeleteBucket(bucketName)
+	}
+	if err != nil {
 		errorHandler.Handle(err)
 		ginutils.ReplyWithErrorResponse(c, errorResponseFrom(err))
 
 		return
 	}
 
-	logger.Infof("object store bucket deleted")
+	if err := auth.RevokeResourcePermissionsOf(organization.ID, auth.BucketResource, bucketName); err != nil {
+		errorHandler.Handle(err)
+	}
+
+	if forget {
+		logger.Infof("object store bucket forgotten")
+	} else {
+		logger.Infof("object store bucket deleted")
+	}
 }
 
 func getBucketContext(c *gin.Context, logger logrus.FieldLogger) (*auth.Organization, *secret.SecretItemResponse, string, bool) {
@@ -425,25 +645,51 @@ func getValidatedSecret(organizationId uint, secretId, cloudType string) (*secre
 	return retrievedSecret, nil
 }
 
-func determineCloudProviderFromRequest(req CreateBucketRequest) (string, error) {
-	if req.Properties.Alibaba != nil {
+func determineCloudProviderFromProperties(properties BucketProperties) (string, error) {
+	if properties.Alibaba != nil {
 		return pkgCluster.Alibaba, nil
 	}
-	if req.Properties.Azure != nil {
+	if properties.Azure != nil {
 		return pkgCluster.Azure, nil
 	}
-	if req.Properties.Amazon != nil {
+	if properties.Amazon != nil {
 		return pkgCluster.Amazon, nil
 	}
-	if req.Properties.Google != nil {
+	if properties.Google != nil {
 		return pkgCluster.Google, nil
 	}
-	if req.Properties.Oracle != nil {
+	if properties.Oracle != nil {
 		return pkgCluster.Oracle, nil
 	}
+	if properties.S3Compatible != nil {
+		return pkgProviders.S3Compatible, nil
+	}
 	return "", pkgErrors.ErrorNotSupportedCloudType
 }
 
+// setObjectStoreContextProperties sets the location (and the Azure specific parameters) of the bucket
+// from the properties of the cloud provider of the object store context
+func setObjectStoreContextProperties(objectStoreCtx *providers.ObjectStoreContext, properties BucketProperties) {
+	switch objectStoreCtx.Provider {
+	case pkgProviders.Alibaba:
+		objectStoreCtx.Location = properties.Alibaba.Location
+
+	case pkgProviders.Amazon:
+		objectStoreCtx.Location = properties.Amazon.Location
+
+	case pkgProviders.Google:
+		objectStoreCtx.Location = properties.Google.Location
+
+	case pkgProviders.Azure:
+		objectStoreCtx.Location = properties.Azure.Location
+		objectStoreCtx.ResourceGroup = properties.Azure.ResourceGroup
+		objectStoreCtx.StorageAccount = properties.Azure.StorageAccount
+
+	case pkgProviders.Oracle:
+		objectStoreCtx.Location = properties.Oracle.Location
+	}
+}
+
 // errorResponseFrom translates the given error into a components.ErrorResponse
 func errorResponseFrom(err error) *pkgCommon.ErrorResponse {
 	if objectstore.IsNotFoundError(err) {
@@ -454,6 +700,14 @@ func errorResponseFrom(err error) *pkgCommon.ErrorResponse {
 		}
 	}
 
+	if _, ok := errors.Cause(err).(objectstore.UnsupportedSettingError); ok {
+		return &pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Error:   err.Error(),
+			Message: errors.Cause(err).Error(),
+		}
+	}
+
 	// google specific errors
 	if googleApiErr, ok := err.(*googleapi.Error); ok {
 		return &pkgCommon.ErrorResponse{
@@ -14,18 +14,38 @@
 
 package api
 
+import (
+	"time"
+
+	"github.com/banzaicloud/pipeline/internal/objectstore"
+)
+
 // CreateBucketRequest to create bucket
 type CreateBucketRequest struct {
-	SecretId   string `json:"secretId"`
-	SecretName string `json:"secretName"`
-	Name       string `json:"name" binding:"required"`
-	Properties struct {
-		Alibaba *CreateAlibabaObjectStoreBucketProperties `json:"alibaba,omitempty"`
-		Amazon  *CreateAmazonObjectStoreBucketProperties  `json:"amazon,omitempty"`
-		Azure   *CreateAzureObjectStoreBucketProperties   `json:"azure,omitempty"`
-		Google  *CreateGoogleObjectStoreBucketProperties  `json:"google,omitempty"`
-		Oracle  *CreateObjectStoreBucketProperties        `json:"oracle,omitempty"`
-	} `json:"properties" binding:"required"`
+	SecretId   string                     `json:"secretId"`
+	SecretName string                     `json:"secretName"`
+	Name       string                     `json:"name" binding:"required"`
+	Properties BucketProperties           `json:"properties" binding:"required"`
+	Settings   objectstore.BucketSettings `json:"settings"`
+}
+
+// ImportBucketRequest to register an existing bucket as managed by Pipeline
+type ImportBucketRequest struct {
+	SecretId   string           `json:"secretId"`
+	SecretName string           `json:"secretName"`
+	Name       string           `json:"name" binding:"required"`
+	Properties BucketProperties `json:"properties" binding:"required"`
+}
+
+// BucketProperties describes the cloud provider specific properties of a bucket, only one of them is set
+type BucketProperties struct {
+	Alibaba *CreateAlibabaObjectStoreBucketProperties `json:"alibaba,omitempty"`
+	Amazon  *CreateAmazonObjectStoreBucketProperties  `json:"amazon,omitempty"`
+	Azure   *CreateAzureObjectStoreBucketProperties   `json:"azure,omitempty"`
+	Google  *CreateGoogleObjectStoreBucketProperties  `json:"google,omitempty"`
+	Oracle  *CreateObjectStoreBucketProperties        `json:"oracle,omitempty"`
+
+	S3Compatible *CreateS3CompatibleObjectStoreBucketProperties `json:"s3compatible,omitempty"`
 }
 
 // CreateAlibabaObjectStoreBucketProperties describes the properties of
@@ -57,7 +77,18 @@ type CreateObjectStoreBucketProperties struct {
 	Location string `json:"location" binding:"required"`
 }
 
+// CreateS3CompatibleObjectStoreBucketProperties describes S3 compatible Object Store Bucket creation request,
+// buckets are created on the endpoint of the secret
+type CreateS3CompatibleObjectStoreBucketProperties struct {
+}
+
 // CreateBucketResponse describes a storage bucket creation response
 type CreateBucketResponse struct {
 	BucketName string `json:"bucketName"`
 }
+
+// BucketObjectSignedURLResponse describes a pre-signed URL of a bucket object
+type BucketObjectSignedURLResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
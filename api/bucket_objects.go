@@ -0,0 +1,301 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/pipeline/internal/objectstore"
+	"github.com/banzaicloud/pipeline/internal/platform/gin/correlationid"
+	"github.com/banzaicloud/pipeline/internal/platform/gin/utils"
+	"github.com/banzaicloud/pipeline/internal/providers"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	commonObjectstore "github.com/banzaicloud/pipeline/pkg/objectstore"
+	pkgProviders "github.com/banzaicloud/pipeline/pkg/providers"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultBucketObjectsLimit = 1000
+	maxBucketObjectsLimit     = 1000
+
+	defaultSignedURLTTL = 15 * time.Minute
+	maxSignedURLTTL     = 7 * 24 * time.Hour
+)
+
+// ListBucketObjects lists the objects of a bucket, keys containing the delimiter after the prefix are rolled up into common prefixes
+func ListBucketObjects(c *gin.Context) {
+	logger := correlationid.Logger(log, c)
+
+	bucketName := c.Param("name")
+	logger = logger.WithField("bucket", bucketName)
+
+	query := objectstore.ObjectQuery{
+		Prefix:    c.Query("prefix"),
+		Delimiter: c.Query("delimiter"),
+		Marker:    c.Query("marker"),
+		Limit:     defaultBucketObjectsLimit,
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxBucketObjectsLimit {
+			c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "invalid query parameter",
+				Error:   fmt.Sprintf("limit must be a number between 1 and %d: %s", maxBucketObjectsLimit, value),
+			})
+			return
+		}
+
+		query.Limit = limit
+	}
+
+	objectClient, ok := getBucketObjectClient(c, logger)
+	if !ok {
+		return
+	}
+
+	objects, err := objectstore.ListObjects(objectClient, bucketName, query)
+	if err != nil {
+		errorHandler.Handle(err)
+		ginutils.ReplyWithErrorResponse(c, errorResponseFrom(err))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, objects)
+}
+
+// GetBucketObject streams the content of an object of a bucket
+func GetBucketObject(c *gin.Context) {
+	logger := correlationid.Logger(log, c)
+
+	bucketName := c.Param("name")
+	key, ok := getBucketObjectKey(c)
+	if !ok {
+		return
+	}
+
+	logger = logger.WithFields(logrus.Fields{"bucket": bucketName, "key": key})
+
+	objectClient, ok := getBucketObjectClient(c, logger)
+	if !ok {
+		return
+	}
+
+	object, err := objectClient.GetObject(bucketName, key)
+	if err != nil {
+		errorHandler.Handle(err)
+		ginutils.ReplyWithErrorResponse(c, errorResponseFrom(err))
+
+		return
+	}
+	defer object.Close()
+
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(key)))
+	c.Status(http.StatusOK)
+
+	// The status code is already sent, the download is truncated
+	if _, err := io.Copy(c.Writer, object); err != nil {
+		errorHandler.Handle(errors.Wrap(err, "failed to stream object"))
+	}
+}
+
+// PutBucketObject creates or replaces an object of a bucket with the streamed request body
+func PutBucketObject(c *gin.Context) {
+	logger := correlationid.Logger(log, c)
+
+	bucketName := c.Param("name")
+	key, ok := getBucketObjectKey(c)
+	if !ok {
+		return
+	}
+
+	logger = logger.WithFields(logrus.Fields{"bucket": bucketName, "key": key})
+
+	objectClient, ok := getBucketObjectClient(c, logger)
+	if !ok {
+		return
+	}
+
+	logger.Info("uploading object")
+
+	if err := objectClient.PutObject(bucketName, key, c.Request.Body); err != nil {
+		errorHandler.Handle(err)
+		ginutils.ReplyWithErrorResponse(c, errorResponseFrom(err))
+
+		return
+	}
+
+	c.Status(http.StatusCreated)
+}
+
+// DeleteBucketObject deletes an object of a bucket
+func DeleteBucketObject(c *gin.Context) {
+	logger := correlationid.Logger(log, c)
+
+	bucketName := c.Param("name")
+	key, ok := getBucketObjectKey(c)
+	if !ok {
+		return
+	}
+
+	logger = logger.WithFields(logrus.Fields{"bucket": bucketName, "key": key})
+
+	objectClient, ok := getBucketObjectClient(c, logger)
+	if !ok {
+		return
+	}
+
+	logger.Info("deleting object")
+
+	if err := objectClient.DeleteObject(bucketName, key); err != nil {
+		errorHandler.Handle(err)
+		ginutils.ReplyWithErrorResponse(c, errorResponseFrom(err))
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetBucketObjectSignedURL generates a pre-signed URL downloading an object of a bucket without credentials
+func GetBucketObjectSignedURL(c *gin.Context) {
+	logger := correlationid.Logger(log, c)
+
+	bucketName := c.Param("name")
+	key, ok := ginutils.RequiredQueryOrAbort(c, "key")
+	if !ok {
+		return
+	}
+
+	logger = logger.WithFields(logrus.Fields{"bucket": bucketName, "key": key})
+
+	ttl := defaultSignedURLTTL
+	if value := c.Query("ttl"); value != "" {
+		var err error
+		ttl, err = time.ParseDuration(value)
+		if err != nil || ttl <= 0 || ttl > maxSignedURLTTL {
+			c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "invalid query parameter",
+				Error:   fmt.Sprintf("ttl must be a duration up to %s: %s", maxSignedURLTTL, value),
+			})
+			return
+		}
+	}
+
+	objectClient, ok := getBucketObjectClient(c, logger)
+	if !ok {
+		return
+	}
+
+	url, err := objectClient.GetSignedURL(bucketName, key, ttl)
+	if err != nil {
+		errorHandler.Handle(err)
+		ginutils.ReplyWithErrorResponse(c, errorResponseFrom(err))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, BucketObjectSignedURLResponse{
+		URL:       url,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+}
+
+// getBucketObjectKey returns the object key from the catch-all path parameter
+func getBucketObjectKey(c *gin.Context) (string, bool) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if key == "" {
+		ginutils.ReplyWithErrorResponse(c, &pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "object key is missing",
+			Error:   http.StatusText(http.StatusBadRequest),
+		})
+
+		return "", false
+	}
+
+	return key, true
+}
+
+// getBucketObjectClient returns a client for the objects of the bucket of the request, replaced in tests
+var getBucketObjectClient = bucketObjectClient
+
+// bucketObjectClient returns a client for the objects of the bucket, the location of the bucket
+// (or the resource group and storage account of the container in case of Azure) is passed in query parameters
+func bucketObjectClient(c *gin.Context, logger logrus.FieldLogger) (commonObjectstore.ObjectStore, bool) {
+	organization, secret, cloudType, ok := getBucketContext(c, logger)
+	if !ok {
+		return nil, false
+	}
+
+	objectStoreCtx := &providers.ObjectStoreContext{
+		Provider:     cloudType,
+		Secret:       secret,
+		Organization: organization,
+	}
+
+	switch cloudType {
+	case pkgProviders.Alibaba, pkgProviders.Amazon, pkgProviders.Oracle:
+		location, ok := ginutils.RequiredQueryOrAbort(c, "location")
+		if !ok {
+			logger.Debug("missing location")
+
+			return nil, false
+		}
+
+		objectStoreCtx.Location = location
+
+	case pkgProviders.Azure:
+		resourceGroup, ok := ginutils.RequiredQueryOrAbort(c, "resourceGroup")
+		if !ok {
+			logger.Debug("missing resource group")
+
+			return nil, false
+		}
+
+		storageAccount, ok := ginutils.RequiredQueryOrAbort(c, "storageAccount")
+		if !ok {
+			logger.Debug("missing storage account")
+
+			return nil, false
+		}
+
+		objectStoreCtx.ResourceGroup = resourceGroup
+		objectStoreCtx.StorageAccount = storageAccount
+	}
+
+	objectClient, err := providers.NewObjectClient(objectStoreCtx)
+	if err != nil {
+		errorHandler.Handle(err)
+		ginutils.ReplyWithErrorResponse(c, errorResponseFrom(err))
+
+		return nil, false
+	}
+
+	return objectClient, true
+}
@@ -0,0 +1,136 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/internal/audit"
+	commonObjectstore "github.com/banzaicloud/pipeline/pkg/objectstore"
+	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
+	qorAuth "github.com/qor/auth"
+	"github.com/sirupsen/logrus"
+)
+
+// inmemoryObjectStore keeps the uploaded objects in memory
+type inmemoryObjectStore struct {
+	commonObjectstore.ObjectStore
+
+	objects map[string][]byte
+}
+
+func (s *inmemoryObjectStore) PutObject(bucketName string, key string, body io.Reader) error {
+	content, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	s.objects[bucketName+"/"+key] = content
+
+	return nil
+}
+
+// recordingAuditSink keeps the written audit events
+type recordingAuditSink struct {
+	events []audit.AuditEvent
+}
+
+func (s *recordingAuditSink) Write(event audit.AuditEvent) error {
+	s.events = append(s.events, event)
+
+	return nil
+}
+
+func (s *recordingAuditSink) Close() error {
+	return nil
+}
+
+func TestPutBucketObject(t *testing.T) {
+	store := &inmemoryObjectStore{objects: map[string][]byte{}}
+
+	defer func(f func(*gin.Context, logrus.FieldLogger) (commonObjectstore.ObjectStore, bool)) {
+		getBucketObjectClient = f
+	}(getBucketObjectClient)
+	getBucketObjectClient = func(*gin.Context, logrus.FieldLogger) (commonObjectstore.ObjectStore, bool) {
+		return store, true
+	}
+
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+
+	publisher, err := audit.NewPublisher(nil, nil, logger, emperror.NewNopHandler())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink := &recordingAuditSink{}
+	publisher.AddSink(audit.SinkConfig{Name: "test"}, sink)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		ctx := context.WithValue(c.Request.Context(), qorAuth.CurrentUser, &auth.User{ID: 1})
+		ctx = context.WithValue(ctx, auth.CurrentOrganization, &auth.Organization{ID: 1})
+		c.Request = c.Request.WithContext(ctx)
+	})
+	router.Use(audit.LogWriter(nil, nil, publisher, logger))
+	router.PUT("/api/v1/orgs/:orgid/buckets/:name/objects/*key", PutBucketObject)
+
+	content := []byte("\x00\x01 not JSON \xff")
+
+	// the length of the body is not known in advance, like in case of chunked uploads
+	request := httptest.NewRequest(http.MethodPut, "/api/v1/orgs/1/buckets/bucket/objects/dir/object.bin", ioutil.NopCloser(bytes.NewReader(content)))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+	}
+
+	if stored := store.objects["bucket/dir/object.bin"]; !bytes.Equal(stored, content) {
+		t.Errorf("expected the object to be stored with content %q, got %q", content, stored)
+	}
+
+	if err := publisher.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sink.events) != 1 {
+		t.Fatalf("expected one audit event, got %d", len(sink.events))
+	}
+
+	event := sink.events[0]
+
+	if event.StatusCode != http.StatusCreated {
+		t.Errorf("expected the audit event with status %d, got %d", http.StatusCreated, event.StatusCode)
+	}
+
+	if event.OrganizationID != 1 || event.UserID != 1 {
+		t.Errorf("expected the audit event of user 1 in organization 1, got user %d in organization %d", event.UserID, event.OrganizationID)
+	}
+
+	if event.Body != nil {
+		t.Errorf("expected the object not to be recorded, got %q", *event.Body)
+	}
+}
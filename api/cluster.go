@@ -18,6 +18,8 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -26,6 +28,7 @@ import (
 	"github.com/banzaicloud/pipeline/cluster"
 	"github.com/banzaicloud/pipeline/config"
 	"github.com/banzaicloud/pipeline/helm"
+	intAudit "github.com/banzaicloud/pipeline/internal/audit"
 	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
 	"github.com/banzaicloud/pipeline/internal/platform/gin/utils"
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
@@ -67,8 +70,9 @@ func getClusterFromRequest(c *gin.Context) (cluster.CommonCluster, bool) {
 
 	// TODO: move these to a struct and create them only once upon application init
 	clusters := intCluster.NewClusters(config.DB())
+	workflows := intCluster.NewWorkflows(config.DB())
 	secretValidator := providers.NewSecretValidator(secret.Store)
-	clusterManager := cluster.NewManager(clusters, secretValidator, log, errorHandler)
+	clusterManager := cluster.NewManager(clusters, secretValidator, workflows, log, errorHandler)
 
 	ctx := ginutils.Context(context.Background(), c)
 
@@ -119,24 +123,19 @@ func getClusterFromRequest(c *gin.Context) (cluster.CommonCluster, bool) {
 		return nil, false
 	}
 
+	intAudit.SetClusterID(c, cl.GetID())
+
 	return cl, true
 }
 
-func getPostHookFunctions(postHooks pkgCluster.PostHooks) (ph []cluster.PostFunctioner) {
+func getPostHookFunctions(organizationID uint, postHooks pkgCluster.PostHooks) (ph []cluster.PostFunctioner) {
 
 	log.Info("Get posthook function(s)")
 
 	for postHookName, param := range postHooks {
 
-		function := cluster.HookMap[postHookName]
-		if function != nil {
-
-			if f, isOk := function.(*cluster.PostFunctionWithParam); isOk {
-				fa := *f
-				fa.SetParams(param)
-				function = &fa
-			}
-
+		function, ok := cluster.GetOrganizationPostHookWithParams(organizationID, postHookName, param)
+		if ok {
 			log.Infof("posthook function: %s", function)
 			log.Infof("posthook params: %#v", param)
 			ph = append(ph, function)
@@ -168,6 +167,20 @@ func GetClusterStatus(c *gin.Context) {
 		})
 		return
 	}
+
+	// TODO: move these to a struct and create them only once upon application init
+	secretValidator := providers.NewSecretValidator(secret.Store)
+	clusterManager := cluster.NewManager(intCluster.NewClusters(config.DB()), secretValidator, intCluster.NewWorkflows(config.DB()), log, errorHandler)
+
+	ctx := ginutils.Context(context.Background(), c)
+
+	workflow, err := clusterManager.GetClusterWorkflow(ctx, commonCluster.GetOrganizationId(), commonCluster.GetID())
+	if err != nil && !isNotFound(err) {
+		log.Warnf("Error during getting cluster workflow: %s", err.Error())
+	} else if err == nil {
+		response.Workflow = workflow
+	}
+
 	c.JSON(http.StatusOK, response)
 	return
 }
@@ -242,7 +255,7 @@ func GetClusters(c *gin.Context) {
 
 	// TODO: move these to a struct and create them only once upon application init
 	secretValidator := providers.NewSecretValidator(secret.Store)
-	clusterManager := cluster.NewManager(intCluster.NewClusters(config.DB()), secretValidator, log, errorHandler)
+	clusterManager := cluster.NewManager(intCluster.NewClusters(config.DB()), secretValidator, intCluster.NewWorkflows(config.DB()), log, errorHandler)
 
 	logger.Info("fetching clusters")
 
@@ -259,9 +272,26 @@ func GetClusters(c *gin.Context) {
 		return
 	}
 
+	accessibleIDs, all, err := auth.GetAccessibleResourceIDs(c.Request, organizationID, auth.ClusterResource)
+	if err != nil {
+		logger.Errorf("error getting accessible clusters: %s", err.Error())
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error listing clusters",
+			Error:   err.Error(),
+		})
+
+		return
+	}
+
 	response := make([]pkgCluster.GetClusterStatusResponse, 0)
 
 	for _, c := range clusters {
+		if !all && !accessibleIDs[fmt.Sprint(c.GetID())] {
+			continue
+		}
+
 		logger := logger.WithField("cluster", c.GetName())
 
 		status, err := c.GetStatus()
@@ -296,11 +326,29 @@ func ReRunPostHooks(c *gin.Context) {
 		return
 	}
 
+	// Posthooks install organization secrets into the cluster, eg. the secrets of custom posthooks or the logging bucket secret
+	if _, all, err := auth.GetFullAccessResourceIDs(c.Request, commonCluster.GetOrganizationId(), auth.SecretResource); err != nil {
+		log.Errorf("error getting accessible secrets: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error getting accessible secrets",
+			Error:   err.Error(),
+		})
+		return
+	} else if !all {
+		c.JSON(http.StatusForbidden, pkgCommon.ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "running posthooks requires full access to the secrets of the organization",
+			Error:   "access to the secrets is forbidden",
+		})
+		return
+	}
+
 	var posthooks []cluster.PostFunctioner
 	if len(ph) == 0 {
 		posthooks = cluster.BasePostHookFunctions
 	} else {
-		posthooks = getPostHookFunctions(ph)
+		posthooks = getPostHookFunctions(commonCluster.GetOrganizationId(), ph)
 	}
 
 	log.Infof("Cluster id: %d", commonCluster.GetID())
@@ -867,6 +915,27 @@ func InstallSecretsToCluster(c *gin.Context) {
 		return
 	}
 
+	// Only the secrets the user could read the values of can be installed into the cluster
+	accessibleIDs, all, err := auth.GetFullAccessResourceIDs(c.Request, commonCluster.GetOrganizationId(), auth.SecretResource)
+	if err != nil {
+		log.Errorf("Error getting accessible secrets: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error installing secrets into cluster",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if !all && !restrictSecretsQuery(accessibleIDs, &request.Query) {
+		c.AbortWithStatusJSON(http.StatusForbidden, pkgCommon.ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "Error installing secrets into cluster",
+			Error:   "access to the secrets is forbidden",
+		})
+		return
+	}
+
 	secretSources, err := cluster.InstallSecrets(commonCluster, &request.Query, request.Namespace)
 
 	if err != nil {
@@ -879,6 +948,18 @@ func InstallSecretsToCluster(c *gin.Context) {
 		return
 	}
 
+	if request.ImagePullSecrets {
+		if err := cluster.AddImagePullSecrets(commonCluster, &request.Query, request.Namespace); err != nil {
+			log.Errorf("Error adding image pull secrets to namespace [%s] of cluster [%d]: %s", request.Namespace, commonCluster.GetID(), err.Error())
+			c.AbortWithStatusJSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "Error adding image pull secrets to service account",
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, secretSources)
 }
 
@@ -946,19 +1027,45 @@ func ListClusterSecrets(c *gin.Context) {
 
 	log.Info("Start filtering secrets")
 
-	clusterUidTag := fmt.Sprintf("clusterUID:%s", commonCluster.GetUID())
-	releaseTag := fmt.Sprintf("release:%s", releaseName)
-
-	tags := []string{clusterUidTag}
-	if len(releaseName) != 0 {
-		tags = append(tags, releaseTag)
+	installations, err := cluster.GetClusterSecretInstallations(commonCluster)
+	if err != nil {
+		log.Errorf("Error during listing secret installations: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error during listing secret installations",
+			Error:   err.Error(),
+		})
+		return
 	}
 
-	log.Infof("tags: %v", tags)
+	query := &pkgSecret.ListSecretsQuery{}
 
-	secrets, err := secret.RestrictedStore.List(organizationID, &pkgSecret.ListSecretsQuery{
-		Tags: tags,
-	})
+	// list the organization secrets installed into the cluster instead of the ones belonging to it
+	if installed, _ := strconv.ParseBool(c.Query("installed")); installed {
+		if len(installations) == 0 {
+			c.JSON(http.StatusOK, []*secret.SecretItemResponse{})
+			return
+		}
+
+		for secretID := range installations {
+			query.IDs = append(query.IDs, secretID)
+		}
+		sort.Strings(query.IDs)
+	} else {
+		clusterUidTag := fmt.Sprintf("clusterUID:%s", commonCluster.GetUID())
+		releaseTag := fmt.Sprintf("release:%s", releaseName)
+
+		tags := []string{clusterUidTag}
+		if len(releaseName) != 0 {
+			tags = append(tags, releaseTag)
+		}
+
+		log.Infof("tags: %v", tags)
+
+		query.Tags = tags
+	}
+
+	secrets, err := secret.RestrictedStore.List(organizationID, query)
 	if err != nil {
 		log.Errorf("Error during listing secrets: %s", err.Error())
 		c.AbortWithStatusJSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
@@ -969,6 +1076,10 @@ func ListClusterSecrets(c *gin.Context) {
 		return
 	}
 
+	for _, s := range secrets {
+		s.Installations = installations[s.ID]
+	}
+
 	log.Info("Listing secrets succeeded")
 
 	c.JSON(http.StatusOK, secrets)
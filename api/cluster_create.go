@@ -21,6 +21,7 @@ import (
 	"github.com/banzaicloud/pipeline/auth"
 	"github.com/banzaicloud/pipeline/cluster"
 	"github.com/banzaicloud/pipeline/config"
+	intAudit "github.com/banzaicloud/pipeline/internal/audit"
 	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
 	"github.com/banzaicloud/pipeline/internal/platform/gin/utils"
 	"github.com/banzaicloud/pipeline/model/defaults"
@@ -67,7 +68,7 @@ func CreateClusterRequest(c *gin.Context) {
 	orgID := auth.GetCurrentOrganization(c.Request).ID
 	userID := auth.GetCurrentUser(c.Request).ID
 
-	ph := getPostHookFunctions(createClusterRequest.PostHooks)
+	ph := getPostHookFunctions(orgID, createClusterRequest.PostHooks)
 	ctx := ginutils.Context(context.Background(), c)
 	commonCluster, err := CreateCluster(ctx, &createClusterRequest, orgID, userID, ph)
 	if err != nil {
@@ -75,6 +76,8 @@ func CreateClusterRequest(c *gin.Context) {
 		return
 	}
 
+	intAudit.SetClusterID(c, commonCluster.GetID())
+
 	c.JSON(http.StatusAccepted, pkgCluster.CreateClusterResponse{
 		Name:       commonCluster.GetName(),
 		ResourceID: commonCluster.GetID(),
@@ -146,8 +149,9 @@ func CreateCluster(
 
 	// TODO: move these to a struct and create them only once upon application init
 	clusters := intCluster.NewClusters(config.DB())
+	workflows := intCluster.NewWorkflows(config.DB())
 	secretValidator := providers.NewSecretValidator(secret.Store)
-	clusterManager := cluster.NewManager(clusters, secretValidator, log, errorHandler)
+	clusterManager := cluster.NewManager(clusters, secretValidator, workflows, log, errorHandler)
 
 	creationCtx := cluster.CreationContext{
 		OrganizationID: organizationID,
@@ -156,6 +160,7 @@ func CreateCluster(
 		SecretID:       createClusterRequest.SecretId,
 		Provider:       createClusterRequest.Cloud,
 		PostHooks:      postHooks,
+		Request:        createClusterRequest,
 	}
 
 	creator := cluster.NewCommonClusterCreator(createClusterRequest, commonCluster)
@@ -18,6 +18,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/banzaicloud/pipeline/auth"
 	"github.com/banzaicloud/pipeline/cluster"
 	"github.com/banzaicloud/pipeline/config"
 	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
@@ -49,12 +50,15 @@ func DeleteCluster(c *gin.Context) {
 
 	// TODO: move these to a struct and create them only once upon application init
 	clusters := intCluster.NewClusters(config.DB())
+	workflows := intCluster.NewWorkflows(config.DB())
 	secretValidator := providers.NewSecretValidator(secret.Store)
-	clusterManager := cluster.NewManager(clusters, secretValidator, log, errorHandler)
+	clusterManager := cluster.NewManager(clusters, secretValidator, workflows, log, errorHandler)
 
 	ctx := ginutils.Context(c.Request.Context(), c)
 
-	clusterManager.DeleteCluster(ctx, commonCluster, force, &kubeProxyCache)
+	userID := auth.GetCurrentUser(c.Request).ID
+
+	clusterManager.DeleteCluster(ctx, commonCluster, force, userID, &kubeProxyCache)
 
 	c.JSON(http.StatusAccepted, DeleteClusterResponse{
 		Status:     http.StatusAccepted,
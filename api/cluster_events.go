@@ -0,0 +1,131 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/cluster"
+	"github.com/banzaicloud/pipeline/config"
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
+	"github.com/banzaicloud/pipeline/internal/platform/gin/utils"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/banzaicloud/pipeline/pkg/providers"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultEventsLimit = 50
+	maxEventsLimit     = 500
+)
+
+// ListClusterEvents lists the lifecycle events of a cluster.
+// Events are queried by the cluster ID, so the history of deleted clusters remains available.
+func ListClusterEvents(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	clusterID, ok := ginutils.UintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	query, page, err := parseEventQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid query parameter",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// TODO: move these to a struct and create them only once upon application init
+	clusters := intCluster.NewClusters(config.DB())
+	workflows := intCluster.NewWorkflows(config.DB())
+	secretValidator := providers.NewSecretValidator(secret.Store)
+	clusterManager := cluster.NewManager(clusters, secretValidator, workflows, log, errorHandler)
+
+	ctx := ginutils.Context(context.Background(), c)
+
+	events, err := clusterManager.GetClusterEvents(ctx, organizationID, clusterID, query)
+	if err != nil {
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error listing cluster events",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, pkgCluster.ClusterEventsResponse{
+		Events: events,
+		Page:   page,
+		Limit:  query.Limit,
+	})
+}
+
+// parseEventQuery parses the paging (page, limit) and time filter (from, to) query parameters
+func parseEventQuery(c *gin.Context) (intCluster.EventQuery, int, error) {
+	query := intCluster.EventQuery{
+		Limit: defaultEventsLimit,
+	}
+
+	page := 1
+
+	if value := c.Query("page"); value != "" {
+		p, err := strconv.Atoi(value)
+		if err != nil || p < 1 {
+			return query, 0, errors.Errorf("page must be a positive number: %s", value)
+		}
+
+		page = p
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxEventsLimit {
+			return query, 0, errors.Errorf("limit must be a number between 1 and %d: %s", maxEventsLimit, value)
+		}
+
+		query.Limit = limit
+	}
+
+	query.Offset = (page - 1) * query.Limit
+
+	for param, target := range map[string]**time.Time{"from": &query.From, "to": &query.To} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, 0, errors.Wrapf(err, "%s must be an RFC3339 timestamp", param)
+		}
+
+		*target = &t
+	}
+
+	return query, page, nil
+}
@@ -0,0 +1,73 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseEventQuery(t *testing.T) {
+	from := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name        string
+		url         string
+		expectedErr bool
+		page        int
+		limit       int
+		offset      int
+		from        *time.Time
+	}{
+		{name: "defaults", url: "/events", page: 1, limit: defaultEventsLimit, offset: 0},
+		{name: "paging", url: "/events?page=3&limit=20", page: 3, limit: 20, offset: 40},
+		{name: "time filter", url: "/events?from=2018-10-01T12:00:00Z", page: 1, limit: defaultEventsLimit, from: &from},
+		{name: "invalid page", url: "/events?page=0", expectedErr: true},
+		{name: "too large limit", url: "/events?limit=100000", expectedErr: true},
+		{name: "invalid time", url: "/events?to=yesterday", expectedErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &gin.Context{Request: httptest.NewRequest("GET", tc.url, nil)}
+
+			query, page, err := parseEventQuery(c)
+
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			if page != tc.page || query.Limit != tc.limit || query.Offset != tc.offset {
+				t.Errorf("expected page=%d limit=%d offset=%d, got page=%d limit=%d offset=%d",
+					tc.page, tc.limit, tc.offset, page, query.Limit, query.Offset)
+			}
+
+			if tc.from != nil && (query.From == nil || !query.From.Equal(*tc.from)) {
+				t.Errorf("expected from=%s, got %v", tc.from, query.From)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/cluster"
+	"github.com/banzaicloud/pipeline/config"
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
+	"github.com/banzaicloud/pipeline/internal/platform/gin/utils"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/banzaicloud/pipeline/pkg/providers"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// ListPostHooks lists the status of the posthook functions of a cluster
+func ListPostHooks(c *gin.Context) {
+	commonCluster, ok := getClusterFromRequest(c)
+	if !ok {
+		return
+	}
+
+	// TODO: move these to a struct and create them only once upon application init
+	clusters := intCluster.NewClusters(config.DB())
+	workflows := intCluster.NewWorkflows(config.DB())
+	secretValidator := providers.NewSecretValidator(secret.Store)
+	clusterManager := cluster.NewManager(clusters, secretValidator, workflows, log, errorHandler)
+
+	ctx := ginutils.Context(context.Background(), c)
+
+	postHooks, err := clusterManager.GetPostHookStatuses(ctx, commonCluster.GetOrganizationId(), commonCluster.GetID())
+	if err != nil {
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error listing posthooks",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, postHooks)
+}
+
+// RetryPostHooks reruns the failed and not yet run posthook functions of a cluster
+func RetryPostHooks(c *gin.Context) {
+	commonCluster, ok := getClusterFromRequest(c)
+	if !ok {
+		return
+	}
+
+	// TODO: move these to a struct and create them only once upon application init
+	clusters := intCluster.NewClusters(config.DB())
+	workflows := intCluster.NewWorkflows(config.DB())
+	secretValidator := providers.NewSecretValidator(secret.Store)
+	clusterManager := cluster.NewManager(clusters, secretValidator, workflows, log, errorHandler)
+
+	// Posthooks install organization secrets into the cluster, eg. the secrets of custom posthooks or the logging bucket secret
+	if _, all, err := auth.GetFullAccessResourceIDs(c.Request, commonCluster.GetOrganizationId(), auth.SecretResource); err != nil {
+		log.Errorf("error getting accessible secrets: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error getting accessible secrets",
+			Error:   err.Error(),
+		})
+		return
+	} else if !all {
+		c.JSON(http.StatusForbidden, pkgCommon.ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "running posthooks requires full access to the secrets of the organization",
+			Error:   "access to the secrets is forbidden",
+		})
+		return
+	}
+
+	ctx := ginutils.Context(context.Background(), c)
+
+	postHooks, err := clusterManager.RetryPostHooks(ctx, commonCluster)
+	if err != nil {
+		if isPreconditionFailed(err) {
+			c.JSON(http.StatusPreconditionFailed, pkgCommon.ErrorResponse{
+				Code:    http.StatusPreconditionFailed,
+				Message: errors.Cause(err).Error(),
+			})
+			return
+		}
+
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error retrying posthooks",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if postHooks == nil {
+		postHooks = []string{}
+	}
+
+	c.JSON(http.StatusAccepted, pkgCluster.RetryPostHooksResponse{
+		PostHooks: postHooks,
+	})
+}
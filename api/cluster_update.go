@@ -57,8 +57,9 @@ func UpdateCluster(c *gin.Context) {
 
 	// TODO: move these to a struct and create them only once upon application init
 	clusters := intCluster.NewClusters(config.DB())
+	workflows := intCluster.NewWorkflows(config.DB())
 	secretValidator := providers.NewSecretValidator(secret.Store)
-	clusterManager := cluster.NewManager(clusters, secretValidator, log, errorHandler)
+	clusterManager := cluster.NewManager(clusters, secretValidator, workflows, log, errorHandler)
 
 	updateCtx := cluster.UpdateContext{
 		OrganizationID: auth.GetCurrentOrganization(c.Request).ID,
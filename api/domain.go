@@ -0,0 +1,446 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/cluster"
+	"github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/dns"
+	"github.com/banzaicloud/pipeline/dns/model"
+	"github.com/banzaicloud/pipeline/dns/zone"
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	pkgDns "github.com/banzaicloud/pipeline/pkg/dns"
+	"github.com/banzaicloud/pipeline/pkg/providers"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// GetOrgDomain returns the domain external-dns manages the records of the organization under,
+// together with the status and the name servers of its zone if the zone is managed by Pipeline
+func GetOrgDomain(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	customDomain, err := dns.GetCustomDomain(organization.ID)
+	if err != nil {
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error getting domain",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	response := pkgDns.DomainResponse{
+		Domain:  dns.DefaultOrgDomain(organization),
+		Managed: true,
+	}
+
+	if customDomain != nil {
+		response = newDomainResponse(customDomain)
+	}
+
+	if response.Managed {
+		if err := setDomainState(organization.ID, &response); err != nil {
+			errorHandler.Handle(err)
+
+			c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "error getting state of domain",
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// SetOrgDomain attaches a custom domain to the organization. The domain either has an existing zone accessed
+// with a cloud secret of the organization, or Pipeline creates its zone and returns the name servers to delegate the domain to.
+// external-dns is reconfigured in the running clusters of the organization to manage the records under the domain.
+func SetOrgDomain(c *gin.Context) {
+	organization, ok := getDomainOrganization(c)
+	if !ok {
+		return
+	}
+
+	var request pkgDns.DomainRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "error parsing request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	request.Domain = strings.ToLower(strings.TrimSuffix(request.Domain, "."))
+
+	if err := validateCustomDomain(request.Domain); err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid domain",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if request.SecretID != "" {
+		cloudSecret, err := secret.Store.Get(organization.ID, request.SecretID)
+		if err == secret.ErrSecretNotExists {
+			c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "secret not found",
+				Error:   err.Error(),
+			})
+			return
+		} else if err != nil {
+			errorHandler.Handle(err)
+
+			c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "error getting secret",
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		if err := dns.VerifyZone(cloudSecret, request.ResourceGroup, request.Domain); err != nil {
+			c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "error verifying zone of domain",
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	dnsSvc, err := dns.GetExternalDnsServiceClient()
+	if err != nil {
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error getting DNS service",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if dnsSvc == nil && request.SecretID == "" {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "DNS service is not enabled, the zone of the domain can't be created",
+			Error:   "secretId is required",
+		})
+		return
+	}
+
+	customDomain, err := dns.GetCustomDomain(organization.ID)
+	if err != nil {
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error getting domain",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if customDomain == nil {
+		customDomain = &dnsmodel.CustomDomain{
+			OrganizationId: organization.ID,
+			CreatedBy:      auth.GetCurrentUser(c.Request).ID,
+		}
+	}
+
+	customDomain.Domain = request.Domain
+	customDomain.SecretId = request.SecretID
+	customDomain.ResourceGroup = request.ResourceGroup
+
+	var nameServers []string
+
+	// the domain registered for the organization in the DNS service, which is replaced by the new one
+	var previousDomain string
+	if dnsSvc != nil {
+		previousDomain, err = dnsSvc.GetOrgDomain(organization.ID)
+		if err != nil {
+			errorHandler.Handle(err)
+
+			c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "error getting registered domain",
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		if customDomain.IsManaged() && previousDomain == customDomain.Domain {
+			previousDomain = ""
+		}
+	}
+
+	if customDomain.IsManaged() {
+		// the DNS service registers a single domain per organization, the new one takes the place of the previous one
+		// which is registered again if the new one can't be set up
+		if previousDomain != "" {
+			if err := dnsSvc.UnregisterDomain(organization.ID, previousDomain); err != nil {
+				errorHandler.Handle(err)
+
+				c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+					Code:    http.StatusInternalServerError,
+					Message: "error unregistering previous domain",
+					Error:   err.Error(),
+				})
+				return
+			}
+		}
+
+		if err := dnsSvc.RegisterDomain(organization.ID, customDomain.Domain); err != nil {
+			errorHandler.Handle(err)
+			rollbackOrgDomain(dnsSvc, organization.ID, customDomain.Domain, previousDomain)
+
+			c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "error registering domain",
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		nameServers, err = dnsSvc.GetNameServers(organization.ID)
+		if err != nil {
+			errorHandler.Handle(err)
+			rollbackOrgDomain(dnsSvc, organization.ID, customDomain.Domain, previousDomain)
+
+			c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "error getting name servers of domain",
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := dns.SaveCustomDomain(customDomain); err != nil {
+		errorHandler.Handle(err)
+
+		if customDomain.IsManaged() {
+			rollbackOrgDomain(dnsSvc, organization.ID, customDomain.Domain, previousDomain)
+		}
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error saving domain",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// the previous domain is torn down only after the new one is in place
+	if previousDomain != "" {
+		if err := tearDownOrgDomain(dnsSvc, organization.ID, previousDomain, !customDomain.IsManaged()); err != nil {
+			errorHandler.Handle(errors.WithMessage(err, "failed to tear down previous domain"))
+		}
+	}
+
+	go reconfigureExternalDns(organization.ID)
+
+	response := newDomainResponse(customDomain)
+	response.NameServers = nameServers
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteOrgDomain detaches the custom domain from the organization, the organization falls back to its default domain
+func DeleteOrgDomain(c *gin.Context) {
+	organization, ok := getDomainOrganization(c)
+	if !ok {
+		return
+	}
+
+	customDomain, err := dns.GetCustomDomain(organization.ID)
+	if err != nil {
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error getting domain",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if customDomain == nil {
+		c.JSON(http.StatusNotFound, pkgCommon.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "the organization has no custom domain",
+			Error:   http.StatusText(http.StatusNotFound),
+		})
+		return
+	}
+
+	if customDomain.IsManaged() {
+		dnsSvc, err := dns.GetExternalDnsServiceClient()
+		if err == nil && dnsSvc != nil {
+			err = dnsSvc.UnregisterDomain(organization.ID, customDomain.Domain)
+		}
+		if err == nil {
+			err = dns.DeleteManualRecords(organization.ID, customDomain.Domain)
+		}
+		if err != nil {
+			errorHandler.Handle(err)
+
+			c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "error unregistering domain",
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := dns.DeleteCustomDomain(customDomain); err != nil {
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error deleting domain",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	go reconfigureExternalDns(organization.ID)
+
+	c.Status(http.StatusNoContent)
+}
+
+// getDomainOrganization returns the organization of the request, the domain of the organization can be changed by organization admins only
+func getDomainOrganization(c *gin.Context) (*auth.Organization, bool) {
+	organization := auth.GetCurrentOrganization(c.Request)
+	user := auth.GetCurrentUser(c.Request)
+
+	if user == nil || !auth.HasOrgRole(user.ID, organization.ID, auth.OrgRoleAdmin) {
+		c.AbortWithStatusJSON(http.StatusForbidden, pkgCommon.ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "the domain can be changed by organization admins only",
+			Error:   http.StatusText(http.StatusForbidden),
+		})
+		return nil, false
+	}
+
+	return organization, true
+}
+
+// validateCustomDomain checks that the domain is a valid domain name outside of the base domain,
+// the domains under the base domain are reserved for the default domains of organizations
+func validateCustomDomain(domain string) error {
+	if errs := validation.IsDNS1123Subdomain(domain); errs != nil {
+		return errors.New(strings.Join(errs, ", "))
+	}
+
+	if !strings.Contains(domain, ".") {
+		return errors.Errorf("%q is a top level domain", domain)
+	}
+
+	baseDomain := viper.GetString(config.DNSBaseDomain)
+	if domain == baseDomain || zone.IsSubdomain(domain, baseDomain) {
+		return errors.Errorf("domains under %q are reserved", baseDomain)
+	}
+
+	return nil
+}
+
+// rollbackOrgDomain unregisters the new domain of the organization which could not be set up,
+// and registers the previous domain again which was unregistered to make room for it
+func rollbackOrgDomain(dnsSvc dns.DnsServiceClient, orgId uint, domain, previousDomain string) {
+	if previousDomain == "" {
+		return
+	}
+
+	if err := dnsSvc.UnregisterDomain(orgId, domain); err != nil {
+		errorHandler.Handle(errors.WithMessage(err, "failed to unregister domain during rollback"))
+		return
+	}
+
+	if err := dnsSvc.RegisterDomain(orgId, previousDomain); err != nil {
+		errorHandler.Handle(errors.WithMessage(err, "failed to register previous domain during rollback"))
+	}
+}
+
+// tearDownOrgDomain deletes the manual records of the previous domain of the organization,
+// unregistering the domain first unless it was already unregistered to make room for the new one
+func tearDownOrgDomain(dnsSvc dns.DnsServiceClient, orgId uint, domain string, unregister bool) error {
+	if unregister {
+		if err := dnsSvc.UnregisterDomain(orgId, domain); err != nil {
+			return err
+		}
+	}
+
+	return dns.DeleteManualRecords(orgId, domain)
+}
+
+// setDomainState sets the status and the name servers of the zone of the domain registered for the organization in the DNS service
+func setDomainState(orgId uint, response *pkgDns.DomainResponse) error {
+	dnsSvc, err := dns.GetExternalDnsServiceClient()
+	if err != nil || dnsSvc == nil {
+		return err
+	}
+
+	state, err := dnsSvc.GetDomainState(orgId)
+	if err != nil || state == nil || state.Domain != response.Domain {
+		return err
+	}
+
+	response.Status = state.Status
+	response.ErrorMessage = state.ErrorMessage
+
+	response.NameServers, err = dnsSvc.GetNameServers(orgId)
+
+	return err
+}
+
+// reconfigureExternalDns reconfigures external-dns in the clusters of the organization after its domain changed
+func reconfigureExternalDns(orgId uint) {
+	secretValidator := providers.NewSecretValidator(secret.Store)
+	clusterManager := cluster.NewManager(intCluster.NewClusters(config.DB()), secretValidator, intCluster.NewWorkflows(config.DB()), log, errorHandler)
+
+	if err := clusterManager.ReconfigureExternalDns(context.Background(), orgId); err != nil {
+		errorHandler.Handle(errors.Wrap(err, "failed to reconfigure external-dns"))
+	}
+}
+
+func newDomainResponse(customDomain *dnsmodel.CustomDomain) pkgDns.DomainResponse {
+	return pkgDns.DomainResponse{
+		Domain:        customDomain.Domain,
+		Custom:        true,
+		Managed:       customDomain.IsManaged(),
+		SecretID:      customDomain.SecretId,
+		ResourceGroup: customDomain.ResourceGroup,
+	}
+}
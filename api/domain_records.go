@@ -0,0 +1,223 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/dns"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	pkgDns "github.com/banzaicloud/pipeline/pkg/dns"
+	"github.com/gin-gonic/gin"
+)
+
+// ListDomainRecords lists the records of the zone of the organization domain with the clusters (external-dns owners) managing them
+func ListDomainRecords(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	dnsSvc, domain, ok := getRecordsDnsService(c, organization.ID)
+	if !ok {
+		return
+	}
+
+	records, err := dns.ListRecords(dnsSvc, organization.ID)
+	if err != nil {
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error listing records",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if records == nil {
+		records = []pkgDns.Record{}
+	}
+
+	c.JSON(http.StatusOK, pkgDns.RecordsResponse{
+		Domain:  domain,
+		Records: records,
+	})
+}
+
+// CreateDomainRecord creates or replaces a manual record in the zone of the organization domain,
+// manual records are left alone by external-dns and the DNS garbage collector
+func CreateDomainRecord(c *gin.Context) {
+	organization, ok := getDomainOrganization(c)
+	if !ok {
+		return
+	}
+
+	var request pkgDns.RecordRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "error parsing request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	dnsSvc, domain, ok := getRecordsDnsService(c, organization.ID)
+	if !ok {
+		return
+	}
+
+	record := pkgDns.Record{
+		Name:   strings.ToLower(strings.TrimSuffix(request.Name, ".")),
+		Type:   strings.ToUpper(request.Type),
+		TTL:    request.TTL,
+		Values: request.Values,
+		Manual: true,
+	}
+
+	if record.TTL == 0 {
+		record.TTL = pkgDns.DefaultRecordTTL
+	}
+
+	if err := dns.ValidateRecord(domain, record); err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid record",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	err := dns.CreateManualRecord(dnsSvc, organization.ID, auth.GetCurrentUser(c.Request).ID, domain, record)
+	if err == dns.ErrRecordOwnedByExternalDns {
+		c.JSON(http.StatusConflict, pkgCommon.ErrorResponse{
+			Code:    http.StatusConflict,
+			Message: "a record with this name is managed by external-dns",
+			Error:   err.Error(),
+		})
+		return
+	} else if err != nil {
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error creating record",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, record)
+}
+
+// DeleteDomainRecord deletes a manual record from the zone of the organization domain
+func DeleteDomainRecord(c *gin.Context) {
+	organization, ok := getDomainOrganization(c)
+	if !ok {
+		return
+	}
+
+	dnsSvc, _, ok := getRecordsDnsService(c, organization.ID)
+	if !ok {
+		return
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(c.Param("name"), "."))
+	recordType := strings.ToUpper(c.Param("type"))
+
+	err := dns.DeleteManualRecord(dnsSvc, organization.ID, name, recordType)
+	if err == dns.ErrManualRecordNotFound {
+		c.JSON(http.StatusNotFound, pkgCommon.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "only manual records can be deleted",
+			Error:   err.Error(),
+		})
+		return
+	} else if err != nil {
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error deleting record",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// getRecordsDnsService returns the DNS service and the domain registered in it for the organization,
+// records are available for the zones managed by Pipeline only
+func getRecordsDnsService(c *gin.Context, orgId uint) (dns.DnsServiceClient, string, bool) {
+	customDomain, err := dns.GetCustomDomain(orgId)
+	if err != nil {
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error getting domain",
+			Error:   err.Error(),
+		})
+		return nil, "", false
+	}
+
+	if customDomain != nil && !customDomain.IsManaged() {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "the zone of the domain is not managed by Pipeline",
+			Error:   http.StatusText(http.StatusBadRequest),
+		})
+		return nil, "", false
+	}
+
+	dnsSvc, err := dns.GetExternalDnsServiceClient()
+	if err != nil {
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error getting DNS service",
+			Error:   err.Error(),
+		})
+		return nil, "", false
+	}
+
+	var state *pkgDns.DomainState
+	if dnsSvc != nil {
+		state, err = dnsSvc.GetDomainState(orgId)
+		if err != nil {
+			errorHandler.Handle(err)
+
+			c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "error getting state of domain",
+				Error:   err.Error(),
+			})
+			return nil, "", false
+		}
+	}
+
+	if state == nil {
+		c.JSON(http.StatusNotFound, pkgCommon.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "no domain is registered for the organization",
+			Error:   http.StatusText(http.StatusNotFound),
+		})
+		return nil, "", false
+	}
+
+	return dnsSvc, state.Domain, true
+}
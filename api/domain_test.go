@@ -0,0 +1,87 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/banzaicloud/pipeline/dns"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+)
+
+// recordingDnsServiceClient records the domain registrations of an organization
+type recordingDnsServiceClient struct {
+	dns.DnsServiceClient
+
+	calls         []string
+	unregisterErr error
+}
+
+func (c *recordingDnsServiceClient) RegisterDomain(orgId uint, domain string) error {
+	c.calls = append(c.calls, "register "+domain)
+
+	return nil
+}
+
+func (c *recordingDnsServiceClient) UnregisterDomain(orgId uint, domain string) error {
+	c.calls = append(c.calls, "unregister "+domain)
+
+	return c.unregisterErr
+}
+
+func TestRollbackOrgDomain(t *testing.T) {
+	cases := map[string]struct {
+		previousDomain string
+		unregisterErr  error
+		expected       []string
+	}{
+		"previous domain": {
+			previousDomain: "example.org",
+			expected:       []string{"unregister example.com", "register example.org"},
+		},
+		"no previous domain": {
+			previousDomain: "",
+			expected:       nil,
+		},
+		"unregister failed": {
+			previousDomain: "example.org",
+			unregisterErr:  errors.New("unregister failed"),
+			expected:       []string{"unregister example.com"},
+		},
+	}
+
+	defer func(h emperror.Handler) { errorHandler = h }(errorHandler)
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var handled []error
+			errorHandler = emperror.HandlerFunc(func(err error) { handled = append(handled, err) })
+
+			dnsSvc := &recordingDnsServiceClient{unregisterErr: tc.unregisterErr}
+
+			rollbackOrgDomain(dnsSvc, 1, "example.com", tc.previousDomain)
+
+			if !reflect.DeepEqual(dnsSvc.calls, tc.expected) {
+				t.Errorf("expected calls %v, got %v", tc.expected, dnsSvc.calls)
+			}
+
+			if tc.unregisterErr != nil && len(handled) != 1 {
+				t.Errorf("expected the error to be handled, got %v", handled)
+			}
+		})
+	}
+}
@@ -160,7 +160,7 @@ func CreateOrganization(c *gin.Context) {
 	}
 
 	auth.AddOrgRoles(organization.ID)
-	auth.AddOrgRoleForUser(user.ID, organization.ID)
+	auth.AddOrgRoleForUser(user.ID, auth.OrgRoleAdmin, organization.ID)
 
 	helm.InstallLocalHelm(helm.GenerateHelmRepoEnv(organization.Name))
 
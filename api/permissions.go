@@ -0,0 +1,205 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ListPermissions lists the resource permissions of an organization, optionally filtered by resourceType and resourceId.
+func ListPermissions(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	permissions, err := auth.ListResourcePermissions(organization.ID, c.Query("resourceType"), c.Query("resourceId"))
+	if err != nil {
+		log.Errorf("Error listing resource permissions: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, common.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error listing resource permissions",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, permissions)
+}
+
+// GrantPermission grants a role on a cluster, secret or bucket of the organization to a user or a team.
+func GrantPermission(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	var permission auth.ResourcePermission
+	if err := c.ShouldBindJSON(&permission); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if permission.SubjectType == auth.UserSubject {
+		userID, err := strconv.ParseUint(permission.Subject, 10, 32)
+		if err == nil {
+			_, err = auth.GetUserById(uint(userID))
+		}
+		if err != nil {
+			message := fmt.Sprintf("user not found with id: %s", permission.Subject)
+			c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: message,
+				Error:   message,
+			})
+			return
+		}
+	}
+
+	permission.ID = 0
+	permission.OrganizationID = organization.ID
+	permission.CreatedBy = auth.GetCurrentUser(c.Request).ID
+
+	if err := auth.GrantResourcePermission(&permission); err != nil {
+		log.Errorf("Error granting resource permission: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, common.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error granting resource permission",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, permission)
+}
+
+// RevokePermission deletes a resource permission of the organization.
+func RevokePermission(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	id, ok := parsePathID(c, "id", "permission")
+	if !ok {
+		return
+	}
+
+	if err := auth.RevokeResourcePermission(organization.ID, id); err != nil {
+		message := "failed to revoke resource permission: " + err.Error()
+		log.Info(message)
+		statusCode := auth.GormErrorToStatusCode(err)
+		c.AbortWithStatusJSON(statusCode, common.ErrorResponse{
+			Code:    statusCode,
+			Message: message,
+			Error:   message,
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListTeams lists the team memberships of the organization.
+func ListTeams(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	members, err := auth.ListTeamMembers(organization.ID)
+	if err != nil {
+		log.Errorf("Error listing teams: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, common.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error listing teams",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// AddTeamMember adds a user to a team of the organization, the team is created implicitly.
+func AddTeamMember(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	userID, ok := parsePathID(c, "id", "user")
+	if !ok {
+		return
+	}
+
+	if _, err := auth.GetUserById(userID); err != nil {
+		message := fmt.Sprintf("user not found with id: %d", userID)
+		statusCode := auth.GormErrorToStatusCode(err)
+		c.AbortWithStatusJSON(statusCode, common.ErrorResponse{
+			Code:    statusCode,
+			Message: message,
+			Error:   message,
+		})
+		return
+	}
+
+	if err := auth.AddTeamMember(organization.ID, c.Param("team"), userID); err != nil {
+		log.Errorf("Error adding team member: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, common.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error adding team member",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RemoveTeamMember removes a user from a team of the organization.
+func RemoveTeamMember(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	userID, ok := parsePathID(c, "id", "user")
+	if !ok {
+		return
+	}
+
+	if err := auth.RemoveTeamMember(organization.ID, c.Param("team"), userID); err != nil {
+		log.Errorf("Error removing team member: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, common.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error removing team member",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// parsePathID parses an ID path parameter, it aborts the request if it is invalid
+func parsePathID(c *gin.Context, param string, name string) (uint, bool) {
+	idParam := c.Param(param)
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		message := fmt.Sprintf("error parsing %s id: %s", name, err)
+		log.Info(message)
+		c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: message,
+			Error:   message,
+		})
+		return 0, false
+	}
+
+	return uint(id), true
+}
@@ -0,0 +1,300 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/cluster"
+	"github.com/banzaicloud/pipeline/config"
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// ListCustomPostHooks lists the posthooks defined by the organization
+func ListCustomPostHooks(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	postHooks, err := intCluster.NewCustomPostHooks(config.DB()).FindAll(organization.ID)
+	if err != nil {
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error listing custom posthooks",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	response := make([]pkgCluster.CustomPostHookResponse, 0, len(postHooks))
+	for _, postHook := range postHooks {
+		response = append(response, convertCustomPostHook(postHook))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetCustomPostHook returns a posthook defined by the organization
+func GetCustomPostHook(c *gin.Context) {
+	postHook, ok := getCustomPostHookFromRequest(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, convertCustomPostHook(postHook))
+}
+
+// CreateCustomPostHook defines a new posthook installing a chart from the organization's Helm repositories
+func CreateCustomPostHook(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	var request pkgCluster.CustomPostHookRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "error parsing request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if !validateCustomPostHook(c, organization.ID, organization.Name, &request) {
+		return
+	}
+
+	postHooks := intCluster.NewCustomPostHooks(config.DB())
+
+	exists, err := postHooks.Exists(organization.ID, request.Name)
+	if err != nil {
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error creating custom posthook",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if exists {
+		c.JSON(http.StatusConflict, pkgCommon.ErrorResponse{
+			Code:    http.StatusConflict,
+			Message: "custom posthook already exists with this name",
+		})
+		return
+	}
+
+	postHook := &intCluster.CustomPostHookModel{
+		OrganizationID: organization.ID,
+		CreatedBy:      auth.GetCurrentUser(c.Request).ID,
+	}
+
+	if !saveCustomPostHook(c, postHook, &request) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, convertCustomPostHook(postHook))
+}
+
+// UpdateCustomPostHook updates a posthook defined by the organization
+func UpdateCustomPostHook(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	postHook, ok := getCustomPostHookFromRequest(c)
+	if !ok {
+		return
+	}
+
+	var request pkgCluster.CustomPostHookRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "error parsing request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if request.Name != postHook.Name {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "custom posthooks cannot be renamed",
+		})
+		return
+	}
+
+	if !validateCustomPostHook(c, organization.ID, organization.Name, &request) {
+		return
+	}
+
+	if !saveCustomPostHook(c, postHook, &request) {
+		return
+	}
+
+	c.JSON(http.StatusOK, convertCustomPostHook(postHook))
+}
+
+// DeleteCustomPostHook deletes a posthook defined by the organization
+func DeleteCustomPostHook(c *gin.Context) {
+	postHook, ok := getCustomPostHookFromRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := intCluster.NewCustomPostHooks(config.DB()).Delete(postHook); err != nil {
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error deleting custom posthook",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListOrganizationFunctions lists the built-in posthook functions and the ones defined by the organization
+func ListOrganizationFunctions(c *gin.Context) {
+	functionList, err := cluster.ListOrganizationPostHookNames(auth.GetCurrentOrganization(c.Request).ID)
+	if err != nil {
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error listing functions",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, functionList)
+}
+
+func getCustomPostHookFromRequest(c *gin.Context) (*intCluster.CustomPostHookModel, bool) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	postHook, err := intCluster.NewCustomPostHooks(config.DB()).FindOne(organization.ID, c.Param("name"))
+	if isNotFound(err) {
+		c.JSON(http.StatusNotFound, pkgCommon.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "custom posthook not found",
+			Error:   err.Error(),
+		})
+		return nil, false
+	} else if err != nil {
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error getting custom posthook",
+			Error:   err.Error(),
+		})
+		return nil, false
+	}
+
+	return postHook, true
+}
+
+func validateCustomPostHook(c *gin.Context, organizationID uint, organizationName string, request *pkgCluster.CustomPostHookRequest) bool {
+	err := cluster.ValidateCustomPostHook(organizationID, organizationName, request)
+	if isInvalid(err) {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: errors.Cause(err).Error(),
+			Error:   err.Error(),
+		})
+		return false
+	} else if err != nil {
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error validating custom posthook",
+			Error:   err.Error(),
+		})
+		return false
+	}
+
+	return true
+}
+
+func saveCustomPostHook(c *gin.Context, postHook *intCluster.CustomPostHookModel, request *pkgCluster.CustomPostHookRequest) bool {
+	postHook.Name = request.Name
+	postHook.Chart = request.Chart
+	postHook.ChartVersion = request.ChartVersion
+	postHook.ReleaseName = request.ReleaseName
+	postHook.Namespace = request.Namespace
+	postHook.Values = request.Values
+	postHook.Secrets = ""
+
+	if len(request.Secrets) > 0 {
+		secrets, err := json.Marshal(request.Secrets)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "error parsing request",
+				Error:   err.Error(),
+			})
+			return false
+		}
+
+		postHook.Secrets = string(secrets)
+	}
+
+	if err := intCluster.NewCustomPostHooks(config.DB()).Save(postHook); err != nil {
+		errorHandler.Handle(err)
+
+		c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "error saving custom posthook",
+			Error:   err.Error(),
+		})
+		return false
+	}
+
+	return true
+}
+
+func convertCustomPostHook(postHook *intCluster.CustomPostHookModel) pkgCluster.CustomPostHookResponse {
+	response := pkgCluster.CustomPostHookResponse{
+		CustomPostHookRequest: pkgCluster.CustomPostHookRequest{
+			Name:         postHook.Name,
+			Chart:        postHook.Chart,
+			ChartVersion: postHook.ChartVersion,
+			ReleaseName:  postHook.ReleaseName,
+			Namespace:    postHook.Namespace,
+			Values:       postHook.Values,
+		},
+		CreatedAt: postHook.CreatedAt,
+		UpdatedAt: postHook.UpdatedAt,
+		CreatedBy: postHook.CreatedBy,
+	}
+
+	if postHook.Secrets != "" {
+		if err := json.Unmarshal([]byte(postHook.Secrets), &response.Secrets); err != nil {
+			log.Warnf("could not unmarshal custom posthook secrets [%s]: %s", postHook.Name, err.Error())
+		}
+	}
+
+	return response
+}
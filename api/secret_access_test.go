@@ -0,0 +1,76 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+)
+
+func TestRestrictSecretsQuery(t *testing.T) {
+	accessibleIDs := map[string]bool{"b": true, "a": true}
+
+	cases := []struct {
+		name          string
+		accessibleIDs map[string]bool
+		ids           []string
+		expected      bool
+		expectedIDs   []string
+	}{
+		{
+			name:          "accessible IDs",
+			accessibleIDs: accessibleIDs,
+			ids:           []string{"a"},
+			expected:      true,
+			expectedIDs:   []string{"a"},
+		},
+		{
+			name:          "inaccessible ID",
+			accessibleIDs: accessibleIDs,
+			ids:           []string{"a", "c"},
+			expected:      false,
+		},
+		{
+			name:          "query by type and tags",
+			accessibleIDs: accessibleIDs,
+			expected:      true,
+			expectedIDs:   []string{"a", "b"},
+		},
+		{
+			name:     "no accessible secrets",
+			expected: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			query := secretTypes.ListSecretsQuery{Type: secretTypes.GenericSecret, Tags: []string{"tag"}, IDs: tc.ids}
+
+			if actual := restrictSecretsQuery(tc.accessibleIDs, &query); actual != tc.expected {
+				t.Fatalf("expected %t, got %t", tc.expected, actual)
+			}
+
+			if tc.expected && !reflect.DeepEqual(query.IDs, tc.expectedIDs) {
+				t.Errorf("expected IDs %v, got %v", tc.expectedIDs, query.IDs)
+			}
+
+			if query.Type != secretTypes.GenericSecret || len(query.Tags) != 1 {
+				t.Error("expected the type and tags of the query to be kept")
+			}
+		})
+	}
+}
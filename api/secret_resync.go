@@ -0,0 +1,124 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"sync"
+
+	"github.com/goph/emperror"
+)
+
+// maxConcurrentSecretResyncs is the maximum number of secrets updated in clusters at the same time
+const maxConcurrentSecretResyncs = 4
+
+type secretResyncKey struct {
+	organizationID uint
+	secretID       string
+}
+
+// secretResyncQueue updates changed secrets in the clusters they are installed into in the background.
+// Changes of a secret arriving while it is being updated are coalesced into a single rerun,
+// and the number of concurrently updated secrets is bounded.
+type secretResyncQueue struct {
+	resync       func(organizationID uint, secretID string) error
+	errorHandler func() emperror.Handler
+	slots        chan struct{}
+
+	mu sync.Mutex
+	// pending holds the queued and running resyncs, the value is true if a rerun has been requested
+	pending map[secretResyncKey]bool
+}
+
+func newSecretResyncQueue(
+	workers int,
+	resync func(organizationID uint, secretID string) error,
+	errorHandler func() emperror.Handler,
+) *secretResyncQueue {
+	return &secretResyncQueue{
+		resync:       resync,
+		errorHandler: errorHandler,
+		slots:        make(chan struct{}, workers),
+		pending:      make(map[secretResyncKey]bool),
+	}
+}
+
+var (
+	secretResyncsOnce sync.Once
+	secretResyncs     *secretResyncQueue
+)
+
+// getSecretResyncQueue returns the resync queue of the application.
+func getSecretResyncQueue() *secretResyncQueue {
+	secretResyncsOnce.Do(func() {
+		secretResyncs = newSecretResyncQueue(
+			maxConcurrentSecretResyncs,
+			func(organizationID uint, secretID string) error {
+				return getSecretClusterManager().ResyncSecret(context.Background(), organizationID, secretID)
+			},
+			func() emperror.Handler { return errorHandler },
+		)
+	})
+
+	return secretResyncs
+}
+
+// enqueue schedules the update of a secret in the clusters it is installed into.
+func (q *secretResyncQueue) enqueue(organizationID uint, secretID string) {
+	key := secretResyncKey{organizationID: organizationID, secretID: secretID}
+
+	q.mu.Lock()
+	if _, ok := q.pending[key]; ok {
+		q.pending[key] = true
+		q.mu.Unlock()
+
+		return
+	}
+	q.pending[key] = false
+	q.mu.Unlock()
+
+	go q.run(key)
+}
+
+func (q *secretResyncQueue) run(key secretResyncKey) {
+	q.slots <- struct{}{}
+	defer func() { <-q.slots }()
+
+	for {
+		// changes arriving from now on need a rerun, the earlier ones are picked up by this one
+		q.mu.Lock()
+		q.pending[key] = false
+		q.mu.Unlock()
+
+		q.resyncSecret(key)
+
+		q.mu.Lock()
+		if !q.pending[key] {
+			delete(q.pending, key)
+			q.mu.Unlock()
+
+			return
+		}
+		q.mu.Unlock()
+	}
+}
+
+func (q *secretResyncQueue) resyncSecret(key secretResyncKey) {
+	defer emperror.HandleRecover(q.errorHandler())
+
+	if err := q.resync(key.organizationID, key.secretID); err != nil {
+		q.errorHandler().Handle(emperror.With(err, "organization", key.organizationID, "secret", key.secretID))
+	}
+}
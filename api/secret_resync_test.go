@@ -0,0 +1,114 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goph/emperror"
+)
+
+func TestSecretResyncQueue(t *testing.T) {
+	var mu sync.Mutex
+	runs := make(map[string]int)
+	running, maxRunning := 0, 0
+
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+
+	var handled []error
+
+	queue := newSecretResyncQueue(
+		2,
+		func(organizationID uint, secretID string) error {
+			mu.Lock()
+			runs[secretID]++
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+
+			started <- struct{}{}
+			<-release
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+
+			if secretID == "panic" {
+				panic("resync panicked")
+			}
+
+			return nil
+		},
+		func() emperror.Handler {
+			return emperror.HandlerFunc(func(err error) {
+				mu.Lock()
+				handled = append(handled, err)
+				mu.Unlock()
+			})
+		},
+	)
+
+	queue.enqueue(1, "secret")
+	<-started
+
+	// changes during a running resync are coalesced into a single rerun
+	queue.enqueue(1, "secret")
+	queue.enqueue(1, "secret")
+
+	queue.enqueue(1, "panic")
+	<-started
+
+	// no more than two secrets are updated at the same time
+	queue.enqueue(1, "other")
+
+	close(release)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		queue.mu.Lock()
+		done := len(queue.pending) == 0
+		queue.mu.Unlock()
+
+		if done {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("resyncs did not finish in time")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if runs["secret"] != 2 || runs["panic"] != 1 || runs["other"] != 1 {
+		t.Errorf("unexpected resync runs: %v", runs)
+	}
+
+	if maxRunning > 2 {
+		t.Errorf("expected at most 2 concurrent resyncs, got %d", maxRunning)
+	}
+
+	if len(handled) != 1 {
+		t.Errorf("expected the panic to be handled, got %v", handled)
+	}
+}
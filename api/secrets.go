@@ -18,11 +18,13 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
 
 	"github.com/banzaicloud/pipeline/auth"
 	"github.com/banzaicloud/pipeline/cluster"
 	"github.com/banzaicloud/pipeline/config"
+	intAudit "github.com/banzaicloud/pipeline/internal/audit"
 	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
 	"github.com/banzaicloud/pipeline/pkg/common"
 	"github.com/banzaicloud/pipeline/pkg/providers"
@@ -174,6 +176,8 @@ func AddSecrets(c *gin.Context) {
 		return
 	}
 
+	intAudit.SetSecretID(c, secretID)
+
 	c.JSON(http.StatusCreated, secret.CreateSecretResponse{
 		Name:      s.Name,
 		Type:      s.Type,
@@ -254,6 +258,8 @@ func UpdateSecrets(c *gin.Context) {
 
 	log.Debugf("Secret updated at: %s/%s", organizationID, secretID)
 
+	getSecretResyncQueue().enqueue(organizationID, secretID)
+
 	s, err := secret.RestrictedStore.Get(organizationID, secretID)
 	if err != nil {
 		log.Errorf("error during getting secret: %s", err.Error())
@@ -315,7 +321,24 @@ func ListSecrets(c *gin.Context) {
 				Message: "Error during listing secrets",
 				Error:   err.Error(),
 			})
+		} else if accessibleIDs, all, err := auth.GetAccessibleResourceIDs(c.Request, organizationID, auth.SecretResource); err != nil {
+			log.Errorf("Error during getting accessible secrets: %s", err.Error())
+			c.AbortWithStatusJSON(http.StatusInternalServerError, common.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "Error during listing secrets",
+				Error:   err.Error(),
+			})
 		} else {
+			if !all {
+				accessibleSecrets := make([]*secret.SecretItemResponse, 0, len(accessibleIDs))
+				for _, s := range secrets {
+					if accessibleIDs[s.ID] {
+						accessibleSecrets = append(accessibleSecrets, s)
+					}
+				}
+				secrets = accessibleSecrets
+			}
+
 			c.JSON(http.StatusOK, secrets)
 		}
 	}
@@ -328,16 +351,250 @@ func GetSecret(c *gin.Context) {
 
 	secretID := c.Param("id")
 
-	if secret, err := secret.RestrictedStore.Get(organizationID, secretID); err != nil {
+	secretItem, err := secret.RestrictedStore.Get(organizationID, secretID)
+	if err != nil {
 		log.Errorf("Error during getting secret: %s", err.Error())
 		c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
 			Code:    http.StatusBadRequest,
 			Message: "Error during listing secret",
 			Error:   err.Error(),
 		})
-	} else {
-		c.JSON(http.StatusOK, secret)
+		return
+	}
+
+	secretItem.Installations, err = cluster.GetSecretInstallations(organizationID, secretID)
+	if err != nil {
+		log.Errorf("Error during listing secret installations: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, common.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error during listing secret installations",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, secretItem)
+}
+
+// ListSecretVersions returns the versions of a secret
+func ListSecretVersions(c *gin.Context) {
+
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	secretID := c.Param("id")
+
+	versions, err := secret.RestrictedStore.ListVersions(organizationID, secretID)
+	if err != nil {
+		log.Errorf("Error during listing secret versions: %s", err.Error())
+		statusCode := http.StatusBadRequest
+		if err == secret.ErrSecretNotExists {
+			statusCode = http.StatusNotFound
+		}
+		c.AbortWithStatusJSON(statusCode, common.ErrorResponse{
+			Code:    statusCode,
+			Message: "Error during listing secret versions",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// GetSecretVersion returns a version of a secret, the values are hidden unless requested
+func GetSecretVersion(c *gin.Context) {
+
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	secretID := c.Param("id")
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid secret version",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	values, err := strconv.ParseBool(c.DefaultQuery("values", "false"))
+	if err != nil {
+		values = false
+	}
+
+	secretItem, err := secret.RestrictedStore.GetVersion(organizationID, secretID, version)
+	if err != nil {
+		log.Errorf("Error during getting secret version: %s", err.Error())
+		statusCode := http.StatusBadRequest
+		if err == secret.ErrSecretNotExists || err == secret.ErrSecretVersionNotExists {
+			statusCode = http.StatusNotFound
+		}
+		c.AbortWithStatusJSON(statusCode, common.ErrorResponse{
+			Code:    statusCode,
+			Message: "Error during getting secret version",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if !values {
+		secretItem.HideValues()
+	}
+
+	c.JSON(http.StatusOK, secretItem)
+}
+
+// RollbackSecret restores an earlier version of a secret as its latest version
+func RollbackSecret(c *gin.Context) {
+
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	secretID := c.Param("id")
+
+	var request secret.RollbackSecretRequest
+	if err := c.ShouldBind(&request); err != nil {
+		log.Errorf("Error during binding RollbackSecretRequest: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error during binding",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	err := secret.RestrictedStore.Rollback(organizationID, secretID, request.Version, auth.GetCurrentUser(c.Request).Login)
+	if err != nil {
+		log.Errorf("Error during rolling back secret: %s", err.Error())
+		statusCode := http.StatusInternalServerError
+		switch err.(type) {
+		case secret.ForbiddenError, secret.ReadOnlyError:
+			statusCode = http.StatusBadRequest
+		default:
+			if err == secret.ErrSecretNotExists || err == secret.ErrSecretVersionNotExists {
+				statusCode = http.StatusNotFound
+			} else if secret.IsCASError(err) {
+				statusCode = http.StatusConflict
+			}
+		}
+		c.AbortWithStatusJSON(statusCode, common.ErrorResponse{
+			Code:    statusCode,
+			Message: "Error during rolling back secret",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	log.Debugf("Secret rolled back to version %d: %d/%s", request.Version, organizationID, secretID)
+
+	getSecretResyncQueue().enqueue(organizationID, secretID)
+
+	s, err := secret.RestrictedStore.Get(organizationID, secretID)
+	if err != nil {
+		log.Errorf("error during getting secret: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Error:   err.Error(),
+		})
+		return
 	}
+
+	c.JSON(http.StatusOK, secret.CreateSecretResponse{
+		Name:      s.Name,
+		Type:      s.Type,
+		ID:        secretID,
+		UpdatedAt: s.UpdatedAt,
+		UpdatedBy: s.UpdatedBy,
+		Version:   s.Version,
+	})
+}
+
+// GetSecretRotationPolicy returns the rotation policy of a secret
+func GetSecretRotationPolicy(c *gin.Context) {
+
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	secretID := c.Param("id")
+
+	policy, err := secret.GetRotationPolicy(organizationID, secretID)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err == secret.ErrRotationPolicyNotExists {
+			statusCode = http.StatusNotFound
+		} else {
+			log.Errorf("Error during getting secret rotation policy: %s", err.Error())
+		}
+		c.AbortWithStatusJSON(statusCode, common.ErrorResponse{
+			Code:    statusCode,
+			Message: "Error during getting secret rotation policy",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// SetSecretRotationPolicy creates or updates the rotation policy of a secret
+func SetSecretRotationPolicy(c *gin.Context) {
+
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	secretID := c.Param("id")
+
+	var request secret.RotationPolicyRequest
+	if err := c.ShouldBind(&request); err != nil {
+		log.Errorf("Error during binding RotationPolicyRequest: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error during binding",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	policy, err := secret.SetRotationPolicy(secret.RestrictedStore, organizationID, secretID, &request, auth.GetCurrentUser(c.Request).Login)
+	if err != nil {
+		log.Errorf("Error during setting secret rotation policy: %s", err.Error())
+		statusCode := http.StatusInternalServerError
+		switch err.(type) {
+		case secret.RotationPolicyError, secret.ForbiddenError, secret.ReadOnlyError:
+			statusCode = http.StatusBadRequest
+		default:
+			if err == secret.ErrSecretNotExists {
+				statusCode = http.StatusNotFound
+			}
+		}
+		c.AbortWithStatusJSON(statusCode, common.ErrorResponse{
+			Code:    statusCode,
+			Message: "Error during setting secret rotation policy",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeleteSecretRotationPolicy deletes the rotation policy of a secret
+func DeleteSecretRotationPolicy(c *gin.Context) {
+
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	secretID := c.Param("id")
+
+	if err := secret.DeleteRotationPolicy(organizationID, secretID); err != nil {
+		log.Errorf("Error during deleting secret rotation policy: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, common.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error during deleting secret rotation policy",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
 }
 
 // DeleteSecrets delete a secret with the given secret id
@@ -350,6 +607,11 @@ func DeleteSecrets(c *gin.Context) {
 
 	secretID := c.Param("id")
 
+	cascade, err := strconv.ParseBool(c.DefaultQuery("cascade", "false"))
+	if err != nil {
+		cascade = false
+	}
+
 	log.Infof("Check clusters before delete secret[%s]", secretID)
 	if err := checkClustersBeforeDelete(organizationID, secretID); err != nil {
 		log.Errorf("Cluster found with this secret[%s]: %s", secretID, err.Error())
@@ -358,6 +620,13 @@ func DeleteSecrets(c *gin.Context) {
 			Message: fmt.Sprintf("Cluster found with this secret[%s]", secretID),
 			Error:   err.Error(),
 		})
+	} else if err := checkInstallationsBeforeDelete(organizationID, secretID, cascade); err != nil {
+		log.Errorf("Error during deleting secret from clusters[%s]: %s", secretID, err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("Secret is installed into clusters[%s]", secretID),
+			Error:   err.Error(),
+		})
 	} else if err := secret.RestrictedStore.Delete(organizationID, secretID); err != nil {
 		log.Errorf("Error during deleting secrets: %s", err.Error())
 		code := http.StatusInternalServerError
@@ -368,6 +637,14 @@ func DeleteSecrets(c *gin.Context) {
 		}
 		c.AbortWithStatusJSON(code, resp)
 	} else {
+		if err := secret.DeleteRotationPolicy(organizationID, secretID); err != nil {
+			errorHandler.Handle(err)
+		}
+
+		if err := auth.RevokeResourcePermissionsOf(organizationID, auth.SecretResource, secretID); err != nil {
+			errorHandler.Handle(err)
+		}
+
 		log.Info("Delete secrets succeeded")
 		c.Status(http.StatusNoContent)
 	}
@@ -418,9 +695,7 @@ func IsValidSecretType(secretType string) error {
 
 // checkClustersBeforeDelete returns error if there's a running cluster that created with the given secret
 func checkClustersBeforeDelete(orgId uint, secretId string) error {
-	// TODO: move these to a struct and create them only once upon application init
-	secretValidator := providers.NewSecretValidator(secret.Store)
-	clusterManager := cluster.NewManager(intCluster.NewClusters(config.DB()), secretValidator, log, errorHandler)
+	clusterManager := getSecretClusterManager()
 
 	clusters, err := clusterManager.GetClustersBySecretID(context.Background(), orgId, secretId)
 	if err != nil {
@@ -440,3 +715,50 @@ func checkClustersBeforeDelete(orgId uint, secretId string) error {
 
 	return nil
 }
+
+// checkInstallationsBeforeDelete returns error if the secret is installed into clusters,
+// or deletes it from the clusters if cascade is set
+func checkInstallationsBeforeDelete(orgId uint, secretId string, cascade bool) error {
+	if cascade {
+		return getSecretClusterManager().UninstallSecret(context.Background(), orgId, secretId)
+	}
+
+	installations, err := cluster.GetSecretInstallations(orgId, secretId)
+	if err != nil {
+		return err
+	}
+
+	if len(installations) > 0 {
+		return fmt.Errorf("the secret is installed into %d namespaces of clusters, use cascade=true to delete them as well", len(installations))
+	}
+
+	return nil
+}
+
+func getSecretClusterManager() *cluster.Manager {
+	// TODO: move these to a struct and create them only once upon application init
+	secretValidator := providers.NewSecretValidator(secret.Store)
+	return cluster.NewManager(intCluster.NewClusters(config.DB()), secretValidator, intCluster.NewWorkflows(config.DB()), log, errorHandler)
+}
+
+// restrictSecretsQuery limits a secret query to the accessible secrets.
+// It returns false if the query selects a secret which is not accessible or there are no accessible secrets at all.
+func restrictSecretsQuery(accessibleIDs map[string]bool, query *secretTypes.ListSecretsQuery) bool {
+	if len(query.IDs) > 0 {
+		for _, id := range query.IDs {
+			if !accessibleIDs[id] {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	query.IDs = make([]string, 0, len(accessibleIDs))
+	for id := range accessibleIDs {
+		query.IDs = append(query.IDs, id)
+	}
+	sort.Strings(query.IDs)
+
+	return len(query.IDs) > 0
+}
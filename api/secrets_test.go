@@ -29,6 +29,10 @@ import (
 	"github.com/banzaicloud/pipeline/secret/verify"
 )
 
+func init() {
+	secret.SetStore(secret.NewInMemorySecretStore())
+}
+
 func TestIsValidSecretType(t *testing.T) {
 
 	cases := []struct {
@@ -79,7 +79,8 @@ func GetUsers(c *gin.Context) {
 	}
 }
 
-// AddUser adds a user to an organization, role=admin|member has to be in the body, otherwise member is the default role.
+// AddUser adds a user to an organization, role=admin|member|viewer has to be in the body, otherwise member is the default role.
+// Adding a user who is already in the organization changes the role of the user.
 func AddUser(c *gin.Context) {
 
 	log.Info("Adding user to organization")
@@ -98,7 +99,7 @@ func AddUser(c *gin.Context) {
 	}
 
 	role := struct {
-		Role string `json:"role" binding:"required,eq=member|eq=admin"`
+		Role string `json:"role" binding:"required,eq=member|eq=admin|eq=viewer"`
 	}{Role: "member"}
 
 	if c.Request.ContentLength != 0 {
@@ -132,7 +133,7 @@ func AddUser(c *gin.Context) {
 		return
 	}
 
-	auth.AddOrgRoleForUser(user.ID, organization.ID)
+	auth.AddOrgRoleForUser(user.ID, role.Role, organization.ID)
 
 	c.Status(http.StatusNoContent)
 }
@@ -192,20 +193,7 @@ func RemoveUser(c *gin.Context) {
 		return
 	}
 
-	user, err := auth.GetCurrentUserFromDB(c.Request)
-	if err != nil {
-		message := "failed to query user for deletion: " + err.Error()
-		log.Info(message)
-		statusCode := auth.GormErrorToStatusCode(err)
-		c.AbortWithStatusJSON(statusCode, common.ErrorResponse{
-			Code:    statusCode,
-			Message: message,
-			Error:   message,
-		})
-		return
-	}
-
-	auth.DeleteOrgRoleForUser(user.ID, organization.ID)
+	auth.DeleteOrgRoleForUser(uint(id), organization.ID)
 
 	c.Status(http.StatusNoContent)
 }
@@ -20,6 +20,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	bauth "github.com/banzaicloud/bank-vaults/auth"
 	"github.com/banzaicloud/pipeline/config"
@@ -183,6 +184,25 @@ func Init(db *gorm.DB) {
 	githubProvider.AuthorizeHandler = NewGithubAuthorizeHandler(githubProvider)
 	Auth.RegisterProvider(githubProvider)
 
+	if viper.GetBool("auth.oidc.enabled") {
+		Auth.RegisterProvider(newOIDCProvider(OIDCConfig{
+			Issuer:       viper.GetString("auth.oidc.issuer"),
+			ClientID:     viper.GetString("auth.oidc.clientid"),
+			ClientSecret: viper.GetString("auth.oidc.clientsecret"),
+			GroupsClaim:  viper.GetString("auth.oidc.groupsClaim"),
+			DefaultRole:  viper.GetString("auth.oidc.defaultRole"),
+			Scopes:       viper.GetStringSlice("auth.oidc.scopes"),
+		}))
+	}
+
+	if viper.GetBool("auth.gitlab.enabled") {
+		Auth.RegisterProvider(newGitLabProvider(
+			viper.GetString("auth.gitlab.url"),
+			viper.GetString("auth.gitlab.clientid"),
+			viper.GetString("auth.gitlab.clientsecret"),
+		))
+	}
+
 	TokenStore = bauth.NewVaultTokenStore("pipeline")
 
 	jwtAuth := bauth.JWTAuth(TokenStore, signingKey, func(claims *bauth.ScopedClaims) interface{} {
@@ -191,6 +211,7 @@ func Init(db *gorm.DB) {
 			ID:      uint(userID),
 			Login:   claims.Text, // This is needed for Drone virtual user tokens
 			Virtual: claims.Type == DroneHookTokenType,
+			TokenID: claims.Id,
 		}
 	})
 
@@ -221,11 +242,53 @@ func Install(engine *gin.Engine) {
 		authGroup.GET("/github/logout", authHandler)
 		authGroup.GET("/github/register", authHandler)
 		authGroup.GET("/github/callback", authHandler)
-		authGroup.POST("/tokens", GenerateToken)
-		authGroup.GET("/tokens", GetTokens)
-		authGroup.GET("/tokens/:id", GetTokens)
-		authGroup.DELETE("/tokens/:id", DeleteToken)
+		for _, provider := range []string{OIDCProviderName, GitLabProviderName} {
+			authGroup.GET("/"+provider+"/login", authHandler)
+			authGroup.GET("/"+provider+"/logout", authHandler)
+			authGroup.GET("/"+provider+"/register", authHandler)
+			authGroup.GET("/"+provider+"/callback", authHandler)
+		}
+		installTokenRoutes(authGroup)
+	}
+}
+
+// installTokenRoutes registers the token management endpoints.
+// The /auth/ group is not authorized, so the handlers check the restrictions of the token of the request themselves.
+func installTokenRoutes(router gin.IRoutes) {
+	router.POST("/tokens", GenerateToken)
+	router.GET("/tokens", GetTokens)
+	router.GET("/tokens/:id", GetTokens)
+	router.DELETE("/tokens/:id", DeleteToken)
+}
+
+// requireUnrestrictedToken aborts the request if it is authenticated by a restricted token,
+// otherwise expiring or scoped tokens could create unrestricted ones or revoke other tokens.
+func requireUnrestrictedToken(c *gin.Context, currentUser *User) bool {
+	if currentUser.TokenID == "" {
+		return true
+	}
+
+	token, err := getAPIToken(currentUser.TokenID)
+	if err != nil {
+		log.Errorf("failed to get token restrictions: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "failed to get token restrictions",
+			Error:   err.Error(),
+		})
+		return false
 	}
+
+	if token != nil && token.IsRestricted() {
+		c.AbortWithStatusJSON(http.StatusForbidden, pkgCommon.ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "restricted tokens can't manage tokens",
+			Error:   "access to tokens is forbidden",
+		})
+		return false
+	}
+
+	return true
 }
 
 //GenerateToken generates token from context
@@ -261,12 +324,12 @@ func GenerateToken(c *gin.Context) {
 			log.Info(c.ClientIP(), " ", err.Error())
 			return
 		}
+		if !requireUnrestrictedToken(c, currentUser) {
+			return
+		}
 	}
 
-	tokenRequest := struct {
-		Name        string `json:"name,omitempty"`
-		VirtualUser string `json:"virtualUser,omitempty"`
-	}{Name: "generated"}
+	tokenRequest := TokenRequest{Name: "generated"}
 
 	if c.Request.Method == http.MethodPost && c.Request.ContentLength > 0 {
 		if err := c.ShouldBindJSON(&tokenRequest); err != nil {
@@ -274,6 +337,12 @@ func GenerateToken(c *gin.Context) {
 			log.Info(c.ClientIP(), " ", err.Error())
 			return
 		}
+
+		if err := tokenRequest.Validate(); err != nil {
+			err := c.AbortWithError(http.StatusBadRequest, err)
+			log.Info(c.ClientIP(), " ", err.Error())
+			return
+		}
 	}
 
 	isForVirtualUser := tokenRequest.VirtualUser != ""
@@ -287,7 +356,7 @@ func GenerateToken(c *gin.Context) {
 		tokenType = DroneHookTokenType
 	}
 
-	tokenID, signedToken, err := createAndStoreAPIToken(userID, userLogin, tokenType, tokenRequest.Name)
+	tokenID, signedToken, err := createAndStoreAPIToken(userID, userLogin, tokenType, tokenRequest.Name, tokenRequest.ExpiresAt)
 
 	if err != nil {
 		err = c.AbortWithError(http.StatusInternalServerError, fmt.Errorf("%s", err))
@@ -295,6 +364,13 @@ func GenerateToken(c *gin.Context) {
 		return
 	}
 
+	if err := saveAPIToken(tokenID, userID, &tokenRequest); err != nil {
+		TokenStore.Revoke(userID, tokenID)
+		err = c.AbortWithError(http.StatusInternalServerError, err)
+		log.Info(c.ClientIP(), " ", err.Error())
+		return
+	}
+
 	if isForVirtualUser {
 		orgName := GetOrgNameFromVirtualUser(tokenRequest.VirtualUser)
 		organization := Organization{Name: orgName}
@@ -310,22 +386,27 @@ func GenerateToken(c *gin.Context) {
 		}
 
 		AddDefaultRoleForVirtualUser(userID)
-		AddOrgRoleForUser(userID, organization.ID)
+		AddOrgRoleForUser(userID, OrgRoleMember, organization.ID)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"id": tokenID, "token": signedToken})
 }
 
-func createAPIToken(userID string, userLogin string, tokenType bauth.TokenType) (string, string, error) {
+func createAPIToken(userID string, userLogin string, tokenType bauth.TokenType, expiresAt *time.Time) (string, string, error) {
 	tokenID := uuid.NewV4().String()
 
+	var expiresAtUnix int64
+	if expiresAt != nil {
+		expiresAtUnix = expiresAt.Unix()
+	}
+
 	// Create the Claims
 	claims := &bauth.ScopedClaims{
 		StandardClaims: jwt.StandardClaims{
 			Issuer:    JwtIssuer,
 			Audience:  JwtAudience,
 			IssuedAt:  jwt.TimeFunc().Unix(),
-			ExpiresAt: 0,
+			ExpiresAt: expiresAtUnix,
 			Subject:   userID,
 			Id:        tokenID,
 		},
@@ -343,8 +424,8 @@ func createAPIToken(userID string, userLogin string, tokenType bauth.TokenType)
 	return tokenID, signedToken, nil
 }
 
-func createAndStoreAPIToken(userID string, userLogin string, tokenType bauth.TokenType, tokenName string) (string, string, error) {
-	tokenID, signedToken, err := createAPIToken(userID, userLogin, tokenType)
+func createAndStoreAPIToken(userID string, userLogin string, tokenType bauth.TokenType, tokenName string, expiresAt *time.Time) (string, string, error) {
+	tokenID, signedToken, err := createAPIToken(userID, userLogin, tokenType, expiresAt)
 	if err != nil {
 		return "", "", err
 	}
@@ -360,27 +441,52 @@ func createAndStoreAPIToken(userID string, userLogin string, tokenType bauth.Tok
 
 // GetTokens returns the calling user's access tokens
 func GetTokens(c *gin.Context) {
+	Handler(c)
+	if c.IsAborted() {
+		return
+	}
 	currentUser := GetCurrentUser(c.Request)
 	if currentUser == nil {
 		err := c.AbortWithError(http.StatusUnauthorized, fmt.Errorf("Invalid session"))
 		log.Info(c.ClientIP(), " ", err.Error())
 		return
 	}
+	if !requireUnrestrictedToken(c, currentUser) {
+		return
+	}
 	tokenID := c.Param("id")
 
 	if tokenID == "" {
 		tokens, err := TokenStore.List(currentUser.IDString())
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, err)
-		} else {
-			c.JSON(http.StatusOK, tokens)
+			return
 		}
+
+		apiTokens, err := listAPITokens(currentUser.IDString())
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, err)
+			return
+		}
+
+		response := make([]*TokenResponse, 0, len(tokens))
+		for _, token := range tokens {
+			response = append(response, newTokenResponse(token, apiTokens[token.ID]))
+		}
+
+		c.JSON(http.StatusOK, response)
 	} else {
 		token, err := TokenStore.Lookup(currentUser.IDString(), tokenID)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, err)
 		} else if token != nil {
-			c.JSON(http.StatusOK, token)
+			apiToken, err := getAPIToken(tokenID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, err)
+				return
+			}
+
+			c.JSON(http.StatusOK, newTokenResponse(token, apiToken))
 		} else {
 			c.AbortWithStatusJSON(http.StatusNotFound, pkgCommon.ErrorResponse{
 				Code:    http.StatusNotFound,
@@ -393,12 +499,19 @@ func GetTokens(c *gin.Context) {
 
 // DeleteToken deletes the calling user's access token specified by token id
 func DeleteToken(c *gin.Context) {
+	Handler(c)
+	if c.IsAborted() {
+		return
+	}
 	currentUser := GetCurrentUser(c.Request)
 	if currentUser == nil {
 		err := c.AbortWithError(http.StatusUnauthorized, fmt.Errorf("Invalid session"))
 		log.Info(c.ClientIP(), err.Error())
 		return
 	}
+	if !requireUnrestrictedToken(c, currentUser) {
+		return
+	}
 	tokenID := c.Param("id")
 
 	if tokenID == "" {
@@ -408,6 +521,10 @@ func DeleteToken(c *gin.Context) {
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, err)
 		} else {
+			if err := deleteAPIToken(tokenID); err != nil {
+				log.Warnf("failed to delete restrictions of token %s: %s", tokenID, err.Error())
+			}
+
 			c.Status(http.StatusNoContent)
 		}
 	}
@@ -435,7 +552,7 @@ func (sessionStorer *BanzaiSessionStorer) Update(w http.ResponseWriter, req *htt
 
 	// Drone tokens have to stored in Vault, because they act as Pipeline API tokens as well
 	// TODO We need GC them somehow
-	_, droneToken, err := createAndStoreAPIToken(claims.UserID, currentUser.Login, DroneUserTokenType, "Drone session token")
+	_, droneToken, err := createAndStoreAPIToken(claims.UserID, currentUser.Login, DroneUserTokenType, "Drone session token", nil)
 	if err != nil {
 		log.Info(req.RemoteAddr, err.Error())
 		return err
@@ -533,6 +650,10 @@ func BanzaiDeregisterHandler(context *auth.Context) {
 			http.Error(context.Writer, err.Error(), http.StatusInternalServerError)
 			return
 		}
+
+		if err := deleteAPIToken(token.ID); err != nil {
+			log.Warnln("Failed remove user's token restrictions during user deletetion:", err)
+		}
 	}
 
 	// Delete Casbin roles
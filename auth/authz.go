@@ -17,8 +17,11 @@ package auth
 import (
 	"fmt"
 	"net/http"
+	"regexp"
+	"strconv"
 	"time"
 
+	"github.com/banzaicloud/pipeline/config"
 	"github.com/casbin/casbin"
 	"github.com/casbin/gorm-adapter"
 	"github.com/gin-gonic/gin"
@@ -53,6 +56,8 @@ func NewAuthorizer(dsn string) gin.HandlerFunc {
 	enforcer = casbin.NewSyncedEnforcer(model, adapter, logging)
 	enforcer.StartAutoLoadPolicy(10 * time.Second)
 	addDefaultPolicies()
+	migrateOrgRoles()
+	updateOrgRoles()
 	return newAuthorizer(enforcer)
 }
 
@@ -83,12 +88,56 @@ func (a *BearerAuthorizer) GetUserID(r *http.Request) string {
 }
 
 // CheckPermission checks the user/method/path combination from the request.
+// The scopes and organizations of restricted API tokens are checked first.
+// Permissions granted on individual resources are checked when the organization roles of the user do not allow the request.
 // Returns true (permission granted) or false (permission forbidden)
 func (a *BearerAuthorizer) CheckPermission(r *http.Request) bool {
 	userID := a.GetUserID(r)
-	method := r.Method
+	action := requestAction(r)
 	path := r.URL.Path
-	return a.enforcer.Enforce(userID, path, method)
+	if !checkTokenRestrictions(r) {
+		return false
+	}
+
+	if a.enforcer.Enforce(userID, path, action) {
+		return true
+	}
+
+	// Resource permissions are granted on cluster IDs, the ID in the path must not be resolved as a cluster name
+	if r.URL.Query().Get("field") == "name" {
+		return false
+	}
+
+	return checkResourcePermission(GetCurrentUser(r), path, action)
+}
+
+// requestAction returns the action a request is authorized for.
+// Reading credentials is authorized as full access, so viewers, who are granted read-only methods, are not allowed to do it.
+func requestAction(r *http.Request) string {
+	if isReadOnlyMethod(r.Method) && isSensitiveRead(r) {
+		return fullAccessAction
+	}
+
+	return r.Method
+}
+
+// isSensitiveRead checks whether a read-only request returns credentials:
+// the admin kubeconfig of a cluster, anything through the cluster API proxy, secret values
+// or bucket objects, as backup archives contain the secrets of the backed up cluster.
+func isSensitiveRead(r *http.Request) bool {
+	path := trimBasePath(r.URL.Path)
+
+	if sensitiveReadPath.MatchString(path) {
+		return true
+	}
+
+	if secretValuesPath.MatchString(path) {
+		values, _ := strconv.ParseBool(r.URL.Query().Get("values"))
+
+		return values
+	}
+
+	return false
 }
 
 // RequirePermission returns the 403 Forbidden to the client
@@ -116,26 +165,120 @@ func AddDefaultRoleForVirtualUser(userID interface{}) {
 	enforcer.AddRoleForUser(fmt.Sprint(userID), "defaultVirtual")
 }
 
-// AddOrgRoles creates an organization role, by adding the default (*) org policies for the given organization.
+// Organization roles stored in UserOrganization
+const (
+	// OrgRoleAdmin has full access to the organization
+	OrgRoleAdmin = "admin"
+
+	// OrgRoleMember has full access to the resources of the organization,
+	// but cannot manage its users or delete it
+	OrgRoleMember = "member"
+
+	// OrgRoleViewer has read-only access to the organization
+	OrgRoleViewer = "viewer"
+)
+
+// OrgRoles are the valid organization roles
+var OrgRoles = []string{OrgRoleAdmin, OrgRoleMember, OrgRoleViewer}
+
+// orgMemberResources are the organization API resources members have full access to
+var orgMemberResources = []string{
+	"azure",
+	"backups",
+	"buckets",
+	"cloudinfo",
+	"clusters",
+	"domain",
+	"functions",
+	"helm",
+	"posthooks",
+	"profiles",
+	"secrets",
+	"spotguides",
+}
+
+// readOnlyMethods are the HTTP methods viewers are allowed to use
+var readOnlyMethods = []string{http.MethodGet, http.MethodHead}
+
+// fullAccessAction is the policy action granting every method, read-only requests returning credentials require it
+const fullAccessAction = "*"
+
+// sensitiveReadPath matches the organization API paths returning credentials on any read
+var sensitiveReadPath = regexp.MustCompile(`^/api/v1/orgs/\d+/(clusters/[^/]+/(config|proxy(/.*)?)|secrets/[^/]+/?|buckets/[^/]+/(objects/.*|signedurl))$`)
+
+// secretValuesPath matches the organization API paths returning secret values when requested with the values query parameter
+var secretValuesPath = regexp.MustCompile(`^/api/v1/orgs/\d+/secrets(/[^/]+/versions/[^/]+)?/?$`)
+
+// legacyOrgRole matches the single organization role used before the admin/member/viewer split
+var legacyOrgRole = regexp.MustCompile(`^org-(\d+)$`)
+
+// AddOrgRoles creates the organization roles, by adding the admin, member and viewer policies for the given organization.
 func AddOrgRoles(orgids ...uint) {
 	basePath := viper.GetString("pipeline.basepath")
 	for _, orgid := range orgids {
-		enforcer.AddPolicy(orgRoleName(orgid), fmt.Sprintf("%s/api/v1/orgs/%d", basePath, orgid), "*")
-		enforcer.AddPolicy(orgRoleName(orgid), fmt.Sprintf("%s/api/v1/orgs/%d/*", basePath, orgid), "*")
-		enforcer.AddPolicy(orgRoleName(orgid), fmt.Sprintf("%s/dashboard/orgs/%d/*", basePath, orgid), "*")
+		orgPath := fmt.Sprintf("%s/api/v1/orgs/%d", basePath, orgid)
+		dashboardPath := fmt.Sprintf("%s/dashboard/orgs/%d/*", basePath, orgid)
+
+		admin := orgRoleName(orgid, OrgRoleAdmin)
+		enforcer.AddPolicy(admin, orgPath, fullAccessAction)
+		enforcer.AddPolicy(admin, orgPath+"/*", fullAccessAction)
+		enforcer.AddPolicy(admin, dashboardPath, fullAccessAction)
+
+		member := orgRoleName(orgid, OrgRoleMember)
+		for _, resource := range orgMemberResources {
+			enforcer.AddPolicy(member, orgPath+"/"+resource, fullAccessAction)
+			enforcer.AddPolicy(member, orgPath+"/"+resource+"/*", fullAccessAction)
+		}
+		for _, method := range readOnlyMethods {
+			enforcer.AddPolicy(member, orgPath, method)
+			enforcer.AddPolicy(member, orgPath+"/users", method)
+			enforcer.AddPolicy(member, orgPath+"/users/*", method)
+		}
+		enforcer.AddPolicy(member, dashboardPath, fullAccessAction)
+
+		viewer := orgRoleName(orgid, OrgRoleViewer)
+		for _, method := range readOnlyMethods {
+			enforcer.AddPolicy(viewer, orgPath, method)
+			enforcer.AddPolicy(viewer, orgPath+"/*", method)
+			enforcer.AddPolicy(viewer, dashboardPath, method)
+		}
 	}
 }
 
-// AddOrgRoleForUser adds a user to an organization by adding the associated organization role.
-func AddOrgRoleForUser(userID interface{}, orgids ...uint) {
+// IsValidOrgRole checks whether a role is a valid organization role.
+func IsValidOrgRole(role string) bool {
+	for _, orgRole := range OrgRoles {
+		if role == orgRole {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddOrgRoleForUser adds a user to organizations by adding the associated organization role.
+// Any other role the user had in the organizations is removed, so this can be used to change roles as well.
+func AddOrgRoleForUser(userID interface{}, role string, orgids ...uint) {
 	for _, orgid := range orgids {
-		enforcer.AddRoleForUser(fmt.Sprint(userID), orgRoleName(orgid))
+		for _, orgRole := range OrgRoles {
+			if orgRole != role {
+				enforcer.DeleteRoleForUser(fmt.Sprint(userID), orgRoleName(orgid, orgRole))
+			}
+		}
+		enforcer.AddRoleForUser(fmt.Sprint(userID), orgRoleName(orgid, role))
 	}
 }
 
-// DeleteOrgRoleForUser removes a user from an organization by removing the associated organization role.
+// DeleteOrgRoleForUser removes a user from an organization by removing the associated organization roles.
 func DeleteOrgRoleForUser(userID uint, orgid uint) {
-	enforcer.DeleteRoleForUser(fmt.Sprint(userID), orgRoleName(orgid))
+	for _, role := range OrgRoles {
+		enforcer.DeleteRoleForUser(fmt.Sprint(userID), orgRoleName(orgid, role))
+	}
+}
+
+// HasOrgRole checks whether a user has the given role in an organization.
+func HasOrgRole(userID uint, orgid uint, role string) bool {
+	return enforcer.HasRoleForUser(fmt.Sprint(userID), orgRoleName(orgid, role))
 }
 
 // DeleteRolesForUser removes all roles for a given user.
@@ -143,6 +286,68 @@ func DeleteRolesForUser(userID uint) {
 	enforcer.DeleteUser(fmt.Sprint(userID))
 }
 
-func orgRoleName(orgid uint) string {
+// migrateOrgRoles replaces the legacy organization roles, which granted full access to every user,
+// with the role stored for the user in the organization. Virtual users become members.
+func migrateOrgRoles() {
+	migrated := make(map[uint]bool)
+
+	for _, grouping := range enforcer.GetGroupingPolicy() {
+		if len(grouping) < 2 {
+			continue
+		}
+
+		match := legacyOrgRole.FindStringSubmatch(grouping[1])
+		if match == nil {
+			continue
+		}
+
+		orgid64, err := strconv.ParseUint(match[1], 10, 32)
+		if err != nil {
+			continue
+		}
+		orgid := uint(orgid64)
+
+		if !migrated[orgid] {
+			AddOrgRoles(orgid)
+			migrated[orgid] = true
+		}
+
+		role := OrgRoleMember
+
+		if userID, err := strconv.ParseUint(grouping[0], 10, 32); err == nil {
+			userOrg := UserOrganization{UserID: uint(userID), OrganizationID: orgid}
+			if err := config.DB().Where(&userOrg).First(&userOrg).Error; err != nil {
+				log.Warnf("failed to get role of user %d in organization %d: %s", userID, orgid, err.Error())
+			} else if IsValidOrgRole(userOrg.Role) {
+				role = userOrg.Role
+			}
+		}
+
+		enforcer.RemoveGroupingPolicy(grouping[0], grouping[1])
+		AddOrgRoleForUser(grouping[0], role, orgid)
+	}
+
+	for orgid := range migrated {
+		enforcer.RemoveFilteredPolicy(0, legacyOrgRoleName(orgid))
+	}
+}
+
+// updateOrgRoles adds the policies of the organization roles to every organization,
+// so resources added to the roles later are available in existing organizations as well.
+func updateOrgRoles() {
+	var orgids []uint
+	if err := config.DB().Model(&Organization{}).Pluck("id", &orgids).Error; err != nil {
+		log.Errorf("failed to list organizations for updating their roles: %s", err.Error())
+		return
+	}
+
+	AddOrgRoles(orgids...)
+}
+
+func orgRoleName(orgid uint, role string) string {
+	return fmt.Sprintf("org-%d-%s", orgid, role)
+}
+
+func legacyOrgRoleName(orgid uint) string {
 	return fmt.Sprint("org-", orgid)
 }
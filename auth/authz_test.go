@@ -0,0 +1,122 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/casbin/casbin"
+)
+
+func TestOrgRolePolicies(t *testing.T) {
+	original := enforcer
+	defer func() { enforcer = original }()
+
+	enforcer = casbin.NewSyncedEnforcer(casbin.NewModel(modelDefinition))
+	AddOrgRoles(1, 2)
+	AddOrgRoleForUser("1", OrgRoleAdmin, 1)
+	AddOrgRoleForUser("2", OrgRoleMember, 1)
+	AddOrgRoleForUser("3", OrgRoleViewer, 1)
+
+	type allowed struct {
+		admin, member, viewer bool
+	}
+
+	cases := []struct {
+		method  string
+		url     string
+		allowed allowed
+	}{
+		{http.MethodGet, "/api/v1/orgs/1", allowed{true, true, true}},
+		{http.MethodDelete, "/api/v1/orgs/1", allowed{true, false, false}},
+		{http.MethodGet, "/api/v1/orgs/1/users", allowed{true, true, true}},
+		{http.MethodPost, "/api/v1/orgs/1/users/4", allowed{true, false, false}},
+		{http.MethodGet, "/api/v1/orgs/1/permissions", allowed{true, false, true}},
+		{http.MethodPost, "/api/v1/orgs/1/permissions", allowed{true, false, false}},
+		{http.MethodGet, "/api/v1/orgs/1/clusters", allowed{true, true, true}},
+		{http.MethodPost, "/api/v1/orgs/1/clusters", allowed{true, true, false}},
+		{http.MethodGet, "/api/v1/orgs/1/clusters/42", allowed{true, true, true}},
+		{http.MethodHead, "/api/v1/orgs/1/clusters/42", allowed{true, true, true}},
+		{http.MethodDelete, "/api/v1/orgs/1/clusters/42", allowed{true, true, false}},
+		{http.MethodGet, "/api/v1/orgs/1/clusters/42/details", allowed{true, true, true}},
+		{http.MethodGet, "/api/v1/orgs/1/clusters/42/config", allowed{true, true, false}},
+		{http.MethodGet, "/api/v1/orgs/1/clusters/42/proxy/api/v1/secrets", allowed{true, true, false}},
+		{http.MethodPost, "/api/v1/orgs/1/clusters/42/proxy/api/v1/namespaces", allowed{true, true, false}},
+		{http.MethodGet, "/api/v1/orgs/1/secrets", allowed{true, true, true}},
+		{http.MethodGet, "/api/v1/orgs/1/secrets?values=false", allowed{true, true, true}},
+		{http.MethodGet, "/api/v1/orgs/1/secrets?values=true", allowed{true, true, false}},
+		{http.MethodGet, "/api/v1/orgs/1/secrets/abc", allowed{true, true, false}},
+		{http.MethodGet, "/api/v1/orgs/1/secrets/abc/versions", allowed{true, true, true}},
+		{http.MethodGet, "/api/v1/orgs/1/secrets/abc/versions/2", allowed{true, true, true}},
+		{http.MethodGet, "/api/v1/orgs/1/secrets/abc/versions/2?values=true", allowed{true, true, false}},
+		{http.MethodGet, "/api/v1/orgs/1/secrets/abc/rotation", allowed{true, true, true}},
+		{http.MethodPut, "/api/v1/orgs/1/secrets/abc", allowed{true, true, false}},
+		{http.MethodGet, "/api/v1/orgs/1/buckets/abc/objects", allowed{true, true, true}},
+		{http.MethodGet, "/api/v1/orgs/1/buckets/abc/objects/backup.tgz", allowed{true, true, false}},
+		{http.MethodGet, "/api/v1/orgs/1/buckets/abc/signedurl", allowed{true, true, false}},
+		{http.MethodGet, "/api/v1/orgs/1/backups", allowed{true, true, true}},
+		{http.MethodGet, "/api/v1/orgs/1/domain", allowed{true, true, true}},
+		{http.MethodPut, "/api/v1/orgs/1/domain", allowed{true, true, false}},
+		{http.MethodPost, "/api/v1/orgs/1/domain/records", allowed{true, true, false}},
+		{http.MethodGet, "/api/v1/orgs/1/audit", allowed{true, false, true}},
+		{http.MethodGet, "/dashboard/orgs/1/clusters", allowed{true, true, true}},
+		{http.MethodGet, "/api/v1/orgs/2", allowed{false, false, false}},
+		{http.MethodGet, "/api/v1/orgs/2/clusters", allowed{false, false, false}},
+		{http.MethodGet, "/api/v1/orgs/12/clusters", allowed{false, false, false}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.method+" "+tc.url, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.url, nil)
+
+			for userID, expected := range map[string]bool{"1": tc.allowed.admin, "2": tc.allowed.member, "3": tc.allowed.viewer} {
+				if actual := enforcer.Enforce(userID, r.URL.Path, requestAction(r)); actual != expected {
+					t.Errorf("user %s: expected allowed to be %t, got %t", userID, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestRequestAction(t *testing.T) {
+	cases := []struct {
+		method   string
+		url      string
+		expected string
+	}{
+		{http.MethodGet, "/api/v1/orgs/1/clusters/42", http.MethodGet},
+		{http.MethodGet, "/api/v1/orgs/1/clusters/42/config", fullAccessAction},
+		{http.MethodHead, "/api/v1/orgs/1/clusters/42/proxy", fullAccessAction},
+		{http.MethodPut, "/api/v1/orgs/1/clusters/42/proxy/api", http.MethodPut},
+		{http.MethodGet, "/api/v1/orgs/1/clusters/42/configmaps", http.MethodGet},
+		{http.MethodGet, "/api/v1/orgs/1/secrets/abc", fullAccessAction},
+		{http.MethodGet, "/api/v1/orgs/1/secrets/abc/rotation", http.MethodGet},
+		{http.MethodGet, "/api/v1/orgs/1/secrets?values=1", fullAccessAction},
+		{http.MethodGet, "/api/v1/orgs/1/secrets?values=invalid", http.MethodGet},
+		{http.MethodGet, "/api/v1/orgs/1/buckets/abc/objects", http.MethodGet},
+		{http.MethodGet, "/api/v1/orgs/1/buckets/abc/objects/backups/cluster.tgz", fullAccessAction},
+		{http.MethodGet, "/api/v1/orgs/1/buckets/abc/signedurl", fullAccessAction},
+		{http.MethodPut, "/api/v1/orgs/1/buckets/abc/objects/config", http.MethodPut},
+	}
+
+	for _, tc := range cases {
+		r := httptest.NewRequest(tc.method, tc.url, nil)
+		if action := requestAction(r); action != tc.expected {
+			t.Errorf("%s %s: expected action %s, got %s", tc.method, tc.url, tc.expected, action)
+		}
+	}
+}
@@ -27,6 +27,9 @@ import (
 	"golang.org/x/oauth2"
 )
 
+// GithubProviderName is the name of the GitHub login provider
+const GithubProviderName = "github"
+
 //GithubExtraInfo struct for github credentials
 type GithubExtraInfo struct {
 	Login string
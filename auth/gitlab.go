@@ -0,0 +1,145 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// GitLabProviderName is the name of the GitLab login provider
+const GitLabProviderName = "gitlab"
+
+// gitlabGroupsPerPage is the page size of GitLab group list requests, it is the maximum allowed by the API
+const gitlabGroupsPerPage = 100
+
+// GitLab group access levels mapped to organization roles, from the highest to the lowest
+var gitlabAccessLevels = []struct {
+	level int
+	role  string
+}{
+	{level: 50, role: OrgRoleAdmin},  // Owner
+	{level: 30, role: OrgRoleMember}, // Developer
+	{level: 10, role: OrgRoleViewer}, // Guest
+}
+
+type gitlabUser struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+type gitlabGroup struct {
+	FullPath string `json:"full_path"`
+}
+
+type gitlabProvider struct {
+	url string
+}
+
+// newGitLabProvider returns a GitLab login provider for gitlab.com or a self-hosted GitLab instance.
+// Organizations are mapped from the GitLab groups of the user.
+func newGitLabProvider(url string, clientID string, clientSecret string) *oauth2Provider {
+	p := &gitlabProvider{url: strings.TrimSuffix(url, "/")}
+
+	return &oauth2Provider{
+		name:         GitLabProviderName,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		scopes:       []string{"read_user", "read_api"},
+		endpoint:     p.endpoint,
+		userInfo:     p.userInfo,
+	}
+}
+
+func (p *gitlabProvider) endpoint(ctx context.Context) (oauth2.Endpoint, error) {
+	return oauth2.Endpoint{
+		AuthURL:  p.url + "/oauth/authorize",
+		TokenURL: p.url + "/oauth/token",
+	}, nil
+}
+
+func (p *gitlabProvider) userInfo(ctx context.Context, client *http.Client, token *oauth2.Token) (*ExternalUserInfo, error) {
+	var user gitlabUser
+	if err := getJSON(ctx, client, p.url+"/api/v4/user", &user); err != nil {
+		return nil, errors.Wrap(err, "failed to get GitLab user")
+	}
+
+	organizations, err := p.organizations(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExternalUserInfo{
+		UID:           fmt.Sprint(user.ID),
+		Login:         user.Username,
+		Name:          user.Name,
+		Email:         user.Email,
+		Image:         user.AvatarURL,
+		Organizations: organizations,
+	}, nil
+}
+
+// organizations maps the groups of the user to organizations with the role matching the access level in the group
+func (p *gitlabProvider) organizations(ctx context.Context, client *http.Client) ([]*Organization, error) {
+	var organizations []*Organization
+	seen := make(map[string]bool)
+
+	for _, accessLevel := range gitlabAccessLevels {
+		groups, err := p.groups(ctx, client, accessLevel.level)
+		if err != nil {
+			return nil, err
+		}
+
+		// Groups are listed with the highest access level first, so the first role found is kept
+		for _, group := range groups {
+			name := strings.Replace(group.FullPath, "/", "-", -1)
+			if name == "" || seen[name] {
+				continue
+			}
+
+			seen[name] = true
+			organizations = append(organizations, &Organization{Name: name, Role: accessLevel.role})
+		}
+	}
+
+	return organizations, nil
+}
+
+// groups lists every group of the user with at least the given access level, page by page
+func (p *gitlabProvider) groups(ctx context.Context, client *http.Client, minAccessLevel int) ([]gitlabGroup, error) {
+	var groups []gitlabGroup
+
+	for page := 1; ; page++ {
+		var pageGroups []gitlabGroup
+		url := fmt.Sprintf("%s/api/v4/groups?min_access_level=%d&per_page=%d&page=%d", p.url, minAccessLevel, gitlabGroupsPerPage, page)
+		if err := getJSON(ctx, client, url, &pageGroups); err != nil {
+			return nil, errors.Wrap(err, "failed to list GitLab groups")
+		}
+
+		groups = append(groups, pageGroups...)
+
+		if len(pageGroups) < gitlabGroupsPerPage {
+			return groups, nil
+		}
+	}
+}
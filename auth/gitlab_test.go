@@ -0,0 +1,87 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestGitLabProvider_Organizations(t *testing.T) {
+	// groups by minimum access level
+	groups := map[string][]gitlabGroup{
+		"50": {{FullPath: "owned/subgroup"}},
+		"30": {{FullPath: "owned/subgroup"}, {FullPath: "developed"}},
+	}
+	for i := 0; i < gitlabGroupsPerPage; i++ {
+		groups["10"] = append(groups["10"], gitlabGroup{FullPath: fmt.Sprint("guest-", i)})
+	}
+	groups["10"] = append(groups["10"], gitlabGroup{FullPath: "last"})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/groups" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		levelGroups := groups[r.URL.Query().Get("min_access_level")]
+
+		start, end := (page-1)*perPage, page*perPage
+		if start > len(levelGroups) {
+			start = len(levelGroups)
+		}
+		if end > len(levelGroups) {
+			end = len(levelGroups)
+		}
+
+		json.NewEncoder(w).Encode(levelGroups[start:end])
+	}))
+	defer server.Close()
+
+	p := &gitlabProvider{url: server.URL}
+
+	organizations, err := p.organizations(context.Background(), server.Client())
+	if err != nil {
+		t.Fatal("unexpected error: ", err.Error())
+	}
+
+	if expected := gitlabGroupsPerPage + 3; len(organizations) != expected {
+		t.Fatalf("expected %d organizations, got %d", expected, len(organizations))
+	}
+
+	roles := make(map[string]string, len(organizations))
+	for _, organization := range organizations {
+		roles[organization.Name] = organization.Role
+	}
+
+	expectedRoles := map[string]string{
+		"owned-subgroup": OrgRoleAdmin,
+		"developed":      OrgRoleMember,
+		"guest-0":        OrgRoleViewer,
+		"last":           OrgRoleViewer,
+	}
+	for name, role := range expectedRoles {
+		if roles[name] != role {
+			t.Errorf("expected role %q in organization %s, got %q", role, name, roles[name])
+		}
+	}
+}
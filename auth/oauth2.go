@@ -0,0 +1,240 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/qor/auth"
+	"github.com/qor/auth/auth_identity"
+	"github.com/qor/auth/claims"
+	"github.com/qor/qor/utils"
+	"golang.org/x/oauth2"
+)
+
+// ExternalUserInfo holds the identity of a user logged in with an OIDC or GitLab provider
+type ExternalUserInfo struct {
+	UID   string
+	Login string
+	Name  string
+	Email string
+	Image string
+
+	// Organizations the user is a member of with the role in them, mapped from groups
+	Organizations []*Organization
+}
+
+// oauth2Provider is a login provider using the OAuth2 authorization code flow.
+// The provider specific parts are getting the endpoints and the user info with the access token.
+type oauth2Provider struct {
+	name         string
+	clientID     string
+	clientSecret string
+	scopes       []string
+
+	endpoint func(ctx context.Context) (oauth2.Endpoint, error)
+	userInfo func(ctx context.Context, client *http.Client, token *oauth2.Token) (*ExternalUserInfo, error)
+}
+
+// GetName returns the name of the provider
+func (p *oauth2Provider) GetName() string {
+	return p.name
+}
+
+// ConfigAuth implements the auth.Provider interface
+func (p *oauth2Provider) ConfigAuth(*auth.Auth) {}
+
+// Login redirects the user to the authorization endpoint of the provider
+func (p *oauth2Provider) Login(context *auth.Context) {
+	config, err := p.oauthConfig(context)
+	if err != nil {
+		log.Errorf("failed to get %s OAuth2 config: %s", p.name, err.Error())
+		http.Error(context.Writer, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	state := claims.Claims{}
+	state.Subject = "state"
+	signedState := context.Auth.SessionStorer.SignedToken(&state)
+
+	http.Redirect(context.Writer, context.Request, config.AuthCodeURL(signedState), http.StatusFound)
+}
+
+// Logout implements the auth.Provider interface
+func (p *oauth2Provider) Logout(context *auth.Context) {}
+
+// Register is the same as login, users are registered on their first login
+func (p *oauth2Provider) Register(context *auth.Context) {
+	p.Login(context)
+}
+
+// Deregister implements the auth.Provider interface
+func (p *oauth2Provider) Deregister(context *auth.Context) {}
+
+// Callback handles the redirect from the authorization endpoint of the provider
+func (p *oauth2Provider) Callback(context *auth.Context) {
+	context.Auth.LoginHandler(context, p.authorize)
+}
+
+// ServeHTTP implements the auth.Provider interface
+func (p *oauth2Provider) ServeHTTP(context *auth.Context) {}
+
+func (p *oauth2Provider) oauthConfig(context *auth.Context) (*oauth2.Config, error) {
+	endpoint, err := p.endpoint(context.Request.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	req := context.Request
+	scheme := "http"
+	if req.TLS != nil || req.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	return &oauth2.Config{
+		ClientID:     p.clientID,
+		ClientSecret: p.clientSecret,
+		Endpoint:     endpoint,
+		RedirectURL:  scheme + "://" + req.Host + context.Auth.AuthURL(p.name+"/callback"),
+		Scopes:       p.scopes,
+	}, nil
+}
+
+// authorize exchanges the authorization code for a token, and logs in or registers the user
+func (p *oauth2Provider) authorize(context *auth.Context) (*claims.Claims, error) {
+	var (
+		schema       auth.Schema
+		authInfo     auth_identity.Basic
+		authIdentity = reflect.New(utils.ModelType(context.Auth.Config.AuthIdentityModel)).Interface()
+		req          = context.Request
+		tx           = context.Auth.GetDB(req)
+	)
+
+	state, err := context.Auth.SessionStorer.ValidateClaims(req.URL.Query().Get("state"))
+	if err != nil {
+		log.Info(req.RemoteAddr, err.Error())
+		return nil, err
+	}
+
+	if state.Valid() != nil || state.Subject != "state" {
+		log.Info(req.RemoteAddr, auth.ErrUnauthorized.Error())
+		return nil, auth.ErrUnauthorized
+	}
+
+	config, err := p.oauthConfig(context)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := config.Exchange(req.Context(), req.URL.Query().Get("code"))
+	if err != nil {
+		log.Info(req.RemoteAddr, err.Error())
+		return nil, err
+	}
+
+	userInfo, err := p.userInfo(req.Context(), config.Client(req.Context(), token), token)
+	if err != nil {
+		log.Info(req.RemoteAddr, err.Error())
+		return nil, err
+	}
+
+	authInfo.Provider = p.name
+	authInfo.UID = userInfo.UID
+
+	if !tx.Model(authIdentity).Where(authInfo).Scan(&authInfo).RecordNotFound() {
+		// Group memberships are synchronized on every login, memberships which are not listed anymore are removed
+		userID, err := strconv.ParseUint(authInfo.UserID, 10, 32)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid user id")
+		}
+
+		if err := addUserToOrganizations(&User{ID: uint(userID)}, tx, p.name, userInfo.Organizations); err != nil {
+			log.Warnf("failed to synchronize organizations of user %d: %s", userID, err.Error())
+		}
+
+		return authInfo.ToClaims(), nil
+	}
+
+	schema.Provider = p.name
+	schema.UID = userInfo.UID
+	schema.Name = userInfo.Name
+	schema.Email = userInfo.Email
+	schema.Image = userInfo.Image
+	schema.RawInfo = userInfo
+
+	_, userID, err := context.Auth.UserStorer.Save(&schema, context)
+	if err != nil {
+		return nil, err
+	}
+	authInfo.UserID = userID
+
+	if err = tx.Where(authInfo).FirstOrCreate(authIdentity).Error; err != nil {
+		log.Info(req.RemoteAddr, err.Error())
+		return nil, err
+	}
+
+	return authInfo.ToClaims(), nil
+}
+
+// groupsToOrganizations maps groups to organizations.
+// A group is mapped to the organization with the same name, the role can be given as a suffix: <organization>:<role>.
+// Leading slashes of group paths (Keycloak) are removed, the remaining slashes are replaced with dashes.
+func groupsToOrganizations(groups []string, defaultRole string) []*Organization {
+	organizations := make(map[string]*Organization)
+	var names []string
+
+	for _, group := range groups {
+		name, role := group, defaultRole
+		if i := strings.LastIndex(group, ":"); i >= 0 {
+			name, role = group[:i], group[i+1:]
+		}
+
+		name = strings.Replace(strings.TrimPrefix(name, "/"), "/", "-", -1)
+		if name == "" || !IsValidOrgRole(role) {
+			continue
+		}
+
+		if org, ok := organizations[name]; ok {
+			org.Role = higherOrgRole(org.Role, role)
+			continue
+		}
+
+		organizations[name] = &Organization{Name: name, Role: role}
+		names = append(names, name)
+	}
+
+	result := make([]*Organization, 0, len(names))
+	for _, name := range names {
+		result = append(result, organizations[name])
+	}
+
+	return result
+}
+
+// higherOrgRole returns the role with more permissions
+func higherOrgRole(role1 string, role2 string) string {
+	for _, role := range OrgRoles { // ordered by permissions
+		if role == role1 || role == role2 {
+			return role
+		}
+	}
+
+	return role1
+}
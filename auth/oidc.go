@@ -0,0 +1,198 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// OIDCProviderName is the name of the OpenID Connect login provider
+const OIDCProviderName = "oidc"
+
+// OIDCConfig holds the configuration of the OpenID Connect login provider
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+
+	// GroupsClaim is the name of the claim holding the groups of the user
+	GroupsClaim string
+
+	// DefaultRole is the role of the user in organizations mapped from groups without a role suffix
+	DefaultRole string
+
+	// Scopes are requested in addition to openid, profile and email
+	Scopes []string
+}
+
+// oidcDiscovery holds the endpoints of the OpenID Provider Metadata used by Pipeline
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+type oidcProvider struct {
+	config OIDCConfig
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+}
+
+// newOIDCProvider returns an OpenID Connect login provider.
+// Organizations are mapped from the groups claim of the user info.
+func newOIDCProvider(config OIDCConfig) *oauth2Provider {
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+
+	if config.DefaultRole == "" {
+		config.DefaultRole = OrgRoleMember
+	}
+
+	p := &oidcProvider{config: config}
+
+	return &oauth2Provider{
+		name:         OIDCProviderName,
+		clientID:     config.ClientID,
+		clientSecret: config.ClientSecret,
+		scopes:       append([]string{"openid", "profile", "email"}, config.Scopes...),
+		endpoint:     p.endpoint,
+		userInfo:     p.userInfo,
+	}
+}
+
+// discover fetches the OpenID Provider Metadata of the issuer, it is cached after the first successful request
+func (p *oidcProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	wellKnown := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+
+	var discovery oidcDiscovery
+	if err := getJSON(ctx, http.DefaultClient, wellKnown, &discovery); err != nil {
+		return nil, errors.Wrap(err, "failed to discover OpenID Connect provider")
+	}
+
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.UserinfoEndpoint == "" {
+		return nil, errors.Errorf("OpenID Connect provider %s is missing required endpoints", p.config.Issuer)
+	}
+
+	p.discovery = &discovery
+
+	return p.discovery, nil
+}
+
+func (p *oidcProvider) endpoint(ctx context.Context) (oauth2.Endpoint, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return oauth2.Endpoint{}, err
+	}
+
+	return oauth2.Endpoint{
+		AuthURL:  discovery.AuthorizationEndpoint,
+		TokenURL: discovery.TokenEndpoint,
+	}, nil
+}
+
+func (p *oidcProvider) userInfo(ctx context.Context, client *http.Client, token *oauth2.Token) (*ExternalUserInfo, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := getJSON(ctx, client, discovery.UserinfoEndpoint, &claims); err != nil {
+		return nil, errors.Wrap(err, "failed to get user info")
+	}
+
+	info := &ExternalUserInfo{
+		UID:   stringClaim(claims, "sub"),
+		Login: stringClaim(claims, "preferred_username"),
+		Name:  stringClaim(claims, "name"),
+		Email: stringClaim(claims, "email"),
+		Image: stringClaim(claims, "picture"),
+	}
+
+	if info.UID == "" {
+		return nil, errors.New("user info is missing the sub claim")
+	}
+
+	if info.Login == "" {
+		info.Login = strings.Split(info.Email, "@")[0]
+	}
+
+	if info.Login == "" {
+		return nil, errors.New("user info is missing the preferred_username and email claims")
+	}
+
+	var groups []string
+	switch claim := claims[p.config.GroupsClaim].(type) {
+	case []interface{}:
+		for _, group := range claim {
+			if group, ok := group.(string); ok {
+				groups = append(groups, group)
+			}
+		}
+	case string:
+		groups = strings.Split(claim, ",")
+	}
+
+	info.Organizations = groupsToOrganizations(groups, p.config.DefaultRole)
+
+	return info, nil
+}
+
+func stringClaim(claims map[string]interface{}, name string) string {
+	if value, ok := claims[name].(string); ok {
+		return value
+	}
+
+	return ""
+}
+
+// getJSON requests an URL with the client and decodes the JSON response
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,127 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+// newOIDCStub returns an OpenID Connect provider stub serving the discovery document and the user info
+func newOIDCStub(userInfo map[string]interface{}) *httptest.Server {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"userinfo_endpoint":      server.URL + "/userinfo",
+		})
+	})
+
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(userInfo)
+	})
+
+	return server
+}
+
+func TestOIDCProvider(t *testing.T) {
+	server := newOIDCStub(map[string]interface{}{
+		"sub":                "248289761001",
+		"preferred_username": "janedoe",
+		"name":               "Jane Doe",
+		"email":              "janedoe@example.com",
+		"groups":             []string{"/engineering/platform:admin", "sales", "finance:owner"},
+	})
+	defer server.Close()
+
+	provider := newOIDCProvider(OIDCConfig{Issuer: server.URL, DefaultRole: OrgRoleViewer})
+
+	ctx := context.Background()
+
+	endpoint, err := provider.endpoint(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if endpoint.AuthURL != server.URL+"/authorize" || endpoint.TokenURL != server.URL+"/token" {
+		t.Errorf("unexpected endpoint: %+v", endpoint)
+	}
+
+	token := &oauth2.Token{AccessToken: "access-token", TokenType: "Bearer"}
+	client := (&oauth2.Config{}).Client(ctx, token)
+
+	info, err := provider.userInfo(ctx, client, token)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if info.UID != "248289761001" || info.Login != "janedoe" || info.Name != "Jane Doe" || info.Email != "janedoe@example.com" {
+		t.Errorf("unexpected user info: %+v", info)
+	}
+
+	expected := []*Organization{
+		{Name: "engineering-platform", Role: OrgRoleAdmin},
+		{Name: "sales", Role: OrgRoleViewer},
+	}
+	if !reflect.DeepEqual(info.Organizations, expected) {
+		t.Errorf("unexpected organizations: %+v", info.Organizations)
+	}
+}
+
+func TestGroupsToOrganizations(t *testing.T) {
+	cases := []struct {
+		name     string
+		groups   []string
+		expected []*Organization
+	}{
+		{
+			name:     "default role",
+			groups:   []string{"dev"},
+			expected: []*Organization{{Name: "dev", Role: OrgRoleMember}},
+		},
+		{
+			name:     "higher role wins",
+			groups:   []string{"dev:viewer", "dev:admin", "dev"},
+			expected: []*Organization{{Name: "dev", Role: OrgRoleAdmin}},
+		},
+		{
+			name:     "invalid groups are skipped",
+			groups:   []string{"", "/", "dev:unknown", "ops:viewer"},
+			expected: []*Organization{{Name: "ops", Role: OrgRoleViewer}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			organizations := groupsToOrganizations(tc.groups, OrgRoleMember)
+			if !reflect.DeepEqual(organizations, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, organizations)
+			}
+		})
+	}
+}
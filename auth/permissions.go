@@ -0,0 +1,301 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/pipeline/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// Resource types permissions can be granted on
+const (
+	ClusterResource = "cluster"
+	SecretResource  = "secret"
+	BucketResource  = "bucket"
+)
+
+// Subject types permissions can be granted to
+const (
+	UserSubject = "user"
+	TeamSubject = "team"
+)
+
+// resourcePaths maps the API path segments of resources to resource types
+var resourcePaths = map[string]string{
+	"clusters": ClusterResource,
+	"secrets":  SecretResource,
+	"buckets":  BucketResource,
+}
+
+// resourcePath matches organization resource API paths: /api/v1/orgs/<orgid>/<resources>[/<id>[/...]]
+var resourcePath = regexp.MustCompile(`^/api/v1/orgs/(\d+)/(clusters|secrets|buckets)(?:/([^/]+))?(/.*)?$`)
+
+// ResourcePermission grants a role on a single organization resource to a user or a team
+type ResourcePermission struct {
+	ID             uint      `gorm:"primary_key" json:"id"`
+	CreatedAt      time.Time `json:"createdAt"`
+	OrganizationID uint      `gorm:"unique_index:idx_resource_permission" json:"-"`
+	SubjectType    string    `gorm:"unique_index:idx_resource_permission" json:"subjectType" binding:"required,eq=user|eq=team"`
+	Subject        string    `gorm:"unique_index:idx_resource_permission" json:"subject" binding:"required"`
+	ResourceType   string    `gorm:"unique_index:idx_resource_permission" json:"resourceType" binding:"required,eq=cluster|eq=secret|eq=bucket"`
+	ResourceID     string    `gorm:"unique_index:idx_resource_permission" json:"resourceId" binding:"required"`
+	Role           string    `json:"role" binding:"required,eq=admin|eq=member|eq=viewer"`
+	CreatedBy      uint      `json:"createdBy"`
+}
+
+// TableName sets the ResourcePermission table name
+func (ResourcePermission) TableName() string {
+	return "resource_permissions"
+}
+
+// TeamMember describes the membership of a user in a team of an organization
+type TeamMember struct {
+	OrganizationID uint   `gorm:"primary_key;auto_increment:false" json:"-"`
+	Team           string `gorm:"primary_key" json:"team"`
+	UserID         uint   `gorm:"primary_key;auto_increment:false" json:"userId"`
+}
+
+// TableName sets the TeamMember table name
+func (TeamMember) TableName() string {
+	return "team_members"
+}
+
+// GrantResourcePermission creates or updates a resource permission.
+func GrantResourcePermission(permission *ResourcePermission) error {
+	err := config.DB().Where(ResourcePermission{
+		OrganizationID: permission.OrganizationID,
+		SubjectType:    permission.SubjectType,
+		Subject:        permission.Subject,
+		ResourceType:   permission.ResourceType,
+		ResourceID:     permission.ResourceID,
+	}).Assign(ResourcePermission{
+		Role:      permission.Role,
+		CreatedBy: permission.CreatedBy,
+	}).FirstOrCreate(permission).Error
+
+	return errors.Wrap(err, "could not save resource permission")
+}
+
+// ListResourcePermissions returns the resource permissions of an organization, optionally filtered by resource.
+func ListResourcePermissions(orgID uint, resourceType string, resourceID string) ([]ResourcePermission, error) {
+	query := ResourcePermission{
+		OrganizationID: orgID,
+		ResourceType:   resourceType,
+		ResourceID:     resourceID,
+	}
+
+	permissions := []ResourcePermission{}
+	err := config.DB().Where(&query).Order("resource_type, resource_id, subject_type, subject").Find(&permissions).Error
+
+	return permissions, errors.Wrap(err, "could not list resource permissions")
+}
+
+// RevokeResourcePermission deletes a resource permission of an organization.
+func RevokeResourcePermission(orgID uint, permissionID uint) error {
+	permission := ResourcePermission{ID: permissionID, OrganizationID: orgID}
+
+	db := config.DB()
+	if err := db.Where(&permission).First(&permission).Error; err != nil {
+		return err
+	}
+
+	return errors.Wrap(db.Delete(&permission).Error, "could not delete resource permission")
+}
+
+// RevokeResourcePermissionsOf deletes every permission granted on a resource, eg. when it is deleted.
+func RevokeResourcePermissionsOf(orgID uint, resourceType string, resourceID string) error {
+	err := config.DB().Where(&ResourcePermission{
+		OrganizationID: orgID,
+		ResourceType:   resourceType,
+		ResourceID:     resourceID,
+	}).Delete(ResourcePermission{}).Error
+
+	return errors.Wrap(err, "could not delete resource permissions")
+}
+
+// ListTeamMembers returns the team memberships of an organization.
+func ListTeamMembers(orgID uint) ([]TeamMember, error) {
+	members := []TeamMember{}
+	err := config.DB().Where(&TeamMember{OrganizationID: orgID}).Order("team, user_id").Find(&members).Error
+
+	return members, errors.Wrap(err, "could not list team members")
+}
+
+// AddTeamMember adds a user to a team of an organization.
+func AddTeamMember(orgID uint, team string, userID uint) error {
+	member := TeamMember{OrganizationID: orgID, Team: team, UserID: userID}
+
+	return errors.Wrap(config.DB().FirstOrCreate(&member, member).Error, "could not add team member")
+}
+
+// RemoveTeamMember removes a user from a team of an organization.
+func RemoveTeamMember(orgID uint, team string, userID uint) error {
+	err := config.DB().Delete(&TeamMember{OrganizationID: orgID, Team: team, UserID: userID}).Error
+
+	return errors.Wrap(err, "could not remove team member")
+}
+
+// findResourcePermissions returns the permissions granted to a user directly or through its teams on resources of an organization.
+var findResourcePermissions = userResourcePermissions
+
+// userResourcePermissions returns the permissions granted to a user directly or through its teams on resources of an organization.
+func userResourcePermissions(userID uint, orgID uint, resourceType string, resourceID string) ([]ResourcePermission, error) {
+	db := config.DB()
+
+	var teams []string
+	err := db.Model(&TeamMember{}).Where(&TeamMember{OrganizationID: orgID, UserID: userID}).Pluck("team", &teams).Error
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.Where(&ResourcePermission{OrganizationID: orgID, ResourceType: resourceType, ResourceID: resourceID})
+
+	if len(teams) > 0 {
+		query = query.Where(
+			"(subject_type = ? AND subject = ?) OR (subject_type = ? AND subject IN (?))",
+			UserSubject, fmt.Sprint(userID), TeamSubject, teams,
+		)
+	} else {
+		query = query.Where("subject_type = ? AND subject = ?", UserSubject, fmt.Sprint(userID))
+	}
+
+	var permissions []ResourcePermission
+	err = query.Find(&permissions).Error
+
+	return permissions, err
+}
+
+// checkResourcePermission checks whether the user is allowed to access an organization resource by a resource permission.
+func checkResourcePermission(user *User, path string, method string) bool {
+	if user == nil || user.ID == 0 {
+		return false
+	}
+
+	match := resourcePath.FindStringSubmatch(trimBasePath(path))
+	if match == nil {
+		return false
+	}
+
+	orgID, err := strconv.ParseUint(match[1], 10, 32)
+	if err != nil {
+		return false
+	}
+
+	resourceType, resourceID, subPath := resourcePaths[match[2]], match[3], match[4]
+
+	permissions, err := findResourcePermissions(user.ID, uint(orgID), resourceType, resourceID)
+	if err != nil {
+		log.Errorf("failed to get resource permissions of user %d: %s", user.ID, err.Error())
+		return false
+	}
+
+	// The resource list is available to anyone with a permission on any resource of its type, it is filtered by the handlers
+	if resourceID == "" {
+		return len(permissions) > 0 && isReadOnlyMethod(method)
+	}
+
+	for _, permission := range permissions {
+		if permissionAllows(permission, method, subPath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// permissionAllows checks whether a resource permission allows an action on the resource (empty sub path) or on its sub resources.
+func permissionAllows(permission ResourcePermission, action string, subPath string) bool {
+	switch permission.Role {
+	case OrgRoleAdmin:
+		return true
+	case OrgRoleMember:
+		// members cannot delete the resource itself
+		return action != http.MethodDelete || subPath != ""
+	case OrgRoleViewer:
+		return isReadOnlyMethod(action)
+	}
+
+	return false
+}
+
+// GetAccessibleResourceIDs returns the IDs of the resources of an organization the user of the request has access to.
+// If the user has access to all of them through an organization role, all is true.
+func GetAccessibleResourceIDs(r *http.Request, orgID uint, resourceType string) (ids map[string]bool, all bool, err error) {
+	return accessibleResourceIDs(GetCurrentUser(r), orgID, resourceType, http.MethodGet)
+}
+
+// GetFullAccessResourceIDs returns the IDs of the resources of an organization the user of the request has full access to,
+// eg. the secrets whose values can be read or installed into a cluster.
+// If the user has full access to all of them through an organization role, all is true.
+func GetFullAccessResourceIDs(r *http.Request, orgID uint, resourceType string) (ids map[string]bool, all bool, err error) {
+	return accessibleResourceIDs(GetCurrentUser(r), orgID, resourceType, fullAccessAction)
+}
+
+// accessibleResourceIDs returns the IDs of the resources of an organization the user is allowed to do an action on.
+func accessibleResourceIDs(user *User, orgID uint, resourceType string, action string) (ids map[string]bool, all bool, err error) {
+	if user == nil {
+		return nil, false, nil
+	}
+
+	subject := user.IDString()
+	if user.ID == 0 {
+		subject = user.Login // Drone virtual users
+	}
+
+	collectionPath := fmt.Sprintf("%s/api/v1/orgs/%d/%ss", viper.GetString("pipeline.basepath"), orgID, resourceType)
+	if enforcer.Enforce(subject, collectionPath, action) {
+		return nil, true, nil
+	}
+
+	if user.ID == 0 {
+		return nil, false, nil
+	}
+
+	permissions, err := findResourcePermissions(user.ID, orgID, resourceType, "")
+	if err != nil {
+		return nil, false, err
+	}
+
+	ids = make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		if permissionAllows(permission, action, "/") {
+			ids[permission.ResourceID] = true
+		}
+	}
+
+	return ids, false, nil
+}
+
+func isReadOnlyMethod(method string) bool {
+	for _, readOnlyMethod := range readOnlyMethods {
+		if method == readOnlyMethod {
+			return true
+		}
+	}
+
+	return false
+}
+
+func trimBasePath(path string) string {
+	return strings.TrimPrefix(path, viper.GetString("pipeline.basepath"))
+}
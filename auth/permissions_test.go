@@ -0,0 +1,166 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin"
+	qorAuth "github.com/qor/auth"
+)
+
+// setupResourcePermissions replaces the policies and the stored resource permissions for a test
+func setupResourcePermissions(permissions []ResourcePermission) func() {
+	originalEnforcer, originalFind := enforcer, findResourcePermissions
+
+	enforcer = casbin.NewSyncedEnforcer(casbin.NewModel(modelDefinition))
+	AddOrgRoles(1)
+	AddOrgRoleForUser("1", OrgRoleMember, 1)
+	AddOrgRoleForUser("2", OrgRoleViewer, 1)
+
+	findResourcePermissions = func(userID uint, orgID uint, resourceType string, resourceID string) ([]ResourcePermission, error) {
+		var found []ResourcePermission
+		for _, permission := range permissions {
+			if permission.Subject == (&User{ID: userID}).IDString() && permission.OrganizationID == orgID &&
+				permission.ResourceType == resourceType && (resourceID == "" || permission.ResourceID == resourceID) {
+				found = append(found, permission)
+			}
+		}
+
+		return found, nil
+	}
+
+	return func() {
+		enforcer, findResourcePermissions = originalEnforcer, originalFind
+	}
+}
+
+func TestCheckResourcePermission(t *testing.T) {
+	defer setupResourcePermissions([]ResourcePermission{
+		{OrganizationID: 1, SubjectType: UserSubject, Subject: "3", ResourceType: ClusterResource, ResourceID: "10", Role: OrgRoleAdmin},
+		{OrganizationID: 1, SubjectType: UserSubject, Subject: "3", ResourceType: ClusterResource, ResourceID: "11", Role: OrgRoleMember},
+		{OrganizationID: 1, SubjectType: UserSubject, Subject: "3", ResourceType: ClusterResource, ResourceID: "12", Role: OrgRoleViewer},
+		{OrganizationID: 1, SubjectType: UserSubject, Subject: "3", ResourceType: SecretResource, ResourceID: "abc", Role: OrgRoleViewer},
+	})()
+
+	cases := []struct {
+		method   string
+		path     string
+		expected bool
+	}{
+		{http.MethodGet, "/api/v1/orgs/1/clusters", true},
+		{http.MethodPost, "/api/v1/orgs/1/clusters", false},
+		{http.MethodDelete, "/api/v1/orgs/1/clusters/10", true},
+		{http.MethodGet, "/api/v1/orgs/1/clusters/10/config", true},
+		{http.MethodPut, "/api/v1/orgs/1/clusters/11", true},
+		{http.MethodDelete, "/api/v1/orgs/1/clusters/11", false},
+		{http.MethodDelete, "/api/v1/orgs/1/clusters/11/deployments/release", true},
+		{fullAccessAction, "/api/v1/orgs/1/clusters/11/config", true},
+		{http.MethodGet, "/api/v1/orgs/1/clusters/12", true},
+		{http.MethodPut, "/api/v1/orgs/1/clusters/12", false},
+		{fullAccessAction, "/api/v1/orgs/1/clusters/12/config", false},
+		{http.MethodGet, "/api/v1/orgs/1/clusters/13", false},
+		{fullAccessAction, "/api/v1/orgs/1/secrets/abc", false},
+		{http.MethodGet, "/api/v1/orgs/1/secrets/abc/versions", true},
+		{http.MethodGet, "/api/v1/orgs/1/buckets", false},
+		{http.MethodGet, "/api/v1/orgs/2/clusters/10", false},
+		{http.MethodGet, "/api/v1/orgs/1/users", false},
+	}
+
+	user := &User{ID: 3}
+
+	for _, tc := range cases {
+		if actual := checkResourcePermission(user, tc.path, tc.method); actual != tc.expected {
+			t.Errorf("%s %s: expected %t, got %t", tc.method, tc.path, tc.expected, actual)
+		}
+	}
+
+	if checkResourcePermission(&User{Login: "drone"}, "/api/v1/orgs/1/clusters/10", http.MethodGet) {
+		t.Error("expected virtual users to have no resource permissions")
+	}
+}
+
+func TestCheckPermission_ClusterName(t *testing.T) {
+	defer setupResourcePermissions([]ResourcePermission{
+		{OrganizationID: 1, SubjectType: UserSubject, Subject: "3", ResourceType: ClusterResource, ResourceID: "5", Role: OrgRoleAdmin},
+	})()
+
+	cases := []struct {
+		userID   uint
+		url      string
+		expected bool
+	}{
+		{3, "/api/v1/orgs/1/clusters/5", true},
+		{3, "/api/v1/orgs/1/clusters/5?field=id", true},
+		{3, "/api/v1/orgs/1/clusters/5?field=name", false},
+		{1, "/api/v1/orgs/1/clusters/5?field=name", true},
+	}
+
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+		r = r.WithContext(context.WithValue(r.Context(), qorAuth.CurrentUser, &User{ID: tc.userID}))
+
+		if actual := (&BearerAuthorizer{enforcer: enforcer}).CheckPermission(r); actual != tc.expected {
+			t.Errorf("user %d %s: expected %t, got %t", tc.userID, tc.url, tc.expected, actual)
+		}
+	}
+}
+
+func TestAccessibleResourceIDs(t *testing.T) {
+	defer setupResourcePermissions([]ResourcePermission{
+		{OrganizationID: 1, SubjectType: UserSubject, Subject: "2", ResourceType: SecretResource, ResourceID: "abc", Role: OrgRoleMember},
+		{OrganizationID: 1, SubjectType: UserSubject, Subject: "3", ResourceType: SecretResource, ResourceID: "abc", Role: OrgRoleMember},
+		{OrganizationID: 1, SubjectType: UserSubject, Subject: "3", ResourceType: SecretResource, ResourceID: "def", Role: OrgRoleViewer},
+	})()
+
+	cases := []struct {
+		name        string
+		user        *User
+		action      string
+		expectedIDs map[string]bool
+		expectedAll bool
+	}{
+		{name: "member list", user: &User{ID: 1}, action: http.MethodGet, expectedAll: true},
+		{name: "member full access", user: &User{ID: 1}, action: fullAccessAction, expectedAll: true},
+		{name: "viewer list", user: &User{ID: 2}, action: http.MethodGet, expectedAll: true},
+		{name: "viewer full access", user: &User{ID: 2}, action: fullAccessAction, expectedIDs: map[string]bool{"abc": true}},
+		{name: "granted list", user: &User{ID: 3}, action: http.MethodGet, expectedIDs: map[string]bool{"abc": true, "def": true}},
+		{name: "granted full access", user: &User{ID: 3}, action: fullAccessAction, expectedIDs: map[string]bool{"abc": true}},
+		{name: "no permissions", user: &User{ID: 4}, action: http.MethodGet, expectedIDs: map[string]bool{}},
+		{name: "virtual user", user: &User{Login: "drone"}, action: http.MethodGet},
+		{name: "no user", action: http.MethodGet},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ids, all, err := accessibleResourceIDs(tc.user, 1, SecretResource, tc.action)
+			if err != nil {
+				t.Fatal("unexpected error: ", err.Error())
+			}
+
+			if all != tc.expectedAll {
+				t.Errorf("expected all to be %t, got %t", tc.expectedAll, all)
+			}
+
+			if !reflect.DeepEqual(ids, tc.expectedIDs) {
+				t.Errorf("expected IDs %v, got %v", tc.expectedIDs, ids)
+			}
+		})
+	}
+}
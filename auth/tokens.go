@@ -0,0 +1,322 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	bauth "github.com/banzaicloud/bank-vaults/auth"
+	"github.com/banzaicloud/pipeline/config"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// Token scope access levels
+const (
+	ScopeNone  = "none"
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// tokenLastUsedResolution limits how often the last used time of a token is updated
+const tokenLastUsedResolution = time.Minute
+
+// tokenScope matches scopes like clusters:read
+var tokenScope = regexp.MustCompile(`^([a-z]+):(none|read|write)$`)
+
+// orgResourcePath matches organization API paths: /api/v1/orgs/<orgid>[/<resources>[/<id>[/<subresources>]]]
+var orgResourcePath = regexp.MustCompile(`^/(?:api/v1|dashboard)/orgs/(\d+)(?:/([^/]+)(?:/[^/]+(?:/([^/]+))?)?)?`)
+
+// restrictedTokenPath matches the API paths outside of organizations restricted tokens are allowed to read
+var restrictedTokenPath = regexp.MustCompile(`^/api/v1/(orgs|allowed/secrets(/[^/]+)?)/?$`)
+
+// clusterSubresourceScopes are cluster subresources which have their own scope
+var clusterSubresourceScopes = map[string]string{
+	"deployments": "deployments",
+	"hpa":         "deployments",
+	"helminit":    "deployments",
+	"secrets":     "secrets",
+}
+
+// APIToken holds the restrictions and usage of an API token, the token itself is stored in the token store.
+// Tokens created before restrictions were introduced have no record and are not restricted.
+type APIToken struct {
+	ID            string     `gorm:"primary_key"`
+	UserID        string     `gorm:"index"`
+	ExpiresAt     *time.Time `gorm:"index"`
+	LastUsedAt    *time.Time
+	Organizations string // comma separated organization IDs
+	Scopes        string // comma separated resource:level pairs
+}
+
+// TableName sets the APIToken table name
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+// OrganizationIDs returns the organizations the token is restricted to.
+func (t *APIToken) OrganizationIDs() []uint {
+	var ids []uint
+	for _, id := range splitList(t.Organizations) {
+		if orgID, err := strconv.ParseUint(id, 10, 32); err == nil {
+			ids = append(ids, uint(orgID))
+		}
+	}
+
+	return ids
+}
+
+// ScopeList returns the scopes of the token.
+func (t *APIToken) ScopeList() []string {
+	return splitList(t.Scopes)
+}
+
+// IsRestricted checks whether the token expires or is restricted to organizations or scopes.
+func (t *APIToken) IsRestricted() bool {
+	return t.ExpiresAt != nil || t.Organizations != "" || t.Scopes != ""
+}
+
+// TokenRequest describes an API token request
+type TokenRequest struct {
+	Name        string `json:"name,omitempty"`
+	VirtualUser string `json:"virtualUser,omitempty"`
+
+	// ExpiresAt is the expiry time of the token, it never expires if not specified
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+
+	// Organizations restricts the token to the given organizations
+	Organizations []uint `json:"organizations,omitempty"`
+
+	// Scopes restrict the access of the token to resources, eg. clusters:read, deployments:write, secrets:none
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// TokenResponse describes an API token with its restrictions and usage
+type TokenResponse struct {
+	*bauth.Token
+	ExpiresAt     *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt    *time.Time `json:"lastUsedAt,omitempty"`
+	Organizations []uint     `json:"organizations,omitempty"`
+	Scopes        []string   `json:"scopes,omitempty"`
+}
+
+// Validate validates the restrictions of a token request.
+func (r *TokenRequest) Validate() error {
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(time.Now()) {
+		return errors.New("expiry time must be in the future")
+	}
+
+	for _, scope := range r.Scopes {
+		if !tokenScope.MatchString(scope) {
+			return errors.Errorf("invalid scope %q, it should be in resource:none|read|write format", scope)
+		}
+	}
+
+	return nil
+}
+
+// saveAPIToken stores the restrictions of a token.
+func saveAPIToken(tokenID string, userID string, request *TokenRequest) error {
+	organizations := make([]string, 0, len(request.Organizations))
+	for _, orgID := range request.Organizations {
+		organizations = append(organizations, fmt.Sprint(orgID))
+	}
+
+	token := APIToken{
+		ID:            tokenID,
+		UserID:        userID,
+		ExpiresAt:     request.ExpiresAt,
+		Organizations: strings.Join(organizations, ","),
+		Scopes:        strings.Join(request.Scopes, ","),
+	}
+
+	return errors.Wrap(config.DB().Create(&token).Error, "failed to save token restrictions")
+}
+
+// getAPIToken returns the restrictions of a token, nil if it has none.
+var getAPIToken = func(tokenID string) (*APIToken, error) {
+	var token APIToken
+	err := config.DB().Where(&APIToken{ID: tokenID}).First(&token).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// listAPITokens returns the restrictions of the tokens of a user by token ID.
+func listAPITokens(userID string) (map[string]*APIToken, error) {
+	var tokens []*APIToken
+	if err := config.DB().Where(&APIToken{UserID: userID}).Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+
+	tokensByID := make(map[string]*APIToken, len(tokens))
+	for _, token := range tokens {
+		tokensByID[token.ID] = token
+	}
+
+	return tokensByID, nil
+}
+
+// newTokenResponse merges a stored token with its restrictions.
+func newTokenResponse(token *bauth.Token, apiToken *APIToken) *TokenResponse {
+	response := &TokenResponse{Token: token}
+	if apiToken != nil {
+		response.ExpiresAt = apiToken.ExpiresAt
+		response.LastUsedAt = apiToken.LastUsedAt
+		response.Organizations = apiToken.OrganizationIDs()
+		response.Scopes = apiToken.ScopeList()
+	}
+
+	return response
+}
+
+// markTokenUsed updates the last used time of a token.
+func markTokenUsed(tokenID string) {
+	now := time.Now()
+	err := config.DB().Model(&APIToken{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", tokenID, now.Add(-tokenLastUsedResolution)).
+		Update("last_used_at", now).Error
+	if err != nil {
+		log.Warnf("failed to update last used time of token %s: %s", tokenID, err.Error())
+	}
+}
+
+// checkTokenRestrictions checks whether the token of the request is allowed to access the requested path.
+// Requests authenticated by a session or an unrestricted token are always allowed.
+func checkTokenRestrictions(r *http.Request) bool {
+	user := GetCurrentUser(r)
+	if user == nil || user.TokenID == "" {
+		return true
+	}
+
+	token, err := getAPIToken(user.TokenID)
+	if err != nil {
+		log.Errorf("failed to get token restrictions: %s", err.Error())
+		return false
+	}
+
+	if token == nil {
+		return true
+	}
+
+	markTokenUsed(token.ID)
+
+	return tokenAllows(token, r.Method, trimBasePath(r.URL.Path), time.Now())
+}
+
+// tokenAllows checks whether a token allows a request to an API path.
+func tokenAllows(token *APIToken, method string, path string, now time.Time) bool {
+	if token.ExpiresAt != nil && token.ExpiresAt.Before(now) {
+		return false
+	}
+
+	match := orgResourcePath.FindStringSubmatch(path)
+	if match == nil {
+		// Restricted tokens would be able to create unrestricted tokens through the token API,
+		// so they can only read a few endpoints outside of organizations.
+		return !token.IsRestricted() || (isReadOnlyMethod(method) && restrictedTokenPath.MatchString(path))
+	}
+
+	if orgIDs := token.OrganizationIDs(); len(orgIDs) > 0 {
+		orgID, _ := strconv.ParseUint(match[1], 10, 32)
+
+		allowed := false
+		for _, id := range orgIDs {
+			if id == uint(orgID) {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			return false
+		}
+	}
+
+	resource := match[2]
+	if scope, ok := clusterSubresourceScopes[match[3]]; ok && resource == "clusters" {
+		resource = scope
+	}
+
+	if resource == "" {
+		resource = "orgs"
+	}
+
+	for _, scope := range token.ScopeList() {
+		parts := strings.SplitN(scope, ":", 2)
+		if len(parts) != 2 || parts[0] != resource {
+			continue
+		}
+
+		switch parts[1] {
+		case ScopeNone:
+			return false
+		case ScopeRead:
+			return isReadOnlyMethod(method)
+		case ScopeWrite:
+			return true
+		}
+	}
+
+	// resources without a scope are not restricted
+	return true
+}
+
+// PurgeExpiredTokens deletes the expired tokens from the token store.
+func PurgeExpiredTokens() error {
+	var tokens []*APIToken
+	err := config.DB().Where("expires_at < ?", time.Now()).Find(&tokens).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to list expired tokens")
+	}
+
+	for _, token := range tokens {
+		if err := TokenStore.Revoke(token.UserID, token.ID); err != nil {
+			return errors.Wrapf(err, "failed to revoke expired token %s", token.ID)
+		}
+
+		if err := config.DB().Delete(token).Error; err != nil {
+			return errors.Wrapf(err, "failed to delete expired token %s", token.ID)
+		}
+
+		log.Infof("purged expired token %s", token.ID)
+	}
+
+	return nil
+}
+
+// deleteAPIToken deletes the restrictions of a revoked token.
+func deleteAPIToken(tokenID string) error {
+	return config.DB().Delete(&APIToken{ID: tokenID}).Error
+}
+
+func splitList(list string) []string {
+	if list == "" {
+		return nil
+	}
+
+	return strings.Split(list, ",")
+}
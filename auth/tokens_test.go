@@ -0,0 +1,182 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	bauth "github.com/banzaicloud/bank-vaults/auth"
+	"github.com/gin-gonic/gin"
+	qorAuth "github.com/qor/auth"
+)
+
+func TestTokenAllows(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+
+	unrestricted := &APIToken{}
+	expiring := &APIToken{ExpiresAt: &future}
+	expired := &APIToken{ExpiresAt: &past}
+	org := &APIToken{Organizations: "1,3"}
+	scoped := &APIToken{Scopes: "clusters:read,deployments:write,secrets:none,orgs:read"}
+
+	cases := []struct {
+		name     string
+		token    *APIToken
+		method   string
+		path     string
+		expected bool
+	}{
+		{"unrestricted cluster", unrestricted, http.MethodDelete, "/api/v1/orgs/1/clusters/1", true},
+		{"unrestricted token creation", unrestricted, http.MethodPost, "/api/v1/tokens", true},
+
+		{"expiring", expiring, http.MethodDelete, "/api/v1/orgs/1/clusters/1", true},
+		{"expiring token creation", expiring, http.MethodPost, "/api/v1/tokens", false},
+		{"expired", expired, http.MethodGet, "/api/v1/orgs/1/clusters", false},
+		{"expired organization list", expired, http.MethodGet, "/api/v1/orgs", false},
+
+		{"organization", org, http.MethodGet, "/api/v1/orgs/1", true},
+		{"other organization", org, http.MethodGet, "/api/v1/orgs/2/clusters", false},
+		{"organization prefix", org, http.MethodGet, "/api/v1/orgs/13/clusters", false},
+		{"organization dashboard", org, http.MethodGet, "/dashboard/orgs/3/clusters", true},
+		{"other organization dashboard", org, http.MethodGet, "/dashboard/orgs/2/clusters", false},
+
+		{"read scope", scoped, http.MethodGet, "/api/v1/orgs/1/clusters/1", true},
+		{"read scope head", scoped, http.MethodHead, "/api/v1/orgs/1/clusters/1", true},
+		{"read scope write", scoped, http.MethodPut, "/api/v1/orgs/1/clusters/1", false},
+		{"read scope delete", scoped, http.MethodDelete, "/api/v1/orgs/1/clusters/1", false},
+		{"write scope", scoped, http.MethodPost, "/api/v1/orgs/1/clusters/1/deployments", true},
+		{"write scope subresource", scoped, http.MethodPut, "/api/v1/orgs/1/clusters/1/hpa", true},
+		{"none scope", scoped, http.MethodGet, "/api/v1/orgs/1/secrets", false},
+		{"none scope cluster subresource", scoped, http.MethodGet, "/api/v1/orgs/1/clusters/1/secrets", false},
+		{"cluster subresource without scope", scoped, http.MethodPut, "/api/v1/orgs/1/clusters/1/posthooks", false},
+		{"organization scope", scoped, http.MethodGet, "/api/v1/orgs/1", true},
+		{"organization scope delete", scoped, http.MethodDelete, "/api/v1/orgs/1", false},
+		{"resource without scope", scoped, http.MethodPost, "/api/v1/orgs/1/buckets", true},
+
+		{"organization list", scoped, http.MethodGet, "/api/v1/orgs", true},
+		{"organization creation", scoped, http.MethodPost, "/api/v1/orgs", false},
+		{"allowed secrets", scoped, http.MethodGet, "/api/v1/allowed/secrets/amazon", true},
+		{"token creation", scoped, http.MethodPost, "/api/v1/tokens", false},
+		{"legacy token creation", org, http.MethodGet, "/api/v1/token", false},
+		{"token list", scoped, http.MethodGet, "/api/v1/tokens", false},
+		{"token deletion", org, http.MethodDelete, "/api/v1/tokens/abc", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := tokenAllows(tc.token, tc.method, tc.path, now); actual != tc.expected {
+				t.Errorf("%s %s: expected %t, got %t", tc.method, tc.path, tc.expected, actual)
+			}
+		})
+	}
+}
+
+// recordingTokenStore records whether the token store was used
+type recordingTokenStore struct {
+	bauth.TokenStore
+
+	used bool
+}
+
+func (s *recordingTokenStore) Store(userID string, token *bauth.Token) error {
+	s.used = true
+	return nil
+}
+
+func (s *recordingTokenStore) List(userID string) ([]*bauth.Token, error) {
+	s.used = true
+	return nil, nil
+}
+
+func (s *recordingTokenStore) Lookup(userID string, tokenID string) (*bauth.Token, error) {
+	s.used = true
+	return nil, nil
+}
+
+func (s *recordingTokenStore) Revoke(userID string, tokenID string) error {
+	s.used = true
+	return nil
+}
+
+func TestTokenRoutes_RestrictedToken(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	tokens := map[string]*APIToken{
+		"expiring": {ID: "expiring", ExpiresAt: &future},
+		"scoped":   {ID: "scoped", Scopes: "clusters:read"},
+	}
+
+	defer func(f func(string) (*APIToken, error)) { getAPIToken = f }(getAPIToken)
+	getAPIToken = func(tokenID string) (*APIToken, error) {
+		return tokens[tokenID], nil
+	}
+
+	defer func(h gin.HandlerFunc) { Handler = h }(Handler)
+	defer func(s bauth.TokenStore) { TokenStore = s }(TokenStore)
+
+	requests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPost, "/auth/tokens", `{"name":"unrestricted"}`},
+		{http.MethodGet, "/auth/tokens", ""},
+		{http.MethodGet, "/auth/tokens/abc", ""},
+		{http.MethodDelete, "/auth/tokens/abc", ""},
+	}
+
+	for tokenID := range tokens {
+		for _, req := range requests {
+			t.Run(tokenID+" "+req.method+" "+req.path, func(t *testing.T) {
+				// Authenticates the request with the bearer token like the JWT middleware does
+				Handler = func(c *gin.Context) {
+					if c.GetHeader("Authorization") != "Bearer "+tokenID {
+						c.AbortWithStatus(http.StatusUnauthorized)
+						return
+					}
+
+					ctx := context.WithValue(c.Request.Context(), qorAuth.CurrentUser, &User{ID: 1, TokenID: tokenID})
+					c.Request = c.Request.WithContext(ctx)
+				}
+
+				tokenStore := &recordingTokenStore{}
+				TokenStore = tokenStore
+
+				engine := gin.New()
+				installTokenRoutes(engine.Group("/auth/"))
+
+				request := httptest.NewRequest(req.method, req.path, strings.NewReader(req.body))
+				request.Header.Set("Authorization", "Bearer "+tokenID)
+				recorder := httptest.NewRecorder()
+
+				engine.ServeHTTP(recorder, request)
+
+				if recorder.Code != http.StatusForbidden {
+					t.Errorf("expected status %d, got %d", http.StatusForbidden, recorder.Code)
+				}
+
+				if tokenStore.used {
+					t.Error("expected the token store to be left untouched")
+				}
+			})
+		}
+	}
+}
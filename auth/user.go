@@ -34,6 +34,7 @@ import (
 	"github.com/qor/auth/auth_identity"
 	"github.com/qor/auth/claims"
 	"github.com/qor/qor/utils"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"golang.org/x/oauth2"
 )
@@ -66,6 +67,7 @@ type User struct {
 	Image         string         `form:"image" json:"image,omitempty"`
 	Organizations []Organization `gorm:"many2many:user_organizations" json:"organizations,omitempty"`
 	Virtual       bool           `json:"-" gorm:"-"` // Used only internally
+	TokenID       string         `json:"-" gorm:"-"` // Used only internally
 }
 
 //DroneUser struct
@@ -97,6 +99,7 @@ type Organization struct {
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 	Name      string    `gorm:"unique;not null" json:"name"`
+	Provider  string    `json:"provider,omitempty"` // The identity provider the organization is imported from
 	Users     []User    `gorm:"many2many:user_organizations" json:"users,omitempty"`
 	Role      string    `json:"-" gorm:"-"` // Used only internally
 }
@@ -191,7 +194,8 @@ type BanzaiUserStorer struct {
 }
 
 // Save differs from the default UserStorer.Save() in that it
-// extracts Token and Login and saves to Drone DB as well
+// extracts Token and Login and saves to Drone DB as well for GitHub users,
+// and imports the organizations of the user from the identity provider
 func (bus BanzaiUserStorer) Save(schema *auth.Schema, context *auth.Context) (user interface{}, userID string, err error) {
 
 	currentUser := &User{}
@@ -200,16 +204,29 @@ func (bus BanzaiUserStorer) Save(schema *auth.Schema, context *auth.Context) (us
 		return nil, "", err
 	}
 
-	// This assumes GitHub auth only right now
-	githubExtraInfo := schema.RawInfo.(*GithubExtraInfo)
-	currentUser.Login = githubExtraInfo.Login
-	err = bus.createUserInDroneDB(currentUser, githubExtraInfo.Token)
-	if err != nil {
-		log.Info(context.Request.RemoteAddr, err.Error())
-		return nil, "", err
-	}
+	var githubExtraInfo *GithubExtraInfo
+	var organizations []*Organization
+
+	switch info := schema.RawInfo.(type) {
+	case *GithubExtraInfo:
+		githubExtraInfo = info
+		currentUser.Login = githubExtraInfo.Login
+		err = bus.createUserInDroneDB(currentUser, githubExtraInfo.Token)
+		if err != nil {
+			log.Info(context.Request.RemoteAddr, err.Error())
+			return nil, "", err
+		}
 
-	synchronizeDroneRepos(currentUser.Login)
+		synchronizeDroneRepos(currentUser.Login)
+
+	case *ExternalUserInfo:
+		// Drone is integrated with GitHub only
+		currentUser.Login = info.Login
+		organizations = info.Organizations
+
+	default:
+		return nil, "", fmt.Errorf("unsupported user info: %T", schema.RawInfo)
+	}
 
 	// When a user registers a default organization is created in which he/she is admin
 	userOrg := Organization{
@@ -230,22 +247,26 @@ func (bus BanzaiUserStorer) Save(schema *auth.Schema, context *auth.Context) (us
 
 	AddDefaultRoleForUser(currentUser.ID)
 
-	// Save the Github token to Vault
-	token := bauth.NewToken(GithubTokenID, "Github access token")
-	token.Value = githubExtraInfo.Token
-	err = TokenStore.Store(fmt.Sprint(currentUser.ID), token)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to store Github access token: %s", err.Error())
+	if githubExtraInfo != nil {
+		// Save the Github token to Vault
+		token := bauth.NewToken(GithubTokenID, "Github access token")
+		token.Value = githubExtraInfo.Token
+		err = TokenStore.Store(fmt.Sprint(currentUser.ID), token)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to store Github access token: %s", err.Error())
+		}
+
+		organizations, err = getGithubOrganizations(githubExtraInfo.Token)
+		if err != nil {
+			log.Info("Failed to list organizations", err)
+			organizations = []*Organization{}
+		}
 	}
 
-	githubOrgIDs, err := importGithubOrganizations(currentUser, context, githubExtraInfo.Token)
+	AddOrgRoles(currentUser.Organizations[0].ID)
+	AddOrgRoleForUser(currentUser.ID, OrgRoleAdmin, currentUser.Organizations[0].ID)
 
-	if err == nil {
-		orgids := []uint{currentUser.Organizations[0].ID}
-		orgids = append(orgids, githubOrgIDs...)
-		AddOrgRoles(orgids...)
-		AddOrgRoleForUser(currentUser.ID, orgids...)
-	}
+	err = addUserToOrganizations(currentUser, db, schema.Provider, organizations)
 
 	return currentUser, fmt.Sprint(db.NewScope(currentUser).PrimaryKeyValue()), err
 }
@@ -297,45 +318,105 @@ func getGithubOrganizations(token string) ([]*Organization, error) {
 	return orgs, nil
 }
 
-func importGithubOrganizations(currentUser *User, context *auth.Context, githubToken string) ([]uint, error) {
-
-	githubOrgs, err := getGithubOrganizations(githubToken)
+// addUserToOrganizations synchronizes the organizations of a user imported from an identity provider:
+// the user is added to the organizations with the role it has in them, and removed from the organizations
+// of the provider which are not listed anymore.
+// Organizations which do not exist yet are created, existing organizations of other providers
+// or created in Pipeline are never joined.
+func addUserToOrganizations(currentUser *User, db *gorm.DB, provider string, organizations []*Organization) error {
+	imported, removed, err := importOrganizations(currentUser, db, provider, organizations)
 	if err != nil {
-		log.Info("Failed to list organizations", err)
-		githubOrgs = []*Organization{}
+		return err
 	}
 
-	orgids := []uint{}
+	for _, organization := range imported {
+		AddOrgRoles(organization.ID)
+		AddOrgRoleForUser(currentUser.ID, organization.Role, organization.ID)
+	}
+
+	for _, organization := range removed {
+		DeleteOrgRoleForUser(currentUser.ID, organization.ID)
+	}
+
+	return nil
+}
+
+func importOrganizations(currentUser *User, db *gorm.DB, provider string, organizations []*Organization) ([]*Organization, []Organization, error) {
+	var imported []*Organization
+	var removed []Organization
 
-	tx := context.Auth.GetDB(context.Request).Begin()
+	tx := db.Begin()
 	{
-		for _, githubOrg := range githubOrgs {
-			err = tx.Where(&githubOrg).FirstOrCreate(githubOrg).Error
+		listed := make(map[uint]bool, len(organizations))
+
+		for _, organization := range organizations {
+			var org Organization
+			err := tx.Where(Organization{Name: organization.Name}).
+				Attrs(Organization{GithubID: organization.GithubID, Provider: provider}).
+				FirstOrCreate(&org).Error
 			if err != nil {
 				tx.Rollback()
-				return nil, err
+				return nil, nil, err
+			}
+
+			if org.Provider != provider {
+				log.Warnf("organization %s is not imported from %s, skipping membership of user %d", org.Name, provider, currentUser.ID)
+				continue
 			}
-			err = tx.Model(currentUser).Association("Organizations").Append(githubOrg).Error
+
+			organization.ID = org.ID
+			organization.Provider = org.Provider
+
+			err = tx.Model(currentUser).Association("Organizations").Append(&org).Error
 			if err != nil {
 				tx.Rollback()
-				return nil, err
+				return nil, nil, err
 			}
-			userRoleInOrg := UserOrganization{UserID: currentUser.ID, OrganizationID: githubOrg.ID}
-			err = tx.Model(&UserOrganization{}).Where(userRoleInOrg).Update("role", githubOrg.Role).Error
+			userRoleInOrg := UserOrganization{UserID: currentUser.ID, OrganizationID: organization.ID}
+			err = tx.Model(&UserOrganization{}).Where(userRoleInOrg).Update("role", organization.Role).Error
 			if err != nil {
 				tx.Rollback()
-				return nil, err
+				return nil, nil, err
 			}
-			orgids = append(orgids, githubOrg.ID)
+
+			listed[organization.ID] = true
+			imported = append(imported, organization)
 		}
-	}
 
-	err = tx.Commit().Error
-	if err != nil {
-		return nil, err
+		var current []Organization
+		err := tx.Joins("JOIN user_organizations ON user_organizations.organization_id = organizations.id").
+			Where("user_organizations.user_id = ? AND organizations.provider = ?", currentUser.ID, provider).
+			Find(&current).Error
+		if err != nil {
+			tx.Rollback()
+			return nil, nil, err
+		}
+
+		for _, organization := range current {
+			if listed[organization.ID] {
+				continue
+			}
+
+			err = tx.Model(currentUser).Association("Organizations").Delete(&organization).Error
+			if err != nil {
+				tx.Rollback()
+				return nil, nil, err
+			}
+
+			removed = append(removed, organization)
+		}
 	}
 
-	return orgids, nil
+	return imported, removed, tx.Commit().Error
+}
+
+// Migrate marks the organizations imported from GitHub, so memberships are only synchronized from GitHub to them.
+func Migrate(db *gorm.DB, logger logrus.FieldLogger) error {
+	logger.Info("marking organizations imported from GitHub")
+
+	return db.Model(&Organization{}).
+		Where("github_id IS NOT NULL AND (provider IS NULL OR provider = '')").
+		Update("provider", GithubProviderName).Error
 }
 
 // GetOrganizationById returns an organization from database by ID
@@ -611,7 +611,7 @@ func (c *ACSKCluster) GetOrganizationId() uint {
 }
 
 func (c *ACSKCluster) UpdateStatus(status, statusMessage string) error {
-	return c.modelCluster.UpdateStatus(status, statusMessage)
+	return updateModelStatus(c, c.modelCluster, status, statusMessage)
 }
 
 func (c *ACSKCluster) GetClusterDetails() (*pkgCluster.DetailsResponse, error) {
@@ -548,7 +548,7 @@ func getAKSClient(orgId uint, secretId string) (azureClient.ClusterManager, erro
 
 // UpdateStatus updates cluster status in database
 func (c *AKSCluster) UpdateStatus(status, statusMessage string) error {
-	return c.modelCluster.UpdateStatus(status, statusMessage)
+	return updateModelStatus(c, c.modelCluster, status, statusMessage)
 }
 
 // GetClusterDetails gets cluster details from cloud
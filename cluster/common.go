@@ -18,6 +18,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"os"
+	"sync"
 	"syscall"
 	"time"
 
@@ -87,6 +88,9 @@ type CommonCluster interface {
 // CommonClusterBase holds the fields that is common to all cluster types
 // also provides default implementation for common interface methods.
 type CommonClusterBase struct {
+	// mu guards the lazily loaded fields, posthook functions access them concurrently
+	mu sync.Mutex
+
 	secret    *secret.SecretItemResponse
 	sshSecret *secret.SecretItemResponse
 
@@ -100,6 +104,9 @@ func (c *CommonClusterBase) RequiresSshPublicKey() bool {
 }
 
 func (c *CommonClusterBase) getSecret(cluster CommonCluster) (*secret.SecretItemResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.secret == nil {
 		log.Debug("Secret is nil.. load from vault")
 		s, err := getSecret(cluster.GetOrganizationId(), cluster.GetSecretId())
@@ -118,6 +125,9 @@ func (c *CommonClusterBase) getSecret(cluster CommonCluster) (*secret.SecretItem
 }
 
 func (c *CommonClusterBase) getSshSecret(cluster CommonCluster) (*secret.SecretItemResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.sshSecret == nil {
 		log.Debug("SSH secret is nil.. load from vault")
 		s, err := getSecret(cluster.GetOrganizationId(), cluster.GetSshSecretId())
@@ -136,6 +146,9 @@ func (c *CommonClusterBase) getSshSecret(cluster CommonCluster) (*secret.SecretI
 }
 
 func (c *CommonClusterBase) getConfig(cluster CommonCluster) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.config == nil {
 		log.Debug("k8s config is nil.. load from vault")
 		var loadedConfig []byte
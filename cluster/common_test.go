@@ -33,6 +33,10 @@ import (
 	"github.com/banzaicloud/pipeline/secret"
 )
 
+func init() {
+	secret.SetStore(secret.NewInMemorySecretStore())
+}
+
 const (
 	clusterRequestName           = "testName"
 	clusterRequestLocation       = "testLocation"
@@ -0,0 +1,258 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"text/template"
+
+	pipConfig "github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/helm"
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgSecret "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+)
+
+// customPostHookNameRegexp matches names which are valid Helm release names as well
+var customPostHookNameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// maxCustomPostHookNameLength is the maximum length of a Helm release name
+const maxCustomPostHookNameLength = 53
+
+type customPostHookRepository interface {
+	FindAll(organizationID uint) ([]*intCluster.CustomPostHookModel, error)
+	FindOne(organizationID uint, name string) (*intCluster.CustomPostHookModel, error)
+}
+
+// customPostHooks returns the organization defined posthook repository backed by the application database.
+var customPostHooks = func() customPostHookRepository {
+	return intCluster.NewCustomPostHooks(pipConfig.DB())
+}
+
+// CustomPostFunction is an organization defined posthook function installing a chart from the organization's Helm repositories
+type CustomPostFunction struct {
+	postHook *intCluster.CustomPostHookModel
+	params   pkgCluster.PostHookParam
+	ErrorHandler
+}
+
+// customPostHookValues is the data available in the values template of a custom posthook
+type customPostHookValues struct {
+	Cluster struct {
+		ID           uint
+		UID          string
+		Name         string
+		Cloud        string
+		Distribution string
+		Location     string
+	}
+	OrganizationID uint
+	Namespace      string
+	Params         pkgCluster.PostHookParam
+	Secrets        []string
+}
+
+// NewCustomPostFunction returns a posthook function installing the chart of an organization defined posthook
+func NewCustomPostFunction(postHook *intCluster.CustomPostHookModel, params pkgCluster.PostHookParam) *CustomPostFunction {
+	return &CustomPostFunction{
+		postHook: postHook,
+		params:   params,
+	}
+}
+
+// Do installs the secrets of the posthook into the cluster then the chart with the rendered values
+func (c *CustomPostFunction) Do(cluster CommonCluster) error {
+	kubeConfig, err := cluster.GetK8sConfig()
+	if err != nil {
+		return errors.Wrap(err, "could not get k8s config")
+	}
+
+	if err := helm.CreateNamespaceIfNotExist(kubeConfig, c.postHook.Namespace); err != nil {
+		return err
+	}
+
+	var secretIDs []string
+	if c.postHook.Secrets != "" {
+		if err := json.Unmarshal([]byte(c.postHook.Secrets), &secretIDs); err != nil {
+			return errors.Wrap(err, "could not unmarshal posthook secrets")
+		}
+	}
+
+	var secretNames []string
+	if len(secretIDs) > 0 {
+		secretSources, err := InstallSecrets(cluster, &pkgSecret.ListSecretsQuery{IDs: secretIDs}, c.postHook.Namespace)
+		if err != nil {
+			return errors.Wrap(err, "could not install posthook secrets into cluster")
+		}
+
+		for _, secretSource := range secretSources {
+			secretNames = append(secretNames, secretSource.Name)
+		}
+	}
+
+	values, err := c.renderValues(cluster, secretNames)
+	if err != nil {
+		return err
+	}
+
+	releaseName := c.postHook.ReleaseName
+	if releaseName == "" {
+		releaseName = c.postHook.Name
+	}
+
+	return installDeployment(cluster, c.postHook.Namespace, c.postHook.Chart, releaseName, values, c.postHook.Name, c.postHook.ChartVersion)
+}
+
+func (c *CustomPostFunction) renderValues(cluster CommonCluster, secretNames []string) ([]byte, error) {
+	if c.postHook.Values == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New(c.postHook.Name).Option("missingkey=zero").Parse(c.postHook.Values)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse posthook values template")
+	}
+
+	data := customPostHookValues{
+		OrganizationID: cluster.GetOrganizationId(),
+		Namespace:      c.postHook.Namespace,
+		Params:         c.params,
+		Secrets:        secretNames,
+	}
+	data.Cluster.ID = cluster.GetID()
+	data.Cluster.UID = cluster.GetUID()
+	data.Cluster.Name = cluster.GetName()
+	data.Cluster.Cloud = cluster.GetCloud()
+	data.Cluster.Distribution = cluster.GetDistribution()
+	data.Cluster.Location = cluster.GetLocation()
+
+	var values bytes.Buffer
+	if err := tmpl.Execute(&values, data); err != nil {
+		return nil, errors.Wrap(err, "could not render posthook values template")
+	}
+
+	return values.Bytes(), nil
+}
+
+// String returns the name of the posthook
+func (c *CustomPostFunction) String() string {
+	return c.postHook.Name
+}
+
+// Params returns the params of the posthook
+func (c *CustomPostFunction) Params() pkgCluster.PostHookParam {
+	return c.params
+}
+
+// DependsOn returns the names of the posthook functions which have to finish before this one starts
+func (c *CustomPostFunction) DependsOn() []string {
+	return []string{pkgCluster.InstallHelmPostHook}
+}
+
+// GetOrganizationPostHookWithParams returns the posthook function registered with the given name in HookMap
+// or defined by the organization with its params set.
+func GetOrganizationPostHookWithParams(organizationID uint, name string, params pkgCluster.PostHookParam) (PostFunctioner, bool) {
+	if postHook, ok := GetPostHookWithParams(name, params); ok {
+		return postHook, true
+	}
+
+	postHook, err := customPostHooks().FindOne(organizationID, name)
+	if err != nil {
+		if !isNotFoundError(err) {
+			log.Errorf("could not get custom posthook [%s]: %s", name, err.Error())
+		}
+
+		return nil, false
+	}
+
+	return NewCustomPostFunction(postHook, params), true
+}
+
+// ListOrganizationPostHookNames returns the names of the posthook functions in HookMap
+// followed by the ones defined by the organization.
+func ListOrganizationPostHookNames(organizationID uint) ([]string, error) {
+	var names []string
+	for name := range HookMap {
+		names = append(names, name)
+	}
+
+	postHooks, err := customPostHooks().FindAll(organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, postHook := range postHooks {
+		names = append(names, postHook.Name)
+	}
+
+	return names, nil
+}
+
+// ValidateCustomPostHook checks whether an organization defined posthook can be saved.
+func ValidateCustomPostHook(organizationID uint, organizationName string, request *pkgCluster.CustomPostHookRequest) error {
+	if _, ok := HookMap[request.Name]; ok {
+		return &invalidError{errors.Errorf("name is reserved by a built-in posthook: %s", request.Name)}
+	}
+
+	for _, name := range []string{request.Name, request.ReleaseName} {
+		if name == "" {
+			continue
+		}
+
+		if len(name) > maxCustomPostHookNameLength || !customPostHookNameRegexp.MatchString(name) {
+			return &invalidError{errors.Errorf("name must be a valid Helm release name: %s", name)}
+		}
+	}
+
+	chart := strings.SplitN(request.Chart, "/", 2)
+	if len(chart) != 2 || chart[0] == "" || chart[1] == "" {
+		return &invalidError{errors.Errorf("chart must be in repository/name format: %s", request.Chart)}
+	}
+
+	repos, err := helm.ReposGet(helm.GenerateHelmRepoEnv(organizationName))
+	if err != nil {
+		return emperror.Wrap(err, "could not list helm repositories")
+	}
+
+	repoFound := false
+	for _, repo := range repos {
+		if repo.Name == chart[0] {
+			repoFound = true
+			break
+		}
+	}
+
+	if !repoFound {
+		return &invalidError{errors.Errorf("helm repository not found: %s", chart[0])}
+	}
+
+	if _, err := template.New(request.Name).Parse(request.Values); err != nil {
+		return &invalidError{errors.Wrap(err, "invalid values template")}
+	}
+
+	for _, secretID := range request.Secrets {
+		if _, err := secret.Store.Get(organizationID, secretID); err != nil {
+			return &invalidError{errors.Wrapf(err, "secret not found: %s", secretID)}
+		}
+	}
+
+	return nil
+}
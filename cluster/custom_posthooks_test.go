@@ -0,0 +1,157 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+)
+
+type testCluster struct {
+	CommonCluster
+
+	id             uint
+	organizationID uint
+	name           string
+}
+
+func (c *testCluster) GetID() uint             { return c.id }
+func (c *testCluster) GetUID() string          { return "uid" }
+func (c *testCluster) GetOrganizationId() uint { return c.organizationID }
+func (c *testCluster) GetName() string         { return c.name }
+func (c *testCluster) GetCloud() string        { return pkgCluster.Amazon }
+func (c *testCluster) GetDistribution() string { return pkgCluster.EKS }
+func (c *testCluster) GetLocation() string     { return "eu-west-1" }
+
+type testNotFoundError struct{}
+
+func (testNotFoundError) Error() string  { return "custom posthook not found" }
+func (testNotFoundError) NotFound() bool { return true }
+
+type inmemoryCustomPostHooks map[uint][]*intCluster.CustomPostHookModel
+
+func (r inmemoryCustomPostHooks) FindAll(organizationID uint) ([]*intCluster.CustomPostHookModel, error) {
+	return r[organizationID], nil
+}
+
+func (r inmemoryCustomPostHooks) FindOne(organizationID uint, name string) (*intCluster.CustomPostHookModel, error) {
+	for _, postHook := range r[organizationID] {
+		if postHook.Name == name {
+			return postHook, nil
+		}
+	}
+
+	return nil, testNotFoundError{}
+}
+
+func TestCustomPostFunction_RenderValues(t *testing.T) {
+	postHook := NewCustomPostFunction(
+		&intCluster.CustomPostHookModel{
+			Name:      "monitoring",
+			Namespace: "monitoring",
+			Values:    "cluster: {{ .Cluster.Name }}-{{ .Cluster.ID }}\nowner: {{ .OrganizationID }}\nnamespace: {{ .Namespace }}\nsize: {{ .Params.size }}\nsecret: {{ index .Secrets 0 }}\nmissing: {{ .Params.missing }}",
+		},
+		map[string]interface{}{"size": "large"},
+	)
+
+	values, err := postHook.renderValues(&testCluster{id: 42, organizationID: 1, name: "cluster"}, []string{"credentials"})
+	if err != nil {
+		t.Fatal("could not render values: ", err.Error())
+	}
+
+	expected := "cluster: cluster-42\nowner: 1\nnamespace: monitoring\nsize: large\nsecret: credentials\nmissing: <no value>"
+	if string(values) != expected {
+		t.Errorf("expected values %q, got %q", expected, string(values))
+	}
+
+	postHook = NewCustomPostFunction(&intCluster.CustomPostHookModel{Name: "empty"}, nil)
+	if values, err := postHook.renderValues(&testCluster{}, nil); err != nil || values != nil {
+		t.Errorf("expected no values for an empty template, got %q, %v", values, err)
+	}
+
+	postHook = NewCustomPostFunction(&intCluster.CustomPostHookModel{Name: "invalid", Values: "{{ index .Secrets 1 }}"}, nil)
+	if _, err := postHook.renderValues(&testCluster{}, nil); err == nil {
+		t.Error("expected an error for a failing template")
+	}
+}
+
+func TestValidateCustomPostHook(t *testing.T) {
+	cases := []struct {
+		name    string
+		request pkgCluster.CustomPostHookRequest
+	}{
+		{
+			name:    "built-in name",
+			request: pkgCluster.CustomPostHookRequest{Name: pkgCluster.InstallHelmPostHook, Chart: "stable/chart"},
+		},
+		{
+			name:    "invalid name",
+			request: pkgCluster.CustomPostHookRequest{Name: "Invalid_Name", Chart: "stable/chart"},
+		},
+		{
+			name:    "invalid release name",
+			request: pkgCluster.CustomPostHookRequest{Name: "posthook", ReleaseName: "-release", Chart: "stable/chart"},
+		},
+		{
+			name:    "invalid chart",
+			request: pkgCluster.CustomPostHookRequest{Name: "posthook", Chart: "chart"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateCustomPostHook(1, "org", &tc.request)
+			if _, ok := err.(*invalidError); !ok {
+				t.Errorf("expected a validation error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGetOrganizationPostHookWithParams(t *testing.T) {
+	original := customPostHooks
+	defer func() { customPostHooks = original }()
+
+	repository := inmemoryCustomPostHooks{
+		1: {{OrganizationID: 1, Name: "monitoring"}},
+		2: {{OrganizationID: 2, Name: pkgCluster.InstallHelmPostHook}},
+	}
+	customPostHooks = func() customPostHookRepository { return repository }
+
+	postHook, ok := GetOrganizationPostHookWithParams(1, "monitoring", nil)
+	if !ok {
+		t.Fatal("expected the posthook of the organization to be found")
+	}
+
+	if _, ok := postHook.(*CustomPostFunction); !ok {
+		t.Errorf("expected a custom posthook, got %T", postHook)
+	}
+
+	if _, ok := GetOrganizationPostHookWithParams(2, "monitoring", nil); ok {
+		t.Error("the posthook of another organization must not be found")
+	}
+
+	// built-in posthooks take precedence over organization defined ones
+	postHook, ok = GetOrganizationPostHookWithParams(2, pkgCluster.InstallHelmPostHook, nil)
+	if !ok {
+		t.Fatal("expected the built-in posthook to be found")
+	}
+
+	if _, ok := postHook.(*CustomPostFunction); ok {
+		t.Error("expected the built-in posthook, got the organization defined one")
+	}
+}
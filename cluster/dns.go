@@ -0,0 +1,71 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ReconfigureExternalDns reconfigures external-dns in the running clusters of an organization
+// to manage the records under the current domain of the organization.
+func (m *Manager) ReconfigureExternalDns(ctx context.Context, organizationID uint) error {
+	logger := m.getLogger(ctx).WithField("organization", organizationID)
+
+	clusters, err := m.GetClusters(ctx, organizationID)
+	if err != nil {
+		return err
+	}
+
+	errorHandler := m.getErrorHandler(ctx)
+	failed := 0
+
+	for _, cluster := range clusters {
+		logger := logger.WithFields(logrus.Fields{"cluster": cluster.GetName()})
+
+		status, err := cluster.GetStatus()
+		if err != nil {
+			errorHandler.Handle(err)
+			failed++
+			continue
+		}
+
+		if status.Status != pkgCluster.Running {
+			logger.Infof("skipping external-dns reconfiguration as cluster is in %s state", status.Status)
+			continue
+		}
+
+		logger.Info("reconfiguring external-dns in cluster")
+
+		if err := configureExternalDns(cluster, true); err != nil {
+			errorHandler.Handle(emperror.With(
+				errors.Wrap(err, "could not reconfigure external-dns in cluster"),
+				"organization", organizationID,
+				"cluster", cluster.GetID(),
+			))
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return errors.Errorf("could not reconfigure external-dns in %d of %d clusters", failed, len(clusters))
+	}
+
+	return nil
+}
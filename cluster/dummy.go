@@ -241,7 +241,7 @@ func CreateDummyClusterFromModel(clusterModel *model.ClusterModel) (*DummyCluste
 
 // UpdateStatus updates cluster status in database
 func (c *DummyCluster) UpdateStatus(status, statusMessage string) error {
-	return c.modelCluster.UpdateStatus(status, statusMessage)
+	return updateModelStatus(c, c.modelCluster, status, statusMessage)
 }
 
 // GetClusterDetails gets cluster details from cloud
@@ -1120,7 +1120,7 @@ func (c *EC2Cluster) newEC2Client(region string) (*ec2.EC2, error) {
 
 // UpdateStatus updates cluster status in database
 func (c *EC2Cluster) UpdateStatus(status, statusMessage string) error {
-	return c.modelCluster.UpdateStatus(status, statusMessage)
+	return updateModelStatus(c, c.modelCluster, status, statusMessage)
 }
 
 // GetClusterDetails gets cluster details from cloud
@@ -779,7 +779,7 @@ func (c *EKSCluster) ListNodeNames() (nodeNames pkgCommon.NodeNames, err error)
 
 // UpdateStatus updates cluster status in database
 func (c *EKSCluster) UpdateStatus(status string, statusMessage string) error {
-	return c.modelCluster.UpdateStatus(status, statusMessage)
+	return updateModelStatus(c, c.modelCluster, status, statusMessage)
 }
 
 // GetClusterDetails gets cluster details from cloud
@@ -0,0 +1,121 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+
+	pipConfig "github.com/banzaicloud/pipeline/config"
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
+	"github.com/banzaicloud/pipeline/model"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/sirupsen/logrus"
+)
+
+type eventRepository interface {
+	Record(event *intCluster.EventModel) error
+	FindByCluster(organizationID uint, clusterID uint, query intCluster.EventQuery) ([]*intCluster.EventModel, error)
+}
+
+// clusterEvents returns the event repository backed by the application database.
+// Status changes are recorded by the cluster implementations themselves, so events are not injected into the Manager.
+func clusterEvents() eventRepository {
+	return intCluster.NewEvents(pipConfig.DB())
+}
+
+// recordEvent appends an event to the lifecycle history of a cluster.
+// Failing to record an event is logged, but never fails the operation itself.
+func recordEvent(cluster CommonCluster, eventType string, userID uint, status string, message string) {
+	event := &intCluster.EventModel{
+		OrganizationID: cluster.GetOrganizationId(),
+		ClusterID:      cluster.GetID(),
+		UserID:         userID,
+		Type:           eventType,
+		Status:         status,
+		Message:        message,
+	}
+
+	if err := clusterEvents().Record(event); err != nil {
+		log.WithFields(logrus.Fields{
+			"organization": event.OrganizationID,
+			"cluster":      event.ClusterID,
+			"event":        event.Type,
+		}).Warnf("could not record cluster event: %s", err.Error())
+	}
+}
+
+// recordStatusTransition records a status change of a cluster.
+func recordStatusTransition(cluster CommonCluster, originalStatus string, status string, statusMessage string) {
+	if originalStatus == status {
+		return
+	}
+
+	recordEvent(
+		cluster,
+		intCluster.EventStatusChanged,
+		0,
+		status,
+		fmt.Sprintf("%s -> %s: %s", originalStatus, status, statusMessage),
+	)
+}
+
+// updateModelStatus updates the status of a cluster stored in the common cluster model and records the status change.
+func updateModelStatus(cluster CommonCluster, clusterModel *model.ClusterModel, status string, statusMessage string) error {
+	originalStatus := clusterModel.Status
+
+	if err := clusterModel.UpdateStatus(status, statusMessage); err != nil {
+		return err
+	}
+
+	recordStatusTransition(cluster, originalStatus, status, statusMessage)
+
+	return nil
+}
+
+// GetClusterEvents returns the lifecycle events of a cluster.
+func (m *Manager) GetClusterEvents(
+	ctx context.Context,
+	organizationID uint,
+	clusterID uint,
+	query intCluster.EventQuery,
+) ([]pkgCluster.ClusterEvent, error) {
+	logger := m.getLogger(ctx).WithFields(logrus.Fields{
+		"organization": organizationID,
+		"cluster":      clusterID,
+	})
+
+	logger.Debug("fetching cluster events from database")
+
+	events, err := clusterEvents().FindByCluster(organizationID, clusterID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]pkgCluster.ClusterEvent, 0, len(events))
+	for _, event := range events {
+		response = append(response, pkgCluster.ClusterEvent{
+			ID:        event.ID,
+			Time:      event.CreatedAt,
+			Type:      event.Type,
+			Status:    event.Status,
+			Message:   event.Message,
+			UserID:    event.UserID,
+			ClusterID: event.ClusterID,
+		})
+	}
+
+	return response, nil
+}
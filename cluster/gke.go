@@ -1752,6 +1752,8 @@ func (c *GKECluster) getProjectId() (string, error) {
 
 // UpdateStatus updates cluster status in database
 func (c *GKECluster) UpdateStatus(status, statusMessage string) error {
+	originalStatus := c.model.Cluster.Status
+
 	c.model.Cluster.Status = status
 	c.model.Cluster.StatusMessage = statusMessage
 
@@ -1760,6 +1762,8 @@ func (c *GKECluster) UpdateStatus(status, statusMessage string) error {
 		return errors.Wrap(err, "failed to update status")
 	}
 
+	recordStatusTransition(c, originalStatus, status, statusMessage)
+
 	return nil
 }
 
@@ -15,11 +15,15 @@
 package cluster
 
 import (
+	"fmt"
 	"reflect"
 	"runtime"
 	"strings"
+	"time"
 
+	pipConfig "github.com/banzaicloud/pipeline/config"
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/spf13/viper"
 )
 
 // HookMap for api hook endpoints
@@ -31,58 +35,74 @@ var HookMap = map[string]PostFunctioner{
 	pkgCluster.SetupPrivileges: &BasePostFunction{
 		f:            SetupPrivileges,
 		ErrorHandler: ErrorHandler{},
+		dependsOn:    []string{pkgCluster.StoreKubeConfig},
 	},
 	pkgCluster.PersistKubernetesKeys: &BasePostFunction{
 		f:            PersistKubernetesKeys,
 		ErrorHandler: ErrorHandler{},
+		dependsOn:    []string{pkgCluster.StoreKubeConfig},
 	},
 	pkgCluster.UpdatePrometheusPostHook: &BasePostFunction{
 		f:            UpdatePrometheusPostHook,
 		ErrorHandler: ErrorHandler{},
+		dependsOn:    []string{pkgCluster.StoreKubeConfig},
 	},
 	pkgCluster.InstallHelmPostHook: &BasePostFunction{
 		f:            InstallHelmPostHook,
 		ErrorHandler: ErrorHandler{},
+		dependsOn:    []string{pkgCluster.SetupPrivileges},
+		retryPolicy:  RetryPolicy{Attempts: 3, Backoff: 15 * time.Second, MaxBackoff: time.Minute},
 	},
 	pkgCluster.InstallIngressControllerPostHook: &BasePostFunction{
 		f:            InstallIngressControllerPostHook,
 		ErrorHandler: ErrorHandler{},
+		dependsOn:    []string{pkgCluster.InstallHelmPostHook},
 	},
 	pkgCluster.InstallKubernetesDashboardPostHook: &BasePostFunction{
 		f:            InstallKubernetesDashboardPostHook,
 		ErrorHandler: ErrorHandler{},
+		dependsOn:    []string{pkgCluster.InstallHelmPostHook},
 	},
 	pkgCluster.InstallClusterAutoscalerPostHook: &BasePostFunction{
 		f:            InstallClusterAutoscalerPostHook,
 		ErrorHandler: ErrorHandler{},
+		dependsOn:    []string{pkgCluster.InstallHelmPostHook},
 	},
 	pkgCluster.InstallHorizontalPodAutoscalerPostHook: &BasePostFunction{
 		f:            InstallHorizontalPodAutoscalerPostHook,
 		ErrorHandler: ErrorHandler{},
+		dependsOn:    []string{pkgCluster.InstallHelmPostHook},
 	},
 	pkgCluster.InstallMonitoring: &BasePostFunction{
 		f:            InstallMonitoring,
 		ErrorHandler: ErrorHandler{},
+		dependsOn:    []string{pkgCluster.InstallHelmPostHook},
 	},
 	pkgCluster.InstallLogging: &PostFunctionWithParam{
 		f:            InstallLogging,
 		ErrorHandler: ErrorHandler{},
+		dependsOn:    []string{pkgCluster.InstallHelmPostHook},
 	},
 	pkgCluster.RegisterDomainPostHook: &BasePostFunction{
 		f:            RegisterDomainPostHook,
 		ErrorHandler: ErrorHandler{},
+		dependsOn:    []string{pkgCluster.InstallHelmPostHook},
+		retryPolicy:  RetryPolicy{Attempts: 3, Backoff: 30 * time.Second, MaxBackoff: 2 * time.Minute},
 	},
 	pkgCluster.LabelNodes: &BasePostFunction{
 		f:            LabelNodes,
 		ErrorHandler: ErrorHandler{},
+		dependsOn:    []string{pkgCluster.StoreKubeConfig},
 	},
 	pkgCluster.TaintHeadNodes: &BasePostFunction{
 		f:            TaintHeadNodes,
 		ErrorHandler: ErrorHandler{},
+		dependsOn:    []string{pkgCluster.LabelNodes},
 	},
 	pkgCluster.InstallPVCOperator: &BasePostFunction{
 		f:            InstallPVCOperatorPostHook,
 		ErrorHandler: ErrorHandler{},
+		dependsOn:    []string{pkgCluster.InstallHelmPostHook},
 	},
 }
 
@@ -108,6 +128,11 @@ type PostFunctioner interface {
 	Error(CommonCluster, error)
 }
 
+// postFunctionWithParams is implemented by posthook functions accepting params
+type postFunctionWithParams interface {
+	Params() pkgCluster.PostHookParam
+}
+
 // ErrorHandler is the common struct which implement Error function
 type ErrorHandler struct {
 }
@@ -116,19 +141,118 @@ func (*ErrorHandler) Error(c CommonCluster, err error) {
 	c.UpdateStatus(pkgCluster.Error, err.Error())
 }
 
+// RetryPolicy describes how a failing posthook function is retried
+type RetryPolicy struct {
+	// Attempts is the maximum number of times the function is called (1 means no retry)
+	Attempts int
+
+	// Backoff is the delay before the first retry, it is doubled for every further retry
+	Backoff time.Duration
+
+	// MaxBackoff is the upper limit of the delay between two attempts
+	MaxBackoff time.Duration
+}
+
+// Delay returns the delay before the given retry (starting from 1)
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < retry; i++ {
+		delay *= 2
+
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+
+	return delay
+}
+
+// GetPostHookRetryPolicy returns the retry policy of a posthook function.
+// The policy configured for the posthook takes precedence over the one set in HookMap,
+// which takes precedence over the configured default policy.
+func GetPostHookRetryPolicy(name string, postHook PostFunctioner) RetryPolicy {
+	policy := RetryPolicy{
+		Attempts:   viper.GetInt(pipConfig.PostHookRetryAttempts),
+		Backoff:    viper.GetDuration(pipConfig.PostHookRetryBackoff),
+		MaxBackoff: viper.GetDuration(pipConfig.PostHookRetryMaxBackoff),
+	}
+
+	if p, ok := postHook.(interface {
+		RetryPolicy() RetryPolicy
+	}); ok && p.RetryPolicy().Attempts > 0 {
+		policy = p.RetryPolicy()
+	}
+
+	key := fmt.Sprintf("%s.%s", pipConfig.PostHookRetryHooks, name)
+	if viper.IsSet(key + ".attempts") {
+		policy.Attempts = viper.GetInt(key + ".attempts")
+	}
+	if viper.IsSet(key + ".backoff") {
+		policy.Backoff = viper.GetDuration(key + ".backoff")
+	}
+	if viper.IsSet(key + ".maxBackoff") {
+		policy.MaxBackoff = viper.GetDuration(key + ".maxBackoff")
+	}
+
+	if policy.Attempts < 1 {
+		policy.Attempts = 1
+	}
+
+	return policy
+}
+
 // BasePostFunction describe a default posthook function
 type BasePostFunction struct {
-	f func(interface{}) error
+	f           func(interface{}) error
+	dependsOn   []string
+	retryPolicy RetryPolicy
 	ErrorHandler
 }
 
 // PostFunctionWithParam describes a posthook function with params
 type PostFunctionWithParam struct {
-	f      func(interface{}, pkgCluster.PostHookParam) error
-	params pkgCluster.PostHookParam
+	f           func(interface{}, pkgCluster.PostHookParam) error
+	params      pkgCluster.PostHookParam
+	dependsOn   []string
+	retryPolicy RetryPolicy
 	ErrorHandler
 }
 
+// DependsOn returns the names of the posthook functions which have to finish before this one starts
+func (b *BasePostFunction) DependsOn() []string {
+	return b.dependsOn
+}
+
+// DependsOn returns the names of the posthook functions which have to finish before this one starts
+func (p *PostFunctionWithParam) DependsOn() []string {
+	return p.dependsOn
+}
+
+// GetPostHookDependencies returns the names of the posthook functions a posthook function depends on
+func GetPostHookDependencies(postHook PostFunctioner) []string {
+	if p, ok := postHook.(interface {
+		DependsOn() []string
+	}); ok {
+		return p.DependsOn()
+	}
+
+	return nil
+}
+
+// RetryPolicy returns the retry policy of the posthook function
+func (b *BasePostFunction) RetryPolicy() RetryPolicy {
+	return b.retryPolicy
+}
+
+// RetryPolicy returns the retry policy of the posthook function
+func (p *PostFunctionWithParam) RetryPolicy() RetryPolicy {
+	return p.retryPolicy
+}
+
 // Do call function and pass CommonCluster and posthookParams
 func (p *PostFunctionWithParam) Do(cluster CommonCluster) error {
 	return p.f(cluster, p.params)
@@ -159,3 +283,38 @@ func (p *PostFunctionWithParam) String() string {
 func (p *PostFunctionWithParam) SetParams(params pkgCluster.PostHookParam) {
 	p.params = params
 }
+
+// Params returns the posthook params
+func (p *PostFunctionWithParam) Params() pkgCluster.PostHookParam {
+	return p.params
+}
+
+// GetPostHookName returns the HookMap name of a posthook function
+func GetPostHookName(postHook PostFunctioner) string {
+	functionName := fmt.Sprint(postHook)
+
+	for name, function := range HookMap {
+		if fmt.Sprint(function) == functionName {
+			return name
+		}
+	}
+
+	return functionName
+}
+
+// GetPostHookWithParams returns the posthook function registered with the given name in HookMap
+// with its params set (if the function accepts params)
+func GetPostHookWithParams(name string, params pkgCluster.PostHookParam) (PostFunctioner, bool) {
+	function, ok := HookMap[name]
+	if !ok || function == nil {
+		return nil, false
+	}
+
+	if f, isOk := function.(*PostFunctionWithParam); isOk {
+		fa := *f
+		fa.SetParams(params)
+		function = &fa
+	}
+
+	return function, true
+}
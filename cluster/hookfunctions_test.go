@@ -0,0 +1,47 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/banzaicloud/pipeline/cluster"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := cluster.RetryPolicy{
+		Attempts:   5,
+		Backoff:    10 * time.Second,
+		MaxBackoff: time.Minute,
+	}
+
+	cases := []struct {
+		retry    int
+		expected time.Duration
+	}{
+		{retry: 1, expected: 10 * time.Second},
+		{retry: 2, expected: 20 * time.Second},
+		{retry: 3, expected: 40 * time.Second},
+		{retry: 4, expected: time.Minute},
+		{retry: 10, expected: time.Minute},
+	}
+
+	for _, tc := range cases {
+		if delay := policy.Delay(tc.retry); delay != tc.expected {
+			t.Errorf("retry %d: expected delay %s, got %s", tc.retry, tc.expected, delay)
+		}
+	}
+}
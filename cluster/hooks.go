@@ -23,13 +23,18 @@ import (
 	"github.com/banzaicloud/pipeline/auth"
 	pipConfig "github.com/banzaicloud/pipeline/config"
 	"github.com/banzaicloud/pipeline/dns"
+	"github.com/banzaicloud/pipeline/dns/azuredns"
+	"github.com/banzaicloud/pipeline/dns/clouddns"
 	"github.com/banzaicloud/pipeline/dns/route53"
 	"github.com/banzaicloud/pipeline/helm"
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
 	"github.com/banzaicloud/pipeline/internal/providers/azure"
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
 	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
 	pkgHelm "github.com/banzaicloud/pipeline/pkg/helm"
 	"github.com/banzaicloud/pipeline/pkg/k8sutil"
+	s3compatibleObjectstore "github.com/banzaicloud/pipeline/pkg/providers/s3compatible/objectstore"
+	s3compatibleSecret "github.com/banzaicloud/pipeline/pkg/providers/s3compatible/secret"
 	pkgSecret "github.com/banzaicloud/pipeline/pkg/secret"
 	"github.com/banzaicloud/pipeline/secret"
 	"github.com/banzaicloud/pipeline/utils"
@@ -47,27 +52,23 @@ import (
 )
 
 //RunPostHooks calls posthook functions with created cluster
+// Independent posthook functions run concurrently, respecting the dependencies declared in HookMap.
 func RunPostHooks(postHooks []PostFunctioner, cluster CommonCluster) (err error) {
 
 	log := log.WithFields(logrus.Fields{"cluster": cluster.GetName(), "org": cluster.GetOrganizationId()})
 
-	for _, postHook := range postHooks {
-		if postHook != nil {
-			log.Infof("Start posthook function[%s]", postHook)
-			err = postHook.Do(cluster)
-			if err != nil {
-				log.Errorf("Error during posthook function[%s]: %s", postHook, err.Error())
-				postHook.Error(cluster, err)
-				return
-			}
+	if err := registerPostHooks(cluster, postHooks); err != nil {
+		log.Warnf("could not register posthooks: %s", err.Error())
+	}
 
-			statusMsg := fmt.Sprintf("Posthook function finished: %s", postHook)
-			err = cluster.UpdateStatus(pkgCluster.Creating, statusMsg)
-			if err != nil {
-				log.Errorf("Error during posthook status update in db [%s]: %s", postHook, err.Error())
-				return
-			}
-		}
+	return runRegisteredPostHooks(postHooks, cluster, log)
+}
+
+// runRegisteredPostHooks calls posthook functions which already have a status and marks the cluster running when all of them succeed.
+func runRegisteredPostHooks(postHooks []PostFunctioner, cluster CommonCluster, log logrus.FieldLogger) (err error) {
+	err = runPostHookGraph(postHooks, cluster, log, nil)
+	if err != nil {
+		return
 	}
 
 	log.Info("Run all posthooks for cluster successfully.")
@@ -81,6 +82,59 @@ func RunPostHooks(postHooks []PostFunctioner, cluster CommonCluster) (err error)
 	return
 }
 
+// execPostHook calls a single posthook function according to its retry policy and persists its outcome.
+// It does not touch the cluster status, so it is safe to call concurrently for the same cluster.
+func execPostHook(postHook PostFunctioner, cluster CommonCluster, log logrus.FieldLogger) error {
+	postHookName := GetPostHookName(postHook)
+	policy := GetPostHookRetryPolicy(postHookName, postHook)
+
+	log.Infof("Start posthook function[%s]", postHook)
+	recordEvent(cluster, intCluster.EventPostHookStarted, 0, "", postHookName)
+
+	record := startPostHook(cluster, postHookName, log)
+
+	var err error
+	for attempt := 1; attempt <= policy.Attempts; attempt++ {
+		if attempt > 1 {
+			delay := policy.Delay(attempt - 1)
+			log.Infof("Retrying posthook function[%s] in %s (attempt %d/%d)", postHook, delay, attempt, policy.Attempts)
+			time.Sleep(delay)
+		}
+
+		savePostHookAttempt(record, attempt, log)
+
+		err = postHook.Do(cluster)
+		if err == nil {
+			break
+		}
+
+		log.Errorf("Error during posthook function[%s] attempt %d/%d: %s", postHook, attempt, policy.Attempts, err.Error())
+	}
+
+	finishPostHook(record, err, log)
+
+	if err != nil {
+		recordEvent(cluster, intCluster.EventPostHookFailed, 0, "", fmt.Sprintf("%s: %s", postHookName, err.Error()))
+		return err
+	}
+
+	recordEvent(cluster, intCluster.EventPostHookFinished, 0, "", postHookName)
+
+	return nil
+}
+
+// updatePostHookFinishedStatus records the completion of a posthook function in the cluster status message
+func updatePostHookFinishedStatus(postHook PostFunctioner, cluster CommonCluster, log logrus.FieldLogger) error {
+	statusMsg := fmt.Sprintf("Posthook function finished: %s", postHook)
+	err := cluster.UpdateStatus(pkgCluster.Creating, statusMsg)
+	if err != nil {
+		log.Errorf("Error during posthook status update in db [%s]: %s", postHook, err.Error())
+		return err
+	}
+
+	return nil
+}
+
 // PollingKubernetesConfig polls kubeconfig from the cloud
 func PollingKubernetesConfig(cluster CommonCluster) ([]byte, error) {
 
@@ -212,6 +266,16 @@ func InstallLogging(input interface{}, param pkgCluster.PostHookParam) error {
 		}
 		loggingParam.SecretId = secret.GenerateSecretIDFromName(loggingParam.SecretName)
 	}
+
+	// Determine the type of output plugin
+	logSecret, err := secret.Store.Get(cluster.GetOrganizationId(), loggingParam.SecretId)
+	if err != nil {
+		return err
+	}
+	if err := validateLoggingSecret(logSecret); err != nil {
+		return err
+	}
+
 	if loggingParam.GenTLSForLogging.Namespace == "" {
 		loggingParam.GenTLSForLogging.Namespace = namespace
 	}
@@ -266,11 +330,6 @@ func InstallLogging(input interface{}, param pkgCluster.PostHookParam) error {
 		return err
 	}
 
-	// Determine the type of output plugin
-	logSecret, err := secret.Store.Get(cluster.GetOrganizationId(), loggingParam.SecretId)
-	if err != nil {
-		return err
-	}
 	log.Infof("logging-hook secret type: %s", logSecret.Type)
 	switch logSecret.Type {
 	case pkgCluster.Amazon:
@@ -358,6 +417,70 @@ func InstallLogging(input interface{}, param pkgCluster.PostHookParam) error {
 		}
 
 		return installDeployment(cluster, namespace, pkgHelm.BanzaiRepository+"/azure-output", "pipeline-azure-output", marshaledValues, "ConfigureLoggingOutPut", "")
+	case pkgSecret.S3CompatibleSecretType:
+		// The s3-output chart expects the credentials under the Amazon keys
+		s3Config, err := s3compatibleSecret.CreateConfig(logSecret.Values)
+		if err != nil {
+			return err
+		}
+
+		region := loggingParam.Region
+		if region == "" {
+			region = s3Config.Region
+		}
+		if region == "" {
+			region = s3compatibleObjectstore.DefaultRegion
+		}
+
+		clusterUidTag := fmt.Sprintf("clusterUID:%s", cluster.GetUID())
+
+		genericSecretName := fmt.Sprintf("logging-generic-%d", cluster.GetID())
+		req := &secret.CreateSecretRequest{
+			Name: genericSecretName,
+			Type: pkgSecret.GenericSecret,
+			Tags: []string{
+				clusterUidTag,
+				pkgSecret.TagBanzaiReadonly,
+				releaseTag,
+			},
+			Values: map[string]string{
+				pkgSecret.AwsAccessKeyId:     logSecret.Values[s3compatibleSecret.AccessKeyID],
+				pkgSecret.AwsSecretAccessKey: logSecret.Values[s3compatibleSecret.SecretAccessKey],
+			},
+		}
+		if _, err = secret.Store.GetOrCreate(cluster.GetOrganizationId(), req); err != nil {
+			return errors.Errorf("failed generate Generic secrets to logging operator: %s", err)
+		}
+
+		_, err = InstallSecrets(cluster,
+			&pkgSecret.ListSecretsQuery{
+				Type: pkgSecret.GenericSecret,
+				Tags: []string{
+					clusterUidTag,
+					releaseTag,
+				},
+			}, loggingParam.GenTLSForLogging.Namespace)
+		if err != nil {
+			return errors.Errorf("could not install created Generic secret to cluster: %s", err)
+		}
+
+		loggingValues := map[string]interface{}{
+			"bucketName":     loggingParam.BucketName,
+			"region":         region,
+			"endpoint":       s3Config.Endpoint,
+			"forcePathStyle": s3Config.ForcePathStyle,
+			"sslVerifyPeer":  !s3Config.InsecureSkipVerify,
+			"secret": map[string]interface{}{
+				"secretName": genericSecretName,
+			},
+		}
+
+		marshaledValues, err := yaml.Marshal(loggingValues)
+		if err != nil {
+			return err
+		}
+
+		return installDeployment(cluster, namespace, pkgHelm.BanzaiRepository+"/s3-output", "pipeline-s3-output", marshaledValues, "ConfigureLoggingOutPut", "")
 
 	default:
 		return fmt.Errorf("unexpected logging secret type: %s", logSecret.Type)
@@ -366,6 +489,25 @@ func InstallLogging(input interface{}, param pkgCluster.PostHookParam) error {
 
 }
 
+// validateLoggingSecret checks whether the output plugins can be configured with the secret
+func validateLoggingSecret(logSecret *secret.SecretItemResponse) error {
+	if logSecret.Type != pkgSecret.S3CompatibleSecretType {
+		return nil
+	}
+
+	s3Config, err := s3compatibleSecret.CreateConfig(logSecret.Values)
+	if err != nil {
+		return err
+	}
+
+	// The s3-output chart can't be configured with a private certificate authority
+	if s3Config.CACert != "" && !s3Config.InsecureSkipVerify {
+		return errors.Errorf("logging does not support secrets with %s, the certificate of the endpoint has to be publicly trusted", s3compatibleSecret.CACert)
+	}
+
+	return nil
+}
+
 //func checkIfTLSRelatedValuesArePresent(v *pkgCluster.GenTLSForLogging) bool {
 //	if v.TLSEnabled {
 //		if v.TLSHost == "" || v.GenTLSSecretName == "" || v.Namespace == "" {
@@ -527,6 +669,25 @@ func InstallIngressControllerPostHook(input interface{}) error {
 		return errors.Errorf("Get User failed : %s", err.Error())
 	}
 
+	dnsSecretName := externalDnsSecretName(viper.GetString(pipConfig.DNSProvider))
+	acmeDnsProvider := acmeDnsProviderName(dnsProviderSecretType(viper.GetString(pipConfig.DNSProvider)))
+
+	// the ACME DNS challenge of custom domains is solved in their zones managed by the organization
+	customDomain, err := dns.GetCustomDomain(cluster.GetOrganizationId())
+	if err != nil {
+		return errors.Errorf("Get custom domain failed : %s", err.Error())
+	}
+
+	if customDomain != nil && !customDomain.IsManaged() {
+		dnsSecret, err := secret.Store.Get(cluster.GetOrganizationId(), customDomain.SecretId)
+		if err != nil {
+			return errors.Errorf("Get DNS secret failed : %s", err.Error())
+		}
+
+		dnsSecretName = dnsSecret.Name
+		acmeDnsProvider = acmeDnsProviderName(dnsSecret.Type)
+	}
+
 	ingressValues := map[string]interface{}{
 		"traefik": map[string]interface{}{
 			"acme": map[string]interface{}{
@@ -538,8 +699,8 @@ func InstallIngressControllerPostHook(input interface{}) error {
 				"email":             user.Email,
 				"persistence":       map[string]interface{}{"enabled": true},
 				"dnsProvider": map[string]interface{}{
-					"name":       "route53",
-					"secretName": route53.IAMUserAccessKeySecretName,
+					"name":       acmeDnsProvider,
+					"secretName": dnsSecretName,
 				},
 			},
 		},
@@ -851,93 +1012,239 @@ func SetupPrivileges(input interface{}) error {
 
 // RegisterDomainPostHook registers a subdomain using the name of the current organization
 // in external Dns service. It ensures that only one domain is registered per organization.
+// If the organization has a custom domain, external-dns manages the records under that domain instead.
 func RegisterDomainPostHook(input interface{}) error {
 	commonCluster, ok := input.(CommonCluster)
 	if !ok {
 		return errors.Errorf("Wrong parameter type: %T", commonCluster)
 	}
 
-	domainBase := viper.GetString(pipConfig.DNSBaseDomain)
-	route53SecretNamespace := viper.GetString(pipConfig.PipelineSystemNamespace)
+	return configureExternalDns(commonCluster, false)
+}
+
+// configureExternalDns registers the domain of the organization of the cluster and installs external-dns managing
+// the records under the domain. If upgrade is true an already installed external-dns is reconfigured.
+func configureExternalDns(commonCluster CommonCluster, upgrade bool) error {
+	dnsSecretNamespace := viper.GetString(pipConfig.PipelineSystemNamespace)
 
 	orgId := commonCluster.GetOrganizationId()
 
-	dnsSvc, err := dns.GetExternalDnsServiceClient()
+	org, err := auth.GetOrganizationById(orgId)
 	if err != nil {
-		log.Errorf("Getting external dns service client failed: %s", err.Error())
+		log.Errorf("Retrieving organization with id %d failed: %s", orgId, err.Error())
 		return err
 	}
 
-	if dnsSvc == nil {
-		log.Info("Exiting as external dns service functionality is not enabled")
-		return nil
-	}
-
-	org, err := auth.GetOrganizationById(orgId)
+	customDomain, err := dns.GetCustomDomain(orgId)
 	if err != nil {
-		log.Errorf("Retrieving organization with id %d failed: %s", orgId, err.Error())
+		log.Errorf("Retrieving custom domain of organization with id %d failed: %s", orgId, err.Error())
 		return err
 	}
 
-	domain := fmt.Sprintf("%s.%s", org.Name, domainBase)
+	domain := dns.DefaultOrgDomain(org)
+	secretID := secret.GenerateSecretIDFromName(externalDnsSecretName(viper.GetString(pipConfig.DNSProvider)))
+	resourceGroup := viper.GetString(pipConfig.DNSAzureResourceGroup)
 
-	registered, err := dnsSvc.IsDomainRegistered(orgId, domain)
-	if err != nil {
-		log.Errorf("Checking if domain '%s' is already registered failed: %s", domain, err.Error())
-		return err
+	if customDomain != nil {
+		domain = customDomain.Domain
 	}
 
-	if !registered {
-		if err = dnsSvc.RegisterDomain(orgId, domain); err != nil {
-			log.Errorf("Registering domain '%s' failed: %s", domain, err.Error())
+	if customDomain != nil && !customDomain.IsManaged() {
+		// the zone of the domain is managed by the organization, external-dns uses the cloud secret of the organization
+		secretID = customDomain.SecretId
+		resourceGroup = customDomain.ResourceGroup
+	} else {
+		dnsSvc, err := dns.GetExternalDnsServiceClient()
+		if err != nil {
+			log.Errorf("Getting external dns service client failed: %s", err.Error())
 			return err
 		}
-	} else {
-		log.Infof("Domain '%s' already registered", domain)
+
+		if dnsSvc == nil {
+			log.Info("Exiting as external dns service functionality is not enabled")
+			return nil
+		}
+
+		registered, err := dnsSvc.IsDomainRegistered(orgId, domain)
+		if err != nil {
+			log.Errorf("Checking if domain '%s' is already registered failed: %s", domain, err.Error())
+			return err
+		}
+
+		if !registered {
+			if err = dnsSvc.RegisterDomain(orgId, domain); err != nil {
+				log.Errorf("Registering domain '%s' failed: %s", domain, err.Error())
+				return err
+			}
+		} else {
+			log.Infof("Domain '%s' already registered", domain)
+		}
 	}
 
-	secretSources, err := InstallSecrets(
+	_, err = InstallSecrets(
 		commonCluster,
 		&pkgSecret.ListSecretsQuery{
-			Type: pkgCluster.Amazon,
-			Tags: []string{pkgSecret.TagBanzaiHidden},
+			IDs: []string{secretID},
 		},
-		route53SecretNamespace,
+		dnsSecretNamespace,
 	)
 	if err != nil {
-		log.Errorf("Failed to install route53 secret into cluster: %s", err.Error())
+		log.Errorf("Failed to install %s secret into cluster: %s", secretID, err.Error())
 		return err
 	}
 
-	route53Secret, err := secret.Store.GetByName(orgId, secretSources[0].Name)
+	dnsSecret, err := secret.Store.Get(orgId, secretID)
 	if err != nil {
-		log.Errorf("Failed to get the route53 secret : %s", err.Error())
+		log.Errorf("Failed to get the %s secret : %s", secretID, err.Error())
 		return err
 	}
 
-	log.Info("route53 secret successfully installed into cluster.")
+	log.Infof("%s secret successfully installed into cluster.", dnsSecret.Name)
 
 	externalDnsValues := map[string]interface{}{
 		"rbac": map[string]bool{
 			"create": commonCluster.RbacEnabled() == true,
 		},
-		"aws": map[string]string{
-			"secretKey": route53Secret.Values[pkgSecret.AwsSecretAccessKey],
-			"accessKey": route53Secret.Values[pkgSecret.AwsAccessKeyId],
-			"region":    route53Secret.Values[pkgSecret.AwsRegion],
-		},
 		"domainFilters": []string{domain},
 		"policy":        "sync",
 		"txtOwnerId":    commonCluster.GetUID(),
 	}
 
+	if err := setExternalDnsProviderValues(externalDnsValues, dnsSecret, resourceGroup); err != nil {
+		return err
+	}
+
 	externalDnsValuesJson, err := json.Marshal(externalDnsValues)
 	if err != nil {
 		return errors.Errorf("Json Convert Failed : %s", err.Error())
 	}
 	chartVersion := viper.GetString(pipConfig.DNSExternalDnsChartVersion)
+	chartName := pkgHelm.StableRepository + "/external-dns"
 
-	return installDeployment(commonCluster, route53SecretNamespace, pkgHelm.StableRepository+"/external-dns", "dns", externalDnsValuesJson, "InstallExternalDNS", chartVersion)
+	if upgrade {
+		return upgradeDeployment(commonCluster, dnsSecretNamespace, chartName, "dns", externalDnsValuesJson, chartVersion)
+	}
+
+	return installDeployment(commonCluster, dnsSecretNamespace, chartName, "dns", externalDnsValuesJson, "InstallExternalDNS", chartVersion)
+}
+
+// setExternalDnsProviderValues sets the DNS provider and its credentials in the values of the external-dns chart based on the type of the DNS secret
+func setExternalDnsProviderValues(externalDnsValues map[string]interface{}, dnsSecret *secret.SecretItemResponse, resourceGroup string) error {
+	switch dnsSecret.Type {
+	case pkgCluster.Google:
+		serviceAccountKey, err := json.Marshal(dnsSecret.Values)
+		if err != nil {
+			return errors.Errorf("Json Convert Failed : %s", err.Error())
+		}
+
+		externalDnsValues["provider"] = "google"
+		externalDnsValues["google"] = map[string]string{
+			"project":           dnsSecret.Values[pkgSecret.ProjectId],
+			"serviceAccountKey": string(serviceAccountKey),
+		}
+
+	case pkgCluster.Azure:
+		externalDnsValues["provider"] = "azure"
+		externalDnsValues["azure"] = map[string]string{
+			"resourceGroup":   resourceGroup,
+			"tenantId":        dnsSecret.Values[pkgSecret.AzureTenantId],
+			"subscriptionId":  dnsSecret.Values[pkgSecret.AzureSubscriptionId],
+			"aadClientId":     dnsSecret.Values[pkgSecret.AzureClientId],
+			"aadClientSecret": dnsSecret.Values[pkgSecret.AzureClientSecret],
+		}
+
+	case pkgCluster.Amazon:
+		externalDnsValues["aws"] = map[string]string{
+			"secretKey": dnsSecret.Values[pkgSecret.AwsSecretAccessKey],
+			"accessKey": dnsSecret.Values[pkgSecret.AwsAccessKeyId],
+			"region":    dnsSecret.Values[pkgSecret.AwsRegion],
+		}
+
+	default:
+		return errors.Errorf("secret type %q is not supported by external-dns", dnsSecret.Type)
+	}
+
+	return nil
+}
+
+// upgradeDeployment upgrades a deployment with new values, the deployment is installed if it doesn't exist
+func upgradeDeployment(cluster CommonCluster, namespace string, deploymentName string, releaseName string, values []byte, chartVersion string) error {
+	kubeConfig, err := cluster.GetK8sConfig()
+	if err != nil {
+		log.Errorf("Unable to fetch config for upgrade: %s", err.Error())
+		return err
+	}
+
+	org, err := auth.GetOrganizationById(cluster.GetOrganizationId())
+	if err != nil {
+		log.Errorf("Error during getting organization: %s", err.Error())
+		return err
+	}
+
+	deployments, err := helm.ListDeployments(&releaseName, kubeConfig)
+	if err != nil {
+		log.Errorln("Unable to fetch deployments from helm:", err)
+		return err
+	}
+
+	var found bool
+	if deployments != nil {
+		for _, release := range deployments.Releases {
+			if release.Name == releaseName {
+				found = true
+				break
+			}
+		}
+	}
+
+	if !found {
+		_, err = helm.CreateDeployment(deploymentName, chartVersion, nil, namespace, releaseName, values, kubeConfig, helm.GenerateHelmRepoEnv(org.Name))
+	} else {
+		_, err = helm.UpgradeDeployment(releaseName, deploymentName, chartVersion, nil, values, false, kubeConfig, helm.GenerateHelmRepoEnv(org.Name))
+	}
+	if err != nil {
+		log.Errorf("Upgrading '%s' failed due to: %s", deploymentName, err.Error())
+		return err
+	}
+
+	log.Infof("'%s' upgraded", deploymentName)
+	return nil
+}
+
+// externalDnsSecretName returns the name of the hidden secret storing the credentials of external-dns for the DNS provider
+func externalDnsSecretName(provider string) string {
+	switch provider {
+	case dns.GoogleProvider:
+		return clouddns.ServiceAccountSecretName
+	case dns.AzureProvider:
+		return azuredns.ServicePrincipalSecretName
+	default:
+		return route53.IAMUserAccessKeySecretName
+	}
+}
+
+// dnsProviderSecretType returns the type of the hidden secret storing the credentials of external-dns for the DNS provider
+func dnsProviderSecretType(provider string) string {
+	switch provider {
+	case dns.GoogleProvider:
+		return pkgCluster.Google
+	case dns.AzureProvider:
+		return pkgCluster.Azure
+	default:
+		return pkgCluster.Amazon
+	}
+}
+
+// acmeDnsProviderName returns the name of the DNS provider for the ACME DNS challenge of Traefik based on the type of the DNS secret
+func acmeDnsProviderName(secretType string) string {
+	switch secretType {
+	case pkgCluster.Google:
+		return "gcloud"
+	case pkgCluster.Azure:
+		return "azure"
+	default:
+		return "route53"
+	}
 }
 
 // LabelNodes adds labels for all nodes
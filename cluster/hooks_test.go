@@ -0,0 +1,67 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	s3compatibleSecret "github.com/banzaicloud/pipeline/pkg/providers/s3compatible/secret"
+	pkgSecret "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/banzaicloud/pipeline/secret"
+)
+
+func TestValidateLoggingSecret(t *testing.T) {
+	cases := map[string]struct {
+		secretType string
+		values     map[string]string
+		valid      bool
+	}{
+		"generic": {
+			secretType: pkgSecret.GenericSecret,
+			values:     map[string]string{},
+			valid:      true,
+		},
+		"s3 compatible": {
+			secretType: pkgSecret.S3CompatibleSecretType,
+			values:     map[string]string{s3compatibleSecret.Endpoint: "https://minio.example.com"},
+			valid:      true,
+		},
+		"s3 compatible with certificate authority": {
+			secretType: pkgSecret.S3CompatibleSecretType,
+			values:     map[string]string{s3compatibleSecret.CACert: "-----BEGIN CERTIFICATE-----"},
+			valid:      false,
+		},
+		"s3 compatible skipping verification": {
+			secretType: pkgSecret.S3CompatibleSecretType,
+			values: map[string]string{
+				s3compatibleSecret.CACert:             "-----BEGIN CERTIFICATE-----",
+				s3compatibleSecret.InsecureSkipVerify: "true",
+			},
+			valid: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := validateLoggingSecret(&secret.SecretItemResponse{Type: tc.secretType, Values: tc.values})
+
+			if tc.valid && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			} else if !tc.valid && err == nil {
+				t.Error("expected the secret to be rejected")
+			}
+		})
+	}
+}
@@ -240,7 +240,7 @@ func CreateKubernetesClusterFromModel(clusterModel *model.ClusterModel) (*KubeCl
 
 // UpdateStatus updates cluster status in database
 func (c *KubeCluster) UpdateStatus(status, statusMessage string) error {
-	return c.modelCluster.UpdateStatus(status, statusMessage)
+	return updateModelStatus(c, c.modelCluster, status, statusMessage)
 }
 
 // GetClusterDetails gets cluster details from cloud
@@ -37,17 +37,25 @@ type secretValidator interface {
 }
 
 type Manager struct {
-	clusters clusterRepository
-	secrets  secretValidator
+	clusters  clusterRepository
+	secrets   secretValidator
+	workflows workflowRepository
 
 	logger       logrus.FieldLogger
 	errorHandler emperror.Handler
 }
 
-func NewManager(clusters clusterRepository, secrets secretValidator, logger logrus.FieldLogger, errorHandler emperror.Handler) *Manager {
+func NewManager(
+	clusters clusterRepository,
+	secrets secretValidator,
+	workflows workflowRepository,
+	logger logrus.FieldLogger,
+	errorHandler emperror.Handler,
+) *Manager {
 	return &Manager{
 		clusters:     clusters,
 		secrets:      secrets,
+		workflows:    workflows,
 		logger:       logger,
 		errorHandler: errorHandler,
 	}
@@ -108,3 +108,11 @@ func (c *commonUpdater) Prepare(ctx context.Context) (CommonCluster, error) {
 func (c *commonUpdater) Update(ctx context.Context) error {
 	return c.cluster.UpdateCluster(c.request, c.userID)
 }
+
+// WorkflowData implements the clusterUpdater interface.
+func (c *commonUpdater) WorkflowData() interface{} {
+	return updateWorkflowData{
+		Request: c.request,
+		UserID:  c.userID,
+	}
+}
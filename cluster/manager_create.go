@@ -18,6 +18,7 @@ import (
 	"context"
 	stderrors "errors"
 
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
 	"github.com/banzaicloud/pipeline/secret"
 	"github.com/goph/emperror"
@@ -33,10 +34,20 @@ type CreationContext struct {
 	Provider       string
 	SecretID       string
 	PostHooks      []PostFunctioner
+
+	// Request is persisted with the create workflow, so that an interrupted creation can be resumed
+	Request *pkgCluster.CreateClusterRequest
 }
 
 var ErrAlreadyExists = stderrors.New("cluster already exists with this name")
 
+// Steps of the create workflow
+const (
+	createClusterStep   = "CreateCluster"
+	storeKubeConfigStep = pkgCluster.StoreKubeConfig
+	runPostHooksStep    = "RunPostHooks"
+)
+
 type clusterCreator interface {
 	// Validate validates the cluster creation context.
 	Validate(ctx context.Context) error
@@ -85,12 +96,25 @@ func (m *Manager) CreateCluster(ctx context.Context, creationCtx CreationContext
 		return nil, err
 	}
 
+	recordEvent(cluster, intCluster.EventCreateRequested, creationCtx.UserID, pkgCluster.Creating, pkgCluster.CreatingMessage)
+
+	if err := registerPostHooks(cluster, createPostHookFunctions(creationCtx.PostHooks)); err != nil {
+		logger.Warnf("could not register posthooks: %s", err.Error())
+	}
+
+	data := createWorkflowData{Request: creationCtx.Request}
+
+	workflow, err := m.newWorkflow(intCluster.WorkflowCreate, cluster, creationCtx.UserID, createWorkflowSteps(creationCtx.PostHooks), data)
+	if err != nil {
+		return nil, emperror.Wrap(err, "could not create cluster workflow")
+	}
+
 	logger.Info("creating cluster")
 
 	go func() {
 		defer emperror.HandleRecover(m.errorHandler)
 
-		err := m.createCluster(ctx, cluster, creator, creationCtx.PostHooks, logger)
+		err := m.runCreateWorkflow(ctx, workflow, cluster, creator, logger)
 		if err != nil {
 			logger.Errorf("failed to create cluster: %s", err.Error())
 		}
@@ -99,6 +123,36 @@ func (m *Manager) CreateCluster(ctx context.Context, creationCtx CreationContext
 	return cluster, nil
 }
 
+// createPostHookFunctions returns the posthook functions run by a create workflow.
+// The kubeconfig is stored by a separate step of the workflow, so it is left out.
+func createPostHookFunctions(postHooks []PostFunctioner) []PostFunctioner {
+	var postHookFunctions []PostFunctioner
+	for _, postHook := range getPostHookFunctions(postHooks) {
+		if postHook != nil && GetPostHookName(postHook) != storeKubeConfigStep {
+			postHookFunctions = append(postHookFunctions, postHook)
+		}
+	}
+
+	return postHookFunctions
+}
+
+// createWorkflowSteps returns the steps of a create workflow: the provider specific creation and storing the kubeconfig,
+// followed by the base and the requested posthooks, which are run as a single step recording the status of each posthook.
+func createWorkflowSteps(postHooks []PostFunctioner) []workflowStep {
+	postHookStep := workflowStep{Name: runPostHooksStep}
+
+	for _, postHook := range createPostHookFunctions(postHooks) {
+		step := workflowStep{Name: GetPostHookName(postHook), Status: intCluster.PostHookPending}
+		if f, ok := postHook.(postFunctionWithParams); ok {
+			step.Params = f.Params()
+		}
+
+		postHookStep.PostHooks = append(postHookStep.PostHooks, step)
+	}
+
+	return []workflowStep{{Name: createClusterStep}, {Name: storeKubeConfigStep}, postHookStep}
+}
+
 func (m *Manager) assertNotExists(ctx CreationContext) error {
 	exists, err := m.clusters.Exists(ctx.OrganizationID, ctx.Name)
 	if err != nil {
@@ -112,11 +166,64 @@ func (m *Manager) assertNotExists(ctx CreationContext) error {
 	return nil
 }
 
+func (m *Manager) runCreateWorkflow(
+	ctx context.Context,
+	workflow *intCluster.WorkflowModel,
+	cluster CommonCluster,
+	creator clusterCreator,
+	logger logrus.FieldLogger,
+) error {
+	err := m.runWorkflow(workflow, func(step workflowStep) error {
+		switch step.Name {
+		case createClusterStep:
+			return m.createCluster(ctx, cluster, creator, logger)
+
+		case storeKubeConfigStep:
+			postHook := HookMap[pkgCluster.StoreKubeConfig]
+			if err := postHook.Do(cluster); err != nil {
+				postHook.Error(cluster, err)
+				return err
+			}
+
+			return nil
+
+		case runPostHooksStep:
+			var postHooks []PostFunctioner
+			for _, postHookStep := range step.PostHooks {
+				postHook, ok := GetOrganizationPostHookWithParams(cluster.GetOrganizationId(), postHookStep.Name, postHookStep.Params)
+				if !ok {
+					return errors.Errorf("there's no posthook function with this name [%s]", postHookStep.Name)
+				}
+
+				postHooks = append(postHooks, postHook)
+			}
+
+			progress := func(postHook string, status string) {
+				if err := m.savePostHookStep(workflow, postHook, status); err != nil {
+					logger.Warnf("could not save posthook step [%s]: %s", postHook, err.Error())
+				}
+			}
+
+			// posthooks which succeeded before the workflow got interrupted are not run again
+			return runPostHookGraph(unfinishedPostHooks(cluster, postHooks, logger), cluster, logger, progress)
+
+		default:
+			return errors.Errorf("unknown create workflow step [%s]", step.Name)
+		}
+	}, logger)
+	if err != nil {
+		return errors.Wrap(err, "error during running cluster create workflow")
+	}
+
+	logger.Info("Run all posthooks for cluster successfully.")
+
+	return cluster.UpdateStatus(pkgCluster.Running, pkgCluster.RunningMessage)
+}
+
 func (m *Manager) createCluster(
 	ctx context.Context,
 	cluster CommonCluster,
 	creator clusterCreator,
-	postHooks []PostFunctioner,
 	logger logrus.FieldLogger,
 ) error {
 	// Check if public ssh key is needed for the cluster. If so and there is generate one and store it Vault
@@ -144,19 +251,5 @@ func (m *Manager) createCluster(
 		return err
 	}
 
-	// Apply PostHooks
-	// These are hardcoded posthooks maybe we will want a bit more dynamic
-	postHookFunctions := BasePostHookFunctions
-
-	if postHooks != nil && len(postHooks) != 0 {
-		postHookFunctions = append(postHookFunctions, postHooks...)
-	}
-
-	err = RunPostHooks(postHookFunctions, cluster)
-
-	if err != nil {
-		return errors.Wrap(err, "error during running cluster posthooks")
-	}
-
 	return nil
 }
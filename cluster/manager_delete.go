@@ -20,29 +20,64 @@ import (
 	"sync"
 	"time"
 
+	"github.com/banzaicloud/pipeline/auth"
 	"github.com/banzaicloud/pipeline/dns"
 	"github.com/banzaicloud/pipeline/helm"
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
 	"github.com/goph/emperror"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const retry = 3
 
+// Names of the delete workflow steps.
+const (
+	deleteDeploymentsStep  = "DeleteDeployments"
+	deleteResourcesStep    = "DeleteResources"
+	deleteDnsRecordsStep   = "DeleteDnsRecords"
+	deleteClusterStep      = "DeleteCluster"
+	deleteFromDatabaseStep = "DeleteFromDatabase"
+	cleanStateStoreStep    = "CleanStateStore"
+)
+
 // DeleteCluster deletes a cluster.
-func (m *Manager) DeleteCluster(ctx context.Context, cluster CommonCluster, force bool, kubeProxyCache *sync.Map) error {
+func (m *Manager) DeleteCluster(ctx context.Context, cluster CommonCluster, force bool, userID uint, kubeProxyCache *sync.Map) error {
 	errorHandler := emperror.HandlerWith(
 		m.getErrorHandler(ctx),
 		"organization", cluster.GetOrganizationId(),
+		"user", userID,
 		"cluster", cluster.GetID(),
 		"force", force,
 	)
 
+	message := pkgCluster.DeletingMessage
+	if force {
+		message = fmt.Sprintf("%s (forced)", message)
+	}
+
+	recordEvent(cluster, intCluster.EventDeleteRequested, userID, pkgCluster.Deleting, message)
+
+	steps := []workflowStep{
+		{Name: deleteDeploymentsStep},
+		{Name: deleteResourcesStep},
+		{Name: deleteDnsRecordsStep},
+		{Name: deleteClusterStep},
+		{Name: deleteFromDatabaseStep},
+		{Name: cleanStateStoreStep},
+	}
+
+	workflow, err := m.newWorkflow(intCluster.WorkflowDelete, cluster, userID, steps, deleteWorkflowData{Force: force})
+	if err != nil {
+		return emperror.Wrap(err, "could not create cluster workflow")
+	}
+
 	go func() {
 		defer emperror.HandleRecover(m.errorHandler)
 
-		err := m.deleteCluster(ctx, cluster, force, kubeProxyCache)
+		err := m.deleteCluster(ctx, workflow, cluster, force, kubeProxyCache)
 		if err != nil {
 			errorHandler.Handle(err)
 		}
@@ -103,7 +138,13 @@ func deleteDnsRecordsOwnedByCluster(cluster CommonCluster) error {
 	return nil
 }
 
-func (m *Manager) deleteCluster(ctx context.Context, cluster CommonCluster, force bool, kubeProxyCache *sync.Map) error {
+func (m *Manager) deleteCluster(
+	ctx context.Context,
+	workflow *intCluster.WorkflowModel,
+	cluster CommonCluster,
+	force bool,
+	kubeProxyCache *sync.Map,
+) error {
 	logger := m.getLogger(ctx).WithFields(logrus.Fields{
 		"organization": cluster.GetOrganizationId(),
 		"cluster":      cluster.GetID(),
@@ -126,86 +167,136 @@ func (m *Manager) deleteCluster(ctx context.Context, cluster CommonCluster, forc
 		if !force {
 			cluster.UpdateStatus(pkgCluster.Error, err.Error())
 
-			return emperror.Wrap(err, "error getting kubeconfig")
+			return m.failWorkflow(workflow, emperror.Wrap(err, "error getting kubeconfig"))
 		}
 
 		logger.Errorf("error during getting kubeconfig: %s", err.Error())
 	}
 
-	if !(force && c == nil) {
-		// delete deployments
-		for i := 0; i < retry; i++ {
-			err = helm.DeleteAllDeployment(c)
-			// TODO we could check to the Authorization IAM error explicit
+	deleteName := cluster.GetName()
+
+	err = m.runWorkflow(workflow, func(step workflowStep) error {
+		switch step.Name {
+		case deleteDeploymentsStep:
+			if force && c == nil {
+				logger.Info("skipping deployment deletion without kubeconfig")
+
+				return nil
+			}
+
+			for i := 0; i < retry; i++ {
+				err := helm.DeleteAllDeployment(c)
+				// TODO we could check to the Authorization IAM error explicit
+				if err != nil {
+					logger.Errorf("deleting deployments attempt %d/%d failed: %s", i, retry, err.Error())
+					time.Sleep(1)
+				} else {
+					break
+				}
+			}
+
+		case deleteResourcesStep:
+			if force && c == nil {
+				return nil
+			}
+
+			err := deleteAllResource(c, logger)
 			if err != nil {
-				logger.Errorf("deleting deployments attempt %d/%d failed: %s", i, retry, err.Error())
-				time.Sleep(1)
-			} else {
-				break
+				if force {
+					logger.Errorf("deleting resources failed: %s", err.Error())
+				} else {
+					return emperror.Wrap(err, "deleting resources failed")
+				}
 			}
-		}
-		err = deleteAllResource(c, logger)
-		if err != nil {
-			if force {
-				logger.Errorf("deleting resources failed: %s", err.Error())
-			} else {
-				return emperror.Wrap(err, "deleting resources failed")
+
+		case deleteDnsRecordsStep:
+			// clean up dns registrations
+			err := deleteDnsRecordsOwnedByCluster(cluster)
+			if err != nil {
+				logger.Errorf("deleting DNS records failed: %s", err.Error())
 			}
-		}
 
-	} else {
-		logger.Info("skipping deployment deletion without kubeconfig")
-	}
+		case deleteClusterStep:
+			err := cluster.DeleteCluster()
+			if err != nil {
+				if !force {
+					cluster.UpdateStatus(pkgCluster.Error, err.Error())
 
-	// clean up dns registrations
-	err = deleteDnsRecordsOwnedByCluster(cluster)
+					return emperror.Wrap(err, "error deleting cluster")
+				}
 
-	// delete cluster
-	err = cluster.DeleteCluster()
-	if err != nil {
-		if !force {
-			cluster.UpdateStatus(pkgCluster.Error, err.Error())
+				logger.Errorf("error during deleting cluster: %s", err.Error())
+			}
 
-			return emperror.Wrap(err, "error deleting cluster")
-		}
+			// delete from proxy from kubeProxyCache if any
+			// TODO: this should be handled somewhere else
+			kubeProxyCache.Delete(fmt.Sprint(cluster.GetOrganizationId(), "-", cluster.GetID()))
 
-		logger.Errorf("error during deleting cluster: %s", err.Error())
-	}
+		case deleteFromDatabaseStep:
+			err := cluster.DeleteFromDatabase()
+			if err != nil {
+				if !force {
+					cluster.UpdateStatus(pkgCluster.Error, err.Error())
 
-	// delete from proxy from kubeProxyCache if any
-	// TODO: this should be handled somewhere else
-	kubeProxyCache.Delete(fmt.Sprint(cluster.GetOrganizationId(), "-", cluster.GetID()))
+					return emperror.Wrap(err, "error deleting cluster from the database")
+				}
 
-	// delete cluster from database
-	deleteName := cluster.GetName()
-	err = cluster.DeleteFromDatabase()
-	if err != nil {
-		if !force {
-			cluster.UpdateStatus(pkgCluster.Error, err.Error())
+				logger.Errorf("error during deleting cluster from the database: %s", err.Error())
+			}
+
+			if err := forgetSecretInstallations(cluster); err != nil {
+				logger.Errorf("could not delete secret installation records: %s", err.Error())
+			}
+
+			if err := auth.RevokeResourcePermissionsOf(cluster.GetOrganizationId(), auth.ClusterResource, fmt.Sprint(cluster.GetID())); err != nil {
+				logger.Errorf("could not delete resource permissions of cluster: %s", err.Error())
+			}
+
+			// Asyncron update prometheus
+			go func() {
+				err := UpdatePrometheusConfig()
+				if err != nil {
+					logger.Warnf("could not update prometheus configmap: %v", err)
+				}
+			}()
 
-			return emperror.Wrap(err, "error deleting cluster from the database")
+		case cleanStateStoreStep:
+			return cleanClusterStateStore(deleteName, logger)
+
+		default:
+			return errors.Errorf("unknown delete step: %s", step.Name)
 		}
 
-		logger.Errorf("error during deleting cluster from the database: %s", err.Error())
+		return nil
+	}, logger)
+	if err != nil {
+		return err
 	}
 
-	// Asyncron update prometheus
-	go func() {
-		err := UpdatePrometheusConfig()
-		if err != nil {
-			logger.Warnf("could not update prometheus configmap: %v", err)
+	logger.Info("cluster deleted successfully")
+
+	return nil
+}
+
+// finishDeleteWorkflow runs the remaining steps of a delete workflow whose cluster
+// has already been removed from the database.
+func (m *Manager) finishDeleteWorkflow(workflow *intCluster.WorkflowModel, logger logrus.FieldLogger) error {
+	return m.runWorkflow(workflow, func(step workflowStep) error {
+		if step.Name == cleanStateStoreStep {
+			return cleanClusterStateStore(workflow.ClusterName, logger)
 		}
-	}()
 
-	// clean statestore
+		return nil
+	}, logger)
+}
+
+func cleanClusterStateStore(clusterName string, logger logrus.FieldLogger) error {
 	logger.Info("cleaning cluster's statestore folder")
-	if err := CleanStateStore(deleteName); err != nil {
+	if err := CleanStateStore(clusterName); err != nil {
 		return emperror.Wrap(err, "cleaning cluster statestore failed")
 	}
 
 	logger.Info("cluster's statestore folder cleaned")
 
-	logger.Info("cluster deleted successfully")
-
 	return nil
 }
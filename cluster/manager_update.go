@@ -17,6 +17,7 @@ package cluster
 import (
 	"context"
 
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
 	"github.com/goph/emperror"
 	"github.com/pkg/errors"
@@ -39,8 +40,18 @@ type clusterUpdater interface {
 
 	// Update updates a cluster.
 	Update(ctx context.Context) error
+
+	// WorkflowData returns the data necessary to resume the update after a restart.
+	WorkflowData() interface{}
 }
 
+// Names of the update workflow steps.
+const (
+	updateClusterStep           = "UpdateCluster"
+	deployClusterAutoscalerStep = "DeployClusterAutoscaler"
+	labelNodesStep              = "LabelNodes"
+)
+
 // UpdateCluster updates a cluster.
 func (m *Manager) UpdateCluster(ctx context.Context, updateCtx UpdateContext, updater clusterUpdater) error {
 	logger := m.getLogger(ctx).WithFields(logrus.Fields{
@@ -76,12 +87,25 @@ func (m *Manager) UpdateCluster(ctx context.Context, updateCtx UpdateContext, up
 		return emperror.With(err, "could not update cluster status")
 	}
 
+	recordEvent(cluster, intCluster.EventUpdateRequested, updateCtx.UserID, pkgCluster.Updating, pkgCluster.UpdatingMessage)
+
+	steps := []workflowStep{
+		{Name: updateClusterStep},
+		{Name: deployClusterAutoscalerStep},
+		{Name: labelNodesStep},
+	}
+
+	workflow, err := m.newWorkflow(intCluster.WorkflowUpdate, cluster, updateCtx.UserID, steps, updater.WorkflowData())
+	if err != nil {
+		return emperror.Wrap(err, "could not create cluster workflow")
+	}
+
 	logger.Info("updating cluster")
 
 	go func() {
 		defer emperror.HandleRecover(m.errorHandler)
 
-		err := m.updateCluster(ctx, updateCtx, cluster, updater)
+		err := m.runUpdateWorkflow(ctx, workflow, cluster, updater, logger)
 		if err != nil {
 			errorHandler.Handle(err)
 		}
@@ -90,34 +114,49 @@ func (m *Manager) UpdateCluster(ctx context.Context, updateCtx UpdateContext, up
 	return nil
 }
 
-func (m *Manager) updateCluster(ctx context.Context, updateCtx UpdateContext, cluster CommonCluster, updater clusterUpdater) error {
-	logger := m.getLogger(ctx).WithFields(logrus.Fields{
-		"organization": updateCtx.OrganizationID,
-		"user":         updateCtx.UserID,
-		"cluster":      updateCtx.ClusterID,
-	})
-
-	logger.Info("updating cluster")
+func (m *Manager) runUpdateWorkflow(
+	ctx context.Context,
+	workflow *intCluster.WorkflowModel,
+	cluster CommonCluster,
+	updater clusterUpdater,
+	logger logrus.FieldLogger,
+) error {
+	err := m.runWorkflow(workflow, func(step workflowStep) error {
+		switch step.Name {
+		case updateClusterStep:
+			logger.Info("updating cluster")
+
+			err := updater.Update(ctx)
+			if err != nil {
+				cluster.UpdateStatus(pkgCluster.Error, err.Error())
+
+				return emperror.Wrap(err, "error updating cluster")
+			}
+
+			if err := cluster.UpdateStatus(pkgCluster.Running, pkgCluster.RunningMessage); err != nil {
+				return emperror.Wrap(err, "could not update cluster status")
+			}
+
+		case deployClusterAutoscalerStep:
+			logger.Info("deploying cluster autoscaler")
+			if err := DeployClusterAutoscaler(cluster); err != nil {
+				return emperror.Wrap(err, "deploying cluster autoscaler failed")
+			}
+
+		case labelNodesStep:
+			logger.Info("adding labels to nodes")
+			if err := LabelNodes(cluster); err != nil {
+				return emperror.Wrap(err, "adding labels to nodes failed")
+			}
+
+		default:
+			return errors.Errorf("unknown update step: %s", step.Name)
+		}
 
-	err := updater.Update(ctx)
+		return nil
+	}, logger)
 	if err != nil {
-		cluster.UpdateStatus(pkgCluster.Error, err.Error())
-
-		return emperror.Wrap(err, "error deleting cluster")
-	}
-
-	if err := cluster.UpdateStatus(pkgCluster.Running, pkgCluster.RunningMessage); err != nil {
-		return emperror.Wrap(err, "could not update cluster status")
-	}
-
-	logger.Info("deploying cluster autoscaler")
-	if err := DeployClusterAutoscaler(cluster); err != nil {
-		return emperror.Wrap(err, "deploying cluster autoscaler failed")
-	}
-
-	logger.Info("adding labels to nodes")
-	if err := LabelNodes(cluster); err != nil {
-		return emperror.Wrap(err, "adding labels to nodes failed")
+		return err
 	}
 
 	logger.Info("cluster updated successfully")
@@ -334,7 +334,7 @@ func (o *OKECluster) SaveSshSecretId(sshSecretId string) error {
 
 // UpdateStatus updates cluster status in database
 func (o *OKECluster) UpdateStatus(status, statusMessage string) error {
-	return o.modelCluster.UpdateStatus(status, statusMessage)
+	return updateModelStatus(o, o.modelCluster, status, statusMessage)
 }
 
 // GetClusterDetails gets cluster details from cloud
@@ -0,0 +1,257 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"strings"
+
+	pipConfig "github.com/banzaicloud/pipeline/config"
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// postHookNode is a posthook function in the dependency graph of a posthook run.
+type postHookNode struct {
+	name     string
+	postHook PostFunctioner
+
+	// number of dependencies not finished yet
+	pending    int
+	dependents []*postHookNode
+}
+
+// postHookResult is the outcome of a posthook function executed by a worker.
+type postHookResult struct {
+	node *postHookNode
+	err  error
+}
+
+// newPostHookGraph builds the dependency graph of the given posthook functions and returns its nodes in the original order.
+// Dependencies which are not part of the run (eg. they already succeeded earlier) are considered satisfied.
+func newPostHookGraph(postHooks []PostFunctioner) ([]*postHookNode, error) {
+	var nodes []*postHookNode
+	nodesByName := make(map[string]*postHookNode, len(postHooks))
+
+	for _, postHook := range postHooks {
+		if postHook == nil {
+			continue
+		}
+
+		name := GetPostHookName(postHook)
+		if _, ok := nodesByName[name]; ok {
+			continue
+		}
+
+		node := &postHookNode{
+			name:     name,
+			postHook: postHook,
+		}
+
+		nodes = append(nodes, node)
+		nodesByName[name] = node
+	}
+
+	for _, node := range nodes {
+		for _, dependency := range GetPostHookDependencies(node.postHook) {
+			dependencyNode, ok := nodesByName[dependency]
+			if !ok {
+				continue
+			}
+
+			node.pending++
+			dependencyNode.dependents = append(dependencyNode.dependents, node)
+		}
+	}
+
+	if err := checkPostHookGraph(nodes); err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+// checkPostHookGraph makes sure that the dependencies of the posthook functions do not form a cycle.
+func checkPostHookGraph(nodes []*postHookNode) error {
+	pending := make(map[*postHookNode]int, len(nodes))
+	var queue []*postHookNode
+
+	for _, node := range nodes {
+		pending[node] = node.pending
+		if node.pending == 0 {
+			queue = append(queue, node)
+		}
+	}
+
+	visited := 0
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		visited++
+
+		for _, dependent := range node.dependents {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	if visited == len(nodes) {
+		return nil
+	}
+
+	var names []string
+	for _, node := range nodes {
+		if pending[node] > 0 {
+			names = append(names, node.name)
+		}
+	}
+
+	return errors.Errorf("posthook dependencies form a cycle: %s", strings.Join(names, ", "))
+}
+
+// postHookProgressFunc is notified about the status changes of the posthook functions in a graph run.
+// It is called on the goroutine running the graph, never concurrently.
+type postHookProgressFunc func(postHook string, status string)
+
+// runPostHookGraph runs the given posthook functions on a bounded number of workers.
+// A posthook function starts once all of its dependencies have succeeded.
+// After the first failure no new posthook functions are started, but the running ones are waited for.
+// Cluster status updates happen on the calling goroutine only.
+func runPostHookGraph(postHooks []PostFunctioner, cluster CommonCluster, log logrus.FieldLogger, progress postHookProgressFunc) error {
+	nodes, err := newPostHookGraph(postHooks)
+	if err != nil {
+		return err
+	}
+
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	workers := viper.GetInt(pipConfig.PostHookWorkers)
+	if workers < 1 {
+		workers = 1
+	}
+
+	if progress == nil {
+		progress = func(string, string) {}
+	}
+
+	// The cluster implementations load their credentials and API endpoint lazily.
+	// Loading them before the workers start leaves the concurrently running posthook functions with read-only access.
+	if _, err := cluster.GetK8sConfig(); err != nil {
+		log.Debugf("could not load kubeconfig before running posthooks: %s", err.Error())
+	}
+	if _, err := cluster.GetAPIEndpoint(); err != nil {
+		log.Debugf("could not load API endpoint before running posthooks: %s", err.Error())
+	}
+
+	return executePostHookGraph(
+		nodes,
+		workers,
+		func(node *postHookNode) {
+			progress(node.name, intCluster.PostHookRunning)
+		},
+		func(node *postHookNode) error {
+			return execPostHook(node.postHook, cluster, log)
+		},
+		func(node *postHookNode, err error) error {
+			if err != nil {
+				progress(node.name, intCluster.PostHookFailed)
+				node.postHook.Error(cluster, err)
+
+				return err
+			}
+
+			progress(node.name, intCluster.PostHookSucceeded)
+
+			return updatePostHookFinishedStatus(node.postHook, cluster, log)
+		},
+	)
+}
+
+// executePostHookGraph schedules the nodes of a posthook graph on a bounded number of workers.
+// Only exec is called on the workers, started and finished are called on the calling goroutine.
+// The first error returned by finished stops scheduling new nodes and is returned once the running ones are done.
+func executePostHookGraph(
+	nodes []*postHookNode,
+	workers int,
+	started func(node *postHookNode),
+	exec func(node *postHookNode) error,
+	finished func(node *postHookNode, err error) error,
+) error {
+	var ready []*postHookNode
+	for _, node := range nodes {
+		if node.pending == 0 {
+			ready = append(ready, node)
+		}
+	}
+
+	results := make(chan postHookResult)
+	running := 0
+
+	var firstErr error
+
+	for {
+		for firstErr == nil && running < workers && len(ready) > 0 {
+			node := ready[0]
+			ready = ready[1:]
+			running++
+
+			started(node)
+
+			go func(node *postHookNode) {
+				result := postHookResult{node: node}
+
+				defer func() {
+					if r := recover(); r != nil {
+						result.err = emperror.Recover(r)
+					}
+
+					results <- result
+				}()
+
+				result.err = exec(node)
+			}(node)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		result := <-results
+		running--
+
+		if err := finished(result.node, result.err); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+
+			continue
+		}
+
+		for _, dependent := range result.node.dependents {
+			dependent.pending--
+			if dependent.pending == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	return firstErr
+}
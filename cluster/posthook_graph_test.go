@@ -0,0 +1,179 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type testPostHook struct {
+	name      string
+	dependsOn []string
+}
+
+func (p *testPostHook) String() string                         { return p.name }
+func (p *testPostHook) DependsOn() []string                    { return p.dependsOn }
+func (p *testPostHook) Do(cluster CommonCluster) error         { return nil }
+func (p *testPostHook) Error(cluster CommonCluster, err error) {}
+
+func TestNewPostHookGraph(t *testing.T) {
+	nodes, err := newPostHookGraph([]PostFunctioner{
+		&testPostHook{name: "a"},
+		&testPostHook{name: "b", dependsOn: []string{"a", "missing"}},
+		&testPostHook{name: "c", dependsOn: []string{"a", "b"}},
+		nil,
+		&testPostHook{name: "a"},
+	})
+	if err != nil {
+		t.Fatal("unexpected error: ", err.Error())
+	}
+
+	pending := make(map[string]int)
+	for _, node := range nodes {
+		pending[node.name] = node.pending
+	}
+
+	// missing dependencies are satisfied, duplicates and nil posthooks are left out
+	if expected := map[string]int{"a": 0, "b": 1, "c": 2}; !reflect.DeepEqual(pending, expected) {
+		t.Errorf("expected pending dependencies %v, got %v", expected, pending)
+	}
+
+	_, err = newPostHookGraph([]PostFunctioner{
+		&testPostHook{name: "a"},
+		&testPostHook{name: "b", dependsOn: []string{"c"}},
+		&testPostHook{name: "c", dependsOn: []string{"b"}},
+	})
+	if err == nil {
+		t.Error("expected an error for a dependency cycle")
+	}
+}
+
+func TestExecutePostHookGraph(t *testing.T) {
+	nodes, err := newPostHookGraph([]PostFunctioner{
+		&testPostHook{name: "a"},
+		&testPostHook{name: "b"},
+		&testPostHook{name: "c"},
+		&testPostHook{name: "d", dependsOn: []string{"a", "b", "c"}},
+	})
+	if err != nil {
+		t.Fatal("unexpected error: ", err.Error())
+	}
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+
+	var finished []string
+
+	err = executePostHookGraph(
+		nodes,
+		2,
+		func(node *postHookNode) {},
+		func(node *postHookNode) error {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+
+			return nil
+		},
+		func(node *postHookNode, err error) error {
+			finished = append(finished, node.name)
+			return err
+		},
+	)
+	if err != nil {
+		t.Fatal("unexpected error: ", err.Error())
+	}
+
+	if maxRunning != 2 {
+		t.Errorf("expected 2 posthooks running at the same time, got %d", maxRunning)
+	}
+
+	if len(finished) != 4 || finished[3] != "d" {
+		t.Errorf("expected the dependent posthook to finish last, got %v", finished)
+	}
+}
+
+func TestExecutePostHookGraph_Failure(t *testing.T) {
+	nodes, err := newPostHookGraph([]PostFunctioner{
+		&testPostHook{name: "a"},
+		&testPostHook{name: "b"},
+		&testPostHook{name: "c", dependsOn: []string{"a"}},
+	})
+	if err != nil {
+		t.Fatal("unexpected error: ", err.Error())
+	}
+
+	var started []string
+
+	err = executePostHookGraph(
+		nodes,
+		1,
+		func(node *postHookNode) {
+			started = append(started, node.name)
+		},
+		func(node *postHookNode) error {
+			if node.name == "a" {
+				return errors.New("posthook failed")
+			}
+
+			panic("posthook panicked")
+		},
+		func(node *postHookNode, err error) error {
+			return err
+		},
+	)
+	if err == nil || err.Error() != "posthook failed" {
+		t.Fatalf("expected the first failure to be returned, got %v", err)
+	}
+
+	// no new posthooks are started after the first failure
+	if expected := []string{"a"}; !reflect.DeepEqual(started, expected) {
+		t.Errorf("expected started posthooks %v, got %v", expected, started)
+	}
+
+	nodes, err = newPostHookGraph([]PostFunctioner{&testPostHook{name: "b"}})
+	if err != nil {
+		t.Fatal("unexpected error: ", err.Error())
+	}
+
+	err = executePostHookGraph(
+		nodes,
+		1,
+		func(node *postHookNode) {},
+		func(node *postHookNode) error {
+			panic("posthook panicked")
+		},
+		func(node *postHookNode, err error) error {
+			return err
+		},
+	)
+	if err == nil {
+		t.Error("expected a panicking posthook to fail the run")
+	}
+}
@@ -0,0 +1,397 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	pipConfig "github.com/banzaicloud/pipeline/config"
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+type postHookRepository interface {
+	FindByCluster(organizationID uint, clusterID uint) ([]*intCluster.PostHookModel, error)
+	FindOne(organizationID uint, clusterID uint, name string) (*intCluster.PostHookModel, error)
+	Save(postHook *intCluster.PostHookModel) error
+	SwapStatus(postHook *intCluster.PostHookModel, from string, to string) (bool, error)
+	FailRunning() error
+}
+
+// clusterPostHooks returns the posthook status repository backed by the application database.
+func clusterPostHooks() postHookRepository {
+	return intCluster.NewPostHooks(pipConfig.DB())
+}
+
+// postHooksRunningError is returned when posthooks are retried while some of them are still running.
+type postHooksRunningError struct {
+	postHook string
+}
+
+func (e *postHooksRunningError) Error() string {
+	return "posthook function is still running: " + e.postHook
+}
+
+func (e *postHooksRunningError) PreconditionFailed() bool {
+	return true
+}
+
+// clusterWorkflowRunningError is returned when posthooks are retried while a workflow of the cluster is still running.
+type clusterWorkflowRunningError struct {
+	workflow string
+}
+
+func (e *clusterWorkflowRunningError) Error() string {
+	return "cluster workflow is still running: " + e.workflow
+}
+
+func (e *clusterWorkflowRunningError) PreconditionFailed() bool {
+	return true
+}
+
+// getPostHookFunctions returns the base posthook functions followed by the requested ones.
+func getPostHookFunctions(postHooks []PostFunctioner) []PostFunctioner {
+	// These are hardcoded posthooks maybe we will want a bit more dynamic
+	postHookFunctions := append([]PostFunctioner{}, BasePostHookFunctions...)
+
+	if len(postHooks) != 0 {
+		postHookFunctions = append(postHookFunctions, postHooks...)
+	}
+
+	return postHookFunctions
+}
+
+// registerPostHooks marks the given posthook functions pending for a cluster.
+// Already known posthooks keep their original position, new ones are appended to the end.
+func registerPostHooks(cluster CommonCluster, postHooks []PostFunctioner) error {
+	repository := clusterPostHooks()
+
+	records, err := repository.FindByCluster(cluster.GetOrganizationId(), cluster.GetID())
+	if err != nil {
+		return err
+	}
+
+	position := len(records)
+
+	for _, postHook := range postHooks {
+		if postHook == nil {
+			continue
+		}
+
+		name := GetPostHookName(postHook)
+
+		record, err := repository.FindOne(cluster.GetOrganizationId(), cluster.GetID(), name)
+		if err != nil {
+			return err
+		}
+
+		if record == nil {
+			record = &intCluster.PostHookModel{
+				OrganizationID: cluster.GetOrganizationId(),
+				ClusterID:      cluster.GetID(),
+				Name:           name,
+				Position:       position,
+			}
+			position++
+		}
+
+		record.Params = ""
+		if f, ok := postHook.(postFunctionWithParams); ok && f.Params() != nil {
+			params, err := json.Marshal(f.Params())
+			if err != nil {
+				return emperror.With(errors.Wrap(err, "could not marshal posthook params"), "posthook", name)
+			}
+
+			record.Params = string(params)
+		}
+
+		record.Status = intCluster.PostHookPending
+		record.Attempts = 0
+		record.StartedAt = nil
+		record.FinishedAt = nil
+		record.Duration = 0
+		record.ErrorMessage = ""
+
+		if err := repository.Save(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unfinishedPostHooks filters out the posthook functions which have already succeeded on a cluster.
+func unfinishedPostHooks(cluster CommonCluster, postHooks []PostFunctioner, log logrus.FieldLogger) []PostFunctioner {
+	records, err := clusterPostHooks().FindByCluster(cluster.GetOrganizationId(), cluster.GetID())
+	if err != nil {
+		log.Warnf("could not get posthook statuses, running every posthook: %s", err.Error())
+		return postHooks
+	}
+
+	succeeded := make(map[string]bool, len(records))
+	for _, record := range records {
+		succeeded[record.Name] = record.Status == intCluster.PostHookSucceeded
+	}
+
+	var unfinished []PostFunctioner
+	for _, postHook := range postHooks {
+		if postHook != nil && !succeeded[GetPostHookName(postHook)] {
+			unfinished = append(unfinished, postHook)
+		}
+	}
+
+	return unfinished
+}
+
+// startPostHook marks a posthook function running.
+func startPostHook(cluster CommonCluster, name string, log logrus.FieldLogger) *intCluster.PostHookModel {
+	repository := clusterPostHooks()
+
+	record, err := repository.FindOne(cluster.GetOrganizationId(), cluster.GetID(), name)
+	if err != nil {
+		log.Warnf("could not get posthook status [%s]: %s", name, err.Error())
+		return nil
+	}
+
+	if record == nil {
+		record = &intCluster.PostHookModel{
+			OrganizationID: cluster.GetOrganizationId(),
+			ClusterID:      cluster.GetID(),
+			Name:           name,
+		}
+	}
+
+	now := time.Now()
+
+	record.Status = intCluster.PostHookRunning
+	record.Attempts = 0
+	record.StartedAt = &now
+	record.FinishedAt = nil
+	record.Duration = 0
+	record.ErrorMessage = ""
+
+	if err := repository.Save(record); err != nil {
+		log.Warnf("could not save posthook status [%s]: %s", name, err.Error())
+		return nil
+	}
+
+	return record
+}
+
+// savePostHookAttempt records an attempt of a running posthook function.
+func savePostHookAttempt(record *intCluster.PostHookModel, attempt int, log logrus.FieldLogger) {
+	if record == nil {
+		return
+	}
+
+	record.Attempts = attempt
+
+	if err := clusterPostHooks().Save(record); err != nil {
+		log.Warnf("could not save posthook status [%s]: %s", record.Name, err.Error())
+	}
+}
+
+// finishPostHook records the outcome of a posthook function.
+func finishPostHook(record *intCluster.PostHookModel, postHookErr error, log logrus.FieldLogger) {
+	if record == nil {
+		return
+	}
+
+	now := time.Now()
+
+	record.FinishedAt = &now
+	if record.StartedAt != nil {
+		record.Duration = now.Sub(*record.StartedAt)
+	}
+
+	if postHookErr != nil {
+		record.Status = intCluster.PostHookFailed
+		record.ErrorMessage = postHookErr.Error()
+	} else {
+		record.Status = intCluster.PostHookSucceeded
+		record.ErrorMessage = ""
+	}
+
+	if err := clusterPostHooks().Save(record); err != nil {
+		log.Warnf("could not save posthook status [%s]: %s", record.Name, err.Error())
+	}
+}
+
+// GetPostHookStatuses returns the outcome of the posthook functions of a cluster in their original order.
+func (m *Manager) GetPostHookStatuses(ctx context.Context, organizationID uint, clusterID uint) ([]pkgCluster.PostHookStatus, error) {
+	logger := m.getLogger(ctx).WithFields(logrus.Fields{
+		"organization": organizationID,
+		"cluster":      clusterID,
+	})
+
+	logger.Debug("fetching posthook statuses from database")
+
+	records, err := clusterPostHooks().FindByCluster(organizationID, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]pkgCluster.PostHookStatus, 0, len(records))
+	for _, record := range records {
+		status := pkgCluster.PostHookStatus{
+			Name:         record.Name,
+			Position:     record.Position,
+			Status:       record.Status,
+			Attempts:     record.Attempts,
+			StartedAt:    record.StartedAt,
+			FinishedAt:   record.FinishedAt,
+			ErrorMessage: record.ErrorMessage,
+		}
+
+		if record.FinishedAt != nil {
+			status.Duration = record.Duration.String()
+		}
+
+		if record.Params != "" {
+			if err := json.Unmarshal([]byte(record.Params), &status.Params); err != nil {
+				logger.Warnf("could not unmarshal posthook params [%s]: %s", record.Name, err.Error())
+			}
+		}
+
+		response = append(response, status)
+	}
+
+	return response, nil
+}
+
+// RetryPostHooks reruns the failed and not yet run posthook functions of a cluster in their original order.
+// It returns the names of the posthook functions being retried.
+func (m *Manager) RetryPostHooks(ctx context.Context, cluster CommonCluster) ([]string, error) {
+	logger := m.getLogger(ctx).WithFields(logrus.Fields{
+		"organization": cluster.GetOrganizationId(),
+		"cluster":      cluster.GetName(),
+	})
+
+	// the posthooks of a running workflow are pending until the workflow gets to them
+	workflow, err := m.workflows.FindLastByCluster(cluster.GetOrganizationId(), cluster.GetID())
+	if err != nil && !isNotFoundError(err) {
+		return nil, err
+	} else if err == nil && workflow.Status == intCluster.WorkflowRunning {
+		return nil, &clusterWorkflowRunningError{workflow: workflow.Type}
+	}
+
+	repository := clusterPostHooks()
+
+	records, err := repository.FindByCluster(cluster.GetOrganizationId(), cluster.GetID())
+	if err != nil {
+		return nil, err
+	}
+
+	retried, err := retriedPostHooks(records)
+	if err != nil {
+		return nil, err
+	}
+
+	var postHooks []PostFunctioner
+	var names []string
+
+	for _, record := range retried {
+		var params pkgCluster.PostHookParam
+		if record.Params != "" {
+			if err := json.Unmarshal([]byte(record.Params), &params); err != nil {
+				return nil, emperror.With(errors.Wrap(err, "could not unmarshal posthook params"), "posthook", record.Name)
+			}
+		}
+
+		postHook, ok := GetOrganizationPostHookWithParams(cluster.GetOrganizationId(), record.Name, params)
+		if !ok {
+			return nil, errors.Errorf("there's no posthook function with this name [%s]", record.Name)
+		}
+
+		postHooks = append(postHooks, postHook)
+		names = append(names, record.Name)
+	}
+
+	if len(postHooks) == 0 {
+		logger.Info("there are no posthooks to retry")
+
+		return names, nil
+	}
+
+	if err := claimPostHooks(repository, retried, logger); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("retrying posthooks: %v", names)
+
+	go func() {
+		defer emperror.HandleRecover(m.errorHandler)
+
+		// posthooks not started because of a failed dependency are left as they were before the retry
+		defer releasePostHooks(repository, retried, logger)
+
+		if err := runRegisteredPostHooks(postHooks, cluster, logger); err != nil {
+			logger.Errorf("posthook retry failed: %s", err.Error())
+		}
+	}()
+
+	return names, nil
+}
+
+// retriedPostHooks returns the failed and not yet run posthooks.
+// Posthooks interrupted by a restart are failed on startup, so a running posthook means that a run is in progress.
+func retriedPostHooks(records []*intCluster.PostHookModel) ([]*intCluster.PostHookModel, error) {
+	var retried []*intCluster.PostHookModel
+
+	for _, record := range records {
+		switch record.Status {
+		case intCluster.PostHookRunning:
+			return nil, &postHooksRunningError{postHook: record.Name}
+
+		case intCluster.PostHookFailed, intCluster.PostHookPending:
+			retried = append(retried, record)
+		}
+	}
+
+	return retried, nil
+}
+
+// claimPostHooks marks the retried posthooks running, unless their status has been changed since they were fetched.
+// Either every posthook is claimed, or none of them, so concurrent retries can't run the same posthook twice.
+func claimPostHooks(repository postHookRepository, records []*intCluster.PostHookModel, log logrus.FieldLogger) error {
+	for i, record := range records {
+		claimed, err := repository.SwapStatus(record, record.Status, intCluster.PostHookRunning)
+		if err == nil && !claimed {
+			err = &postHooksRunningError{postHook: record.Name}
+		}
+
+		if err != nil {
+			releasePostHooks(repository, records[:i], log)
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// releasePostHooks restores the status the claimed posthooks had before they were claimed, unless they have been started since then.
+func releasePostHooks(repository postHookRepository, records []*intCluster.PostHookModel, log logrus.FieldLogger) {
+	for _, record := range records {
+		if _, err := repository.SwapStatus(record, intCluster.PostHookRunning, record.Status); err != nil {
+			log.Warnf("could not release posthook [%s]: %s", record.Name, err.Error())
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRetriedPostHooks(t *testing.T) {
+	cases := []struct {
+		name        string
+		statuses    map[string]string
+		expected    []string
+		expectedErr bool
+	}{
+		{
+			name:     "failed and pending",
+			statuses: map[string]string{"a": intCluster.PostHookSucceeded, "b": intCluster.PostHookFailed, "c": intCluster.PostHookPending},
+			expected: []string{"b", "c"},
+		},
+		{
+			name:     "all succeeded",
+			statuses: map[string]string{"a": intCluster.PostHookSucceeded, "b": intCluster.PostHookSucceeded},
+		},
+		{
+			name:        "running",
+			statuses:    map[string]string{"a": intCluster.PostHookFailed, "b": intCluster.PostHookRunning},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var records []*intCluster.PostHookModel
+			for _, name := range []string{"a", "b", "c"} {
+				if status, ok := tc.statuses[name]; ok {
+					records = append(records, &intCluster.PostHookModel{Name: name, Status: status})
+				}
+			}
+
+			retried, err := retriedPostHooks(records)
+			if tc.expectedErr {
+				if _, ok := err.(*postHooksRunningError); !ok {
+					t.Fatalf("expected a running posthook error, got %v", err)
+				}
+
+				return
+			} else if err != nil {
+				t.Fatal("unexpected error: ", err.Error())
+			}
+
+			var actual []string
+			for _, record := range retried {
+				actual = append(actual, record.Name)
+			}
+
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected retried posthooks %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+// inmemoryPostHooks stores the statuses of posthooks by ID
+type inmemoryPostHooks struct {
+	postHookRepository
+
+	statuses map[uint]string
+}
+
+func (p *inmemoryPostHooks) SwapStatus(postHook *intCluster.PostHookModel, from string, to string) (bool, error) {
+	if p.statuses[postHook.ID] != from {
+		return false, nil
+	}
+
+	p.statuses[postHook.ID] = to
+
+	return true, nil
+}
+
+func TestClaimPostHooks(t *testing.T) {
+	log := logrus.New()
+	log.Out = ioutil.Discard
+
+	records := []*intCluster.PostHookModel{
+		{ID: 1, Name: "a", Status: intCluster.PostHookFailed},
+		{ID: 2, Name: "b", Status: intCluster.PostHookPending},
+	}
+
+	t.Run("claimed", func(t *testing.T) {
+		repository := &inmemoryPostHooks{statuses: map[uint]string{1: intCluster.PostHookFailed, 2: intCluster.PostHookPending}}
+
+		if err := claimPostHooks(repository, records, log); err != nil {
+			t.Fatal("unexpected error: ", err.Error())
+		}
+
+		expected := map[uint]string{1: intCluster.PostHookRunning, 2: intCluster.PostHookRunning}
+		if !reflect.DeepEqual(repository.statuses, expected) {
+			t.Errorf("expected statuses %v, got %v", expected, repository.statuses)
+		}
+
+		if err := claimPostHooks(repository, records, log); err == nil {
+			t.Error("expected claimed posthooks not to be claimed again")
+		}
+
+		// the first posthook finished, the second one was not started
+		repository.statuses[1] = intCluster.PostHookSucceeded
+
+		releasePostHooks(repository, records, log)
+
+		expected = map[uint]string{1: intCluster.PostHookSucceeded, 2: intCluster.PostHookPending}
+		if !reflect.DeepEqual(repository.statuses, expected) {
+			t.Errorf("expected statuses %v, got %v", expected, repository.statuses)
+		}
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		repository := &inmemoryPostHooks{statuses: map[uint]string{1: intCluster.PostHookFailed, 2: intCluster.PostHookRunning}}
+
+		err := claimPostHooks(repository, records, log)
+		if _, ok := err.(*postHooksRunningError); !ok {
+			t.Fatalf("expected a running posthook error, got %v", err)
+		}
+
+		expected := map[uint]string{1: intCluster.PostHookFailed, 2: intCluster.PostHookRunning}
+		if !reflect.DeepEqual(repository.statuses, expected) {
+			t.Errorf("expected statuses %v, got %v", expected, repository.statuses)
+		}
+	})
+}
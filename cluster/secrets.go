@@ -15,15 +15,38 @@
 package cluster
 
 import (
+	"context"
+
+	pipConfig "github.com/banzaicloud/pipeline/config"
 	"github.com/banzaicloud/pipeline/helm"
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
 	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
 	"github.com/banzaicloud/pipeline/secret"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 	"k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+type secretInstallationRepository interface {
+	FindAll() ([]*intCluster.SecretInstallationModel, error)
+	FindBySecret(organizationID uint, secretID string) ([]*intCluster.SecretInstallationModel, error)
+	FindByCluster(organizationID uint, clusterID uint) ([]*intCluster.SecretInstallationModel, error)
+	Save(installation *intCluster.SecretInstallationModel) error
+	Delete(installation *intCluster.SecretInstallationModel) error
+	DeleteByCluster(organizationID uint, clusterID uint) error
+}
+
+// secretInstallations returns the secret installation repository backed by the application database.
+var secretInstallations = func() secretInstallationRepository {
+	return intCluster.NewSecretInstallations(pipConfig.DB())
+}
+
 // InstallSecrets installs or updates secrets that matches the query under the name into namespace of a Kubernetes cluster.
 // It returns the list of installed secret names and meta about how to mount them.
+// The installations are recorded, so the secrets are updated in the cluster when they change.
 func InstallSecrets(cc CommonCluster, query *secretTypes.ListSecretsQuery, namespace string) ([]secretTypes.K8SSourceMeta, error) {
 
 	k8sConfig, err := cc.GetK8sConfig()
@@ -32,7 +55,16 @@ func InstallSecrets(cc CommonCluster, query *secretTypes.ListSecretsQuery, names
 		return nil, err
 	}
 
-	return InstallSecretsByK8SConfig(k8sConfig, cc.GetOrganizationId(), query, namespace)
+	secretSources, err := InstallSecretsByK8SConfig(k8sConfig, cc.GetOrganizationId(), query, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := trackSecretInstallations(cc, secretSources, namespace); err != nil {
+		log.Errorf("Error during recording secret installations: %s", err.Error())
+	}
+
+	return secretSources, nil
 }
 
 // InstallSecretsByK8SConfig is the same as InstallSecrets but use this if you already have a K8S config at hand.
@@ -94,20 +126,33 @@ func InstallSecretsByK8SConfig(k8sConfig []byte, orgID uint, query *secretTypes.
 			}
 		}
 
-		for fieldName, fieldValue := range s.Values {
-			secretMeta := secretTypes.DefaultRules[s.Type]
-			opaque := false
-			// Generic secrets are not opaque at all
-			if s.Type != secretTypes.GenericSecret {
-				for _, fieldMeta := range secretMeta.Fields {
-					if fieldName == fieldMeta.Name {
-						opaque = fieldMeta.Opaque
-						break
+		if secretTypes.IsRegistrySecretType(s.Type) {
+			// Registry secrets are installed in the format expected by image pull secrets
+			dockerConfig, err := secret.DockerConfigJSON(orgID, s)
+			if err != nil {
+				log.Errorf("Error during creating docker config of registry secret: %s", err.Error())
+				return nil, err
+			}
+
+			k8sSecret.Type = v1.SecretTypeDockerConfigJson
+			k8sSecret.Data = map[string][]byte{v1.DockerConfigJsonKey: dockerConfig}
+			k8sSecret.StringData = nil
+		} else {
+			for fieldName, fieldValue := range s.Values {
+				secretMeta := secretTypes.DefaultRules[s.Type]
+				opaque := false
+				// Generic secrets are not opaque at all
+				if s.Type != secretTypes.GenericSecret {
+					for _, fieldMeta := range secretMeta.Fields {
+						if fieldName == fieldMeta.Name {
+							opaque = fieldMeta.Opaque
+							break
+						}
 					}
 				}
-			}
-			if !opaque {
-				k8sSecret.StringData[fieldName] = fieldValue
+				if !opaque {
+					k8sSecret.StringData[fieldName] = fieldValue
+				}
 			}
 		}
 
@@ -127,3 +172,289 @@ func InstallSecretsByK8SConfig(k8sConfig []byte, orgID uint, query *secretTypes.
 
 	return secretSources, nil
 }
+
+// AddImagePullSecrets adds the container registry secrets that matches the query to the image pull secrets
+// of the default service account in a namespace of a Kubernetes cluster.
+// The secrets should already be installed into the namespace.
+func AddImagePullSecrets(cc CommonCluster, query *secretTypes.ListSecretsQuery, namespace string) error {
+	secrets, err := secret.Store.List(cc.GetOrganizationId(), &secretTypes.ListSecretsQuery{
+		Type: query.Type,
+		Tags: query.Tags,
+		IDs:  query.IDs,
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not list secrets")
+	}
+
+	k8sConfig, err := cc.GetK8sConfig()
+	if err != nil {
+		return errors.Wrap(err, "could not get k8s config")
+	}
+
+	client, err := helm.GetK8sConnection(k8sConfig)
+	if err != nil {
+		return errors.Wrap(err, "could not create k8s client")
+	}
+
+	serviceAccount, err := client.CoreV1().ServiceAccounts(namespace).Get("default", metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "could not get default service account")
+	}
+
+	changed := false
+
+	for _, s := range secrets {
+		if !secretTypes.IsRegistrySecretType(s.Type) || hasImagePullSecret(serviceAccount, s.Name) {
+			continue
+		}
+
+		serviceAccount.ImagePullSecrets = append(serviceAccount.ImagePullSecrets, v1.LocalObjectReference{Name: s.Name})
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	_, err = client.CoreV1().ServiceAccounts(namespace).Update(serviceAccount)
+
+	return errors.Wrap(err, "could not update default service account")
+}
+
+func hasImagePullSecret(serviceAccount *v1.ServiceAccount, name string) bool {
+	for _, reference := range serviceAccount.ImagePullSecrets {
+		if reference.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// trackSecretInstallations records the secrets installed into a namespace of a cluster, so they can be updated later.
+func trackSecretInstallations(cc CommonCluster, secretSources []secretTypes.K8SSourceMeta, namespace string) error {
+	for _, secretSource := range secretSources {
+		err := secretInstallations().Save(&intCluster.SecretInstallationModel{
+			OrganizationID: cc.GetOrganizationId(),
+			SecretID:       secret.GenerateSecretIDFromName(secretSource.Name),
+			ClusterID:      cc.GetID(),
+			Namespace:      namespace,
+			Name:           secretSource.Name,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ResyncSecret updates the copies of a secret in the clusters it has been installed into.
+// Installations into clusters which do not exist anymore are forgotten.
+func (m *Manager) ResyncSecret(ctx context.Context, organizationID uint, secretID string) error {
+	logger := m.getLogger(ctx).WithFields(logrus.Fields{
+		"organization": organizationID,
+		"secret":       secretID,
+	})
+
+	installations, err := secretInstallations().FindBySecret(organizationID, secretID)
+	if err != nil {
+		return err
+	}
+
+	errorHandler := m.getErrorHandler(ctx)
+	failed := 0
+
+	for _, installation := range installations {
+		logger := logger.WithFields(logrus.Fields{
+			"cluster":   installation.ClusterID,
+			"namespace": installation.Namespace,
+		})
+
+		cluster, err := m.GetClusterByID(ctx, organizationID, installation.ClusterID)
+		if isNotFoundError(err) {
+			logger.Info("cluster does not exist anymore, forgetting secret installation")
+
+			if err := secretInstallations().Delete(installation); err != nil {
+				errorHandler.Handle(err)
+			}
+
+			continue
+		} else if err != nil {
+			errorHandler.Handle(err)
+			failed++
+			continue
+		}
+
+		logger.Info("updating secret in cluster")
+
+		_, err = InstallSecrets(cluster, &secretTypes.ListSecretsQuery{IDs: []string{secretID}}, installation.Namespace)
+		if err != nil {
+			errorHandler.Handle(emperror.With(
+				errors.Wrap(err, "could not update secret in cluster"),
+				"organization", organizationID,
+				"secret", secretID,
+				"cluster", installation.ClusterID,
+				"namespace", installation.Namespace,
+			))
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return errors.Errorf("could not update secret in %d of %d installations", failed, len(installations))
+	}
+
+	return nil
+}
+
+// RefreshRegistrySecrets issues new tokens for the installed container registry secrets using short-lived tokens (ECR, GCR and ACR).
+func (m *Manager) RefreshRegistrySecrets(ctx context.Context) error {
+	installations, err := secretInstallations().FindAll()
+	if err != nil {
+		return err
+	}
+
+	type secretKey struct {
+		organizationID uint
+		secretID       string
+	}
+
+	refreshed := make(map[secretKey]bool)
+	errorHandler := m.getErrorHandler(ctx)
+
+	for _, installation := range installations {
+		key := secretKey{installation.OrganizationID, installation.SecretID}
+		if refreshed[key] {
+			continue
+		}
+		refreshed[key] = true
+
+		s, err := secret.Store.Get(installation.OrganizationID, installation.SecretID)
+		if err == secret.ErrSecretNotExists {
+			continue
+		} else if err != nil {
+			errorHandler.Handle(err)
+			continue
+		}
+
+		if !secretTypes.IsRegistrySecretType(s.Type) || s.Type == secretTypes.DockerConfigJSONSecretType {
+			continue
+		}
+
+		if err := m.ResyncSecret(ctx, installation.OrganizationID, installation.SecretID); err != nil {
+			errorHandler.Handle(err)
+		}
+	}
+
+	return nil
+}
+
+// GetSecretInstallations returns the clusters an organization secret has been installed into.
+func GetSecretInstallations(organizationID uint, secretID string) ([]secretTypes.SecretInstallation, error) {
+	installations, err := secretInstallations().FindBySecret(organizationID, secretID)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertSecretInstallations(installations), nil
+}
+
+// GetClusterSecretInstallations returns the organization secrets installed into a cluster by secret ID.
+func GetClusterSecretInstallations(cc CommonCluster) (map[string][]secretTypes.SecretInstallation, error) {
+	installations, err := secretInstallations().FindByCluster(cc.GetOrganizationId(), cc.GetID())
+	if err != nil {
+		return nil, err
+	}
+
+	installationsBySecret := make(map[string][]secretTypes.SecretInstallation)
+	for _, installation := range installations {
+		installationsBySecret[installation.SecretID] = append(
+			installationsBySecret[installation.SecretID],
+			convertSecretInstallations([]*intCluster.SecretInstallationModel{installation})...,
+		)
+	}
+
+	return installationsBySecret, nil
+}
+
+func convertSecretInstallations(installations []*intCluster.SecretInstallationModel) []secretTypes.SecretInstallation {
+	result := make([]secretTypes.SecretInstallation, 0, len(installations))
+	for _, installation := range installations {
+		result = append(result, secretTypes.SecretInstallation{
+			ClusterID: installation.ClusterID,
+			Namespace: installation.Namespace,
+			Name:      installation.Name,
+			UpdatedAt: installation.UpdatedAt,
+		})
+	}
+
+	return result
+}
+
+// UninstallSecret deletes the copies of a secret from the clusters it has been installed into.
+func (m *Manager) UninstallSecret(ctx context.Context, organizationID uint, secretID string) error {
+	logger := m.getLogger(ctx).WithFields(logrus.Fields{
+		"organization": organizationID,
+		"secret":       secretID,
+	})
+
+	installations, err := secretInstallations().FindBySecret(organizationID, secretID)
+	if err != nil {
+		return err
+	}
+
+	for _, installation := range installations {
+		logger := logger.WithFields(logrus.Fields{
+			"cluster":   installation.ClusterID,
+			"namespace": installation.Namespace,
+		})
+
+		cluster, err := m.GetClusterByID(ctx, organizationID, installation.ClusterID)
+		if err != nil && !isNotFoundError(err) {
+			return err
+		}
+
+		if cluster != nil {
+			logger.Info("deleting secret from cluster")
+
+			if err := deleteInstalledSecret(cluster, installation); err != nil {
+				return emperror.With(
+					errors.Wrap(err, "could not delete secret from cluster"),
+					"cluster", installation.ClusterID,
+					"namespace", installation.Namespace,
+				)
+			}
+		}
+
+		if err := secretInstallations().Delete(installation); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func deleteInstalledSecret(cluster CommonCluster, installation *intCluster.SecretInstallationModel) error {
+	k8sConfig, err := cluster.GetK8sConfig()
+	if err != nil {
+		return errors.Wrap(err, "could not get k8s config")
+	}
+
+	client, err := helm.GetK8sConnection(k8sConfig)
+	if err != nil {
+		return errors.Wrap(err, "could not create k8s client")
+	}
+
+	err = client.CoreV1().Secrets(installation.Namespace).Delete(installation.Name, &metav1.DeleteOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// forgetSecretInstallations deletes the installation records of a deleted cluster.
+func forgetSecretInstallations(cluster CommonCluster) error {
+	return secretInstallations().DeleteByCluster(cluster.GetOrganizationId(), cluster.GetID())
+}
@@ -0,0 +1,223 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
+	"github.com/banzaicloud/pipeline/model"
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+type inmemorySecretInstallations struct {
+	installations []*intCluster.SecretInstallationModel
+}
+
+func (r *inmemorySecretInstallations) FindAll() ([]*intCluster.SecretInstallationModel, error) {
+	return append([]*intCluster.SecretInstallationModel(nil), r.installations...), nil
+}
+
+func (r *inmemorySecretInstallations) FindBySecret(organizationID uint, secretID string) ([]*intCluster.SecretInstallationModel, error) {
+	var installations []*intCluster.SecretInstallationModel
+	for _, installation := range r.installations {
+		if installation.OrganizationID == organizationID && installation.SecretID == secretID {
+			installations = append(installations, installation)
+		}
+	}
+
+	return installations, nil
+}
+
+func (r *inmemorySecretInstallations) FindByCluster(organizationID uint, clusterID uint) ([]*intCluster.SecretInstallationModel, error) {
+	var installations []*intCluster.SecretInstallationModel
+	for _, installation := range r.installations {
+		if installation.OrganizationID == organizationID && installation.ClusterID == clusterID {
+			installations = append(installations, installation)
+		}
+	}
+
+	return installations, nil
+}
+
+func (r *inmemorySecretInstallations) Save(installation *intCluster.SecretInstallationModel) error {
+	r.installations = append(r.installations, installation)
+
+	return nil
+}
+
+func (r *inmemorySecretInstallations) Delete(installation *intCluster.SecretInstallationModel) error {
+	for i, item := range r.installations {
+		if item == installation {
+			r.installations = append(r.installations[:i], r.installations[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+func (r *inmemorySecretInstallations) DeleteByCluster(organizationID uint, clusterID uint) error {
+	for _, installation := range r.installations {
+		if installation.OrganizationID == organizationID && installation.ClusterID == clusterID {
+			r.Delete(installation)
+		}
+	}
+
+	return nil
+}
+
+// testClusters is a cluster repository where clusters can only be looked up by ID, the lookup returns err.
+type testClusters struct {
+	clusterRepository
+
+	err error
+}
+
+func (c *testClusters) FindOneByID(organizationID uint, clusterID uint) (*model.ClusterModel, error) {
+	return nil, c.err
+}
+
+func TestManager_UninstallSecret(t *testing.T) {
+	original := secretInstallations
+	defer func() { secretInstallations = original }()
+
+	cases := []struct {
+		name        string
+		clusterErr  error
+		expectedErr bool
+		remaining   int
+	}{
+		{
+			name:       "deleted cluster",
+			clusterErr: testNotFoundError{},
+			remaining:  1,
+		},
+		{
+			name:        "cluster lookup failure",
+			clusterErr:  errors.New("database is down"),
+			expectedErr: true,
+			remaining:   2,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repository := &inmemorySecretInstallations{
+				installations: []*intCluster.SecretInstallationModel{
+					{OrganizationID: 1, ClusterID: 1, SecretID: "secret", Namespace: "default"},
+					{OrganizationID: 1, ClusterID: 1, SecretID: "other", Namespace: "default"},
+				},
+			}
+			secretInstallations = func() secretInstallationRepository { return repository }
+
+			manager := NewManager(&testClusters{err: tc.clusterErr}, nil, nil, logrus.New(), emperror.NewNopHandler())
+
+			err := manager.UninstallSecret(context.Background(), 1, "secret")
+			if tc.expectedErr && err == nil {
+				t.Error("expected an error")
+			} else if !tc.expectedErr && err != nil {
+				t.Error("unexpected error: ", err.Error())
+			}
+
+			if len(repository.installations) != tc.remaining {
+				t.Errorf("expected %d remaining installations, got %d", tc.remaining, len(repository.installations))
+			}
+		})
+	}
+}
+
+func TestManager_ResyncSecret_DeletedCluster(t *testing.T) {
+	original := secretInstallations
+	defer func() { secretInstallations = original }()
+
+	repository := &inmemorySecretInstallations{
+		installations: []*intCluster.SecretInstallationModel{
+			{OrganizationID: 1, ClusterID: 1, SecretID: "secret", Namespace: "default"},
+		},
+	}
+	secretInstallations = func() secretInstallationRepository { return repository }
+
+	manager := NewManager(&testClusters{err: testNotFoundError{}}, nil, nil, logrus.New(), emperror.NewNopHandler())
+
+	if err := manager.ResyncSecret(context.Background(), 1, "secret"); err != nil {
+		t.Fatal("unexpected error: ", err.Error())
+	}
+
+	if len(repository.installations) != 0 {
+		t.Errorf("expected the installation of the deleted cluster to be forgotten, got %d installations", len(repository.installations))
+	}
+}
+
+func TestManager_RefreshRegistrySecrets(t *testing.T) {
+	original := secretInstallations
+	defer func() { secretInstallations = original }()
+
+	ecrSecretID, err := secret.Store.Store(1, &secret.CreateSecretRequest{
+		Name: "refresh-ecr",
+		Type: secretTypes.ECRSecretType,
+		Values: map[string]string{
+			secretTypes.RegistryURL:   "123456789012.dkr.ecr.eu-west-1.amazonaws.com",
+			secretTypes.CloudSecretID: "aws",
+		},
+	})
+	if err != nil {
+		t.Fatal("could not store secret: ", err.Error())
+	}
+
+	dockerSecretID, err := secret.Store.Store(1, &secret.CreateSecretRequest{
+		Name: "refresh-docker",
+		Type: secretTypes.DockerConfigJSONSecretType,
+		Values: map[string]string{
+			secretTypes.RegistryURL: "registry.example.com",
+			secretTypes.Username:    "user",
+			secretTypes.Password:    "password",
+		},
+	})
+	if err != nil {
+		t.Fatal("could not store secret: ", err.Error())
+	}
+
+	repository := &inmemorySecretInstallations{
+		installations: []*intCluster.SecretInstallationModel{
+			{OrganizationID: 1, ClusterID: 1, SecretID: ecrSecretID, Namespace: "default"},
+			{OrganizationID: 1, ClusterID: 1, SecretID: dockerSecretID, Namespace: "default"},
+			{OrganizationID: 1, ClusterID: 1, SecretID: "deleted", Namespace: "default"},
+		},
+	}
+	secretInstallations = func() secretInstallationRepository { return repository }
+
+	// the cluster is gone, so resynced installations get forgotten
+	manager := NewManager(&testClusters{err: testNotFoundError{}}, nil, nil, logrus.New(), emperror.NewNopHandler())
+
+	if err := manager.RefreshRegistrySecrets(context.Background()); err != nil {
+		t.Fatal("unexpected error: ", err.Error())
+	}
+
+	for _, installation := range repository.installations {
+		if installation.SecretID == ecrSecretID {
+			t.Error("expected the ECR secret to be resynced")
+		}
+	}
+
+	if len(repository.installations) != 2 {
+		t.Errorf("expected only the ECR secret to be resynced, got %d remaining installations", len(repository.installations))
+	}
+}
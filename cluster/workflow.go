@@ -0,0 +1,326 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+type workflowRepository interface {
+	Save(workflow *intCluster.WorkflowModel) error
+	FindRunning() ([]*intCluster.WorkflowModel, error)
+	FindLastByCluster(organizationID uint, clusterID uint) (*intCluster.WorkflowModel, error)
+}
+
+// workflowStep is a single persisted step of a cluster workflow.
+type workflowStep struct {
+	Name   string                   `json:"name"`
+	Params pkgCluster.PostHookParam `json:"params,omitempty"`
+
+	// PostHooks are the posthook functions run concurrently by the step
+	PostHooks []workflowStep `json:"postHooks,omitempty"`
+
+	// Status is the status of a posthook function run by the step
+	Status string `json:"status,omitempty"`
+}
+
+// workflowStepFunc executes a single workflow step.
+type workflowStepFunc func(step workflowStep) error
+
+// createWorkflowData is the data necessary to resume a create workflow.
+type createWorkflowData struct {
+	Request *pkgCluster.CreateClusterRequest `json:"request"`
+}
+
+// updateWorkflowData is the data necessary to resume an update workflow.
+type updateWorkflowData struct {
+	Request *pkgCluster.UpdateClusterRequest `json:"request"`
+	UserID  uint                             `json:"userId"`
+}
+
+// deleteWorkflowData is the data necessary to resume a delete workflow.
+type deleteWorkflowData struct {
+	Force bool `json:"force"`
+}
+
+// newWorkflow persists a new workflow for a cluster in running state.
+func (m *Manager) newWorkflow(
+	workflowType string,
+	cluster CommonCluster,
+	userID uint,
+	steps []workflowStep,
+	data interface{},
+) (*intCluster.WorkflowModel, error) {
+	rawSteps, err := json.Marshal(steps)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal workflow steps")
+	}
+
+	workflow := &intCluster.WorkflowModel{
+		OrganizationID: cluster.GetOrganizationId(),
+		ClusterID:      cluster.GetID(),
+		ClusterName:    cluster.GetName(),
+		UserID:         userID,
+		Type:           workflowType,
+		Status:         intCluster.WorkflowRunning,
+		Steps:          string(rawSteps),
+	}
+
+	if data != nil {
+		rawData, err := json.Marshal(data)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not marshal workflow data")
+		}
+
+		workflow.Data = string(rawData)
+	}
+
+	if err := m.workflows.Save(workflow); err != nil {
+		return nil, err
+	}
+
+	return workflow, nil
+}
+
+// runWorkflow executes the steps of a workflow starting from the first unfinished one.
+// Progress is saved after every step, so the workflow can be resumed after a restart.
+func (m *Manager) runWorkflow(workflow *intCluster.WorkflowModel, run workflowStepFunc, logger logrus.FieldLogger) error {
+	var steps []workflowStep
+	if err := json.Unmarshal([]byte(workflow.Steps), &steps); err != nil {
+		return errors.Wrap(err, "could not unmarshal workflow steps")
+	}
+
+	for i := workflow.FinishedSteps; i < len(steps); i++ {
+		step := steps[i]
+
+		workflow.CurrentStep = step.Name
+		if err := m.workflows.Save(workflow); err != nil {
+			return err
+		}
+
+		logger.WithField("step", step.Name).Info("running workflow step")
+
+		if err := run(step); err != nil {
+			workflow.Status = intCluster.WorkflowFailed
+			workflow.ErrorMessage = err.Error()
+			if err := m.workflows.Save(workflow); err != nil {
+				logger.Errorf("could not save failed workflow: %s", err.Error())
+			}
+
+			return emperror.With(err, "step", step.Name)
+		}
+
+		workflow.FinishedSteps = i + 1
+		if err := m.workflows.Save(workflow); err != nil {
+			return err
+		}
+	}
+
+	workflow.CurrentStep = ""
+	workflow.Status = intCluster.WorkflowFinished
+
+	return m.workflows.Save(workflow)
+}
+
+// ResumeWorkflows continues every workflow that was interrupted by a restart of Pipeline.
+// Posthooks interrupted by the restart are marked failed, so that they can be retried.
+func (m *Manager) ResumeWorkflows(ctx context.Context) error {
+	logger := m.getLogger(ctx)
+
+	// posthooks left running by the restart are failed, resumed workflows run them again
+	if err := clusterPostHooks().FailRunning(); err != nil {
+		return err
+	}
+
+	workflows, err := m.workflows.FindRunning()
+	if err != nil {
+		return err
+	}
+
+	for _, workflow := range workflows {
+		workflow := workflow
+
+		logger.WithFields(logrus.Fields{
+			"organization": workflow.OrganizationID,
+			"cluster":      workflow.ClusterID,
+			"workflow":     workflow.Type,
+		}).Info("resuming cluster workflow")
+
+		go func() {
+			defer emperror.HandleRecover(m.errorHandler)
+
+			err := m.resumeWorkflow(ctx, workflow)
+			if err != nil {
+				m.getErrorHandler(ctx).Handle(emperror.With(
+					err,
+					"organization", workflow.OrganizationID,
+					"cluster", workflow.ClusterID,
+					"workflow", workflow.Type,
+				))
+			}
+		}()
+	}
+
+	return nil
+}
+
+func (m *Manager) resumeWorkflow(ctx context.Context, workflow *intCluster.WorkflowModel) error {
+	logger := m.getLogger(ctx).WithFields(logrus.Fields{
+		"organization": workflow.OrganizationID,
+		"cluster":      workflow.ClusterID,
+	})
+
+	clusterModel, err := m.clusters.FindOneByID(workflow.OrganizationID, workflow.ClusterID)
+	if err != nil {
+		if workflow.Type == intCluster.WorkflowDelete && isNotFoundError(err) {
+			// the cluster has already been removed from the database, only the cleanup is left
+			return m.finishDeleteWorkflow(workflow, logger)
+		}
+
+		return m.failWorkflow(workflow, err)
+	}
+
+	cluster, err := m.getClusterFromModel(clusterModel)
+	if err != nil {
+		return m.failWorkflow(workflow, err)
+	}
+
+	switch workflow.Type {
+	case intCluster.WorkflowCreate:
+		var data createWorkflowData
+		if workflow.Data != "" {
+			if err := json.Unmarshal([]byte(workflow.Data), &data); err != nil {
+				return m.failWorkflow(workflow, errors.Wrap(err, "could not unmarshal workflow data"))
+			}
+		}
+
+		creator := NewCommonClusterCreator(data.Request, cluster)
+
+		return m.runCreateWorkflow(ctx, workflow, cluster, creator, logger)
+
+	case intCluster.WorkflowUpdate:
+		var data updateWorkflowData
+		if err := json.Unmarshal([]byte(workflow.Data), &data); err != nil {
+			return m.failWorkflow(workflow, errors.Wrap(err, "could not unmarshal workflow data"))
+		}
+
+		updater := NewCommonClusterUpdater(data.Request, cluster, data.UserID)
+
+		return m.runUpdateWorkflow(ctx, workflow, cluster, updater, logger)
+
+	case intCluster.WorkflowDelete:
+		var data deleteWorkflowData
+		if err := json.Unmarshal([]byte(workflow.Data), &data); err != nil {
+			return m.failWorkflow(workflow, errors.Wrap(err, "could not unmarshal workflow data"))
+		}
+
+		// the proxy cache does not survive a restart, so there is nothing to clean up in it
+		return m.deleteCluster(ctx, workflow, cluster, data.Force, new(sync.Map))
+
+	default:
+		return m.failWorkflow(workflow, errors.Errorf("unknown workflow type: %s", workflow.Type))
+	}
+}
+
+// savePostHookStep records the status of a posthook function run by a workflow step.
+func (m *Manager) savePostHookStep(workflow *intCluster.WorkflowModel, postHook string, status string) error {
+	var steps []workflowStep
+	if err := json.Unmarshal([]byte(workflow.Steps), &steps); err != nil {
+		return errors.Wrap(err, "could not unmarshal workflow steps")
+	}
+
+	for i := range steps {
+		for j := range steps[i].PostHooks {
+			if steps[i].PostHooks[j].Name == postHook {
+				steps[i].PostHooks[j].Status = status
+			}
+		}
+	}
+
+	rawSteps, err := json.Marshal(steps)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal workflow steps")
+	}
+
+	workflow.Steps = string(rawSteps)
+
+	return m.workflows.Save(workflow)
+}
+
+func (m *Manager) failWorkflow(workflow *intCluster.WorkflowModel, err error) error {
+	workflow.Status = intCluster.WorkflowFailed
+	workflow.ErrorMessage = err.Error()
+
+	if err := m.workflows.Save(workflow); err != nil {
+		return err
+	}
+
+	return err
+}
+
+// GetClusterWorkflow returns the status of the last workflow of a cluster.
+func (m *Manager) GetClusterWorkflow(ctx context.Context, organizationID uint, clusterID uint) (*pkgCluster.WorkflowStatus, error) {
+	workflow, err := m.workflows.FindLastByCluster(organizationID, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []workflowStep
+	if err := json.Unmarshal([]byte(workflow.Steps), &steps); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal workflow steps")
+	}
+
+	status := &pkgCluster.WorkflowStatus{
+		Type:          workflow.Type,
+		Status:        workflow.Status,
+		CurrentStep:   workflow.CurrentStep,
+		Steps:         make([]string, 0, len(steps)),
+		FinishedSteps: workflow.FinishedSteps,
+		ErrorMessage:  workflow.ErrorMessage,
+		StartedAt:     workflow.CreatedAt,
+		UpdatedAt:     workflow.UpdatedAt,
+	}
+
+	for _, step := range steps {
+		status.Steps = append(status.Steps, step.Name)
+
+		for _, postHook := range step.PostHooks {
+			status.PostHooks = append(status.PostHooks, pkgCluster.WorkflowPostHookStatus{
+				Name:   postHook.Name,
+				Status: postHook.Status,
+			})
+		}
+	}
+
+	return status, nil
+}
+
+func isNotFoundError(err error) bool {
+	if e, ok := errors.Cause(err).(interface {
+		NotFound() bool
+	}); ok {
+		return e.NotFound()
+	}
+
+	return false
+}
@@ -0,0 +1,193 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+type inmemoryWorkflows struct {
+	saved []intCluster.WorkflowModel
+}
+
+func (w *inmemoryWorkflows) Save(workflow *intCluster.WorkflowModel) error {
+	w.saved = append(w.saved, *workflow)
+
+	return nil
+}
+
+func (w *inmemoryWorkflows) FindRunning() ([]*intCluster.WorkflowModel, error) {
+	return nil, nil
+}
+
+func (w *inmemoryWorkflows) FindLastByCluster(organizationID uint, clusterID uint) (*intCluster.WorkflowModel, error) {
+	return nil, nil
+}
+
+func newTestWorkflow(t *testing.T, steps ...string) *intCluster.WorkflowModel {
+	var workflowSteps []workflowStep
+	for _, step := range steps {
+		workflowSteps = append(workflowSteps, workflowStep{Name: step})
+	}
+
+	rawSteps, err := json.Marshal(workflowSteps)
+	if err != nil {
+		t.Fatal("could not marshal steps: ", err.Error())
+	}
+
+	return &intCluster.WorkflowModel{
+		Type:   intCluster.WorkflowCreate,
+		Status: intCluster.WorkflowRunning,
+		Steps:  string(rawSteps),
+	}
+}
+
+func TestRunWorkflow(t *testing.T) {
+	workflows := &inmemoryWorkflows{}
+	manager := NewManager(nil, nil, workflows, logrus.New(), nil)
+
+	workflow := newTestWorkflow(t, "first", "second", "third")
+
+	var executed []string
+	err := manager.runWorkflow(workflow, func(step workflowStep) error {
+		executed = append(executed, step.Name)
+		if step.Name == "second" {
+			return errors.New("step failed")
+		}
+
+		return nil
+	}, logrus.New())
+	if err == nil {
+		t.Fatal("expected the failing step to fail the workflow")
+	}
+
+	if workflow.Status != intCluster.WorkflowFailed || workflow.FinishedSteps != 1 || workflow.CurrentStep != "second" {
+		t.Errorf("unexpected failed workflow: %+v", workflow)
+	}
+
+	if last := workflows.saved[len(workflows.saved)-1]; last.Status != intCluster.WorkflowFailed || last.FinishedSteps != 1 {
+		t.Errorf("the failed workflow is not persisted: %+v", last)
+	}
+
+	// resume the workflow from the failed step, as ResumeWorkflows does after a restart
+	workflow.Status = intCluster.WorkflowRunning
+	executed = nil
+
+	err = manager.runWorkflow(workflow, func(step workflowStep) error {
+		executed = append(executed, step.Name)
+		return nil
+	}, logrus.New())
+	if err != nil {
+		t.Fatal("could not resume workflow: ", err.Error())
+	}
+
+	if expected := []string{"second", "third"}; !reflect.DeepEqual(executed, expected) {
+		t.Errorf("expected resumed steps %v, got %v", expected, executed)
+	}
+
+	if last := workflows.saved[len(workflows.saved)-1]; last.Status != intCluster.WorkflowFinished || last.FinishedSteps != 3 || last.CurrentStep != "" {
+		t.Errorf("the finished workflow is not persisted: %+v", last)
+	}
+}
+
+func TestCreateWorkflowSteps(t *testing.T) {
+	steps := createWorkflowSteps(nil)
+
+	if len(steps) != 3 {
+		t.Fatalf("expected 3 steps, got %d", len(steps))
+	}
+
+	if steps[0].Name != createClusterStep || steps[1].Name != storeKubeConfigStep || steps[2].Name != runPostHooksStep {
+		t.Errorf("unexpected steps: %s, %s, %s", steps[0].Name, steps[1].Name, steps[2].Name)
+	}
+
+	for _, postHook := range steps[2].PostHooks {
+		if postHook.Name == pkgCluster.StoreKubeConfig {
+			t.Error("the kubeconfig must not be stored by the posthooks step")
+		}
+	}
+
+	if len(steps[2].PostHooks) != len(BasePostHookFunctions)-1 {
+		t.Errorf("expected %d posthooks, got %d", len(BasePostHookFunctions)-1, len(steps[2].PostHooks))
+	}
+}
+
+func TestCreateWorkflowData(t *testing.T) {
+	data := createWorkflowData{
+		Request: &pkgCluster.CreateClusterRequest{
+			Name:     "cluster",
+			Location: "eu-west-1",
+			Cloud:    pkgCluster.Amazon,
+			SecretId: "secret",
+		},
+	}
+
+	rawData, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal("could not marshal workflow data: ", err.Error())
+	}
+
+	var actual createWorkflowData
+	if err := json.Unmarshal(rawData, &actual); err != nil {
+		t.Fatal("could not unmarshal workflow data: ", err.Error())
+	}
+
+	if !reflect.DeepEqual(actual, data) {
+		t.Errorf("expected workflow data %+v, got %+v", data, actual)
+	}
+}
+
+func TestSavePostHookStep(t *testing.T) {
+	workflows := &inmemoryWorkflows{}
+	manager := NewManager(nil, nil, workflows, logrus.New(), nil)
+
+	steps := []workflowStep{
+		{Name: createClusterStep},
+		{Name: runPostHooksStep, PostHooks: []workflowStep{
+			{Name: "a", Status: intCluster.PostHookPending},
+			{Name: "b", Status: intCluster.PostHookPending},
+		}},
+	}
+
+	rawSteps, err := json.Marshal(steps)
+	if err != nil {
+		t.Fatal("could not marshal steps: ", err.Error())
+	}
+
+	workflow := &intCluster.WorkflowModel{Steps: string(rawSteps)}
+
+	if err := manager.savePostHookStep(workflow, "b", intCluster.PostHookRunning); err != nil {
+		t.Fatal("could not save posthook step: ", err.Error())
+	}
+
+	if err := json.Unmarshal([]byte(workflows.saved[len(workflows.saved)-1].Steps), &steps); err != nil {
+		t.Fatal("could not unmarshal steps: ", err.Error())
+	}
+
+	if status := steps[1].PostHooks[0].Status; status != intCluster.PostHookPending {
+		t.Errorf("expected posthook a to be pending, got %s", status)
+	}
+
+	if status := steps[1].PostHooks[1].Status; status != intCluster.PostHookRunning {
+		t.Errorf("expected posthook b to be running, got %s", status)
+	}
+}
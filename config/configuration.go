@@ -43,6 +43,18 @@ const (
 	// DNSExternalDnsChartVersion set the external-dns chart version default value: "0.5.4"
 	DNSExternalDnsChartVersion = "dns.externalDnsChartVersion"
 
+	// DNSProvider configuration key for the DNS service organisation level domains are registered in: route53, google or azure
+	DNSProvider = "dns.provider"
+
+	// DNSGoogleCredentialPath is the path in Vault to get the Google service account credentials for Cloud DNS from
+	DNSGoogleCredentialPath = "dns.google.credentials.path"
+
+	// DNSAzureCredentialPath is the path in Vault to get the Azure service principal credentials for Azure DNS from
+	DNSAzureCredentialPath = "dns.azure.credentials.path"
+
+	// DNSAzureResourceGroup configuration key for the resource group of the Azure DNS zones
+	DNSAzureResourceGroup = "dns.azure.resourceGroup"
+
 	// Route53MaintenanceWndMinute configuration key for the maintenance window for Route53.
 	// This is the maintenance window before the next AWS Route53 pricing period starts
 	Route53MaintenanceWndMinute = "route53.maintenanceWindowMinute"
@@ -67,6 +79,62 @@ const (
 	// Config keys to OKE nodepool wait
 	OKEWaitAttemptsForNodepoolActive = "oke.waitAttemptsForNodepoolActive"
 	OKESleepSecondsForNodepoolActive = "oke.sleepSecondsForNodepoolActive"
+
+	// PostHookRetryAttempts is the default number of attempts of a failing posthook function
+	PostHookRetryAttempts = "posthook.retry.attempts"
+
+	// PostHookRetryBackoff is the default delay before retrying a failed posthook function, doubled for every further retry
+	PostHookRetryBackoff = "posthook.retry.backoff"
+
+	// PostHookRetryMaxBackoff is the default upper limit of the delay between two attempts of a posthook function
+	PostHookRetryMaxBackoff = "posthook.retry.maxBackoff"
+
+	// PostHookRetryHooks is the configuration key prefix of the retry policies of individual posthook functions,
+	// eg. posthook.retry.hooks.InstallHelmPostHook.attempts
+	PostHookRetryHooks = "posthook.retry.hooks"
+
+	// PostHookWorkers is the maximum number of posthook functions running concurrently on a cluster
+	PostHookWorkers = "posthook.workers"
+
+	// SecretStoreBackend is the name of the backend secrets are stored in (vault, sql or memory)
+	SecretStoreBackend = "secret.backend"
+
+	// SecretStoreSQLMasterKey is the base64 encoded AES key (16, 24 or 32 bytes) secrets are encrypted with in the SQL backend
+	SecretStoreSQLMasterKey = "secret.sql.masterKey"
+
+	// SecretRotationCheckInterval is the interval at which the rotation scheduler looks for secrets due to rotation
+	SecretRotationCheckInterval = "secret.rotation.checkInterval"
+
+	// SecretRotationTLSRenewBefore is how long before expiry TLS certificates with a rotation policy are re-issued
+	SecretRotationTLSRenewBefore = "secret.rotation.tlsRenewBefore"
+
+	// SecretRegistryRefreshInterval is the interval at which short-lived container registry tokens are refreshed in the clusters
+	SecretRegistryRefreshInterval = "secret.registry.refreshInterval"
+
+	// AuthTokenGCInterval is the interval at which expired API tokens are removed
+	AuthTokenGCInterval = "auth.tokenGCInterval"
+
+	// AuditSinks is the list of sinks audit events are published to, see audit.SinkConfig
+	AuditSinks = "audit.sinks"
+
+	// AuditRetention is how long audit events are kept in the database, 0 keeps them forever
+	AuditRetention = "audit.retention"
+
+	// AuditRetentionCheckInterval is the interval at which audit events older than the retention period are removed
+	AuditRetentionCheckInterval = "audit.retentionCheckInterval"
+
+	// ObjectStoreReconcileInterval is the interval at which managed buckets are checked whether they still exist, 0 disables the check
+	ObjectStoreReconcileInterval = "objectstore.reconcileInterval"
+
+	// BackupScheduleCheckInterval is the interval at which the backup scheduler looks for clusters due to backup
+	BackupScheduleCheckInterval = "backup.scheduleCheckInterval"
+)
+
+// Secret store backends
+const (
+	SecretStoreBackendVault  = "vault"
+	SecretStoreBackendSQL    = "sql"
+	SecretStoreBackendMemory = "memory"
 )
 
 //Init initializes the configurations
@@ -103,6 +171,12 @@ func init() {
 	viper.SetDefault("auth.jwtissuer", "https://banzaicloud.com/")
 	viper.SetDefault("auth.jwtaudience", "https://pipeline.banzaicloud.com")
 	viper.SetDefault("auth.secureCookie", true)
+	viper.SetDefault(AuthTokenGCInterval, "1h")
+	viper.SetDefault("auth.oidc.enabled", false)
+	viper.SetDefault("auth.oidc.groupsClaim", "groups")
+	viper.SetDefault("auth.oidc.defaultRole", "member")
+	viper.SetDefault("auth.gitlab.enabled", false)
+	viper.SetDefault("auth.gitlab.url", "https://gitlab.com")
 
 	viper.SetDefault("pipeline.listenport", 9090)
 	viper.SetDefault("pipeline.certfile", "")
@@ -121,10 +195,15 @@ func init() {
 	viper.SetDefault("audit.enabled", true)
 	viper.SetDefault("audit.headers", []string{"secretId"})
 	viper.SetDefault("audit.skippaths", []string{"/auth/github/callback", "/pipeline/api"})
+	viper.SetDefault(AuditRetention, "0")
+	viper.SetDefault(AuditRetentionCheckInterval, "1h")
 	viper.SetDefault("tls.validity", "8760h") // 1 year
 	viper.SetDefault(DNSBaseDomain, "banzaicloud.io")
 	viper.SetDefault(DNSGcIntervalMinute, 1)
 	viper.SetDefault(DNSExternalDnsChartVersion, "0.7.5")
+	viper.SetDefault(DNSProvider, "route53")
+	viper.SetDefault(DNSGoogleCredentialPath, "secret/data/banzaicloud/google")
+	viper.SetDefault(DNSAzureCredentialPath, "secret/data/banzaicloud/azure")
 	viper.SetDefault(DNSGcLogLevel, "debug")
 	viper.SetDefault(Route53MaintenanceWndMinute, 15)
 
@@ -134,6 +213,20 @@ func init() {
 	viper.SetDefault(OKEWaitAttemptsForNodepoolActive, 60)
 	viper.SetDefault(OKESleepSecondsForNodepoolActive, 30)
 
+	viper.SetDefault(PostHookRetryAttempts, 1)
+	viper.SetDefault(PostHookRetryBackoff, "10s")
+	viper.SetDefault(PostHookRetryMaxBackoff, "2m")
+	viper.SetDefault(PostHookWorkers, 4)
+
+	viper.SetDefault(SecretStoreBackend, SecretStoreBackendVault)
+	viper.SetDefault(SecretRotationCheckInterval, "1m")
+	viper.SetDefault(SecretRotationTLSRenewBefore, "720h")
+	viper.SetDefault(SecretRegistryRefreshInterval, "30m")
+
+	viper.SetDefault(ObjectStoreReconcileInterval, "1h")
+
+	viper.SetDefault(BackupScheduleCheckInterval, "5m")
+
 	ReleaseName := os.Getenv("KUBERNETES_RELEASE_NAME")
 	if ReleaseName == "" {
 		ReleaseName = "pipeline"
@@ -0,0 +1,695 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azuredns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/authorization/mgmt/2015-07-01/authorization"
+	"github.com/Azure/azure-sdk-for-go/services/dns/mgmt/2017-09-01/dns"
+	"github.com/Azure/azure-sdk-for-go/services/graphrbac/1.6/graphrbac"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/Azure/go-autorest/autorest/date"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/dns/zone"
+	"github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgDns "github.com/banzaicloud/pipeline/pkg/dns"
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+var logger *logrus.Logger
+
+func init() {
+	logger = config.Logger()
+}
+
+const (
+	// ProviderName identifies Azure DNS in the configuration and in the state store
+	ProviderName = "azure"
+
+	// ServicePrincipalSecretName is the name of the hidden secret which stores the service principal credentials of external-dns
+	ServicePrincipalSecretName = "azuredns"
+
+	applicationNameTemplate = "pipeline-dns-%s-%d"
+
+	// dnsZoneContributorRoleId is the id of the built-in DNS Zone Contributor role
+	dnsZoneContributorRoleId = "befefa01-2a29-4197-83a8-272ff33ce314"
+
+	delegationTTL = 300
+
+	// a new service principal may not be visible to the role assignment API for a while
+	roleAssignmentRetries  = 10
+	roleAssignmentInterval = 10 * time.Second
+
+	passwordValidity = 10 * 365 * 24 * time.Hour
+)
+
+func loggerWithFields(fields logrus.Fields) *logrus.Entry {
+	fields["tag"] = "AzureDNS"
+
+	return logger.WithFields(fields)
+}
+
+// azureDns manages domains through the DNS zones of Azure DNS
+// and service principals with DNS Zone Contributor role on a single zone
+type azureDns struct {
+	subscriptionId string
+	tenantId       string
+	resourceGroup  string
+	baseDomain     string
+
+	zonesClient             dns.ZonesClient
+	recordSetsClient        dns.RecordSetsClient
+	applicationsClient      graphrbac.ApplicationsClient
+	servicePrincipalsClient graphrbac.ServicePrincipalsClient
+	roleAssignmentsClient   authorization.RoleAssignmentsClient
+}
+
+// NewAzureDns creates a new DNS service client managing domains in Azure DNS using the provided service principal credentials.
+// The zone of the base domain and the zones of the organisations are in the configured resource group.
+func NewAzureDns(credentials map[string]string, notifications chan interface{}) (*zone.Service, error) {
+	resourceGroup := viper.GetString(config.DNSAzureResourceGroup)
+	log := loggerWithFields(logrus.Fields{"resourceGroup": resourceGroup})
+
+	baseDomain := viper.GetString(config.DNSBaseDomain)
+	if len(baseDomain) == 0 {
+		log.Errorf("base domain is not configured !")
+		return nil, errors.New("base domain is not configured !")
+	}
+
+	if len(resourceGroup) == 0 {
+		return nil, errors.New("resource group of Azure DNS zones is not configured")
+	}
+
+	subscriptionId := credentials[secretTypes.AzureSubscriptionId]
+	tenantId := credentials[secretTypes.AzureTenantId]
+
+	clientCredentials := auth.NewClientCredentialsConfig(credentials[secretTypes.AzureClientId], credentials[secretTypes.AzureClientSecret], tenantId)
+
+	authorizer, err := clientCredentials.Authorizer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Azure authorizer")
+	}
+
+	clientCredentials.Resource = azure.PublicCloud.GraphEndpoint
+	graphAuthorizer, err := clientCredentials.Authorizer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Azure graph authorizer")
+	}
+
+	a := &azureDns{
+		subscriptionId: subscriptionId,
+		tenantId:       tenantId,
+		resourceGroup:  resourceGroup,
+		baseDomain:     baseDomain,
+
+		zonesClient:             dns.NewZonesClient(subscriptionId),
+		recordSetsClient:        dns.NewRecordSetsClient(subscriptionId),
+		applicationsClient:      graphrbac.NewApplicationsClient(tenantId),
+		servicePrincipalsClient: graphrbac.NewServicePrincipalsClient(tenantId),
+		roleAssignmentsClient:   authorization.NewRoleAssignmentsClient(subscriptionId),
+	}
+
+	a.zonesClient.Authorizer = authorizer
+	a.recordSetsClient.Authorizer = authorizer
+	a.roleAssignmentsClient.Authorizer = authorizer
+	a.applicationsClient.Authorizer = graphAuthorizer
+	a.servicePrincipalsClient.Authorizer = graphAuthorizer
+
+	baseZone, err := a.getZone(baseDomain)
+	if err != nil {
+		log.Errorf("retrieving zone for base domain '%s' failed: %s", baseDomain, err.Error())
+		return nil, err
+	}
+
+	if baseZone == nil {
+		return nil, fmt.Errorf("zone for base domain '%s' not found", baseDomain)
+	}
+
+	return zone.NewService(ProviderName, a, notifications), nil
+}
+
+// ZoneExists returns true if the DNS zone of the domain exists in the resource group using the provided service principal credentials
+func ZoneExists(credentials map[string]string, resourceGroup, domain string) (bool, error) {
+	clientCredentials := auth.NewClientCredentialsConfig(credentials[secretTypes.AzureClientId], credentials[secretTypes.AzureClientSecret], credentials[secretTypes.AzureTenantId])
+
+	authorizer, err := clientCredentials.Authorizer()
+	if err != nil {
+		return false, errors.Wrap(err, "could not create Azure authorizer")
+	}
+
+	a := &azureDns{
+		resourceGroup: resourceGroup,
+		zonesClient:   dns.NewZonesClient(credentials[secretTypes.AzureSubscriptionId]),
+	}
+	a.zonesClient.Authorizer = authorizer
+
+	dnsZone, err := a.getZone(domain)
+
+	return dnsZone != nil, err
+}
+
+// EnsureZone creates the DNS zone of the domain unless it exists and adds its name servers to the zone of the base domain
+func (a *azureDns) EnsureZone(domain string) (string, error) {
+	log := loggerWithFields(logrus.Fields{"domain": domain})
+	ctx := context.Background()
+
+	dnsZone, err := a.getZone(domain)
+	if err != nil {
+		return "", err
+	}
+
+	if dnsZone == nil {
+		created, err := a.zonesClient.CreateOrUpdate(ctx, a.resourceGroup, domain, dns.Zone{Location: to.StringPtr("global")}, "", "*")
+		if err != nil {
+			return "", errors.Wrap(err, "creating DNS zone failed")
+		}
+
+		dnsZone = &created
+		log.Infof("DNS zone '%s' created", to.String(dnsZone.ID))
+	} else {
+		log.Infof("skip creating DNS zone as it already exists with id: '%s'", to.String(dnsZone.ID))
+	}
+
+	var nsRecords []dns.NsRecord
+	if dnsZone.ZoneProperties != nil && dnsZone.NameServers != nil {
+		for _, nameServer := range *dnsZone.NameServers {
+			nsRecords = append(nsRecords, dns.NsRecord{Nsdname: to.StringPtr(nameServer)})
+		}
+	}
+
+	if !zone.IsSubdomain(domain, a.baseDomain) {
+		log.Info("skip adding domain to base domain as it is not a subdomain of it")
+		return to.String(dnsZone.ID), nil
+	}
+
+	delegation := dns.RecordSet{
+		RecordSetProperties: &dns.RecordSetProperties{
+			TTL:       to.Int64Ptr(delegationTTL),
+			NsRecords: &nsRecords,
+		},
+	}
+
+	_, err = a.recordSetsClient.CreateOrUpdate(ctx, a.resourceGroup, a.baseDomain, a.relativeName(domain), dns.NS, delegation, "", "")
+	if err != nil {
+		return "", errors.Wrap(err, "adding domain to base domain failed")
+	}
+
+	return to.String(dnsZone.ID), nil
+}
+
+// DeleteZone removes the name servers of the domain from the zone of the base domain and deletes its DNS zone together with its records
+func (a *azureDns) DeleteZone(domain string) error {
+	ctx := context.Background()
+
+	if zone.IsSubdomain(domain, a.baseDomain) {
+		resp, err := a.recordSetsClient.Delete(ctx, a.resourceGroup, a.baseDomain, a.relativeName(domain), dns.NS, "")
+		if err != nil && !isNotFound(resp) {
+			return errors.Wrap(err, "removing domain from base domain failed")
+		}
+	}
+
+	future, err := a.zonesClient.Delete(ctx, a.resourceGroup, domain, "")
+	if err != nil {
+		if isNotFound(autorest.Response{Response: future.Response()}) {
+			return nil
+		}
+
+		return errors.Wrapf(err, "deleting DNS zone '%s' failed", domain)
+	}
+
+	if err := future.WaitForCompletion(ctx, a.zonesClient.Client); err != nil {
+		return errors.Wrapf(err, "deleting DNS zone '%s' failed", domain)
+	}
+
+	return nil
+}
+
+// NameServers returns the name servers of the DNS zone of the domain
+func (a *azureDns) NameServers(domain string) ([]string, error) {
+	dnsZone, err := a.getZone(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	if dnsZone == nil {
+		return nil, fmt.Errorf("DNS zone for domain '%s' not found", domain)
+	}
+
+	if dnsZone.ZoneProperties == nil || dnsZone.NameServers == nil {
+		return nil, nil
+	}
+
+	return *dnsZone.NameServers, nil
+}
+
+// DeleteRecordsOwnedBy deletes the records created by the external-dns instance with the given owner id
+func (a *azureDns) DeleteRecordsOwnedBy(domain, ownerId string) error {
+	ctx := context.Background()
+
+	var recordSets []dns.RecordSet
+	page, err := a.recordSetsClient.ListByDNSZone(ctx, a.resourceGroup, domain, nil, "")
+	for ; err == nil && page.NotDone(); err = page.Next() {
+		recordSets = append(recordSets, page.Values()...)
+	}
+	if err != nil {
+		if isNotFound(page.Response().Response) {
+			return nil
+		}
+
+		return errors.Wrap(err, "retrieving record sets of the DNS zone failed")
+	}
+
+	ownedNames := make(map[string]bool)
+	for _, recordSet := range recordSets {
+		if recordType(recordSet) != dns.TXT || recordSet.RecordSetProperties == nil || recordSet.TxtRecords == nil {
+			continue
+		}
+
+		for _, txtRecord := range *recordSet.TxtRecords {
+			if txtRecord.Value != nil && zone.IsOwnedBy(strings.Join(*txtRecord.Value, ""), ownerId) {
+				ownedNames[to.String(recordSet.Name)] = true
+				break
+			}
+		}
+	}
+
+	for _, recordSet := range recordSets {
+		t := recordType(recordSet)
+		if !ownedNames[to.String(recordSet.Name)] || t == dns.NS || t == dns.SOA {
+			continue
+		}
+
+		if _, err := a.recordSetsClient.Delete(ctx, a.resourceGroup, domain, to.String(recordSet.Name), t, ""); err != nil {
+			return errors.Wrapf(err, "deleting record set '%s' of the DNS zone failed", to.String(recordSet.Name))
+		}
+	}
+
+	return nil
+}
+
+// ListRecords returns the record sets of the DNS zone of the domain
+func (a *azureDns) ListRecords(domain string) ([]pkgDns.Record, error) {
+	ctx := context.Background()
+
+	var records []pkgDns.Record
+	page, err := a.recordSetsClient.ListByDNSZone(ctx, a.resourceGroup, domain, nil, "")
+	for ; err == nil && page.NotDone(); err = page.Next() {
+		for _, recordSet := range page.Values() {
+			records = append(records, newRecord(domain, recordSet))
+		}
+	}
+	if err != nil {
+		if isNotFound(page.Response().Response) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrap(err, "retrieving record sets of the DNS zone failed")
+	}
+
+	return records, nil
+}
+
+// UpsertRecord creates or replaces a record set in the DNS zone of the domain
+func (a *azureDns) UpsertRecord(domain string, record pkgDns.Record) error {
+	properties := &dns.RecordSetProperties{TTL: to.Int64Ptr(record.TTL)}
+
+	switch record.Type {
+	case pkgDns.RecordTypeA:
+		var aRecords []dns.ARecord
+		for _, value := range record.Values {
+			aRecords = append(aRecords, dns.ARecord{Ipv4Address: to.StringPtr(value)})
+		}
+		properties.ARecords = &aRecords
+
+	case pkgDns.RecordTypeAAAA:
+		var aaaaRecords []dns.AaaaRecord
+		for _, value := range record.Values {
+			aaaaRecords = append(aaaaRecords, dns.AaaaRecord{Ipv6Address: to.StringPtr(value)})
+		}
+		properties.AaaaRecords = &aaaaRecords
+
+	case pkgDns.RecordTypeCNAME:
+		if len(record.Values) != 1 {
+			return errors.New("CNAME record must have exactly one value")
+		}
+		properties.CnameRecord = &dns.CnameRecord{Cname: to.StringPtr(record.Values[0])}
+
+	case pkgDns.RecordTypeTXT:
+		var txtRecords []dns.TxtRecord
+		for _, value := range record.Values {
+			txtRecords = append(txtRecords, dns.TxtRecord{Value: &[]string{value}})
+		}
+		properties.TxtRecords = &txtRecords
+
+	default:
+		return fmt.Errorf("record type %q is not supported", record.Type)
+	}
+
+	recordSet := dns.RecordSet{RecordSetProperties: properties}
+
+	_, err := a.recordSetsClient.CreateOrUpdate(context.Background(), a.resourceGroup, domain, zoneRelativeName(domain, record.Name), dns.RecordType(record.Type), recordSet, "", "")
+	if err != nil {
+		return errors.Wrapf(err, "updating record set '%s' failed", record.Name)
+	}
+
+	return nil
+}
+
+// DeleteRecord deletes a record set from the DNS zone of the domain
+func (a *azureDns) DeleteRecord(domain, name, recordType string) error {
+	resp, err := a.recordSetsClient.Delete(context.Background(), a.resourceGroup, domain, zoneRelativeName(domain, name), dns.RecordType(recordType), "")
+	if err != nil && !isNotFound(resp) {
+		return errors.Wrapf(err, "deleting record set '%s' failed", name)
+	}
+
+	return nil
+}
+
+// EnsureAccess creates an application and its service principal for external-dns with DNS Zone Contributor role on the zone,
+// and stores its credentials in Vault unless valid credentials are stored already
+func (a *azureDns) EnsureAccess(orgId uint, zoneId string) (string, error) {
+	log := loggerWithFields(logrus.Fields{"organisationId": orgId, "zone": zoneId})
+	ctx := context.Background()
+
+	appName := fmt.Sprintf(applicationNameTemplate, a.resourceGroup, orgId)
+
+	app, err := a.getApplication(appName)
+	if err != nil {
+		return "", err
+	}
+
+	if app == nil {
+		created, err := a.applicationsClient.Create(ctx, graphrbac.ApplicationCreateParameters{
+			DisplayName:             to.StringPtr(appName),
+			IdentifierUris:          &[]string{"http://" + appName},
+			AvailableToOtherTenants: to.BoolPtr(false),
+		})
+		if err != nil {
+			return "", errors.Wrapf(err, "creating application '%s' failed", appName)
+		}
+
+		app = &created
+	}
+
+	servicePrincipal, err := a.getServicePrincipal(to.String(app.AppID))
+	if err != nil {
+		return "", err
+	}
+
+	if servicePrincipal == nil {
+		created, err := a.servicePrincipalsClient.Create(ctx, graphrbac.ServicePrincipalCreateParameters{
+			AppID:          app.AppID,
+			AccountEnabled: to.BoolPtr(true),
+		})
+		if err != nil {
+			return "", errors.Wrapf(err, "creating service principal of application '%s' failed", appName)
+		}
+
+		servicePrincipal = &created
+	}
+
+	if err := a.ensureRoleAssignment(zoneId, to.String(servicePrincipal.ObjectID)); err != nil {
+		return "", err
+	}
+
+	accessSecret, err := zone.GetAccessSecret(orgId, ServicePrincipalSecretName)
+	if err != nil {
+		return "", err
+	}
+
+	if accessSecret != nil && accessSecret.Values[secretTypes.AzureClientId] == to.String(app.AppID) {
+		log.Info("skip creating service principal password as it is already set up")
+		return to.String(app.ObjectID), nil
+	}
+
+	// replacing the password credentials of the application revokes the stale passwords
+	password := uuid.NewV4().String()
+	now := time.Now()
+
+	_, err = a.applicationsClient.UpdatePasswordCredentials(ctx, to.String(app.ObjectID), graphrbac.PasswordCredentialsUpdateParameters{
+		Value: &[]graphrbac.PasswordCredential{
+			{
+				KeyID:     to.StringPtr(uuid.NewV4().String()),
+				StartDate: &date.Time{Time: now},
+				EndDate:   &date.Time{Time: now.Add(passwordValidity)},
+				Value:     to.StringPtr(password),
+			},
+		},
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "updating password of application '%s' failed", appName)
+	}
+
+	err = zone.StoreAccessSecret(orgId, ServicePrincipalSecretName, cluster.Azure, map[string]string{
+		secretTypes.AzureClientId:       to.String(app.AppID),
+		secretTypes.AzureClientSecret:   password,
+		secretTypes.AzureTenantId:       a.tenantId,
+		secretTypes.AzureSubscriptionId: a.subscriptionId,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "storing service principal credentials failed")
+	}
+
+	return to.String(app.ObjectID), nil
+}
+
+// DeleteAccess deletes the role assignments and the application of external-dns together with its credentials stored in Vault
+func (a *azureDns) DeleteAccess(orgId uint, identity string) error {
+	ctx := context.Background()
+
+	var app *graphrbac.Application
+	if identity != "" {
+		found, err := a.applicationsClient.Get(ctx, identity)
+		if err != nil && !isNotFound(found.Response) {
+			return errors.Wrapf(err, "retrieving application '%s' failed", identity)
+		}
+
+		if err == nil {
+			app = &found
+		}
+	} else {
+		var err error
+		if app, err = a.getApplication(fmt.Sprintf(applicationNameTemplate, a.resourceGroup, orgId)); err != nil {
+			return err
+		}
+	}
+
+	if app != nil {
+		servicePrincipal, err := a.getServicePrincipal(to.String(app.AppID))
+		if err != nil {
+			return err
+		}
+
+		if servicePrincipal != nil {
+			if err := a.deleteRoleAssignments(to.String(servicePrincipal.ObjectID)); err != nil {
+				return err
+			}
+		}
+
+		// deleting the application deletes its service principal as well
+		resp, err := a.applicationsClient.Delete(ctx, to.String(app.ObjectID))
+		if err != nil && !isNotFound(resp) {
+			return errors.Wrapf(err, "deleting application '%s' failed", to.String(app.DisplayName))
+		}
+	}
+
+	return zone.DeleteAccessSecret(orgId, ServicePrincipalSecretName)
+}
+
+// ensureRoleAssignment assigns DNS Zone Contributor role on the zone to the service principal.
+// The name of the role assignment is derived from the zone and the principal, so an existing assignment is not duplicated.
+func (a *azureDns) ensureRoleAssignment(zoneId, principalId string) error {
+	ctx := context.Background()
+
+	name := uuid.NewV5(uuid.NamespaceURL, zoneId+"/"+principalId).String()
+	parameters := authorization.RoleAssignmentCreateParameters{
+		Properties: &authorization.RoleAssignmentProperties{
+			RoleDefinitionID: to.StringPtr(fmt.Sprintf("/subscriptions/%s/providers/Microsoft.Authorization/roleDefinitions/%s", a.subscriptionId, dnsZoneContributorRoleId)),
+			PrincipalID:      to.StringPtr(principalId),
+		},
+	}
+
+	var err error
+	for i := 0; i < roleAssignmentRetries; i++ {
+		var roleAssignment authorization.RoleAssignment
+		roleAssignment, err = a.roleAssignmentsClient.Create(ctx, zoneId, name, parameters)
+		if err == nil || roleAssignment.StatusCode == http.StatusConflict {
+			return nil
+		}
+
+		time.Sleep(roleAssignmentInterval)
+	}
+
+	return errors.Wrapf(err, "assigning DNS Zone Contributor role to service principal '%s' failed", principalId)
+}
+
+// deleteRoleAssignments deletes the role assignments of the service principal in the resource group
+func (a *azureDns) deleteRoleAssignments(principalId string) error {
+	ctx := context.Background()
+	scope := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", a.subscriptionId, a.resourceGroup)
+
+	var roleAssignments []authorization.RoleAssignment
+	page, err := a.roleAssignmentsClient.ListForScope(ctx, scope, fmt.Sprintf("principalId eq '%s'", principalId))
+	for ; err == nil && page.NotDone(); err = page.Next() {
+		roleAssignments = append(roleAssignments, page.Values()...)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "retrieving role assignments of service principal '%s' failed", principalId)
+	}
+
+	for _, roleAssignment := range roleAssignments {
+		if _, err := a.roleAssignmentsClient.DeleteByID(ctx, to.String(roleAssignment.ID)); err != nil {
+			return errors.Wrapf(err, "deleting role assignment '%s' failed", to.String(roleAssignment.ID))
+		}
+	}
+
+	return nil
+}
+
+// getZone returns the DNS zone of the domain, nil if it doesn't exist
+func (a *azureDns) getZone(domain string) (*dns.Zone, error) {
+	dnsZone, err := a.zonesClient.Get(context.Background(), a.resourceGroup, domain)
+	if err != nil {
+		if isNotFound(dnsZone.Response) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrapf(err, "retrieving DNS zone '%s' failed", domain)
+	}
+
+	return &dnsZone, nil
+}
+
+// getApplication returns the application with the given display name, nil if it doesn't exist
+func (a *azureDns) getApplication(displayName string) (*graphrbac.Application, error) {
+	page, err := a.applicationsClient.List(context.Background(), fmt.Sprintf("displayName eq '%s'", displayName))
+	if err != nil {
+		return nil, errors.Wrapf(err, "retrieving application '%s' failed", displayName)
+	}
+
+	if apps := page.Values(); len(apps) > 0 {
+		return &apps[0], nil
+	}
+
+	return nil, nil
+}
+
+// getServicePrincipal returns the service principal of the application, nil if it doesn't exist
+func (a *azureDns) getServicePrincipal(appId string) (*graphrbac.ServicePrincipal, error) {
+	page, err := a.servicePrincipalsClient.List(context.Background(), fmt.Sprintf("appId eq '%s'", appId))
+	if err != nil {
+		return nil, errors.Wrapf(err, "retrieving service principal of application '%s' failed", appId)
+	}
+
+	if servicePrincipals := page.Values(); len(servicePrincipals) > 0 {
+		return &servicePrincipals[0], nil
+	}
+
+	return nil, nil
+}
+
+// relativeName returns the name of the domain relative to the base domain
+func (a *azureDns) relativeName(domain string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(domain, "."), "."+a.baseDomain)
+}
+
+// zoneRelativeName returns the name of a record relative to the zone of the domain, @ for the apex of the zone
+func zoneRelativeName(domain, name string) string {
+	name = strings.TrimSuffix(name, ".")
+	if name == domain {
+		return "@"
+	}
+
+	return strings.TrimSuffix(name, "."+domain)
+}
+
+// newRecord converts a record set of the DNS zone of the domain
+func newRecord(domain string, recordSet dns.RecordSet) pkgDns.Record {
+	record := pkgDns.Record{
+		Name: to.String(recordSet.Fqdn),
+		Type: string(recordType(recordSet)),
+	}
+
+	if record.Name == "" {
+		record.Name = to.String(recordSet.Name) + "." + domain
+		if to.String(recordSet.Name) == "@" {
+			record.Name = domain
+		}
+	}
+	record.Name = strings.TrimSuffix(record.Name, ".")
+
+	properties := recordSet.RecordSetProperties
+	if properties == nil {
+		return record
+	}
+
+	record.TTL = to.Int64(properties.TTL)
+
+	if properties.ARecords != nil {
+		for _, r := range *properties.ARecords {
+			record.Values = append(record.Values, to.String(r.Ipv4Address))
+		}
+	}
+	if properties.AaaaRecords != nil {
+		for _, r := range *properties.AaaaRecords {
+			record.Values = append(record.Values, to.String(r.Ipv6Address))
+		}
+	}
+	if properties.CnameRecord != nil {
+		record.Values = append(record.Values, to.String(properties.CnameRecord.Cname))
+	}
+	if properties.TxtRecords != nil {
+		for _, r := range *properties.TxtRecords {
+			if r.Value != nil {
+				record.Values = append(record.Values, strings.Join(*r.Value, ""))
+			}
+		}
+	}
+	if properties.NsRecords != nil {
+		for _, r := range *properties.NsRecords {
+			record.Values = append(record.Values, to.String(r.Nsdname))
+		}
+	}
+	if properties.MxRecords != nil {
+		for _, r := range *properties.MxRecords {
+			record.Values = append(record.Values, fmt.Sprintf("%d %s", to.Int32(r.Preference), to.String(r.Exchange)))
+		}
+	}
+
+	return record
+}
+
+// recordType returns the type of a record set from its resource type, e.g. Microsoft.Network/dnszones/TXT
+func recordType(recordSet dns.RecordSet) dns.RecordType {
+	t := to.String(recordSet.Type)
+
+	return dns.RecordType(t[strings.LastIndex(t, "/")+1:])
+}
+
+func isNotFound(resp autorest.Response) bool {
+	return resp.Response != nil && resp.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,774 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clouddns
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/dns/zone"
+	"github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgDns "github.com/banzaicloud/pipeline/pkg/dns"
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/dns/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iam/v1"
+)
+
+var logger *logrus.Logger
+
+func init() {
+	logger = config.Logger()
+}
+
+const (
+	// ProviderName identifies Google Cloud DNS in the configuration and in the state store
+	ProviderName = "google"
+
+	// ServiceAccountSecretName is the name of the hidden secret which stores the service account key of external-dns
+	ServiceAccountSecretName = "clouddns"
+
+	createZoneDescription    = "Managed zone created by Banzai Cloud Pipeline"
+	serviceAccountIdTemplate = "pipeline-dns-%d"
+	dnsAdminRole             = "roles/dns.admin"
+	zoneListerRoleId         = "pipelineDnsZoneLister"
+	delegationTTL            = 300
+
+	// zoneIamBasePath is the endpoint of the Cloud DNS API which manages the IAM policies of managed zones
+	zoneIamBasePath = "https://dns.googleapis.com/dns/v1/"
+)
+
+func loggerWithFields(fields logrus.Fields) *logrus.Entry {
+	fields["tag"] = "GoogleCloudDNS"
+
+	return logger.WithFields(fields)
+}
+
+// cloudDns manages domains through the managed zones of Google Cloud DNS
+// and service accounts to control access to the managed zones.
+//
+// The service accounts are granted the DNS Administrator role on the managed zone of their organisation only,
+// and a custom role on the project which allows listing the managed zones, as external-dns looks up its zone that way.
+type cloudDns struct {
+	project      string
+	baseDomain   string
+	baseZoneName string // the name of the managed zone of the base domain
+
+	client          *http.Client
+	zoneIamBasePath string
+
+	dnsSvc *dns.Service
+	iamSvc *iam.Service
+	crmSvc *cloudresourcemanager.Service
+}
+
+// NewCloudDns creates a new DNS service client managing domains in Google Cloud DNS using the provided service account credentials
+func NewCloudDns(credentials map[string]string, notifications chan interface{}) (*zone.Service, error) {
+	project := credentials[secretTypes.ProjectId]
+	log := loggerWithFields(logrus.Fields{"project": project})
+
+	baseDomain := viper.GetString(config.DNSBaseDomain)
+	if len(baseDomain) == 0 {
+		log.Errorf("base domain is not configured !")
+		return nil, errors.New("base domain is not configured !")
+	}
+
+	client, err := newClient(credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &cloudDns{project: project, baseDomain: baseDomain, client: client, zoneIamBasePath: zoneIamBasePath}
+
+	if c.dnsSvc, err = dns.New(client); err != nil {
+		return nil, errors.Wrap(err, "could not create Cloud DNS client")
+	}
+
+	if c.iamSvc, err = iam.New(client); err != nil {
+		return nil, errors.Wrap(err, "could not create IAM client")
+	}
+
+	if c.crmSvc, err = cloudresourcemanager.New(client); err != nil {
+		return nil, errors.Wrap(err, "could not create Cloud Resource Manager client")
+	}
+
+	baseZone, err := c.getManagedZone(baseDomain)
+	if err != nil {
+		log.Errorf("retrieving managed zone for base domain '%s' failed: %s", baseDomain, err.Error())
+		return nil, err
+	}
+
+	if baseZone == nil {
+		return nil, fmt.Errorf("managed zone for base domain '%s' not found", baseDomain)
+	}
+
+	c.baseZoneName = baseZone.Name
+
+	return zone.NewService(ProviderName, c, notifications), nil
+}
+
+// ManagedZoneExists returns true if the managed zone of the domain exists in the project of the service account credentials
+func ManagedZoneExists(credentials map[string]string, domain string) (bool, error) {
+	client, err := newClient(credentials)
+	if err != nil {
+		return false, err
+	}
+
+	c := &cloudDns{project: credentials[secretTypes.ProjectId]}
+
+	if c.dnsSvc, err = dns.New(client); err != nil {
+		return false, errors.Wrap(err, "could not create Cloud DNS client")
+	}
+
+	managedZone, err := c.getManagedZone(domain)
+
+	return managedZone != nil, err
+}
+
+// newClient returns an HTTP client authenticated with the service account credentials
+func newClient(credentials map[string]string) (*http.Client, error) {
+	credentialsJSON, err := json.Marshal(credentials)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal credentials")
+	}
+
+	ctx := context.Background()
+
+	creds, err := google.CredentialsFromJSON(ctx, credentialsJSON, dns.NdevClouddnsReadwriteScope, iam.CloudPlatformScope)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get credentials from JSON")
+	}
+
+	return oauth2.NewClient(ctx, creds.TokenSource), nil
+}
+
+// EnsureZone creates the managed zone of the domain unless it exists and adds its name servers to the zone of the base domain
+func (c *cloudDns) EnsureZone(domain string) (string, error) {
+	log := loggerWithFields(logrus.Fields{"domain": domain})
+
+	managedZone, err := c.getManagedZone(domain)
+	if err != nil {
+		return "", err
+	}
+
+	if managedZone == nil {
+		managedZone, err = c.dnsSvc.ManagedZones.Create(c.project, &dns.ManagedZone{
+			Name:        managedZoneName(domain),
+			DnsName:     fqdn(domain),
+			Description: createZoneDescription,
+		}).Do()
+		if err != nil {
+			return "", errors.Wrap(err, "creating managed zone failed")
+		}
+
+		log.Infof("managed zone '%s' created", managedZone.Name)
+	} else {
+		log.Infof("skip creating managed zone as it already exists with name: '%s'", managedZone.Name)
+	}
+
+	if !zone.IsSubdomain(domain, c.baseDomain) {
+		log.Info("skip adding domain to base domain as it is not a subdomain of it")
+		return managedZone.Name, nil
+	}
+
+	delegation := &dns.ResourceRecordSet{
+		Name:    fqdn(domain),
+		Type:    "NS",
+		Ttl:     delegationTTL,
+		Rrdatas: managedZone.NameServers,
+	}
+
+	current, err := c.getResourceRecordSet(c.baseZoneName, fqdn(domain), "NS")
+	if err != nil {
+		return "", err
+	}
+
+	change := &dns.Change{Additions: []*dns.ResourceRecordSet{delegation}}
+	if current != nil {
+		if sameRrdatas(current.Rrdatas, delegation.Rrdatas) {
+			return managedZone.Name, nil
+		}
+
+		change.Deletions = []*dns.ResourceRecordSet{current}
+	}
+
+	if _, err := c.dnsSvc.Changes.Create(c.project, c.baseZoneName, change).Do(); err != nil {
+		return "", errors.Wrap(err, "adding domain to base domain failed")
+	}
+
+	return managedZone.Name, nil
+}
+
+// DeleteZone removes the name servers of the domain from the zone of the base domain and deletes its managed zone
+func (c *cloudDns) DeleteZone(domain string) error {
+	if zone.IsSubdomain(domain, c.baseDomain) {
+		delegation, err := c.getResourceRecordSet(c.baseZoneName, fqdn(domain), "NS")
+		if err != nil {
+			return err
+		}
+
+		if delegation != nil {
+			change := &dns.Change{Deletions: []*dns.ResourceRecordSet{delegation}}
+			if _, err := c.dnsSvc.Changes.Create(c.project, c.baseZoneName, change).Do(); err != nil {
+				return errors.Wrap(err, "removing domain from base domain failed")
+			}
+		}
+	}
+
+	managedZone, err := c.getManagedZone(domain)
+	if err != nil || managedZone == nil {
+		return err
+	}
+
+	// managed zones can be deleted only if they don't contain records other than the ones created with the zone
+	err = c.deleteResourceRecordSets(managedZone.Name, func(rrs *dns.ResourceRecordSet) bool {
+		return rrs.Name != managedZone.DnsName || (rrs.Type != "NS" && rrs.Type != "SOA")
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := c.dnsSvc.ManagedZones.Delete(c.project, managedZone.Name).Do(); err != nil && !isNotFound(err) {
+		return errors.Wrapf(err, "deleting managed zone '%s' failed", managedZone.Name)
+	}
+
+	return nil
+}
+
+// NameServers returns the name servers of the managed zone of the domain
+func (c *cloudDns) NameServers(domain string) ([]string, error) {
+	managedZone, err := c.getManagedZone(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	if managedZone == nil {
+		return nil, fmt.Errorf("managed zone for domain '%s' not found", domain)
+	}
+
+	return managedZone.NameServers, nil
+}
+
+// DeleteRecordsOwnedBy deletes the records created by the external-dns instance with the given owner id
+func (c *cloudDns) DeleteRecordsOwnedBy(domain, ownerId string) error {
+	managedZone, err := c.getManagedZone(domain)
+	if err != nil || managedZone == nil {
+		return err
+	}
+
+	ownedNames := make(map[string]bool)
+	err = c.dnsSvc.ResourceRecordSets.List(c.project, managedZone.Name).Pages(context.Background(), func(page *dns.ResourceRecordSetsListResponse) error {
+		for _, rrs := range page.Rrsets {
+			if rrs.Type != "TXT" {
+				continue
+			}
+
+			for _, rrdata := range rrs.Rrdatas {
+				if zone.IsOwnedBy(rrdata, ownerId) {
+					ownedNames[rrs.Name] = true
+					break
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "retrieving resource record sets of the managed zone failed")
+	}
+
+	if len(ownedNames) == 0 {
+		return nil
+	}
+
+	return c.deleteResourceRecordSets(managedZone.Name, func(rrs *dns.ResourceRecordSet) bool {
+		return ownedNames[rrs.Name] && rrs.Type != "NS" && rrs.Type != "SOA"
+	})
+}
+
+// ListRecords returns the resource record sets of the managed zone of the domain
+func (c *cloudDns) ListRecords(domain string) ([]pkgDns.Record, error) {
+	managedZone, err := c.getManagedZone(domain)
+	if err != nil || managedZone == nil {
+		return nil, err
+	}
+
+	var records []pkgDns.Record
+	err = c.dnsSvc.ResourceRecordSets.List(c.project, managedZone.Name).Pages(context.Background(), func(page *dns.ResourceRecordSetsListResponse) error {
+		for _, rrs := range page.Rrsets {
+			records = append(records, pkgDns.Record{
+				Name:   strings.TrimSuffix(rrs.Name, "."),
+				Type:   rrs.Type,
+				TTL:    rrs.Ttl,
+				Values: rrs.Rrdatas,
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "retrieving resource record sets of the managed zone failed")
+	}
+
+	return records, nil
+}
+
+// UpsertRecord creates or replaces a resource record set in the managed zone of the domain
+func (c *cloudDns) UpsertRecord(domain string, record pkgDns.Record) error {
+	managedZone, err := c.getManagedZone(domain)
+	if err != nil {
+		return err
+	}
+
+	if managedZone == nil {
+		return fmt.Errorf("managed zone for domain '%s' not found", domain)
+	}
+
+	rrs := &dns.ResourceRecordSet{
+		Name:    fqdn(record.Name),
+		Type:    record.Type,
+		Ttl:     record.TTL,
+		Rrdatas: record.Values,
+	}
+
+	if record.Type == pkgDns.RecordTypeTXT {
+		rrs.Rrdatas = make([]string, 0, len(record.Values))
+		for _, value := range record.Values {
+			rrs.Rrdatas = append(rrs.Rrdatas, pkgDns.QuoteTXT(value))
+		}
+	}
+
+	current, err := c.getResourceRecordSet(managedZone.Name, rrs.Name, rrs.Type)
+	if err != nil {
+		return err
+	}
+
+	change := &dns.Change{Additions: []*dns.ResourceRecordSet{rrs}}
+	if current != nil {
+		change.Deletions = []*dns.ResourceRecordSet{current}
+	}
+
+	if _, err := c.dnsSvc.Changes.Create(c.project, managedZone.Name, change).Do(); err != nil {
+		return errors.Wrapf(err, "updating resource record set '%s' failed", record.Name)
+	}
+
+	return nil
+}
+
+// DeleteRecord deletes a resource record set from the managed zone of the domain
+func (c *cloudDns) DeleteRecord(domain, name, recordType string) error {
+	managedZone, err := c.getManagedZone(domain)
+	if err != nil || managedZone == nil {
+		return err
+	}
+
+	current, err := c.getResourceRecordSet(managedZone.Name, fqdn(name), recordType)
+	if err != nil || current == nil {
+		return err
+	}
+
+	change := &dns.Change{Deletions: []*dns.ResourceRecordSet{current}}
+	if _, err := c.dnsSvc.Changes.Create(c.project, managedZone.Name, change).Do(); err != nil {
+		return errors.Wrapf(err, "deleting resource record set '%s' failed", name)
+	}
+
+	return nil
+}
+
+// EnsureAccess creates a service account for external-dns with DNS administrator role on the managed zone,
+// and stores its key in Vault unless a valid key is stored already
+func (c *cloudDns) EnsureAccess(orgId uint, zoneId string) (string, error) {
+	log := loggerWithFields(logrus.Fields{"organisationId": orgId, "zone": zoneId})
+
+	accountId := fmt.Sprintf(serviceAccountIdTemplate, orgId)
+	email := c.serviceAccountEmail(accountId)
+	name := c.serviceAccountName(email)
+
+	_, err := c.iamSvc.Projects.ServiceAccounts.Get(name).Do()
+	if isNotFound(err) {
+		_, err = c.iamSvc.Projects.ServiceAccounts.Create("projects/"+c.project, &iam.CreateServiceAccountRequest{
+			AccountId: accountId,
+			ServiceAccount: &iam.ServiceAccount{
+				DisplayName: fmt.Sprintf("Pipeline external-dns of organisation %d", orgId),
+			},
+		}).Do()
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "creating service account '%s' failed", email)
+	}
+
+	member := "serviceAccount:" + email
+
+	zoneListerRole, err := c.ensureZoneListerRole()
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.updateProjectIamMember(zoneListerRole, member, true); err != nil {
+		return "", err
+	}
+
+	// service accounts created by earlier versions were granted the DNS administrator role on the whole project
+	if err := c.updateProjectIamMember(dnsAdminRole, member, false); err != nil {
+		return "", err
+	}
+
+	if err := c.updateManagedZoneIamMember(zoneId, dnsAdminRole, member, true); err != nil {
+		return "", err
+	}
+
+	keys, err := c.iamSvc.Projects.ServiceAccounts.Keys.List(name).KeyTypes("USER_MANAGED").Do()
+	if err != nil {
+		return "", errors.Wrapf(err, "listing keys of service account '%s' failed", email)
+	}
+
+	accessSecret, err := zone.GetAccessSecret(orgId, ServiceAccountSecretName)
+	if err != nil {
+		return "", err
+	}
+
+	if accessSecret != nil {
+		for _, key := range keys.Keys {
+			if keyId(key.Name) == accessSecret.Values[secretTypes.PrivateKeyId] {
+				log.Info("skip creating service account key as it is already set up")
+				return email, nil
+			}
+		}
+	}
+
+	key, err := c.iamSvc.Projects.ServiceAccounts.Keys.Create(name, &iam.CreateServiceAccountKeyRequest{}).Do()
+	if err != nil {
+		return "", errors.Wrapf(err, "creating key for service account '%s' failed", email)
+	}
+
+	keyJSON, err := base64.StdEncoding.DecodeString(key.PrivateKeyData)
+	if err != nil {
+		return "", errors.Wrap(err, "decoding service account key failed")
+	}
+
+	var values map[string]string
+	if err := json.Unmarshal(keyJSON, &values); err != nil {
+		return "", errors.Wrap(err, "decoding service account key failed")
+	}
+
+	if err := zone.StoreAccessSecret(orgId, ServiceAccountSecretName, cluster.Google, values); err != nil {
+		c.iamSvc.Projects.ServiceAccounts.Keys.Delete(key.Name).Do()
+		return "", errors.Wrap(err, "storing service account key failed")
+	}
+
+	// the keys which are not stored in Vault any more are not used by anyone
+	for _, staleKey := range keys.Keys {
+		if _, err := c.iamSvc.Projects.ServiceAccounts.Keys.Delete(staleKey.Name).Do(); err != nil && !isNotFound(err) {
+			log.Warnf("deleting stale service account key '%s' failed: %s", staleKey.Name, err.Error())
+		}
+	}
+
+	return email, nil
+}
+
+// DeleteAccess deletes the service account of external-dns and its key stored in Vault
+func (c *cloudDns) DeleteAccess(orgId uint, identity string) error {
+	email := identity
+	if email == "" {
+		email = c.serviceAccountEmail(fmt.Sprintf(serviceAccountIdTemplate, orgId))
+	}
+
+	member := "serviceAccount:" + email
+
+	for _, role := range []string{c.zoneListerRoleName(), dnsAdminRole} {
+		if err := c.updateProjectIamMember(role, member, false); err != nil {
+			return err
+		}
+	}
+
+	if _, err := c.iamSvc.Projects.ServiceAccounts.Delete(c.serviceAccountName(email)).Do(); err != nil && !isNotFound(err) {
+		return errors.Wrapf(err, "deleting service account '%s' failed", email)
+	}
+
+	return zone.DeleteAccessSecret(orgId, ServiceAccountSecretName)
+}
+
+// updateProjectIamMember adds the member to or removes it from the role on the project
+func (c *cloudDns) updateProjectIamMember(role, member string, add bool) error {
+	policy, err := c.crmSvc.Projects.GetIamPolicy(c.project, &cloudresourcemanager.GetIamPolicyRequest{}).Do()
+	if err != nil {
+		return errors.Wrap(err, "retrieving IAM policy of the project failed")
+	}
+
+	if !updateBindingMember(policy, role, member, add) {
+		return nil
+	}
+
+	// the etag of the policy protects against concurrent modifications
+	_, err = c.crmSvc.Projects.SetIamPolicy(c.project, &cloudresourcemanager.SetIamPolicyRequest{Policy: policy}).Do()
+
+	return errors.Wrap(err, "updating IAM policy of the project failed")
+}
+
+// updateManagedZoneIamMember adds the member to or removes it from the role on the managed zone
+func (c *cloudDns) updateManagedZoneIamMember(managedZone, role, member string, add bool) error {
+	var policy cloudresourcemanager.Policy
+	if err := c.callManagedZoneIamPolicy(managedZone, "getIamPolicy", struct{}{}, &policy); err != nil {
+		return errors.Wrapf(err, "retrieving IAM policy of managed zone '%s' failed", managedZone)
+	}
+
+	if !updateBindingMember(&policy, role, member, add) {
+		return nil
+	}
+
+	// the etag of the policy protects against concurrent modifications
+	request := cloudresourcemanager.SetIamPolicyRequest{Policy: &policy}
+	if err := c.callManagedZoneIamPolicy(managedZone, "setIamPolicy", request, nil); err != nil {
+		return errors.Wrapf(err, "updating IAM policy of managed zone '%s' failed", managedZone)
+	}
+
+	return nil
+}
+
+// callManagedZoneIamPolicy calls an IAM policy method of the managed zone,
+// which is not supported by the Cloud DNS client library yet
+func (c *cloudDns) callManagedZoneIamPolicy(managedZone, method string, request, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal request")
+	}
+
+	url := fmt.Sprintf("%sprojects/%s/managedZones/%s:%s", c.zoneIamBasePath, c.project, managedZone, method)
+
+	res, err := c.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if err := googleapi.CheckResponse(res); err != nil {
+		return err
+	}
+
+	if response == nil {
+		return nil
+	}
+
+	return errors.Wrap(json.NewDecoder(res.Body).Decode(response), "could not decode response")
+}
+
+// ensureZoneListerRole creates the custom role of the project which allows listing managed zones unless it exists
+func (c *cloudDns) ensureZoneListerRole() (string, error) {
+	name := c.zoneListerRoleName()
+
+	role, err := c.iamSvc.Projects.Roles.Get(name).Do()
+	if isNotFound(err) {
+		role, err = c.iamSvc.Projects.Roles.Create("projects/"+c.project, &iam.CreateRoleRequest{
+			RoleId: zoneListerRoleId,
+			Role: &iam.Role{
+				Title:               "Pipeline DNS zone lister",
+				Description:         "Allows external-dns to look up the managed zone of its organisation",
+				IncludedPermissions: []string{"dns.managedZones.list"},
+				Stage:               "GA",
+			},
+		}).Do()
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "creating role '%s' failed", name)
+	}
+
+	if role.Deleted {
+		if _, err := c.iamSvc.Projects.Roles.Undelete(name, &iam.UndeleteRoleRequest{}).Do(); err != nil {
+			return "", errors.Wrapf(err, "undeleting role '%s' failed", name)
+		}
+	}
+
+	return name, nil
+}
+
+func (c *cloudDns) zoneListerRoleName() string {
+	return fmt.Sprintf("projects/%s/roles/%s", c.project, zoneListerRoleId)
+}
+
+// updateBindingMember adds the member to or removes it from the binding of the role in the policy,
+// it returns false if the policy is left unchanged
+func updateBindingMember(policy *cloudresourcemanager.Policy, role, member string, add bool) bool {
+	var binding *cloudresourcemanager.Binding
+	for _, b := range policy.Bindings {
+		if b.Role == role {
+			binding = b
+			break
+		}
+	}
+
+	if binding == nil {
+		if !add {
+			return false
+		}
+
+		binding = &cloudresourcemanager.Binding{Role: role}
+		policy.Bindings = append(policy.Bindings, binding)
+	}
+
+	var members []string
+	for _, m := range binding.Members {
+		if m != member {
+			members = append(members, m)
+		}
+	}
+
+	if add {
+		if len(members) < len(binding.Members) {
+			return false
+		}
+
+		members = append(members, member)
+	} else if len(members) == len(binding.Members) {
+		return false
+	}
+
+	binding.Members = members
+
+	// bindings without members are not accepted
+	if len(members) == 0 {
+		var bindings []*cloudresourcemanager.Binding
+		for _, b := range policy.Bindings {
+			if b != binding {
+				bindings = append(bindings, b)
+			}
+		}
+		policy.Bindings = bindings
+	}
+
+	return true
+}
+
+// getManagedZone returns the managed zone of the domain, nil if it doesn't exist
+func (c *cloudDns) getManagedZone(domain string) (*dns.ManagedZone, error) {
+	zones, err := c.dnsSvc.ManagedZones.List(c.project).DnsName(fqdn(domain)).Do()
+	if err != nil {
+		return nil, errors.Wrap(err, "retrieving managed zones failed")
+	}
+
+	if len(zones.ManagedZones) == 0 {
+		return nil, nil
+	}
+
+	return zones.ManagedZones[0], nil
+}
+
+// getResourceRecordSet returns the resource record set with given name and type, nil if it doesn't exist
+func (c *cloudDns) getResourceRecordSet(managedZone, name, recordType string) (*dns.ResourceRecordSet, error) {
+	rrsets, err := c.dnsSvc.ResourceRecordSets.List(c.project, managedZone).Name(name).Type(recordType).Do()
+	if err != nil {
+		return nil, errors.Wrap(err, "retrieving resource record sets failed")
+	}
+
+	if len(rrsets.Rrsets) == 0 {
+		return nil, nil
+	}
+
+	return rrsets.Rrsets[0], nil
+}
+
+// deleteResourceRecordSets deletes the resource record sets of the managed zone matching the filter
+func (c *cloudDns) deleteResourceRecordSets(managedZone string, filter func(rrs *dns.ResourceRecordSet) bool) error {
+	var deletions []*dns.ResourceRecordSet
+
+	err := c.dnsSvc.ResourceRecordSets.List(c.project, managedZone).Pages(context.Background(), func(page *dns.ResourceRecordSetsListResponse) error {
+		for _, rrs := range page.Rrsets {
+			if filter(rrs) {
+				deletions = append(deletions, rrs)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "retrieving resource record sets of the managed zone failed")
+	}
+
+	if len(deletions) == 0 {
+		return nil
+	}
+
+	if _, err := c.dnsSvc.Changes.Create(c.project, managedZone, &dns.Change{Deletions: deletions}).Do(); err != nil {
+		return errors.Wrap(err, "deleting resource record sets of the managed zone failed")
+	}
+
+	return nil
+}
+
+func (c *cloudDns) serviceAccountEmail(accountId string) string {
+	return fmt.Sprintf("%s@%s.iam.gserviceaccount.com", accountId, c.project)
+}
+
+func (c *cloudDns) serviceAccountName(email string) string {
+	return fmt.Sprintf("projects/%s/serviceAccounts/%s", c.project, email)
+}
+
+// managedZoneName returns a valid managed zone name for the domain: it must start with a letter
+// and contain at most 63 lowercase letters, digits or dashes
+func managedZoneName(domain string) string {
+	name := "pipeline-" + strings.Replace(strings.ToLower(strings.TrimSuffix(domain, ".")), ".", "-", -1)
+	if len(name) > 63 {
+		name = strings.TrimRight(name[:63], "-")
+	}
+
+	return name
+}
+
+func fqdn(domain string) string {
+	return strings.TrimSuffix(domain, ".") + "."
+}
+
+// keyId returns the id of a service account key from its resource name
+func keyId(name string) string {
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
+func sameRrdatas(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	values := make(map[string]bool, len(a))
+	for _, v := range a {
+		values[v] = true
+	}
+
+	for _, v := range b {
+		if !values[v] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isNotFound(err error) bool {
+	if e, ok := err.(*googleapi.Error); ok {
+		return e.Code == http.StatusNotFound
+	}
+
+	return false
+}
@@ -0,0 +1,145 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clouddns
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/cloudresourcemanager/v1"
+)
+
+const testMember = "serviceAccount:pipeline-dns-1@project.iam.gserviceaccount.com"
+
+func TestUpdateBindingMember(t *testing.T) {
+	t.Run("add to new binding", func(t *testing.T) {
+		policy := &cloudresourcemanager.Policy{}
+
+		if !updateBindingMember(policy, dnsAdminRole, testMember, true) {
+			t.Fatal("expected the policy to change")
+		}
+
+		expected := []*cloudresourcemanager.Binding{{Role: dnsAdminRole, Members: []string{testMember}}}
+		if !reflect.DeepEqual(policy.Bindings, expected) {
+			t.Errorf("unexpected bindings: %+v", policy.Bindings)
+		}
+	})
+
+	t.Run("add existing member", func(t *testing.T) {
+		policy := &cloudresourcemanager.Policy{
+			Bindings: []*cloudresourcemanager.Binding{{Role: dnsAdminRole, Members: []string{"user:admin", testMember}}},
+		}
+
+		if updateBindingMember(policy, dnsAdminRole, testMember, true) {
+			t.Error("expected the policy to be left unchanged")
+		}
+	})
+
+	t.Run("remove member", func(t *testing.T) {
+		policy := &cloudresourcemanager.Policy{
+			Bindings: []*cloudresourcemanager.Binding{{Role: dnsAdminRole, Members: []string{"user:admin", testMember}}},
+		}
+
+		if !updateBindingMember(policy, dnsAdminRole, testMember, false) {
+			t.Fatal("expected the policy to change")
+		}
+
+		expected := []*cloudresourcemanager.Binding{{Role: dnsAdminRole, Members: []string{"user:admin"}}}
+		if !reflect.DeepEqual(policy.Bindings, expected) {
+			t.Errorf("unexpected bindings: %+v", policy.Bindings)
+		}
+	})
+
+	t.Run("remove last member", func(t *testing.T) {
+		policy := &cloudresourcemanager.Policy{
+			Bindings: []*cloudresourcemanager.Binding{
+				{Role: "roles/viewer", Members: []string{"user:admin"}},
+				{Role: dnsAdminRole, Members: []string{testMember}},
+			},
+		}
+
+		if !updateBindingMember(policy, dnsAdminRole, testMember, false) {
+			t.Fatal("expected the policy to change")
+		}
+
+		expected := []*cloudresourcemanager.Binding{{Role: "roles/viewer", Members: []string{"user:admin"}}}
+		if !reflect.DeepEqual(policy.Bindings, expected) {
+			t.Errorf("unexpected bindings: %+v", policy.Bindings)
+		}
+	})
+
+	t.Run("remove missing member", func(t *testing.T) {
+		policy := &cloudresourcemanager.Policy{}
+
+		if updateBindingMember(policy, dnsAdminRole, testMember, false) {
+			t.Error("expected the policy to be left unchanged")
+		}
+	})
+}
+
+func TestCloudDns_UpdateManagedZoneIamMember(t *testing.T) {
+	var updated *cloudresourcemanager.Policy
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("unexpected method: %s", r.Method)
+		}
+
+		switch r.URL.Path {
+		case "/projects/project/managedZones/pipeline-example-org:getIamPolicy":
+			json.NewEncoder(w).Encode(cloudresourcemanager.Policy{Etag: "etag"})
+
+		case "/projects/project/managedZones/pipeline-example-org:setIamPolicy":
+			var request cloudresourcemanager.SetIamPolicyRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				t.Error(err)
+			}
+			updated = request.Policy
+
+			json.NewEncoder(w).Encode(request.Policy)
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := &cloudDns{project: "project", client: server.Client(), zoneIamBasePath: server.URL + "/"}
+
+	if err := c.updateManagedZoneIamMember("pipeline-example-org", dnsAdminRole, testMember, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if updated == nil {
+		t.Fatal("expected the IAM policy of the managed zone to be updated")
+	}
+
+	if updated.Etag != "etag" {
+		t.Errorf("expected the etag of the retrieved policy, got %q", updated.Etag)
+	}
+
+	expected := []*cloudresourcemanager.Binding{{Role: dnsAdminRole, Members: []string{testMember}}}
+	if !reflect.DeepEqual(updated.Bindings, expected) {
+		t.Errorf("unexpected bindings: %+v", updated.Bindings)
+	}
+
+	if err := c.updateManagedZoneIamMember("missing", dnsAdminRole, testMember, true); !isNotFound(errors.Cause(err)) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
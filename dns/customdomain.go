@@ -0,0 +1,118 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"fmt"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/dns/azuredns"
+	"github.com/banzaicloud/pipeline/dns/clouddns"
+	"github.com/banzaicloud/pipeline/dns/model"
+	"github.com/banzaicloud/pipeline/dns/route53"
+	"github.com/banzaicloud/pipeline/pkg/cluster"
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// defaultRoute53Region is used to access Route53 with Amazon secrets having no region, Route53 is a global service
+const defaultRoute53Region = "us-east-1"
+
+// GetCustomDomain returns the custom domain of the organization, nil if the organization uses its default domain
+func GetCustomDomain(orgId uint) (*dnsmodel.CustomDomain, error) {
+	var customDomain dnsmodel.CustomDomain
+
+	err := config.DB().Where(&dnsmodel.CustomDomain{OrganizationId: orgId}).First(&customDomain).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "could not fetch custom domain of organization %d", orgId)
+	}
+
+	return &customDomain, nil
+}
+
+// SaveCustomDomain creates or updates the custom domain of an organization
+func SaveCustomDomain(customDomain *dnsmodel.CustomDomain) error {
+	return errors.Wrapf(config.DB().Save(customDomain).Error, "could not save custom domain %q", customDomain.Domain)
+}
+
+// DeleteCustomDomain deletes the custom domain of an organization, the organization falls back to its default domain
+func DeleteCustomDomain(customDomain *dnsmodel.CustomDomain) error {
+	return errors.Wrapf(config.DB().Delete(customDomain).Error, "could not delete custom domain %q", customDomain.Domain)
+}
+
+// DefaultOrgDomain returns the default domain of an organization under the base domain
+func DefaultOrgDomain(org *auth.Organization) string {
+	return fmt.Sprintf("%s.%s", org.Name, viper.GetString(config.DNSBaseDomain))
+}
+
+// GetOrgDomainName returns the domain external-dns manages the records of the organization under:
+// its custom domain if it has one, the default domain under the base domain otherwise
+func GetOrgDomainName(org *auth.Organization) (string, error) {
+	customDomain, err := GetCustomDomain(org.ID)
+	if err != nil {
+		return "", err
+	}
+
+	if customDomain != nil {
+		return customDomain.Domain, nil
+	}
+
+	return DefaultOrgDomain(org), nil
+}
+
+// VerifyZone checks that the zone of the domain exists and it can be accessed with the given cloud secret
+func VerifyZone(cloudSecret *secret.SecretItemResponse, resourceGroup, domain string) error {
+	var exists bool
+	var err error
+
+	switch cloudSecret.Type {
+	case cluster.Amazon:
+		region := cloudSecret.Values[secretTypes.AwsRegion]
+		if region == "" {
+			region = defaultRoute53Region
+		}
+
+		exists, err = route53.HostedZoneExists(region, cloudSecret.Values[secretTypes.AwsAccessKeyId], cloudSecret.Values[secretTypes.AwsSecretAccessKey], domain)
+
+	case cluster.Google:
+		exists, err = clouddns.ManagedZoneExists(cloudSecret.Values, domain)
+
+	case cluster.Azure:
+		if resourceGroup == "" {
+			return errors.New("resource group of the Azure DNS zone is required")
+		}
+
+		exists, err = azuredns.ZoneExists(cloudSecret.Values, resourceGroup, domain)
+
+	default:
+		return errors.Errorf("secret type %q is not supported for DNS zones", cloudSecret.Type)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "could not check zone of domain %q", domain)
+	}
+
+	if !exists {
+		return errors.Errorf("zone of domain %q not found", domain)
+	}
+
+	return nil
+}
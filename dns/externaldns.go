@@ -15,18 +15,28 @@
 package dns
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/dns/azuredns"
+	"github.com/banzaicloud/pipeline/dns/clouddns"
 	"github.com/banzaicloud/pipeline/dns/route53"
+	pkgDns "github.com/banzaicloud/pipeline/pkg/dns"
 	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
 	"github.com/banzaicloud/pipeline/secret"
 	"github.com/satori/go.uuid"
-	"github.com/spf13/cast"
 	"github.com/spf13/viper"
 )
 
+// DNS providers organisation level domains can be registered in
+const (
+	Route53Provider = "route53"
+	GoogleProvider  = clouddns.ProviderName
+	AzureProvider   = azuredns.ProviderName
+)
+
 var once sync.Once
 var errCreate error
 
@@ -35,7 +45,7 @@ var dnsServiceClient DnsServiceClient
 
 var gc garbageCollector
 
-// dnsNotificationsChannel is used to receive DNS related events from Route53 and fan out the events to consumers.
+// dnsNotificationsChannel is used to receive DNS related events from the DNS provider and fan out the events to consumers.
 var dnsNotificationsChannel chan interface{}
 
 // dnsEventsConsumers stores the channels through which subscribers receive DNS events
@@ -55,6 +65,11 @@ type DnsServiceClient interface {
 	UnregisterDomain(orgId uint, domain string) error
 	IsDomainRegistered(orgId uint, domain string) (bool, error)
 	GetOrgDomain(orgId uint) (string, error)
+	GetNameServers(orgId uint) ([]string, error)
+	GetDomainState(orgId uint) (*pkgDns.DomainState, error)
+	ListRecords(orgId uint) ([]pkgDns.Record, error)
+	UpsertRecord(orgId uint, record pkgDns.Record) error
+	DeleteRecord(orgId uint, name, recordType string) error
 	Cleanup()
 	DeleteDnsRecordsOwnedBy(ownerId string, orgId uint) error
 	ProcessUnfinishedTasks()
@@ -66,41 +81,21 @@ func newExternalDnsServiceClientInstance() {
 
 	gcInterval := time.Duration(viper.GetInt(config.DNSGcIntervalMinute)) * time.Minute
 
-	// This is how the secrets are expected to be written in Vault:
-	// vault kv put secret/banzaicloud/aws AWS_REGION=... AWS_ACCESS_KEY_ID=... AWS_SECRET_ACCESS_KEY=...
-	awsCredentialsPath := viper.GetString(config.AwsCredentialPath)
+	dnsNotificationsChannel = make(chan interface{})
 
-	secret, err := secret.Store.Logical.Read(awsCredentialsPath)
+	client, err := newDnsServiceClient(viper.GetString(config.DNSProvider), dnsNotificationsChannel)
 	if err != nil {
-		log.Errorf("Failed to read AWS credentials from Vault: %s", err.Error())
 		errCreate = err
-		return
-	}
-
-	if secret == nil {
-		log.Infoln("No AWS credentials for Route53 provided in Vault")
-		return
-	}
-
-	awsCredentials := cast.ToStringMapString(secret.Data["data"])
-	region := awsCredentials[secretTypes.AwsRegion]
-	awsSecretId := awsCredentials[secretTypes.AwsAccessKeyId]
-	awsSecretKey := awsCredentials[secretTypes.AwsSecretAccessKey]
 
-	if len(region) == 0 || len(awsSecretId) == 0 || len(awsSecretKey) == 0 {
-		log.Infoln("No AWS credentials for Route53 provided in Vault")
+		close(dnsNotificationsChannel)
 		return
 	}
 
-	dnsNotificationsChannel = make(chan interface{})
-	awsRoute53, err := route53.NewAwsRoute53(region, awsSecretId, awsSecretKey, dnsNotificationsChannel)
-	if err != nil {
-		errCreate = err
-
+	if client == nil {
 		close(dnsNotificationsChannel)
 		return
 	}
-	dnsServiceClient = awsRoute53
+	dnsServiceClient = client
 
 	// initiate and start DNS garbage collector
 	garbageCollector, err := newGarbageCollector(dnsServiceClient, gcInterval)
@@ -127,6 +122,70 @@ func newExternalDnsServiceClientInstance() {
 	dnsServiceClient.ProcessUnfinishedTasks()
 }
 
+// newDnsServiceClient creates the client of the given DNS provider.
+// It returns nil if the credentials of the provider are not provided in Vault.
+func newDnsServiceClient(provider string, notifications chan interface{}) (DnsServiceClient, error) {
+	switch provider {
+	case Route53Provider:
+		return newRoute53Client(notifications)
+
+	case GoogleProvider:
+		credentials, err := readCredentials(viper.GetString(config.DNSGoogleCredentialPath), secretTypes.ProjectId, secretTypes.PrivateKey, secretTypes.ClientEmail)
+		if err != nil || credentials == nil {
+			return nil, err
+		}
+
+		return clouddns.NewCloudDns(credentials, notifications)
+
+	case AzureProvider:
+		credentials, err := readCredentials(viper.GetString(config.DNSAzureCredentialPath),
+			secretTypes.AzureClientId, secretTypes.AzureClientSecret, secretTypes.AzureTenantId, secretTypes.AzureSubscriptionId)
+		if err != nil || credentials == nil {
+			return nil, err
+		}
+
+		return azuredns.NewAzureDns(credentials, notifications)
+
+	default:
+		return nil, fmt.Errorf("unknown DNS provider: %s", provider)
+	}
+}
+
+func newRoute53Client(notifications chan interface{}) (DnsServiceClient, error) {
+	// This is how the secrets are expected to be written in Vault:
+	// vault kv put secret/banzaicloud/aws AWS_REGION=... AWS_ACCESS_KEY_ID=... AWS_SECRET_ACCESS_KEY=...
+	awsCredentials, err := readCredentials(viper.GetString(config.AwsCredentialPath),
+		secretTypes.AwsRegion, secretTypes.AwsAccessKeyId, secretTypes.AwsSecretAccessKey)
+	if err != nil || awsCredentials == nil {
+		return nil, err
+	}
+
+	region := awsCredentials[secretTypes.AwsRegion]
+	awsSecretId := awsCredentials[secretTypes.AwsAccessKeyId]
+	awsSecretKey := awsCredentials[secretTypes.AwsSecretAccessKey]
+
+	return route53.NewAwsRoute53(region, awsSecretId, awsSecretKey, notifications)
+}
+
+// readCredentials reads the credentials of a DNS provider from Vault.
+// It returns nil if any of the required keys is missing.
+func readCredentials(path string, requiredKeys ...string) (map[string]string, error) {
+	credentials, err := secret.ReadVaultSecret(path)
+	if err != nil {
+		log.Errorf("Failed to read DNS provider credentials from Vault: %s", err.Error())
+		return nil, err
+	}
+
+	for _, key := range requiredKeys {
+		if len(credentials[key]) == 0 {
+			log.Infof("No credentials for DNS provider provided in Vault at %s", path)
+			return nil, nil
+		}
+	}
+
+	return credentials, nil
+}
+
 // GetExternalDnsServiceClient creates a new external dns service client
 func GetExternalDnsServiceClient() (DnsServiceClient, error) {
 
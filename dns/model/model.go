@@ -0,0 +1,76 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsmodel
+
+import (
+	"time"
+
+	"github.com/banzaicloud/pipeline/auth"
+)
+
+// TableName constants
+const (
+	CustomDomainsTableName = "dns_custom_domains"
+	ManualRecordsTableName = "dns_manual_records"
+)
+
+// CustomDomain describes the database model for storing the domain an organization brought
+// in place of the default <organization>.<base domain> domain
+type CustomDomain struct {
+	ID        uint `gorm:"primary_key"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	CreatedBy uint
+
+	Organization auth.Organization `gorm:"foreignkey:OrganizationId"`
+
+	OrganizationId uint   `gorm:"unique_index;not null"`
+	Domain         string `gorm:"unique_index;not null"`
+
+	// SecretId is the id of the cloud secret used to access the existing zone of the domain,
+	// it is empty if the zone is created and managed by Pipeline
+	SecretId string
+
+	// ResourceGroup is the resource group of the existing Azure DNS zone of the domain
+	ResourceGroup string
+}
+
+// TableName changes the default table name.
+func (CustomDomain) TableName() string {
+	return CustomDomainsTableName
+}
+
+// IsManaged returns true if the zone of the domain is created and managed by Pipeline
+func (d CustomDomain) IsManaged() bool {
+	return d.SecretId == ""
+}
+
+// ManualRecord describes the database model for storing the records created through the API in the zone of an organization domain.
+// The zones of the organizations having manual records are not deleted by the garbage collector.
+type ManualRecord struct {
+	ID        uint `gorm:"primary_key"`
+	CreatedAt time.Time
+	CreatedBy uint
+
+	OrganizationId uint   `gorm:"unique_index:idx_dns_manual_record;not null"`
+	Domain         string `gorm:"not null"`
+	Name           string `gorm:"unique_index:idx_dns_manual_record;not null"`
+	Type           string `gorm:"unique_index:idx_dns_manual_record;not null"`
+}
+
+// TableName changes the default table name.
+func (ManualRecord) TableName() string {
+	return ManualRecordsTableName
+}
@@ -0,0 +1,183 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/dns/model"
+	pkgDns "github.com/banzaicloud/pipeline/pkg/dns"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ErrRecordOwnedByExternalDns is returned when a manual record would conflict with a record managed by external-dns
+var ErrRecordOwnedByExternalDns = errors.New("record is managed by external-dns")
+
+// ErrManualRecordNotFound is returned when a record to delete is not a manual record
+var ErrManualRecordNotFound = errors.New("manual record not found")
+
+// ListRecords returns the records of the zone of the domain registered for the organization,
+// with the owner of the records managed by external-dns and the manual records flagged
+func ListRecords(dnsSvc DnsServiceClient, orgId uint) ([]pkgDns.Record, error) {
+	records, err := dnsSvc.ListRecords(orgId)
+	if err != nil {
+		return nil, err
+	}
+
+	manualRecords, err := ListManualRecords(orgId)
+	if err != nil {
+		return nil, err
+	}
+
+	manual := make(map[string]bool, len(manualRecords))
+	for _, manualRecord := range manualRecords {
+		manual[manualRecord.Name+"/"+manualRecord.Type] = true
+	}
+
+	pkgDns.SetRecordOwners(records)
+
+	for i := range records {
+		records[i].Manual = manual[records[i].Name+"/"+records[i].Type]
+	}
+
+	return records, nil
+}
+
+// ListManualRecords returns the records created through the API for the organization
+func ListManualRecords(orgId uint) ([]dnsmodel.ManualRecord, error) {
+	var manualRecords []dnsmodel.ManualRecord
+
+	err := config.DB().Where(&dnsmodel.ManualRecord{OrganizationId: orgId}).Find(&manualRecords).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not fetch manual records of organization %d", orgId)
+	}
+
+	return manualRecords, nil
+}
+
+// CreateManualRecord creates or replaces a record in the zone of the domain registered for the organization.
+// Records managed by external-dns can't be overridden.
+func CreateManualRecord(dnsSvc DnsServiceClient, orgId, userId uint, domain string, record pkgDns.Record) error {
+	if err := ValidateRecord(domain, record); err != nil {
+		return err
+	}
+
+	records, err := dnsSvc.ListRecords(orgId)
+	if err != nil {
+		return err
+	}
+
+	pkgDns.SetRecordOwners(records)
+
+	for _, r := range records {
+		if r.Name == record.Name && r.Owner != "" {
+			return ErrRecordOwnedByExternalDns
+		}
+	}
+
+	if err := dnsSvc.UpsertRecord(orgId, record); err != nil {
+		return err
+	}
+
+	manualRecord := dnsmodel.ManualRecord{
+		OrganizationId: orgId,
+		Domain:         domain,
+		Name:           record.Name,
+		Type:           record.Type,
+	}
+
+	err = config.DB().Where(&manualRecord).Attrs(dnsmodel.ManualRecord{CreatedBy: userId}).FirstOrCreate(&manualRecord).Error
+
+	return errors.Wrapf(err, "could not save manual record %q", record.Name)
+}
+
+// DeleteManualRecord deletes a record created through the API from the zone of the domain registered for the organization
+func DeleteManualRecord(dnsSvc DnsServiceClient, orgId uint, name, recordType string) error {
+	var manualRecord dnsmodel.ManualRecord
+
+	db := config.DB()
+
+	err := db.Where(&dnsmodel.ManualRecord{OrganizationId: orgId, Name: name, Type: recordType}).First(&manualRecord).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return ErrManualRecordNotFound
+	} else if err != nil {
+		return errors.Wrapf(err, "could not fetch manual record %q", name)
+	}
+
+	if err := dnsSvc.DeleteRecord(orgId, name, recordType); err != nil {
+		return err
+	}
+
+	return errors.Wrapf(db.Delete(&manualRecord).Error, "could not delete manual record %q", name)
+}
+
+// DeleteManualRecords forgets the manual records of the organization in a domain, used when the zone of the domain is deleted
+func DeleteManualRecords(orgId uint, domain string) error {
+	err := config.DB().Where(&dnsmodel.ManualRecord{OrganizationId: orgId, Domain: domain}).Delete(&dnsmodel.ManualRecord{}).Error
+
+	return errors.Wrapf(err, "could not delete manual records of domain %q", domain)
+}
+
+// ValidateRecord checks that a manual record is in the domain and its values are valid for its type
+func ValidateRecord(domain string, record pkgDns.Record) error {
+	if record.Name != domain && !strings.HasSuffix(record.Name, "."+domain) {
+		return errors.Errorf("record name must be in domain %q", domain)
+	}
+
+	name := strings.TrimPrefix(record.Name, "*.")
+	if errs := validation.IsDNS1123Subdomain(name); errs != nil {
+		return errors.Errorf("invalid record name: %s", strings.Join(errs, ", "))
+	}
+
+	if len(record.Values) == 0 {
+		return errors.New("record must have at least one value")
+	}
+
+	if record.TTL < 0 {
+		return errors.New("TTL must not be negative")
+	}
+
+	switch record.Type {
+	case pkgDns.RecordTypeA, pkgDns.RecordTypeAAAA:
+		for _, value := range record.Values {
+			ip := net.ParseIP(value)
+			if ip == nil || (ip.To4() != nil) != (record.Type == pkgDns.RecordTypeA) {
+				return errors.Errorf("invalid %s record value: %s", record.Type, value)
+			}
+		}
+
+	case pkgDns.RecordTypeCNAME:
+		if len(record.Values) != 1 {
+			return errors.New("CNAME record must have exactly one value")
+		}
+
+		if record.Name == domain {
+			return errors.New("CNAME record is not allowed at the apex of the domain")
+		}
+
+	case pkgDns.RecordTypeTXT:
+		// any text is a valid value
+
+	default:
+		return fmt.Errorf("record type must be one of %s", strings.Join(pkgDns.ManualRecordTypes, ", "))
+	}
+
+	return nil
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 
 	"github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/dns/model"
 	"github.com/banzaicloud/pipeline/dns/route53/model"
 	"github.com/banzaicloud/pipeline/pkg/cluster"
 )
@@ -84,7 +85,13 @@ func (stateStore *awsRoute53DatabaseStateStore) listUnused() ([]domainState, err
 
 	sqlFilter := fmt.Sprintf("organization_id NOT IN (SELECT organization_id FROM clusters WHERE deleted_at is NULL AND status<>'%s')", cluster.Error)
 
-	err := db.Where(&route53model.Route53Domain{Status: CREATED}).Where(sqlFilter).Find(&dbRecs).Error
+	// custom domains are kept as their owners delegated them to the name servers of their zones
+	customDomainsFilter := fmt.Sprintf("domain NOT IN (SELECT domain FROM %s)", dnsmodel.CustomDomainsTableName)
+
+	// manual records would be lost together with the zone
+	manualRecordsFilter := fmt.Sprintf("organization_id NOT IN (SELECT organization_id FROM %s)", dnsmodel.ManualRecordsTableName)
+
+	err := db.Where(&route53model.Route53Domain{Status: CREATED}).Where(sqlFilter).Where(customDomainsFilter).Where(manualRecordsFilter).Find(&dbRecs).Error
 	if err != nil {
 		return nil, err
 	}
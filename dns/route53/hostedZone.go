@@ -23,6 +23,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/route53"
 	"github.com/banzaicloud/pipeline/pkg/amazon"
+	pkgDns "github.com/banzaicloud/pipeline/pkg/dns"
 	"github.com/sirupsen/logrus"
 )
 
@@ -409,6 +410,48 @@ func (dns *awsRoute53) getResourceRecordSetFromBaseHostedZone(name *string) (*ro
 	return nil, nil
 }
 
+// listResourceRecordSets returns all the ResourceRecordSets of the hosted zone with the given id
+func (dns *awsRoute53) listResourceRecordSets(hostedZoneId *string) ([]*route53.ResourceRecordSet, error) {
+	var resourceRecordSets []*route53.ResourceRecordSet
+
+	input := &route53.ListResourceRecordSetsInput{HostedZoneId: hostedZoneId}
+	err := dns.route53Svc.ListResourceRecordSetsPages(input, func(page *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
+		resourceRecordSets = append(resourceRecordSets, page.ResourceRecordSets...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resourceRecordSets, nil
+}
+
+// getResourceRecordSet retrieves the ResourceRecordSet with the given name and type from the hosted zone with the given id
+// If none ResourceRecordSet found returns nil
+func (dns *awsRoute53) getResourceRecordSet(hostedZoneId *string, name, recordType string) (*route53.ResourceRecordSet, error) {
+	name = strings.TrimSuffix(name, ".") + "."
+
+	input := &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    hostedZoneId,
+		StartRecordName: aws.String(name),
+		StartRecordType: aws.String(recordType),
+		MaxItems:        aws.String("1"),
+	}
+
+	resourceRecordSets, err := dns.route53Svc.ListResourceRecordSets(input)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, resourceRecordSet := range resourceRecordSets.ResourceRecordSets {
+		if recordName(resourceRecordSet) == strings.TrimSuffix(name, ".") && aws.StringValue(resourceRecordSet.Type) == recordType {
+			return resourceRecordSet, nil
+		}
+	}
+
+	return nil, nil
+}
+
 // createResourceRecordSets creates a ResourceRecordSets in the hosted zone with the given id in Route53 service
 func (dns *awsRoute53) createResourceRecordSets(zoneId *string, rrs []*route53.ResourceRecordSet) error {
 	log := loggerWithFields(logrus.Fields{"hosted zone": aws.StringValue(zoneId)})
@@ -515,3 +558,27 @@ func createResourceRecordsFromDelegationSet(ds *route53.DelegationSet) []*route5
 func stripHostedZoneId(id string) string {
 	return strings.Replace(id, "/hostedzone/", "", 1)
 }
+
+// recordName returns the name of the ResourceRecordSet without the trailing dot and with unescaped wildcard
+func recordName(rrs *route53.ResourceRecordSet) string {
+	return strings.Replace(strings.TrimSuffix(aws.StringValue(rrs.Name), "."), `\052`, "*", 1)
+}
+
+// newRecord converts a ResourceRecordSet, alias records have the DNS name of their target as value
+func newRecord(rrs *route53.ResourceRecordSet) pkgDns.Record {
+	record := pkgDns.Record{
+		Name: recordName(rrs),
+		Type: aws.StringValue(rrs.Type),
+		TTL:  aws.Int64Value(rrs.TTL),
+	}
+
+	for _, resourceRecord := range rrs.ResourceRecords {
+		record.Values = append(record.Values, aws.StringValue(resourceRecord.Value))
+	}
+
+	if rrs.AliasTarget != nil {
+		record.Values = append(record.Values, strings.TrimSuffix(aws.StringValue(rrs.AliasTarget.DNSName), "."))
+	}
+
+	return record
+}
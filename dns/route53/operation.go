@@ -22,4 +22,9 @@ const (
 	unregisterDomain        operationType = "UnregisterDomain"
 	deleteDnsRecordsOwnedBy operationType = "DeleteDnsRecordsOwnedBy"
 	getOrgDomain            operationType = "GetOrgDomain"
+	getNameServers          operationType = "GetNameServers"
+	getDomainState          operationType = "GetDomainState"
+	listRecords             operationType = "ListRecords"
+	upsertRecord            operationType = "UpsertRecord"
+	deleteRecord            operationType = "DeleteRecord"
 )
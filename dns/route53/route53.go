@@ -16,6 +16,7 @@ package route53
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -31,6 +32,7 @@ import (
 	"github.com/banzaicloud/pipeline/config"
 	"github.com/banzaicloud/pipeline/pkg/amazon"
 	"github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgDns "github.com/banzaicloud/pipeline/pkg/dns"
 	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
 	"github.com/banzaicloud/pipeline/secret"
 	"github.com/jinzhu/now"
@@ -109,6 +111,7 @@ type workerTask struct {
 	organisationId   uint
 	domain           *string
 	dnsRecordownerId *string
+	record           *pkgDns.Record
 
 	responseQueue chan<- workerResponse
 }
@@ -133,6 +136,7 @@ type awsRoute53 struct {
 	route53Svc       route53iface.Route53API
 	iamSvc           iamiface.IAMAPI
 	stateStore       awsRoute53StateStore
+	baseDomain       string
 	baseHostedZoneId string // the id of the hosted zone of the base domain
 
 	getOrganization func(orgId uint) (*auth.Organization, error)
@@ -171,6 +175,7 @@ func NewAwsRoute53(region, awsSecretId, awsSecretKey string, notifications chan
 		getOrganization:     getOrgById,
 		notificationChannel: notifications,
 		region:              region,
+		baseDomain:          baseDomain,
 	}
 
 	baseHostedZoneId, err := awsRoute53.hostedZoneExistsByDomain(baseDomain)
@@ -188,6 +193,25 @@ func NewAwsRoute53(region, awsSecretId, awsSecretKey string, notifications chan
 	return awsRoute53, nil
 }
 
+// HostedZoneExists returns true if the hosted zone of the domain exists in the AWS account of the provided route53 credentials
+func HostedZoneExists(region, awsSecretId, awsSecretKey, domain string) (bool, error) {
+	creds := credentials.NewStaticCredentials(awsSecretId, awsSecretKey, "")
+
+	session, err := session.NewSession(aws.NewConfig().WithRegion(region).WithCredentials(creds))
+	if err != nil {
+		return false, err
+	}
+
+	dns := &awsRoute53{route53Svc: route53.New(session)}
+
+	hostedZoneId, err := dns.hostedZoneExistsByDomain(domain)
+	if err != nil {
+		return false, errors.New(extractErrorMessage(err))
+	}
+
+	return hostedZoneId != "", nil
+}
+
 // IsDomainRegistered returns true if the domain has already been registered in Route53 for the given organisation
 func (dns *awsRoute53) IsDomainRegistered(orgId uint, domain string) (bool, error) {
 	responseQueue := make(chan workerResponse)
@@ -354,13 +378,15 @@ func (dns *awsRoute53) registerDomain(orgId uint, domain string) error {
 
 	log.Info("authorisation for hosted zone configured")
 
-	// link the registered domain to base domain
-	if err := dns.chainToBaseDomain(hostedZoneId, ctx); err != nil {
-		log.Errorf("adding domain %q to base domain failed: %s", domain, extractErrorMessage(err))
+	// link the registered domain to base domain, domains outside of the base domain are delegated by their owners
+	if dns.isSubdomainOfBaseDomain(domain) {
+		if err := dns.chainToBaseDomain(hostedZoneId, ctx); err != nil {
+			log.Errorf("adding domain %q to base domain failed: %s", domain, extractErrorMessage(err))
 
-		ctx.rollback()
-		dns.updateStateWithError(state, err)
-		return err
+			ctx.rollback()
+			dns.updateStateWithError(state, err)
+			return err
+		}
 	}
 
 	state.status = CREATED
@@ -525,10 +551,12 @@ func (dns *awsRoute53) unregisterDomain(orgId uint, domain string) error {
 	}
 
 	// unlink from parent base domain
-	if err := dns.unChainFromBaseDomain(state.domain); err != nil {
-		log.Errorf("removing domain '%s' from base domain failed: %s", state.domain, extractErrorMessage(err))
-		dns.updateStateWithError(state, err)
-		return err
+	if dns.isSubdomainOfBaseDomain(state.domain) {
+		if err := dns.unChainFromBaseDomain(state.domain); err != nil {
+			log.Errorf("removing domain '%s' from base domain failed: %s", state.domain, extractErrorMessage(err))
+			dns.updateStateWithError(state, err)
+			return err
+		}
 	}
 
 	if err := dns.stateStore.delete(state); err != nil {
@@ -679,6 +707,82 @@ func (dns *awsRoute53) GetOrgDomain(orgId uint) (string, error) {
 	return fmt.Sprintf("%s", response.result), nil
 }
 
+// GetNameServers returns the name servers of the hosted zone of the domain registered for the organization with given id
+func (dns *awsRoute53) GetNameServers(orgId uint) ([]string, error) {
+	responseQueue := make(chan workerResponse)
+
+	dns.getWorker(orgId) <- newWorkerTask(getNameServers, orgId, nil, responseQueue)
+	defer close(responseQueue)
+
+	response := <-responseQueue
+	if response.error != nil {
+		return nil, response.error
+	}
+
+	nameServers, _ := response.result.([]string)
+	return nameServers, nil
+}
+
+// GetDomainState returns the registration state of the domain of the organization with given id, nil if no domain is registered
+func (dns *awsRoute53) GetDomainState(orgId uint) (*pkgDns.DomainState, error) {
+	responseQueue := make(chan workerResponse)
+
+	dns.getWorker(orgId) <- newWorkerTask(getDomainState, orgId, nil, responseQueue)
+	defer close(responseQueue)
+
+	response := <-responseQueue
+	if response.error != nil {
+		return nil, response.error
+	}
+
+	state, _ := response.result.(*pkgDns.DomainState)
+	return state, nil
+}
+
+// ListRecords returns the resource record sets of the hosted zone of the domain registered for the organization with given id
+func (dns *awsRoute53) ListRecords(orgId uint) ([]pkgDns.Record, error) {
+	responseQueue := make(chan workerResponse)
+
+	dns.getWorker(orgId) <- newWorkerTask(listRecords, orgId, nil, responseQueue)
+	defer close(responseQueue)
+
+	response := <-responseQueue
+	if response.error != nil {
+		return nil, response.error
+	}
+
+	records, _ := response.result.([]pkgDns.Record)
+	return records, nil
+}
+
+// UpsertRecord creates or replaces a resource record set in the hosted zone of the domain registered for the organization with given id
+func (dns *awsRoute53) UpsertRecord(orgId uint, record pkgDns.Record) error {
+	responseQueue := make(chan workerResponse)
+
+	task := newWorkerTask(upsertRecord, orgId, nil, responseQueue)
+	task.record = &record
+
+	dns.getWorker(orgId) <- task
+	defer close(responseQueue)
+
+	response := <-responseQueue
+	return response.error
+}
+
+// DeleteRecord deletes a resource record set from the hosted zone of the domain registered for the organization with given id
+func (dns *awsRoute53) DeleteRecord(orgId uint, name, recordType string) error {
+	responseQueue := make(chan workerResponse)
+
+	task := newWorkerTask(deleteRecord, orgId, nil, responseQueue)
+	task.record = &pkgDns.Record{Name: name, Type: recordType}
+
+	dns.getWorker(orgId) <- task
+	defer close(responseQueue)
+
+	response := <-responseQueue
+	return response.error
+}
+
 // setupAmazonAccess creates Amazon access key for the IAM user
 // and stores it in Vault. If there is a stale Amazon access key in Vault
 // creates a new Amazon access key and updates Vault
@@ -868,6 +972,107 @@ func (dns *awsRoute53) getOrgDomain(orgId uint) (string, error) {
 	return "", nil
 }
 
+func (dns *awsRoute53) getNameServers(orgId uint) ([]string, error) {
+	state := domainState{}
+	found, err := dns.stateStore.findByOrgId(orgId, &state)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	hostedZone, err := dns.getHostedZoneWithNameServers(aws.String(state.hostedZoneId))
+	if err != nil {
+		return nil, err
+	}
+
+	if hostedZone.DelegationSet == nil {
+		return nil, nil
+	}
+
+	return aws.StringValueSlice(hostedZone.DelegationSet.NameServers), nil
+}
+
+func (dns *awsRoute53) getDomainState(orgId uint) (*pkgDns.DomainState, error) {
+	state := domainState{}
+	found, err := dns.stateStore.findByOrgId(orgId, &state)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &pkgDns.DomainState{
+		Domain:       state.domain,
+		Status:       state.status,
+		ErrorMessage: state.errMsg,
+	}, nil
+}
+
+func (dns *awsRoute53) listRecords(orgId uint) ([]pkgDns.Record, error) {
+	state := domainState{}
+	found, err := dns.stateStore.findByOrgId(orgId, &state)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	resourceRecordSets, err := dns.listResourceRecordSets(aws.String(state.hostedZoneId))
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]pkgDns.Record, 0, len(resourceRecordSets))
+	for _, resourceRecordSet := range resourceRecordSets {
+		records = append(records, newRecord(resourceRecordSet))
+	}
+
+	return records, nil
+}
+
+func (dns *awsRoute53) upsertRecord(orgId uint, record pkgDns.Record) error {
+	state := domainState{}
+	found, err := dns.stateStore.findByOrgId(orgId, &state)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return fmt.Errorf("no domain registered for organisation %d", orgId)
+	}
+
+	resourceRecordSet := &route53.ResourceRecordSet{
+		Name: aws.String(record.Name),
+		Type: aws.String(record.Type),
+		TTL:  aws.Int64(record.TTL),
+	}
+
+	for _, value := range record.Values {
+		if record.Type == pkgDns.RecordTypeTXT {
+			value = pkgDns.QuoteTXT(value)
+		}
+
+		resourceRecordSet.ResourceRecords = append(resourceRecordSet.ResourceRecords, &route53.ResourceRecord{Value: aws.String(value)})
+	}
+
+	return dns.updateResourceRecordSets(aws.String(state.hostedZoneId), []*route53.ResourceRecordSet{resourceRecordSet})
+}
+
+func (dns *awsRoute53) deleteRecord(orgId uint, name, recordType string) error {
+	state := domainState{}
+	found, err := dns.stateStore.findByOrgId(orgId, &state)
+	if err != nil || !found {
+		return err
+	}
+
+	resourceRecordSet, err := dns.getResourceRecordSet(aws.String(state.hostedZoneId), name, recordType)
+	if err != nil || resourceRecordSet == nil {
+		return err
+	}
+
+	return dns.deleteResourceRecordSets(aws.String(state.hostedZoneId), []*route53.ResourceRecordSet{resourceRecordSet})
+}
+
+// isSubdomainOfBaseDomain returns true if the domain is a subdomain of the base domain thus it can be linked to the base domain
+func (dns *awsRoute53) isSubdomainOfBaseDomain(domain string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(domain, "."), "."+strings.TrimSuffix(dns.baseDomain, "."))
+}
+
 func (dns *awsRoute53) updateStateWithError(state *domainState, err error) {
 	state.status = FAILED
 	state.errMsg = extractErrorMessage(err)
@@ -942,6 +1147,21 @@ func (dns *awsRoute53) startNewWorker() chan workerTask {
 			case getOrgDomain:
 				domain, err := dns.getOrgDomain(task.organisationId)
 				task.responseQueue <- workerResponse{error: err, result: domain}
+			case getNameServers:
+				nameServers, err := dns.getNameServers(task.organisationId)
+				task.responseQueue <- workerResponse{error: err, result: nameServers}
+			case getDomainState:
+				state, err := dns.getDomainState(task.organisationId)
+				task.responseQueue <- workerResponse{error: err, result: state}
+			case listRecords:
+				records, err := dns.listRecords(task.organisationId)
+				task.responseQueue <- workerResponse{error: err, result: records}
+			case upsertRecord:
+				err := dns.upsertRecord(task.organisationId, *task.record)
+				task.responseQueue <- workerResponse{error: err}
+			case deleteRecord:
+				err := dns.deleteRecord(task.organisationId, task.record.Name, task.record.Type)
+				task.responseQueue <- workerResponse{error: err}
 			default:
 				task.responseQueue <- workerResponse{error: fmt.Errorf("operation %q not supported", task.operation)}
 			}
@@ -32,6 +32,10 @@ import (
 	"github.com/pkg/errors"
 )
 
+func init() {
+	secret.SetStore(secret.NewInMemorySecretStore())
+}
+
 const (
 	testOrgId                uint = 1
 	testOrgName                   = "testorg"
@@ -670,7 +674,7 @@ func TestAwsRoute53_RegisterDomain(t *testing.T) {
 		orgDomains: make(map[string]*domainState),
 	}
 
-	awsRoute53 := &awsRoute53{route53Svc: &mockRoute53Svc{}, iamSvc: &mockIamSvc{}, stateStore: stateStore, getOrganization: getTestOrgById, baseHostedZoneId: testBaseHostedZoneId}
+	awsRoute53 := &awsRoute53{route53Svc: &mockRoute53Svc{}, iamSvc: &mockIamSvc{}, stateStore: stateStore, getOrganization: getTestOrgById, baseHostedZoneId: testBaseHostedZoneId, baseDomain: testBaseDomain}
 
 	err := awsRoute53.RegisterDomain(testOrgId, testDomain)
 
@@ -839,7 +843,7 @@ func TestAwsRoute53_RegisterDomain_Fail(t *testing.T) {
 				orgDomains: make(map[string]*domainState),
 			}
 
-			awsRoute53 := &awsRoute53{route53Svc: tc.route53Svc, iamSvc: tc.iamSvc, stateStore: stateStore, getOrganization: getTestOrgById, baseHostedZoneId: testBaseHostedZoneId}
+			awsRoute53 := &awsRoute53{route53Svc: tc.route53Svc, iamSvc: tc.iamSvc, stateStore: stateStore, getOrganization: getTestOrgById, baseHostedZoneId: testBaseHostedZoneId, baseDomain: testBaseDomain}
 
 			err := awsRoute53.RegisterDomain(testOrgId, testDomain)
 			if err.Error() != tc.expectedErrMsg {
@@ -873,7 +877,7 @@ func TestAwsRoute53_UnregisterDomain(t *testing.T) {
 	route53Svc := &mockRoute53Svc{testCaseName: tcUnregisterDomain}
 	iamSvc := &mockIamSvc{testCaseName: tcUnregisterDomain}
 
-	awsRoute53 := &awsRoute53{route53Svc: route53Svc, iamSvc: iamSvc, stateStore: stateStore, getOrganization: getTestOrgById, baseHostedZoneId: testBaseHostedZoneId}
+	awsRoute53 := &awsRoute53{route53Svc: route53Svc, iamSvc: iamSvc, stateStore: stateStore, getOrganization: getTestOrgById, baseHostedZoneId: testBaseHostedZoneId, baseDomain: testBaseDomain}
 
 	err := awsRoute53.UnregisterDomain(testOrgId, testDomain)
 	if err != nil {
@@ -982,7 +986,7 @@ func TestAwsRoute53_Cleanup(t *testing.T) {
 				orgDomains: map[string]*domainState{key: tc.state},
 			}
 
-			awsRoute53 := &awsRoute53{route53Svc: route53Svc, iamSvc: iamSvc, stateStore: stateStore, getOrganization: getTestOrgById, baseHostedZoneId: testBaseHostedZoneId}
+			awsRoute53 := &awsRoute53{route53Svc: route53Svc, iamSvc: iamSvc, stateStore: stateStore, getOrganization: getTestOrgById, baseHostedZoneId: testBaseHostedZoneId, baseDomain: testBaseDomain}
 			awsRoute53.Cleanup()
 
 			found, _ := stateStore.find(testOrgId, testDomain, &domainState{})
@@ -1060,7 +1064,7 @@ func TestAwsRoute53_RegisterDomainRerun(t *testing.T) {
 				orgDomains: map[string]*domainState{key: tc.state},
 			}
 
-			awsRoute53 := &awsRoute53{route53Svc: route53Svc, iamSvc: iamSvc, stateStore: stateStore, getOrganization: getTestOrgById, baseHostedZoneId: testBaseHostedZoneId}
+			awsRoute53 := &awsRoute53{route53Svc: route53Svc, iamSvc: iamSvc, stateStore: stateStore, getOrganization: getTestOrgById, baseHostedZoneId: testBaseHostedZoneId, baseDomain: testBaseDomain}
 
 			err := awsRoute53.RegisterDomain(testOrgId, testDomain)
 			if err != nil {
@@ -0,0 +1,46 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zonemodel
+
+import (
+	"time"
+
+	"github.com/banzaicloud/pipeline/auth"
+)
+
+const (
+	domainsTableName = "dns_zone_domains"
+)
+
+// ZoneDomain stores the state of an organisation level domain registered in a DNS service other than Route53
+type ZoneDomain struct {
+	ID        uint `gorm:"primary_key"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Organization auth.Organization `gorm:"foreignkey:OrganizationId"`
+
+	Provider       string `gorm:"unique_index:idx_dns_zone_domain_org;not null"`
+	OrganizationId uint   `gorm:"unique_index:idx_dns_zone_domain_org;not null"`
+	Domain         string `gorm:"unique_index;not null"`
+	ZoneId         string
+	Identity       string
+	Status         string `gorm:"not null"`
+	ErrorMessage   string `sql:"type:text;"`
+}
+
+func (ZoneDomain) TableName() string {
+	return domainsTableName
+}
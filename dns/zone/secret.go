@@ -0,0 +1,62 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zone
+
+import (
+	pkgDns "github.com/banzaicloud/pipeline/pkg/dns"
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/banzaicloud/pipeline/secret"
+)
+
+// IsOwnedBy returns true if the value of an external-dns registry TXT record refers to the given owner
+func IsOwnedBy(txt, ownerId string) bool {
+	return ownerId != "" && pkgDns.RecordOwner(txt) == ownerId
+}
+
+// GetAccessSecret returns the hidden secret of the organisation which stores the credentials of external-dns,
+// nil if there is no such secret
+func GetAccessSecret(orgId uint, name string) (*secret.SecretItemResponse, error) {
+	accessSecret, err := secret.Store.Get(orgId, secret.GenerateSecretIDFromName(name))
+	if err == secret.ErrSecretNotExists {
+		return nil, nil
+	}
+
+	return accessSecret, err
+}
+
+// StoreAccessSecret creates or updates the hidden secret of the organisation which stores the credentials of external-dns
+func StoreAccessSecret(orgId uint, name, secretType string, values map[string]string) error {
+	_, err := secret.Store.CreateOrUpdate(orgId, &secret.CreateSecretRequest{
+		Name: name,
+		Type: secretType,
+		Tags: []string{
+			secretTypes.TagBanzaiHidden,
+			secretTypes.TagBanzaiReadonly,
+		},
+		Values: values,
+	})
+
+	return err
+}
+
+// DeleteAccessSecret deletes the hidden secret of the organisation which stores the credentials of external-dns
+func DeleteAccessSecret(orgId uint, name string) error {
+	accessSecret, err := GetAccessSecret(orgId, name)
+	if err != nil || accessSecret == nil {
+		return err
+	}
+
+	return secret.Store.Delete(orgId, accessSecret.ID)
+}
@@ -0,0 +1,179 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zone
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/dns/model"
+	"github.com/banzaicloud/pipeline/dns/zone/model"
+	"github.com/banzaicloud/pipeline/pkg/cluster"
+)
+
+const (
+	CREATING = "CREATING"
+	CREATED  = "CREATED"
+	FAILED   = "FAILED"
+	REMOVING = "REMOVING"
+)
+
+type domainState struct {
+	createdAt      time.Time
+	organisationId uint
+	domain         string
+	zoneId         string
+	identity       string
+	status         string
+	errMsg         string
+}
+
+type stateStore interface {
+	create(state *domainState) error
+	update(state *domainState) error
+	find(orgId uint, domain string, state *domainState) (bool, error)
+	findByStatus(status string) ([]domainState, error)
+	findByOrgId(orgId uint, state *domainState) (bool, error)
+	listUnused() ([]domainState, error)
+	delete(state *domainState) error
+}
+
+// databaseStateStore stores the domain states of a provider in the database
+type databaseStateStore struct {
+	provider string
+}
+
+func (stateStore *databaseStateStore) create(state *domainState) error {
+	db := config.DB()
+
+	rec := &zonemodel.ZoneDomain{
+		Provider:       stateStore.provider,
+		OrganizationId: state.organisationId,
+		Domain:         state.domain,
+		ZoneId:         state.zoneId,
+		Identity:       state.identity,
+		Status:         state.status,
+		ErrorMessage:   state.errMsg,
+	}
+
+	return db.Create(rec).Error
+}
+
+func (stateStore *databaseStateStore) update(state *domainState) error {
+	db := config.DB()
+
+	dbRec := &zonemodel.ZoneDomain{}
+	err := db.Where(&zonemodel.ZoneDomain{Provider: stateStore.provider, OrganizationId: state.organisationId, Domain: state.domain}).First(dbRec).Error
+	if err != nil {
+		return err
+	}
+
+	dbRec.Status = state.status
+	dbRec.ZoneId = state.zoneId
+	dbRec.Identity = state.identity
+	dbRec.ErrorMessage = state.errMsg
+
+	return db.Save(dbRec).Error
+}
+
+func (stateStore *databaseStateStore) find(orgId uint, domain string, stateOut *domainState) (bool, error) {
+	return stateStore.first(&zonemodel.ZoneDomain{Provider: stateStore.provider, OrganizationId: orgId, Domain: domain}, stateOut)
+}
+
+func (stateStore *databaseStateStore) findByOrgId(orgId uint, stateOut *domainState) (bool, error) {
+	return stateStore.first(&zonemodel.ZoneDomain{Provider: stateStore.provider, OrganizationId: orgId}, stateOut)
+}
+
+func (stateStore *databaseStateStore) first(crit *zonemodel.ZoneDomain, stateOut *domainState) (bool, error) {
+	db := config.DB()
+
+	dbRec := &zonemodel.ZoneDomain{}
+	res := db.Where(crit).First(dbRec)
+
+	if res.RecordNotFound() {
+		return false, nil
+	}
+	if res.Error != nil {
+		return false, res.Error
+	}
+
+	initStateFromZoneDomain(dbRec, stateOut)
+
+	return true, nil
+}
+
+func (stateStore *databaseStateStore) findByStatus(status string) ([]domainState, error) {
+	db := config.DB()
+	var dbRecs []zonemodel.ZoneDomain
+
+	err := db.Where(&zonemodel.ZoneDomain{Provider: stateStore.provider, Status: status}).Find(&dbRecs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainStates(dbRecs), nil
+}
+
+func (stateStore *databaseStateStore) listUnused() ([]domainState, error) {
+	db := config.DB()
+	var dbRecs []zonemodel.ZoneDomain
+
+	sqlFilter := fmt.Sprintf("organization_id NOT IN (SELECT organization_id FROM clusters WHERE deleted_at is NULL AND status<>'%s')", cluster.Error)
+
+	// custom domains are kept as their owners delegated them to the name servers of their zones
+	customDomainsFilter := fmt.Sprintf("domain NOT IN (SELECT domain FROM %s)", dnsmodel.CustomDomainsTableName)
+
+	// manual records would be lost together with the zone
+	manualRecordsFilter := fmt.Sprintf("organization_id NOT IN (SELECT organization_id FROM %s)", dnsmodel.ManualRecordsTableName)
+
+	err := db.Where(&zonemodel.ZoneDomain{Provider: stateStore.provider, Status: CREATED}).Where(sqlFilter).Where(customDomainsFilter).Where(manualRecordsFilter).Find(&dbRecs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainStates(dbRecs), nil
+}
+
+func (stateStore *databaseStateStore) delete(state *domainState) error {
+	db := config.DB()
+
+	crit := &zonemodel.ZoneDomain{Provider: stateStore.provider, OrganizationId: state.organisationId, Domain: state.domain}
+
+	return db.Where(crit).Delete(&zonemodel.ZoneDomain{}).Error
+}
+
+func toDomainStates(dbRecs []zonemodel.ZoneDomain) []domainState {
+	var domainStates []domainState
+
+	for i := 0; i < len(dbRecs); i++ {
+		var state domainState
+
+		initStateFromZoneDomain(&dbRecs[i], &state)
+		domainStates = append(domainStates, state)
+	}
+
+	return domainStates
+}
+
+func initStateFromZoneDomain(dbRecord *zonemodel.ZoneDomain, state *domainState) {
+	state.createdAt = dbRecord.CreatedAt
+	state.organisationId = dbRecord.OrganizationId
+	state.domain = dbRecord.Domain
+	state.status = dbRecord.Status
+	state.zoneId = dbRecord.ZoneId
+	state.identity = dbRecord.Identity
+	state.errMsg = dbRecord.ErrorMessage
+}
@@ -0,0 +1,470 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zone
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/banzaicloud/pipeline/config"
+	pkgDns "github.com/banzaicloud/pipeline/pkg/dns"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var logger *logrus.Logger
+
+func init() {
+	logger = config.Logger()
+}
+
+// Provider manages the DNS zones of organisation level domains and the credentials
+// external-dns uses to access them in a DNS service
+type Provider interface {
+	// EnsureZone creates the zone of the domain unless it already exists, delegates the domain to it
+	// from the zone of the base domain if it is a subdomain of the base domain and returns the ID of the zone
+	EnsureZone(domain string) (string, error)
+
+	// DeleteZone removes the delegation of the domain from the zone of the base domain
+	// and deletes the zone of the domain together with its records
+	DeleteZone(domain string) error
+
+	// NameServers returns the name servers of the zone of the domain
+	NameServers(domain string) ([]string, error)
+
+	// EnsureAccess creates an identity which is allowed to change the records of the zone only (as far as the DNS service permits),
+	// stores its credentials as a hidden secret of the organisation and returns the identity
+	EnsureAccess(orgId uint, zoneId string) (string, error)
+
+	// DeleteAccess deletes the identity created by EnsureAccess together with its credentials
+	DeleteAccess(orgId uint, identity string) error
+
+	// DeleteRecordsOwnedBy deletes the records the external-dns instance with the given owner id created in the zone of the domain
+	DeleteRecordsOwnedBy(domain, ownerId string) error
+
+	// ListRecords returns the record sets of the zone of the domain
+	ListRecords(domain string) ([]pkgDns.Record, error)
+
+	// UpsertRecord creates or replaces a record set in the zone of the domain
+	UpsertRecord(domain string, record pkgDns.Record) error
+
+	// DeleteRecord deletes a record set from the zone of the domain
+	DeleteRecord(domain, name, recordType string) error
+}
+
+// Service implements the organisation level domain management of Pipeline on top of a Provider
+type Service struct {
+	name     string
+	provider Provider
+
+	stateStore stateStore
+
+	// orgLocks serialize the operations of an organisation
+	orgLocks    map[uint]*sync.Mutex
+	muxOrgLocks sync.Mutex
+
+	notificationChannel chan<- interface{}
+}
+
+// NewService creates a new Service managing domains through the given provider
+func NewService(name string, provider Provider, notifications chan interface{}) *Service {
+	return &Service{
+		name:                name,
+		provider:            provider,
+		stateStore:          &databaseStateStore{provider: name},
+		orgLocks:            make(map[uint]*sync.Mutex),
+		notificationChannel: notifications,
+	}
+}
+
+func (s *Service) loggerWithFields(fields logrus.Fields) *logrus.Entry {
+	fields["tag"] = s.name
+
+	return logger.WithFields(fields)
+}
+
+// lockOrg locks the operations of an organisation, the returned function releases the lock
+func (s *Service) lockOrg(orgId uint) func() {
+	s.muxOrgLocks.Lock()
+	lock, ok := s.orgLocks[orgId]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.orgLocks[orgId] = lock
+	}
+	s.muxOrgLocks.Unlock()
+
+	lock.Lock()
+
+	return lock.Unlock
+}
+
+// IsDomainRegistered returns true if the domain has already been registered for the given organisation
+func (s *Service) IsDomainRegistered(orgId uint, domain string) (bool, error) {
+	defer s.lockOrg(orgId)()
+
+	state := &domainState{}
+	found, err := s.stateStore.find(orgId, domain, state)
+	if err != nil {
+		return false, errors.Wrap(err, "querying state store failed")
+	}
+
+	return found && state.status == CREATED, nil
+}
+
+// RegisterDomain registers the given domain for the organisation
+func (s *Service) RegisterDomain(orgId uint, domain string) error {
+	err := s.registerDomain(orgId, domain)
+
+	if s.notificationChannel != nil {
+		event := DomainEvent{Provider: s.name, Domain: domain, OrganisationId: orgId}
+
+		if err != nil {
+			s.notificationChannel <- RegisterDomainFailedEvent{DomainEvent: event, Cause: err}
+		} else {
+			s.notificationChannel <- RegisterDomainSucceededEvent{DomainEvent: event}
+		}
+	}
+
+	return err
+}
+
+// UnregisterDomain deletes the zone of the domain together with the identity that was created for accessing the zone
+func (s *Service) UnregisterDomain(orgId uint, domain string) error {
+	err := s.unregisterDomain(orgId, domain)
+
+	if s.notificationChannel != nil {
+		event := DomainEvent{Provider: s.name, Domain: domain, OrganisationId: orgId}
+
+		if err != nil {
+			s.notificationChannel <- UnregisterDomainFailedEvent{DomainEvent: event, Cause: err}
+		} else {
+			s.notificationChannel <- UnregisterDomainSucceededEvent{DomainEvent: event}
+		}
+	}
+
+	return err
+}
+
+func (s *Service) registerDomain(orgId uint, domain string) error {
+	defer s.lockOrg(orgId)()
+
+	log := s.loggerWithFields(logrus.Fields{"organisationId": orgId, "domain": domain})
+
+	state := &domainState{}
+	foundInStateStore, err := s.stateStore.find(orgId, domain, state)
+	if err != nil {
+		log.Errorf("querying state store failed: %s", err.Error())
+		return err
+	}
+
+	if foundInStateStore && state.status == REMOVING {
+		return fmt.Errorf("%s is in progress", state.status)
+	}
+
+	if foundInStateStore {
+		state.errMsg = ""
+		state.status = CREATING
+
+		err = s.stateStore.update(state)
+	} else {
+		state.organisationId = orgId
+		state.domain = domain
+		state.status = CREATING
+
+		err = s.stateStore.create(state)
+	}
+
+	if err != nil {
+		log.Errorf("updating state store failed: %s", err.Error())
+		return err
+	}
+
+	// a zone which was not recorded before is deleted if the registration fails
+	newZone := state.zoneId == ""
+
+	zoneId, err := s.provider.EnsureZone(domain)
+	if err != nil {
+		log.Errorf("creating zone for domain failed: %s", err.Error())
+		s.updateStateWithError(state, err)
+		return err
+	}
+
+	state.zoneId = zoneId
+	if err := s.stateStore.update(state); err != nil {
+		log.Errorf("updating state store failed: %s", err.Error())
+		return err
+	}
+
+	identity, err := s.provider.EnsureAccess(orgId, zoneId)
+	if err != nil {
+		log.Errorf("setting up access to zone '%s' failed: %s", zoneId, err.Error())
+
+		if newZone {
+			log.Info("rolling back...")
+			if err := s.provider.DeleteZone(domain); err != nil {
+				log.Errorf("rollback failed: %s", err.Error())
+			} else {
+				state.zoneId = ""
+			}
+		}
+
+		s.updateStateWithError(state, err)
+		return err
+	}
+
+	log.Info("access to zone configured")
+
+	state.identity = identity
+	state.status = CREATED
+	if err := s.stateStore.update(state); err != nil {
+		log.Errorf("updating state store failed: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (s *Service) unregisterDomain(orgId uint, domain string) error {
+	defer s.lockOrg(orgId)()
+
+	log := s.loggerWithFields(logrus.Fields{"organisationId": orgId, "domain": domain})
+
+	log.Info("unregistering domain")
+
+	state := &domainState{}
+	found, err := s.stateStore.find(orgId, domain, state)
+	if err != nil {
+		log.Errorf("querying state store failed: %s", err.Error())
+		return err
+	}
+
+	if !found {
+		return fmt.Errorf("domain '%s' not found in state store", domain)
+	}
+
+	if state.status == CREATING {
+		return fmt.Errorf("%s is in progress", state.status)
+	}
+
+	state.status = REMOVING
+	if err := s.stateStore.update(state); err != nil {
+		log.Errorf("updating state store failed: %s", err.Error())
+		return err
+	}
+
+	// revoke access first to avoid changes to the zone while it's being deleted
+	if err := s.provider.DeleteAccess(orgId, state.identity); err != nil {
+		log.Errorf("deleting access to zone '%s' failed: %s", state.zoneId, err.Error())
+		s.updateStateWithError(state, err)
+		return err
+	}
+
+	if err := s.provider.DeleteZone(domain); err != nil {
+		log.Errorf("deleting zone '%s' failed: %s", state.zoneId, err.Error())
+		s.updateStateWithError(state, err)
+		return err
+	}
+
+	if err := s.stateStore.delete(state); err != nil {
+		log.Errorf("deleting domain state from state store failed: %s", err.Error())
+		return err
+	}
+
+	log.Info("domain deleted")
+
+	return nil
+}
+
+// Cleanup unregisters the domains of the organisations without clusters.
+// Unlike Route53, Google Cloud DNS and Azure DNS charge for zones proportionally to the time they exist,
+// so unused zones are deleted right away instead of waiting for the end of the billing period.
+func (s *Service) Cleanup() {
+	log := s.loggerWithFields(logrus.Fields{})
+
+	domainStates, err := s.stateStore.listUnused()
+	if err != nil {
+		log.Errorf("retrieving domains that are not used failed: %s", err.Error())
+		return
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(len(domainStates))
+	for _, state := range domainStates {
+		go func(state domainState) {
+			defer wg.Done()
+
+			log.Infof("cleanup zone '%s' as it is not used by organisation '%d'", state.zoneId, state.organisationId)
+
+			if err := s.UnregisterDomain(state.organisationId, state.domain); err != nil {
+				log.Errorf("cleanup zone '%s' failed: %s", state.zoneId, err.Error())
+			}
+		}(state)
+	}
+
+	wg.Wait()
+}
+
+// ProcessUnfinishedTasks continues processing in-progress domain registrations/unregistrations
+func (s *Service) ProcessUnfinishedTasks() {
+	log := s.loggerWithFields(logrus.Fields{})
+
+	pendingUnregister, err := s.stateStore.findByStatus(REMOVING)
+	if err != nil {
+		log.Errorf("retrieving domains pending removal failed: %s", err.Error())
+		return
+	}
+
+	for _, state := range pendingUnregister {
+		log.Infof("continue un-registering domain '%s'", state.domain)
+
+		go s.UnregisterDomain(state.organisationId, state.domain)
+	}
+
+	pendingRegister, err := s.stateStore.findByStatus(CREATING)
+	if err != nil {
+		log.Errorf("retrieving domains pending registration failed: %s", err.Error())
+		return
+	}
+
+	for _, state := range pendingRegister {
+		log.Infof("continue registering domain '%s'", state.domain)
+
+		go s.RegisterDomain(state.organisationId, state.domain)
+	}
+}
+
+// DeleteDnsRecordsOwnedBy deletes DNS records that belong to the specified owner
+func (s *Service) DeleteDnsRecordsOwnedBy(ownerId string, orgId uint) error {
+	defer s.lockOrg(orgId)()
+
+	domain, err := s.getOrgDomain(orgId)
+	if err != nil {
+		return err
+	}
+
+	if domain == "" {
+		return nil
+	}
+
+	return s.provider.DeleteRecordsOwnedBy(domain, ownerId)
+}
+
+// GetOrgDomain returns the DNS domain name registered for the organization with given id
+func (s *Service) GetOrgDomain(orgId uint) (string, error) {
+	defer s.lockOrg(orgId)()
+
+	return s.getOrgDomain(orgId)
+}
+
+// GetDomainState returns the registration state of the domain of the organization with given id, nil if no domain is registered
+func (s *Service) GetDomainState(orgId uint) (*pkgDns.DomainState, error) {
+	defer s.lockOrg(orgId)()
+
+	state := domainState{}
+	found, err := s.stateStore.findByOrgId(orgId, &state)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &pkgDns.DomainState{
+		Domain:       state.domain,
+		Status:       state.status,
+		ErrorMessage: state.errMsg,
+	}, nil
+}
+
+// ListRecords returns the record sets of the domain registered for the organization with given id
+func (s *Service) ListRecords(orgId uint) ([]pkgDns.Record, error) {
+	defer s.lockOrg(orgId)()
+
+	domain, err := s.getOrgDomain(orgId)
+	if err != nil || domain == "" {
+		return nil, err
+	}
+
+	return s.provider.ListRecords(domain)
+}
+
+// UpsertRecord creates or replaces a record set of the domain registered for the organization with given id
+func (s *Service) UpsertRecord(orgId uint, record pkgDns.Record) error {
+	defer s.lockOrg(orgId)()
+
+	domain, err := s.getOrgDomain(orgId)
+	if err != nil {
+		return err
+	}
+
+	if domain == "" {
+		return fmt.Errorf("no domain registered for organisation %d", orgId)
+	}
+
+	return s.provider.UpsertRecord(domain, record)
+}
+
+// DeleteRecord deletes a record set of the domain registered for the organization with given id
+func (s *Service) DeleteRecord(orgId uint, name, recordType string) error {
+	defer s.lockOrg(orgId)()
+
+	domain, err := s.getOrgDomain(orgId)
+	if err != nil || domain == "" {
+		return err
+	}
+
+	return s.provider.DeleteRecord(domain, name, recordType)
+}
+
+// GetNameServers returns the name servers of the domain registered for the organization with given id
+func (s *Service) GetNameServers(orgId uint) ([]string, error) {
+	defer s.lockOrg(orgId)()
+
+	domain, err := s.getOrgDomain(orgId)
+	if err != nil || domain == "" {
+		return nil, err
+	}
+
+	return s.provider.NameServers(domain)
+}
+
+func (s *Service) getOrgDomain(orgId uint) (string, error) {
+	state := domainState{}
+	found, err := s.stateStore.findByOrgId(orgId, &state)
+	if err != nil {
+		return "", err
+	}
+
+	if found {
+		return state.domain, nil
+	}
+
+	return "", nil
+}
+
+func (s *Service) updateStateWithError(state *domainState, err error) {
+	state.status = FAILED
+	state.errMsg = err.Error()
+
+	s.stateStore.update(state)
+}
+
+// IsSubdomain returns true if the domain is a subdomain of the base domain.
+// Only these domains can be delegated from the zone of the base domain, the others have to be delegated by their owners.
+func IsSubdomain(domain, baseDomain string) bool {
+	domain = strings.TrimSuffix(domain, ".")
+	baseDomain = strings.TrimSuffix(baseDomain, ".")
+
+	return strings.HasSuffix(domain, "."+baseDomain)
+}
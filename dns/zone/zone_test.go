@@ -0,0 +1,56 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zone
+
+import "testing"
+
+func TestIsOwnedBy(t *testing.T) {
+	cases := []struct {
+		txt      string
+		ownerId  string
+		expected bool
+	}{
+		{txt: `"heritage=external-dns,external-dns/owner=cluster-1"`, ownerId: "cluster-1", expected: true},
+		{txt: `heritage=external-dns,external-dns/owner=cluster-1,external-dns/resource=service/default/app`, ownerId: "cluster-1", expected: true},
+		{txt: `"heritage=external-dns,external-dns/owner=cluster-10"`, ownerId: "cluster-1", expected: false},
+		{txt: `"external-dns/owner=cluster-1 is not a label"`, ownerId: "cluster-1", expected: false},
+		{txt: `"v=spf1 include:_spf.example.org ~all"`, ownerId: "cluster-1", expected: false},
+	}
+
+	for _, tc := range cases {
+		if actual := IsOwnedBy(tc.txt, tc.ownerId); actual != tc.expected {
+			t.Errorf("IsOwnedBy(%q, %q): expected %t, got %t", tc.txt, tc.ownerId, tc.expected, actual)
+		}
+	}
+}
+
+func TestIsSubdomain(t *testing.T) {
+	cases := []struct {
+		domain   string
+		expected bool
+	}{
+		{domain: "org.example.com", expected: true},
+		{domain: "org.example.com.", expected: true},
+		{domain: "example.com", expected: false},
+		{domain: "apps.example.org", expected: false},
+		{domain: "org.myexample.com", expected: false},
+	}
+
+	for _, tc := range cases {
+		if actual := IsSubdomain(tc.domain, "example.com"); actual != tc.expected {
+			t.Errorf("IsSubdomain(%q): expected %t, got %t", tc.domain, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// organizationPath matches the organization ID in API and dashboard paths
+var organizationPath = regexp.MustCompile(`/(?:api/v1|dashboard)/orgs/(\d+)(?:/|$)`)
+
+// likeEscaper escapes the wildcard characters of LIKE patterns
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// EventQuery filters and pages the audit events of an organization.
+// Events are returned from the newest to the oldest, Cursor is the ID of the last event of the previous page.
+type EventQuery struct {
+	OrganizationID uint
+	ClusterID      uint
+	SecretID       string
+	CorrelationID  string
+	UserID         *uint
+	Method         string
+	PathPrefix     string // relative to the base path of Pipeline
+	StatusCode     int
+	From           *time.Time
+	To             *time.Time
+	Cursor         uint
+	Limit          int
+}
+
+type Events struct {
+	db       *gorm.DB
+	basePath string
+}
+
+// NewEvents returns the audit events stored in the database.
+// Recorded paths start with basePath, the base path Pipeline is served on.
+func NewEvents(db *gorm.DB, basePath string) *Events {
+	return &Events{db: db, basePath: strings.TrimSuffix(basePath, "/")}
+}
+
+func (e *Events) filter(query EventQuery) *gorm.DB {
+	db := e.db.Model(&AuditEvent{}).Where("organization_id = ?", query.OrganizationID)
+
+	if query.ClusterID != 0 {
+		db = db.Where("cluster_id = ?", query.ClusterID)
+	}
+
+	if query.SecretID != "" {
+		db = db.Where("secret_id = ?", query.SecretID)
+	}
+
+	if query.CorrelationID != "" {
+		db = db.Where("correlation_id = ?", query.CorrelationID)
+	}
+
+	if query.UserID != nil {
+		db = db.Where("user_id = ?", *query.UserID)
+	}
+
+	if query.Method != "" {
+		db = db.Where("method = ?", strings.ToUpper(query.Method))
+	}
+
+	if query.PathPrefix != "" {
+		db = db.Where("path LIKE ?", likeEscaper.Replace(fullPathPrefix(e.basePath, query.PathPrefix))+"%")
+	}
+
+	if query.StatusCode != 0 {
+		db = db.Where("status_code = ?", query.StatusCode)
+	}
+
+	if query.From != nil {
+		db = db.Where("time >= ?", *query.From)
+	}
+
+	if query.To != nil {
+		db = db.Where("time < ?", *query.To)
+	}
+
+	if query.Cursor > 0 {
+		db = db.Where("id < ?", query.Cursor)
+	}
+
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+
+	return db.Order("id DESC")
+}
+
+// Find returns a page of the audit events of an organization.
+func (e *Events) Find(query EventQuery) ([]*AuditEvent, error) {
+	var events []*AuditEvent
+
+	err := e.filter(query).Find(&events).Error
+	if err != nil {
+		return nil, emperror.With(
+			errors.Wrap(err, "could not fetch audit events"),
+			"organization", query.OrganizationID,
+		)
+	}
+
+	return events, nil
+}
+
+// Each calls fn with every audit event of an organization matching the query, without loading all of them into memory.
+func (e *Events) Each(query EventQuery, fn func(event *AuditEvent) error) error {
+	rows, err := e.filter(query).Rows()
+	if err != nil {
+		return emperror.With(
+			errors.Wrap(err, "could not fetch audit events"),
+			"organization", query.OrganizationID,
+		)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event AuditEvent
+		if err := e.db.ScanRows(rows, &event); err != nil {
+			return errors.Wrap(err, "could not scan audit event")
+		}
+
+		if err := fn(&event); err != nil {
+			return err
+		}
+	}
+
+	return errors.Wrap(rows.Err(), "could not fetch audit events")
+}
+
+// DeleteOlderThan deletes the audit events recorded before the given time and returns the number of deleted events.
+func (e *Events) DeleteOlderThan(t time.Time) (int64, error) {
+	result := e.db.Where("time < ?", t).Delete(&AuditEvent{})
+	if result.Error != nil {
+		return 0, errors.Wrap(result.Error, "could not delete audit events")
+	}
+
+	return result.RowsAffected, nil
+}
+
+// fullPathPrefix returns a path prefix prepended with the base path, unless it already starts with it
+func fullPathPrefix(basePath string, prefix string) string {
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+
+	if basePath == "" || prefix == basePath || strings.HasPrefix(prefix, basePath+"/") {
+		return prefix
+	}
+
+	return basePath + prefix
+}
+
+// organizationID returns the ID of the organization an API path belongs to, 0 if it does not belong to any.
+func organizationID(path string) uint {
+	match := organizationPath.FindStringSubmatch(path)
+	if match == nil {
+		return 0
+	}
+
+	id, err := strconv.ParseUint(match[1], 10, 32)
+	if err != nil {
+		return 0
+	}
+
+	return uint(id)
+}
@@ -0,0 +1,58 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import "testing"
+
+func TestOrganizationID(t *testing.T) {
+	cases := map[string]uint{
+		"/pipeline/api/v1/orgs/12/clusters/3": 12,
+		"/api/v1/orgs/7":                      7,
+		"/pipeline/dashboard/orgs/5/clusters": 5,
+		"/pipeline/api/v1/orgs":               0,
+		"/pipeline/api/v1/orgs/12abc":         0,
+		"/pipeline/api/v1/tokens":             0,
+		"/auth/github/callback":               0,
+	}
+
+	for path, expected := range cases {
+		t.Run(path, func(t *testing.T) {
+			if id := organizationID(path); id != expected {
+				t.Errorf("expected %d, got %d", expected, id)
+			}
+		})
+	}
+}
+
+func TestFullPathPrefix(t *testing.T) {
+	cases := []struct {
+		basePath string
+		prefix   string
+		expected string
+	}{
+		{"/pipeline", "/api/v1/orgs/1/clusters", "/pipeline/api/v1/orgs/1/clusters"},
+		{"/pipeline", "api/v1/orgs/1", "/pipeline/api/v1/orgs/1"},
+		{"/pipeline", "/pipeline/api/v1/orgs/1", "/pipeline/api/v1/orgs/1"},
+		{"/pipeline", "/pipeline", "/pipeline"},
+		{"/pipeline", "/pipelines", "/pipeline/pipelines"},
+		{"", "/api/v1/orgs/1", "/api/v1/orgs/1"},
+	}
+
+	for _, tc := range cases {
+		if actual := fullPathPrefix(tc.basePath, tc.prefix); actual != tc.expected {
+			t.Errorf("base path %q, prefix %q: expected %q, got %q", tc.basePath, tc.prefix, tc.expected, actual)
+		}
+	}
+}
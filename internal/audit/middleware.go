@@ -21,12 +21,14 @@ import (
 	"io"
 	"net/http"
 	"net/textproto"
+	"regexp"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/internal/platform/gin/correlationid"
 	"github.com/gin-gonic/gin"
-	"github.com/jinzhu/gorm"
 	"github.com/sirupsen/logrus"
 )
 
@@ -38,11 +40,15 @@ func (*closeableBuffer) Close() error {
 	return nil
 }
 
-// LogWriter instance is a Gin Middleware which logs all request data into MySQL audit_events table.
+// streamedBodyPath matches the paths of the APIs which stream arbitrary request bodies, eg. object uploads,
+// these bodies are neither buffered nor recorded
+var streamedBodyPath = regexp.MustCompile(`/api/v1/orgs/\d+/buckets/[^/]+/objects/`)
+
+// LogWriter instance is a Gin Middleware which publishes all request data and the outcome of the request to the audit sinks.
 func LogWriter(
 	skipPaths []string,
 	whitelistedHeaders []string,
-	db *gorm.DB,
+	publisher *Publisher,
 	logger logrus.FieldLogger,
 ) gin.HandlerFunc {
 	skip := map[string]struct{}{}
@@ -59,72 +65,34 @@ func LogWriter(
 
 		// Log only when path is not being skipped
 		if _, ok := skip[path]; !ok {
-			// Copy request body into a new buffer, so other handlers can use it safely
-			bodyBuffer := &closeableBuffer{bytes.NewBuffer(nil)}
-
-			written, err := io.Copy(bodyBuffer, c.Request.Body)
-			if err != nil {
-				c.AbortWithError(http.StatusInternalServerError, err)
-				logger.Errorln(err)
+			var body *string
+			if !streamedBodyPath.MatchString(path) {
+				var ok bool
+				if body, ok = requestBody(c, path, logger); !ok {
+					return
+				}
+			}
 
-				return
+			filteredHeaders := http.Header{}
+			for _, header := range whitelistedHeaders {
+				if values := c.Request.Header[textproto.CanonicalMIMEHeaderKey(header)]; len(values) != 0 {
+					filteredHeaders[header] = values
+				}
 			}
 
-			if written != c.Request.ContentLength {
-				c.AbortWithError(http.StatusInternalServerError, fmt.Errorf("Failed to copy request body correctly"))
+			headers, err := json.Marshal(filteredHeaders)
+			if err != nil {
+				c.AbortWithError(http.StatusInternalServerError, err)
 				logger.Errorln(err)
 
 				return
 			}
 
-			rawBody := bodyBuffer.Bytes()
-			c.Request.Body = bodyBuffer
-
-			// Filter out sensitive data from body
-			var body *string
-
-			if len(rawBody) > 0 {
-
-				if !json.Valid(rawBody) {
-					c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error ": "invalid JSON in body"})
-					return
-				}
-
-				if strings.Contains(path, "/secrets") {
-
-					data := map[string]interface{}{}
-
-					err := json.Unmarshal(rawBody, &data)
-					if err != nil {
-						c.AbortWithError(http.StatusInternalServerError, err)
-						logger.Errorln(err)
+			// Capture the error message of failed requests
+			writer := &responseErrorWriter{ResponseWriter: c.Writer}
+			c.Writer = writer
 
-						return
-					}
-
-					if values, ok := data["values"].(map[string]interface{}); ok {
-						for k := range values {
-							values[k] = ""
-						}
-					}
-
-					newBody, err := json.Marshal(data)
-					if err != nil {
-						c.AbortWithError(http.StatusInternalServerError, err)
-						logger.Errorln(err)
-
-						return
-					}
-
-					newBodyString := string(newBody)
-					body = &newBodyString
-
-				} else {
-
-					newBodyString := string(rawBody)
-					body = &newBodyString
-				}
-			}
+			c.Next()
 
 			clientIP := c.ClientIP()
 			method := c.Request.Method
@@ -141,40 +109,184 @@ func LogWriter(
 				userID = user.ID
 			}
 
-			filteredHeaders := http.Header{}
-			for _, header := range whitelistedHeaders {
-				if values := c.Request.Header[textproto.CanonicalMIMEHeaderKey(header)]; len(values) != 0 {
-					filteredHeaders[header] = values
-				}
+			orgID := organizationID(c.Request.URL.Path)
+			if organization := auth.GetCurrentOrganization(c.Request); organization != nil {
+				orgID = organization.ID
 			}
 
-			headers, err := json.Marshal(filteredHeaders)
+			event := AuditEvent{
+				Time:           start,
+				ClientIP:       clientIP,
+				UserAgent:      userAgent,
+				OrganizationID: orgID,
+				ClusterID:      clusterID(c),
+				SecretID:       secretID(c),
+				UserID:         userID,
+				StatusCode:     statusCode,
+				Latency:        int64(time.Since(start) / time.Millisecond),
+				Method:         method,
+				Path:           path,
+				Body:           body,
+				Headers:        string(headers),
+				CorrelationID:  truncate(c.GetString(correlationid.ContextKey), 64),
+				ErrorMessage:   errorMessage(c, writer),
+			}
+
+			publisher.Publish(event)
+		}
+	}
+}
+
+// requestBody copies the request body so that the handlers can read it as well, and returns it with the secret values blanked out
+func requestBody(c *gin.Context, path string, logger logrus.FieldLogger) (*string, bool) {
+	// Copy request body into a new buffer, so other handlers can use it safely
+	bodyBuffer := &closeableBuffer{bytes.NewBuffer(nil)}
+
+	written, err := io.Copy(bodyBuffer, c.Request.Body)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		logger.Errorln(err)
+
+		return nil, false
+	}
+
+	// the length of chunked bodies is unknown in advance
+	if c.Request.ContentLength >= 0 && written != c.Request.ContentLength {
+		c.AbortWithError(http.StatusInternalServerError, fmt.Errorf("Failed to copy request body correctly"))
+		logger.Errorln(err)
+
+		return nil, false
+	}
+
+	rawBody := bodyBuffer.Bytes()
+	c.Request.Body = bodyBuffer
+
+	// Filter out sensitive data from body
+	var body *string
+
+	if len(rawBody) > 0 {
+
+		if !json.Valid(rawBody) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error ": "invalid JSON in body"})
+			return nil, false
+		}
+
+		if strings.Contains(path, "/secrets") {
+
+			data := map[string]interface{}{}
+
+			err := json.Unmarshal(rawBody, &data)
 			if err != nil {
 				c.AbortWithError(http.StatusInternalServerError, err)
 				logger.Errorln(err)
 
-				return
+				return nil, false
 			}
 
-			event := AuditEvent{
-				Time:       start,
-				ClientIP:   clientIP,
-				UserAgent:  userAgent,
-				UserID:     userID,
-				StatusCode: statusCode,
-				Method:     method,
-				Path:       path,
-				Body:       body,
-				Headers:    string(headers),
+			if values, ok := data["values"].(map[string]interface{}); ok {
+				for k := range values {
+					values[k] = ""
+				}
 			}
 
-			err = db.Save(&event).Error
+			newBody, err := json.Marshal(data)
 			if err != nil {
 				c.AbortWithError(http.StatusInternalServerError, err)
 				logger.Errorln(err)
 
-				return
+				return nil, false
 			}
+
+			newBodyString := string(newBody)
+			body = &newBodyString
+
+		} else {
+
+			newBodyString := string(rawBody)
+			body = &newBodyString
 		}
 	}
+
+	return body, true
+}
+
+// maxErrorMessageSize limits the size of the error messages recorded from failed responses
+const maxErrorMessageSize = 1024
+
+// responseErrorWriter keeps the beginning of the response body of failed requests
+type responseErrorWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseErrorWriter) keep(b []byte) {
+	if w.Status() < http.StatusBadRequest {
+		return
+	}
+
+	if room := maxErrorMessageSize - w.body.Len(); room > 0 {
+		if len(b) > room {
+			b = b[:runeBoundary(b, room)]
+		}
+		w.body.Write(b)
+	}
+}
+
+func (w *responseErrorWriter) Write(b []byte) (int, error) {
+	w.keep(b)
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseErrorWriter) WriteString(s string) (int, error) {
+	w.keep([]byte(s))
+
+	return w.ResponseWriter.WriteString(s)
+}
+
+// errorMessage returns the error message of a failed request from the error response or the errors of the context
+func errorMessage(c *gin.Context, writer *responseErrorWriter) string {
+	if c.Writer.Status() < http.StatusBadRequest {
+		return ""
+	}
+
+	body := writer.body.Bytes()
+
+	var response struct {
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &response); err == nil {
+		if response.Message != "" {
+			return truncate(response.Message, maxErrorMessageSize)
+		}
+
+		if response.Error != "" {
+			return truncate(response.Error, maxErrorMessageSize)
+		}
+	}
+
+	if err := c.Errors.Last(); err != nil {
+		return truncate(err.Error(), maxErrorMessageSize)
+	}
+
+	return strings.TrimSpace(string(body))
+}
+
+// truncate cuts the string to at most size bytes without splitting a multibyte character
+func truncate(s string, size int) string {
+	if len(s) > size {
+		return s[:runeBoundary([]byte(s), size)]
+	}
+
+	return s
+}
+
+// runeBoundary returns the largest index not greater than size where b can be cut without splitting a UTF-8 character
+func runeBoundary(b []byte, size int) int {
+	for size > 0 && !utf8.RuneStart(b[size]) {
+		size--
+	}
+
+	return size
 }
@@ -0,0 +1,136 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	cases := []struct {
+		s        string
+		size     int
+		expected string
+	}{
+		{"abcdef", 10, "abcdef"},
+		{"abcdef", 6, "abcdef"},
+		{"abcdef", 3, "abc"},
+		{"abcdef", 0, ""},
+		{"árvíztűrő", 2, "á"},
+		{"árvíztűrő", 1, ""},
+		{"日本語", 5, "日"},
+		{"日本語", 6, "日本"},
+	}
+
+	for _, tc := range cases {
+		actual := truncate(tc.s, tc.size)
+
+		if actual != tc.expected {
+			t.Errorf("%q to %d bytes: expected %q, got %q", tc.s, tc.size, tc.expected, actual)
+		}
+
+		if !utf8.ValidString(actual) {
+			t.Errorf("%q to %d bytes: invalid UTF-8 %q", tc.s, tc.size, actual)
+		}
+	}
+}
+
+func TestResponseErrorWriter(t *testing.T) {
+	t.Run("successful response", func(t *testing.T) {
+		c := newTestContext("GET", "/pipeline/api/v1/orgs/1/clusters")
+		writer := &responseErrorWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.String(http.StatusOK, "ok")
+
+		if writer.body.Len() != 0 {
+			t.Errorf("expected no body to be kept, got %q", writer.body.String())
+		}
+
+		if message := errorMessage(c, writer); message != "" {
+			t.Errorf("expected no error message, got %q", message)
+		}
+	})
+
+	t.Run("large failed response", func(t *testing.T) {
+		c := newTestContext("GET", "/pipeline/api/v1/orgs/1/clusters")
+		writer := &responseErrorWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		// the limit falls into the middle of a two byte character
+		body := "x" + strings.Repeat("é", maxErrorMessageSize)
+		c.String(http.StatusBadGateway, body)
+
+		kept := writer.body.String()
+		if len(kept) != maxErrorMessageSize-1 {
+			t.Errorf("expected %d bytes to be kept, got %d", maxErrorMessageSize-1, len(kept))
+		}
+
+		if !utf8.ValidString(kept) {
+			t.Error("expected the kept body to be valid UTF-8")
+		}
+
+		if message := errorMessage(c, writer); message != kept {
+			t.Errorf("expected the kept body as error message, got %q", message)
+		}
+	})
+}
+
+func TestErrorMessage(t *testing.T) {
+	cases := map[string]struct {
+		body     string
+		err      error
+		expected string
+	}{
+		"message": {
+			body:     `{"code":400,"message":"cluster not found","error":"record not found"}`,
+			expected: "cluster not found",
+		},
+		"error": {
+			body:     `{"error":"invalid JSON in body"}`,
+			expected: "invalid JSON in body",
+		},
+		"context error": {
+			body:     `{}`,
+			err:      errors.New("context error"),
+			expected: "context error",
+		},
+		"raw body": {
+			body:     "  forbidden\n",
+			expected: "forbidden",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := newTestContext("GET", "/pipeline/api/v1/orgs/1/clusters/42")
+			writer := &responseErrorWriter{ResponseWriter: c.Writer}
+			c.Writer = writer
+
+			if tc.err != nil {
+				c.Error(tc.err)
+			}
+			c.String(http.StatusForbidden, tc.body)
+
+			if message := errorMessage(c, writer); message != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, message)
+			}
+		})
+	}
+}
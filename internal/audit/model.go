@@ -19,6 +19,7 @@ import (
 	"strings"
 
 	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
@@ -37,5 +38,62 @@ func Migrate(db *gorm.DB, logger logrus.FieldLogger) error {
 		"table_names": strings.TrimSpace(tableNames),
 	}).Info("migrating audit tables")
 
-	return db.AutoMigrate(tables...).Error
+	if err := db.AutoMigrate(tables...).Error; err != nil {
+		return err
+	}
+
+	return backfillOrganizationIDs(db, logger)
+}
+
+// backfillOrganizationIDBatchSize is the number of events updated at once by the organization ID backfill
+const backfillOrganizationIDBatchSize = 1000
+
+// backfillOrganizationIDs sets the organization of the events recorded before it was stored with them,
+// so they can be queried by organization.
+func backfillOrganizationIDs(db *gorm.DB, logger logrus.FieldLogger) error {
+	rows, err := db.Model(&AuditEvent{}).
+		Select("id, path").
+		Where("(organization_id = 0 OR organization_id IS NULL) AND path LIKE ?", "%/orgs/%").
+		Rows()
+	if err != nil {
+		return errors.Wrap(err, "could not list audit events without organization")
+	}
+
+	eventIDs := make(map[uint][]uint)
+	for rows.Next() {
+		var id uint
+		var path string
+		if err := rows.Scan(&id, &path); err != nil {
+			rows.Close()
+			return errors.Wrap(err, "could not scan audit event")
+		}
+
+		if orgID := organizationID(path); orgID != 0 {
+			eventIDs[orgID] = append(eventIDs[orgID], id)
+		}
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return errors.Wrap(err, "could not list audit events without organization")
+	}
+
+	for orgID, ids := range eventIDs {
+		logger.WithField("organization", orgID).Infof("setting the organization of %d audit events", len(ids))
+
+		for len(ids) > 0 {
+			batch := ids
+			if len(batch) > backfillOrganizationIDBatchSize {
+				batch = batch[:backfillOrganizationIDBatchSize]
+			}
+			ids = ids[len(batch):]
+
+			err := db.Model(&AuditEvent{}).Where("id IN (?)", batch).UpdateColumn("organization_id", orgID).Error
+			if err != nil {
+				return errors.Wrap(err, "could not set the organization of audit events")
+			}
+		}
+	}
+
+	return nil
 }
@@ -23,16 +23,22 @@ const (
 
 // AuditEvent holds all information related to a user interaction.
 type AuditEvent struct {
-	ID         uint      `gorm:"primary_key"`
-	Time       time.Time `gorm:"index"`
-	ClientIP   string    `gorm:"size:45"`
-	UserAgent  string
-	Path       string `gorm:"size:8000"`
-	Method     string `gorm:"size:7"`
-	UserID     uint
-	StatusCode int
-	Body       *string `gorm:"type:json"`
-	Headers    string  `gorm:"type:json"`
+	ID             uint      `gorm:"primary_key" json:"id,omitempty"`
+	Time           time.Time `gorm:"index" json:"time"`
+	CorrelationID  string    `gorm:"size:64;index" json:"correlationId,omitempty"`
+	ClientIP       string    `gorm:"size:45" json:"clientIp"`
+	UserAgent      string    `json:"userAgent"`
+	Path           string    `gorm:"size:8000" json:"path"`
+	Method         string    `gorm:"size:7" json:"method"`
+	OrganizationID uint      `gorm:"index" json:"organizationId,omitempty"`
+	ClusterID      uint      `gorm:"index" json:"clusterId,omitempty"`
+	SecretID       string    `gorm:"size:64;index" json:"secretId,omitempty"`
+	UserID         uint      `json:"userId"`
+	StatusCode     int       `json:"statusCode"`
+	Latency        int64     `json:"latency"` // milliseconds
+	ErrorMessage   string    `gorm:"size:1024" json:"errorMessage,omitempty"`
+	Body           *string   `gorm:"type:json" json:"body,omitempty"`
+	Headers        string    `gorm:"type:json" json:"headers"`
 }
 
 // TableName specifies a database table name for the model.
@@ -0,0 +1,84 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Context keys of the resources resolved by the handlers
+const (
+	clusterIDKey = "audit.clusterID"
+	secretIDKey  = "audit.secretID"
+)
+
+// clusterPath matches the cluster ID in cluster API paths
+var clusterPath = regexp.MustCompile(`/api/v1/orgs/\d+/clusters/(\d+)(?:/|$)`)
+
+// secretPath matches the secret ID in secret API paths
+var secretPath = regexp.MustCompile(`/api/v1/orgs/\d+/secrets/([^/]+)`)
+
+// SetClusterID records the cluster a request operates on, eg. when it is resolved by name or created.
+func SetClusterID(c *gin.Context, clusterID uint) {
+	c.Set(clusterIDKey, clusterID)
+}
+
+// SetSecretID records the secret a request operates on, eg. when it is created.
+func SetSecretID(c *gin.Context, secretID string) {
+	c.Set(secretIDKey, secretID)
+}
+
+// clusterID returns the cluster recorded by the handler or the one in the path,
+// so that requests rejected before reaching the handler are recorded with their cluster as well
+func clusterID(c *gin.Context) uint {
+	if value, ok := c.Get(clusterIDKey); ok {
+		if id, ok := value.(uint); ok {
+			return id
+		}
+	}
+
+	// the path contains the name of the cluster instead of its ID
+	if c.Query("field") == "name" {
+		return 0
+	}
+
+	match := clusterPath.FindStringSubmatch(c.Request.URL.Path)
+	if match == nil {
+		return 0
+	}
+
+	id, err := strconv.ParseUint(match[1], 10, 32)
+	if err != nil {
+		return 0
+	}
+
+	return uint(id)
+}
+
+// secretID returns the secret recorded by the handler or the one in the path
+func secretID(c *gin.Context) string {
+	if id := c.GetString(secretIDKey); id != "" {
+		return id
+	}
+
+	if match := secretPath.FindStringSubmatch(c.Request.URL.Path); match != nil {
+		return match[1]
+	}
+
+	return ""
+}
@@ -0,0 +1,89 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestContext(method string, target string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(method, target, nil)
+
+	return c
+}
+
+func TestClusterID(t *testing.T) {
+	cases := map[string]uint{
+		"/pipeline/api/v1/orgs/1/clusters/42":                    42,
+		"/pipeline/api/v1/orgs/1/clusters/42/":                   42,
+		"/pipeline/api/v1/orgs/1/clusters/42/secrets":            42,
+		"/pipeline/api/v1/orgs/1/clusters/my-cluster":            0,
+		"/pipeline/api/v1/orgs/1/clusters/my-cluster?field=name": 0,
+		"/pipeline/api/v1/orgs/1/clusters/42?field=name":         0,
+		"/pipeline/api/v1/orgs/1/clusters":                       0,
+		"/pipeline/api/v1/orgs/1/secrets/42":                     0,
+	}
+
+	for target, expected := range cases {
+		t.Run(target, func(t *testing.T) {
+			c := newTestContext("DELETE", target)
+
+			if id := clusterID(c); id != expected {
+				t.Errorf("expected %d, got %d", expected, id)
+			}
+		})
+	}
+
+	t.Run("recorded by the handler", func(t *testing.T) {
+		c := newTestContext("DELETE", "/pipeline/api/v1/orgs/1/clusters/my-cluster?field=name")
+		SetClusterID(c, 7)
+
+		if id := clusterID(c); id != 7 {
+			t.Errorf("expected %d, got %d", 7, id)
+		}
+	})
+}
+
+func TestSecretID(t *testing.T) {
+	cases := map[string]string{
+		"/pipeline/api/v1/orgs/1/secrets/abc123":          "abc123",
+		"/pipeline/api/v1/orgs/1/secrets/abc123/tags/foo": "abc123",
+		"/pipeline/api/v1/orgs/1/secrets":                 "",
+		"/pipeline/api/v1/orgs/1/clusters/42":             "",
+	}
+
+	for target, expected := range cases {
+		t.Run(target, func(t *testing.T) {
+			c := newTestContext("GET", target)
+
+			if id := secretID(c); id != expected {
+				t.Errorf("expected %q, got %q", expected, id)
+			}
+		})
+	}
+
+	t.Run("recorded by the handler", func(t *testing.T) {
+		c := newTestContext("POST", "/pipeline/api/v1/orgs/1/secrets")
+		SetSecretID(c, "abc123")
+
+		if id := secretID(c); id != "abc123" {
+			t.Errorf("expected %q, got %q", "abc123", id)
+		}
+	})
+}
@@ -0,0 +1,263 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// Sink types
+const (
+	DatabaseSink = "database"
+	WebhookSink  = "webhook"
+	SyslogSink   = "syslog"
+	FileSink     = "file"
+)
+
+// Sink modes when the buffer of a sink is full
+const (
+	// DropMode drops the events which do not fit into the buffer, requests are never slowed down
+	DropMode = "drop"
+
+	// BlockMode blocks the request until there is room in the buffer
+	BlockMode = "block"
+)
+
+const defaultSinkBufferSize = 1000
+
+// Sink writes audit events to a destination.
+type Sink interface {
+	Write(event AuditEvent) error
+	Close() error
+}
+
+// SinkConfig holds the configuration of an audit event sink.
+type SinkConfig struct {
+	// Name identifies the sink in logs and metrics, defaults to the type
+	Name string
+
+	// Type is one of database, webhook, syslog and file
+	Type string
+
+	// BufferSize is the number of events waiting to be written to the sink
+	BufferSize int
+
+	// Mode is what happens when the buffer is full: drop or block
+	Mode string
+
+	// Webhook
+	URL     string
+	Secret  string
+	Timeout time.Duration
+
+	// Syslog
+	Network  string
+	Address  string
+	Facility int
+	AppName  string
+
+	// File
+	Path       string
+	MaxSize    int // megabytes
+	MaxBackups int
+}
+
+// NewSink returns a new sink based on its configuration.
+func NewSink(config SinkConfig, db *gorm.DB) (Sink, error) {
+	switch config.Type {
+	case DatabaseSink:
+		return NewDatabaseSink(db), nil
+
+	case WebhookSink:
+		return NewWebhookSink(config.URL, config.Secret, config.Timeout)
+
+	case SyslogSink:
+		return NewSyslogSink(config.Network, config.Address, config.Facility, config.AppName)
+
+	case FileSink:
+		return NewFileSink(config.Path, int64(config.MaxSize)*1024*1024, config.MaxBackups)
+
+	default:
+		return nil, errors.Errorf("unknown audit sink type: %s", config.Type)
+	}
+}
+
+var (
+	sinkWrittenEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "pipeline",
+		Subsystem: "audit_sink",
+		Name:      "written_events_total",
+		Help:      "Number of audit events written to the sink.",
+	}, []string{"sink"})
+
+	sinkFailedEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "pipeline",
+		Subsystem: "audit_sink",
+		Name:      "failed_events_total",
+		Help:      "Number of audit events the sink failed to write.",
+	}, []string{"sink"})
+
+	sinkDroppedEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "pipeline",
+		Subsystem: "audit_sink",
+		Name:      "dropped_events_total",
+		Help:      "Number of audit events dropped because the buffer of the sink was full.",
+	}, []string{"sink"})
+
+	sinkBlockedSeconds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "pipeline",
+		Subsystem: "audit_sink",
+		Name:      "blocked_seconds_total",
+		Help:      "Time requests spent waiting for room in the buffer of the sink.",
+	}, []string{"sink"})
+
+	sinkBufferedEvents = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "pipeline",
+		Subsystem: "audit_sink",
+		Name:      "buffered_events",
+		Help:      "Number of audit events waiting to be written to the sink.",
+	}, []string{"sink"})
+)
+
+func init() {
+	prometheus.MustRegister(sinkWrittenEvents, sinkFailedEvents, sinkDroppedEvents, sinkBlockedSeconds, sinkBufferedEvents)
+}
+
+type asyncSink struct {
+	name   string
+	sink   Sink
+	block  bool
+	events chan AuditEvent
+}
+
+// Publisher publishes audit events asynchronously to a set of sinks, each of them having its own buffer.
+type Publisher struct {
+	sinks []*asyncSink
+	wg    sync.WaitGroup
+
+	logger       logrus.FieldLogger
+	errorHandler emperror.Handler
+}
+
+// NewPublisher creates the configured sinks and starts writing the published events to them.
+func NewPublisher(configs []SinkConfig, db *gorm.DB, logger logrus.FieldLogger, errorHandler emperror.Handler) (*Publisher, error) {
+	p := &Publisher{
+		logger:       logger,
+		errorHandler: errorHandler,
+	}
+
+	for _, config := range configs {
+		sink, err := NewSink(config, db)
+		if err != nil {
+			p.Close()
+
+			return nil, emperror.With(errors.Wrap(err, "failed to create audit sink"), "sink", config.Name, "type", config.Type)
+		}
+
+		p.AddSink(config, sink)
+	}
+
+	return p, nil
+}
+
+// AddSink starts writing the published events to a sink.
+func (p *Publisher) AddSink(config SinkConfig, sink Sink) {
+	name := config.Name
+	if name == "" {
+		name = config.Type
+	}
+
+	bufferSize := config.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultSinkBufferSize
+	}
+
+	s := &asyncSink{
+		name:   name,
+		sink:   sink,
+		block:  config.Mode == BlockMode,
+		events: make(chan AuditEvent, bufferSize),
+	}
+	p.sinks = append(p.sinks, s)
+
+	p.logger.WithFields(logrus.Fields{"sink": name, "type": config.Type, "buffer": bufferSize}).Info("audit sink started")
+
+	p.wg.Add(1)
+	go p.run(s)
+}
+
+func (p *Publisher) run(s *asyncSink) {
+	defer p.wg.Done()
+
+	for event := range s.events {
+		sinkBufferedEvents.WithLabelValues(s.name).Set(float64(len(s.events)))
+
+		if err := s.sink.Write(event); err != nil {
+			sinkFailedEvents.WithLabelValues(s.name).Inc()
+			p.errorHandler.Handle(emperror.With(errors.Wrap(err, "failed to write audit event"), "sink", s.name))
+
+			continue
+		}
+
+		sinkWrittenEvents.WithLabelValues(s.name).Inc()
+	}
+}
+
+// Publish hands over an event to every sink. Events are dropped if the buffer of a sink is full,
+// unless the sink is in block mode, when it waits until the event fits into the buffer.
+func (p *Publisher) Publish(event AuditEvent) {
+	for _, s := range p.sinks {
+		select {
+		case s.events <- event:
+
+		default:
+			if !s.block {
+				sinkDroppedEvents.WithLabelValues(s.name).Inc()
+				continue
+			}
+
+			start := time.Now()
+			s.events <- event
+			sinkBlockedSeconds.WithLabelValues(s.name).Add(time.Since(start).Seconds())
+		}
+
+		sinkBufferedEvents.WithLabelValues(s.name).Set(float64(len(s.events)))
+	}
+}
+
+// Close writes the buffered events to the sinks and closes them. Events must not be published after closing.
+func (p *Publisher) Close() error {
+	for _, s := range p.sinks {
+		close(s.events)
+	}
+
+	p.wg.Wait()
+
+	errs := emperror.NewMultiErrorBuilder()
+	for _, s := range p.sinks {
+		if err := s.sink.Close(); err != nil {
+			errs.Add(emperror.With(errors.Wrap(err, "failed to close audit sink"), "sink", s.name))
+		}
+	}
+
+	return errs.ErrOrNil()
+}
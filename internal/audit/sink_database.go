@@ -0,0 +1,38 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+type databaseSink struct {
+	db *gorm.DB
+}
+
+// NewDatabaseSink returns a sink writing audit events into the audit_events table.
+func NewDatabaseSink(db *gorm.DB) Sink {
+	return &databaseSink{db: db}
+}
+
+func (s *databaseSink) Write(event AuditEvent) error {
+	event.ID = 0
+
+	return s.db.Create(&event).Error
+}
+
+func (s *databaseSink) Close() error {
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultFileMaxSize    = 100 * 1024 * 1024
+	defaultFileMaxBackups = 5
+)
+
+type fileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	file *os.File
+	size int64
+}
+
+// NewFileSink returns a sink writing audit events as JSON lines into a file.
+// The file is rotated when it reaches maxSize bytes, keeping maxBackups rotated files: <path>.1 is the newest.
+func NewFileSink(path string, maxSize int64, maxBackups int) (Sink, error) {
+	if path == "" {
+		return nil, errors.New("audit file path is required")
+	}
+
+	if maxSize <= 0 {
+		maxSize = defaultFileMaxSize
+	}
+
+	if maxBackups <= 0 {
+		maxBackups = defaultFileMaxBackups
+	}
+
+	s := &fileSink{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *fileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to open audit file")
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+
+		return errors.Wrap(err, "failed to stat audit file")
+	}
+
+	s.file = file
+	s.size = info.Size()
+
+	return nil
+}
+
+// rotate shifts the rotated files, the oldest one is removed, and starts a new file.
+// The file is reopened even if rotation fails, so that events are not lost.
+func (s *fileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return errors.Wrap(err, "failed to close audit file")
+	}
+
+	var rotateErr error
+	for i := s.maxBackups - 1; i > 0 && rotateErr == nil; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			rotateErr = err
+		}
+	}
+
+	if rotateErr == nil {
+		rotateErr = os.Rename(s.path, s.path+".1")
+	}
+
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	return errors.Wrap(rotateErr, "failed to rotate audit file")
+}
+
+func (s *fileSink) Write(event AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal audit event")
+	}
+	line = append(line, '\n')
+
+	if s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+
+	return errors.Wrap(err, "failed to write audit file")
+}
+
+func (s *fileSink) Close() error {
+	return s.file.Close()
+}
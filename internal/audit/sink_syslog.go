@@ -0,0 +1,147 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// syslogAuditFacility is the "log audit" facility of RFC5424
+	syslogAuditFacility = 13
+
+	// syslogInfoSeverity is the "informational" severity of RFC5424
+	syslogInfoSeverity = 6
+
+	syslogDialTimeout  = 10 * time.Second
+	syslogWriteTimeout = 10 * time.Second
+)
+
+type syslogSink struct {
+	network  string
+	address  string
+	facility int
+	appName  string
+	hostname string
+
+	conn net.Conn
+}
+
+// NewSyslogSink returns a sink sending audit events as JSON in RFC5424 syslog messages over TCP or UDP.
+// TCP messages are framed by octet counting (RFC6587).
+func NewSyslogSink(network string, address string, facility int, appName string) (Sink, error) {
+	if network == "" {
+		network = "udp"
+	}
+
+	if network != "tcp" && network != "udp" {
+		return nil, errors.Errorf("syslog network must be tcp or udp: %s", network)
+	}
+
+	if address == "" {
+		return nil, errors.New("syslog address is required")
+	}
+
+	if facility == 0 {
+		facility = syslogAuditFacility
+	}
+
+	if facility < 0 || facility > 23 {
+		return nil, errors.Errorf("invalid syslog facility: %d", facility)
+	}
+
+	if appName == "" {
+		appName = "pipeline"
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	return &syslogSink{
+		network:  network,
+		address:  address,
+		facility: facility,
+		appName:  appName,
+		hostname: hostname,
+	}, nil
+}
+
+func (s *syslogSink) Write(event AuditEvent) error {
+	message, err := s.format(event)
+	if err != nil {
+		return err
+	}
+
+	if s.network == "tcp" {
+		message = append([]byte(fmt.Sprintf("%d ", len(message))), message...)
+	}
+
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.address, syslogDialTimeout)
+		if err != nil {
+			return errors.Wrap(err, "failed to connect to syslog server")
+		}
+
+		s.conn = conn
+	}
+
+	s.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+
+	if _, err := s.conn.Write(message); err != nil {
+		// Reconnect on the next write
+		s.conn.Close()
+		s.conn = nil
+
+		return errors.Wrap(err, "failed to send syslog message")
+	}
+
+	return nil
+}
+
+// format formats an audit event as an RFC5424 syslog message:
+// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (s *syslogSink) format(event AuditEvent) ([]byte, error) {
+	msg, err := json.Marshal(event)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal audit event")
+	}
+
+	header := fmt.Sprintf(
+		"<%d>1 %s %s %s %d audit - ",
+		s.facility*8+syslogInfoSeverity,
+		event.Time.UTC().Format(time.RFC3339Nano),
+		s.hostname,
+		s.appName,
+		os.Getpid(),
+	)
+
+	return append([]byte(header), msg...), nil
+}
+
+func (s *syslogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+
+	return s.conn.Close()
+}
@@ -0,0 +1,149 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/sirupsen/logrus"
+)
+
+type blockingSink struct {
+	release chan struct{}
+
+	mu     sync.Mutex
+	events []AuditEvent
+}
+
+func (s *blockingSink) Write(event AuditEvent) error {
+	<-s.release
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+
+	return nil
+}
+
+func (s *blockingSink) Close() error {
+	return nil
+}
+
+func TestPublisherDropsWhenBufferIsFull(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+
+	publisher := &Publisher{logger: logger, errorHandler: emperror.NewNopHandler()}
+
+	sink := &blockingSink{release: make(chan struct{})}
+	publisher.AddSink(SinkConfig{Name: "test-drop", Type: "test", BufferSize: 1, Mode: DropMode}, sink)
+
+	// The first event is being written, the second one is buffered, the rest are dropped
+	for i := 1; i <= 5; i++ {
+		publisher.Publish(AuditEvent{ID: uint(i)})
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(sink.release)
+	publisher.Close()
+
+	if len(sink.events) != 2 || sink.events[0].ID != 1 || sink.events[1].ID != 2 {
+		t.Errorf("unexpected events written: %+v", sink.events)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	secret := "s3cr3t"
+
+	var received AuditEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		if r.Header.Get(WebhookSignatureHeader) != "sha256="+WebhookSignature([]byte(secret), body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		json.Unmarshal(body, &received)
+	}))
+	defer server.Close()
+
+	sink, err := NewWebhookSink(server.URL, secret, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if err := sink.Write(AuditEvent{Method: http.MethodDelete, Path: "/api/v1/orgs/1/clusters/2"}); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if received.Method != http.MethodDelete || received.Path != "/api/v1/orgs/1/clusters/2" {
+		t.Errorf("unexpected event received: %+v", received)
+	}
+
+	sink, _ = NewWebhookSink(server.URL, "wrong", time.Second)
+	if err := sink.Write(AuditEvent{}); err == nil {
+		t.Error("expected an error for an invalid signature")
+	}
+}
+
+func TestFileSinkRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+
+	// Every event is larger than half of the limit, so each of them starts a new file
+	sink, err := NewFileSink(path, 150, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	for _, method := range []string{"GET", "PUT", "POST", "DELETE"} {
+		if err := sink.Write(AuditEvent{Method: method, Path: "/api/v1/orgs/1/clusters"}); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+	sink.Close()
+
+	expected := map[string]string{path: "DELETE", path + ".1": "POST", path + ".2": "PUT"}
+	for file, method := range expected {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if lines := strings.Split(strings.TrimSpace(string(content)), "\n"); len(lines) != 1 || !strings.Contains(lines[0], method) {
+			t.Errorf("unexpected content of %s: %s", file, content)
+		}
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("only 2 rotated files should be kept")
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WebhookSignatureHeader holds the hex encoded HMAC-SHA256 signature of the webhook request body
+const WebhookSignatureHeader = "X-Pipeline-Signature"
+
+const defaultWebhookTimeout = 10 * time.Second
+
+type webhookSink struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// NewWebhookSink returns a sink posting audit events as JSON to an HTTP endpoint.
+// If a secret is given, the request body is signed with it in the X-Pipeline-Signature header: sha256=<hex HMAC>.
+func NewWebhookSink(url string, secret string, timeout time.Duration) (Sink, error) {
+	if url == "" {
+		return nil, errors.New("webhook url is required")
+	}
+
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+
+	return &webhookSink{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (s *webhookSink) Write(event AuditEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal audit event")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "failed to create webhook request")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if len(s.secret) > 0 {
+		req.Header.Set(WebhookSignatureHeader, "sha256="+WebhookSignature(s.secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to send webhook request")
+	}
+	defer resp.Body.Close()
+
+	// Drain the body, so the connection can be reused
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("webhook responded with status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (s *webhookSink) Close() error {
+	return nil
+}
+
+// WebhookSignature returns the hex encoded HMAC-SHA256 signature of a webhook request body.
+func WebhookSignature(secret []byte, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
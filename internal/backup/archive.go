@@ -0,0 +1,102 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	pkgBackup "github.com/banzaicloud/pipeline/pkg/backup"
+	"github.com/pkg/errors"
+)
+
+const (
+	archiveVersion = 1
+
+	// objectKeyPrefix is the common prefix of the backup objects in a bucket
+	objectKeyPrefix = "pipeline-backups"
+)
+
+// Archive is the content of a backup object.
+type Archive struct {
+	Version     int              `json:"version"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	ClusterID   uint             `json:"clusterId"`
+	ClusterName string           `json:"clusterName"`
+	Filter      pkgBackup.Filter `json:"filter"`
+	Resources   []Resource       `json:"resources"`
+	Releases    []Release        `json:"releases"`
+}
+
+// Resource is a backed up Kubernetes object.
+type Resource struct {
+	Kind      string          `json:"kind"`
+	Namespace string          `json:"namespace,omitempty"`
+	Name      string          `json:"name"`
+	Object    json.RawMessage `json:"object"`
+}
+
+// Release is the metadata of a backed up Helm release.
+// The release itself is restored from the Tiller ConfigMaps of the backup.
+type Release struct {
+	Name         string `json:"name"`
+	Namespace    string `json:"namespace"`
+	Chart        string `json:"chart"`
+	ChartVersion string `json:"chartVersion"`
+	Version      int32  `json:"version"`
+	Status       string `json:"status"`
+	Values       string `json:"values,omitempty"`
+}
+
+// objectKey returns the key of a backup object in the bucket.
+func objectKey(clusterID uint, name string) string {
+	return fmt.Sprintf("%s/%d/%s.json.gz", objectKeyPrefix, clusterID, name)
+}
+
+// writeArchive writes a gzip compressed archive.
+func writeArchive(w io.Writer, archive *Archive) error {
+	gz := gzip.NewWriter(w)
+
+	if err := json.NewEncoder(gz).Encode(archive); err != nil {
+		gz.Close()
+
+		return errors.Wrap(err, "could not encode backup archive")
+	}
+
+	return errors.Wrap(gz.Close(), "could not compress backup archive")
+}
+
+// readArchive reads a gzip compressed archive.
+func readArchive(r io.Reader) (*Archive, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decompress backup archive")
+	}
+	defer gz.Close()
+
+	var archive Archive
+	if err := json.NewDecoder(gz).Decode(&archive); err != nil {
+		return nil, errors.Wrap(err, "could not decode backup archive")
+	}
+
+	if archive.Version != archiveVersion {
+		return nil, errors.Errorf("unsupported backup archive version: %d", archive.Version)
+	}
+
+	return &archive, nil
+}
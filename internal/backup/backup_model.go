@@ -0,0 +1,173 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"strings"
+	"time"
+
+	pkgBackup "github.com/banzaicloud/pipeline/pkg/backup"
+)
+
+const (
+	backupsTableName   = "cluster_backups"
+	schedulesTableName = "cluster_backup_schedules"
+)
+
+// BucketColumns identify the object store bucket of backups.
+type BucketColumns struct {
+	Provider       string
+	SecretID       string
+	BucketName     string
+	Location       string
+	ResourceGroup  string
+	StorageAccount string
+}
+
+// FilterColumns select the Kubernetes resources of backups.
+type FilterColumns struct {
+	// Namespaces is a comma separated list, namespace names cannot contain commas
+	Namespaces    string
+	LabelSelector string
+}
+
+// BackupModel describes a backup of a cluster stored in an object store bucket.
+type BackupModel struct {
+	ID uint `gorm:"primary_key"`
+
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	CompletedAt *time.Time
+
+	OrganizationID uint `gorm:"index:idx_backup_cluster"`
+	ClusterID      uint `gorm:"index:idx_backup_cluster"`
+	ClusterName    string
+	ScheduleID     uint `gorm:"index:idx_backup_schedule_id"`
+
+	Name    string
+	Status  string
+	Message string `sql:"type:text;"`
+
+	BucketColumns
+	FilterColumns
+
+	ResourceCount int
+	ReleaseCount  int
+	Size          int64
+}
+
+// TableName changes the default table name.
+func (BackupModel) TableName() string {
+	return backupsTableName
+}
+
+// ScheduleModel describes the periodic backups of a cluster.
+type ScheduleModel struct {
+	ID uint `gorm:"primary_key"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	OrganizationID uint
+	ClusterID      uint `gorm:"unique_index:idx_backup_schedule_cluster"`
+
+	Interval  time.Duration
+	Retention int
+
+	BucketColumns
+	FilterColumns
+
+	NextBackupAt time.Time `gorm:"index:idx_backup_schedule_next_backup_at"`
+	LastBackupAt *time.Time
+	LastError    string `sql:"type:text;"`
+}
+
+// TableName changes the default table name.
+func (ScheduleModel) TableName() string {
+	return schedulesTableName
+}
+
+func newBucketColumns(bucket pkgBackup.Bucket) BucketColumns {
+	return BucketColumns{
+		Provider:       bucket.Provider,
+		SecretID:       bucket.SecretID,
+		BucketName:     bucket.Name,
+		Location:       bucket.Location,
+		ResourceGroup:  bucket.ResourceGroup,
+		StorageAccount: bucket.StorageAccount,
+	}
+}
+
+func (c BucketColumns) bucket() pkgBackup.Bucket {
+	return pkgBackup.Bucket{
+		Provider:       c.Provider,
+		SecretID:       c.SecretID,
+		Name:           c.BucketName,
+		Location:       c.Location,
+		ResourceGroup:  c.ResourceGroup,
+		StorageAccount: c.StorageAccount,
+	}
+}
+
+func newFilterColumns(filter pkgBackup.Filter) FilterColumns {
+	return FilterColumns{
+		Namespaces:    strings.Join(filter.Namespaces, ","),
+		LabelSelector: filter.LabelSelector,
+	}
+}
+
+func (c FilterColumns) filter() pkgBackup.Filter {
+	filter := pkgBackup.Filter{
+		LabelSelector: c.LabelSelector,
+	}
+
+	if c.Namespaces != "" {
+		filter.Namespaces = strings.Split(c.Namespaces, ",")
+	}
+
+	return filter
+}
+
+func (m *BackupModel) response() pkgBackup.Backup {
+	return pkgBackup.Backup{
+		ID:          m.ID,
+		Name:        m.Name,
+		ClusterID:   m.ClusterID,
+		ClusterName: m.ClusterName,
+		Status:      m.Status,
+		Message:     m.Message,
+		Bucket:      m.bucket(),
+		Filter:      m.filter(),
+		ScheduleID:  m.ScheduleID,
+		Resources:   m.ResourceCount,
+		Releases:    m.ReleaseCount,
+		Size:        m.Size,
+		CreatedAt:   m.CreatedAt,
+		CompletedAt: m.CompletedAt,
+	}
+}
+
+func (m *ScheduleModel) response() pkgBackup.ScheduleResponse {
+	return pkgBackup.ScheduleResponse{
+		ID:           m.ID,
+		Interval:     m.Interval.String(),
+		Retention:    m.Retention,
+		Bucket:       m.bucket(),
+		Filter:       m.filter(),
+		NextBackupAt: m.NextBackupAt,
+		LastBackupAt: m.LastBackupAt,
+		LastError:    m.LastError,
+	}
+}
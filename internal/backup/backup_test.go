@@ -0,0 +1,143 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	pkgBackup "github.com/banzaicloud/pipeline/pkg/backup"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestArchive(t *testing.T) {
+	archive := &Archive{
+		Version:     archiveVersion,
+		CreatedAt:   time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC),
+		ClusterID:   1,
+		ClusterName: "cluster",
+		Filter:      pkgBackup.Filter{Namespaces: []string{"default"}, LabelSelector: "app=web"},
+		Resources: []Resource{
+			{Kind: "ConfigMap", Namespace: "default", Name: "config", Object: json.RawMessage(`{"data":{"key":"value"}}`)},
+		},
+		Releases: []Release{
+			{Name: "web", Namespace: "default", Chart: "nginx", ChartVersion: "1.0.0", Version: 2, Status: "DEPLOYED"},
+		},
+	}
+
+	var buffer bytes.Buffer
+	if err := writeArchive(&buffer, archive); err != nil {
+		t.Fatal("could not write archive: ", err.Error())
+	}
+
+	actual, err := readArchive(&buffer)
+	if err != nil {
+		t.Fatal("could not read archive: ", err.Error())
+	}
+
+	if !reflect.DeepEqual(actual, archive) {
+		t.Errorf("expected archive %+v, got %+v", archive, actual)
+	}
+
+	if _, err := readArchive(bytes.NewBufferString("invalid")); err == nil {
+		t.Error("expected an error for an invalid archive")
+	}
+}
+
+func TestSelectNamespaces(t *testing.T) {
+	var namespaces []corev1.Namespace
+	for _, name := range []string{"default", "kube-system", "kube-public", "web"} {
+		namespaces = append(namespaces, corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+
+	cases := []struct {
+		name     string
+		filter   pkgBackup.Filter
+		expected []string
+	}{
+		{
+			name:     "all namespaces",
+			expected: []string{"default", "web"},
+		},
+		{
+			name:     "listed namespaces",
+			filter:   pkgBackup.Filter{Namespaces: []string{"web", "kube-system", "missing"}},
+			expected: []string{"kube-system", "web"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var actual []string
+			for _, namespace := range selectNamespaces(namespaces, tc.filter) {
+				actual = append(actual, namespace.Name)
+			}
+
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected namespaces %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestNewResource(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "web",
+			Namespace:       "default",
+			ResourceVersion: "42",
+			UID:             "uid",
+		},
+		Spec: corev1.ServiceSpec{ClusterIP: "10.0.0.1"},
+	}
+
+	resource, err := newResource("Service", service)
+	if err != nil {
+		t.Fatal("could not create resource: ", err.Error())
+	}
+
+	if resource.Namespace != "default" || resource.Name != "web" {
+		t.Errorf("unexpected resource: %s/%s", resource.Namespace, resource.Name)
+	}
+
+	var actual corev1.Service
+	if err := json.Unmarshal(resource.Object, &actual); err != nil {
+		t.Fatal("could not decode resource: ", err.Error())
+	}
+
+	if actual.ResourceVersion != "" || actual.UID != "" || actual.Spec.ClusterIP != "" {
+		t.Errorf("cluster specific fields are not removed: %+v", actual)
+	}
+
+	if service.ResourceVersion != "42" {
+		t.Error("the original object is modified")
+	}
+}
+
+func TestExpiredBackups(t *testing.T) {
+	backups := []*BackupModel{{ID: 3}, {ID: 2}, {ID: 1}}
+
+	if expired := expiredBackups(backups, 2); len(expired) != 1 || expired[0].ID != 1 {
+		t.Errorf("expected the oldest backup to expire, got %v", expired)
+	}
+
+	if expired := expiredBackups(backups, 3); len(expired) != 0 {
+		t.Errorf("expected no expired backups, got %v", expired)
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"fmt"
+	"time"
+
+	pkgBackup "github.com/banzaicloud/pipeline/pkg/backup"
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// notFoundError is returned when a backup or a backup schedule does not exist
+type notFoundError struct {
+	what           string
+	organizationID uint
+	id             uint
+}
+
+func (e *notFoundError) Error() string {
+	return fmt.Sprintf("%s not found", e.what)
+}
+
+func (e *notFoundError) Context() []interface{} {
+	return []interface{}{
+		"organization", e.organizationID,
+		"id", e.id,
+	}
+}
+
+// NotFound tells a client that this error is related to a resource being not found.
+func (e *notFoundError) NotFound() bool {
+	return true
+}
+
+type Backups struct {
+	db *gorm.DB
+}
+
+func NewBackups(db *gorm.DB) *Backups {
+	return &Backups{db: db}
+}
+
+// Save creates or updates a backup.
+func (b *Backups) Save(backup *BackupModel) error {
+	err := b.db.Save(backup).Error
+	if err != nil {
+		return emperror.With(
+			errors.Wrap(err, "could not save backup"),
+			"organization", backup.OrganizationID,
+			"cluster", backup.ClusterID,
+			"backup", backup.Name,
+		)
+	}
+
+	return nil
+}
+
+// FindOne returns a backup of an organization.
+func (b *Backups) FindOne(organizationID uint, id uint) (*BackupModel, error) {
+	var backup BackupModel
+
+	err := b.db.Where(&BackupModel{ID: id, OrganizationID: organizationID}).First(&backup).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, errors.WithStack(&notFoundError{what: "backup", organizationID: organizationID, id: id})
+	} else if err != nil {
+		return nil, emperror.With(
+			errors.Wrap(err, "could not fetch backup"),
+			"organization", organizationID,
+			"backup", id,
+		)
+	}
+
+	return &backup, nil
+}
+
+// Find returns the backups of an organization from the newest to the oldest, of a single cluster if its ID is not 0.
+func (b *Backups) Find(organizationID uint, clusterID uint) ([]*BackupModel, error) {
+	var backups []*BackupModel
+
+	err := b.db.Where(&BackupModel{OrganizationID: organizationID, ClusterID: clusterID}).Order("id DESC").Find(&backups).Error
+	if err != nil {
+		return nil, emperror.With(
+			errors.Wrap(err, "could not fetch backups"),
+			"organization", organizationID,
+			"cluster", clusterID,
+		)
+	}
+
+	return backups, nil
+}
+
+// FindCompletedBySchedule returns the completed backups created by a schedule from the newest to the oldest.
+func (b *Backups) FindCompletedBySchedule(scheduleID uint) ([]*BackupModel, error) {
+	var backups []*BackupModel
+
+	err := b.db.Where(&BackupModel{ScheduleID: scheduleID, Status: pkgBackup.StatusCompleted}).Order("id DESC").Find(&backups).Error
+	if err != nil {
+		return nil, emperror.With(errors.Wrap(err, "could not fetch scheduled backups"), "schedule", scheduleID)
+	}
+
+	return backups, nil
+}
+
+// FailRunning flags the backups interrupted by a restart as failed.
+func (b *Backups) FailRunning() error {
+	err := b.db.Model(&BackupModel{}).Where("status = ?", pkgBackup.StatusRunning).Updates(map[string]interface{}{
+		"status":       pkgBackup.StatusFailed,
+		"message":      "backup was interrupted",
+		"completed_at": time.Now(),
+	}).Error
+
+	return errors.Wrap(err, "could not fail interrupted backups")
+}
+
+// Delete deletes a backup.
+func (b *Backups) Delete(backup *BackupModel) error {
+	err := b.db.Delete(backup).Error
+	if err != nil {
+		return emperror.With(
+			errors.Wrap(err, "could not delete backup"),
+			"organization", backup.OrganizationID,
+			"backup", backup.ID,
+		)
+	}
+
+	return nil
+}
+
+type Schedules struct {
+	db *gorm.DB
+}
+
+func NewSchedules(db *gorm.DB) *Schedules {
+	return &Schedules{db: db}
+}
+
+// FindOneByCluster returns the backup schedule of a cluster.
+func (s *Schedules) FindOneByCluster(organizationID uint, clusterID uint) (*ScheduleModel, error) {
+	var schedule ScheduleModel
+
+	err := s.db.Where(&ScheduleModel{OrganizationID: organizationID, ClusterID: clusterID}).First(&schedule).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, errors.WithStack(&notFoundError{what: "backup schedule", organizationID: organizationID, id: clusterID})
+	} else if err != nil {
+		return nil, emperror.With(
+			errors.Wrap(err, "could not fetch backup schedule"),
+			"organization", organizationID,
+			"cluster", clusterID,
+		)
+	}
+
+	return &schedule, nil
+}
+
+// FindDue returns the schedules whose next backup is due at the given time.
+func (s *Schedules) FindDue(t time.Time) ([]*ScheduleModel, error) {
+	var schedules []*ScheduleModel
+
+	err := s.db.Where("next_backup_at <= ?", t).Order("next_backup_at").Find(&schedules).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "could not fetch backup schedules")
+	}
+
+	return schedules, nil
+}
+
+// Save creates or updates a backup schedule.
+func (s *Schedules) Save(schedule *ScheduleModel) error {
+	err := s.db.Save(schedule).Error
+	if err != nil {
+		return emperror.With(
+			errors.Wrap(err, "could not save backup schedule"),
+			"organization", schedule.OrganizationID,
+			"cluster", schedule.ClusterID,
+		)
+	}
+
+	return nil
+}
+
+// DeleteByCluster deletes the backup schedule of a cluster, if there is any.
+func (s *Schedules) DeleteByCluster(organizationID uint, clusterID uint) error {
+	err := s.db.Where("organization_id = ? AND cluster_id = ?", organizationID, clusterID).Delete(&ScheduleModel{}).Error
+	if err != nil {
+		return emperror.With(
+			errors.Wrap(err, "could not delete backup schedule"),
+			"organization", organizationID,
+			"cluster", clusterID,
+		)
+	}
+
+	return nil
+}
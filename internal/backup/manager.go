@@ -0,0 +1,503 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/banzaicloud/pipeline/helm"
+	"github.com/banzaicloud/pipeline/internal/objectstore"
+	intProviders "github.com/banzaicloud/pipeline/internal/providers"
+	pkgBackup "github.com/banzaicloud/pipeline/pkg/backup"
+	pkgObjectstore "github.com/banzaicloud/pipeline/pkg/objectstore"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+	rls "k8s.io/helm/pkg/proto/hapi/services"
+)
+
+const (
+	// minScheduleInterval is the shortest interval a cluster can be backed up at
+	minScheduleInterval = time.Hour
+
+	backupNameTimeFormat = "20060102150405"
+)
+
+// ValidationError describes an invalid backup, restore or schedule request
+type ValidationError struct {
+	Reason string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("invalid request: %s", e.Reason)
+}
+
+// Cluster is the subset of the cluster interface used by backups.
+type Cluster interface {
+	GetID() uint
+	GetOrganizationId() uint
+	GetName() string
+	GetK8sConfig() ([]byte, error)
+}
+
+// ClusterGetter returns a cluster of an organization.
+type ClusterGetter func(ctx context.Context, organizationID uint, clusterID uint) (Cluster, error)
+
+// Manager creates, restores and schedules backups of the Kubernetes resources and Helm releases of clusters.
+// Volume data is not backed up.
+type Manager struct {
+	backups    *Backups
+	schedules  *Schedules
+	secrets    secret.SecretStore
+	getCluster ClusterGetter
+
+	logger       logrus.FieldLogger
+	errorHandler emperror.Handler
+}
+
+// NewManager returns a new backup manager.
+func NewManager(
+	db *gorm.DB,
+	secrets secret.SecretStore,
+	getCluster ClusterGetter,
+	logger logrus.FieldLogger,
+	errorHandler emperror.Handler,
+) *Manager {
+	return &Manager{
+		backups:      NewBackups(db),
+		schedules:    NewSchedules(db),
+		secrets:      secrets,
+		getCluster:   getCluster,
+		logger:       logger,
+		errorHandler: errorHandler,
+	}
+}
+
+// CreateBackup checks the bucket and starts backing up a cluster in the background.
+func (m *Manager) CreateBackup(ctx context.Context, organizationID uint, clusterID uint, request *pkgBackup.CreateBackupRequest) (*pkgBackup.Backup, error) {
+	cluster, err := m.getCluster(ctx, organizationID, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateFilter(request.Filter); err != nil {
+		return nil, err
+	}
+
+	bucket, client, err := m.getObjectClient(organizationID, request.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	backup := newBackupModel(cluster, bucket, request.Filter, 0)
+	if err := m.backups.Save(backup); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		defer emperror.HandleRecover(m.errorHandler)
+
+		if err := m.runBackup(cluster, client, backup); err != nil {
+			m.errorHandler.Handle(err)
+		}
+	}()
+
+	response := backup.response()
+
+	return &response, nil
+}
+
+// ListBackups returns the backups of an organization, of a single cluster if its ID is not 0.
+func (m *Manager) ListBackups(organizationID uint, clusterID uint) ([]pkgBackup.Backup, error) {
+	backups, err := m.backups.Find(organizationID, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]pkgBackup.Backup, 0, len(backups))
+	for _, backup := range backups {
+		response = append(response, backup.response())
+	}
+
+	return response, nil
+}
+
+// GetBackup returns a backup of an organization.
+func (m *Manager) GetBackup(organizationID uint, backupID uint) (*pkgBackup.Backup, error) {
+	backup, err := m.backups.FindOne(organizationID, backupID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := backup.response()
+
+	return &response, nil
+}
+
+// DeleteBackup deletes a backup and its object.
+func (m *Manager) DeleteBackup(organizationID uint, backupID uint) error {
+	backup, err := m.backups.FindOne(organizationID, backupID)
+	if err != nil {
+		return err
+	}
+
+	if backup.Status == pkgBackup.StatusRunning {
+		return ValidationError{Reason: "backup is still running"}
+	}
+
+	return m.deleteBackup(backup)
+}
+
+// Restore replays a backup of any cluster of the organization onto a cluster.
+// Resources which already exist on the cluster are skipped.
+// The caller has to check whether the backed up cluster can be accessed.
+func (m *Manager) Restore(ctx context.Context, organizationID uint, clusterID uint, request *pkgBackup.RestoreRequest) (*pkgBackup.RestoreResponse, error) {
+	backup, err := m.backups.FindOne(organizationID, request.BackupID)
+	if err != nil {
+		return nil, err
+	}
+
+	if backup.Status != pkgBackup.StatusCompleted {
+		return nil, ValidationError{Reason: fmt.Sprintf("backup is %s", backup.Status)}
+	}
+
+	cluster, err := m.getCluster(ctx, organizationID, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, client, err := m.getObjectClient(organizationID, backup.bucket())
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := client.GetObject(backup.BucketName, objectKey(backup.ClusterID, backup.Name))
+	if err != nil {
+		return nil, emperror.With(errors.Wrap(err, "could not download backup"), "backup", backup.ID)
+	}
+	defer object.Close()
+
+	archive, err := readArchive(object)
+	if err != nil {
+		return nil, emperror.With(err, "backup", backup.ID)
+	}
+
+	kubeConfig, err := cluster.GetK8sConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get cluster config")
+	}
+
+	k8sClient, err := helm.GetK8sConnection(kubeConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to cluster")
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"organization": organizationID,
+		"cluster":      clusterID,
+		"backup":       backup.ID,
+	}).Info("restoring backup")
+
+	response := restoreResources(k8sClient, archive.Resources)
+	response.BackupID = backup.ID
+
+	return &response, nil
+}
+
+// SetSchedule creates or updates the backup schedule of a cluster.
+func (m *Manager) SetSchedule(ctx context.Context, organizationID uint, clusterID uint, request *pkgBackup.ScheduleRequest) (*pkgBackup.ScheduleResponse, error) {
+	if _, err := m.getCluster(ctx, organizationID, clusterID); err != nil {
+		return nil, err
+	}
+
+	interval, err := time.ParseDuration(request.Interval)
+	if err != nil {
+		return nil, ValidationError{Reason: err.Error()}
+	}
+
+	if interval < minScheduleInterval {
+		return nil, ValidationError{Reason: fmt.Sprintf("interval must be at least %s", minScheduleInterval)}
+	}
+
+	if request.Retention < 1 {
+		return nil, ValidationError{Reason: "retention must be at least 1"}
+	}
+
+	if err := validateFilter(request.Filter); err != nil {
+		return nil, err
+	}
+
+	bucket, _, err := m.getObjectClient(organizationID, request.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := m.schedules.FindOneByCluster(organizationID, clusterID)
+	if isNotFound(err) {
+		schedule = &ScheduleModel{OrganizationID: organizationID, ClusterID: clusterID}
+	} else if err != nil {
+		return nil, err
+	}
+
+	schedule.Interval = interval
+	schedule.Retention = request.Retention
+	schedule.BucketColumns = newBucketColumns(bucket)
+	schedule.FilterColumns = newFilterColumns(request.Filter)
+
+	lastBackup := time.Now()
+	if schedule.LastBackupAt != nil {
+		lastBackup = *schedule.LastBackupAt
+	}
+	schedule.NextBackupAt = lastBackup.Add(interval)
+
+	if err := m.schedules.Save(schedule); err != nil {
+		return nil, err
+	}
+
+	response := schedule.response()
+
+	return &response, nil
+}
+
+// GetSchedule returns the backup schedule of a cluster.
+func (m *Manager) GetSchedule(organizationID uint, clusterID uint) (*pkgBackup.ScheduleResponse, error) {
+	schedule, err := m.schedules.FindOneByCluster(organizationID, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := schedule.response()
+
+	return &response, nil
+}
+
+// DeleteSchedule deletes the backup schedule of a cluster, if there is any.
+// Backups created by the schedule are kept.
+func (m *Manager) DeleteSchedule(organizationID uint, clusterID uint) error {
+	return m.schedules.DeleteByCluster(organizationID, clusterID)
+}
+
+// getObjectClient returns a client for the bucket, the provider of the bucket is set from its secret.
+func (m *Manager) getObjectClient(organizationID uint, bucket pkgBackup.Bucket) (pkgBackup.Bucket, pkgObjectstore.ObjectStore, error) {
+	bucketSecret, err := m.secrets.Get(organizationID, bucket.SecretID)
+	if err == secret.ErrSecretNotExists {
+		return bucket, nil, ValidationError{Reason: "bucket secret does not exist"}
+	} else if err != nil {
+		return bucket, nil, emperror.With(errors.Wrap(err, "could not get bucket secret"), "secret", bucket.SecretID)
+	}
+
+	if bucket.Provider != "" && bucket.Provider != bucketSecret.Type {
+		return bucket, nil, ValidationError{Reason: fmt.Sprintf("bucket secret is not a %s secret", bucket.Provider)}
+	}
+	bucket.Provider = bucketSecret.Type
+
+	client, err := intProviders.NewObjectClient(&intProviders.ObjectStoreContext{
+		Provider:       bucket.Provider,
+		Secret:         bucketSecret,
+		Location:       bucket.Location,
+		ResourceGroup:  bucket.ResourceGroup,
+		StorageAccount: bucket.StorageAccount,
+	})
+	if err != nil {
+		return bucket, nil, ValidationError{Reason: err.Error()}
+	}
+
+	err = client.CheckBucket(bucket.Name)
+	if objectstore.IsNotFoundError(err) {
+		return bucket, nil, ValidationError{Reason: fmt.Sprintf("bucket %s does not exist", bucket.Name)}
+	} else if err != nil {
+		return bucket, nil, emperror.With(errors.Wrap(err, "could not check bucket"), "bucket", bucket.Name)
+	}
+
+	return bucket, client, nil
+}
+
+// runBackup uploads the archive of a cluster and records the result, the returned error is recorded as the message of the backup.
+func (m *Manager) runBackup(cluster Cluster, client pkgObjectstore.ObjectStore, backup *BackupModel) error {
+	logger := m.logger.WithFields(logrus.Fields{
+		"organization": backup.OrganizationID,
+		"cluster":      backup.ClusterID,
+		"backup":       backup.Name,
+	})
+
+	logger.Info("backing up cluster")
+
+	err := m.uploadArchive(cluster, client, backup)
+
+	now := time.Now()
+	backup.CompletedAt = &now
+
+	if err != nil {
+		err = emperror.With(err, "organization", backup.OrganizationID, "cluster", backup.ClusterID, "backup", backup.Name)
+
+		backup.Status = pkgBackup.StatusFailed
+		backup.Message = err.Error()
+	} else {
+		logger.Info("cluster backed up")
+
+		backup.Status = pkgBackup.StatusCompleted
+	}
+
+	if err := m.backups.Save(backup); err != nil {
+		m.errorHandler.Handle(err)
+	}
+
+	return err
+}
+
+func (m *Manager) uploadArchive(cluster Cluster, client pkgObjectstore.ObjectStore, backup *BackupModel) error {
+	kubeConfig, err := cluster.GetK8sConfig()
+	if err != nil {
+		return errors.Wrap(err, "could not get cluster config")
+	}
+
+	k8sClient, err := helm.GetK8sConnection(kubeConfig)
+	if err != nil {
+		return errors.Wrap(err, "could not connect to cluster")
+	}
+
+	filter := backup.filter()
+
+	resources, err := collectResources(k8sClient, filter)
+	if err != nil {
+		return err
+	}
+
+	var releases []Release
+
+	deployments, err := helm.ListDeployments(nil, kubeConfig)
+	if err != nil {
+		// clusters without Tiller are backed up without releases
+		m.logger.WithField("cluster", backup.ClusterID).Warnf("could not list Helm releases: %s", err.Error())
+	} else {
+		releases = selectReleases(deployments, backedUpNamespaces(resources))
+	}
+
+	tillerResources, err := collectTillerResources(k8sClient, releases)
+	if err != nil {
+		return err
+	}
+	resources = append(resources, tillerResources...)
+
+	archive := &Archive{
+		Version:     archiveVersion,
+		CreatedAt:   backup.CreatedAt,
+		ClusterID:   backup.ClusterID,
+		ClusterName: backup.ClusterName,
+		Filter:      filter,
+		Resources:   resources,
+		Releases:    releases,
+	}
+
+	var buffer bytes.Buffer
+	if err := writeArchive(&buffer, archive); err != nil {
+		return err
+	}
+
+	backup.ResourceCount = len(resources)
+	backup.ReleaseCount = len(releases)
+	backup.Size = int64(buffer.Len())
+
+	err = client.PutObject(backup.BucketName, objectKey(backup.ClusterID, backup.Name), &buffer)
+
+	return errors.Wrap(err, "could not upload backup")
+}
+
+// deleteBackup deletes the object and the record of a backup.
+func (m *Manager) deleteBackup(backup *BackupModel) error {
+	if backup.Status == pkgBackup.StatusCompleted {
+		_, client, err := m.getObjectClient(backup.OrganizationID, backup.bucket())
+		if _, ok := err.(ValidationError); ok {
+			// the bucket or its secret is gone, there is no object to delete
+			m.logger.WithField("backup", backup.ID).Warnf("could not delete backup object: %s", err.Error())
+		} else if err != nil {
+			return err
+		} else {
+			err = client.DeleteObject(backup.BucketName, objectKey(backup.ClusterID, backup.Name))
+			if err != nil && !objectstore.IsNotFoundError(err) {
+				return emperror.With(errors.Wrap(err, "could not delete backup object"), "backup", backup.ID)
+			}
+		}
+	}
+
+	return m.backups.Delete(backup)
+}
+
+func newBackupModel(cluster Cluster, bucket pkgBackup.Bucket, filter pkgBackup.Filter, scheduleID uint) *BackupModel {
+	return &BackupModel{
+		OrganizationID: cluster.GetOrganizationId(),
+		ClusterID:      cluster.GetID(),
+		ClusterName:    cluster.GetName(),
+		ScheduleID:     scheduleID,
+		Name:           fmt.Sprintf("%s-%s", cluster.GetName(), time.Now().UTC().Format(backupNameTimeFormat)),
+		Status:         pkgBackup.StatusRunning,
+		BucketColumns:  newBucketColumns(bucket),
+		FilterColumns:  newFilterColumns(filter),
+	}
+}
+
+func validateFilter(filter pkgBackup.Filter) error {
+	if _, err := labels.Parse(filter.LabelSelector); err != nil {
+		return ValidationError{Reason: err.Error()}
+	}
+
+	return nil
+}
+
+// backedUpNamespaces returns the namespaces among the resources.
+func backedUpNamespaces(resources []Resource) map[string]bool {
+	namespaces := make(map[string]bool)
+	for _, resource := range resources {
+		if resource.Kind == namespaceKindName {
+			namespaces[resource.Name] = true
+		}
+	}
+
+	return namespaces
+}
+
+// selectReleases returns the metadata of the releases deployed into the given namespaces.
+func selectReleases(deployments *rls.ListReleasesResponse, namespaces map[string]bool) []Release {
+	var releases []Release
+	for _, release := range deployments.GetReleases() {
+		if !namespaces[release.GetNamespace()] {
+			continue
+		}
+
+		releases = append(releases, Release{
+			Name:         release.GetName(),
+			Namespace:    release.GetNamespace(),
+			Chart:        release.GetChart().GetMetadata().GetName(),
+			ChartVersion: release.GetChart().GetMetadata().GetVersion(),
+			Version:      release.GetVersion(),
+			Status:       release.GetInfo().GetStatus().GetCode().String(),
+			Values:       release.GetConfig().GetRaw(),
+		})
+	}
+
+	return releases
+}
+
+func isNotFound(err error) bool {
+	notFoundErr, ok := errors.Cause(err).(interface{ NotFound() bool })
+
+	return ok && notFoundErr.NotFound()
+}
@@ -0,0 +1,454 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	pkgBackup "github.com/banzaicloud/pipeline/pkg/backup"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	tillerNamespace   = "kube-system"
+	tillerOwnerLabel  = "OWNER"
+	tillerOwner       = "TILLER"
+	tillerNameLabel   = "NAME"
+	configMapKindName = "ConfigMap"
+	namespaceKindName = "Namespace"
+)
+
+// systemNamespaces are only backed up if they are listed explicitly in the filter
+var systemNamespaces = map[string]bool{
+	"kube-system": true,
+	"kube-public": true,
+}
+
+// resourceKind knows how to list and create the objects of a Kubernetes resource kind
+type resourceKind struct {
+	name string
+
+	// list returns the objects of a namespace, cluster scoped kinds ignore the namespace
+	list func(client kubernetes.Interface, namespace string, options metav1.ListOptions) (runtime.Object, error)
+
+	// create creates an object from its JSON representation
+	create func(client kubernetes.Interface, data []byte) error
+}
+
+// resourceKinds are the backed up kinds in the order they are restored
+var resourceKinds = []resourceKind{
+	{
+		name: namespaceKindName,
+		list: func(client kubernetes.Interface, _ string, options metav1.ListOptions) (runtime.Object, error) {
+			return client.CoreV1().Namespaces().List(options)
+		},
+		create: func(client kubernetes.Interface, data []byte) error {
+			var object corev1.Namespace
+			if err := json.Unmarshal(data, &object); err != nil {
+				return err
+			}
+			_, err := client.CoreV1().Namespaces().Create(&object)
+			return err
+		},
+	},
+	{
+		name: "ServiceAccount",
+		list: func(client kubernetes.Interface, namespace string, options metav1.ListOptions) (runtime.Object, error) {
+			return client.CoreV1().ServiceAccounts(namespace).List(options)
+		},
+		create: func(client kubernetes.Interface, data []byte) error {
+			var object corev1.ServiceAccount
+			if err := json.Unmarshal(data, &object); err != nil {
+				return err
+			}
+			_, err := client.CoreV1().ServiceAccounts(object.Namespace).Create(&object)
+			return err
+		},
+	},
+	{
+		name: "Secret",
+		list: func(client kubernetes.Interface, namespace string, options metav1.ListOptions) (runtime.Object, error) {
+			return client.CoreV1().Secrets(namespace).List(options)
+		},
+		create: func(client kubernetes.Interface, data []byte) error {
+			var object corev1.Secret
+			if err := json.Unmarshal(data, &object); err != nil {
+				return err
+			}
+			_, err := client.CoreV1().Secrets(object.Namespace).Create(&object)
+			return err
+		},
+	},
+	{
+		name: configMapKindName,
+		list: func(client kubernetes.Interface, namespace string, options metav1.ListOptions) (runtime.Object, error) {
+			return client.CoreV1().ConfigMaps(namespace).List(options)
+		},
+		create: func(client kubernetes.Interface, data []byte) error {
+			var object corev1.ConfigMap
+			if err := json.Unmarshal(data, &object); err != nil {
+				return err
+			}
+			_, err := client.CoreV1().ConfigMaps(object.Namespace).Create(&object)
+			return err
+		},
+	},
+	{
+		name: "PersistentVolumeClaim",
+		list: func(client kubernetes.Interface, namespace string, options metav1.ListOptions) (runtime.Object, error) {
+			return client.CoreV1().PersistentVolumeClaims(namespace).List(options)
+		},
+		create: func(client kubernetes.Interface, data []byte) error {
+			var object corev1.PersistentVolumeClaim
+			if err := json.Unmarshal(data, &object); err != nil {
+				return err
+			}
+			_, err := client.CoreV1().PersistentVolumeClaims(object.Namespace).Create(&object)
+			return err
+		},
+	},
+	{
+		name: "Service",
+		list: func(client kubernetes.Interface, namespace string, options metav1.ListOptions) (runtime.Object, error) {
+			return client.CoreV1().Services(namespace).List(options)
+		},
+		create: func(client kubernetes.Interface, data []byte) error {
+			var object corev1.Service
+			if err := json.Unmarshal(data, &object); err != nil {
+				return err
+			}
+			_, err := client.CoreV1().Services(object.Namespace).Create(&object)
+			return err
+		},
+	},
+	{
+		name: "Deployment",
+		list: func(client kubernetes.Interface, namespace string, options metav1.ListOptions) (runtime.Object, error) {
+			return client.AppsV1().Deployments(namespace).List(options)
+		},
+		create: func(client kubernetes.Interface, data []byte) error {
+			var object appsv1.Deployment
+			if err := json.Unmarshal(data, &object); err != nil {
+				return err
+			}
+			_, err := client.AppsV1().Deployments(object.Namespace).Create(&object)
+			return err
+		},
+	},
+	{
+		name: "StatefulSet",
+		list: func(client kubernetes.Interface, namespace string, options metav1.ListOptions) (runtime.Object, error) {
+			return client.AppsV1().StatefulSets(namespace).List(options)
+		},
+		create: func(client kubernetes.Interface, data []byte) error {
+			var object appsv1.StatefulSet
+			if err := json.Unmarshal(data, &object); err != nil {
+				return err
+			}
+			_, err := client.AppsV1().StatefulSets(object.Namespace).Create(&object)
+			return err
+		},
+	},
+	{
+		name: "DaemonSet",
+		list: func(client kubernetes.Interface, namespace string, options metav1.ListOptions) (runtime.Object, error) {
+			return client.AppsV1().DaemonSets(namespace).List(options)
+		},
+		create: func(client kubernetes.Interface, data []byte) error {
+			var object appsv1.DaemonSet
+			if err := json.Unmarshal(data, &object); err != nil {
+				return err
+			}
+			_, err := client.AppsV1().DaemonSets(object.Namespace).Create(&object)
+			return err
+		},
+	},
+	{
+		name: "CronJob",
+		list: func(client kubernetes.Interface, namespace string, options metav1.ListOptions) (runtime.Object, error) {
+			return client.BatchV1beta1().CronJobs(namespace).List(options)
+		},
+		create: func(client kubernetes.Interface, data []byte) error {
+			var object batchv1beta1.CronJob
+			if err := json.Unmarshal(data, &object); err != nil {
+				return err
+			}
+			_, err := client.BatchV1beta1().CronJobs(object.Namespace).Create(&object)
+			return err
+		},
+	},
+	{
+		name: "Ingress",
+		list: func(client kubernetes.Interface, namespace string, options metav1.ListOptions) (runtime.Object, error) {
+			return client.ExtensionsV1beta1().Ingresses(namespace).List(options)
+		},
+		create: func(client kubernetes.Interface, data []byte) error {
+			var object extensionsv1beta1.Ingress
+			if err := json.Unmarshal(data, &object); err != nil {
+				return err
+			}
+			_, err := client.ExtensionsV1beta1().Ingresses(object.Namespace).Create(&object)
+			return err
+		},
+	},
+}
+
+// selectNamespaces returns the backed up namespaces of a cluster.
+func selectNamespaces(namespaces []corev1.Namespace, filter pkgBackup.Filter) []corev1.Namespace {
+	included := make(map[string]bool, len(filter.Namespaces))
+	for _, namespace := range filter.Namespaces {
+		included[namespace] = true
+	}
+
+	var selected []corev1.Namespace
+	for _, namespace := range namespaces {
+		if len(included) > 0 && !included[namespace.Name] {
+			continue
+		}
+
+		if len(included) == 0 && systemNamespaces[namespace.Name] {
+			continue
+		}
+
+		selected = append(selected, namespace)
+	}
+
+	return selected
+}
+
+// collectResources lists the Kubernetes resources matching the filter.
+// The label selector does not apply to namespaces.
+func collectResources(client kubernetes.Interface, filter pkgBackup.Filter) ([]Resource, error) {
+	if _, err := labels.Parse(filter.LabelSelector); err != nil {
+		return nil, errors.Wrap(err, "invalid label selector")
+	}
+
+	namespaceList, err := client.CoreV1().Namespaces().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list namespaces")
+	}
+
+	namespaces := selectNamespaces(namespaceList.Items, filter)
+
+	var resources []Resource
+	for i := range namespaces {
+		resource, err := newResource(namespaceKindName, &namespaces[i])
+		if err != nil {
+			return nil, err
+		}
+
+		resources = append(resources, resource)
+	}
+
+	options := metav1.ListOptions{LabelSelector: filter.LabelSelector}
+
+	for _, kind := range resourceKinds {
+		if kind.name == namespaceKindName {
+			continue
+		}
+
+		for _, namespace := range namespaces {
+			list, err := kind.list(client, namespace.Name, options)
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not list %s resources in namespace %s", kind.name, namespace.Name)
+			}
+
+			objects, err := meta.ExtractList(list)
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not extract %s resources", kind.name)
+			}
+
+			for _, object := range objects {
+				if !isBackedUp(object) {
+					continue
+				}
+
+				resource, err := newResource(kind.name, object)
+				if err != nil {
+					return nil, err
+				}
+
+				resources = append(resources, resource)
+			}
+		}
+	}
+
+	return resources, nil
+}
+
+// collectTillerResources returns the Tiller ConfigMaps of the given releases,
+// restoring them makes Tiller aware of the releases on the target cluster.
+func collectTillerResources(client kubernetes.Interface, releases []Release) ([]Resource, error) {
+	if len(releases) == 0 {
+		return nil, nil
+	}
+
+	included := make(map[string]bool, len(releases))
+	for _, release := range releases {
+		included[release.Name] = true
+	}
+
+	selector := labels.Set{tillerOwnerLabel: tillerOwner}.AsSelector().String()
+
+	configMaps, err := client.CoreV1().ConfigMaps(tillerNamespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list Tiller ConfigMaps")
+	}
+
+	var resources []Resource
+	for i := range configMaps.Items {
+		if !included[configMaps.Items[i].Labels[tillerNameLabel]] {
+			continue
+		}
+
+		resource, err := newResource(configMapKindName, &configMaps.Items[i])
+		if err != nil {
+			return nil, err
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
+
+// isBackedUp filters out the objects which are recreated by Kubernetes.
+func isBackedUp(object runtime.Object) bool {
+	switch o := object.(type) {
+	case *corev1.Secret:
+		return o.Type != corev1.SecretTypeServiceAccountToken
+	}
+
+	return true
+}
+
+func newResource(kind string, object runtime.Object) (Resource, error) {
+	object = object.DeepCopyObject()
+
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return Resource{}, errors.Wrapf(err, "could not access %s metadata", kind)
+	}
+
+	cleanObject(accessor, object)
+
+	data, err := json.Marshal(object)
+	if err != nil {
+		return Resource{}, errors.Wrapf(err, "could not encode %s %s", kind, accessor.GetName())
+	}
+
+	return Resource{
+		Kind:      kind,
+		Namespace: accessor.GetNamespace(),
+		Name:      accessor.GetName(),
+		Object:    data,
+	}, nil
+}
+
+// cleanObject removes the cluster specific fields of an object, so that it can be created on any cluster.
+func cleanObject(accessor metav1.Object, object runtime.Object) {
+	accessor.SetResourceVersion("")
+	accessor.SetUID("")
+	accessor.SetSelfLink("")
+	accessor.SetCreationTimestamp(metav1.Time{})
+	accessor.SetGeneration(0)
+
+	switch o := object.(type) {
+	case *corev1.Namespace:
+		o.Status = corev1.NamespaceStatus{}
+
+	case *corev1.ServiceAccount:
+		// token secrets are recreated by the token controller
+		o.Secrets = nil
+
+	case *corev1.Service:
+		if o.Spec.ClusterIP != corev1.ClusterIPNone {
+			o.Spec.ClusterIP = ""
+		}
+		o.Status = corev1.ServiceStatus{}
+
+	case *corev1.PersistentVolumeClaim:
+		// volume data is not backed up, the claim is bound to a new volume
+		o.Spec.VolumeName = ""
+		delete(o.Annotations, "pv.kubernetes.io/bind-completed")
+		delete(o.Annotations, "pv.kubernetes.io/bound-by-controller")
+		o.Status = corev1.PersistentVolumeClaimStatus{}
+
+	case *appsv1.Deployment:
+		o.Status = appsv1.DeploymentStatus{}
+
+	case *appsv1.StatefulSet:
+		o.Status = appsv1.StatefulSetStatus{}
+
+	case *appsv1.DaemonSet:
+		o.Status = appsv1.DaemonSetStatus{}
+
+	case *batchv1beta1.CronJob:
+		o.Status = batchv1beta1.CronJobStatus{}
+
+	case *extensionsv1beta1.Ingress:
+		o.Status = extensionsv1beta1.IngressStatus{}
+	}
+}
+
+// restoreResources creates the resources of an archive in dependency order, existing resources are skipped.
+func restoreResources(client kubernetes.Interface, resources []Resource) pkgBackup.RestoreResponse {
+	order := make(map[string]int, len(resourceKinds))
+	kinds := make(map[string]resourceKind, len(resourceKinds))
+	for i, kind := range resourceKinds {
+		order[kind.name] = i
+		kinds[kind.name] = kind
+	}
+
+	resources = append([]Resource(nil), resources...)
+	sort.SliceStable(resources, func(i, j int) bool {
+		return order[resources[i].Kind] < order[resources[j].Kind]
+	})
+
+	var response pkgBackup.RestoreResponse
+	for _, resource := range resources {
+		kind, ok := kinds[resource.Kind]
+		if !ok {
+			response.Errors = append(response.Errors, fmt.Sprintf("unsupported resource kind: %s", resource.Kind))
+			continue
+		}
+
+		err := kind.create(client, resource.Object)
+		if k8sErrors.IsAlreadyExists(err) {
+			response.Skipped++
+		} else if err != nil {
+			response.Errors = append(
+				response.Errors,
+				fmt.Sprintf("could not create %s %s/%s: %s", resource.Kind, resource.Namespace, resource.Name, err.Error()),
+			)
+		} else {
+			response.Created++
+		}
+	}
+
+	return response
+}
@@ -0,0 +1,166 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/sirupsen/logrus"
+)
+
+// scheduleRetryDelay is the delay before retrying a failed scheduled backup
+const scheduleRetryDelay = 15 * time.Minute
+
+// Scheduler backs up clusters when their backup schedule makes them due and deletes the expired backups.
+type Scheduler struct {
+	manager       *Manager
+	checkInterval time.Duration
+	ticker        *time.Ticker
+
+	logger       logrus.FieldLogger
+	errorHandler emperror.Handler
+}
+
+// NewScheduler returns a new backup scheduler.
+func NewScheduler(manager *Manager, checkInterval time.Duration, logger logrus.FieldLogger, errorHandler emperror.Handler) *Scheduler {
+	return &Scheduler{
+		manager:       manager,
+		checkInterval: checkInterval,
+		logger:        logger,
+		errorHandler:  errorHandler,
+	}
+}
+
+// Start flags the backups interrupted by a restart as failed and starts checking for due backups in the background.
+func (s *Scheduler) Start() {
+	if err := s.manager.backups.FailRunning(); err != nil {
+		s.errorHandler.Handle(err)
+	}
+
+	s.ticker = time.NewTicker(s.checkInterval)
+
+	go func() {
+		for range s.ticker.C {
+			s.backupDueClusters()
+		}
+	}()
+}
+
+// Stop stops the scheduler.
+func (s *Scheduler) Stop() {
+	s.ticker.Stop()
+}
+
+func (s *Scheduler) backupDueClusters() {
+	schedules, err := s.manager.schedules.FindDue(time.Now())
+	if err != nil {
+		s.errorHandler.Handle(err)
+		return
+	}
+
+	for _, schedule := range schedules {
+		s.backup(schedule)
+	}
+}
+
+func (s *Scheduler) backup(schedule *ScheduleModel) {
+	logger := s.logger.WithFields(logrus.Fields{
+		"organization": schedule.OrganizationID,
+		"cluster":      schedule.ClusterID,
+	})
+
+	cluster, err := s.manager.getCluster(context.Background(), schedule.OrganizationID, schedule.ClusterID)
+	if isNotFound(err) {
+		logger.Info("cluster does not exist anymore, deleting its backup schedule")
+
+		if err := s.manager.schedules.DeleteByCluster(schedule.OrganizationID, schedule.ClusterID); err != nil {
+			s.errorHandler.Handle(err)
+		}
+
+		return
+	}
+
+	now := time.Now()
+
+	if err == nil {
+		err = s.runBackup(cluster, schedule)
+	}
+
+	if err != nil {
+		s.errorHandler.Handle(emperror.With(err, "organization", schedule.OrganizationID, "cluster", schedule.ClusterID))
+
+		schedule.LastError = err.Error()
+		schedule.NextBackupAt = now.Add(scheduleRetryDelay)
+	} else {
+		schedule.LastError = ""
+		schedule.LastBackupAt = &now
+		schedule.NextBackupAt = now.Add(schedule.Interval)
+	}
+
+	if err := s.manager.schedules.Save(schedule); err != nil {
+		s.errorHandler.Handle(err)
+		return
+	}
+
+	if err == nil {
+		s.deleteExpiredBackups(schedule)
+	}
+}
+
+func (s *Scheduler) runBackup(cluster Cluster, schedule *ScheduleModel) error {
+	bucket, client, err := s.manager.getObjectClient(schedule.OrganizationID, schedule.bucket())
+	if err != nil {
+		return err
+	}
+
+	backup := newBackupModel(cluster, bucket, schedule.filter(), schedule.ID)
+	if err := s.manager.backups.Save(backup); err != nil {
+		return err
+	}
+
+	return s.manager.runBackup(cluster, client, backup)
+}
+
+// deleteExpiredBackups deletes the completed scheduled backups exceeding the retention of the schedule.
+func (s *Scheduler) deleteExpiredBackups(schedule *ScheduleModel) {
+	backups, err := s.manager.backups.FindCompletedBySchedule(schedule.ID)
+	if err != nil {
+		s.errorHandler.Handle(err)
+		return
+	}
+
+	for _, backup := range expiredBackups(backups, schedule.Retention) {
+		s.logger.WithFields(logrus.Fields{
+			"organization": backup.OrganizationID,
+			"cluster":      backup.ClusterID,
+			"backup":       backup.Name,
+		}).Info("deleting expired backup")
+
+		if err := s.manager.deleteBackup(backup); err != nil {
+			s.errorHandler.Handle(err)
+		}
+	}
+}
+
+// expiredBackups returns the backups beyond the retention, backups are ordered from the newest to the oldest.
+func expiredBackups(backups []*BackupModel, retention int) []*BackupModel {
+	if retention < 1 || len(backups) <= retention {
+		return nil
+	}
+
+	return backups[retention:]
+}
@@ -0,0 +1,47 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+)
+
+const (
+	customPostHooksTableName = "custom_posthooks"
+)
+
+// CustomPostHookModel is an organization defined posthook installing a Helm chart.
+type CustomPostHookModel struct {
+	ID uint `gorm:"primary_key"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	CreatedBy uint
+
+	OrganizationID uint   `gorm:"unique_index:idx_custom_posthook_org_name"`
+	Name           string `gorm:"unique_index:idx_custom_posthook_org_name"`
+
+	Chart        string
+	ChartVersion string
+	ReleaseName  string
+	Namespace    string
+	Values       string `sql:"type:text;"`
+	Secrets      string `sql:"type:text;"`
+}
+
+// TableName changes the default table name.
+func (CustomPostHookModel) TableName() string {
+	return customPostHooksTableName
+}
@@ -0,0 +1,135 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+type CustomPostHooks struct {
+	db *gorm.DB
+}
+
+func NewCustomPostHooks(db *gorm.DB) *CustomPostHooks {
+	return &CustomPostHooks{db: db}
+}
+
+func (p *CustomPostHooks) FindAll(organizationID uint) ([]*CustomPostHookModel, error) {
+	var postHooks []*CustomPostHookModel
+
+	err := p.db.Order("name").Find(&postHooks, map[string]interface{}{"organization_id": organizationID}).Error
+	if err != nil {
+		return nil, emperror.With(
+			errors.Wrap(err, "could not fetch custom posthooks"),
+			"organization", organizationID,
+		)
+	}
+
+	return postHooks, nil
+}
+
+type customPostHookNotFoundError struct {
+	organizationID uint
+	name           string
+}
+
+func (e *customPostHookNotFoundError) Error() string {
+	return "custom posthook not found"
+}
+
+func (e *customPostHookNotFoundError) Context() []interface{} {
+	return []interface{}{
+		"organization", e.organizationID,
+		"posthook", e.name,
+	}
+}
+
+func (e *customPostHookNotFoundError) NotFound() bool {
+	return true
+}
+
+func (p *CustomPostHooks) FindOne(organizationID uint, name string) (*CustomPostHookModel, error) {
+	var postHook CustomPostHookModel
+
+	err := p.db.First(
+		&postHook,
+		map[string]interface{}{
+			"organization_id": organizationID,
+			"name":            name,
+		},
+	).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, errors.WithStack(&customPostHookNotFoundError{
+			organizationID: organizationID,
+			name:           name,
+		})
+	} else if err != nil {
+		return nil, emperror.With(
+			errors.Wrap(err, "could not get custom posthook"),
+			"organization", organizationID,
+			"posthook", name,
+		)
+	}
+
+	return &postHook, nil
+}
+
+func (p *CustomPostHooks) Exists(organizationID uint, name string) (bool, error) {
+	var count int
+
+	err := p.db.Model(&CustomPostHookModel{}).Where(
+		map[string]interface{}{
+			"organization_id": organizationID,
+			"name":            name,
+		},
+	).Count(&count).Error
+	if err != nil {
+		return false, emperror.With(
+			errors.Wrap(err, "could not check custom posthook existence"),
+			"organization", organizationID,
+			"posthook", name,
+		)
+	}
+
+	return count > 0, nil
+}
+
+func (p *CustomPostHooks) Save(postHook *CustomPostHookModel) error {
+	err := p.db.Save(postHook).Error
+	if err != nil {
+		return emperror.With(
+			errors.Wrap(err, "could not save custom posthook"),
+			"organization", postHook.OrganizationID,
+			"posthook", postHook.Name,
+		)
+	}
+
+	return nil
+}
+
+func (p *CustomPostHooks) Delete(postHook *CustomPostHookModel) error {
+	err := p.db.Delete(postHook).Error
+	if err != nil {
+		return emperror.With(
+			errors.Wrap(err, "could not delete custom posthook"),
+			"organization", postHook.OrganizationID,
+			"posthook", postHook.Name,
+		)
+	}
+
+	return nil
+}
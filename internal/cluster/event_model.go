@@ -0,0 +1,53 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+)
+
+const (
+	eventsTableName = "cluster_events"
+)
+
+// Event types
+const (
+	EventCreateRequested  = "CREATE_REQUESTED"
+	EventUpdateRequested  = "UPDATE_REQUESTED"
+	EventDeleteRequested  = "DELETE_REQUESTED"
+	EventStatusChanged    = "STATUS_CHANGED"
+	EventPostHookStarted  = "POSTHOOK_STARTED"
+	EventPostHookFinished = "POSTHOOK_FINISHED"
+	EventPostHookFailed   = "POSTHOOK_FAILED"
+)
+
+// EventModel is an entry of the append-only lifecycle event history of a cluster.
+type EventModel struct {
+	ID uint `gorm:"primary_key"`
+
+	CreatedAt time.Time `gorm:"index"`
+
+	OrganizationID uint `gorm:"index:idx_event_cluster"`
+	ClusterID      uint `gorm:"index:idx_event_cluster"`
+	UserID         uint
+
+	Type    string
+	Status  string
+	Message string `sql:"type:text;"`
+}
+
+func (EventModel) TableName() string {
+	return eventsTableName
+}
@@ -0,0 +1,87 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// EventQuery filters and pages the event history of a cluster.
+type EventQuery struct {
+	From   *time.Time
+	To     *time.Time
+	Limit  int
+	Offset int
+}
+
+type Events struct {
+	db *gorm.DB
+}
+
+func NewEvents(db *gorm.DB) *Events {
+	return &Events{db: db}
+}
+
+// Record appends an event to the history of a cluster.
+func (e *Events) Record(event *EventModel) error {
+	err := e.db.Create(event).Error
+	if err != nil {
+		return emperror.With(
+			errors.Wrap(err, "could not record cluster event"),
+			"cluster", event.ClusterID,
+			"event", event.Type,
+		)
+	}
+
+	return nil
+}
+
+// FindByCluster returns the events of a cluster in chronological order.
+func (e *Events) FindByCluster(organizationID uint, clusterID uint, query EventQuery) ([]*EventModel, error) {
+	var events []*EventModel
+
+	db := e.db.Where(&EventModel{OrganizationID: organizationID, ClusterID: clusterID})
+
+	if query.From != nil {
+		db = db.Where("created_at >= ?", *query.From)
+	}
+
+	if query.To != nil {
+		db = db.Where("created_at < ?", *query.To)
+	}
+
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+
+	if query.Offset > 0 {
+		db = db.Offset(query.Offset)
+	}
+
+	err := db.Order("created_at, id").Find(&events).Error
+	if err != nil {
+		return nil, emperror.With(
+			errors.Wrap(err, "could not fetch cluster events"),
+			"cluster", clusterID,
+			"organization", organizationID,
+		)
+	}
+
+	return events, nil
+}
@@ -25,6 +25,11 @@ import (
 func Migrate(db *gorm.DB, logger logrus.FieldLogger) error {
 	tables := []interface{}{
 		&ClusterModel{},
+		&WorkflowModel{},
+		&EventModel{},
+		&PostHookModel{},
+		&CustomPostHookModel{},
+		&SecretInstallationModel{},
 	}
 
 	var tableNames string
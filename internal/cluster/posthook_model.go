@@ -0,0 +1,56 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+)
+
+const (
+	postHooksTableName = "cluster_posthooks"
+)
+
+// Posthook statuses
+const (
+	PostHookPending   = "PENDING"
+	PostHookRunning   = "RUNNING"
+	PostHookSucceeded = "SUCCEEDED"
+	PostHookFailed    = "FAILED"
+)
+
+// PostHookModel is the persisted outcome of a posthook function run on a cluster.
+type PostHookModel struct {
+	ID uint `gorm:"primary_key"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	OrganizationID uint
+	ClusterID      uint   `gorm:"unique_index:idx_posthook_cluster_name"`
+	Name           string `gorm:"unique_index:idx_posthook_cluster_name"`
+	Position       int
+	Params         string `sql:"type:text;"`
+
+	Status       string
+	Attempts     int
+	StartedAt    *time.Time
+	FinishedAt   *time.Time
+	Duration     time.Duration
+	ErrorMessage string `sql:"type:text;"`
+}
+
+func (PostHookModel) TableName() string {
+	return postHooksTableName
+}
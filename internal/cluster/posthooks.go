@@ -0,0 +1,118 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+type PostHooks struct {
+	db *gorm.DB
+}
+
+func NewPostHooks(db *gorm.DB) *PostHooks {
+	return &PostHooks{db: db}
+}
+
+// FindByCluster returns the posthooks of a cluster in their original order.
+func (p *PostHooks) FindByCluster(organizationID uint, clusterID uint) ([]*PostHookModel, error) {
+	var postHooks []*PostHookModel
+
+	err := p.db.Order("position").Find(
+		&postHooks,
+		map[string]interface{}{
+			"organization_id": organizationID,
+			"cluster_id":      clusterID,
+		},
+	).Error
+	if err != nil {
+		return nil, emperror.With(
+			errors.Wrap(err, "could not fetch posthooks"),
+			"cluster", clusterID,
+			"organization", organizationID,
+		)
+	}
+
+	return postHooks, nil
+}
+
+// FindOne returns a posthook of a cluster by name, or nil if it has not been registered yet.
+func (p *PostHooks) FindOne(organizationID uint, clusterID uint, name string) (*PostHookModel, error) {
+	var postHook PostHookModel
+
+	err := p.db.First(
+		&postHook,
+		map[string]interface{}{
+			"organization_id": organizationID,
+			"cluster_id":      clusterID,
+			"name":            name,
+		},
+	).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, emperror.With(
+			errors.Wrap(err, "could not get posthook"),
+			"cluster", clusterID,
+			"organization", organizationID,
+			"posthook", name,
+		)
+	}
+
+	return &postHook, nil
+}
+
+func (p *PostHooks) Save(postHook *PostHookModel) error {
+	err := p.db.Save(postHook).Error
+	if err != nil {
+		return emperror.With(
+			errors.Wrap(err, "could not save posthook"),
+			"cluster", postHook.ClusterID,
+			"posthook", postHook.Name,
+		)
+	}
+
+	return nil
+}
+
+// SwapStatus changes the status of a posthook only if it has not been changed since the given status.
+// It returns whether the status was changed.
+func (p *PostHooks) SwapStatus(postHook *PostHookModel, from string, to string) (bool, error) {
+	result := p.db.Model(&PostHookModel{}).Where("id = ? AND status = ?", postHook.ID, from).Update("status", to)
+	if result.Error != nil {
+		return false, emperror.With(
+			errors.Wrap(result.Error, "could not update posthook status"),
+			"cluster", postHook.ClusterID,
+			"posthook", postHook.Name,
+		)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// FailRunning flags the posthooks interrupted by a restart as failed, so that they can be retried.
+func (p *PostHooks) FailRunning() error {
+	err := p.db.Model(&PostHookModel{}).Where("status = ?", PostHookRunning).Updates(map[string]interface{}{
+		"status":        PostHookFailed,
+		"error_message": "posthook was interrupted",
+		"finished_at":   time.Now(),
+	}).Error
+
+	return errors.Wrap(err, "could not fail interrupted posthooks")
+}
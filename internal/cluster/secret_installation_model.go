@@ -0,0 +1,41 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+)
+
+const (
+	secretInstallationsTableName = "cluster_secret_installations"
+)
+
+// SecretInstallationModel records an organization secret installed into a namespace of a cluster.
+type SecretInstallationModel struct {
+	ID uint `gorm:"primary_key"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	OrganizationID uint   `gorm:"index:idx_secret_installation_org_secret"`
+	SecretID       string `gorm:"index:idx_secret_installation_org_secret;unique_index:idx_secret_installation_cluster_namespace_secret"`
+	ClusterID      uint   `gorm:"unique_index:idx_secret_installation_cluster_namespace_secret"`
+	Namespace      string `gorm:"unique_index:idx_secret_installation_cluster_namespace_secret"`
+	Name           string
+}
+
+func (SecretInstallationModel) TableName() string {
+	return secretInstallationsTableName
+}
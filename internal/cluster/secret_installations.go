@@ -0,0 +1,134 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+type SecretInstallations struct {
+	db *gorm.DB
+}
+
+func NewSecretInstallations(db *gorm.DB) *SecretInstallations {
+	return &SecretInstallations{db: db}
+}
+
+// FindBySecret returns the installations of an organization secret.
+func (s *SecretInstallations) FindBySecret(organizationID uint, secretID string) ([]*SecretInstallationModel, error) {
+	var installations []*SecretInstallationModel
+
+	err := s.db.Order("cluster_id, namespace").Find(
+		&installations,
+		map[string]interface{}{
+			"organization_id": organizationID,
+			"secret_id":       secretID,
+		},
+	).Error
+	if err != nil {
+		return nil, emperror.With(
+			errors.Wrap(err, "could not fetch secret installations"),
+			"organization", organizationID,
+			"secret", secretID,
+		)
+	}
+
+	return installations, nil
+}
+
+// FindAll returns all installation records.
+func (s *SecretInstallations) FindAll() ([]*SecretInstallationModel, error) {
+	var installations []*SecretInstallationModel
+
+	err := s.db.Order("organization_id, secret_id").Find(&installations).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "could not fetch secret installations")
+	}
+
+	return installations, nil
+}
+
+// FindByCluster returns the secret installations of a cluster.
+func (s *SecretInstallations) FindByCluster(organizationID uint, clusterID uint) ([]*SecretInstallationModel, error) {
+	var installations []*SecretInstallationModel
+
+	err := s.db.Order("namespace, name").Find(
+		&installations,
+		map[string]interface{}{
+			"organization_id": organizationID,
+			"cluster_id":      clusterID,
+		},
+	).Error
+	if err != nil {
+		return nil, emperror.With(
+			errors.Wrap(err, "could not fetch secret installations"),
+			"organization", organizationID,
+			"cluster", clusterID,
+		)
+	}
+
+	return installations, nil
+}
+
+// Save records an installation, updating the existing record of the same secret in the same namespace of the cluster.
+func (s *SecretInstallations) Save(installation *SecretInstallationModel) error {
+	err := s.db.Where(SecretInstallationModel{
+		ClusterID: installation.ClusterID,
+		Namespace: installation.Namespace,
+		SecretID:  installation.SecretID,
+	}).Assign(SecretInstallationModel{
+		OrganizationID: installation.OrganizationID,
+		Name:           installation.Name,
+	}).FirstOrCreate(installation).Error
+	if err != nil {
+		return emperror.With(
+			errors.Wrap(err, "could not save secret installation"),
+			"cluster", installation.ClusterID,
+			"secret", installation.SecretID,
+		)
+	}
+
+	return nil
+}
+
+// Delete deletes an installation record.
+func (s *SecretInstallations) Delete(installation *SecretInstallationModel) error {
+	err := s.db.Delete(installation).Error
+	if err != nil {
+		return emperror.With(
+			errors.Wrap(err, "could not delete secret installation"),
+			"cluster", installation.ClusterID,
+			"secret", installation.SecretID,
+		)
+	}
+
+	return nil
+}
+
+// DeleteByCluster deletes the installation records of a cluster.
+func (s *SecretInstallations) DeleteByCluster(organizationID uint, clusterID uint) error {
+	err := s.db.Where("organization_id = ? AND cluster_id = ?", organizationID, clusterID).Delete(&SecretInstallationModel{}).Error
+	if err != nil {
+		return emperror.With(
+			errors.Wrap(err, "could not delete secret installations"),
+			"organization", organizationID,
+			"cluster", clusterID,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+)
+
+const (
+	workflowsTableName = "cluster_workflows"
+)
+
+// Workflow types
+const (
+	WorkflowCreate = "CREATE"
+	WorkflowUpdate = "UPDATE"
+	WorkflowDelete = "DELETE"
+)
+
+// Workflow statuses
+const (
+	WorkflowRunning  = "RUNNING"
+	WorkflowFinished = "FINISHED"
+	WorkflowFailed   = "FAILED"
+)
+
+// WorkflowModel is the durable record of a cluster lifecycle operation.
+// Steps are executed in order, FinishedSteps points to the first step that has not finished yet.
+type WorkflowModel struct {
+	ID uint `gorm:"primary_key"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	OrganizationID uint `gorm:"index:idx_workflow_cluster"`
+	ClusterID      uint `gorm:"index:idx_workflow_cluster"`
+	ClusterName    string
+	UserID         uint
+
+	Type          string
+	Status        string `gorm:"index"`
+	CurrentStep   string
+	FinishedSteps int
+	Steps         string `sql:"type:text;"`
+	Data          string `sql:"type:text;"`
+	ErrorMessage  string `sql:"type:text;"`
+}
+
+func (WorkflowModel) TableName() string {
+	return workflowsTableName
+}
@@ -0,0 +1,99 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+type Workflows struct {
+	db *gorm.DB
+}
+
+func NewWorkflows(db *gorm.DB) *Workflows {
+	return &Workflows{db: db}
+}
+
+func (w *Workflows) Save(workflow *WorkflowModel) error {
+	err := w.db.Save(workflow).Error
+	if err != nil {
+		return emperror.With(
+			errors.Wrap(err, "could not save workflow"),
+			"cluster", workflow.ClusterID,
+			"workflow", workflow.Type,
+		)
+	}
+
+	return nil
+}
+
+func (w *Workflows) FindRunning() ([]*WorkflowModel, error) {
+	var workflows []*WorkflowModel
+
+	err := w.db.Order("id").Find(&workflows, map[string]interface{}{"status": WorkflowRunning}).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "could not fetch running workflows")
+	}
+
+	return workflows, nil
+}
+
+type workflowNotFoundError struct {
+	clusterID      uint
+	organizationID uint
+}
+
+func (e *workflowNotFoundError) Error() string {
+	return "workflow not found"
+}
+
+func (e *workflowNotFoundError) Context() []interface{} {
+	return []interface{}{
+		"cluster", e.clusterID,
+		"organization", e.organizationID,
+	}
+}
+
+func (e *workflowNotFoundError) NotFound() bool {
+	return true
+}
+
+func (w *Workflows) FindLastByCluster(organizationID uint, clusterID uint) (*WorkflowModel, error) {
+	var workflow WorkflowModel
+
+	err := w.db.Order("id desc").First(
+		&workflow,
+		map[string]interface{}{
+			"organization_id": organizationID,
+			"cluster_id":      clusterID,
+		},
+	).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, errors.WithStack(&workflowNotFoundError{
+			clusterID:      clusterID,
+			organizationID: organizationID,
+		})
+	} else if err != nil {
+		return nil, emperror.With(
+			errors.Wrap(err, "could not get workflow"),
+			"cluster", clusterID,
+			"organization", organizationID,
+		)
+	}
+
+	return &workflow, nil
+}
@@ -0,0 +1,97 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objectstore
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/banzaicloud/pipeline/pkg/objectstore"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+)
+
+// ObjectQuery filters and pages the objects of a bucket.
+// Keys containing the delimiter after the prefix are rolled up into common prefixes, like directories.
+// Objects and common prefixes are returned in lexicographical order, Marker is the last entry of the previous page.
+type ObjectQuery struct {
+	Prefix    string
+	Delimiter string
+	Marker    string
+	Limit     int
+}
+
+// ObjectList is a page of the objects and common prefixes of a bucket.
+type ObjectList struct {
+	Objects        []string `json:"objects"`
+	CommonPrefixes []string `json:"commonPrefixes"`
+	NextMarker     string   `json:"nextMarker,omitempty"`
+}
+
+// ListObjects returns a page of the objects of a bucket matching the query.
+func ListObjects(store objectstore.ObjectStore, bucketName string, query ObjectQuery) (*ObjectList, error) {
+	keys, err := store.ListObjectsWithPrefix(bucketName, query.Prefix)
+	if err != nil {
+		return nil, emperror.With(errors.Wrap(err, "could not list objects"), "bucket", bucketName, "prefix", query.Prefix)
+	}
+
+	return newObjectList(keys, query), nil
+}
+
+func newObjectList(keys []string, query ObjectQuery) *ObjectList {
+	sort.Strings(keys)
+
+	list := &ObjectList{
+		Objects:        []string{},
+		CommonPrefixes: []string{},
+	}
+
+	var count int
+	var last string
+
+	for _, key := range keys {
+		if !strings.HasPrefix(key, query.Prefix) {
+			continue
+		}
+
+		name, isPrefix := key, false
+		if query.Delimiter != "" {
+			if i := strings.Index(key[len(query.Prefix):], query.Delimiter); i >= 0 {
+				name, isPrefix = key[:len(query.Prefix)+i+len(query.Delimiter)], true
+			}
+		}
+
+		// Keys sharing a common prefix are next to each other in the sorted list
+		if name == last || (query.Marker != "" && name <= query.Marker) {
+			continue
+		}
+
+		if query.Limit > 0 && count == query.Limit {
+			list.NextMarker = last
+			break
+		}
+
+		if isPrefix {
+			list.CommonPrefixes = append(list.CommonPrefixes, name)
+		} else {
+			list.Objects = append(list.Objects, name)
+		}
+
+		count++
+		last = name
+	}
+
+	return list
+}
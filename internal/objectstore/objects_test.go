@@ -0,0 +1,89 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objectstore
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewObjectList(t *testing.T) {
+	keys := []string{
+		"logs/2018/10/01.log",
+		"backups/cluster-1/resources.tgz",
+		"logs/2018/09/30.log",
+		"logs/2018/10/02.log",
+		"logs/README",
+		"logs-archive.tgz",
+		"backups/cluster-2/resources.tgz",
+	}
+
+	cases := []struct {
+		name     string
+		query    ObjectQuery
+		expected ObjectList
+	}{
+		{
+			name:  "flat",
+			query: ObjectQuery{Prefix: "logs/2018/10/"},
+			expected: ObjectList{
+				Objects:        []string{"logs/2018/10/01.log", "logs/2018/10/02.log"},
+				CommonPrefixes: []string{},
+			},
+		},
+		{
+			name:  "delimiter",
+			query: ObjectQuery{Delimiter: "/"},
+			expected: ObjectList{
+				Objects:        []string{"logs-archive.tgz"},
+				CommonPrefixes: []string{"backups/", "logs/"},
+			},
+		},
+		{
+			name:  "prefix and delimiter",
+			query: ObjectQuery{Prefix: "logs/", Delimiter: "/"},
+			expected: ObjectList{
+				Objects:        []string{"logs/README"},
+				CommonPrefixes: []string{"logs/2018/"},
+			},
+		},
+		{
+			name:  "first page",
+			query: ObjectQuery{Delimiter: "/", Limit: 2},
+			expected: ObjectList{
+				Objects:        []string{"logs-archive.tgz"},
+				CommonPrefixes: []string{"backups/"},
+				NextMarker:     "logs-archive.tgz",
+			},
+		},
+		{
+			name:  "last page",
+			query: ObjectQuery{Delimiter: "/", Marker: "logs-archive.tgz", Limit: 2},
+			expected: ObjectList{
+				Objects:        []string{},
+				CommonPrefixes: []string{"logs/"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			list := newObjectList(append([]string{}, keys...), tc.query)
+			if !reflect.DeepEqual(*list, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, *list)
+			}
+		})
+	}
+}
@@ -17,10 +17,42 @@ package objectstore
 // ObjectStoreService is the interface that cloud specific object store implementation
 // must implement
 type ObjectStoreService interface {
-	CreateBucket(string) error
+	CreateBucket(string, BucketSettings) error
 	ListBuckets() ([]*BucketInfo, error)
 	DeleteBucket(string) error
 	CheckBucket(string) error
+
+	// UpdateBucketSettings applies the settings to a bucket managed by Pipeline
+	UpdateBucketSettings(string, BucketSettings) error
+
+	// ImportBucket registers an existing bucket as managed by Pipeline after checking it can be accessed
+	ImportBucket(string) error
+
+	// ForgetBucket removes a bucket from the ones managed by Pipeline without deleting it
+	ForgetBucket(string) error
+}
+
+// Managed bucket statuses
+const (
+	// BucketAvailable is the status of managed buckets which exist in the cloud account
+	BucketAvailable = "AVAILABLE"
+
+	// BucketMissing is the status of managed buckets which were deleted out-of-band
+	BucketMissing = "MISSING"
+)
+
+// ManagedBucket describes a bucket managed by Pipeline independently from the cloud provider.
+type ManagedBucket struct {
+	ID             uint
+	OrganizationID uint
+	SecretID       string
+	Name           string
+	Location       string
+	Status         string
+
+	// Azure specific parameters
+	ResourceGroup  string
+	StorageAccount string
 }
 
 // BucketInfo desribes a storage bucket
@@ -29,6 +61,12 @@ type BucketInfo struct {
 	Managed  bool                      `json:"managed" binding:"required"`
 	Location string                    `json:"location,omitempty"`
 	Azure    *BlobStoragePropsForAzure `json:"aks,omitempty"`
+
+	// Settings of buckets managed by Pipeline
+	Settings *BucketSettings `json:"settings,omitempty"`
+
+	// Status of buckets managed by Pipeline
+	Status string `json:"status,omitempty"`
 }
 
 // BlobStoragePropsForAzure describes the Azure specific properties
@@ -0,0 +1,144 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objectstore
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// BucketSettings describes the provider neutral settings of a bucket.
+type BucketSettings struct {
+	// Versioning keeps the previous versions of overwritten and deleted objects
+	Versioning bool `json:"versioning"`
+
+	// Encryption enables default server-side encryption, providers encrypting every object anyway ignore it
+	// unless a customer managed key is set
+	Encryption *BucketEncryption `json:"encryption,omitempty"`
+
+	// LifecycleRules expire objects or transition them to another storage class
+	LifecycleRules []BucketLifecycleRule `json:"lifecycleRules,omitempty"`
+
+	// Labels are set as tags, labels or metadata of the bucket depending on the provider
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// BucketEncryption describes the server-side encryption of the objects of a bucket.
+type BucketEncryption struct {
+	// KeyID is a customer managed key (AWS KMS key ID or ARN, Google Cloud KMS key resource name),
+	// objects are encrypted with provider managed keys if it is empty
+	KeyID string `json:"keyId,omitempty"`
+}
+
+// BucketLifecycleRule expires the objects having the given key prefix or transitions them to another storage class
+// the given number of days after their creation.
+type BucketLifecycleRule struct {
+	ID             string `json:"id" binding:"required"`
+	Prefix         string `json:"prefix,omitempty"`
+	ExpirationDays int    `json:"expirationDays,omitempty"`
+	TransitionDays int    `json:"transitionDays,omitempty"`
+	StorageClass   string `json:"storageClass,omitempty"`
+}
+
+// Validate checks the provider neutral constraints of the settings.
+func (s BucketSettings) Validate() error {
+	ids := make(map[string]bool, len(s.LifecycleRules))
+
+	for _, rule := range s.LifecycleRules {
+		if rule.ID == "" {
+			return errors.New("lifecycle rule id is required")
+		}
+
+		if ids[rule.ID] {
+			return errors.Errorf("duplicate lifecycle rule id: %s", rule.ID)
+		}
+		ids[rule.ID] = true
+
+		if rule.ExpirationDays < 0 || rule.TransitionDays < 0 {
+			return errors.Errorf("lifecycle rule %s: days must not be negative", rule.ID)
+		}
+
+		if rule.ExpirationDays == 0 && rule.TransitionDays == 0 {
+			return errors.Errorf("lifecycle rule %s: either expirationDays or transitionDays is required", rule.ID)
+		}
+
+		if (rule.TransitionDays > 0) != (rule.StorageClass != "") {
+			return errors.Errorf("lifecycle rule %s: transitionDays and storageClass must be set together", rule.ID)
+		}
+
+		if rule.TransitionDays > 0 && rule.ExpirationDays > 0 && rule.ExpirationDays <= rule.TransitionDays {
+			return errors.Errorf("lifecycle rule %s: expirationDays must be greater than transitionDays", rule.ID)
+		}
+	}
+
+	for key := range s.Labels {
+		if key == "" {
+			return errors.New("label keys must not be empty")
+		}
+	}
+
+	return nil
+}
+
+// Scan implements the sql.Scanner interface, settings are stored as JSON.
+func (s *BucketSettings) Scan(value interface{}) error {
+	*s = BucketSettings{}
+
+	switch v := value.(type) {
+	case nil:
+		return nil
+
+	case []byte:
+		if len(v) == 0 {
+			return nil
+		}
+
+		return errors.Wrap(json.Unmarshal(v, s), "could not unmarshal bucket settings")
+
+	case string:
+		if v == "" {
+			return nil
+		}
+
+		return errors.Wrap(json.Unmarshal([]byte(v), s), "could not unmarshal bucket settings")
+
+	default:
+		return errors.Errorf("unsupported bucket settings type: %T", value)
+	}
+}
+
+// Value implements the driver.Valuer interface, settings are stored as JSON.
+func (s BucketSettings) Value() (driver.Value, error) {
+	value, err := json.Marshal(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal bucket settings")
+	}
+
+	return string(value), nil
+}
+
+// UnsupportedSettingError is returned when a bucket setting is not supported by the provider.
+type UnsupportedSettingError struct {
+	Provider string
+	Setting  string
+}
+
+// Error implements the error interface.
+func (e UnsupportedSettingError) Error() string {
+	return fmt.Sprintf("%s is not supported by %s object storage", e.Setting, e.Provider)
+}
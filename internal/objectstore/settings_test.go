@@ -0,0 +1,91 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objectstore
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBucketSettingsValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		rules   []BucketLifecycleRule
+		isValid bool
+	}{
+		{
+			name: "valid rules",
+			rules: []BucketLifecycleRule{
+				{ID: "expire", Prefix: "logs/", ExpirationDays: 30},
+				{ID: "archive", TransitionDays: 30, StorageClass: "GLACIER", ExpirationDays: 365},
+			},
+			isValid: true,
+		},
+		{
+			name:  "duplicate id",
+			rules: []BucketLifecycleRule{{ID: "expire", ExpirationDays: 30}, {ID: "expire", ExpirationDays: 60}},
+		},
+		{
+			name:  "no action",
+			rules: []BucketLifecycleRule{{ID: "noop", Prefix: "logs/"}},
+		},
+		{
+			name:  "transition without storage class",
+			rules: []BucketLifecycleRule{{ID: "archive", TransitionDays: 30}},
+		},
+		{
+			name:  "expiration before transition",
+			rules: []BucketLifecycleRule{{ID: "archive", TransitionDays: 30, StorageClass: "GLACIER", ExpirationDays: 10}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := BucketSettings{LifecycleRules: tc.rules}.Validate()
+			if tc.isValid && err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			} else if !tc.isValid && err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestBucketSettingsScanValue(t *testing.T) {
+	settings := BucketSettings{
+		Versioning:     true,
+		Encryption:     &BucketEncryption{KeyID: "alias/bucket"},
+		LifecycleRules: []BucketLifecycleRule{{ID: "expire", ExpirationDays: 30}},
+		Labels:         map[string]string{"team": "platform"},
+	}
+
+	value, err := settings.Value()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	var scanned BucketSettings
+	if err := scanned.Scan(value); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if !reflect.DeepEqual(scanned, settings) {
+		t.Errorf("expected %+v, got %+v", settings, scanned)
+	}
+
+	if err := scanned.Scan(nil); err != nil || !reflect.DeepEqual(scanned, BucketSettings{}) {
+		t.Errorf("expected empty settings, got %+v (%v)", scanned, err)
+	}
+}
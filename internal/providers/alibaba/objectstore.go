@@ -15,6 +15,7 @@
 package alibaba
 
 import (
+	"reflect"
 	"sort"
 	"strings"
 
@@ -43,7 +44,35 @@ func NewObjectStore(region string, secret *secret.SecretItemResponse, org *auth.
 	}
 }
 
-func (b *AlibabaObjectStore) CreateBucket(bucketName string) error {
+// ValidateBucketSettings checks if the settings are supported by Alibaba OSS.
+// Only expiration lifecycle rules can be set on OSS buckets.
+func ValidateBucketSettings(settings objectstore.BucketSettings) error {
+	if settings.Versioning {
+		return objectstore.UnsupportedSettingError{Provider: "Alibaba", Setting: "versioning"}
+	}
+
+	if settings.Encryption != nil {
+		return objectstore.UnsupportedSettingError{Provider: "Alibaba", Setting: "default encryption"}
+	}
+
+	for _, rule := range settings.LifecycleRules {
+		if rule.TransitionDays > 0 {
+			return objectstore.UnsupportedSettingError{Provider: "Alibaba", Setting: "lifecycle transition"}
+		}
+	}
+
+	if len(settings.Labels) > 0 {
+		return objectstore.UnsupportedSettingError{Provider: "Alibaba", Setting: "labels"}
+	}
+
+	return nil
+}
+
+func (b *AlibabaObjectStore) CreateBucket(bucketName string, settings objectstore.BucketSettings) error {
+	if err := ValidateBucketSettings(settings); err != nil {
+		return err
+	}
+
 	managedBucket := &ManagedAlibabaBucket{}
 	searchCriteria := b.newManagedBucketSearchCriteria(bucketName)
 	if err := getManagedBucket(searchCriteria, managedBucket); err != nil {
@@ -65,6 +94,8 @@ func (b *AlibabaObjectStore) CreateBucket(bucketName string) error {
 	managedBucket.Name = bucketName
 	managedBucket.Organization = *b.org
 	managedBucket.Region = b.region
+	managedBucket.SecretID = b.secret.ID
+	managedBucket.Status = objectstore.BucketAvailable
 
 	if err = persistToDb(managedBucket); err != nil {
 		return errors.Wrap(err, "Error happened during persisting bucket description to DB")
@@ -82,9 +113,73 @@ func (b *AlibabaObjectStore) CreateBucket(bucketName string) error {
 	// TODO: wait for bucket creation.
 	log.Infof("Bucket %s Created", bucketName)
 
+	if err := applySettings(svc, bucketName, objectstore.BucketSettings{}, settings); err != nil {
+		if e := svc.DeleteBucket(bucketName); e != nil {
+			log.Error(e.Error())
+		} else if e := deleteFromDbByPK(managedBucket); e != nil {
+			log.Error(e.Error())
+		}
+
+		return errors.Wrap(err, "could not apply bucket settings (rolling back)")
+	}
+
+	managedBucket.Settings = settings
+	if err := persistToDb(managedBucket); err != nil {
+		return errors.Wrap(err, "Error happened during persisting bucket settings to DB")
+	}
+
 	return nil
 }
 
+// UpdateBucketSettings applies the changed settings to the managed OSS bucket identified by the specified name.
+func (b *AlibabaObjectStore) UpdateBucketSettings(bucketName string, settings objectstore.BucketSettings) error {
+	if err := ValidateBucketSettings(settings); err != nil {
+		return err
+	}
+
+	managedBucket := &ManagedAlibabaBucket{}
+	searchCriteria := b.newManagedBucketSearchCriteria(bucketName)
+
+	log.Infof("Looking up managed bucket: name=%s", bucketName)
+	if err := getManagedBucket(searchCriteria, managedBucket); err != nil {
+		return err
+	}
+
+	svc, err := createAlibabaOSSClient(managedBucket.Region, b.secret)
+	if err != nil {
+		return errors.Wrap(err, "Creating AlibabaOSSClient failed")
+	}
+
+	if err := applySettings(svc, bucketName, managedBucket.Settings, settings); err != nil {
+		return errors.Wrap(err, "could not apply bucket settings")
+	}
+
+	managedBucket.Settings = settings
+	if err := persistToDb(managedBucket); err != nil {
+		return errors.Wrap(err, "Error happened during persisting bucket settings to DB")
+	}
+
+	return nil
+}
+
+// applySettings applies the lifecycle rules to the OSS bucket if they differ from the current ones.
+func applySettings(svc *oss.Client, bucketName string, current, settings objectstore.BucketSettings) error {
+	if reflect.DeepEqual(settings.LifecycleRules, current.LifecycleRules) {
+		return nil
+	}
+
+	if len(settings.LifecycleRules) == 0 {
+		return svc.DeleteBucketLifecycle(bucketName)
+	}
+
+	rules := make([]oss.LifecycleRule, 0, len(settings.LifecycleRules))
+	for _, rule := range settings.LifecycleRules {
+		rules = append(rules, oss.BuildLifecycleRuleByDays(rule.ID, rule.Prefix, true, rule.ExpirationDays))
+	}
+
+	return svc.SetBucketLifecycle(bucketName, rules)
+}
+
 func (b *AlibabaObjectStore) ListBuckets() ([]*objectstore.BucketInfo, error) {
 	svc, err := createAlibabaOSSClient(b.region, b.secret)
 	if err != nil {
@@ -116,6 +211,8 @@ func (b *AlibabaObjectStore) ListBuckets() ([]*objectstore.BucketInfo, error) {
 		bucketInfo := &objectstore.BucketInfo{Name: bucket.Name, Managed: false}
 		if idx < len(managedAlibabaBuckets) && strings.Compare(managedAlibabaBuckets[idx].Name, bucket.Name) == 0 {
 			bucketInfo.Managed = true
+			bucketInfo.Settings = &managedAlibabaBuckets[idx].Settings
+			bucketInfo.Status = managedAlibabaBuckets[idx].Status
 		}
 
 		bucketList = append(bucketList, bucketInfo)
@@ -169,6 +266,90 @@ func (b *AlibabaObjectStore) CheckBucket(bucketName string) error {
 	return nil
 }
 
+// ImportBucket registers the existing OSS bucket with the given name as managed by Pipeline
+func (b *AlibabaObjectStore) ImportBucket(bucketName string) error {
+	if err := b.CheckBucket(bucketName); err != nil {
+		if ossErr, ok := err.(oss.ServiceError); ok && ossErr.Code == "NoSuchBucket" {
+			return ManagedBucketNotFoundError{errMessage: ossErr.Message}
+		}
+
+		return err
+	}
+
+	managedBucket := &ManagedAlibabaBucket{}
+	searchCriteria := b.newManagedBucketSearchCriteria(bucketName)
+	if err := getManagedBucket(searchCriteria, managedBucket); err != nil {
+		switch err.(type) {
+		case ManagedBucketNotFoundError:
+		default:
+			return errors.Wrap(err, "error happened during getting bucket description from DB")
+		}
+	}
+
+	managedBucket.Name = bucketName
+	managedBucket.Organization = *b.org
+	managedBucket.Region = b.region
+	managedBucket.SecretID = b.secret.ID
+	managedBucket.Status = objectstore.BucketAvailable
+
+	log.Infof("Importing bucket %s", bucketName)
+	if err := persistToDb(managedBucket); err != nil {
+		return errors.Wrap(err, "Error happened during persisting bucket description to DB")
+	}
+
+	return nil
+}
+
+// ForgetBucket removes the managed OSS bucket with the given name from the database without deleting it
+func (b *AlibabaObjectStore) ForgetBucket(bucketName string) error {
+	managedBucket := &ManagedAlibabaBucket{}
+	searchCriteria := b.newManagedBucketSearchCriteria(bucketName)
+
+	log.Infof("Looking up managed bucket: name=%s", bucketName)
+	if err := getManagedBucket(searchCriteria, managedBucket); err != nil {
+		return err
+	}
+
+	return deleteFromDbByPK(managedBucket)
+}
+
+// ManagedBuckets returns the OSS buckets managed by Pipeline in every organization
+func ManagedBuckets(db *gorm.DB) ([]objectstore.ManagedBucket, error) {
+	var buckets []*ManagedAlibabaBucket
+
+	if err := db.Find(&buckets).Error; err != nil {
+		return nil, errors.Wrap(err, "retrieving managed buckets failed")
+	}
+
+	managedBuckets := make([]objectstore.ManagedBucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		managedBuckets = append(managedBuckets, objectstore.ManagedBucket{
+			ID:             bucket.ID,
+			OrganizationID: bucket.OrgID,
+			SecretID:       bucket.SecretID,
+			Name:           bucket.Name,
+			Location:       bucket.Region,
+			Status:         bucket.Status,
+		})
+	}
+
+	return managedBuckets, nil
+}
+
+// SetBucketStatus updates the status of the managed OSS bucket with the given ID
+func SetBucketStatus(db *gorm.DB, id uint, status string) error {
+	err := db.Model(&ManagedAlibabaBucket{ID: id}).Update("status", status).Error
+
+	return errors.Wrap(err, "updating bucket status failed")
+}
+
+// SetBucketSecretID records the secret of the managed OSS bucket with the given ID
+func SetBucketSecretID(db *gorm.DB, id uint, secretID string) error {
+	err := db.Model(&ManagedAlibabaBucket{ID: id}).Update("secret_id", secretID).Error
+
+	return errors.Wrap(err, "updating bucket secret failed")
+}
+
 // newManagedBucketSearchCriteria returns the database search criteria to find managed bucket with the given name
 func (b *AlibabaObjectStore) newManagedBucketSearchCriteria(bucketName string) *ManagedAlibabaBucket {
 	return &ManagedAlibabaBucket{
@@ -14,7 +14,10 @@
 
 package alibaba
 
-import "github.com/banzaicloud/pipeline/auth"
+import (
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/internal/objectstore"
+)
 
 // TableName constants
 const (
@@ -28,6 +31,12 @@ type ManagedAlibabaBucket struct {
 	OrgID        uint              `gorm:"index;not null"`
 	Name         string            `gorm:"unique_index:idx_bucket_name"`
 	Region       string
+
+	// SecretID is the secret the bucket was created or imported with
+	SecretID string
+	Status   string
+
+	Settings objectstore.BucketSettings `gorm:"type:text"`
 }
 
 // TableName changes the default table name.
@@ -16,6 +16,7 @@ package amazon
 
 import (
 	"fmt"
+	"reflect"
 	"sort"
 	"strings"
 
@@ -39,6 +40,11 @@ func (bucketNotFoundError) NotFound() bool { return true }
 type amazonObjectStore interface {
 	commonObjectstore.ObjectStore
 	GetRegion(bucket string) (string, error)
+
+	SetVersioning(bucketName string, enabled bool) error
+	SetEncryption(bucketName string, enabled bool, kmsKeyID string) error
+	SetLifecycleRules(bucketName string, rules []amazonObjectstore.LifecycleRule) error
+	SetTags(bucketName string, tags map[string]string) error
 }
 
 // objectStore stores all required parameters for bucket creation.
@@ -107,8 +113,8 @@ func (s *objectStore) getLogger() logrus.FieldLogger {
 	})
 }
 
-// CreateBucket creates an S3 bucket with the provided name.
-func (s *objectStore) CreateBucket(bucketName string) error {
+// CreateBucket creates an S3 bucket with the provided name and settings.
+func (s *objectStore) CreateBucket(bucketName string, settings objectstore.BucketSettings) error {
 	logger := s.getLogger().WithField("bucket", bucketName)
 
 	bucket := &ObjectStoreBucketModel{}
@@ -123,6 +129,8 @@ func (s *objectStore) CreateBucket(bucketName string) error {
 	bucket.Name = bucketName
 	bucket.Organization = *s.org
 	bucket.Region = s.region
+	bucket.SecretID = s.secret.ID
+	bucket.Status = objectstore.BucketAvailable
 
 	if err := s.db.Save(bucket).Error; err != nil {
 		return errors.Wrap(err, "error happened during saving bucket in DB")
@@ -141,6 +149,164 @@ func (s *objectStore) CreateBucket(bucketName string) error {
 
 	logger.Info("bucket created")
 
+	if err := applySettings(s.objectStore, bucketName, objectstore.BucketSettings{}, settings); err != nil {
+		if e := s.objectStore.DeleteBucket(bucketName); e != nil {
+			logger.Error(e.Error())
+		} else if e := s.db.Delete(bucket).Error; e != nil {
+			logger.Error(e.Error())
+		}
+
+		return errors.Wrap(err, "could not apply bucket settings (rolling back)")
+	}
+
+	bucket.Settings = settings
+	if err := s.db.Save(bucket).Error; err != nil {
+		return errors.Wrap(err, "error happened during saving bucket settings in DB")
+	}
+
+	return nil
+}
+
+// UpdateBucketSettings applies the changed settings to the managed S3 bucket identified by the specified name.
+func (s *objectStore) UpdateBucketSettings(bucketName string, settings objectstore.BucketSettings) error {
+	logger := s.getLogger().WithField("bucket", bucketName)
+
+	bucket := &ObjectStoreBucketModel{}
+	searchCriteria := s.searchCriteria(bucketName)
+
+	if err := s.db.Where(searchCriteria).Find(bucket).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return bucketNotFoundError{}
+		}
+
+		return errors.Wrap(err, "error happened during getting bucket from DB")
+	}
+
+	logger.Info("updating bucket settings")
+
+	objectStore, err := getProviderObjectStore(s.secret, bucket.Region)
+	if err != nil {
+		return errors.Wrap(err, "could not create AWS object storage client")
+	}
+
+	if err := applySettings(objectStore, bucketName, bucket.Settings, settings); err != nil {
+		return errors.Wrap(err, "could not apply bucket settings")
+	}
+
+	bucket.Settings = settings
+	if err := s.db.Save(bucket).Error; err != nil {
+		return errors.Wrap(err, "error happened during saving bucket settings in DB")
+	}
+
+	return nil
+}
+
+// ImportBucket registers the existing S3 bucket identified by the specified name as managed by Pipeline.
+func (s *objectStore) ImportBucket(bucketName string) error {
+	logger := s.getLogger().WithField("bucket", bucketName)
+
+	logger.Info("checking bucket")
+
+	if err := s.objectStore.CheckBucket(bucketName); err != nil {
+		return err
+	}
+
+	region, err := s.objectStore.GetRegion(bucketName)
+	if err != nil {
+		return err
+	}
+
+	bucket := &ObjectStoreBucketModel{}
+	searchCriteria := s.searchCriteria(bucketName)
+
+	if err := s.db.Where(searchCriteria).Find(bucket).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			return errors.Wrap(err, "error happened during getting bucket from DB")
+		}
+	}
+
+	bucket.Name = bucketName
+	bucket.Organization = *s.org
+	bucket.Region = region
+	bucket.SecretID = s.secret.ID
+	bucket.Status = objectstore.BucketAvailable
+
+	logger.Info("importing bucket")
+
+	if err := s.db.Save(bucket).Error; err != nil {
+		return errors.Wrap(err, "error happened during saving bucket in DB")
+	}
+
+	return nil
+}
+
+// ForgetBucket removes the S3 bucket identified by the specified name from the managed ones
+// without deleting it.
+func (s *objectStore) ForgetBucket(bucketName string) error {
+	logger := s.getLogger().WithField("bucket", bucketName)
+
+	bucket := &ObjectStoreBucketModel{}
+	searchCriteria := s.searchCriteria(bucketName)
+
+	if err := s.db.Where(searchCriteria).Find(bucket).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return bucketNotFoundError{}
+		}
+
+		return errors.Wrap(err, "error happened during getting bucket from DB")
+	}
+
+	logger.Info("forgetting bucket")
+
+	if err := s.db.Delete(bucket).Error; err != nil {
+		return errors.Wrap(err, "deleting bucket from database failed")
+	}
+
+	return nil
+}
+
+// applySettings applies the settings which differ from the current ones to the S3 bucket.
+func applySettings(objectStore amazonObjectStore, bucketName string, current, settings objectstore.BucketSettings) error {
+	if settings.Versioning != current.Versioning {
+		if err := objectStore.SetVersioning(bucketName, settings.Versioning); err != nil {
+			return err
+		}
+	}
+
+	if !reflect.DeepEqual(settings.Encryption, current.Encryption) {
+		var kmsKeyID string
+		if settings.Encryption != nil {
+			kmsKeyID = settings.Encryption.KeyID
+		}
+
+		if err := objectStore.SetEncryption(bucketName, settings.Encryption != nil, kmsKeyID); err != nil {
+			return err
+		}
+	}
+
+	if !reflect.DeepEqual(settings.LifecycleRules, current.LifecycleRules) {
+		rules := make([]amazonObjectstore.LifecycleRule, 0, len(settings.LifecycleRules))
+		for _, rule := range settings.LifecycleRules {
+			rules = append(rules, amazonObjectstore.LifecycleRule{
+				ID:             rule.ID,
+				Prefix:         rule.Prefix,
+				ExpirationDays: int64(rule.ExpirationDays),
+				TransitionDays: int64(rule.TransitionDays),
+				StorageClass:   rule.StorageClass,
+			})
+		}
+
+		if err := objectStore.SetLifecycleRules(bucketName, rules); err != nil {
+			return err
+		}
+	}
+
+	if !reflect.DeepEqual(settings.Labels, current.Labels) {
+		if err := objectStore.SetTags(bucketName, settings.Labels); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -223,6 +389,8 @@ func (s *objectStore) ListBuckets() ([]*objectstore.BucketInfo, error) {
 		bucketInfo := &objectstore.BucketInfo{Name: bucket, Managed: false}
 		if idx < len(amazonBuckets) && strings.Compare(amazonBuckets[idx].Name, bucket) == 0 {
 			bucketInfo.Managed = true
+			bucketInfo.Settings = &amazonBuckets[idx].Settings
+			bucketInfo.Status = amazonBuckets[idx].Status
 		}
 
 		region, err := s.objectStore.GetRegion(bucket)
@@ -244,3 +412,40 @@ func (s *objectStore) searchCriteria(bucketName string) *ObjectStoreBucketModel
 		Name:           bucketName,
 	}
 }
+
+// ManagedBuckets returns the S3 buckets managed by Pipeline in every organization.
+func ManagedBuckets(db *gorm.DB) ([]objectstore.ManagedBucket, error) {
+	var buckets []*ObjectStoreBucketModel
+
+	if err := db.Find(&buckets).Error; err != nil {
+		return nil, errors.Wrap(err, "retrieving managed buckets failed")
+	}
+
+	managedBuckets := make([]objectstore.ManagedBucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		managedBuckets = append(managedBuckets, objectstore.ManagedBucket{
+			ID:             bucket.ID,
+			OrganizationID: bucket.OrganizationID,
+			SecretID:       bucket.SecretID,
+			Name:           bucket.Name,
+			Location:       bucket.Region,
+			Status:         bucket.Status,
+		})
+	}
+
+	return managedBuckets, nil
+}
+
+// SetBucketStatus updates the status of the managed S3 bucket with the given ID.
+func SetBucketStatus(db *gorm.DB, id uint, status string) error {
+	err := db.Model(&ObjectStoreBucketModel{ID: id}).Update("status", status).Error
+
+	return errors.Wrap(err, "updating bucket status failed")
+}
+
+// SetBucketSecretID records the secret of the managed S3 bucket with the given ID.
+func SetBucketSecretID(db *gorm.DB, id uint, secretID string) error {
+	err := db.Model(&ObjectStoreBucketModel{ID: id}).Update("secret_id", secretID).Error
+
+	return errors.Wrap(err, "updating bucket secret failed")
+}
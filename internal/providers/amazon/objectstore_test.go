@@ -0,0 +1,236 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package amazon
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/internal/objectstore"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// recordingDriver is a database driver recording the executed statements
+// and answering every query with the same rows
+type recordingDriver struct {
+	statements []string
+
+	columns []string
+	rows    [][]driver.Value
+}
+
+var testDriver = &recordingDriver{}
+
+func init() {
+	sql.Register("amazon-test", testDriver)
+}
+
+func (d *recordingDriver) Open(name string) (driver.Conn, error) { return d, nil }
+func (d *recordingDriver) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{driver: d, query: query}, nil
+}
+func (d *recordingDriver) Close() error              { return nil }
+func (d *recordingDriver) Begin() (driver.Tx, error) { return d, nil }
+func (d *recordingDriver) Commit() error             { return nil }
+func (d *recordingDriver) Rollback() error           { return nil }
+
+type recordingStmt struct {
+	driver *recordingDriver
+	query  string
+}
+
+func (s *recordingStmt) Close() error  { return nil }
+func (s *recordingStmt) NumInput() int { return -1 }
+
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.driver.statements = append(s.driver.statements, s.query)
+
+	return recordingResult{}, nil
+}
+
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.driver.statements = append(s.driver.statements, s.query)
+
+	return &recordingRows{columns: s.driver.columns, rows: s.driver.rows}, nil
+}
+
+type recordingResult struct{}
+
+func (recordingResult) LastInsertId() (int64, error) { return 1, nil }
+func (recordingResult) RowsAffected() (int64, error) { return 1, nil }
+
+type recordingRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *recordingRows) Columns() []string { return r.columns }
+func (r *recordingRows) Close() error      { return nil }
+
+func (r *recordingRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+
+	return nil
+}
+
+// statementsOf returns the recorded statements starting with the given keyword
+func (d *recordingDriver) statementsOf(keyword string) []string {
+	var statements []string
+	for _, statement := range d.statements {
+		if strings.HasPrefix(statement, keyword) {
+			statements = append(statements, statement)
+		}
+	}
+
+	return statements
+}
+
+// checkingObjectStore answers bucket checks and region lookups with the given errors
+type checkingObjectStore struct {
+	amazonObjectStore
+
+	checkErr  error
+	regionErr error
+}
+
+func (s *checkingObjectStore) CheckBucket(bucketName string) error {
+	return s.checkErr
+}
+
+func (s *checkingObjectStore) GetRegion(bucketName string) (string, error) {
+	return "eu-west-1", s.regionErr
+}
+
+func newTestObjectStore(t *testing.T, client amazonObjectStore, existing bool) *objectStore {
+	*testDriver = recordingDriver{columns: []string{"id", "organization_id", "name"}}
+	if existing {
+		testDriver.rows = [][]driver.Value{{int64(1), int64(1), "bucket"}}
+	}
+
+	sqlDB, err := sql.Open("amazon-test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := gorm.Open("mysql", sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+
+	return &objectStore{
+		objectStore: client,
+		region:      "eu-west-1",
+		secret:      &secret.SecretItemResponse{ID: "secret"},
+		org:         &auth.Organization{ID: 1},
+		db:          db,
+		logger:      logger,
+	}
+}
+
+func TestObjectStore_ImportBucket(t *testing.T) {
+	t.Run("new bucket", func(t *testing.T) {
+		s := newTestObjectStore(t, &checkingObjectStore{}, false)
+
+		if err := s.ImportBucket("bucket"); err != nil {
+			t.Fatal(err)
+		}
+
+		if inserts := testDriver.statementsOf("INSERT INTO `amazon_buckets`"); len(inserts) != 1 {
+			t.Errorf("expected the bucket to be inserted, got %v", testDriver.statements)
+		}
+	})
+
+	t.Run("managed bucket", func(t *testing.T) {
+		s := newTestObjectStore(t, &checkingObjectStore{}, true)
+
+		if err := s.ImportBucket("bucket"); err != nil {
+			t.Fatal(err)
+		}
+
+		if inserts := testDriver.statementsOf("INSERT INTO `amazon_buckets`"); len(inserts) != 0 {
+			t.Errorf("expected the bucket not to be inserted again, got %v", inserts)
+		}
+
+		if updates := testDriver.statementsOf("UPDATE `amazon_buckets`"); len(updates) != 1 {
+			t.Errorf("expected the bucket to be updated, got %v", testDriver.statements)
+		}
+	})
+
+	t.Run("missing bucket", func(t *testing.T) {
+		s := newTestObjectStore(t, &checkingObjectStore{checkErr: bucketNotFoundError{}}, false)
+
+		if err := s.ImportBucket("bucket"); !objectstore.IsNotFoundError(err) {
+			t.Errorf("expected not found error, got %v", err)
+		}
+
+		if len(testDriver.statements) != 0 {
+			t.Errorf("expected the database to be left untouched, got %v", testDriver.statements)
+		}
+	})
+
+	t.Run("unknown region", func(t *testing.T) {
+		s := newTestObjectStore(t, &checkingObjectStore{regionErr: errors.New("access denied")}, false)
+
+		if err := s.ImportBucket("bucket"); err == nil {
+			t.Error("expected the region lookup error")
+		}
+
+		if len(testDriver.statements) != 0 {
+			t.Errorf("expected the database to be left untouched, got %v", testDriver.statements)
+		}
+	})
+}
+
+func TestObjectStore_ForgetBucket(t *testing.T) {
+	t.Run("managed bucket", func(t *testing.T) {
+		s := newTestObjectStore(t, &checkingObjectStore{}, true)
+
+		if err := s.ForgetBucket("bucket"); err != nil {
+			t.Fatal(err)
+		}
+
+		if deletes := testDriver.statementsOf("DELETE FROM `amazon_buckets`"); len(deletes) != 1 {
+			t.Errorf("expected the bucket to be deleted from the database, got %v", testDriver.statements)
+		}
+	})
+
+	t.Run("unknown bucket", func(t *testing.T) {
+		s := newTestObjectStore(t, &checkingObjectStore{}, false)
+
+		if err := s.ForgetBucket("bucket"); !objectstore.IsNotFoundError(err) {
+			t.Errorf("expected not found error, got %v", err)
+		}
+
+		if deletes := testDriver.statementsOf("DELETE"); len(deletes) != 0 {
+			t.Errorf("expected nothing to be deleted, got %v", deletes)
+		}
+	})
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"reflect"
 	"regexp"
 	"sort"
 	"strings"
@@ -117,9 +118,56 @@ func (s *ObjectStore) getLogger(bucketName string) logrus.FieldLogger {
 	})
 }
 
-// CreateBucket creates an Azure Object Store Blob with the provided name
+// metadataNameRegexp matches the valid names of container metadata, which have to be C# identifiers
+var metadataNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateBucketSettings checks if the settings are supported by Azure storage containers.
+// Versioning, customer managed encryption keys and lifecycle management are storage account level features,
+// labels are set as the metadata of the container, so their keys have to be valid metadata names
+// and their values valid HTTP header values.
+func ValidateBucketSettings(settings objectstore.BucketSettings) error {
+	if settings.Versioning {
+		return objectstore.UnsupportedSettingError{Provider: "Azure", Setting: "versioning"}
+	}
+
+	if settings.Encryption != nil && settings.Encryption.KeyID != "" {
+		return objectstore.UnsupportedSettingError{Provider: "Azure", Setting: "customer managed encryption key"}
+	}
+
+	if len(settings.LifecycleRules) > 0 {
+		return objectstore.UnsupportedSettingError{Provider: "Azure", Setting: "lifecycle rules"}
+	}
+
+	// metadata names are case-insensitive
+	names := make(map[string]bool, len(settings.Labels))
+
+	for key, value := range settings.Labels {
+		if !metadataNameRegexp.MatchString(key) {
+			return errors.Errorf("label key must be a valid C# identifier: %s", key)
+		}
+
+		if names[strings.ToLower(key)] {
+			return errors.Errorf("label keys must be unique regardless of case: %s", key)
+		}
+		names[strings.ToLower(key)] = true
+
+		for _, c := range value {
+			if c < ' ' || c > '~' {
+				return errors.Errorf("label %s must contain printable ASCII characters only", key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// CreateBucket creates an Azure Object Store Blob with the provided name and settings
 // within a generated/provided ResourceGroup and StorageAccount
-func (s *ObjectStore) CreateBucket(bucketName string) error {
+func (s *ObjectStore) CreateBucket(bucketName string, settings objectstore.BucketSettings) error {
+	if err := ValidateBucketSettings(settings); err != nil {
+		return err
+	}
+
 	resourceGroup := s.getResourceGroup()
 	storageAccount := s.getStorageAccount()
 
@@ -187,10 +235,20 @@ func (s *ObjectStore) CreateBucket(bucketName string) error {
 
 	_, err = containerURL.GetPropertiesAndMetadata(context.TODO(), azblob.LeaseAccessConditions{})
 	if err != nil && err.(azblob.StorageError).ServiceCode() == azblob.ServiceCodeContainerNotFound {
-		_, err = containerURL.Create(context.TODO(), azblob.Metadata{}, azblob.PublicAccessNone)
+		_, err = containerURL.Create(context.TODO(), azblob.Metadata(settings.Labels), azblob.PublicAccessNone)
 		if err != nil {
 			return s.rollback(logger, "cannot access bucket", err, bucket)
 		}
+	} else if err == nil && len(settings.Labels) > 0 {
+		_, err = containerURL.SetMetadata(context.TODO(), azblob.Metadata(settings.Labels), azblob.ContainerAccessConditions{})
+		if err != nil {
+			return s.rollback(logger, "could not set container metadata", err, bucket)
+		}
+	}
+
+	updateField = &ObjectStoreBucketModel{Settings: settings, SecretID: s.secret.ID, Status: objectstore.BucketAvailable}
+	if err := s.db.Model(bucket).Update(updateField).Error; err != nil {
+		return errors.Wrap(err, "error happened during saving bucket settings in DB")
 	}
 
 	secretName, err := s.createUpdateStorageAccountSecret(key)
@@ -390,6 +448,119 @@ func (s *ObjectStore) DeleteBucket(bucketName string) error {
 	return nil
 }
 
+// UpdateBucketSettings applies the changed settings to the managed Azure storage container identified by the specified name
+// under the current resource group and storage account.
+func (s *ObjectStore) UpdateBucketSettings(bucketName string, settings objectstore.BucketSettings) error {
+	if err := ValidateBucketSettings(settings); err != nil {
+		return err
+	}
+
+	resourceGroup := s.getResourceGroup()
+	storageAccount := s.getStorageAccount()
+
+	logger := s.getLogger(bucketName)
+
+	bucket := &ObjectStoreBucketModel{}
+	searchCriteria := s.searchCriteria(bucketName)
+
+	if err := s.db.Where(searchCriteria).Find(bucket).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return bucketNotFoundError{}
+		}
+
+		return errors.Wrap(err, "error happened during getting bucket from DB")
+	}
+
+	logger.Info("updating bucket settings")
+
+	if !reflect.DeepEqual(settings.Labels, bucket.Settings.Labels) {
+		key, err := GetStorageAccountKey(resourceGroup, storageAccount, s.secret, s.logger)
+		if err != nil {
+			return err
+		}
+
+		p := azblob.NewPipeline(azblob.NewSharedKeyCredential(storageAccount, key), azblob.PipelineOptions{})
+		URL, _ := url.Parse(fmt.Sprintf("https://%s.blob.core.windows.net/%s", storageAccount, bucketName))
+		containerURL := azblob.NewContainerURL(*URL, p)
+
+		_, err = containerURL.SetMetadata(context.TODO(), azblob.Metadata(settings.Labels), azblob.ContainerAccessConditions{})
+		if err != nil {
+			return errors.Wrap(err, "could not set container metadata")
+		}
+	}
+
+	bucket.Settings = settings
+	if err := s.db.Save(bucket).Error; err != nil {
+		return errors.Wrap(err, "error happened during saving bucket settings in DB")
+	}
+
+	return nil
+}
+
+// ImportBucket registers the existing Azure storage container identified by the specified name
+// under the current resource group and storage account as managed by Pipeline.
+func (s *ObjectStore) ImportBucket(bucketName string) error {
+	logger := s.getLogger(bucketName)
+
+	if err := s.CheckBucket(bucketName); err != nil {
+		if storageErr, ok := err.(azblob.StorageError); ok && storageErr.ServiceCode() == azblob.ServiceCodeContainerNotFound {
+			return bucketNotFoundError{}
+		}
+
+		return err
+	}
+
+	bucket := &ObjectStoreBucketModel{}
+	searchCriteria := s.searchCriteria(bucketName)
+
+	if err := s.db.Where(searchCriteria).Find(bucket).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			return errors.Wrap(err, "error happened during getting bucket from DB")
+		}
+	}
+
+	bucket.Name = bucketName
+	bucket.ResourceGroup = s.getResourceGroup()
+	bucket.StorageAccount = s.getStorageAccount()
+	bucket.Location = s.location
+	bucket.Organization = *s.org
+	bucket.SecretID = s.secret.ID
+	bucket.Status = objectstore.BucketAvailable
+
+	logger.Info("importing bucket")
+
+	if err := s.db.Save(bucket).Error; err != nil {
+		return errors.Wrap(err, "error happened during saving bucket in DB")
+	}
+
+	return nil
+}
+
+// ForgetBucket removes the Azure storage container identified by the specified name
+// under the current resource group and storage account from the managed ones without deleting it.
+func (s *ObjectStore) ForgetBucket(bucketName string) error {
+	logger := s.getLogger(bucketName)
+
+	bucket := &ObjectStoreBucketModel{}
+	searchCriteria := s.searchCriteria(bucketName)
+
+	if err := s.db.Where(searchCriteria).Find(bucket).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return bucketNotFoundError{}
+		}
+
+		return errors.Wrap(err, "error happened during getting bucket from DB")
+	}
+
+	logger.Info("forgetting bucket")
+
+	if err := s.db.Delete(bucket).Error; err != nil {
+		return errors.Wrap(err, "deleting bucket from database failed")
+	}
+
+	return nil
+}
+
 // CheckBucket checks the status of the given Azure blob.
 func (s *ObjectStore) CheckBucket(bucketName string) error {
 	resourceGroup := s.getResourceGroup()
@@ -507,6 +678,8 @@ func (s *ObjectStore) ListBuckets() ([]*objectstore.BucketInfo, error) {
 			strings.Compare(objectStores[idx].StorageAccount, bucketInfo.Azure.StorageAccount) >= 0 &&
 			strings.Compare(objectStores[idx].Name, bucketInfo.Name) >= 0 {
 			bucketInfo.Managed = true
+			bucketInfo.Settings = &objectStores[idx].Settings
+			bucketInfo.Status = objectStores[idx].Status
 		}
 	}
 
@@ -626,6 +799,45 @@ func newAuthorizer(s *secret.SecretItemResponse) (autorest.Authorizer, error) {
 	return authorizer, nil
 }
 
+// ManagedBuckets returns the Azure storage containers managed by Pipeline in every organization.
+func ManagedBuckets(db *gorm.DB) ([]objectstore.ManagedBucket, error) {
+	var buckets []*ObjectStoreBucketModel
+
+	if err := db.Find(&buckets).Error; err != nil {
+		return nil, errors.Wrap(err, "retrieving managed buckets failed")
+	}
+
+	managedBuckets := make([]objectstore.ManagedBucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		managedBuckets = append(managedBuckets, objectstore.ManagedBucket{
+			ID:             bucket.ID,
+			OrganizationID: bucket.OrganizationID,
+			SecretID:       bucket.SecretID,
+			Name:           bucket.Name,
+			Location:       bucket.Location,
+			Status:         bucket.Status,
+			ResourceGroup:  bucket.ResourceGroup,
+			StorageAccount: bucket.StorageAccount,
+		})
+	}
+
+	return managedBuckets, nil
+}
+
+// SetBucketStatus updates the status of the managed Azure storage container with the given ID.
+func SetBucketStatus(db *gorm.DB, id uint, status string) error {
+	err := db.Model(&ObjectStoreBucketModel{ID: id}).Update("status", status).Error
+
+	return errors.Wrap(err, "updating bucket status failed")
+}
+
+// SetBucketSecretID records the secret of the managed Azure storage container with the given ID.
+func SetBucketSecretID(db *gorm.DB, id uint, secretID string) error {
+	err := db.Model(&ObjectStoreBucketModel{ID: id}).Update("secret_id", secretID).Error
+
+	return errors.Wrap(err, "updating bucket secret failed")
+}
+
 // searchCriteria returns the database search criteria to find a bucket with the given name
 // within the scope of the specified resource group and storage account.
 func (s *ObjectStore) searchCriteria(bucketName string) *ObjectStoreBucketModel {
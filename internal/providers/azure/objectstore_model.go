@@ -14,7 +14,10 @@
 
 package azure
 
-import "github.com/banzaicloud/pipeline/auth"
+import (
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/internal/objectstore"
+)
 
 // TableName constants
 const (
@@ -32,6 +35,12 @@ type ObjectStoreBucketModel struct {
 	ResourceGroup  string `gorm:"unique_index:idx_bucket_name"`
 	StorageAccount string `gorm:"unique_index:idx_bucket_name"`
 	Location       string
+
+	// SecretID is the secret the bucket was created or imported with
+	SecretID string
+	Status   string
+
+	Settings objectstore.BucketSettings `gorm:"type:text"`
 }
 
 // TableName changes the default table name.
@@ -0,0 +1,50 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"testing"
+
+	"github.com/banzaicloud/pipeline/internal/objectstore"
+)
+
+func TestValidateBucketSettings_Labels(t *testing.T) {
+	cases := map[string]struct {
+		labels map[string]string
+		valid  bool
+	}{
+		"identifiers":        {map[string]string{"team": "data", "_cost_center": "42", "env2": "prod"}, true},
+		"leading digit":      {map[string]string{"2env": "prod"}, false},
+		"dash":               {map[string]string{"cost-center": "42"}, false},
+		"dot":                {map[string]string{"app.kubernetes.io": "pipeline"}, false},
+		"case insensitive":   {map[string]string{"Team": "data", "team": "ops"}, false},
+		"non-ASCII value":    {map[string]string{"owner": "Jürgen"}, false},
+		"control char value": {map[string]string{"owner": "a\nb"}, false},
+		"empty value":        {map[string]string{"owner": ""}, true},
+		"no labels":          {nil, true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateBucketSettings(objectstore.BucketSettings{Labels: tc.labels})
+
+			if tc.valid && err != nil {
+				t.Errorf("expected labels to be valid, got %v", err)
+			} else if !tc.valid && err == nil {
+				t.Error("expected labels to be invalid")
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providers
+
+import (
+	"time"
+
+	"github.com/banzaicloud/pipeline/internal/objectstore"
+	"github.com/banzaicloud/pipeline/internal/providers/alibaba"
+	"github.com/banzaicloud/pipeline/internal/providers/amazon"
+	"github.com/banzaicloud/pipeline/internal/providers/azure"
+	"github.com/banzaicloud/pipeline/internal/providers/google"
+	"github.com/banzaicloud/pipeline/internal/providers/oracle"
+	"github.com/banzaicloud/pipeline/internal/providers/s3compatible"
+	pkgErrors "github.com/banzaicloud/pipeline/pkg/errors"
+	commonObjectstore "github.com/banzaicloud/pipeline/pkg/objectstore"
+	"github.com/banzaicloud/pipeline/pkg/providers"
+	pkgSecret "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+// bucketProviders are the cloud providers having managed buckets
+var bucketProviders = []string{providers.Alibaba, providers.Amazon, providers.Azure, providers.Google, providers.Oracle, providers.S3Compatible}
+
+// BucketReconciler periodically checks whether the buckets managed by Pipeline still exist in the cloud accounts
+// and flags the ones deleted out-of-band as missing.
+type BucketReconciler struct {
+	secrets       secret.SecretStore
+	checkInterval time.Duration
+	ticker        *time.Ticker
+
+	managedBuckets    func(provider string) ([]objectstore.ManagedBucket, error)
+	setBucketStatus   func(provider string, id uint, status string) error
+	setBucketSecretID func(provider string, id uint, secretID string) error
+	newObjectClient   func(ctx *ObjectStoreContext) (commonObjectstore.ObjectStore, error)
+
+	logger       logrus.FieldLogger
+	errorHandler emperror.Handler
+}
+
+// NewBucketReconciler returns a new bucket reconciler.
+func NewBucketReconciler(
+	secrets secret.SecretStore,
+	db *gorm.DB,
+	checkInterval time.Duration,
+	logger logrus.FieldLogger,
+	errorHandler emperror.Handler,
+) *BucketReconciler {
+	return &BucketReconciler{
+		secrets:       secrets,
+		checkInterval: checkInterval,
+
+		managedBuckets: func(provider string) ([]objectstore.ManagedBucket, error) {
+			return managedBuckets(db, provider)
+		},
+		setBucketStatus: func(provider string, id uint, status string) error {
+			return setBucketStatus(db, provider, id, status)
+		},
+		setBucketSecretID: func(provider string, id uint, secretID string) error {
+			return setBucketSecretID(db, provider, id, secretID)
+		},
+		newObjectClient: NewObjectClient,
+
+		logger:       logger,
+		errorHandler: errorHandler,
+	}
+}
+
+// Start starts checking the managed buckets in the background.
+func (r *BucketReconciler) Start() {
+	r.ticker = time.NewTicker(r.checkInterval)
+
+	go func() {
+		for range r.ticker.C {
+			r.Reconcile()
+		}
+	}()
+}
+
+// Stop stops the reconciler.
+func (r *BucketReconciler) Stop() {
+	r.ticker.Stop()
+}
+
+// Reconcile checks every managed bucket and updates its status if it changed.
+func (r *BucketReconciler) Reconcile() {
+	for _, provider := range bucketProviders {
+		buckets, err := r.managedBuckets(provider)
+		if err != nil {
+			r.errorHandler.Handle(emperror.With(err, "provider", provider))
+			continue
+		}
+
+		for _, bucket := range buckets {
+			r.reconcile(provider, bucket)
+		}
+	}
+}
+
+func (r *BucketReconciler) reconcile(provider string, bucket objectstore.ManagedBucket) {
+	logger := r.logger.WithFields(logrus.Fields{
+		"organization": bucket.OrganizationID,
+		"provider":     provider,
+		"bucket":       bucket.Name,
+	})
+
+	// the secret of buckets created before it was recorded is looked up among the secrets of the organization
+	if bucket.SecretID == "" {
+		r.backfillSecret(provider, bucket, logger)
+		return
+	}
+
+	bucketSecret, err := r.secrets.Get(bucket.OrganizationID, bucket.SecretID)
+	if err == secret.ErrSecretNotExists {
+		logger.Warn("secret of the bucket does not exist anymore, skipping check")
+		return
+	}
+	if err != nil {
+		r.errorHandler.Handle(emperror.With(err, "organization", bucket.OrganizationID, "secret", bucket.SecretID))
+		return
+	}
+
+	client, err := r.newObjectClient(r.objectStoreContext(provider, bucket, bucketSecret))
+	if err != nil {
+		r.errorHandler.Handle(emperror.With(err, "organization", bucket.OrganizationID, "bucket", bucket.Name))
+		return
+	}
+
+	status := objectstore.BucketAvailable
+
+	err = client.CheckBucket(bucket.Name)
+	if objectstore.IsNotFoundError(err) {
+		status = objectstore.BucketMissing
+	} else if err != nil {
+		r.errorHandler.Handle(emperror.With(err, "organization", bucket.OrganizationID, "bucket", bucket.Name))
+		return
+	}
+
+	if status == bucket.Status {
+		return
+	}
+
+	if status == objectstore.BucketMissing {
+		logger.Warn("managed bucket was deleted out-of-band")
+	} else {
+		logger.Info("managed bucket is available")
+	}
+
+	if err := r.setBucketStatus(provider, bucket.ID, status); err != nil {
+		r.errorHandler.Handle(emperror.With(err, "organization", bucket.OrganizationID, "bucket", bucket.Name))
+	}
+}
+
+// backfillSecret records the first secret of the organization which can access the bucket,
+// the bucket is checked in the next round with the recorded secret.
+func (r *BucketReconciler) backfillSecret(provider string, bucket objectstore.ManagedBucket, logger logrus.FieldLogger) {
+	secrets, err := r.secrets.List(bucket.OrganizationID, &pkgSecret.ListSecretsQuery{Type: provider, Values: true})
+	if err != nil {
+		r.errorHandler.Handle(emperror.With(err, "organization", bucket.OrganizationID, "bucket", bucket.Name))
+		return
+	}
+
+	for _, bucketSecret := range secrets {
+		client, err := r.newObjectClient(r.objectStoreContext(provider, bucket, bucketSecret))
+		if err != nil {
+			continue
+		}
+
+		if err := client.CheckBucket(bucket.Name); err != nil {
+			continue
+		}
+
+		logger.WithField("secret", bucketSecret.ID).Info("recording the secret of the bucket")
+
+		if err := r.setBucketSecretID(provider, bucket.ID, bucketSecret.ID); err != nil {
+			r.errorHandler.Handle(emperror.With(err, "organization", bucket.OrganizationID, "bucket", bucket.Name))
+		}
+
+		return
+	}
+
+	logger.Warn("none of the secrets of the organization can access the bucket, skipping check")
+}
+
+func (r *BucketReconciler) objectStoreContext(provider string, bucket objectstore.ManagedBucket, bucketSecret *secret.SecretItemResponse) *ObjectStoreContext {
+	return &ObjectStoreContext{
+		Provider:       provider,
+		Secret:         bucketSecret,
+		Location:       bucket.Location,
+		ResourceGroup:  bucket.ResourceGroup,
+		StorageAccount: bucket.StorageAccount,
+	}
+}
+
+// managedBuckets returns the buckets managed by Pipeline on the given cloud provider.
+func managedBuckets(db *gorm.DB, provider string) ([]objectstore.ManagedBucket, error) {
+	switch provider {
+	case providers.Alibaba:
+		return alibaba.ManagedBuckets(db)
+
+	case providers.Amazon:
+		return amazon.ManagedBuckets(db)
+
+	case providers.Azure:
+		return azure.ManagedBuckets(db)
+
+	case providers.Google:
+		return google.ManagedBuckets(db)
+
+	case providers.Oracle:
+		return oracle.ManagedBuckets(db)
+
+	case providers.S3Compatible:
+		return s3compatible.ManagedBuckets(db)
+
+	default:
+		return nil, pkgErrors.ErrorNotSupportedCloudType
+	}
+}
+
+// setBucketSecretID records the secret of a bucket managed by Pipeline on the given cloud provider.
+func setBucketSecretID(db *gorm.DB, provider string, id uint, secretID string) error {
+	switch provider {
+	case providers.Alibaba:
+		return alibaba.SetBucketSecretID(db, id, secretID)
+
+	case providers.Amazon:
+		return amazon.SetBucketSecretID(db, id, secretID)
+
+	case providers.Azure:
+		return azure.SetBucketSecretID(db, id, secretID)
+
+	case providers.Google:
+		return google.SetBucketSecretID(db, id, secretID)
+
+	case providers.Oracle:
+		return oracle.SetBucketSecretID(db, id, secretID)
+
+	case providers.S3Compatible:
+		return s3compatible.SetBucketSecretID(db, id, secretID)
+
+	default:
+		return pkgErrors.ErrorNotSupportedCloudType
+	}
+}
+
+// setBucketStatus updates the status of a bucket managed by Pipeline on the given cloud provider.
+func setBucketStatus(db *gorm.DB, provider string, id uint, status string) error {
+	switch provider {
+	case providers.Alibaba:
+		return alibaba.SetBucketStatus(db, id, status)
+
+	case providers.Amazon:
+		return amazon.SetBucketStatus(db, id, status)
+
+	case providers.Azure:
+		return azure.SetBucketStatus(db, id, status)
+
+	case providers.Google:
+		return google.SetBucketStatus(db, id, status)
+
+	case providers.Oracle:
+		return oracle.SetBucketStatus(db, id, status)
+
+	case providers.S3Compatible:
+		return s3compatible.SetBucketStatus(db, id, status)
+
+	default:
+		return pkgErrors.ErrorNotSupportedCloudType
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providers
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/banzaicloud/pipeline/internal/objectstore"
+	commonObjectstore "github.com/banzaicloud/pipeline/pkg/objectstore"
+	"github.com/banzaicloud/pipeline/pkg/providers"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string  { return "bucket not found" }
+func (notFoundError) NotFound() bool { return true }
+
+// checkingObjectStore answers bucket checks with the given error
+type checkingObjectStore struct {
+	commonObjectstore.ObjectStore
+
+	err error
+}
+
+func (s *checkingObjectStore) CheckBucket(bucketName string) error {
+	return s.err
+}
+
+// testBucketReconciler records the updates of a reconciler working on the given buckets
+type testBucketReconciler struct {
+	*BucketReconciler
+
+	statuses  map[uint]string
+	secretIDs map[uint]string
+	handled   []error
+}
+
+func newTestBucketReconciler(t *testing.T, buckets []objectstore.ManagedBucket, checkErrs map[string]error) *testBucketReconciler {
+	secrets := secret.NewInMemorySecretStore()
+	for _, name := range []string{"first", "second"} {
+		_, err := secrets.Store(1, &secret.CreateSecretRequest{Name: name, Type: providers.Amazon, Values: map[string]string{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+
+	r := &testBucketReconciler{
+		statuses:  make(map[uint]string),
+		secretIDs: make(map[uint]string),
+	}
+
+	r.BucketReconciler = &BucketReconciler{
+		secrets: secrets,
+
+		managedBuckets: func(provider string) ([]objectstore.ManagedBucket, error) {
+			if provider != providers.Amazon {
+				return nil, nil
+			}
+
+			return buckets, nil
+		},
+		setBucketStatus: func(provider string, id uint, status string) error {
+			r.statuses[id] = status
+
+			return nil
+		},
+		setBucketSecretID: func(provider string, id uint, secretID string) error {
+			r.secretIDs[id] = secretID
+
+			return nil
+		},
+		newObjectClient: func(ctx *ObjectStoreContext) (commonObjectstore.ObjectStore, error) {
+			return &checkingObjectStore{err: checkErrs[ctx.Secret.Name]}, nil
+		},
+
+		logger: logger,
+		errorHandler: emperror.HandlerFunc(func(err error) {
+			r.handled = append(r.handled, err)
+		}),
+	}
+
+	return r
+}
+
+func TestBucketReconciler_Reconcile(t *testing.T) {
+	firstSecretID := secret.GenerateSecretIDFromName("first")
+	secondSecretID := secret.GenerateSecretIDFromName("second")
+
+	cases := map[string]struct {
+		bucket            objectstore.ManagedBucket
+		checkErrs         map[string]error
+		expectedStatus    string
+		expectedSecretID  string
+		expectedErrorsLen int
+	}{
+		"deleted out-of-band": {
+			bucket:         objectstore.ManagedBucket{ID: 1, OrganizationID: 1, SecretID: firstSecretID, Status: objectstore.BucketAvailable},
+			checkErrs:      map[string]error{"first": notFoundError{}},
+			expectedStatus: objectstore.BucketMissing,
+		},
+		"available again": {
+			bucket:         objectstore.ManagedBucket{ID: 1, OrganizationID: 1, SecretID: firstSecretID, Status: objectstore.BucketMissing},
+			expectedStatus: objectstore.BucketAvailable,
+		},
+		"unchanged": {
+			bucket: objectstore.ManagedBucket{ID: 1, OrganizationID: 1, SecretID: firstSecretID, Status: objectstore.BucketAvailable},
+		},
+		"check failed": {
+			bucket:            objectstore.ManagedBucket{ID: 1, OrganizationID: 1, SecretID: firstSecretID, Status: objectstore.BucketAvailable},
+			checkErrs:         map[string]error{"first": errors.New("access denied")},
+			expectedErrorsLen: 1,
+		},
+		"deleted secret": {
+			bucket: objectstore.ManagedBucket{ID: 1, OrganizationID: 1, SecretID: "deleted", Status: objectstore.BucketAvailable},
+		},
+		"unknown secret": {
+			bucket:           objectstore.ManagedBucket{ID: 1, OrganizationID: 1, Status: objectstore.BucketAvailable},
+			checkErrs:        map[string]error{"first": errors.New("access denied")},
+			expectedSecretID: secondSecretID,
+		},
+		"unknown secret without access": {
+			bucket:    objectstore.ManagedBucket{ID: 1, OrganizationID: 1, Status: objectstore.BucketAvailable},
+			checkErrs: map[string]error{"first": errors.New("access denied"), "second": notFoundError{}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := newTestBucketReconciler(t, []objectstore.ManagedBucket{tc.bucket}, tc.checkErrs)
+
+			r.Reconcile()
+
+			if status := r.statuses[tc.bucket.ID]; status != tc.expectedStatus {
+				t.Errorf("expected status %q, got %q", tc.expectedStatus, status)
+			}
+
+			if secretID := r.secretIDs[tc.bucket.ID]; secretID != tc.expectedSecretID {
+				t.Errorf("expected secret %q, got %q", tc.expectedSecretID, secretID)
+			}
+
+			if len(r.handled) != tc.expectedErrorsLen {
+				t.Errorf("expected %d handled errors, got %v", tc.expectedErrorsLen, r.handled)
+			}
+		})
+	}
+}
@@ -17,6 +17,7 @@ package google
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"sort"
 	"strings"
 
@@ -28,6 +29,7 @@ import (
 	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
@@ -46,6 +48,7 @@ type ObjectStore struct {
 
 	org            *auth.Organization
 	serviceAccount *verify.ServiceAccount
+	secretID       string
 
 	location string
 }
@@ -54,6 +57,7 @@ type ObjectStore struct {
 func NewObjectStore(
 	org *auth.Organization,
 	serviceAccount *verify.ServiceAccount,
+	secretID string,
 	location string,
 	db *gorm.DB,
 	logger logrus.FieldLogger,
@@ -63,6 +67,7 @@ func NewObjectStore(
 		logger:         logger,
 		org:            org,
 		serviceAccount: serviceAccount,
+		secretID:       secretID,
 		location:       location,
 	}
 }
@@ -75,8 +80,24 @@ func (s *ObjectStore) getLogger(bucketName string) logrus.FieldLogger {
 	})
 }
 
-// CreateBucket creates a Google Bucket with the provided name and location.
-func (s *ObjectStore) CreateBucket(bucketName string) error {
+// ValidateBucketSettings checks if the settings are supported by Google Cloud Storage.
+// Lifecycle rules apply to every object of the bucket, they can't be restricted to a key prefix.
+func ValidateBucketSettings(settings objectstore.BucketSettings) error {
+	for _, rule := range settings.LifecycleRules {
+		if rule.Prefix != "" {
+			return objectstore.UnsupportedSettingError{Provider: "Google", Setting: "lifecycle rule prefix"}
+		}
+	}
+
+	return nil
+}
+
+// CreateBucket creates a Google Bucket with the provided name, location and settings.
+func (s *ObjectStore) CreateBucket(bucketName string, settings objectstore.BucketSettings) error {
+	if err := ValidateBucketSettings(settings); err != nil {
+		return err
+	}
+
 	logger := s.getLogger(bucketName)
 
 	bucket := &ObjectStoreBucketModel{}
@@ -110,6 +131,8 @@ func (s *ObjectStore) CreateBucket(bucketName string) error {
 	bucket.Name = bucketName
 	bucket.Organization = *s.org
 	bucket.Location = s.location
+	bucket.SecretID = s.secretID
+	bucket.Status = objectstore.BucketAvailable
 
 	logger.Info("saving bucket in DB")
 
@@ -135,9 +158,231 @@ func (s *ObjectStore) CreateBucket(bucketName string) error {
 
 	logger.Infof("bucket created")
 
+	if err := s.applySettings(ctx, credentials, bucketName, objectstore.BucketSettings{}, settings); err != nil {
+		if e := bucketHandle.Delete(ctx); e != nil {
+			logger.Error(e.Error())
+		} else if e := s.db.Delete(bucket).Error; e != nil {
+			logger.Error(e.Error())
+		}
+
+		return errors.Wrap(err, "could not apply bucket settings (rolling back)")
+	}
+
+	bucket.Settings = settings
+	if err := s.db.Save(bucket).Error; err != nil {
+		return errors.Wrap(err, "error happened during saving bucket settings in DB")
+	}
+
+	return nil
+}
+
+// UpdateBucketSettings applies the changed settings to the managed GS bucket identified by the specified name.
+func (s *ObjectStore) UpdateBucketSettings(bucketName string, settings objectstore.BucketSettings) error {
+	if err := ValidateBucketSettings(settings); err != nil {
+		return err
+	}
+
+	logger := s.getLogger(bucketName)
+
+	bucket := &ObjectStoreBucketModel{}
+	searchCriteria := s.searchCriteria(bucketName)
+
+	if err := s.db.Where(searchCriteria).Find(bucket).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return bucketNotFoundError{}
+		}
+
+		return errors.Wrap(err, "error happened during getting bucket from DB")
+	}
+
+	credentials, err := s.newGoogleCredentials()
+	if err != nil {
+		return errors.Wrap(err, "getting credentials failed")
+	}
+
+	logger.Info("updating bucket settings")
+
+	if err := s.applySettings(context.Background(), credentials, bucketName, bucket.Settings, settings); err != nil {
+		return errors.Wrap(err, "could not apply bucket settings")
+	}
+
+	bucket.Settings = settings
+	if err := s.db.Save(bucket).Error; err != nil {
+		return errors.Wrap(err, "error happened during saving bucket settings in DB")
+	}
+
+	return nil
+}
+
+// ImportBucket registers the existing GS bucket identified by the specified name as managed by Pipeline.
+func (s *ObjectStore) ImportBucket(bucketName string) error {
+	logger := s.getLogger(bucketName)
+
+	credentials, err := s.newGoogleCredentials()
+	if err != nil {
+		return errors.Wrap(err, "getting credentials failed")
+	}
+
+	ctx := context.Background()
+
+	client, err := storage.NewClient(ctx, option.WithCredentials(credentials))
+	if err != nil {
+		return errors.Wrap(err, "failed to create client")
+	}
+	defer client.Close()
+
+	logger.Info("checking bucket")
+
+	attrs, err := client.Bucket(bucketName).Attrs(ctx)
+	if err == storage.ErrBucketNotExist {
+		return bucketNotFoundError{}
+	}
+	if err != nil {
+		return errors.Wrap(err, "could not check bucket")
+	}
+
+	bucket := &ObjectStoreBucketModel{}
+	searchCriteria := s.searchCriteria(bucketName)
+
+	if err := s.db.Where(searchCriteria).Find(bucket).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			return errors.Wrap(err, "error happened during getting bucket from DB")
+		}
+	}
+
+	bucket.Name = bucketName
+	bucket.Organization = *s.org
+	bucket.Location = attrs.Location
+	bucket.SecretID = s.secretID
+	bucket.Status = objectstore.BucketAvailable
+
+	logger.Info("importing bucket")
+
+	if err := s.db.Save(bucket).Error; err != nil {
+		return errors.Wrap(err, "error happened during saving bucket in DB")
+	}
+
+	return nil
+}
+
+// ForgetBucket removes the GS bucket identified by the specified name from the managed ones
+// without deleting it.
+func (s *ObjectStore) ForgetBucket(bucketName string) error {
+	logger := s.getLogger(bucketName)
+
+	bucket := &ObjectStoreBucketModel{}
+	searchCriteria := s.searchCriteria(bucketName)
+
+	if err := s.db.Where(searchCriteria).Find(bucket).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return bucketNotFoundError{}
+		}
+
+		return errors.Wrap(err, "error happened during getting bucket from DB")
+	}
+
+	logger.Info("forgetting bucket")
+
+	if err := s.db.Delete(bucket).Error; err != nil {
+		return errors.Wrap(err, "deleting bucket from database failed")
+	}
+
 	return nil
 }
 
+// applySettings patches the settings which differ from the current ones on the GS bucket.
+// Every object is encrypted by Google, only customer managed keys are set as the default KMS key of the bucket.
+func (s *ObjectStore) applySettings(
+	ctx context.Context,
+	credentials *google.Credentials,
+	bucketName string,
+	current objectstore.BucketSettings,
+	settings objectstore.BucketSettings,
+) error {
+	patch := &apiStorage.Bucket{}
+	changed := false
+
+	if settings.Versioning != current.Versioning {
+		patch.Versioning = &apiStorage.BucketVersioning{
+			Enabled:         settings.Versioning,
+			ForceSendFields: []string{"Enabled"},
+		}
+		changed = true
+	}
+
+	var keyName, currentKeyName string
+	if settings.Encryption != nil {
+		keyName = settings.Encryption.KeyID
+	}
+	if current.Encryption != nil {
+		currentKeyName = current.Encryption.KeyID
+	}
+
+	if keyName != currentKeyName {
+		if keyName != "" {
+			patch.Encryption = &apiStorage.BucketEncryption{DefaultKmsKeyName: keyName}
+		} else {
+			patch.NullFields = append(patch.NullFields, "Encryption")
+		}
+		changed = true
+	}
+
+	if !reflect.DeepEqual(settings.LifecycleRules, current.LifecycleRules) {
+		var rules []*apiStorage.BucketLifecycleRule
+
+		for _, rule := range settings.LifecycleRules {
+			if rule.TransitionDays > 0 {
+				rules = append(rules, &apiStorage.BucketLifecycleRule{
+					Action: &apiStorage.BucketLifecycleRuleAction{
+						Type:         "SetStorageClass",
+						StorageClass: rule.StorageClass,
+					},
+					Condition: &apiStorage.BucketLifecycleRuleCondition{Age: int64(rule.TransitionDays)},
+				})
+			}
+
+			if rule.ExpirationDays > 0 {
+				rules = append(rules, &apiStorage.BucketLifecycleRule{
+					Action:    &apiStorage.BucketLifecycleRuleAction{Type: "Delete"},
+					Condition: &apiStorage.BucketLifecycleRuleCondition{Age: int64(rule.ExpirationDays)},
+				})
+			}
+		}
+
+		if len(rules) > 0 {
+			patch.Lifecycle = &apiStorage.BucketLifecycle{Rule: rules}
+		} else {
+			patch.NullFields = append(patch.NullFields, "Lifecycle")
+		}
+		changed = true
+	}
+
+	if !reflect.DeepEqual(settings.Labels, current.Labels) {
+		patch.Labels = settings.Labels
+
+		// labels are merged by patching, the removed ones have to be deleted explicitly
+		for key := range current.Labels {
+			if _, ok := settings.Labels[key]; !ok {
+				patch.NullFields = append(patch.NullFields, "Labels."+key)
+			}
+		}
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	service, err := apiStorage.New(oauth2.NewClient(ctx, credentials.TokenSource))
+	if err != nil {
+		return errors.Wrap(err, "failed to create storage service")
+	}
+
+	_, err = service.Buckets.Patch(bucketName, patch).Context(ctx).Do()
+
+	return errors.Wrap(err, "failed to patch bucket")
+}
+
 // DeleteBucket deletes the GS bucket identified by the specified name
 // provided the storage container is of 'managed' type.
 func (s *ObjectStore) DeleteBucket(bucketName string) error {
@@ -291,6 +536,8 @@ func (s *ObjectStore) ListBuckets() ([]*objectstore.BucketInfo, error) {
 		})
 		if idx < len(objectStores) && strings.Compare(objectStores[idx].Name, bucket.Name) == 0 {
 			bucketInfo.Managed = true
+			bucketInfo.Settings = &objectStores[idx].Settings
+			bucketInfo.Status = objectStores[idx].Status
 		}
 
 		bucketList = append(bucketList, bucketInfo)
@@ -322,3 +569,40 @@ func (s *ObjectStore) searchCriteria(bucketName string) *ObjectStoreBucketModel
 		Name:           bucketName,
 	}
 }
+
+// ManagedBuckets returns the GS buckets managed by Pipeline in every organization.
+func ManagedBuckets(db *gorm.DB) ([]objectstore.ManagedBucket, error) {
+	var buckets []*ObjectStoreBucketModel
+
+	if err := db.Find(&buckets).Error; err != nil {
+		return nil, errors.Wrap(err, "retrieving managed buckets failed")
+	}
+
+	managedBuckets := make([]objectstore.ManagedBucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		managedBuckets = append(managedBuckets, objectstore.ManagedBucket{
+			ID:             bucket.ID,
+			OrganizationID: bucket.OrganizationID,
+			SecretID:       bucket.SecretID,
+			Name:           bucket.Name,
+			Location:       bucket.Location,
+			Status:         bucket.Status,
+		})
+	}
+
+	return managedBuckets, nil
+}
+
+// SetBucketStatus updates the status of the managed GS bucket with the given ID.
+func SetBucketStatus(db *gorm.DB, id uint, status string) error {
+	err := db.Model(&ObjectStoreBucketModel{ID: id}).Update("status", status).Error
+
+	return errors.Wrap(err, "updating bucket status failed")
+}
+
+// SetBucketSecretID records the secret of the managed GS bucket with the given ID.
+func SetBucketSecretID(db *gorm.DB, id uint, secretID string) error {
+	err := db.Model(&ObjectStoreBucketModel{ID: id}).Update("secret_id", secretID).Error
+
+	return errors.Wrap(err, "updating bucket secret failed")
+}
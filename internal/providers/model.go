@@ -20,6 +20,7 @@ import (
 	"github.com/banzaicloud/pipeline/internal/providers/azure"
 	"github.com/banzaicloud/pipeline/internal/providers/google"
 	"github.com/banzaicloud/pipeline/internal/providers/oracle"
+	"github.com/banzaicloud/pipeline/internal/providers/s3compatible"
 	"github.com/jinzhu/gorm"
 	"github.com/sirupsen/logrus"
 )
@@ -46,5 +47,9 @@ func Migrate(db *gorm.DB, logger logrus.FieldLogger) error {
 		return err
 	}
 
+	if err := s3compatible.Migrate(db, logger); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -23,8 +23,19 @@ import (
 	"github.com/banzaicloud/pipeline/internal/providers/azure"
 	"github.com/banzaicloud/pipeline/internal/providers/google"
 	"github.com/banzaicloud/pipeline/internal/providers/oracle"
+	"github.com/banzaicloud/pipeline/internal/providers/s3compatible"
 	pkgErrors "github.com/banzaicloud/pipeline/pkg/errors"
+	commonObjectstore "github.com/banzaicloud/pipeline/pkg/objectstore"
 	"github.com/banzaicloud/pipeline/pkg/providers"
+	alibabaObjectstore "github.com/banzaicloud/pipeline/pkg/providers/alibaba/objectstore"
+	amazonObjectstore "github.com/banzaicloud/pipeline/pkg/providers/amazon/objectstore"
+	azureObjectstore "github.com/banzaicloud/pipeline/pkg/providers/azure/objectstore"
+	googleObjectstore "github.com/banzaicloud/pipeline/pkg/providers/google/objectstore"
+	oracleObjectstore "github.com/banzaicloud/pipeline/pkg/providers/oracle/objectstore"
+	oracleSecret "github.com/banzaicloud/pipeline/pkg/providers/oracle/secret"
+	s3compatibleObjectstore "github.com/banzaicloud/pipeline/pkg/providers/s3compatible/objectstore"
+	s3compatibleSecret "github.com/banzaicloud/pipeline/pkg/providers/s3compatible/secret"
+	pkgSecret "github.com/banzaicloud/pipeline/pkg/secret"
 	"github.com/banzaicloud/pipeline/secret"
 	"github.com/banzaicloud/pipeline/secret/verify"
 	"github.com/sirupsen/logrus"
@@ -60,11 +71,124 @@ func NewObjectStore(ctx *ObjectStoreContext, logger logrus.FieldLogger) (objects
 		return azure.NewObjectStore(ctx.Location, ctx.ResourceGroup, ctx.StorageAccount, ctx.Secret, ctx.Organization, db, logger), nil
 
 	case providers.Google:
-		return google.NewObjectStore(ctx.Organization, verify.CreateServiceAccount(ctx.Secret.Values), ctx.Location, db, logger), nil
+		return google.NewObjectStore(ctx.Organization, verify.CreateServiceAccount(ctx.Secret.Values), ctx.Secret.ID, ctx.Location, db, logger), nil
 
 	case providers.Oracle:
 		return oracle.NewObjectStore(ctx.Location, ctx.Secret, ctx.Organization, db, logger), nil
 
+	case providers.S3Compatible:
+		return s3compatible.NewObjectStore(ctx.Secret, ctx.Organization, db, logger)
+
+	default:
+		return nil, pkgErrors.ErrorNotSupportedCloudType
+	}
+}
+
+// ValidateBucketSettings checks the bucket settings against the constraints of the given cloud provider.
+func ValidateBucketSettings(provider string, settings objectstore.BucketSettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	switch provider {
+	case providers.Alibaba:
+		return alibaba.ValidateBucketSettings(settings)
+
+	case providers.Azure:
+		return azure.ValidateBucketSettings(settings)
+
+	case providers.Google:
+		return google.ValidateBucketSettings(settings)
+
+	case providers.Oracle:
+		return oracle.ValidateBucketSettings(settings)
+
+	case providers.S3Compatible:
+		return s3compatible.ValidateBucketSettings(settings)
+
+	default:
+		return nil
+	}
+}
+
+// NewObjectClient creates a client for the objects of the buckets of the given cloud provider.
+// Location is the region of the bucket, Azure requires the resource group and storage account of the container instead.
+// S3 compatible object stores are addressed by the endpoint of the secret.
+func NewObjectClient(ctx *ObjectStoreContext) (commonObjectstore.ObjectStore, error) {
+	values := ctx.Secret.Values
+
+	switch ctx.Provider {
+	case providers.Alibaba:
+		return alibabaObjectstore.New(
+			alibabaObjectstore.Config{Region: ctx.Location},
+			alibabaObjectstore.Credentials{
+				AccessKeyID:     values[pkgSecret.AlibabaAccessKeyId],
+				SecretAccessKey: values[pkgSecret.AlibabaSecretAccessKey],
+			},
+		)
+
+	case providers.Amazon:
+		return amazonObjectstore.New(
+			amazonObjectstore.Config{Region: ctx.Location},
+			amazonObjectstore.Credentials{
+				AccessKeyID:     values[pkgSecret.AwsAccessKeyId],
+				SecretAccessKey: values[pkgSecret.AwsSecretAccessKey],
+			},
+		)
+
+	case providers.Azure:
+		return azureObjectstore.New(
+			azureObjectstore.Config{
+				ResourceGroup:  ctx.ResourceGroup,
+				StorageAccount: ctx.StorageAccount,
+			},
+			azureObjectstore.Credentials{
+				SubscriptionID: values[pkgSecret.AzureSubscriptionId],
+				TenantID:       values[pkgSecret.AzureTenantId],
+				ClientID:       values[pkgSecret.AzureClientId],
+				ClientSecret:   values[pkgSecret.AzureClientSecret],
+			},
+		)
+
+	case providers.Google:
+		serviceAccount := verify.CreateServiceAccount(values)
+
+		return googleObjectstore.New(
+			googleObjectstore.Config{Region: ctx.Location},
+			googleObjectstore.Credentials{
+				Type:                   serviceAccount.Type,
+				ProjectID:              serviceAccount.ProjectId,
+				PrivateKeyID:           serviceAccount.PrivateKeyId,
+				PrivateKey:             serviceAccount.PrivateKey,
+				ClientEmail:            serviceAccount.ClientEmail,
+				ClientID:               serviceAccount.ClientId,
+				AuthURI:                serviceAccount.AuthUri,
+				TokenURI:               serviceAccount.TokenUri,
+				AuthProviderX50CertURL: serviceAccount.AuthProviderX50CertUrl,
+				ClientX509CertURL:      serviceAccount.ClientX509CertUrl,
+			},
+		)
+
+	case providers.Oracle:
+		return oracleObjectstore.New(
+			oracleObjectstore.Config{Region: ctx.Location},
+			oracleObjectstore.Credentials{
+				UserOCID:          values[oracleSecret.UserOCID],
+				TenancyOCID:       values[oracleSecret.TenancyOCID],
+				APIKey:            values[oracleSecret.APIKey],
+				APIKeyFingerprint: values[oracleSecret.APIKeyFingerprint],
+				CompartmentOCID:   values[oracleSecret.CompartmentOCID],
+			},
+		)
+
+	case providers.S3Compatible:
+		config, err := s3compatibleSecret.CreateConfig(values)
+		if err != nil {
+			return nil, err
+		}
+
+		return s3compatibleObjectstore.New(config, s3compatibleSecret.CreateCredentials(values))
+
 	default:
 		return nil, pkgErrors.ErrorNotSupportedCloudType
 	}
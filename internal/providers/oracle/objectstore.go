@@ -16,6 +16,8 @@ package oracle
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 
 	"github.com/banzaicloud/pipeline/auth"
 	"github.com/banzaicloud/pipeline/internal/objectstore"
@@ -23,10 +25,15 @@ import (
 	osecret "github.com/banzaicloud/pipeline/pkg/providers/oracle/secret"
 	"github.com/banzaicloud/pipeline/secret"
 	"github.com/jinzhu/gorm"
+	"github.com/oracle/oci-go-sdk/common"
+	"github.com/oracle/oci-go-sdk/objectstorage"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
+// archiveStorageClass is the only storage class objects can be transitioned to by lifecycle rules
+const archiveStorageClass = "ARCHIVE"
+
 type bucketNotFoundError struct{}
 
 func (bucketNotFoundError) Error() string  { return "bucket not found" }
@@ -60,8 +67,33 @@ func NewObjectStore(
 	}
 }
 
-// CreateBucket creates an Oracle object store bucket with the given name and stores it in the database
-func (o *ObjectStore) CreateBucket(name string) error {
+// ValidateBucketSettings checks if the settings are supported by Oracle object storage.
+// Every object is encrypted with Oracle managed keys and can be transitioned to the archive storage class only,
+// labels are set as free-form tags of the bucket.
+func ValidateBucketSettings(settings objectstore.BucketSettings) error {
+	if settings.Versioning {
+		return objectstore.UnsupportedSettingError{Provider: "Oracle", Setting: "versioning"}
+	}
+
+	if settings.Encryption != nil && settings.Encryption.KeyID != "" {
+		return objectstore.UnsupportedSettingError{Provider: "Oracle", Setting: "customer managed encryption key"}
+	}
+
+	for _, rule := range settings.LifecycleRules {
+		if rule.StorageClass != "" && strings.ToUpper(rule.StorageClass) != archiveStorageClass {
+			return objectstore.UnsupportedSettingError{Provider: "Oracle", Setting: "storage class " + rule.StorageClass}
+		}
+	}
+
+	return nil
+}
+
+// CreateBucket creates an Oracle object store bucket with the given name and settings and stores it in the database
+func (o *ObjectStore) CreateBucket(name string, settings objectstore.BucketSettings) error {
+	if err := ValidateBucketSettings(settings); err != nil {
+		return err
+	}
+
 	logger := o.getLogger().WithField("bucket", name)
 
 	oci, err := oci.NewOCI(osecret.CreateOCICredential(o.secret.Values))
@@ -97,6 +129,8 @@ func (o *ObjectStore) CreateBucket(name string) error {
 	bucket.Organization = *o.org
 	bucket.CompartmentID = oci.CompartmentOCID
 	bucket.Location = o.location
+	bucket.SecretID = o.secret.ID
+	bucket.Status = objectstore.BucketAvailable
 
 	if err = o.persistBucketToDB(bucket); err != nil {
 		return errors.Wrap(err, "error happened during persisting bucket description to DB")
@@ -112,6 +146,118 @@ func (o *ObjectStore) CreateBucket(name string) error {
 
 	logger.Infof("%s bucket created", name)
 
+	if err := applySettings(client, name, objectstore.BucketSettings{}, settings); err != nil {
+		if e := client.DeleteBucket(name); e != nil {
+			logger.Error(e.Error())
+		} else if e := o.deleteBucketFromDB(bucket); e != nil {
+			logger.Error(e.Error())
+		}
+
+		return errors.Wrap(err, "could not apply bucket settings (rolling back)")
+	}
+
+	bucket.Settings = settings
+	if err = o.persistBucketToDB(bucket); err != nil {
+		return errors.Wrap(err, "error happened during persisting bucket settings to DB")
+	}
+
+	return nil
+}
+
+// UpdateBucketSettings applies the changed settings to the managed Oracle object store bucket with the given name
+func (o *ObjectStore) UpdateBucketSettings(name string, settings objectstore.BucketSettings) error {
+	if err := ValidateBucketSettings(settings); err != nil {
+		return err
+	}
+
+	logger := o.getLogger().WithField("bucket", name)
+
+	oci, err := oci.NewOCI(osecret.CreateOCICredential(o.secret.Values))
+	if err != nil {
+		return errors.Wrap(err, "OCI client initialization failed")
+	}
+
+	bucket := &ObjectStoreBucketModel{}
+	searchCriteria := o.newBucketSearchCriteria(name, o.location, oci.CompartmentOCID)
+	if err := o.getBucketFromDB(searchCriteria, bucket); err != nil {
+		return err
+	}
+
+	err = oci.ChangeRegion(o.location)
+	if err != nil {
+		return errors.Wrap(err, "changing region failed")
+	}
+
+	client, err := oci.NewObjectStorageClient()
+	if err != nil {
+		return errors.Wrap(err, "creating Oracle object storage client failed")
+	}
+
+	logger.Info("updating bucket settings")
+
+	if err := applySettings(client, name, bucket.Settings, settings); err != nil {
+		return errors.Wrap(err, "could not apply bucket settings")
+	}
+
+	bucket.Settings = settings
+	if err = o.persistBucketToDB(bucket); err != nil {
+		return errors.Wrap(err, "error happened during persisting bucket settings to DB")
+	}
+
+	return nil
+}
+
+// applySettings applies the lifecycle rules and labels to the bucket if they differ from the current ones
+func applySettings(client *oci.ObjectStorage, name string, current, settings objectstore.BucketSettings) error {
+	if !reflect.DeepEqual(settings.LifecycleRules, current.LifecycleRules) {
+		if len(settings.LifecycleRules) == 0 {
+			if err := client.DeleteObjectLifecyclePolicy(name); err != nil {
+				return err
+			}
+		} else {
+			var rules []objectstorage.ObjectLifecycleRule
+
+			for _, rule := range settings.LifecycleRules {
+				var filter *objectstorage.ObjectNameFilter
+				if rule.Prefix != "" {
+					filter = &objectstorage.ObjectNameFilter{InclusionPrefixes: []string{rule.Prefix}}
+				}
+
+				if rule.TransitionDays > 0 {
+					rules = append(rules, objectstorage.ObjectLifecycleRule{
+						Name:             common.String(rule.ID + "-archive"),
+						Action:           common.String("ARCHIVE"),
+						TimeAmount:       common.Int64(int64(rule.TransitionDays)),
+						TimeUnit:         objectstorage.ObjectLifecycleRuleTimeUnitDays,
+						IsEnabled:        common.Bool(true),
+						ObjectNameFilter: filter,
+					})
+				}
+
+				if rule.ExpirationDays > 0 {
+					rules = append(rules, objectstorage.ObjectLifecycleRule{
+						Name:             common.String(rule.ID),
+						Action:           common.String("DELETE"),
+						TimeAmount:       common.Int64(int64(rule.ExpirationDays)),
+						TimeUnit:         objectstorage.ObjectLifecycleRuleTimeUnitDays,
+						IsEnabled:        common.Bool(true),
+						ObjectNameFilter: filter,
+					})
+				}
+			}
+
+			if err := client.PutObjectLifecyclePolicy(name, rules); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !reflect.DeepEqual(settings.Labels, current.Labels) {
+		if err := client.UpdateBucketTags(name, settings.Labels); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -240,6 +386,69 @@ func (o *ObjectStore) CheckBucket(name string) error {
 	return err
 }
 
+// ImportBucket registers the existing Oracle object store bucket with the given name as managed by Pipeline
+func (o *ObjectStore) ImportBucket(name string) error {
+
+	logger := o.getLogger().WithField("bucket", name)
+
+	if err := o.CheckBucket(name); err != nil {
+		if ociErr, ok := err.(common.ServiceError); ok && ociErr.GetCode() == "BucketNotFound" {
+			return bucketNotFoundError{}
+		}
+
+		return err
+	}
+
+	oci, err := oci.NewOCI(osecret.CreateOCICredential(o.secret.Values))
+	if err != nil {
+		return errors.Wrap(err, "OCI client initialization failed")
+	}
+
+	bucket := &ObjectStoreBucketModel{}
+	searchCriteria := o.newBucketSearchCriteria(name, o.location, oci.CompartmentOCID)
+	if err := o.getBucketFromDB(searchCriteria, bucket); err != nil {
+		if _, ok := err.(bucketNotFoundError); !ok {
+			return errors.Wrap(err, "Error happened during getting bucket description from DB")
+		}
+	}
+
+	bucket.Name = name
+	bucket.Organization = *o.org
+	bucket.CompartmentID = oci.CompartmentOCID
+	bucket.Location = o.location
+	bucket.SecretID = o.secret.ID
+	bucket.Status = objectstore.BucketAvailable
+
+	logger.Info("Importing bucket")
+
+	if err := o.persistBucketToDB(bucket); err != nil {
+		return errors.Wrap(err, "error happened during persisting bucket description to DB")
+	}
+
+	return nil
+}
+
+// ForgetBucket removes the managed bucket with the given name from the database without deleting it from Oracle object store
+func (o *ObjectStore) ForgetBucket(name string) error {
+
+	logger := o.getLogger().WithField("bucket", name)
+
+	oci, err := oci.NewOCI(osecret.CreateOCICredential(o.secret.Values))
+	if err != nil {
+		return errors.Wrap(err, "OCI client initialization failed")
+	}
+
+	bucket := &ObjectStoreBucketModel{}
+	searchCriteria := o.newBucketSearchCriteria(name, o.location, oci.CompartmentOCID)
+	if err := o.getBucketFromDB(searchCriteria, bucket); err != nil {
+		return err
+	}
+
+	logger.Info("Forgetting bucket")
+
+	return o.deleteBucketFromDB(bucket)
+}
+
 // markManagedBucket marks buckets exists in the database to 'managed'
 func (o *ObjectStore) markManagedBuckets(buckets []*objectstore.BucketInfo, compartmentID string) error {
 
@@ -252,23 +461,62 @@ func (o *ObjectStore) markManagedBuckets(buckets []*objectstore.BucketInfo, comp
 	}
 
 	// make map for search
-	mBuckets := make(map[string]string, 0)
-	for _, mBucket := range managedBuckets {
+	mBuckets := make(map[string]*ObjectStoreBucketModel, 0)
+	for i, mBucket := range managedBuckets {
 		key := fmt.Sprintf("%s-%s-%s", mBucket.Name, mBucket.Location, mBucket.CompartmentID)
-		mBuckets[key] = key
+		mBuckets[key] = &managedBuckets[i]
 	}
 
 	logger.Debug("Marking managed buckets")
 	for _, bucketInfo := range buckets {
 		key := fmt.Sprintf("%s-%s-%s", bucketInfo.Name, bucketInfo.Location, compartmentID)
-		if mBuckets[key] == key {
+		if mBucket, ok := mBuckets[key]; ok {
 			bucketInfo.Managed = true
+			bucketInfo.Settings = &mBucket.Settings
+			bucketInfo.Status = mBucket.Status
 		}
 	}
 
 	return nil
 }
 
+// ManagedBuckets returns the Oracle object store buckets managed by Pipeline in every organization
+func ManagedBuckets(db *gorm.DB) ([]objectstore.ManagedBucket, error) {
+	var buckets []*ObjectStoreBucketModel
+
+	if err := db.Find(&buckets).Error; err != nil {
+		return nil, errors.Wrap(err, "retrieving managed buckets failed")
+	}
+
+	managedBuckets := make([]objectstore.ManagedBucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		managedBuckets = append(managedBuckets, objectstore.ManagedBucket{
+			ID:             bucket.ID,
+			OrganizationID: bucket.OrgID,
+			SecretID:       bucket.SecretID,
+			Name:           bucket.Name,
+			Location:       bucket.Location,
+			Status:         bucket.Status,
+		})
+	}
+
+	return managedBuckets, nil
+}
+
+// SetBucketStatus updates the status of the managed Oracle object store bucket with the given ID
+func SetBucketStatus(db *gorm.DB, id uint, status string) error {
+	err := db.Model(&ObjectStoreBucketModel{ID: id}).Update("status", status).Error
+
+	return errors.Wrap(err, "updating bucket status failed")
+}
+
+// SetBucketSecretID records the secret of the managed Oracle object store bucket with the given ID
+func SetBucketSecretID(db *gorm.DB, id uint, secretID string) error {
+	err := db.Model(&ObjectStoreBucketModel{ID: id}).Update("secret_id", secretID).Error
+
+	return errors.Wrap(err, "updating bucket secret failed")
+}
+
 // getBucketsFromDB gives back object store buckets from DB
 func (o *ObjectStore) getBucketsFromDB() ([]ObjectStoreBucketModel, error) {
 
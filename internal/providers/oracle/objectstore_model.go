@@ -16,6 +16,7 @@ package oracle
 
 import (
 	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/internal/objectstore"
 )
 
 // TableName constants
@@ -33,6 +34,12 @@ type ObjectStoreBucketModel struct {
 	CompartmentID string `gorm:"unique_index:idx_bucket_name_location_compartment"`
 	Name          string `gorm:"unique_index:idx_bucket_name_location_compartment"`
 	Location      string `gorm:"unique_index:idx_bucket_name_location_compartment"`
+
+	// SecretID is the secret the bucket was created or imported with
+	SecretID string
+	Status   string
+
+	Settings objectstore.BucketSettings `gorm:"type:text"`
 }
 
 // TableName changes the default table name.
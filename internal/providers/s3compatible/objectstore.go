@@ -0,0 +1,394 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3compatible
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/internal/objectstore"
+	commonObjectstore "github.com/banzaicloud/pipeline/pkg/objectstore"
+	s3compatibleObjectstore "github.com/banzaicloud/pipeline/pkg/providers/s3compatible/objectstore"
+	s3compatibleSecret "github.com/banzaicloud/pipeline/pkg/providers/s3compatible/secret"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const providerName = "S3 compatible"
+
+type bucketNotFoundError struct{}
+
+func (bucketNotFoundError) Error() string  { return "bucket not found" }
+func (bucketNotFoundError) NotFound() bool { return true }
+
+type s3compatibleObjectStore interface {
+	commonObjectstore.ObjectStore
+
+	SetVersioning(bucketName string, enabled bool) error
+}
+
+// objectStore stores all required parameters for bucket creation.
+type objectStore struct {
+	objectStore s3compatibleObjectStore
+
+	secret *secret.SecretItemResponse
+
+	org *auth.Organization
+
+	db     *gorm.DB
+	logger logrus.FieldLogger
+}
+
+// NewObjectStore returns a new object store instance.
+func NewObjectStore(
+	secret *secret.SecretItemResponse,
+	org *auth.Organization,
+	db *gorm.DB,
+	logger logrus.FieldLogger,
+) (*objectStore, error) {
+	ostore, err := getProviderObjectStore(secret.Values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &objectStore{
+		objectStore: ostore,
+		secret:      secret,
+		org:         org,
+		db:          db,
+		logger:      logger,
+	}, nil
+}
+
+// getProviderObjectStore creates an S3 compatible object storage client from the values of a secret.
+func getProviderObjectStore(values map[string]string) (s3compatibleObjectStore, error) {
+	config, err := s3compatibleSecret.CreateConfig(values)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid S3 compatible secret")
+	}
+
+	ostore, err := s3compatibleObjectstore.New(config, s3compatibleSecret.CreateCredentials(values))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create S3 compatible object storage client")
+	}
+
+	return ostore, nil
+}
+
+// ValidateBucketSettings checks if the settings are supported by S3 compatible object stores.
+// Only versioning is part of the common subset implemented by them (eg. MinIO).
+func ValidateBucketSettings(settings objectstore.BucketSettings) error {
+	if settings.Encryption != nil {
+		return objectstore.UnsupportedSettingError{Provider: providerName, Setting: "encryption"}
+	}
+
+	if len(settings.LifecycleRules) > 0 {
+		return objectstore.UnsupportedSettingError{Provider: providerName, Setting: "lifecycle rules"}
+	}
+
+	if len(settings.Labels) > 0 {
+		return objectstore.UnsupportedSettingError{Provider: providerName, Setting: "labels"}
+	}
+
+	return nil
+}
+
+func (s *objectStore) getLogger() logrus.FieldLogger {
+	return s.logger.WithFields(logrus.Fields{
+		"organization": s.org.ID,
+		"secret":       s.secret.ID,
+		"endpoint":     s.secret.Values[s3compatibleSecret.Endpoint],
+	})
+}
+
+// CreateBucket creates a bucket with the provided name and settings.
+func (s *objectStore) CreateBucket(bucketName string, settings objectstore.BucketSettings) error {
+	if err := ValidateBucketSettings(settings); err != nil {
+		return err
+	}
+
+	logger := s.getLogger().WithField("bucket", bucketName)
+
+	bucket := &ObjectStoreBucketModel{}
+	searchCriteria := s.searchCriteria(bucketName)
+
+	if err := s.db.Where(searchCriteria).Find(bucket).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			return errors.Wrap(err, "error happened during getting bucket from DB")
+		}
+	}
+
+	bucket.Name = bucketName
+	bucket.Organization = *s.org
+	bucket.SecretID = s.secret.ID
+	bucket.Status = objectstore.BucketAvailable
+
+	if err := s.db.Save(bucket).Error; err != nil {
+		return errors.Wrap(err, "error happened during saving bucket in DB")
+	}
+
+	logger.Info("creating bucket")
+
+	if err := s.objectStore.CreateBucket(bucketName); err != nil {
+		e := s.db.Delete(bucket).Error
+		if e != nil {
+			logger.Error(e.Error())
+		}
+
+		return errors.Wrap(err, "could not create bucket (rolling back)")
+	}
+
+	logger.Info("bucket created")
+
+	if settings.Versioning {
+		if err := s.objectStore.SetVersioning(bucketName, true); err != nil {
+			return errors.Wrap(err, "could not apply bucket settings")
+		}
+	}
+
+	bucket.Settings = settings
+	if err := s.db.Save(bucket).Error; err != nil {
+		return errors.Wrap(err, "error happened during saving bucket settings in DB")
+	}
+
+	return nil
+}
+
+// UpdateBucketSettings applies the changed settings to the managed bucket identified by the specified name.
+func (s *objectStore) UpdateBucketSettings(bucketName string, settings objectstore.BucketSettings) error {
+	if err := ValidateBucketSettings(settings); err != nil {
+		return err
+	}
+
+	logger := s.getLogger().WithField("bucket", bucketName)
+
+	bucket := &ObjectStoreBucketModel{}
+	searchCriteria := s.searchCriteria(bucketName)
+
+	if err := s.db.Where(searchCriteria).Find(bucket).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return bucketNotFoundError{}
+		}
+
+		return errors.Wrap(err, "error happened during getting bucket from DB")
+	}
+
+	logger.Info("updating bucket settings")
+
+	if settings.Versioning != bucket.Settings.Versioning {
+		if err := s.objectStore.SetVersioning(bucketName, settings.Versioning); err != nil {
+			return errors.Wrap(err, "could not apply bucket settings")
+		}
+	}
+
+	bucket.Settings = settings
+	if err := s.db.Save(bucket).Error; err != nil {
+		return errors.Wrap(err, "error happened during saving bucket settings in DB")
+	}
+
+	return nil
+}
+
+// ImportBucket registers the existing bucket identified by the specified name as managed by Pipeline.
+func (s *objectStore) ImportBucket(bucketName string) error {
+	logger := s.getLogger().WithField("bucket", bucketName)
+
+	logger.Info("checking bucket")
+
+	if err := s.objectStore.CheckBucket(bucketName); err != nil {
+		return err
+	}
+
+	bucket := &ObjectStoreBucketModel{}
+	searchCriteria := s.searchCriteria(bucketName)
+
+	if err := s.db.Where(searchCriteria).Find(bucket).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			return errors.Wrap(err, "error happened during getting bucket from DB")
+		}
+	}
+
+	bucket.Name = bucketName
+	bucket.Organization = *s.org
+	bucket.SecretID = s.secret.ID
+	bucket.Status = objectstore.BucketAvailable
+
+	logger.Info("importing bucket")
+
+	if err := s.db.Save(bucket).Error; err != nil {
+		return errors.Wrap(err, "error happened during saving bucket in DB")
+	}
+
+	return nil
+}
+
+// ForgetBucket removes the bucket identified by the specified name from the managed ones
+// without deleting it.
+func (s *objectStore) ForgetBucket(bucketName string) error {
+	logger := s.getLogger().WithField("bucket", bucketName)
+
+	bucket := &ObjectStoreBucketModel{}
+	searchCriteria := s.searchCriteria(bucketName)
+
+	if err := s.db.Where(searchCriteria).Find(bucket).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return bucketNotFoundError{}
+		}
+
+		return errors.Wrap(err, "error happened during getting bucket from DB")
+	}
+
+	logger.Info("forgetting bucket")
+
+	if err := s.db.Delete(bucket).Error; err != nil {
+		return errors.Wrap(err, "deleting bucket from database failed")
+	}
+
+	return nil
+}
+
+// DeleteBucket deletes the bucket identified by the specified name
+// provided the bucket is of 'managed' type.
+func (s *objectStore) DeleteBucket(bucketName string) error {
+	logger := s.getLogger().WithField("bucket", bucketName)
+
+	bucket := &ObjectStoreBucketModel{}
+	searchCriteria := s.searchCriteria(bucketName)
+
+	logger.Info("looking for bucket")
+
+	if err := s.db.Where(searchCriteria).Find(bucket).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return bucketNotFoundError{}
+		}
+
+		return errors.Wrap(err, "error happened during getting bucket from DB")
+	}
+
+	logger.Info("deleting bucket")
+
+	if err := s.objectStore.DeleteBucket(bucketName); err != nil {
+		return err
+	}
+
+	if err := s.db.Delete(bucket).Error; err != nil {
+		return errors.Wrap(err, "deleting bucket from database failed")
+	}
+
+	return nil
+}
+
+// CheckBucket checks the status of the given bucket.
+func (s *objectStore) CheckBucket(bucketName string) error {
+	logger := s.getLogger().WithField("bucket", bucketName)
+
+	logger.Info("looking for bucket")
+
+	return s.objectStore.CheckBucket(bucketName)
+}
+
+// ListBuckets returns a list of buckets that can be accessed with the credentials
+// referenced by the secret field. Buckets that were created by a user in the current
+// org with the same secret are marked as 'managed'.
+func (s *objectStore) ListBuckets() ([]*objectstore.BucketInfo, error) {
+	logger := s.getLogger()
+
+	logger.Info("retrieving bucket list")
+
+	buckets, err := s.objectStore.ListBuckets()
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("retrieving managed buckets")
+
+	var managedBuckets []*ObjectStoreBucketModel
+
+	err = s.db.
+		Where(&ObjectStoreBucketModel{OrganizationID: s.org.ID, SecretID: s.secret.ID}).
+		Order("name asc").
+		Find(&managedBuckets).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "retrieving managed buckets failed")
+	}
+
+	var bucketList []*objectstore.BucketInfo
+	for _, bucket := range buckets {
+		// managedBuckets must be sorted in order to be able to perform binary search on it
+		idx := sort.Search(len(managedBuckets), func(i int) bool {
+			return strings.Compare(managedBuckets[i].Name, bucket) >= 0
+		})
+
+		bucketInfo := &objectstore.BucketInfo{Name: bucket, Managed: false}
+		if idx < len(managedBuckets) && strings.Compare(managedBuckets[idx].Name, bucket) == 0 {
+			bucketInfo.Managed = true
+			bucketInfo.Settings = &managedBuckets[idx].Settings
+			bucketInfo.Status = managedBuckets[idx].Status
+		}
+
+		bucketList = append(bucketList, bucketInfo)
+	}
+
+	return bucketList, nil
+}
+
+// searchCriteria returns the database search criteria to find bucket with the given name.
+// Buckets are looked up by the secret as well, since it determines the endpoint.
+func (s *objectStore) searchCriteria(bucketName string) *ObjectStoreBucketModel {
+	return &ObjectStoreBucketModel{
+		OrganizationID: s.org.ID,
+		SecretID:       s.secret.ID,
+		Name:           bucketName,
+	}
+}
+
+// ManagedBuckets returns the S3 compatible buckets managed by Pipeline in every organization.
+func ManagedBuckets(db *gorm.DB) ([]objectstore.ManagedBucket, error) {
+	var buckets []*ObjectStoreBucketModel
+
+	if err := db.Find(&buckets).Error; err != nil {
+		return nil, errors.Wrap(err, "retrieving managed buckets failed")
+	}
+
+	managedBuckets := make([]objectstore.ManagedBucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		managedBuckets = append(managedBuckets, objectstore.ManagedBucket{
+			ID:             bucket.ID,
+			OrganizationID: bucket.OrganizationID,
+			SecretID:       bucket.SecretID,
+			Name:           bucket.Name,
+			Status:         bucket.Status,
+		})
+	}
+
+	return managedBuckets, nil
+}
+
+// SetBucketStatus updates the status of the managed S3 compatible bucket with the given ID.
+func SetBucketStatus(db *gorm.DB, id uint, status string) error {
+	err := db.Model(&ObjectStoreBucketModel{ID: id}).Update("status", status).Error
+
+	return errors.Wrap(err, "updating bucket status failed")
+}
+
+// SetBucketSecretID records the secret of the managed S3 compatible bucket with the given ID.
+func SetBucketSecretID(db *gorm.DB, id uint, secretID string) error {
+	err := db.Model(&ObjectStoreBucketModel{ID: id}).Update("secret_id", secretID).Error
+
+	return errors.Wrap(err, "updating bucket secret failed")
+}
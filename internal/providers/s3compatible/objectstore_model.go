@@ -0,0 +1,47 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3compatible
+
+import (
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/internal/objectstore"
+)
+
+// TableName constants
+const (
+	bucketsTableName = "s3compatible_buckets"
+)
+
+// ObjectStoreBucketModel is the schema for the DB.
+type ObjectStoreBucketModel struct {
+	ID uint `gorm:"primary_key"`
+
+	Organization   auth.Organization `gorm:"foreignkey:OrganizationID"`
+	OrganizationID uint              `gorm:"index;not null;unique_index:idx_s3compatible_bucket_name"`
+
+	Name string `gorm:"unique_index:idx_s3compatible_bucket_name"`
+
+	// SecretID is the secret the bucket was created or imported with,
+	// it identifies the endpoint as the same bucket name can exist on several servers
+	SecretID string `gorm:"unique_index:idx_s3compatible_bucket_name"`
+	Status   string
+
+	Settings objectstore.BucketSettings `gorm:"type:text"`
+}
+
+// TableName changes the default table name.
+func (ObjectStoreBucketModel) TableName() string {
+	return bucketsTableName
+}
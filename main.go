@@ -15,25 +15,35 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/banzaicloud/go-gin-prometheus"
 	"github.com/banzaicloud/pipeline/api"
 	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/cluster"
 	"github.com/banzaicloud/pipeline/config"
 	"github.com/banzaicloud/pipeline/dns"
+	"github.com/banzaicloud/pipeline/dns/model"
 	"github.com/banzaicloud/pipeline/dns/route53/model"
+	"github.com/banzaicloud/pipeline/dns/zone/model"
 	"github.com/banzaicloud/pipeline/internal/audit"
+	"github.com/banzaicloud/pipeline/internal/backup"
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
 	"github.com/banzaicloud/pipeline/internal/dashboard"
 	ginternal "github.com/banzaicloud/pipeline/internal/platform/gin"
 	"github.com/banzaicloud/pipeline/internal/platform/gin/correlationid"
 	ginlog "github.com/banzaicloud/pipeline/internal/platform/gin/log"
+	intProviders "github.com/banzaicloud/pipeline/internal/providers"
 	"github.com/banzaicloud/pipeline/model"
 	"github.com/banzaicloud/pipeline/model/defaults"
 	"github.com/banzaicloud/pipeline/notify"
+	"github.com/banzaicloud/pipeline/pkg/providers"
+	"github.com/banzaicloud/pipeline/secret"
 	"github.com/banzaicloud/pipeline/spotguide"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -81,6 +91,13 @@ func main() {
 		logger.Panic(err.Error())
 	}
 
+	// Initialize the secret store
+	secretStore, err := secret.NewStore(viper.GetString(config.SecretStoreBackend), db, viper.GetString(config.SecretStoreSQLMasterKey))
+	if err != nil {
+		logger.Panic(err.Error())
+	}
+	secret.SetStore(secretStore)
+
 	casbinDSN, err := config.CasbinDSN()
 	if err != nil {
 		logger.Panic(err.Error())
@@ -103,6 +120,9 @@ func main() {
 		&auth.User{},
 		&auth.UserOrganization{},
 		&auth.Organization{},
+		&auth.ResourcePermission{},
+		&auth.TeamMember{},
+		&auth.APIToken{},
 		&defaults.EC2Profile{},
 		&defaults.EC2NodePoolProfile{},
 		&defaults.EKSProfile{},
@@ -112,6 +132,9 @@ func main() {
 		&defaults.GKEProfile{},
 		&defaults.GKENodePoolProfile{},
 		&route53model.Route53Domain{},
+		&zonemodel.ZoneDomain{},
+		&dnsmodel.CustomDomain{},
+		&dnsmodel.ManualRecord{},
 		&spotguide.SpotguideRepo{},
 	}
 
@@ -139,6 +162,90 @@ func main() {
 		panic(err)
 	}
 
+	// Resume cluster workflows interrupted by a restart
+	secretValidator := providers.NewSecretValidator(secretStore)
+	clusterManager := cluster.NewManager(intCluster.NewClusters(db), secretValidator, intCluster.NewWorkflows(db), log, errorHandler)
+	if err := clusterManager.ResumeWorkflows(context.Background()); err != nil {
+		errorHandler.Handle(errors.Wrap(err, "failed to resume cluster workflows"))
+	}
+
+	// Regenerate secrets with a rotation policy and update them in the clusters they are installed into
+	rotationScheduler := secret.NewRotationScheduler(
+		secretStore,
+		db,
+		viper.GetDuration(config.SecretRotationCheckInterval),
+		func(organizationID uint, secretID string) error {
+			return clusterManager.ResyncSecret(context.Background(), organizationID, secretID)
+		},
+		log,
+		errorHandler,
+	)
+	rotationScheduler.Start()
+
+	// Flag the managed buckets which were deleted out-of-band
+	if interval := viper.GetDuration(config.ObjectStoreReconcileInterval); interval > 0 {
+		bucketReconciler := intProviders.NewBucketReconciler(secretStore, db, interval, log, errorHandler)
+		bucketReconciler.Start()
+	}
+
+	// Back up the clusters with a backup schedule and delete their expired backups
+	backupManager := backup.NewManager(
+		db,
+		secretStore,
+		func(ctx context.Context, organizationID uint, clusterID uint) (backup.Cluster, error) {
+			return clusterManager.GetClusterByID(ctx, organizationID, clusterID)
+		},
+		log,
+		errorHandler,
+	)
+	backupScheduler := backup.NewScheduler(backupManager, viper.GetDuration(config.BackupScheduleCheckInterval), log, errorHandler)
+	backupScheduler.Start()
+
+	// Purge expired API tokens from the token store
+	go func() {
+		ticker := time.NewTicker(viper.GetDuration(config.AuthTokenGCInterval))
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := auth.PurgeExpiredTokens(); err != nil {
+				errorHandler.Handle(err)
+			}
+		}
+	}()
+
+	// Refresh short-lived container registry tokens installed into clusters
+	go func() {
+		ticker := time.NewTicker(viper.GetDuration(config.SecretRegistryRefreshInterval))
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := clusterManager.RefreshRegistrySecrets(context.Background()); err != nil {
+				errorHandler.Handle(errors.Wrap(err, "failed to refresh container registry secrets"))
+			}
+		}
+	}()
+
+	// Prune audit events older than the retention period
+	if retention := viper.GetDuration(config.AuditRetention); retention > 0 {
+		go func() {
+			ticker := time.NewTicker(viper.GetDuration(config.AuditRetentionCheckInterval))
+			defer ticker.Stop()
+
+			auditEvents := audit.NewEvents(db, viper.GetString("pipeline.basepath"))
+			for range ticker.C {
+				deleted, err := auditEvents.DeleteOlderThan(time.Now().Add(-retention))
+				if err != nil {
+					errorHandler.Handle(errors.Wrap(err, "failed to prune audit events"))
+					continue
+				}
+
+				if deleted > 0 {
+					log.Infof("pruned %d audit events older than %s", deleted, retention)
+				}
+			}
+		}()
+	}
+
 	// External DNS service
 	dnsSvc, err := dns.GetExternalDnsServiceClient()
 	if err != nil {
@@ -170,7 +277,26 @@ func main() {
 	router.Use(cors.New(config.GetCORS()))
 	if viper.GetBool("audit.enabled") {
 		log.Infoln("Audit enabled, installing Gin audit middleware")
-		router.Use(audit.LogWriter(skipPaths, viper.GetStringSlice("audit.headers"), db, log))
+
+		var sinkConfigs []audit.SinkConfig
+		if err := viper.UnmarshalKey(config.AuditSinks, &sinkConfigs); err != nil {
+			log.Errorf("Parsing audit sink configuration failed: %s", err.Error())
+			panic(err)
+		}
+
+		// Audit events are written into the database if no sinks are configured
+		if len(sinkConfigs) == 0 {
+			sinkConfigs = []audit.SinkConfig{{Type: audit.DatabaseSink}}
+		}
+
+		auditPublisher, err := audit.NewPublisher(sinkConfigs, db, log, errorHandler)
+		if err != nil {
+			log.Errorf("Creating audit sinks failed: %s", err.Error())
+			panic(err)
+		}
+		defer auditPublisher.Close()
+
+		router.Use(audit.LogWriter(skipPaths, viper.GetStringSlice("audit.headers"), auditPublisher, log))
 	}
 
 	root := router.Group("/")
@@ -220,6 +346,9 @@ func main() {
 			orgs.GET("/:orgid/clusters/:id/pods", api.GetPodDetails)
 			orgs.PUT("/:orgid/clusters/:id", api.UpdateCluster)
 			orgs.PUT("/:orgid/clusters/:id/posthooks", api.ReRunPostHooks)
+			orgs.GET("/:orgid/clusters/:id/posthooks", api.ListPostHooks)
+			orgs.POST("/:orgid/clusters/:id/posthooks/retry", api.RetryPostHooks)
+			orgs.GET("/:orgid/clusters/:id/events", api.ListClusterEvents)
 			orgs.POST("/:orgid/clusters/:id/secrets", api.InstallSecretsToCluster)
 			orgs.Any("/:orgid/clusters/:id/proxy/*path", api.ProxyToCluster)
 			orgs.DELETE("/:orgid/clusters/:id", api.DeleteCluster)
@@ -241,6 +370,15 @@ func main() {
 			orgs.PUT("/:orgid/clusters/:id/deployments/:name", api.UpgradeDeployment)
 			orgs.HEAD("/:orgid/clusters/:id/deployments/:name", api.HelmDeploymentStatus)
 			orgs.POST("/:orgid/clusters/:id/helminit", api.InitHelmOnCluster)
+			orgs.GET("/:orgid/clusters/:id/backups", api.ListClusterBackups)
+			orgs.POST("/:orgid/clusters/:id/backups", api.CreateBackup)
+			orgs.GET("/:orgid/clusters/:id/backups/:backupid", api.GetBackup)
+			orgs.DELETE("/:orgid/clusters/:id/backups/:backupid", api.DeleteBackup)
+			orgs.GET("/:orgid/clusters/:id/backupschedule", api.GetBackupSchedule)
+			orgs.PUT("/:orgid/clusters/:id/backupschedule", api.SetBackupSchedule)
+			orgs.DELETE("/:orgid/clusters/:id/backupschedule", api.DeleteBackupSchedule)
+			orgs.POST("/:orgid/clusters/:id/restore", api.RestoreBackup)
+			orgs.GET("/:orgid/backups", api.ListBackups)
 			orgs.GET("/:orgid/helm/repos", api.HelmReposGet)
 			orgs.POST("/:orgid/helm/repos", api.HelmReposAdd)
 			orgs.PUT("/:orgid/helm/repos/:name", api.HelmReposModify)
@@ -248,6 +386,12 @@ func main() {
 			orgs.DELETE("/:orgid/helm/repos/:name", api.HelmReposDelete)
 			orgs.GET("/:orgid/helm/charts", api.HelmCharts)
 			orgs.GET("/:orgid/helm/chart/:reponame/:name", api.HelmChart)
+			orgs.GET("/:orgid/posthooks", api.ListCustomPostHooks)
+			orgs.POST("/:orgid/posthooks", api.CreateCustomPostHook)
+			orgs.GET("/:orgid/posthooks/:name", api.GetCustomPostHook)
+			orgs.PUT("/:orgid/posthooks/:name", api.UpdateCustomPostHook)
+			orgs.DELETE("/:orgid/posthooks/:name", api.DeleteCustomPostHook)
+			orgs.GET("/:orgid/functions", api.ListOrganizationFunctions)
 			orgs.GET("/:orgid/profiles/cluster/:distribution", api.GetClusterProfiles)
 			orgs.POST("/:orgid/profiles/cluster", api.AddClusterProfile)
 			orgs.PUT("/:orgid/profiles/cluster", api.UpdateClusterProfile)
@@ -258,6 +402,26 @@ func main() {
 			orgs.PUT("/:orgid/secrets/:id", api.UpdateSecrets)
 			orgs.DELETE("/:orgid/secrets/:id", api.DeleteSecrets)
 			orgs.GET("/:orgid/secrets/:id/validate", api.ValidateSecret)
+			orgs.GET("/:orgid/secrets/:id/versions", api.ListSecretVersions)
+			orgs.GET("/:orgid/secrets/:id/versions/:version", api.GetSecretVersion)
+			orgs.POST("/:orgid/secrets/:id/rollback", api.RollbackSecret)
+			orgs.GET("/:orgid/secrets/:id/rotation", api.GetSecretRotationPolicy)
+			orgs.PUT("/:orgid/secrets/:id/rotation", api.SetSecretRotationPolicy)
+			orgs.DELETE("/:orgid/secrets/:id/rotation", api.DeleteSecretRotationPolicy)
+			orgs.GET("/:orgid/permissions", api.ListPermissions)
+			orgs.POST("/:orgid/permissions", api.GrantPermission)
+			orgs.DELETE("/:orgid/permissions/:id", api.RevokePermission)
+			orgs.GET("/:orgid/teams", api.ListTeams)
+			orgs.POST("/:orgid/teams/:team/users/:id", api.AddTeamMember)
+			orgs.DELETE("/:orgid/teams/:team/users/:id", api.RemoveTeamMember)
+			orgs.GET("/:orgid/audit", api.ListAuditEvents)
+			orgs.GET("/:orgid/audit/export", api.ExportAuditEvents)
+			orgs.GET("/:orgid/domain", api.GetOrgDomain)
+			orgs.PUT("/:orgid/domain", api.SetOrgDomain)
+			orgs.DELETE("/:orgid/domain", api.DeleteOrgDomain)
+			orgs.GET("/:orgid/domain/records", api.ListDomainRecords)
+			orgs.POST("/:orgid/domain/records", api.CreateDomainRecord)
+			orgs.DELETE("/:orgid/domain/records/:type/:name", api.DeleteDomainRecord)
 			orgs.GET("/:orgid/users", api.GetUsers)
 			orgs.GET("/:orgid/users/:id", api.GetUsers)
 			orgs.POST("/:orgid/users/:id", api.AddUser)
@@ -265,8 +429,15 @@ func main() {
 
 			orgs.GET("/:orgid/buckets", api.ListBuckets)
 			orgs.POST("/:orgid/buckets", api.CreateBucket)
+			orgs.POST("/:orgid/buckets/import", api.ImportBucket)
 			orgs.HEAD("/:orgid/buckets/:name", api.CheckBucket)
+			orgs.PUT("/:orgid/buckets/:name", api.UpdateBucket)
 			orgs.DELETE("/:orgid/buckets/:name", api.DeleteBucket)
+			orgs.GET("/:orgid/buckets/:name/objects", api.ListBucketObjects)
+			orgs.GET("/:orgid/buckets/:name/objects/*key", api.GetBucketObject)
+			orgs.PUT("/:orgid/buckets/:name/objects/*key", api.PutBucketObject)
+			orgs.DELETE("/:orgid/buckets/:name/objects/*key", api.DeleteBucketObject)
+			orgs.GET("/:orgid/buckets/:name/signedurl", api.GetBucketObjectSignedURL)
 
 			orgs.GET("/:orgid/cloudinfo", api.GetSupportedClusterList)
 			orgs.GET("/:orgid/cloudinfo/:cloudtype", api.GetCloudInfo)
@@ -15,19 +15,30 @@
 package main
 
 import (
+	"github.com/banzaicloud/pipeline/auth"
 	"github.com/banzaicloud/pipeline/internal/audit"
+	"github.com/banzaicloud/pipeline/internal/backup"
 	"github.com/banzaicloud/pipeline/internal/cluster"
 	"github.com/banzaicloud/pipeline/internal/providers"
+	"github.com/banzaicloud/pipeline/secret"
 	"github.com/jinzhu/gorm"
 	"github.com/sirupsen/logrus"
 )
 
 // Migrate runs migrations for the application.
 func Migrate(db *gorm.DB, logger logrus.FieldLogger) error {
+	if err := auth.Migrate(db, logger); err != nil {
+		return err
+	}
+
 	if err := audit.Migrate(db, logger); err != nil {
 		return err
 	}
 
+	if err := backup.Migrate(db, logger); err != nil {
+		return err
+	}
+
 	if err := cluster.Migrate(db, logger); err != nil {
 		return err
 	}
@@ -36,5 +47,9 @@ func Migrate(db *gorm.DB, logger logrus.FieldLogger) error {
 		return err
 	}
 
+	if err := secret.Migrate(db, logger); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -0,0 +1,50 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import "time"
+
+// Export formats
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+)
+
+// Event describes an audited API request
+type Event struct {
+	ID            uint      `json:"id"`
+	Time          time.Time `json:"time"`
+	CorrelationID string    `json:"correlationId,omitempty"`
+	UserID        uint      `json:"userId,omitempty"`
+	ClientIP      string    `json:"clientIp"`
+	UserAgent     string    `json:"userAgent,omitempty"`
+	Method        string    `json:"method"`
+	Path          string    `json:"path"`
+	ClusterID     uint      `json:"clusterId,omitempty"`
+	SecretID      string    `json:"secretId,omitempty"`
+	StatusCode    int       `json:"statusCode"`
+	Latency       int64     `json:"latency"` // milliseconds
+	ErrorMessage  string    `json:"errorMessage,omitempty"`
+	Body          *string   `json:"body,omitempty"`
+}
+
+// EventsResponse describes Pipeline's ListAuditEvents API response
+type EventsResponse struct {
+	Events []Event `json:"events"`
+
+	// NextCursor should be passed as the cursor query parameter to get the next page, it is omitted on the last page
+	NextCursor uint `json:"nextCursor,omitempty"`
+	Limit      int  `json:"limit"`
+}
@@ -0,0 +1,111 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import "time"
+
+// Backup statuses
+const (
+	StatusRunning   = "RUNNING"
+	StatusCompleted = "COMPLETED"
+	StatusFailed    = "FAILED"
+)
+
+// Bucket identifies the object store bucket backups are stored in, the cloud provider is the type of the secret
+type Bucket struct {
+	Provider string `json:"provider,omitempty"`
+	SecretID string `json:"secretId" binding:"required"`
+	Name     string `json:"name" binding:"required"`
+
+	// Location (or region) of the bucket, required by Alibaba, Amazon and Oracle
+	Location string `json:"location,omitempty"`
+
+	// Azure specific parameters
+	ResourceGroup  string `json:"resourceGroup,omitempty"`
+	StorageAccount string `json:"storageAccount,omitempty"`
+}
+
+// Filter selects the Kubernetes resources of a backup.
+// System namespaces are only backed up if they are listed explicitly.
+type Filter struct {
+	Namespaces    []string `json:"namespaces,omitempty"`
+	LabelSelector string   `json:"labelSelector,omitempty"`
+}
+
+// CreateBackupRequest describes a CreateBackup API request
+type CreateBackupRequest struct {
+	Bucket Bucket `json:"bucket" binding:"required"`
+	Filter
+}
+
+// Backup describes a backup of the Kubernetes resources and Helm releases of a cluster
+type Backup struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	ClusterID   uint   `json:"clusterId"`
+	ClusterName string `json:"clusterName"`
+	Status      string `json:"status"`
+	Message     string `json:"message,omitempty"`
+	Bucket      Bucket `json:"bucket"`
+	Filter
+
+	// ScheduleID is set if the backup was created by the backup schedule of the cluster
+	ScheduleID uint `json:"scheduleId,omitempty"`
+
+	Resources int   `json:"resources"`
+	Releases  int   `json:"releases"`
+	Size      int64 `json:"size"`
+
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// RestoreRequest describes a RestoreBackup API request, the backup can be of any cluster of the organization
+type RestoreRequest struct {
+	BackupID uint `json:"backupId" binding:"required"`
+}
+
+// RestoreResponse summarizes the replay of a backup onto a cluster
+type RestoreResponse struct {
+	BackupID uint `json:"backupId"`
+
+	// Created is the number of created resources, resources which already exist are skipped
+	Created int      `json:"created"`
+	Skipped int      `json:"skipped"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// ScheduleRequest describes a SetBackupSchedule API request
+type ScheduleRequest struct {
+	Interval string `json:"interval" binding:"required"`
+
+	// Retention is the number of completed scheduled backups kept, older ones are deleted
+	Retention int    `json:"retention" binding:"required"`
+	Bucket    Bucket `json:"bucket" binding:"required"`
+	Filter
+}
+
+// ScheduleResponse describes the backup schedule of a cluster
+type ScheduleResponse struct {
+	ID        uint   `json:"id"`
+	Interval  string `json:"interval"`
+	Retention int    `json:"retention"`
+	Bucket    Bucket `json:"bucket"`
+	Filter
+
+	NextBackupAt time.Time  `json:"nextBackupAt"`
+	LastBackupAt *time.Time `json:"lastBackupAt,omitempty"`
+	LastError    string     `json:"lastError,omitempty"`
+}
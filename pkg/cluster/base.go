@@ -17,6 +17,7 @@ package cluster
 import (
 	"bytes"
 	"fmt"
+	"time"
 
 	"github.com/banzaicloud/pipeline/pkg/cluster/acsk"
 	"github.com/banzaicloud/pipeline/pkg/cluster/aks"
@@ -165,12 +166,89 @@ type GetClusterStatusResponse struct {
 	Version       string                     `json:"version,omitempty"`
 	ResourceID    uint                       `json:"id"`
 	NodePools     map[string]*NodePoolStatus `json:"nodePools,omitempty"`
+	Workflow      *WorkflowStatus            `json:"workflow,omitempty"`
 	pkgCommon.CreatorBaseFields
 
 	// ONLY in case of GKE
 	Region string `json:"region,omitempty"`
 }
 
+// WorkflowStatus describes the last lifecycle workflow (create, update or delete) of a cluster
+type WorkflowStatus struct {
+	Type          string    `json:"type"`
+	Status        string    `json:"status"`
+	CurrentStep   string    `json:"currentStep,omitempty"`
+	Steps         []string  `json:"steps"`
+	FinishedSteps int       `json:"finishedSteps"`
+	ErrorMessage  string    `json:"errorMessage,omitempty"`
+	StartedAt     time.Time `json:"startedAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+
+	// PostHooks are the posthook functions run by the workflow, several of them may be running at the same time
+	PostHooks []WorkflowPostHookStatus `json:"postHooks,omitempty"`
+}
+
+// WorkflowPostHookStatus describes the status of a posthook function run by a cluster workflow
+type WorkflowPostHookStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status,omitempty"`
+}
+
+// ClusterEvent describes an entry of a cluster's lifecycle event history
+type ClusterEvent struct {
+	ID        uint      `json:"id"`
+	Time      time.Time `json:"time"`
+	ClusterID uint      `json:"clusterId"`
+	UserID    uint      `json:"userId,omitempty"`
+	Type      string    `json:"type"`
+	Status    string    `json:"status,omitempty"`
+	Message   string    `json:"message,omitempty"`
+}
+
+// ClusterEventsResponse describes Pipeline's ListClusterEvents API response
+type ClusterEventsResponse struct {
+	Events []ClusterEvent `json:"events"`
+	Page   int            `json:"page"`
+	Limit  int            `json:"limit"`
+}
+
+// PostHookStatus describes the outcome of a posthook function run on a cluster
+type PostHookStatus struct {
+	Name         string        `json:"name"`
+	Position     int           `json:"position"`
+	Params       PostHookParam `json:"params,omitempty"`
+	Status       string        `json:"status"`
+	Attempts     int           `json:"attempts"`
+	StartedAt    *time.Time    `json:"startedAt,omitempty"`
+	FinishedAt   *time.Time    `json:"finishedAt,omitempty"`
+	Duration     string        `json:"duration,omitempty"`
+	ErrorMessage string        `json:"errorMessage,omitempty"`
+}
+
+// RetryPostHooksResponse describes Pipeline's RetryPostHooks API response
+type RetryPostHooksResponse struct {
+	PostHooks []string `json:"posthooks"`
+}
+
+// CustomPostHookRequest describes an organization defined posthook installing a chart from the organization's Helm repositories
+type CustomPostHookRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	Chart        string   `json:"chart" binding:"required"`
+	ChartVersion string   `json:"chartVersion,omitempty"`
+	ReleaseName  string   `json:"releaseName,omitempty"`
+	Namespace    string   `json:"namespace" binding:"required"`
+	Values       string   `json:"values,omitempty"`
+	Secrets      []string `json:"secrets,omitempty"`
+}
+
+// CustomPostHookResponse describes an organization defined posthook
+type CustomPostHookResponse struct {
+	CustomPostHookRequest
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	CreatedBy uint      `json:"createdBy,omitempty"`
+}
+
 // NodePoolStatus describes cluster's node status
 type NodePoolStatus struct {
 	Autoscaling  bool   `json:"autoscaling,omitempty"`
@@ -0,0 +1,139 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import "strings"
+
+// Record types which can be created manually
+const (
+	RecordTypeA     = "A"
+	RecordTypeAAAA  = "AAAA"
+	RecordTypeCNAME = "CNAME"
+	RecordTypeTXT   = "TXT"
+)
+
+// ManualRecordTypes lists the record types which can be created manually
+var ManualRecordTypes = []string{RecordTypeA, RecordTypeAAAA, RecordTypeCNAME, RecordTypeTXT}
+
+// DefaultRecordTTL is the TTL of manual records in seconds if it is not specified
+const DefaultRecordTTL = 300
+
+// DomainRequest describes the custom domain to attach to an organization.
+// If SecretID is set the existing zone of the domain is accessed with the cloud secret,
+// otherwise Pipeline creates the zone and the domain has to be delegated to its name servers.
+type DomainRequest struct {
+	Domain        string `json:"domain" binding:"required"`
+	SecretID      string `json:"secretId,omitempty"`
+	ResourceGroup string `json:"resourceGroup,omitempty"`
+}
+
+// DomainResponse describes the domain external-dns manages the records of an organization under
+type DomainResponse struct {
+	Domain        string   `json:"domain"`
+	Custom        bool     `json:"custom"`
+	Managed       bool     `json:"managed"`
+	SecretID      string   `json:"secretId,omitempty"`
+	ResourceGroup string   `json:"resourceGroup,omitempty"`
+	NameServers   []string `json:"nameServers,omitempty"`
+	Status        string   `json:"status,omitempty"`
+	ErrorMessage  string   `json:"errorMessage,omitempty"`
+}
+
+// DomainState describes the registration of the domain of an organization in the DNS service
+type DomainState struct {
+	Domain       string
+	Status       string
+	ErrorMessage string
+}
+
+// Record describes a record set of the zone of an organization domain
+type Record struct {
+	// Name is the fully qualified name of the record without the trailing dot
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	TTL    int64    `json:"ttl"`
+	Values []string `json:"values"`
+
+	// Owner is the owner id (the UID of the cluster) of the external-dns instance managing the record
+	Owner string `json:"owner,omitempty"`
+
+	// Manual is true for the records created through the API, these are left alone by external-dns and the garbage collector
+	Manual bool `json:"manual"`
+}
+
+// RecordRequest describes a manual record to create in the zone of an organization domain
+type RecordRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Type   string   `json:"type" binding:"required"`
+	TTL    int64    `json:"ttl,omitempty"`
+	Values []string `json:"values" binding:"required"`
+}
+
+// RecordsResponse lists the records of the zone of an organization domain
+type RecordsResponse struct {
+	Domain  string   `json:"domain"`
+	Records []Record `json:"records"`
+}
+
+// RecordOwner returns the owner id an external-dns registry TXT record refers to, empty if it is not a registry record
+func RecordOwner(txt string) string {
+	var heritage bool
+	var owner string
+
+	for _, label := range strings.Split(strings.Trim(txt, `"`), ",") {
+		if label == "heritage=external-dns" {
+			heritage = true
+		} else if strings.HasPrefix(label, "external-dns/owner=") {
+			owner = strings.TrimPrefix(label, "external-dns/owner=")
+		}
+	}
+
+	if !heritage {
+		return ""
+	}
+
+	return owner
+}
+
+// SetRecordOwners sets the owner of the records managed by external-dns based on the registry TXT records having the same name
+func SetRecordOwners(records []Record) {
+	owners := make(map[string]string)
+
+	for _, record := range records {
+		if record.Type != RecordTypeTXT {
+			continue
+		}
+
+		for _, value := range record.Values {
+			if owner := RecordOwner(value); owner != "" {
+				owners[record.Name] = owner
+				break
+			}
+		}
+	}
+
+	for i := range records {
+		records[i].Owner = owners[records[i].Name]
+	}
+}
+
+// QuoteTXT returns the value of a TXT record enclosed in quotes as Route53 and Cloud DNS expect it
+func QuoteTXT(value string) string {
+	if strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) && len(value) > 1 {
+		return value
+	}
+
+	return `"` + strings.Replace(value, `"`, `\"`, -1) + `"`
+}
@@ -0,0 +1,55 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetRecordOwners(t *testing.T) {
+	records := []Record{
+		{Name: "app.example.com", Type: RecordTypeA, Values: []string{"10.0.0.1"}},
+		{Name: "app.example.com", Type: RecordTypeTXT, Values: []string{`"heritage=external-dns,external-dns/owner=cluster-1,external-dns/resource=service/default/app"`}},
+		{Name: "manual.example.com", Type: RecordTypeCNAME, Values: []string{"app.example.com"}},
+		{Name: "spf.example.com", Type: RecordTypeTXT, Values: []string{`"external-dns/owner=cluster-2"`}},
+	}
+
+	SetRecordOwners(records)
+
+	var owners []string
+	for _, record := range records {
+		owners = append(owners, record.Owner)
+	}
+
+	expected := []string{"cluster-1", "cluster-1", "", ""}
+	if !reflect.DeepEqual(owners, expected) {
+		t.Errorf("expected owners %v, got %v", expected, owners)
+	}
+}
+
+func TestQuoteTXT(t *testing.T) {
+	cases := map[string]string{
+		`v=spf1 -all`:   `"v=spf1 -all"`,
+		`"v=spf1 -all"`: `"v=spf1 -all"`,
+		`say "hi"`:      `"say \"hi\""`,
+	}
+
+	for value, expected := range cases {
+		if actual := QuoteTXT(value); actual != expected {
+			t.Errorf("QuoteTXT(%q): expected %q, got %q", value, expected, actual)
+		}
+	}
+}
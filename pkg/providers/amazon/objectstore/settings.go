@@ -0,0 +1,191 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objectstore
+
+import (
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/goph/emperror"
+)
+
+// LifecycleRule expires the objects having the given key prefix or transitions them to another storage class.
+type LifecycleRule struct {
+	ID             string
+	Prefix         string
+	ExpirationDays int64
+	TransitionDays int64
+	StorageClass   string
+}
+
+// SetVersioning enables or suspends the versioning of the bucket.
+func (s *objectStore) SetVersioning(bucketName string, enabled bool) error {
+	status := s3.BucketVersioningStatusSuspended
+	if enabled {
+		status = s3.BucketVersioningStatusEnabled
+	}
+
+	_, err := s.client.PutBucketVersioning(&s3.PutBucketVersioningInput{
+		Bucket: aws.String(bucketName),
+		VersioningConfiguration: &s3.VersioningConfiguration{
+			Status: aws.String(status),
+		},
+	})
+	if err != nil {
+		err = s.convertError(err)
+		return emperror.With(emperror.Wrap(err, "could not set bucket versioning"), "bucket", bucketName)
+	}
+
+	return nil
+}
+
+// SetEncryption sets the default server-side encryption of the bucket.
+// Objects are encrypted with the given KMS key or with S3 managed keys if it is empty.
+func (s *objectStore) SetEncryption(bucketName string, enabled bool, kmsKeyID string) error {
+	if !enabled {
+		_, err := s.client.DeleteBucketEncryption(&s3.DeleteBucketEncryptionInput{
+			Bucket: aws.String(bucketName),
+		})
+		if err != nil {
+			err = s.convertError(err)
+			return emperror.With(emperror.Wrap(err, "could not delete bucket encryption"), "bucket", bucketName)
+		}
+
+		return nil
+	}
+
+	encryption := &s3.ServerSideEncryptionByDefault{
+		SSEAlgorithm: aws.String(s3.ServerSideEncryptionAes256),
+	}
+
+	if kmsKeyID != "" {
+		encryption.SSEAlgorithm = aws.String(s3.ServerSideEncryptionAwsKms)
+		encryption.KMSMasterKeyID = aws.String(kmsKeyID)
+	}
+
+	_, err := s.client.PutBucketEncryption(&s3.PutBucketEncryptionInput{
+		Bucket: aws.String(bucketName),
+		ServerSideEncryptionConfiguration: &s3.ServerSideEncryptionConfiguration{
+			Rules: []*s3.ServerSideEncryptionRule{
+				{ApplyServerSideEncryptionByDefault: encryption},
+			},
+		},
+	})
+	if err != nil {
+		err = s.convertError(err)
+		return emperror.With(emperror.Wrap(err, "could not set bucket encryption"), "bucket", bucketName)
+	}
+
+	return nil
+}
+
+// SetLifecycleRules replaces the lifecycle rules of the bucket.
+func (s *objectStore) SetLifecycleRules(bucketName string, rules []LifecycleRule) error {
+	if len(rules) == 0 {
+		_, err := s.client.DeleteBucketLifecycle(&s3.DeleteBucketLifecycleInput{
+			Bucket: aws.String(bucketName),
+		})
+		if err != nil {
+			err = s.convertError(err)
+			return emperror.With(emperror.Wrap(err, "could not delete bucket lifecycle rules"), "bucket", bucketName)
+		}
+
+		return nil
+	}
+
+	lifecycleRules := make([]*s3.LifecycleRule, 0, len(rules))
+	for _, rule := range rules {
+		lifecycleRule := &s3.LifecycleRule{
+			ID:     aws.String(rule.ID),
+			Status: aws.String(s3.ExpirationStatusEnabled),
+			Filter: &s3.LifecycleRuleFilter{
+				Prefix: aws.String(rule.Prefix),
+			},
+		}
+
+		if rule.ExpirationDays > 0 {
+			lifecycleRule.Expiration = &s3.LifecycleExpiration{
+				Days: aws.Int64(rule.ExpirationDays),
+			}
+		}
+
+		if rule.TransitionDays > 0 {
+			lifecycleRule.Transitions = []*s3.Transition{
+				{
+					Days:         aws.Int64(rule.TransitionDays),
+					StorageClass: aws.String(rule.StorageClass),
+				},
+			}
+		}
+
+		lifecycleRules = append(lifecycleRules, lifecycleRule)
+	}
+
+	_, err := s.client.PutBucketLifecycleConfiguration(&s3.PutBucketLifecycleConfigurationInput{
+		Bucket: aws.String(bucketName),
+		LifecycleConfiguration: &s3.BucketLifecycleConfiguration{
+			Rules: lifecycleRules,
+		},
+	})
+	if err != nil {
+		err = s.convertError(err)
+		return emperror.With(emperror.Wrap(err, "could not set bucket lifecycle rules"), "bucket", bucketName)
+	}
+
+	return nil
+}
+
+// SetTags replaces the tags of the bucket.
+func (s *objectStore) SetTags(bucketName string, tags map[string]string) error {
+	if len(tags) == 0 {
+		_, err := s.client.DeleteBucketTagging(&s3.DeleteBucketTaggingInput{
+			Bucket: aws.String(bucketName),
+		})
+		if err != nil {
+			err = s.convertError(err)
+			return emperror.With(emperror.Wrap(err, "could not delete bucket tags"), "bucket", bucketName)
+		}
+
+		return nil
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tagSet := make([]*s3.Tag, 0, len(tags))
+	for _, key := range keys {
+		tagSet = append(tagSet, &s3.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(tags[key]),
+		})
+	}
+
+	_, err := s.client.PutBucketTagging(&s3.PutBucketTaggingInput{
+		Bucket: aws.String(bucketName),
+		Tagging: &s3.Tagging{
+			TagSet: tagSet,
+		},
+	})
+	if err != nil {
+		err = s.convertError(err)
+		return emperror.With(emperror.Wrap(err, "could not set bucket tags"), "bucket", bucketName)
+	}
+
+	return nil
+}
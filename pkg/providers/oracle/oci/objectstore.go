@@ -91,6 +91,46 @@ func (os *ObjectStorage) DeleteBucket(name string) error {
 	return err
 }
 
+// UpdateBucketTags replaces the free-form tags of an Object Storage bucket
+func (os *ObjectStorage) UpdateBucketTags(name string, tags map[string]string) error {
+	if tags == nil {
+		tags = map[string]string{}
+	}
+
+	_, err := os.client.UpdateBucket(context.Background(), objectstorage.UpdateBucketRequest{
+		NamespaceName: &os.Namespace,
+		BucketName:    &name,
+		UpdateBucketDetails: objectstorage.UpdateBucketDetails{
+			FreeformTags: tags,
+		},
+	})
+
+	return err
+}
+
+// PutObjectLifecyclePolicy replaces the object lifecycle policy of an Object Storage bucket
+func (os *ObjectStorage) PutObjectLifecyclePolicy(name string, rules []objectstorage.ObjectLifecycleRule) error {
+	_, err := os.client.PutObjectLifecyclePolicy(context.Background(), objectstorage.PutObjectLifecyclePolicyRequest{
+		NamespaceName: &os.Namespace,
+		BucketName:    &name,
+		PutObjectLifecyclePolicyDetails: objectstorage.PutObjectLifecyclePolicyDetails{
+			Items: rules,
+		},
+	})
+
+	return err
+}
+
+// DeleteObjectLifecyclePolicy deletes the object lifecycle policy of an Object Storage bucket
+func (os *ObjectStorage) DeleteObjectLifecyclePolicy(name string) error {
+	_, err := os.client.DeleteObjectLifecyclePolicy(context.Background(), objectstorage.DeleteObjectLifecyclePolicyRequest{
+		NamespaceName: &os.Namespace,
+		BucketName:    &name,
+	})
+
+	return err
+}
+
 // GetBucket gets an Object Storage bucket by name
 func (os *ObjectStorage) GetBucket(name string) (bucket objectstorage.Bucket, err error) {
 
@@ -21,6 +21,7 @@ import (
 	"github.com/banzaicloud/pipeline/pkg/providers/azure"
 	"github.com/banzaicloud/pipeline/pkg/providers/google"
 	"github.com/banzaicloud/pipeline/pkg/providers/oracle"
+	"github.com/banzaicloud/pipeline/pkg/providers/s3compatible"
 )
 
 const (
@@ -29,6 +30,8 @@ const (
 	Azure   = azure.Provider
 	Google  = google.Provider
 	Oracle  = oracle.Provider
+
+	S3Compatible = s3compatible.Provider
 )
 
 // ValidateProvider validates if the passed cloud provider is supported.
@@ -40,6 +43,7 @@ func ValidateProvider(provider string) error {
 	case Google:
 	case Azure:
 	case Oracle:
+	case S3Compatible:
 	default:
 		// TODO: create an error value in this package instead
 		return pkgErrors.ErrorNotSupportedCloudType
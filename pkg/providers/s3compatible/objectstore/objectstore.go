@@ -0,0 +1,341 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objectstore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awsCredentials "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+)
+
+// DefaultRegion is used to sign the requests when no region is configured, most S3 compatible servers accept it
+const DefaultRegion = "us-east-1"
+
+type objectStore struct {
+	config      Config
+	credentials Credentials
+
+	client   *s3.S3
+	uploader *s3manager.Uploader
+}
+
+// Config defines configuration
+type Config struct {
+	// Endpoint is the URL of the S3 compatible API, eg. https://minio.example.com:9000
+	Endpoint string
+
+	// Region is used to sign the requests, defaults to DefaultRegion
+	Region string
+
+	// ForcePathStyle addresses buckets in the path (https://endpoint/bucket) instead of the host name (https://bucket.endpoint)
+	ForcePathStyle bool
+
+	// CACert is the PEM encoded certificate of the authority that signed the certificate of the endpoint
+	CACert string
+
+	// InsecureSkipVerify disables the verification of the certificate of the endpoint
+	InsecureSkipVerify bool
+}
+
+// Credentials represents credentials necessary for access
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// New returns an Object Store instance that manages the buckets of an S3 compatible object store (eg. MinIO).
+func New(config Config, credentials Credentials) (*objectStore, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("endpoint is required")
+	}
+
+	if config.Region == "" {
+		config.Region = DefaultRegion
+	}
+
+	httpClient, err := newHTTPClient(config)
+	if err != nil {
+		return nil, emperror.With(err, "endpoint", config.Endpoint)
+	}
+
+	sess, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(config.Endpoint),
+		Region:           aws.String(config.Region),
+		S3ForcePathStyle: aws.Bool(config.ForcePathStyle),
+		HTTPClient:       httpClient,
+		Credentials: awsCredentials.NewStaticCredentials(
+			credentials.AccessKeyID,
+			credentials.SecretAccessKey,
+			"",
+		),
+	})
+	if err != nil {
+		return nil, emperror.With(emperror.Wrap(err, "could not create S3 compatible session"), "endpoint", config.Endpoint)
+	}
+
+	return &objectStore{
+		config:      config,
+		credentials: credentials,
+
+		client:   s3.New(sess),
+		uploader: s3manager.NewUploader(sess),
+	}, nil
+}
+
+// newHTTPClient returns an HTTP client trusting the configured certificate authority in addition to the system ones.
+func newHTTPClient(config Config) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	if config.CACert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM([]byte(config.CACert)) {
+			return nil, errors.New("could not parse CA certificate")
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			TLSClientConfig:     tlsConfig,
+			TLSHandshakeTimeout: 10 * time.Second,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}, nil
+}
+
+// CreateBucket creates a new bucket in the object store.
+func (s *objectStore) CreateBucket(bucketName string) error {
+	_, err := s.client.CreateBucket(&s3.CreateBucketInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		err = s.convertError(err, bucketName, "")
+		return emperror.With(emperror.Wrap(err, "bucket creation failed"), "bucket", bucketName)
+	}
+
+	return nil
+}
+
+// ListBuckets lists the current buckets in the object store.
+func (s *objectStore) ListBuckets() ([]string, error) {
+	buckets, err := s.client.ListBuckets(&s3.ListBucketsInput{})
+	if err != nil {
+		return nil, emperror.Wrap(err, "could not list buckets")
+	}
+
+	var bucketList []string
+	for _, bucket := range buckets.Buckets {
+		bucketList = append(bucketList, *bucket.Name)
+	}
+
+	return bucketList, nil
+}
+
+// CheckBucket checks the status of the given bucket.
+func (s *objectStore) CheckBucket(bucketName string) error {
+	_, err := s.client.HeadBucket(&s3.HeadBucketInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		err = s.convertError(err, bucketName, "")
+		return emperror.With(emperror.Wrap(err, "checking bucket failed"), "bucket", bucketName)
+	}
+
+	return nil
+}
+
+// DeleteBucket removes a bucket from the object store.
+func (s *objectStore) DeleteBucket(bucketName string) error {
+	_, err := s.client.DeleteBucket(&s3.DeleteBucketInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		err = s.convertError(err, bucketName, "")
+		return emperror.With(emperror.Wrap(err, "bucket deletion failed"), "bucket", bucketName)
+	}
+
+	return nil
+}
+
+// ListObjects gets all keys in the bucket
+func (s *objectStore) ListObjects(bucketName string) ([]string, error) {
+	return s.ListObjectsWithPrefix(bucketName, "")
+}
+
+// ListObjectsWithPrefix gets all keys with the given prefix from the bucket
+func (s *objectStore) ListObjectsWithPrefix(bucketName, prefix string) ([]string, error) {
+	var keys []string
+	err := s.client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			keys = append(keys, *obj.Key)
+		}
+		return !lastPage
+	})
+	if err != nil {
+		err = s.convertError(err, bucketName, "")
+		return nil, emperror.With(emperror.Wrap(err, "error listing object for bucket"), "bucket", bucketName, "prefix", prefix)
+	}
+
+	return keys, nil
+}
+
+// ListObjectKeyPrefixes gets a list of all object key prefixes that come before the provided delimiter
+func (s *objectStore) ListObjectKeyPrefixes(bucketName string, delimiter string) ([]string, error) {
+	var prefixes []string
+	err := s.client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket:    aws.String(bucketName),
+		Delimiter: aws.String(delimiter),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, prefix := range page.CommonPrefixes {
+			p := *prefix.Prefix
+			prefixes = append(prefixes, p[0:strings.LastIndex(p, delimiter)])
+		}
+		return !lastPage
+	})
+	if err != nil {
+		err = s.convertError(err, bucketName, "")
+		return nil, emperror.With(emperror.Wrap(err, "error getting prefixes for bucket"), "bucket", bucketName, "delimiter", delimiter)
+	}
+
+	return prefixes, nil
+}
+
+// GetObject retrieves the object by it's key from the given bucket
+func (s *objectStore) GetObject(bucketName string, key string) (io.ReadCloser, error) {
+	output, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		err = s.convertError(err, bucketName, key)
+		return nil, emperror.With(emperror.Wrap(err, "error getting object"), "bucket", bucketName, "object", key)
+	}
+
+	return output.Body, nil
+}
+
+// PutObject creates a new object using the data in body with the given key
+func (s *objectStore) PutObject(bucketName string, key string, body io.Reader) error {
+	_, err := s.uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+		Body:   body,
+	})
+	if err != nil {
+		err = s.convertError(err, bucketName, key)
+		return emperror.With(emperror.Wrap(err, "error putting object"), "bucket", bucketName, "object", key)
+	}
+
+	return nil
+}
+
+// DeleteObject deletes the object from the given bucket by it's key
+func (s *objectStore) DeleteObject(bucketName string, key string) error {
+	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		err = s.convertError(err, bucketName, key)
+		return emperror.With(emperror.Wrap(err, "error deleting object"), "bucket", bucketName, "object", key)
+	}
+
+	return nil
+}
+
+// GetSignedURL gives back a signed URL for the object that expires after the given ttl
+func (s *objectStore) GetSignedURL(bucketName, key string, ttl time.Duration) (string, error) {
+	req, _ := s.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+
+	url, err := req.Presign(ttl)
+	if err != nil {
+		err = s.convertError(err, bucketName, key)
+		return "", emperror.With(emperror.Wrap(err, "could not get signed url"), "bucket", bucketName, "object", key)
+	}
+
+	return url, nil
+}
+
+// SetVersioning enables or suspends the versioning of the bucket.
+func (s *objectStore) SetVersioning(bucketName string, enabled bool) error {
+	status := s3.BucketVersioningStatusSuspended
+	if enabled {
+		status = s3.BucketVersioningStatusEnabled
+	}
+
+	_, err := s.client.PutBucketVersioning(&s3.PutBucketVersioningInput{
+		Bucket: aws.String(bucketName),
+		VersioningConfiguration: &s3.VersioningConfiguration{
+			Status: aws.String(status),
+		},
+	})
+	if err != nil {
+		err = s.convertError(err, bucketName, "")
+		return emperror.With(emperror.Wrap(err, "could not set bucket versioning"), "bucket", bucketName)
+	}
+
+	return nil
+}
+
+func (s *objectStore) convertError(err error, bucketName, key string) error {
+	if awsErr, ok := err.(awserr.Error); ok {
+		switch awsErr.Code() {
+		case s3.ErrCodeBucketAlreadyExists, s3.ErrCodeBucketAlreadyOwnedByYou:
+			err = errBucketAlreadyExists{bucketName: bucketName}
+
+		case s3.ErrCodeNoSuchBucket:
+			err = errBucketNotFound{bucketName: bucketName}
+
+		case s3.ErrCodeNoSuchKey:
+			err = errObjectNotFound{bucketName: bucketName, objectName: key}
+
+		case "NotFound":
+			// HEAD requests have no body, so the error code is derived from the status code
+			if key != "" {
+				err = errObjectNotFound{bucketName: bucketName, objectName: key}
+			} else {
+				err = errBucketNotFound{bucketName: bucketName}
+			}
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,171 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objectstore
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The tests run against a local MinIO server, eg.:
+//
+//	docker run -p 9000:9000 -e MINIO_ACCESS_KEY=minio -e MINIO_SECRET_KEY=minio123 minio/minio server /data
+//	S3_COMPATIBLE_ENDPOINT=http://localhost:9000 S3_COMPATIBLE_ACCESS_KEY=minio S3_COMPATIBLE_SECRET_KEY=minio123 go test
+func getObjectStore(t *testing.T) *objectStore {
+	t.Helper()
+
+	endpoint := strings.TrimSpace(os.Getenv("S3_COMPATIBLE_ENDPOINT"))
+	accessKey := strings.TrimSpace(os.Getenv("S3_COMPATIBLE_ACCESS_KEY"))
+	secretKey := strings.TrimSpace(os.Getenv("S3_COMPATIBLE_SECRET_KEY"))
+
+	if endpoint == "" || accessKey == "" || secretKey == "" {
+		t.Skip("missing endpoint or credentials")
+	}
+
+	config := Config{
+		Endpoint:       endpoint,
+		ForcePathStyle: true,
+	}
+
+	credentials := Credentials{
+		AccessKeyID:     accessKey,
+		SecretAccessKey: secretKey,
+	}
+
+	s, err := New(config, credentials)
+	if err != nil {
+		t.Fatal("could not create object storage client: ", err.Error())
+	}
+
+	return s
+}
+
+func getBucketName() string {
+	return fmt.Sprintf("banzaicloud-test-bucket-%d", time.Now().UnixNano())
+}
+
+func TestNew_InvalidCACert(t *testing.T) {
+	_, err := New(Config{Endpoint: "https://localhost:9000", CACert: "invalid"}, Credentials{})
+	if err == nil {
+		t.Fatal("expected an error for an invalid CA certificate")
+	}
+}
+
+func TestObjectStore_Buckets(t *testing.T) {
+	s := getObjectStore(t)
+
+	bucketName := getBucketName()
+
+	if err := s.CreateBucket(bucketName); err != nil {
+		t.Fatal("could not create bucket: ", err.Error())
+	}
+
+	err := s.CreateBucket(bucketName)
+	if alreadyExists, ok := errors.Cause(err).(interface{ AlreadyExists() bool }); !ok || !alreadyExists.AlreadyExists() {
+		t.Errorf("expected an already exists error, got: %v", err)
+	}
+
+	if err := s.CheckBucket(bucketName); err != nil {
+		t.Error("could not check bucket: ", err.Error())
+	}
+
+	buckets, err := s.ListBuckets()
+	if err != nil {
+		t.Fatal("could not list buckets: ", err.Error())
+	}
+
+	var found bool
+	for _, bucket := range buckets {
+		found = found || bucket == bucketName
+	}
+	if !found {
+		t.Errorf("bucket %s is not listed", bucketName)
+	}
+
+	if err := s.DeleteBucket(bucketName); err != nil {
+		t.Fatal("could not delete bucket: ", err.Error())
+	}
+
+	err = s.CheckBucket(bucketName)
+	if notFound, ok := errors.Cause(err).(interface{ NotFound() bool }); !ok || !notFound.NotFound() {
+		t.Errorf("expected a not found error, got: %v", err)
+	}
+}
+
+func TestObjectStore_Objects(t *testing.T) {
+	s := getObjectStore(t)
+
+	bucketName := getBucketName()
+
+	if err := s.CreateBucket(bucketName); err != nil {
+		t.Fatal("could not create bucket: ", err.Error())
+	}
+	defer s.DeleteBucket(bucketName)
+
+	keys := []string{"logs/2018/a.log", "logs/2018/b.log", "backups/c.tgz"}
+	for _, key := range keys {
+		if err := s.PutObject(bucketName, key, bytes.NewBufferString(key)); err != nil {
+			t.Fatal("could not put object: ", err.Error())
+		}
+		defer s.DeleteObject(bucketName, key)
+	}
+
+	objects, err := s.ListObjectsWithPrefix(bucketName, "logs/")
+	if err != nil {
+		t.Fatal("could not list objects: ", err.Error())
+	}
+	if expected := keys[:2]; !reflect.DeepEqual(objects, expected) {
+		t.Errorf("expected objects %v, got %v", expected, objects)
+	}
+
+	prefixes, err := s.ListObjectKeyPrefixes(bucketName, "/")
+	if err != nil {
+		t.Fatal("could not list prefixes: ", err.Error())
+	}
+	if expected := []string{"backups", "logs"}; !reflect.DeepEqual(prefixes, expected) {
+		t.Errorf("expected prefixes %v, got %v", expected, prefixes)
+	}
+
+	body, err := s.GetObject(bucketName, keys[0])
+	if err != nil {
+		t.Fatal("could not get object: ", err.Error())
+	}
+	content, _ := ioutil.ReadAll(body)
+	body.Close()
+	if string(content) != keys[0] {
+		t.Errorf("unexpected object content: %s", content)
+	}
+
+	_, err = s.GetObject(bucketName, "missing")
+	if notFound, ok := errors.Cause(err).(interface{ NotFound() bool }); !ok || !notFound.NotFound() {
+		t.Errorf("expected a not found error, got: %v", err)
+	}
+
+	url, err := s.GetSignedURL(bucketName, keys[0], time.Minute)
+	if err != nil {
+		t.Fatal("could not get signed url: ", err.Error())
+	}
+	if !strings.Contains(url, bucketName) {
+		t.Errorf("unexpected signed url: %s", url)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"strconv"
+
+	"github.com/banzaicloud/pipeline/pkg/providers/s3compatible/objectstore"
+	"github.com/pkg/errors"
+)
+
+// S3 compatible keys
+const (
+	Endpoint           = "endpoint"
+	Region             = "region"
+	AccessKeyID        = "access_key_id"
+	SecretAccessKey    = "secret_access_key"
+	CACert             = "ca_cert"
+	InsecureSkipVerify = "insecure_skip_verify"
+	ForcePathStyle     = "force_path_style"
+)
+
+// S3CompatibleVerify for validation S3 compatible credentials
+type S3CompatibleVerify struct {
+	values map[string]string
+}
+
+// CreateS3CompatibleSecret creates a new 'S3CompatibleVerify' instance
+func CreateS3CompatibleSecret(values map[string]string) *S3CompatibleVerify {
+	return &S3CompatibleVerify{
+		values: values,
+	}
+}
+
+// CreateConfig creates an object store configuration from secret's values.
+// Path-style addressing is used unless it is disabled explicitly, as most on-prem servers have no wildcard DNS.
+func CreateConfig(values map[string]string) (objectstore.Config, error) {
+	config := objectstore.Config{
+		Endpoint:       values[Endpoint],
+		Region:         values[Region],
+		CACert:         values[CACert],
+		ForcePathStyle: true,
+	}
+
+	if value := values[ForcePathStyle]; value != "" {
+		forcePathStyle, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, errors.Wrapf(err, "%s must be a boolean", ForcePathStyle)
+		}
+
+		config.ForcePathStyle = forcePathStyle
+	}
+
+	if value := values[InsecureSkipVerify]; value != "" {
+		insecureSkipVerify, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, errors.Wrapf(err, "%s must be a boolean", InsecureSkipVerify)
+		}
+
+		config.InsecureSkipVerify = insecureSkipVerify
+	}
+
+	return config, nil
+}
+
+// CreateCredentials creates object store credentials from secret's values
+func CreateCredentials(values map[string]string) objectstore.Credentials {
+	return objectstore.Credentials{
+		AccessKeyID:     values[AccessKeyID],
+		SecretAccessKey: values[SecretAccessKey],
+	}
+}
+
+// VerifySecret validates S3 compatible credentials by listing the buckets
+func (v *S3CompatibleVerify) VerifySecret() error {
+	config, err := CreateConfig(v.values)
+	if err != nil {
+		return err
+	}
+
+	client, err := objectstore.New(config, CreateCredentials(v.values))
+	if err != nil {
+		return err
+	}
+
+	_, err = client.ListBuckets()
+
+	return err
+}
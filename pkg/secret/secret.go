@@ -15,8 +15,12 @@
 package secret
 
 import (
+	"time"
+
 	"github.com/banzaicloud/pipeline/pkg/cluster"
 	oracle "github.com/banzaicloud/pipeline/pkg/providers/oracle/secret"
+	"github.com/banzaicloud/pipeline/pkg/providers/s3compatible"
+	s3compatibleSecret "github.com/banzaicloud/pipeline/pkg/providers/s3compatible/secret"
 )
 
 // FieldMeta describes how a secret field should be validated
@@ -111,6 +115,12 @@ const (
 	HtpasswdFile = ".htpasswd"
 )
 
+// Container registry keys
+const (
+	RegistryURL   = "registryUrl"
+	CloudSecretID = "cloudSecretId"
+)
+
 // Internal usage
 const (
 	TagKubeConfig     = "KubeConfig"
@@ -138,8 +148,37 @@ const (
 	PasswordSecretType = "password"
 	// HtpasswdSecretType marks secrets as of type "htpasswd"
 	HtpasswdSecretType = "htpasswd"
+	// DockerConfigJSONSecretType marks secrets as of type "dockerconfigjson"
+	DockerConfigJSONSecretType = "dockerconfigjson"
+	// ECRSecretType marks secrets as of type "ecr", their token is issued with an Amazon secret
+	ECRSecretType = "ecr"
+	// GCRSecretType marks secrets as of type "gcr", their token is issued with a Google secret
+	GCRSecretType = "gcr"
+	// ACRSecretType marks secrets as of type "acr", their token is issued with an Azure secret
+	ACRSecretType = "acr"
+	// S3CompatibleSecretType marks secrets as of type "s3compatible", they hold the endpoint and credentials of an S3 compatible object store
+	S3CompatibleSecretType = s3compatible.Provider
 )
 
+// RegistrySecretTypes are installed into clusters as kubernetes.io/dockerconfigjson secrets
+var RegistrySecretTypes = []string{
+	DockerConfigJSONSecretType,
+	ECRSecretType,
+	GCRSecretType,
+	ACRSecretType,
+}
+
+// IsRegistrySecretType checks whether secrets of a type hold container registry credentials
+func IsRegistrySecretType(secretType string) bool {
+	for _, registrySecretType := range RegistrySecretTypes {
+		if secretType == registrySecretType {
+			return true
+		}
+	}
+
+	return false
+}
+
 // DefaultRules key matching for types
 var DefaultRules = map[string]Meta{
 	cluster.Alibaba: {
@@ -198,6 +237,18 @@ var DefaultRules = map[string]Meta{
 			{Name: oracle.CompartmentOCID, Required: true},
 		},
 	},
+	S3CompatibleSecretType: {
+		Fields: []FieldMeta{
+			{Name: s3compatibleSecret.Endpoint, Required: true, Description: "eg. https://minio.example.com:9000"},
+			{Name: s3compatibleSecret.AccessKeyID, Required: true},
+			{Name: s3compatibleSecret.SecretAccessKey, Required: true},
+			{Name: s3compatibleSecret.Region, Required: false, Description: "Region used to sign the requests, defaults to us-east-1"},
+			{Name: s3compatibleSecret.CACert, Required: false, Description: "PEM encoded certificate of the authority that signed the certificate of the endpoint, not supported by logging"},
+			{Name: s3compatibleSecret.InsecureSkipVerify, Required: false, Description: "Skip the verification of the certificate of the endpoint (true or false)"},
+			{Name: s3compatibleSecret.ForcePathStyle, Required: false, Description: "Address buckets in the path instead of the host name (true or false), defaults to true"},
+		},
+		Sourcing: Volume,
+	},
 	SSHSecretType: {
 		Fields: []FieldMeta{
 			{Name: User, Required: true},
@@ -246,6 +297,35 @@ var DefaultRules = map[string]Meta{
 		},
 		Sourcing: Volume,
 	},
+	DockerConfigJSONSecretType: {
+		Fields: []FieldMeta{
+			{Name: RegistryURL, Required: true},
+			{Name: Username, Required: true},
+			{Name: Password, Required: true},
+		},
+		Sourcing: Volume,
+	},
+	ECRSecretType: {
+		Fields: []FieldMeta{
+			{Name: RegistryURL, Required: true, Description: "<account>.dkr.ecr.<region>.amazonaws.com"},
+			{Name: CloudSecretID, Required: true, Description: "ID of the Amazon secret used to get registry tokens"},
+		},
+		Sourcing: Volume,
+	},
+	GCRSecretType: {
+		Fields: []FieldMeta{
+			{Name: RegistryURL, Required: true, Description: "eg. gcr.io or eu.gcr.io"},
+			{Name: CloudSecretID, Required: true, Description: "ID of the Google secret used to get registry tokens"},
+		},
+		Sourcing: Volume,
+	},
+	ACRSecretType: {
+		Fields: []FieldMeta{
+			{Name: RegistryURL, Required: true, Description: "<registry>.azurecr.io"},
+			{Name: CloudSecretID, Required: true, Description: "ID of the Azure secret used to get registry tokens"},
+		},
+		Sourcing: Volume,
+	},
 }
 
 // ListSecretsQuery represent a secret listing filter
@@ -260,6 +340,17 @@ type ListSecretsQuery struct {
 type InstallSecretsToClusterRequest struct {
 	Namespace string           `json:"namespace" binding:"required"`
 	Query     ListSecretsQuery `json:"query" binding:"required"`
+
+	// ImagePullSecrets adds the installed container registry secrets to the default service account of the namespace
+	ImagePullSecrets bool `json:"imagePullSecrets,omitempty"`
+}
+
+// SecretInstallation describes a copy of an organization secret installed into a namespace of a cluster
+type SecretInstallation struct {
+	ClusterID uint      `json:"clusterId"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	UpdatedAt time.Time `json:"updatedAt"`
 }
 
 // SourcingMethod describes how an installed Secret should be sourced into a Pod in K8S
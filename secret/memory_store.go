@@ -0,0 +1,239 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/pkg/errors"
+)
+
+// inMemorySecretStore keeps secrets in memory, it is meant to be used in tests and for local development.
+type inMemorySecretStore struct {
+	mu sync.RWMutex
+
+	// secrets holds every version of the secrets, the latest one last
+	secrets map[uint]map[string][]*SecretItemResponse
+}
+
+// NewInMemorySecretStore returns a new secret store keeping secrets in memory.
+func NewInMemorySecretStore() SecretStore {
+	return &inMemorySecretStore{
+		secrets: make(map[uint]map[string][]*SecretItemResponse),
+	}
+}
+
+func (s *inMemorySecretStore) Store(organizationID uint, value *CreateSecretRequest) (string, error) {
+	if err := prepareSecretToStore(value); err != nil {
+		return "", err
+	}
+
+	secretID := GenerateSecretID(value)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.latest(organizationID, secretID) != nil {
+		return "", errors.Wrap(errCASMismatch, "Error during storing secret")
+	}
+
+	s.write(organizationID, secretID, value, 1)
+
+	return secretID, nil
+}
+
+func (s *inMemorySecretStore) Update(organizationID uint, secretID string, value *CreateSecretRequest) error {
+	if GenerateSecretID(value) != secretID {
+		return errors.New("Secret name cannot be changed")
+	}
+
+	sort.Strings(value.Tags)
+
+	version := 0
+	if value.Version != nil {
+		version = *value.Version
+		value.Version = nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	currentVersion := 0
+	if current := s.latest(organizationID, secretID); current != nil {
+		currentVersion = current.Version
+	}
+
+	if currentVersion != version {
+		return errors.Wrap(errCASMismatch, "Error during updating secret")
+	}
+
+	s.write(organizationID, secretID, value, currentVersion+1)
+
+	return nil
+}
+
+// write saves a copy of the secret as a new version, the caller must hold the lock
+func (s *inMemorySecretStore) write(organizationID uint, secretID string, value *CreateSecretRequest, version int) {
+	if s.secrets[organizationID] == nil {
+		s.secrets[organizationID] = make(map[string][]*SecretItemResponse)
+	}
+
+	s.secrets[organizationID][secretID] = append(s.secrets[organizationID][secretID], copySecret(&SecretItemResponse{
+		ID:        secretID,
+		Name:      value.Name,
+		Type:      value.Type,
+		Values:    value.Values,
+		Tags:      value.Tags,
+		Version:   version,
+		UpdatedAt: time.Now().UTC(),
+		UpdatedBy: value.UpdatedBy,
+	}))
+}
+
+// latest returns the latest version of a secret or nil, the caller must hold the lock
+func (s *inMemorySecretStore) latest(organizationID uint, secretID string) *SecretItemResponse {
+	versions := s.secrets[organizationID][secretID]
+	if len(versions) == 0 {
+		return nil
+	}
+
+	return versions[len(versions)-1]
+}
+
+func (s *inMemorySecretStore) Get(organizationID uint, secretID string) (*SecretItemResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	secret := s.latest(organizationID, secretID)
+	if secret == nil {
+		return nil, ErrSecretNotExists
+	}
+
+	return copySecret(secret), nil
+}
+
+func (s *inMemorySecretStore) GetByName(organizationID uint, name string) (*SecretItemResponse, error) {
+	return getSecretByName(s, organizationID, name)
+}
+
+func (s *inMemorySecretStore) List(organizationID uint, query *secretTypes.ListSecretsQuery) ([]*SecretItemResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	secretIDs := query.IDs
+	if len(secretIDs) == 0 {
+		for secretID := range s.secrets[organizationID] {
+			secretIDs = append(secretIDs, secretID)
+		}
+
+		sort.Strings(secretIDs)
+	}
+
+	responseItems := []*SecretItemResponse{}
+
+	for _, secretID := range secretIDs {
+		secret := s.latest(organizationID, secretID)
+		if secret == nil || !matchesQuery(secret, query) {
+			continue
+		}
+
+		secret = copySecret(secret)
+		if !query.Values {
+			secret.HideValues()
+		}
+
+		responseItems = append(responseItems, secret)
+	}
+
+	return responseItems, nil
+}
+
+func (s *inMemorySecretStore) Delete(organizationID uint, secretID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.secrets[organizationID], secretID)
+
+	return nil
+}
+
+func (s *inMemorySecretStore) ListVersions(organizationID uint, secretID string) ([]*SecretVersionResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	secrets := s.secrets[organizationID][secretID]
+	if len(secrets) == 0 {
+		return nil, ErrSecretNotExists
+	}
+
+	versions := make([]*SecretVersionResponse, 0, len(secrets))
+	for _, secret := range secrets {
+		versions = append(versions, &SecretVersionResponse{
+			Version:   secret.Version,
+			CreatedAt: secret.UpdatedAt,
+			CreatedBy: secret.UpdatedBy,
+		})
+	}
+
+	sortVersions(versions)
+
+	return versions, nil
+}
+
+func (s *inMemorySecretStore) GetVersion(organizationID uint, secretID string, version int) (*SecretItemResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, secret := range s.secrets[organizationID][secretID] {
+		if secret.Version == version {
+			return copySecret(secret), nil
+		}
+	}
+
+	return nil, ErrSecretVersionNotExists
+}
+
+func (s *inMemorySecretStore) Rollback(organizationID uint, secretID string, version int, updatedBy string) error {
+	return rollbackSecret(s, organizationID, secretID, version, updatedBy)
+}
+
+func (s *inMemorySecretStore) DeleteByClusterUID(organizationID uint, clusterUID string) error {
+	return deleteSecretsByClusterUID(s, organizationID, clusterUID)
+}
+
+func (s *inMemorySecretStore) GetOrCreate(organizationID uint, value *CreateSecretRequest) (string, error) {
+	return getOrCreateSecret(s, organizationID, value)
+}
+
+func (s *inMemorySecretStore) CreateOrUpdate(organizationID uint, value *CreateSecretRequest) (string, error) {
+	return createOrUpdateSecret(s, organizationID, value)
+}
+
+// copySecret returns a deep copy of a secret, so callers cannot modify the stored one
+func copySecret(secret *SecretItemResponse) *SecretItemResponse {
+	c := *secret
+
+	c.Values = make(map[string]string, len(secret.Values))
+	for k, v := range secret.Values {
+		c.Values[k] = v
+	}
+
+	c.Tags = append([]string(nil), secret.Tags...)
+
+	return &c
+}
@@ -0,0 +1,120 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret_test
+
+import (
+	"testing"
+
+	pkgSecret "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/banzaicloud/pipeline/secret"
+)
+
+func TestInMemorySecretStore(t *testing.T) {
+	store := secret.NewInMemorySecretStore()
+
+	const orgID = 1
+
+	secretID, err := store.Store(orgID, &secret.CreateSecretRequest{
+		Name:   "generic-secret",
+		Type:   pkgSecret.GenericSecret,
+		Values: map[string]string{"key": "value"},
+		Tags:   []string{"b", "a"},
+	})
+	if err != nil {
+		t.Fatalf("could not store secret: %s", err.Error())
+	}
+
+	if _, err := store.Store(orgID, &secret.CreateSecretRequest{Name: "generic-secret", Type: pkgSecret.GenericSecret}); err == nil || !secret.IsCASError(err) {
+		t.Errorf("expected CAS error when storing an existing secret, got: %v", err)
+	}
+
+	staleVersion := 0
+	err = store.Update(orgID, secretID, &secret.CreateSecretRequest{
+		Name:    "generic-secret",
+		Type:    pkgSecret.GenericSecret,
+		Values:  map[string]string{"key": "other"},
+		Version: &staleVersion,
+	})
+	if err == nil || !secret.IsCASError(err) {
+		t.Errorf("expected CAS error when updating with a stale version, got: %v", err)
+	}
+
+	currentVersion := 1
+	err = store.Update(orgID, secretID, &secret.CreateSecretRequest{
+		Name:    "generic-secret",
+		Type:    pkgSecret.GenericSecret,
+		Values:  map[string]string{"key": "other"},
+		Version: &currentVersion,
+	})
+	if err != nil {
+		t.Fatalf("could not update secret: %s", err.Error())
+	}
+
+	item, err := store.Get(orgID, secretID)
+	if err != nil {
+		t.Fatalf("could not get secret: %s", err.Error())
+	}
+
+	if item.Version != 2 || item.Values["key"] != "other" {
+		t.Errorf("unexpected secret after update: version %d, value %q", item.Version, item.Values["key"])
+	}
+
+	if _, err := store.Get(orgID+1, secretID); err != secret.ErrSecretNotExists {
+		t.Errorf("expected secret not to exist in another organization, got: %v", err)
+	}
+
+	items, err := store.List(orgID, &pkgSecret.ListSecretsQuery{Type: pkgSecret.GenericSecret})
+	if err != nil {
+		t.Fatalf("could not list secrets: %s", err.Error())
+	}
+
+	if len(items) != 1 || items[0].Values["key"] == "other" {
+		t.Errorf("expected one secret with hidden values, got: %#v", items)
+	}
+
+	if err := store.Rollback(orgID, secretID, 1, "user"); err != nil {
+		t.Fatalf("could not roll back secret: %s", err.Error())
+	}
+
+	item, err = store.Get(orgID, secretID)
+	if err != nil {
+		t.Fatalf("could not get secret: %s", err.Error())
+	}
+
+	if item.Version != 3 || item.Values["key"] != "value" || item.UpdatedBy != "user" {
+		t.Errorf("unexpected secret after rollback: version %d, value %q, updated by %q", item.Version, item.Values["key"], item.UpdatedBy)
+	}
+
+	versions, err := store.ListVersions(orgID, secretID)
+	if err != nil {
+		t.Fatalf("could not list secret versions: %s", err.Error())
+	}
+
+	if len(versions) != 3 || versions[0].Version != 3 {
+		t.Errorf("expected three versions, the latest one first, got: %#v", versions)
+	}
+
+	if _, err := store.GetVersion(orgID, secretID, 4); err != secret.ErrSecretVersionNotExists {
+		t.Errorf("expected version not to exist, got: %v", err)
+	}
+
+	if err := store.Delete(orgID, secretID); err != nil {
+		t.Fatalf("could not delete secret: %s", err.Error())
+	}
+
+	if _, err := store.Get(orgID, secretID); err != secret.ErrSecretNotExists {
+		t.Errorf("expected secret to be deleted, got: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+// TableName constants
+const (
+	secretsTableName          = "secrets"
+	secretVersionsTableName   = "secret_versions"
+	rotationPoliciesTableName = "secret_rotation_policies"
+)
+
+// secretModel is a secret stored by the SQL backend.
+// The values are encrypted, everything else is stored in plain text to allow filtering.
+type secretModel struct {
+	ID uint `gorm:"primary_key"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	OrganizationID uint   `gorm:"unique_index:idx_secret_org_secret_id"`
+	SecretID       string `gorm:"unique_index:idx_secret_org_secret_id"`
+
+	Name      string
+	Type      string
+	Tags      string `sql:"type:text;"`
+	Values    string `gorm:"column:encrypted_values" sql:"type:text;"`
+	Version   int
+	UpdatedBy string
+}
+
+// TableName changes the default table name.
+func (secretModel) TableName() string {
+	return secretsTableName
+}
+
+// secretVersionModel is a version of a secret stored by the SQL backend, the latest one included.
+type secretVersionModel struct {
+	ID uint `gorm:"primary_key"`
+
+	CreatedAt time.Time
+
+	OrganizationID uint   `gorm:"unique_index:idx_secret_version_org_secret_id_version"`
+	SecretID       string `gorm:"unique_index:idx_secret_version_org_secret_id_version"`
+	Version        int    `gorm:"unique_index:idx_secret_version_org_secret_id_version"`
+
+	Name      string
+	Type      string
+	Tags      string `sql:"type:text;"`
+	Values    string `gorm:"column:encrypted_values" sql:"type:text;"`
+	UpdatedBy string
+}
+
+// TableName changes the default table name.
+func (secretVersionModel) TableName() string {
+	return secretVersionsTableName
+}
+
+// rotationPolicyModel is the rotation policy of a secret, it is stored in the database regardless of the secret store backend.
+type rotationPolicyModel struct {
+	ID uint `gorm:"primary_key"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	OrganizationID uint   `gorm:"unique_index:idx_rotation_policy_org_secret_id"`
+	SecretID       string `gorm:"unique_index:idx_rotation_policy_org_secret_id"`
+
+	Interval       time.Duration
+	Fields         string    `sql:"type:text;"`
+	NextRotationAt time.Time `gorm:"index:idx_rotation_policy_next_rotation_at"`
+	LastRotatedAt  *time.Time
+	LastError      string `sql:"type:text;"`
+	UpdatedBy      string
+}
+
+// TableName changes the default table name.
+func (rotationPolicyModel) TableName() string {
+	return rotationPoliciesTableName
+}
+
+// Migrate executes the table migrations for the secret store.
+func Migrate(db *gorm.DB, logger logrus.FieldLogger) error {
+	tables := []interface{}{
+		&secretModel{},
+		&secretVersionModel{},
+		&rotationPolicyModel{},
+	}
+
+	var tableNames string
+	for _, table := range tables {
+		tableNames += fmt.Sprintf(" %s", db.NewScope(table).TableName())
+	}
+
+	logger.WithFields(logrus.Fields{
+		"table_names": tableNames,
+	}).Info("migrating secret tables")
+
+	return db.AutoMigrate(tables...).Error
+}
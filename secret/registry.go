@@ -0,0 +1,255 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/banzaicloud/pipeline/secret/verify"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	// gcrUsername is the user name of OAuth2 access tokens in Google Container Registry
+	gcrUsername = "oauth2accesstoken"
+
+	// acrUsername is the user name of refresh tokens in Azure Container Registry
+	acrUsername = "00000000-0000-0000-0000-000000000000"
+
+	// gcrScope is the OAuth2 scope needed to pull and push images
+	gcrScope = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// registryHTTPClient is used for exchanging Azure tokens to registry tokens
+var registryHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+type dockerConfigEntry struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Auth     string `json:"auth"`
+}
+
+type dockerConfigJSON struct {
+	Auths map[string]dockerConfigEntry `json:"auths"`
+}
+
+// DockerConfigJSON returns the content of a kubernetes.io/dockerconfigjson secret for a container registry secret.
+// ECR, GCR and ACR secrets get a short-lived token issued with the cloud secret they refer to.
+func DockerConfigJSON(organizationID uint, secret *SecretItemResponse) ([]byte, error) {
+	registry := secret.Values[secretTypes.RegistryURL]
+
+	var username, password string
+	var err error
+
+	switch secret.Type {
+	case secretTypes.DockerConfigJSONSecretType:
+		username, password = secret.Values[secretTypes.Username], secret.Values[secretTypes.Password]
+
+	case secretTypes.ECRSecretType:
+		username, password, err = getECRCredentials(organizationID, secret)
+
+	case secretTypes.GCRSecretType:
+		username, password, err = getGCRCredentials(organizationID, secret)
+
+	case secretTypes.ACRSecretType:
+		username, password, err = getACRCredentials(organizationID, secret)
+
+	default:
+		return nil, errors.Errorf("not a container registry secret: %s", secret.Type)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get credentials of registry %s", registry)
+	}
+
+	return json.Marshal(dockerConfigJSON{
+		Auths: map[string]dockerConfigEntry{
+			registry: {
+				Username: username,
+				Password: password,
+				Auth:     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+			},
+		},
+	})
+}
+
+// getCloudSecret returns the cloud secret a registry secret refers to
+func getCloudSecret(organizationID uint, secret *SecretItemResponse, cloudType string) (*SecretItemResponse, error) {
+	cloudSecret, err := Store.Get(organizationID, secret.Values[secretTypes.CloudSecretID])
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get cloud secret")
+	}
+
+	if err := cloudSecret.ValidateSecretType(cloudType); err != nil {
+		return nil, err
+	}
+
+	return cloudSecret, nil
+}
+
+func getECRCredentials(organizationID uint, secret *SecretItemResponse) (string, string, error) {
+	cloudSecret, err := getCloudSecret(organizationID, secret, pkgCluster.Amazon)
+	if err != nil {
+		return "", "", err
+	}
+
+	accountID, region, err := parseECRRegistry(secret.Values[secretTypes.RegistryURL])
+	if err != nil {
+		return "", "", err
+	}
+
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: verify.CreateAWSCredentials(cloudSecret.Values),
+		Region:      aws.String(region),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	output, err := ecr.New(sess).GetAuthorizationToken(&ecr.GetAuthorizationTokenInput{
+		RegistryIds: []*string{aws.String(accountID)},
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	if len(output.AuthorizationData) == 0 {
+		return "", "", errors.New("no ECR authorization data returned")
+	}
+
+	token, err := base64.StdEncoding.DecodeString(aws.StringValue(output.AuthorizationData[0].AuthorizationToken))
+	if err != nil {
+		return "", "", errors.Wrap(err, "could not decode ECR authorization token")
+	}
+
+	// the token is in user:password format
+	credentials := strings.SplitN(string(token), ":", 2)
+	if len(credentials) != 2 {
+		return "", "", errors.New("invalid ECR authorization token")
+	}
+
+	return credentials[0], credentials[1], nil
+}
+
+func getGCRCredentials(organizationID uint, secret *SecretItemResponse) (string, string, error) {
+	cloudSecret, err := getCloudSecret(organizationID, secret, pkgCluster.Google)
+	if err != nil {
+		return "", "", err
+	}
+
+	serviceAccount, err := json.Marshal(verify.CreateServiceAccount(cloudSecret.Values))
+	if err != nil {
+		return "", "", err
+	}
+
+	config, err := google.JWTConfigFromJSON(serviceAccount, gcrScope)
+	if err != nil {
+		return "", "", err
+	}
+
+	token, err := config.TokenSource(context.Background()).Token()
+	if err != nil {
+		return "", "", err
+	}
+
+	return gcrUsername, token.AccessToken, nil
+}
+
+func getACRCredentials(organizationID uint, secret *SecretItemResponse) (string, string, error) {
+	cloudSecret, err := getCloudSecret(organizationID, secret, pkgCluster.Azure)
+	if err != nil {
+		return "", "", err
+	}
+
+	tenantID := cloudSecret.Values[secretTypes.AzureTenantId]
+
+	oauthConfig, err := adal.NewOAuthConfig(azure.PublicCloud.ActiveDirectoryEndpoint, tenantID)
+	if err != nil {
+		return "", "", err
+	}
+
+	spToken, err := adal.NewServicePrincipalToken(
+		*oauthConfig,
+		cloudSecret.Values[secretTypes.AzureClientId],
+		cloudSecret.Values[secretTypes.AzureClientSecret],
+		azure.PublicCloud.ResourceManagerEndpoint,
+	)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := spToken.Refresh(); err != nil {
+		return "", "", err
+	}
+
+	// exchange the Azure AD token for a registry refresh token
+	host := registryHost(secret.Values[secretTypes.RegistryURL])
+	response, err := registryHTTPClient.PostForm("https://"+host+"/oauth2/exchange", url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {host},
+		"tenant":       {tenantID},
+		"access_token": {spToken.OAuthToken()},
+	})
+	if err != nil {
+		return "", "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", "", errors.Errorf("ACR token exchange failed with status %s", response.Status)
+	}
+
+	var token struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&token); err != nil {
+		return "", "", errors.Wrap(err, "could not decode ACR token")
+	}
+
+	return acrUsername, token.RefreshToken, nil
+}
+
+// parseECRRegistry returns the account ID and the region of an ECR registry
+func parseECRRegistry(registry string) (string, string, error) {
+	// <account>.dkr.ecr.<region>.amazonaws.com
+	host := registryHost(registry)
+	parts := strings.Split(host, ".")
+	if len(parts) < 6 || parts[0] == "" || parts[1] != "dkr" || parts[2] != "ecr" || parts[3] == "" {
+		return "", "", errors.Errorf("invalid ECR registry: %s", host)
+	}
+
+	return parts[0], parts[3], nil
+}
+
+// registryHost returns the host name of a registry URL
+func registryHost(registry string) string {
+	registry = strings.TrimPrefix(strings.TrimPrefix(registry, "https://"), "http://")
+
+	return strings.SplitN(registry, "/", 2)[0]
+}
@@ -0,0 +1,141 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+)
+
+func TestRegistryHost(t *testing.T) {
+	cases := map[string]string{
+		"docker.io":                            "docker.io",
+		"https://eu.gcr.io":                    "eu.gcr.io",
+		"http://registry.example.com:5000/v2/": "registry.example.com:5000",
+		"myregistry.azurecr.io/path/to/image":  "myregistry.azurecr.io",
+	}
+
+	for registry, expected := range cases {
+		if host := registryHost(registry); host != expected {
+			t.Errorf("expected host %q of registry %q, got %q", expected, registry, host)
+		}
+	}
+}
+
+func TestParseECRRegistry(t *testing.T) {
+	cases := []struct {
+		registry    string
+		accountID   string
+		region      string
+		expectedErr bool
+	}{
+		{
+			registry:  "123456789012.dkr.ecr.eu-west-1.amazonaws.com",
+			accountID: "123456789012",
+			region:    "eu-west-1",
+		},
+		{
+			registry:  "https://123456789012.dkr.ecr.us-east-2.amazonaws.com/v2/",
+			accountID: "123456789012",
+			region:    "us-east-2",
+		},
+		{
+			registry:  "123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn",
+			accountID: "123456789012",
+			region:    "cn-north-1",
+		},
+		{registry: "docker.io", expectedErr: true},
+		{registry: "123456789012.dkr.ecr.amazonaws.com", expectedErr: true},
+		{registry: "123456789012.docker.ecr.eu-west-1.amazonaws.com", expectedErr: true},
+		{registry: ".dkr.ecr.eu-west-1.amazonaws.com", expectedErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.registry, func(t *testing.T) {
+			accountID, region, err := parseECRRegistry(tc.registry)
+			if tc.expectedErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal("unexpected error: ", err.Error())
+			}
+
+			if accountID != tc.accountID {
+				t.Errorf("expected account ID %q, got %q", tc.accountID, accountID)
+			}
+
+			if region != tc.region {
+				t.Errorf("expected region %q, got %q", tc.region, region)
+			}
+		})
+	}
+}
+
+func TestDockerConfigJSON(t *testing.T) {
+	secret := &SecretItemResponse{
+		Type: secretTypes.DockerConfigJSONSecretType,
+		Values: map[string]string{
+			secretTypes.RegistryURL: "registry.example.com",
+			secretTypes.Username:    "user",
+			secretTypes.Password:    "pass:word",
+		},
+	}
+
+	content, err := DockerConfigJSON(1, secret)
+	if err != nil {
+		t.Fatal("unexpected error: ", err.Error())
+	}
+
+	var config dockerConfigJSON
+	if err := json.Unmarshal(content, &config); err != nil {
+		t.Fatal("could not unmarshal docker config: ", err.Error())
+	}
+
+	entry, ok := config.Auths["registry.example.com"]
+	if !ok || len(config.Auths) != 1 {
+		t.Fatalf("expected a single auth entry for the registry, got %v", config.Auths)
+	}
+
+	if entry.Username != "user" || entry.Password != "pass:word" {
+		t.Errorf("unexpected credentials: %s/%s", entry.Username, entry.Password)
+	}
+
+	auth, err := base64.StdEncoding.DecodeString(entry.Auth)
+	if err != nil {
+		t.Fatal("could not decode auth: ", err.Error())
+	}
+
+	if string(auth) != "user:pass:word" {
+		t.Errorf("expected auth %q, got %q", "user:pass:word", string(auth))
+	}
+}
+
+func TestDockerConfigJSON_NotRegistry(t *testing.T) {
+	secret := &SecretItemResponse{
+		Type:   secretTypes.GenericSecret,
+		Values: map[string]string{},
+	}
+
+	if _, err := DockerConfigJSON(1, secret); err == nil {
+		t.Error("expected an error for a non-registry secret")
+	}
+}
@@ -23,11 +23,11 @@ import (
 // restrictedSecretStore checks whether the user can access a certain secret.
 // For now this only means checking for forbidden tags.
 type restrictedSecretStore struct {
-	*secretStore
+	SecretStore
 }
 
 func (s *restrictedSecretStore) List(orgid uint, query *secretTypes.ListSecretsQuery) ([]*SecretItemResponse, error) {
-	responseItems, err := s.secretStore.List(orgid, query)
+	responseItems, err := s.SecretStore.List(orgid, query)
 	if err != nil {
 		return nil, err
 	}
@@ -48,7 +48,7 @@ func (s *restrictedSecretStore) Update(organizationID uint, secretID string, val
 		return err
 	}
 
-	return s.secretStore.Update(organizationID, secretID, value)
+	return s.SecretStore.Update(organizationID, secretID, value)
 }
 
 func (s *restrictedSecretStore) Delete(organizationID uint, secretID string) error {
@@ -56,12 +56,36 @@ func (s *restrictedSecretStore) Delete(organizationID uint, secretID string) err
 		return err
 	}
 
-	return s.secretStore.Delete(organizationID, secretID)
+	return s.SecretStore.Delete(organizationID, secretID)
+}
+
+func (s *restrictedSecretStore) ListVersions(organizationID uint, secretID string) ([]*SecretVersionResponse, error) {
+	if err := s.checkForbiddenTags(organizationID, secretID); err != nil {
+		return nil, err
+	}
+
+	return s.SecretStore.ListVersions(organizationID, secretID)
+}
+
+func (s *restrictedSecretStore) GetVersion(organizationID uint, secretID string, version int) (*SecretItemResponse, error) {
+	if err := s.checkForbiddenTags(organizationID, secretID); err != nil {
+		return nil, err
+	}
+
+	return s.SecretStore.GetVersion(organizationID, secretID, version)
+}
+
+func (s *restrictedSecretStore) Rollback(organizationID uint, secretID string, version int, updatedBy string) error {
+	if err := s.checkBlockingTags(organizationID, secretID); err != nil {
+		return err
+	}
+
+	return s.SecretStore.Rollback(organizationID, secretID, version, updatedBy)
 }
 
 func (s *restrictedSecretStore) checkBlockingTags(organizationID uint, secretID string) error {
 
-	secretItem, err := s.secretStore.Get(organizationID, secretID)
+	secretItem, err := s.SecretStore.Get(organizationID, secretID)
 	if err != nil {
 		return err
 	}
@@ -80,7 +104,7 @@ func (s *restrictedSecretStore) checkBlockingTags(organizationID uint, secretID
 }
 
 func (s *restrictedSecretStore) checkForbiddenTags(organizationID uint, secretID string) error {
-	secretItem, err := s.secretStore.Get(organizationID, secretID)
+	secretItem, err := s.SecretStore.Get(organizationID, secretID)
 	if err != nil {
 		return err
 	}
@@ -22,6 +22,10 @@ import (
 	"github.com/banzaicloud/pipeline/secret"
 )
 
+func init() {
+	secret.SetStore(secret.NewInMemorySecretStore())
+}
+
 const (
 	orgID = 19
 )
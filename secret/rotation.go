@@ -0,0 +1,456 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/banzaicloud/pipeline/config"
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// rotationUpdatedBy is recorded as the author of secret versions created by rotation
+const rotationUpdatedBy = "pipeline:rotation"
+
+// minRotationInterval is the shortest interval a secret can be rotated at
+const minRotationInterval = time.Minute
+
+// ErrRotationPolicyNotExists denotes 'Not Found' errors for rotation policies
+var ErrRotationPolicyNotExists = fmt.Errorf("There's no rotation policy for this secret")
+
+// rotatableFields are the fields which can be regenerated by secret type, the default ones first.
+// Rotating the CA certificate of a TLS secret regenerates the whole certificate chain.
+var rotatableFields = map[string][]string{
+	secretTypes.PasswordSecretType: {secretTypes.Password},
+	secretTypes.HtpasswdSecretType: {secretTypes.Password},
+	secretTypes.TLSSecretType:      {secretTypes.ServerCert, secretTypes.ClientCert, secretTypes.CACert},
+}
+
+// defaultRotatedFields are the fields regenerated by secret type if the policy does not list any
+var defaultRotatedFields = map[string][]string{
+	secretTypes.PasswordSecretType: {secretTypes.Password},
+	secretTypes.HtpasswdSecretType: {secretTypes.Password},
+	secretTypes.TLSSecretType:      {secretTypes.ServerCert, secretTypes.ClientCert},
+}
+
+// RotationPolicyRequest param for SetRotationPolicy
+type RotationPolicyRequest struct {
+	Interval string   `json:"interval" binding:"required"`
+	Fields   []string `json:"fields"`
+}
+
+// RotationPolicyResponse API response for rotation policies
+type RotationPolicyResponse struct {
+	Interval       string     `json:"interval"`
+	Fields         []string   `json:"fields"`
+	NextRotationAt time.Time  `json:"nextRotationAt"`
+	LastRotatedAt  *time.Time `json:"lastRotatedAt,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	UpdatedBy      string     `json:"updatedBy,omitempty"`
+}
+
+// RotationPolicyError describes an invalid rotation policy
+type RotationPolicyError struct {
+	Reason string
+}
+
+func (e RotationPolicyError) Error() string {
+	return fmt.Sprintf("invalid rotation policy: %s", e.Reason)
+}
+
+// SetRotationPolicy creates or updates the rotation policy of a secret with a generated type
+func SetRotationPolicy(store SecretStore, organizationID uint, secretID string, request *RotationPolicyRequest, updatedBy string) (*RotationPolicyResponse, error) {
+	secret, err := store.Get(organizationID, secretID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := HasForbiddenTag(secret.Tags); err != nil {
+		return nil, err
+	}
+
+	for _, tag := range secret.Tags {
+		if tag == secretTypes.TagBanzaiReadonly {
+			return nil, ReadOnlyError{SecretID: secretID}
+		}
+	}
+
+	interval, err := time.ParseDuration(request.Interval)
+	if err != nil {
+		return nil, RotationPolicyError{Reason: err.Error()}
+	}
+
+	if interval < minRotationInterval {
+		return nil, RotationPolicyError{Reason: fmt.Sprintf("interval must be at least %s", minRotationInterval)}
+	}
+
+	allowedFields, ok := rotatableFields[secret.Type]
+	if !ok {
+		return nil, RotationPolicyError{Reason: fmt.Sprintf("secrets of type %s cannot be rotated", secret.Type)}
+	}
+
+	fields := request.Fields
+	if len(fields) == 0 {
+		fields = defaultRotatedFields[secret.Type]
+	}
+
+	for _, field := range fields {
+		if !containsString(allowedFields, field) {
+			return nil, RotationPolicyError{Reason: fmt.Sprintf("field %s of %s secrets cannot be rotated", field, secret.Type)}
+		}
+	}
+
+	rawFields, err := json.Marshal(fields)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal rotated fields")
+	}
+
+	db := config.DB()
+
+	var policy rotationPolicyModel
+
+	err = db.Where(rotationPolicyModel{OrganizationID: organizationID, SecretID: secretID}).FirstOrInit(&policy).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get rotation policy")
+	}
+
+	policy.Interval = interval
+	policy.Fields = string(rawFields)
+	policy.UpdatedBy = updatedBy
+
+	lastRotation := time.Now()
+	if policy.LastRotatedAt != nil {
+		lastRotation = *policy.LastRotatedAt
+	}
+	policy.NextRotationAt = nextRotation(secret, interval, fields, lastRotation)
+
+	if err := db.Save(&policy).Error; err != nil {
+		return nil, errors.Wrap(err, "could not save rotation policy")
+	}
+
+	return newRotationPolicyResponse(&policy)
+}
+
+// GetRotationPolicy returns the rotation policy of a secret
+func GetRotationPolicy(organizationID uint, secretID string) (*RotationPolicyResponse, error) {
+	var policy rotationPolicyModel
+
+	err := config.DB().First(&policy, map[string]interface{}{"organization_id": organizationID, "secret_id": secretID}).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, ErrRotationPolicyNotExists
+	} else if err != nil {
+		return nil, errors.Wrap(err, "could not get rotation policy")
+	}
+
+	return newRotationPolicyResponse(&policy)
+}
+
+// DeleteRotationPolicy deletes the rotation policy of a secret, if there is any
+func DeleteRotationPolicy(organizationID uint, secretID string) error {
+	err := config.DB().Where("organization_id = ? AND secret_id = ?", organizationID, secretID).Delete(&rotationPolicyModel{}).Error
+	if err != nil {
+		return errors.Wrap(err, "could not delete rotation policy")
+	}
+
+	return nil
+}
+
+func newRotationPolicyResponse(policy *rotationPolicyModel) (*RotationPolicyResponse, error) {
+	fields, err := policy.fields()
+	if err != nil {
+		return nil, err
+	}
+
+	return &RotationPolicyResponse{
+		Interval:       policy.Interval.String(),
+		Fields:         fields,
+		NextRotationAt: policy.NextRotationAt,
+		LastRotatedAt:  policy.LastRotatedAt,
+		LastError:      policy.LastError,
+		UpdatedBy:      policy.UpdatedBy,
+	}, nil
+}
+
+func (policy *rotationPolicyModel) fields() ([]string, error) {
+	var fields []string
+	if err := json.Unmarshal([]byte(policy.Fields), &fields); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal rotated fields")
+	}
+
+	return fields, nil
+}
+
+// RotateSecret regenerates the given fields of a secret and saves them as a new version.
+func RotateSecret(store SecretStore, organizationID uint, secretID string, fields []string) (*SecretItemResponse, error) {
+	current, err := store.Get(organizationID, secretID)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(current.Values))
+	for k, v := range current.Values {
+		values[k] = v
+	}
+
+	if err := regenerateValues(current.Type, values, fields); err != nil {
+		return nil, errors.Wrapf(err, "could not regenerate values of secret %s", current.Name)
+	}
+
+	// the current version is used as the check-and-set parameter, so concurrent changes are not overwritten
+	err = store.Update(organizationID, secretID, &CreateSecretRequest{
+		Name:      current.Name,
+		Type:      current.Type,
+		Values:    values,
+		Tags:      current.Tags,
+		Version:   &current.Version,
+		UpdatedBy: rotationUpdatedBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return store.Get(organizationID, secretID)
+}
+
+// regenerateValues regenerates the given fields of the secret values in place
+func regenerateValues(secretType string, values map[string]string, fields []string) error {
+	switch secretType {
+	case secretTypes.PasswordSecretType:
+		length := len(values[secretTypes.Password])
+		if length == 0 {
+			length = 12
+		}
+
+		// generateValuesIfNeeded generates a new password from a method,length pair
+		values[secretTypes.Password] = fmt.Sprintf("randAlphaNum,%d", length)
+
+	case secretTypes.HtpasswdSecretType:
+		// generateValuesIfNeeded generates a new password and htpasswd file if they are missing
+		delete(values, secretTypes.Password)
+		delete(values, secretTypes.HtpasswdFile)
+
+	case secretTypes.TLSSecretType:
+		if containsString(fields, secretTypes.CACert) {
+			// generateValuesIfNeeded generates the whole certificate chain if only the hosts and validity are present
+			for k := range values {
+				if k != secretTypes.TLSHosts && k != secretTypes.TLSValidity {
+					delete(values, k)
+				}
+			}
+
+			break
+		}
+
+		return reissueCertificates(values, fields)
+
+	default:
+		return errors.Errorf("secrets of type %s cannot be rotated", secretType)
+	}
+
+	return generateValuesIfNeeded(&CreateSecretRequest{Type: secretType, Values: values})
+}
+
+// reissueCertificates issues new server and/or client certificates with the CA of a TLS secret
+func reissueCertificates(values map[string]string, fields []string) error {
+	caCert, err := parseCertificate(values[secretTypes.CACert])
+	if err != nil {
+		return errors.Wrap(err, "could not parse CA certificate")
+	}
+
+	caKey, err := parsePrivateKey(values[secretTypes.CAKey])
+	if err != nil {
+		return errors.Wrap(err, "could not parse CA key")
+	}
+
+	// certificates issued now would be due to renewal right away, because they cannot outlive the CA
+	if renewAt := certificateRenewAt(caCert); !time.Now().Before(renewAt) {
+		return errors.Errorf("CA certificate expires at %s, the CA certificate has to be rotated as well", caCert.NotAfter.Format(time.RFC3339))
+	}
+
+	validity := values[secretTypes.TLSValidity]
+	if validity == "" {
+		validity = viper.GetString("tls.validity")
+	}
+
+	duration, err := time.ParseDuration(validity)
+	if err != nil {
+		return errors.Wrap(err, "invalid certificate validity")
+	}
+
+	hosts := strings.Split(values[secretTypes.TLSHosts], ",")
+
+	if containsString(fields, secretTypes.ServerCert) {
+		cert, key, err := issueCertificate(caCert, caKey, hosts, duration, x509.ExtKeyUsageServerAuth)
+		if err != nil {
+			return errors.Wrap(err, "could not issue server certificate")
+		}
+
+		values[secretTypes.ServerCert] = cert
+		values[secretTypes.ServerKey] = key
+	}
+
+	if containsString(fields, secretTypes.ClientCert) {
+		cert, key, err := issueCertificate(caCert, caKey, hosts, duration, x509.ExtKeyUsageClientAuth)
+		if err != nil {
+			return errors.Wrap(err, "could not issue client certificate")
+		}
+
+		values[secretTypes.ClientCert] = cert
+		values[secretTypes.ClientKey] = key
+	}
+
+	return nil
+}
+
+// issueCertificate returns a PEM encoded certificate and RSA key signed by the CA, it does not outlive the CA.
+func issueCertificate(caCert *x509.Certificate, caKey interface{}, hosts []string, validity time.Duration, usage x509.ExtKeyUsage) (string, string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", err
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", "", err
+	}
+
+	notBefore := time.Now()
+	notAfter := notBefore.Add(validity)
+	if notAfter.After(caCert.NotAfter) {
+		notAfter = caCert.NotAfter
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: caCert.Subject.Organization,
+			CommonName:   strings.TrimSpace(hosts[0]),
+		},
+		NotBefore:   notBefore,
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{usage},
+	}
+
+	for _, host := range hosts {
+		host = strings.TrimSpace(host)
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	return string(cert), string(keyPEM), nil
+}
+
+func parseCertificate(data string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func parsePrivateKey(data string) (interface{}, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		switch key.(type) {
+		case *rsa.PrivateKey, *ecdsa.PrivateKey:
+			return key, nil
+		}
+	}
+
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New("unsupported private key format")
+}
+
+// nextRotation returns when a secret is due to rotation next.
+// TLS certificates are re-issued before they expire even if the interval has not passed yet.
+func nextRotation(secret *SecretItemResponse, interval time.Duration, fields []string, lastRotation time.Time) time.Time {
+	next := lastRotation.Add(interval)
+
+	if secret.Type != secretTypes.TLSSecretType {
+		return next
+	}
+
+	for _, field := range fields {
+		cert, err := parseCertificate(secret.Values[field])
+		if err != nil {
+			continue
+		}
+
+		if renewAt := certificateRenewAt(cert); renewAt.Before(next) {
+			next = renewAt
+		}
+	}
+
+	return next
+}
+
+// certificateRenewAt returns when a certificate is due to renewal.
+// Certificates with a short lifetime are renewed when two thirds of it has passed.
+func certificateRenewAt(cert *x509.Certificate) time.Time {
+	before := viper.GetDuration(config.SecretRotationTLSRenewBefore)
+	if lifetime := cert.NotAfter.Sub(cert.NotBefore); before > lifetime/3 {
+		before = lifetime / 3
+	}
+
+	return cert.NotAfter.Add(-before)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,156 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// rotationRetryDelay is the delay before retrying a failed rotation
+const rotationRetryDelay = 15 * time.Minute
+
+// RotationListener is notified after a secret has been rotated, eg. to update the copies installed into clusters
+type RotationListener func(organizationID uint, secretID string) error
+
+// RotationScheduler regenerates the values of secrets when their rotation policy makes them due.
+type RotationScheduler struct {
+	store         SecretStore
+	db            *gorm.DB
+	checkInterval time.Duration
+	listener      RotationListener
+	ticker        *time.Ticker
+
+	logger       logrus.FieldLogger
+	errorHandler emperror.Handler
+}
+
+// NewRotationScheduler returns a new rotation scheduler.
+func NewRotationScheduler(
+	store SecretStore,
+	db *gorm.DB,
+	checkInterval time.Duration,
+	listener RotationListener,
+	logger logrus.FieldLogger,
+	errorHandler emperror.Handler,
+) *RotationScheduler {
+	return &RotationScheduler{
+		store:         store,
+		db:            db,
+		checkInterval: checkInterval,
+		listener:      listener,
+		logger:        logger,
+		errorHandler:  errorHandler,
+	}
+}
+
+// Start starts checking for secrets due to rotation in the background.
+func (s *RotationScheduler) Start() {
+	s.ticker = time.NewTicker(s.checkInterval)
+
+	go func() {
+		for range s.ticker.C {
+			s.rotateDueSecrets()
+		}
+	}()
+}
+
+// Stop stops the scheduler.
+func (s *RotationScheduler) Stop() {
+	s.ticker.Stop()
+}
+
+func (s *RotationScheduler) rotateDueSecrets() {
+	var policies []*rotationPolicyModel
+
+	err := s.db.Where("next_rotation_at <= ?", time.Now()).Order("next_rotation_at").Find(&policies).Error
+	if err != nil {
+		s.errorHandler.Handle(errors.Wrap(err, "could not list secrets due to rotation"))
+		return
+	}
+
+	for _, policy := range policies {
+		s.rotate(policy)
+	}
+}
+
+func (s *RotationScheduler) rotate(policy *rotationPolicyModel) {
+	logger := s.logger.WithFields(logrus.Fields{
+		"organization": policy.OrganizationID,
+		"secret":       policy.SecretID,
+	})
+
+	logger.Info("rotating secret")
+
+	now := time.Now()
+
+	err := s.rotateSecret(policy, now)
+	if err == ErrSecretNotExists {
+		logger.Info("secret does not exist anymore, deleting its rotation policy")
+
+		if err := s.db.Delete(policy).Error; err != nil {
+			s.errorHandler.Handle(emperror.With(errors.Wrap(err, "could not delete rotation policy"), "secret", policy.SecretID))
+		}
+
+		return
+	}
+
+	if err != nil {
+		s.errorHandler.Handle(emperror.With(err, "organization", policy.OrganizationID, "secret", policy.SecretID))
+
+		policy.LastError = err.Error()
+		policy.NextRotationAt = now.Add(rotationRetryDelay)
+	}
+
+	if err := s.db.Save(policy).Error; err != nil {
+		s.errorHandler.Handle(emperror.With(errors.Wrap(err, "could not save rotation policy"), "secret", policy.SecretID))
+		return
+	}
+
+	if policy.LastError != "" || s.listener == nil {
+		return
+	}
+
+	if err := s.listener(policy.OrganizationID, policy.SecretID); err != nil {
+		s.errorHandler.Handle(emperror.With(
+			errors.Wrap(err, "could not update rotated secret in clusters"),
+			"organization", policy.OrganizationID,
+			"secret", policy.SecretID,
+		))
+	}
+}
+
+// rotateSecret rotates the secret and updates the policy with the next rotation
+func (s *RotationScheduler) rotateSecret(policy *rotationPolicyModel, now time.Time) error {
+	fields, err := policy.fields()
+	if err != nil {
+		return err
+	}
+
+	secret, err := RotateSecret(s.store, policy.OrganizationID, policy.SecretID, fields)
+	if err != nil {
+		return err
+	}
+
+	policy.LastRotatedAt = &now
+	policy.LastError = ""
+	policy.NextRotationAt = nextRotation(secret, policy.Interval, fields, now)
+
+	return nil
+}
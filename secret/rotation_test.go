@@ -0,0 +1,196 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	pkgSecret "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/banzaicloud/pipeline/secret"
+)
+
+func TestRotateSecret(t *testing.T) {
+	store := secret.NewInMemorySecretStore()
+
+	const orgID = 1
+
+	cases := []struct {
+		name    string
+		request secret.CreateSecretRequest
+		fields  []string
+		changed []string
+		kept    []string
+	}{
+		{
+			name: "password",
+			request: secret.CreateSecretRequest{
+				Name:   "rotated-password",
+				Type:   pkgSecret.PasswordSecretType,
+				Values: map[string]string{pkgSecret.Username: "user"},
+			},
+			fields:  []string{pkgSecret.Password},
+			changed: []string{pkgSecret.Password},
+			kept:    []string{pkgSecret.Username},
+		},
+		{
+			name: "htpasswd",
+			request: secret.CreateSecretRequest{
+				Name:   "rotated-htpasswd",
+				Type:   pkgSecret.HtpasswdSecretType,
+				Values: map[string]string{pkgSecret.Username: "user"},
+			},
+			fields:  []string{pkgSecret.Password},
+			changed: []string{pkgSecret.Password, pkgSecret.HtpasswdFile},
+			kept:    []string{pkgSecret.Username},
+		},
+		{
+			name: "tls server certificate",
+			request: secret.CreateSecretRequest{
+				Name:   "rotated-tls",
+				Type:   pkgSecret.TLSSecretType,
+				Values: map[string]string{pkgSecret.TLSHosts: "localhost,127.0.0.1", pkgSecret.TLSValidity: "24h"},
+			},
+			fields:  []string{pkgSecret.ServerCert},
+			changed: []string{pkgSecret.ServerCert, pkgSecret.ServerKey},
+			kept:    []string{pkgSecret.CACert, pkgSecret.CAKey, pkgSecret.ClientCert, pkgSecret.ClientKey},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			secretID, err := store.Store(orgID, &tc.request)
+			if err != nil {
+				t.Fatalf("could not store secret: %s", err.Error())
+			}
+
+			original, err := store.Get(orgID, secretID)
+			if err != nil {
+				t.Fatalf("could not get secret: %s", err.Error())
+			}
+
+			rotated, err := secret.RotateSecret(store, orgID, secretID, tc.fields)
+			if err != nil {
+				t.Fatalf("could not rotate secret: %s", err.Error())
+			}
+
+			if rotated.Version != original.Version+1 {
+				t.Errorf("expected version %d, got: %d", original.Version+1, rotated.Version)
+			}
+
+			for _, field := range tc.changed {
+				if rotated.Values[field] == "" || rotated.Values[field] == original.Values[field] {
+					t.Errorf("expected field %s to be regenerated", field)
+				}
+			}
+
+			for _, field := range tc.kept {
+				if rotated.Values[field] != original.Values[field] {
+					t.Errorf("expected field %s to be kept", field)
+				}
+			}
+
+			if tc.request.Type == pkgSecret.TLSSecretType {
+				verifyCertificate(t, rotated.Values[pkgSecret.CACert], rotated.Values[pkgSecret.ServerCert], "localhost")
+			}
+		})
+	}
+}
+
+func verifyCertificate(t *testing.T, caCertPEM string, certPEM string, host string) {
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(caCertPEM)) {
+		t.Fatal("could not parse CA certificate")
+	}
+
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		t.Fatal("could not decode certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("could not parse certificate: %s", err.Error())
+	}
+
+	_, err = cert.Verify(x509.VerifyOptions{
+		DNSName:   host,
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	if err != nil {
+		t.Errorf("certificate is not issued by the CA: %s", err.Error())
+	}
+}
+
+func TestRotateSecret_ExpiringCA(t *testing.T) {
+	store := secret.NewInMemorySecretStore()
+
+	const orgID = 1
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate CA key: %s", err.Error())
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-48 * time.Hour),
+		NotAfter:              time.Now().Add(-time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("could not create CA certificate: %s", err.Error())
+	}
+
+	secretID, err := store.Store(orgID, &secret.CreateSecretRequest{
+		Name: "expiring-ca",
+		Type: pkgSecret.TLSSecretType,
+		Values: map[string]string{
+			pkgSecret.TLSHosts: "localhost",
+			pkgSecret.CACert:   string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+			pkgSecret.CAKey:    string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		},
+	})
+	if err != nil {
+		t.Fatalf("could not store secret: %s", err.Error())
+	}
+
+	_, err = secret.RotateSecret(store, orgID, secretID, []string{pkgSecret.ServerCert})
+	if err == nil || !strings.Contains(err.Error(), "CA certificate expires") {
+		t.Fatalf("expected an expiring CA error, got: %v", err)
+	}
+
+	current, err := store.Get(orgID, secretID)
+	if err != nil {
+		t.Fatalf("could not get secret: %s", err.Error())
+	}
+
+	if current.Version != 1 {
+		t.Errorf("expected the secret not to be rotated, got version %d", current.Version)
+	}
+}
@@ -0,0 +1,432 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// sqlSecretStore keeps secrets in the application database with their values encrypted by AES-GCM.
+type sqlSecretStore struct {
+	db     *gorm.DB
+	cipher *valueCipher
+}
+
+// NewSQLSecretStore returns a new secret store keeping secrets in the database.
+// The key has to be 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+func NewSQLSecretStore(db *gorm.DB, key []byte) (SecretStore, error) {
+	c, err := newValueCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqlSecretStore{db: db, cipher: c}, nil
+}
+
+func (s *sqlSecretStore) Store(organizationID uint, value *CreateSecretRequest) (string, error) {
+	if err := prepareSecretToStore(value); err != nil {
+		return "", err
+	}
+
+	secretID := GenerateSecretID(value)
+
+	var count int
+	err := s.db.Model(&secretModel{}).Where(&secretModel{OrganizationID: organizationID, SecretID: secretID}).Count(&count).Error
+	if err != nil {
+		return "", errors.Wrap(err, "Error during storing secret")
+	}
+
+	if count > 0 {
+		return "", errors.Wrap(errCASMismatch, "Error during storing secret")
+	}
+
+	model := &secretModel{
+		OrganizationID: organizationID,
+		SecretID:       secretID,
+		Version:        1,
+	}
+
+	if err := s.setValue(model, value); err != nil {
+		return "", err
+	}
+
+	if err := s.create(model); err != nil {
+		return "", errors.Wrap(err, "Error during storing secret")
+	}
+
+	return secretID, nil
+}
+
+func (s *sqlSecretStore) Update(organizationID uint, secretID string, value *CreateSecretRequest) error {
+	if GenerateSecretID(value) != secretID {
+		return errors.New("Secret name cannot be changed")
+	}
+
+	sort.Strings(value.Tags)
+
+	version := 0
+	if value.Version != nil {
+		version = *value.Version
+		value.Version = nil
+	}
+
+	// If secret doesn't exists, create it.
+	if version == 0 {
+		model := &secretModel{
+			OrganizationID: organizationID,
+			SecretID:       secretID,
+			Version:        1,
+		}
+
+		if err := s.setValue(model, value); err != nil {
+			return err
+		}
+
+		// the secret has been created in the meantime if the unique index is violated
+		if err := s.create(model); err != nil {
+			return errors.Wrap(errCASMismatch, "Error during updating secret")
+		}
+
+		return nil
+	}
+
+	model := &secretModel{
+		OrganizationID: organizationID,
+		SecretID:       secretID,
+		Version:        version + 1,
+	}
+	if err := s.setValue(model, value); err != nil {
+		return err
+	}
+
+	tx := s.db.Begin()
+
+	// the version condition makes the update a check-and-set operation
+	result := tx.Model(&secretModel{}).
+		Where("organization_id = ? AND secret_id = ? AND version = ?", organizationID, secretID, version).
+		Updates(map[string]interface{}{
+			"name":             model.Name,
+			"type":             model.Type,
+			"tags":             model.Tags,
+			"encrypted_values": model.Values,
+			"updated_by":       model.UpdatedBy,
+			"version":          model.Version,
+		})
+	if result.Error != nil {
+		tx.Rollback()
+		return errors.Wrap(result.Error, "Error during updating secret")
+	}
+
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return errors.Wrap(errCASMismatch, "Error during updating secret")
+	}
+
+	if err := tx.Create(newSecretVersionModel(model)).Error; err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "Error during updating secret")
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.Wrap(err, "Error during updating secret")
+	}
+
+	return nil
+}
+
+// create saves a new secret with its first version
+func (s *sqlSecretStore) create(model *secretModel) error {
+	tx := s.db.Begin()
+
+	if err := tx.Create(model).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Create(newSecretVersionModel(model)).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func newSecretVersionModel(model *secretModel) *secretVersionModel {
+	return &secretVersionModel{
+		OrganizationID: model.OrganizationID,
+		SecretID:       model.SecretID,
+		Version:        model.Version,
+		Name:           model.Name,
+		Type:           model.Type,
+		Tags:           model.Tags,
+		Values:         model.Values,
+		UpdatedBy:      model.UpdatedBy,
+	}
+}
+
+func (s *sqlSecretStore) Get(organizationID uint, secretID string) (*SecretItemResponse, error) {
+	var model secretModel
+
+	err := s.db.First(&model, map[string]interface{}{"organization_id": organizationID, "secret_id": secretID}).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, ErrSecretNotExists
+	} else if err != nil {
+		return nil, errors.Wrap(err, "Error during reading secret")
+	}
+
+	return s.parseSecret(&model, true)
+}
+
+func (s *sqlSecretStore) GetByName(organizationID uint, name string) (*SecretItemResponse, error) {
+	return getSecretByName(s, organizationID, name)
+}
+
+func (s *sqlSecretStore) List(organizationID uint, query *secretTypes.ListSecretsQuery) ([]*SecretItemResponse, error) {
+	log.Debugf("Searching for secrets [orgid: %d, query: %#v]", organizationID, query)
+
+	db := s.db.Where("organization_id = ?", organizationID)
+	if len(query.IDs) > 0 {
+		db = db.Where("secret_id IN (?)", query.IDs)
+	}
+	if query.Type != secretTypes.AllSecrets {
+		db = db.Where("type = ?", query.Type)
+	}
+
+	var models []*secretModel
+	if err := db.Order("secret_id").Find(&models).Error; err != nil {
+		log.Errorf("Error listing secrets: %s", err.Error())
+		return nil, errors.Wrap(err, "Error during listing secrets")
+	}
+
+	responseItems := []*SecretItemResponse{}
+
+	for _, model := range models {
+		sir, err := s.parseSecret(model, query.Values)
+		if err != nil {
+			return nil, err
+		}
+
+		if matchesQuery(sir, query) {
+			responseItems = append(responseItems, sir)
+		}
+	}
+
+	return responseItems, nil
+}
+
+func (s *sqlSecretStore) Delete(organizationID uint, secretID string) error {
+	log.Debugf("Delete secret: %d/%s", organizationID, secretID)
+
+	tx := s.db.Begin()
+
+	for _, model := range []interface{}{&secretModel{}, &secretVersionModel{}} {
+		err := tx.Where("organization_id = ? AND secret_id = ?", organizationID, secretID).Delete(model).Error
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "Error during deleting secret")
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.Wrap(err, "Error during deleting secret")
+	}
+
+	return nil
+}
+
+func (s *sqlSecretStore) ListVersions(organizationID uint, secretID string) ([]*SecretVersionResponse, error) {
+	var models []*secretVersionModel
+
+	err := s.db.Where("organization_id = ? AND secret_id = ?", organizationID, secretID).Order("version DESC").Find(&models).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "Error during listing secret versions")
+	}
+
+	if len(models) == 0 {
+		return nil, ErrSecretNotExists
+	}
+
+	versions := make([]*SecretVersionResponse, 0, len(models))
+	for _, model := range models {
+		versions = append(versions, &SecretVersionResponse{
+			Version:   model.Version,
+			CreatedAt: model.CreatedAt,
+			CreatedBy: model.UpdatedBy,
+		})
+	}
+
+	return versions, nil
+}
+
+func (s *sqlSecretStore) GetVersion(organizationID uint, secretID string, version int) (*SecretItemResponse, error) {
+	var model secretVersionModel
+
+	err := s.db.First(&model, map[string]interface{}{"organization_id": organizationID, "secret_id": secretID, "version": version}).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, ErrSecretVersionNotExists
+	} else if err != nil {
+		return nil, errors.Wrap(err, "Error during reading secret")
+	}
+
+	return s.parseSecret(&secretModel{
+		OrganizationID: model.OrganizationID,
+		SecretID:       model.SecretID,
+		Name:           model.Name,
+		Type:           model.Type,
+		Tags:           model.Tags,
+		Values:         model.Values,
+		Version:        model.Version,
+		UpdatedAt:      model.CreatedAt,
+		UpdatedBy:      model.UpdatedBy,
+	}, true)
+}
+
+func (s *sqlSecretStore) Rollback(organizationID uint, secretID string, version int, updatedBy string) error {
+	return rollbackSecret(s, organizationID, secretID, version, updatedBy)
+}
+
+func (s *sqlSecretStore) DeleteByClusterUID(organizationID uint, clusterUID string) error {
+	return deleteSecretsByClusterUID(s, organizationID, clusterUID)
+}
+
+func (s *sqlSecretStore) GetOrCreate(organizationID uint, value *CreateSecretRequest) (string, error) {
+	return getOrCreateSecret(s, organizationID, value)
+}
+
+func (s *sqlSecretStore) CreateOrUpdate(organizationID uint, value *CreateSecretRequest) (string, error) {
+	return createOrUpdateSecret(s, organizationID, value)
+}
+
+// setValue sets the fields of the model from a request encrypting its values
+func (s *sqlSecretStore) setValue(model *secretModel, value *CreateSecretRequest) error {
+	tags, err := json.Marshal(value.Tags)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal secret tags")
+	}
+
+	values, err := json.Marshal(value.Values)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal secret values")
+	}
+
+	encryptedValues, err := s.cipher.encrypt(values, secretAdditionalData(model.OrganizationID, model.SecretID))
+	if err != nil {
+		return err
+	}
+
+	model.Name = value.Name
+	model.Type = value.Type
+	model.Tags = string(tags)
+	model.Values = encryptedValues
+	model.UpdatedBy = value.UpdatedBy
+
+	return nil
+}
+
+func (s *sqlSecretStore) parseSecret(model *secretModel, values bool) (*SecretItemResponse, error) {
+	sir := &SecretItemResponse{
+		ID:        model.SecretID,
+		Name:      model.Name,
+		Type:      model.Type,
+		Version:   model.Version,
+		UpdatedAt: model.UpdatedAt,
+		UpdatedBy: model.UpdatedBy,
+	}
+
+	if err := json.Unmarshal([]byte(model.Tags), &sir.Tags); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal secret tags")
+	}
+
+	rawValues, err := s.cipher.decrypt(model.Values, secretAdditionalData(model.OrganizationID, model.SecretID))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(rawValues, &sir.Values); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal secret values")
+	}
+
+	if !values {
+		sir.HideValues()
+	}
+
+	return sir, nil
+}
+
+// secretAdditionalData binds the encrypted values to their secret, so they cannot be swapped between rows
+func secretAdditionalData(organizationID uint, secretID string) []byte {
+	return []byte(fmt.Sprintf("%d/%s", organizationID, secretID))
+}
+
+// valueCipher encrypts secret values with AES-GCM
+type valueCipher struct {
+	aead cipher.AEAD
+}
+
+func newValueCipher(key []byte) (*valueCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid secret store master key")
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create AES-GCM cipher")
+	}
+
+	return &valueCipher{aead: aead}, nil
+}
+
+// encrypt returns the base64 encoded nonce and ciphertext
+func (c *valueCipher) encrypt(plaintext []byte, additionalData []byte) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", errors.Wrap(err, "could not generate nonce")
+	}
+
+	ciphertext := c.aead.Seal(nonce, nonce, plaintext, additionalData)
+
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+func (c *valueCipher) decrypt(encoded string, additionalData []byte) ([]byte, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode encrypted secret values")
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, errors.New("encrypted secret values are too short")
+	}
+
+	plaintext, err := c.aead.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], additionalData)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decrypt secret values")
+	}
+
+	return plaintext, nil
+}
@@ -16,6 +16,7 @@ package secret
 
 import (
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"sort"
@@ -25,9 +26,11 @@ import (
 
 	"github.com/banzaicloud/bank-vaults/pkg/tls"
 	"github.com/banzaicloud/bank-vaults/vault"
+	"github.com/banzaicloud/pipeline/config"
 	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
 	"github.com/banzaicloud/pipeline/secret/verify"
 	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/jinzhu/gorm"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -37,8 +40,47 @@ import (
 	"k8s.io/apimachinery/pkg/util/validation"
 )
 
-// Store object that wraps up vault logical store
-var Store *secretStore
+// SecretStore stores the secrets of organizations
+type SecretStore interface {
+	// Store saves a new secret, it fails if a secret with the same name already exists
+	Store(organizationID uint, value *CreateSecretRequest) (string, error)
+
+	// Get returns a secret with its values
+	Get(organizationID uint, secretID string) (*SecretItemResponse, error)
+
+	// GetByName returns a secret with its values by the name of the secret
+	GetByName(organizationID uint, name string) (*SecretItemResponse, error)
+
+	// List returns the secrets of an organization matching the query
+	List(organizationID uint, query *secretTypes.ListSecretsQuery) ([]*SecretItemResponse, error)
+
+	// Update saves a new version of a secret, value.Version has to match the current version
+	Update(organizationID uint, secretID string, value *CreateSecretRequest) error
+
+	// Delete deletes a secret with all of its versions
+	Delete(organizationID uint, secretID string) error
+
+	// DeleteByClusterUID deletes the secrets belonging to a cluster
+	DeleteByClusterUID(organizationID uint, clusterUID string) error
+
+	// GetOrCreate returns the ID of an existing secret or stores it if it does not exist
+	GetOrCreate(organizationID uint, value *CreateSecretRequest) (string, error)
+
+	// CreateOrUpdate stores a secret or updates it if it already exists
+	CreateOrUpdate(organizationID uint, value *CreateSecretRequest) (string, error)
+
+	// ListVersions returns the versions of a secret, the latest one first
+	ListVersions(organizationID uint, secretID string) ([]*SecretVersionResponse, error)
+
+	// GetVersion returns a version of a secret with its values
+	GetVersion(organizationID uint, secretID string, version int) (*SecretItemResponse, error)
+
+	// Rollback saves the values of an earlier version of a secret as its latest version
+	Rollback(organizationID uint, secretID string, version int, updatedBy string) error
+}
+
+// Store object that wraps up the configured secret store backend, it is set by SetStore upon application init
+var Store SecretStore
 
 // RestrictedStore object that wraps the main secret store and restricts access to certain items
 var RestrictedStore *restrictedSecretStore
@@ -46,9 +88,40 @@ var RestrictedStore *restrictedSecretStore
 // ErrSecretNotExists denotes 'Not Found' errors for secrets
 var ErrSecretNotExists = fmt.Errorf("There's no secret with this ID")
 
-func init() {
-	Store = newVaultSecretStore()
-	RestrictedStore = &restrictedSecretStore{Store}
+// ErrSecretVersionNotExists denotes 'Not Found' errors for secret versions
+var ErrSecretVersionNotExists = fmt.Errorf("There's no secret version with this number")
+
+// errCASMismatch is returned by non-Vault backends when the version of a secret does not match.
+// The message matches the one returned by Vault, so IsCASError works for every backend.
+var errCASMismatch = errors.New("check-and-set parameter did not match the current version")
+
+// SetStore sets the secret store backend used by the application.
+func SetStore(store SecretStore) {
+	Store = store
+	RestrictedStore = &restrictedSecretStore{store}
+}
+
+// NewStore returns the secret store backend configured by name.
+// The master key is the base64 encoded encryption key of the SQL backend.
+func NewStore(backend string, db *gorm.DB, masterKey string) (SecretStore, error) {
+	switch backend {
+	case config.SecretStoreBackendVault:
+		return newVaultSecretStore(), nil
+
+	case config.SecretStoreBackendSQL:
+		key, err := base64.StdEncoding.DecodeString(masterKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "secret store master key must be base64 encoded")
+		}
+
+		return NewSQLSecretStore(db, key)
+
+	case config.SecretStoreBackendMemory:
+		return NewInMemorySecretStore(), nil
+
+	default:
+		return nil, errors.Errorf("unknown secret store backend: %s", backend)
+	}
 }
 
 type secretStore struct {
@@ -87,6 +160,22 @@ type SecretItemResponse struct {
 	Version   int               `json:"version"`
 	UpdatedAt time.Time         `json:"updatedAt"`
 	UpdatedBy string            `json:"updatedBy,omitempty"`
+
+	// Installations lists the clusters the secret has been installed into, it is not stored with the secret
+	Installations []secretTypes.SecretInstallation `json:"installations,omitempty"`
+}
+
+// SecretVersionResponse describes a version of a secret
+type SecretVersionResponse struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	CreatedBy string    `json:"createdBy,omitempty"`
+	Deleted   bool      `json:"deleted,omitempty"`
+}
+
+// RollbackSecretRequest param for Store.Rollback
+type RollbackSecretRequest struct {
+	Version int `json:"version" binding:"required"`
 }
 
 // K8SSourceMeta returns the meta information how to use this secret if installed to K8S
@@ -102,6 +191,13 @@ func (s *SecretItemResponse) GetValue(key string) string {
 	return s.Values[key]
 }
 
+// HideValues clears the values
+func (s *SecretItemResponse) HideValues() {
+	for k := range s.Values {
+		s.Values[k] = "<hidden>"
+	}
+}
+
 // ValidateSecretType validates the secret type
 func (s *SecretItemResponse) ValidateSecretType(validType string) error {
 	if string(s.Type) != validType {
@@ -165,33 +261,7 @@ func (r *CreateSecretRequest) Validate(verifier verify.Verifier) error {
 
 // DeleteByClusterUID Delete secrets by ClusterUID
 func (ss *secretStore) DeleteByClusterUID(orgID uint, clusterUID string) error {
-	if clusterUID == "" {
-		return errors.New("ClusterUID is empty.")
-	}
-
-	log := log.WithFields(logrus.Fields{"organization": orgID, "clusterUID": clusterUID})
-
-	clusterIdTag := fmt.Sprintf("clusterUID:%s", clusterUID)
-	secrets, err := Store.List(orgID,
-		&secretTypes.ListSecretsQuery{
-			Tags: []string{clusterIdTag},
-		})
-
-	if err != nil {
-		log.Errorf("Error during list secrets: %s", err.Error())
-		return err
-	}
-
-	for _, s := range secrets {
-		log := log.WithFields(logrus.Fields{"secret": s.ID, "secretName": s.Name})
-		err := Store.Delete(orgID, s.ID)
-		if err != nil {
-			log.Errorf("Error during delete secret: %s", err.Error())
-		}
-		log.Infoln("Secret Deleted")
-	}
-
-	return nil
+	return deleteSecretsByClusterUID(ss, orgID, clusterUID)
 }
 
 // Delete secret secret/orgs/:orgid:/:id: scope
@@ -211,22 +281,13 @@ func (ss *secretStore) Delete(organizationID uint, secretID string) error {
 // Save secret secret/orgs/:orgid:/:id: scope
 func (ss *secretStore) Store(organizationID uint, value *CreateSecretRequest) (string, error) {
 
-	// We allow only Kubernetes compatible Secret names
-	if errorList := validation.IsDNS1123Subdomain(value.Name); errorList != nil {
-		return "", errors.New(errorList[0])
+	if err := prepareSecretToStore(value); err != nil {
+		return "", err
 	}
 
 	secretID := GenerateSecretID(value)
 	path := secretDataPath(organizationID, secretID)
 
-	if err := generateValuesIfNeeded(value); err != nil {
-		return "", err
-	}
-
-	sort.Strings(value.Tags)
-
-	value.Version = nil
-
 	data := vault.NewData(0, map[string]interface{}{"value": value})
 
 	if _, err := ss.Logical.Write(path, data); err != nil {
@@ -267,48 +328,92 @@ func (ss *secretStore) Update(organizationID uint, secretID string, value *Creat
 
 // GetOrCreate create new secret or get if it's exist. secret/orgs/:orgid:/:id: scope
 func (ss *secretStore) GetOrCreate(organizationID uint, value *CreateSecretRequest) (string, error) {
-	secretID := GenerateSecretID(value)
-
-	// Try to get the secret version first
-	if secret, err := Store.Get(organizationID, secretID); err != nil && err != ErrSecretNotExists {
-		log.Errorf("Error during checking secret: %s", err.Error())
-		return "", err
-	} else if secret != nil {
-		return secret.ID, nil
-	} else {
-		secretID, err = Store.Store(organizationID, value)
-		if err != nil {
-			log.Errorf("Error during storing secret: %s", err.Error())
-			return "", err
-		}
-	}
-	return secretID, nil
+	return getOrCreateSecret(ss, organizationID, value)
 }
 
 // CreateOrUpdate create new secret or update if it's exist. secret/orgs/:orgid:/:id: scope
 func (ss *secretStore) CreateOrUpdate(organizationID uint, value *CreateSecretRequest) (string, error) {
+	return createOrUpdateSecret(ss, organizationID, value)
+}
 
-	secretID := GenerateSecretID(value)
+// ListVersions lists the versions of a secret kept by Vault secret/orgs/:orgid:/:id: scope
+func (ss *secretStore) ListVersions(organizationID uint, secretID string) ([]*SecretVersionResponse, error) {
 
-	// Try to get the secret version first
-	if secret, err := Store.Get(organizationID, secretID); err != nil && err != ErrSecretNotExists {
-		log.Errorf("Error during checking secret: %s", err.Error())
-		return "", err
-	} else if secret != nil {
-		value.Version = &(secret.Version)
-		err := Store.Update(organizationID, secretID, value)
+	path := secretMetadataPath(organizationID, secretID)
+
+	log.Debugln("List secret versions:", path)
+
+	metadata, err := ss.Logical.Read(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error during reading secret metadata")
+	}
+
+	if metadata == nil {
+		return nil, ErrSecretNotExists
+	}
+
+	versions := []*SecretVersionResponse{}
+
+	for key, rawVersion := range cast.ToStringMap(metadata.Data["versions"]) {
+		version, err := strconv.Atoi(key)
 		if err != nil {
-			log.Errorf("Error during updating secret: %s", err.Error())
-			return "", err
+			return nil, errors.Wrapf(err, "invalid secret version: %s", key)
 		}
-	} else {
-		secretID, err = Store.Store(organizationID, value)
+
+		versionMetadata := cast.ToStringMap(rawVersion)
+
+		createdAt, err := time.Parse(time.RFC3339, cast.ToString(versionMetadata["created_time"]))
 		if err != nil {
-			log.Errorf("Error during storing secret: %s", err.Error())
-			return "", err
+			return nil, err
 		}
+
+		item := &SecretVersionResponse{
+			Version:   version,
+			CreatedAt: createdAt,
+			Deleted:   cast.ToString(versionMetadata["deletion_time"]) != "" || cast.ToBool(versionMetadata["destroyed"]),
+		}
+
+		// the authoring user is only stored with the data of the version
+		if !item.Deleted {
+			secret, err := ss.GetVersion(organizationID, secretID, version)
+			if err != nil && err != ErrSecretVersionNotExists {
+				return nil, err
+			} else if secret != nil {
+				item.CreatedBy = secret.UpdatedBy
+			}
+		}
+
+		versions = append(versions, item)
 	}
-	return secretID, nil
+
+	sortVersions(versions)
+
+	return versions, nil
+}
+
+// GetVersion retrieves a version of a secret secret/orgs/:orgid:/:id: scope
+func (ss *secretStore) GetVersion(organizationID uint, secretID string, version int) (*SecretItemResponse, error) {
+
+	path := secretDataPath(organizationID, secretID)
+
+	log.Debugf("Get secret version: %s [%d]", path, version)
+
+	secret, err := ss.Logical.ReadWithData(path, map[string][]string{"version": {strconv.Itoa(version)}})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error during reading secret")
+	}
+
+	// deleted and destroyed versions are returned with metadata only
+	if secret == nil || secret.Data["data"] == nil {
+		return nil, ErrSecretVersionNotExists
+	}
+
+	return parseSecret(secretID, secret, true)
+}
+
+// Rollback restores a version of a secret secret/orgs/:orgid:/:id: scope
+func (ss *secretStore) Rollback(organizationID uint, secretID string, version int, updatedBy string) error {
+	return rollbackSecret(ss, organizationID, secretID, version, updatedBy)
 }
 
 func parseSecret(secretID string, secret *vaultapi.Secret, values bool) (*SecretItemResponse, error) {
@@ -346,9 +451,7 @@ func parseSecret(secretID string, secret *vaultapi.Secret, values bool) (*Secret
 
 	if !values {
 		// Clear the values otherwise
-		for k := range sir.Values {
-			sir.Values[k] = "<hidden>"
-		}
+		sir.HideValues()
 	}
 
 	return &sir, nil
@@ -376,18 +479,7 @@ func (ss *secretStore) Get(organizationID uint, secretID string) (*SecretItemRes
 
 // Retrieve secret by secret Name secret/orgs/:orgid:/:id: scope
 func (ss *secretStore) GetByName(organizationID uint, name string) (*SecretItemResponse, error) {
-
-	secretID := GenerateSecretIDFromName(name)
-	secret, err := Store.Get(organizationID, secretID)
-	if err != nil {
-		return nil, errors.Wrap(err, "Error during reading secret")
-	}
-
-	if secret == nil {
-		return nil, ErrSecretNotExists
-	}
-
-	return secret, nil
+	return getSecretByName(ss, organizationID, name)
 }
 
 func (ss *secretStore) getSecretIDs(orgid uint, query *secretTypes.ListSecretsQuery) ([]string, error) {
@@ -436,7 +528,7 @@ func (ss *secretStore) List(orgid uint, query *secretTypes.ListSecretsQuery) ([]
 				return nil, err
 			}
 
-			if (query.Type == secretTypes.AllSecrets || sir.Type == query.Type) && hasTags(sir.Tags, query.Tags) {
+			if matchesQuery(sir, query) {
 				responseItems = append(responseItems, sir)
 			}
 		}
@@ -466,6 +558,165 @@ func hasTags(tags []string, searchingTag []string) bool {
 	return true
 }
 
+// ReadVaultSecret reads a raw secret from Vault which is not scoped to an organization (eg. Pipeline's own credentials).
+// It returns nil if the secret does not exist or the secret store is not backed by Vault.
+func ReadVaultSecret(path string) (map[string]string, error) {
+	ss, ok := Store.(*secretStore)
+	if !ok {
+		return nil, nil
+	}
+
+	secret, err := ss.Logical.Read(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error during reading secret")
+	}
+
+	if secret == nil {
+		return nil, nil
+	}
+
+	return cast.ToStringMapString(secret.Data["data"]), nil
+}
+
+// prepareSecretToStore validates the name of a new secret and generates its values if needed
+func prepareSecretToStore(value *CreateSecretRequest) error {
+	// We allow only Kubernetes compatible Secret names
+	if errorList := validation.IsDNS1123Subdomain(value.Name); errorList != nil {
+		return errors.New(errorList[0])
+	}
+
+	if err := generateValuesIfNeeded(value); err != nil {
+		return err
+	}
+
+	sort.Strings(value.Tags)
+
+	value.Version = nil
+
+	return nil
+}
+
+// matchesQuery checks whether a secret matches the type and tags of a list query
+func matchesQuery(secret *SecretItemResponse, query *secretTypes.ListSecretsQuery) bool {
+	return (query.Type == secretTypes.AllSecrets || secret.Type == query.Type) && hasTags(secret.Tags, query.Tags)
+}
+
+func getSecretByName(store SecretStore, organizationID uint, name string) (*SecretItemResponse, error) {
+	secretID := GenerateSecretIDFromName(name)
+	secret, err := store.Get(organizationID, secretID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error during reading secret")
+	}
+
+	if secret == nil {
+		return nil, ErrSecretNotExists
+	}
+
+	return secret, nil
+}
+
+func getOrCreateSecret(store SecretStore, organizationID uint, value *CreateSecretRequest) (string, error) {
+	secretID := GenerateSecretID(value)
+
+	// Try to get the secret version first
+	if secret, err := store.Get(organizationID, secretID); err != nil && err != ErrSecretNotExists {
+		log.Errorf("Error during checking secret: %s", err.Error())
+		return "", err
+	} else if secret != nil {
+		return secret.ID, nil
+	} else {
+		secretID, err = store.Store(organizationID, value)
+		if err != nil {
+			log.Errorf("Error during storing secret: %s", err.Error())
+			return "", err
+		}
+	}
+	return secretID, nil
+}
+
+func createOrUpdateSecret(store SecretStore, organizationID uint, value *CreateSecretRequest) (string, error) {
+	secretID := GenerateSecretID(value)
+
+	// Try to get the secret version first
+	if secret, err := store.Get(organizationID, secretID); err != nil && err != ErrSecretNotExists {
+		log.Errorf("Error during checking secret: %s", err.Error())
+		return "", err
+	} else if secret != nil {
+		value.Version = &(secret.Version)
+		err := store.Update(organizationID, secretID, value)
+		if err != nil {
+			log.Errorf("Error during updating secret: %s", err.Error())
+			return "", err
+		}
+	} else {
+		secretID, err = store.Store(organizationID, value)
+		if err != nil {
+			log.Errorf("Error during storing secret: %s", err.Error())
+			return "", err
+		}
+	}
+	return secretID, nil
+}
+
+func rollbackSecret(store SecretStore, organizationID uint, secretID string, version int, updatedBy string) error {
+	current, err := store.Get(organizationID, secretID)
+	if err != nil {
+		return err
+	}
+
+	previous, err := store.GetVersion(organizationID, secretID, version)
+	if err != nil {
+		return err
+	}
+
+	// the current version is used as the check-and-set parameter, so concurrent changes are not overwritten
+	return store.Update(organizationID, secretID, &CreateSecretRequest{
+		Name:      previous.Name,
+		Type:      previous.Type,
+		Values:    previous.Values,
+		Tags:      previous.Tags,
+		Version:   &current.Version,
+		UpdatedBy: updatedBy,
+	})
+}
+
+// sortVersions sorts secret versions, the latest one first
+func sortVersions(versions []*SecretVersionResponse) {
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version > versions[j].Version
+	})
+}
+
+func deleteSecretsByClusterUID(store SecretStore, orgID uint, clusterUID string) error {
+	if clusterUID == "" {
+		return errors.New("ClusterUID is empty.")
+	}
+
+	log := log.WithFields(logrus.Fields{"organization": orgID, "clusterUID": clusterUID})
+
+	clusterIdTag := fmt.Sprintf("clusterUID:%s", clusterUID)
+	secrets, err := store.List(orgID,
+		&secretTypes.ListSecretsQuery{
+			Tags: []string{clusterIdTag},
+		})
+
+	if err != nil {
+		log.Errorf("Error during list secrets: %s", err.Error())
+		return err
+	}
+
+	for _, s := range secrets {
+		log := log.WithFields(logrus.Fields{"secret": s.ID, "secretName": s.Name})
+		err := store.Delete(orgID, s.ID)
+		if err != nil {
+			log.Errorf("Error during delete secret: %s", err.Error())
+		}
+		log.Infoln("Secret Deleted")
+	}
+
+	return nil
+}
+
 // MissmatchError describe a secret error where the given and expected secret type is not equal
 type MissmatchError struct {
 	Err        error
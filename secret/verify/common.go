@@ -17,6 +17,8 @@ package verify
 import (
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
 	oracle "github.com/banzaicloud/pipeline/pkg/providers/oracle/secret"
+	s3compatible "github.com/banzaicloud/pipeline/pkg/providers/s3compatible/secret"
+	pkgSecret "github.com/banzaicloud/pipeline/pkg/secret"
 )
 
 // Verifier validates cloud credentials
@@ -38,6 +40,8 @@ func NewVerifier(cloudType string, values map[string]string) Verifier {
 		return CreateGKESecret(values)
 	case pkgCluster.Oracle:
 		return oracle.CreateOCISecret(values)
+	case pkgSecret.S3CompatibleSecretType:
+		return s3compatible.CreateS3CompatibleSecret(values)
 	default:
 		return nil
 	}